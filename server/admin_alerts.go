@@ -0,0 +1,99 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	alertWebhookAuthFailures = "webhook_auth_failures"
+	alertJiraAuthExpired     = "jira_auth_expired"
+	alertWebhookQueueFull    = "webhook_queue_full"
+	alertJiraUnreachable     = "jira_unreachable"
+	alertJiraReachable       = "jira_reachable"
+
+	// An alert of the same kind is posted at most this often.
+	adminAlertMinInterval = time.Hour
+
+	// Webhook requests with a wrong secret are alerted on when there are
+	// webhookAuthFailureThreshold of them within a webhookAuthFailureWindow,
+	// counted from the first failure of the window.
+	webhookAuthFailureThreshold = 10
+	webhookAuthFailureWindow    = 10 * time.Minute
+)
+
+// adminAlerts records when operational alerts were last posted to the admin
+// channel, and counts the webhook authentication failures of the current
+// window. The failures are counted rather than recorded, since anyone can
+// send them.
+type adminAlerts struct {
+	lock              sync.Mutex
+	last              map[string]time.Time
+	authFailuresSince time.Time
+	authFailures      int
+}
+
+// due returns true, and records the alert, if no alert of the kind was posted
+// within adminAlertMinInterval.
+func (a *adminAlerts) due(kind string, now time.Time) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.last == nil {
+		a.last = map[string]time.Time{}
+	}
+	if last, ok := a.last[kind]; ok && now.Sub(last) < adminAlertMinInterval {
+		return false
+	}
+	a.last[kind] = now
+	return true
+}
+
+// recordAuthFailure counts a webhook authentication failure, and returns the
+// number of failures of the current window. A failure after the window
+// starts a new one.
+func (a *adminAlerts) recordAuthFailure(now time.Time) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if now.Sub(a.authFailuresSince) >= webhookAuthFailureWindow {
+		a.authFailuresSince = now
+		a.authFailures = 0
+	}
+	a.authFailures++
+	return a.authFailures
+}
+
+// alertAdmins posts the message id as an operational alert to the admin alerts
+// channel, if one is configured and the same kind of alert was not posted
+// recently.
+func (p *Plugin) alertAdmins(kind, id string, args ...interface{}) {
+	conf := p.getConfig()
+	if conf.AdminAlertsChannelId == "" || !p.adminAlerts.due(kind, time.Now()) {
+		return
+	}
+
+	post := &model.Post{
+		ChannelId: conf.AdminAlertsChannelId,
+		UserId:    conf.botUserID,
+		Message:   ":warning: " + p.localize(p.channelLocale(conf.AdminAlertsChannelId), id, args...),
+	}
+	_, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		p.errorf("alertAdmins: failed to post %s alert to channel %s: %v", kind, conf.AdminAlertsChannelId, appErr)
+	}
+}
+
+// recordWebhookAuthFailure alerts the admins when webhook requests with a
+// wrong secret spike, which usually means the secret was regenerated without
+// updating the webhooks in Jira.
+func (p *Plugin) recordWebhookAuthFailure() {
+	n := p.adminAlerts.recordAuthFailure(time.Now())
+	if n < webhookAuthFailureThreshold {
+		return
+	}
+	p.alertAdmins(alertWebhookAuthFailures, msgAlertWebhookAuthFailures, n, webhookAuthFailureWindow)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminAlertsDue(t *testing.T) {
+	a := adminAlerts{}
+	now := time.Now()
+	assert.True(t, a.due(alertJiraAuthExpired, now))
+	assert.False(t, a.due(alertJiraAuthExpired, now.Add(adminAlertMinInterval/2)))
+	assert.True(t, a.due(alertWebhookQueueFull, now))
+	assert.True(t, a.due(alertJiraAuthExpired, now.Add(adminAlertMinInterval)))
+}
+
+func TestAdminAlertsRecordAuthFailure(t *testing.T) {
+	a := adminAlerts{}
+	now := time.Now()
+	assert.Equal(t, 1, a.recordAuthFailure(now))
+	assert.Equal(t, 2, a.recordAuthFailure(now.Add(webhookAuthFailureWindow/2)))
+	assert.Equal(t, 1, a.recordAuthFailure(now.Add(webhookAuthFailureWindow)), "starts a new window")
+	assert.Equal(t, 2, a.recordAuthFailure(now.Add(webhookAuthFailureWindow*3/2)))
+	for i := 0; i < 10000; i++ {
+		a.recordAuthFailure(now.Add(webhookAuthFailureWindow * 3 / 2))
+	}
+	assert.Equal(t, 10003, a.recordAuthFailure(now.Add(webhookAuthFailureWindow*3/2)))
+}
+
+func TestRecordWebhookAuthFailure(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "alertsChannelId" && post.UserId == "botUserId"
+	})).Return(&model.Post{}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.botUserID = "botUserId"
+		conf.AdminAlertsChannelId = "alertsChannelId"
+	})
+
+	for i := 0; i < 2*webhookAuthFailureThreshold; i++ {
+		p.recordWebhookAuthFailure()
+	}
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
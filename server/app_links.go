@@ -0,0 +1,143 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// The open query parameter of the issue redirects: openInApp always
+	// redirects to the Jira app, openInAppOnMobile only from mobile devices.
+	openInApp         = "app"
+	openInAppOnMobile = "auto"
+)
+
+var reURLScheme = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// mobileUserAgents are the parts of the user agents of the browsers and the
+// Mattermost app on mobile devices.
+var mobileUserAgents = []string{"Android", "iPhone", "iPad", "Mobile"}
+
+// appLinkScheme returns the URL scheme of the Jira app links, or "" if they
+// are not configured.
+func (p *Plugin) appLinkScheme() string {
+	return normalizeURLScheme(p.getConfig().JiraAppLinkScheme)
+}
+
+func normalizeURLScheme(scheme string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scheme), "://"))
+}
+
+// appURL returns the link opening the issue in the Jira app, the browse URL
+// of the issue with the scheme of the app.
+func (ref issueRef) appURL(scheme string) string {
+	u, err := url.Parse(ref.browseURL())
+	if err != nil {
+		return ref.browseURL()
+	}
+	u.Scheme = scheme
+	return u.String()
+}
+
+func isMobileUserAgent(userAgent string) bool {
+	for _, s := range mobileUserAgents {
+		if strings.Contains(userAgent, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// issueRedirectTarget returns the URL /issue/<issue-key> redirects to: the
+// issue in the Jira app when requested with the open parameter and the app
+// links are configured, or else the issue in Jira.
+func (p *Plugin) issueRedirectTarget(ref issueRef, r *http.Request) string {
+	scheme := p.appLinkScheme()
+	open := r.URL.Query().Get("open")
+	if scheme != "" && (open == openInApp || open == openInAppOnMobile && isMobileUserAgent(r.UserAgent())) {
+		return ref.appURL(scheme)
+	}
+	return ref.browseURL()
+}
+
+// issueAppLink returns the plugin URL opening the issue in the Jira app, or
+// only from mobile devices if open is openInAppOnMobile.
+func (p *Plugin) issueAppLink(issueKey, channelId, open string) string {
+	query := url.Values{"open": {open}}
+	if channelId != "" {
+		query.Set("channel_id", channelId)
+	}
+	return p.issueDeepLink(issueKey) + "?" + query.Encode()
+}
+
+// addIssueAppLink titles the attachment of an issue post with the issue key,
+// linked to the issue in the Jira app on mobile devices, if the app links
+// are configured.
+func (p *Plugin) addIssueAppLink(attachment *model.SlackAttachment, issueKey, channelId string) {
+	if p.appLinkScheme() == "" || attachment.Title != "" {
+		return
+	}
+	attachment.Title = issueKey
+	attachment.TitleLink = p.issueAppLink(issueKey, channelId, openInAppOnMobile)
+}
+
+// openInAppAction returns the button of an issue post responding with the
+// link to the issue in the Jira app, or nil if the app links are not
+// configured. The buttons can't open links themselves.
+func (p *Plugin) openInAppAction(issueKey string, t localizer) *model.PostAction {
+	if p.appLinkScheme() == "" {
+		return nil
+	}
+	return &model.PostAction{
+		Id:   "openinapp",
+		Name: t(msgActionOpenInApp),
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPIOpenInAppAction,
+			Context: map[string]interface{}{
+				"issue_key": issueKey,
+			},
+		},
+	}
+}
+
+func httpAPIOpenInAppAction(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	issueKey, _ := request.Context["issue_key"].(string)
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("missing issue key")
+	}
+
+	response := model.PostActionIntegrationResponse{
+		EphemeralText: "The Jira app links are not enabled.",
+	}
+	if p.appLinkScheme() != "" {
+		response.EphemeralText = fmt.Sprintf("[Open %s in the Jira app](%s)",
+			issueKey, p.issueAppLink(issueKey, request.ChannelId, openInApp))
+	}
+
+	b, _ := json.Marshal(response)
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
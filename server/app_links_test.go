@@ -0,0 +1,94 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	desktopUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.130 Safari/537.36"
+	mobileUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"
+)
+
+func TestIssueRedirectTarget(t *testing.T) {
+	p := &Plugin{}
+	ref := issueRef{Key: "TES-1", InstanceURL: "https://jira.example.com"}
+	request := func(open, userAgent string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, routeIssueRedirect+"TES-1?open="+open, nil)
+		r.Header.Set("User-Agent", userAgent)
+		return r
+	}
+
+	assert.Equal(t, "https://jira.example.com/browse/TES-1", p.issueRedirectTarget(ref, request(openInApp, desktopUserAgent)))
+
+	p.updateConfig(func(conf *config) {
+		conf.JiraAppLinkScheme = " Jira:// "
+	})
+	for _, tc := range []struct {
+		open, userAgent, expected string
+	}{
+		{"", mobileUserAgent, "https://jira.example.com/browse/TES-1"},
+		{openInApp, desktopUserAgent, "jira://jira.example.com/browse/TES-1"},
+		{openInApp, mobileUserAgent, "jira://jira.example.com/browse/TES-1"},
+		{openInAppOnMobile, desktopUserAgent, "https://jira.example.com/browse/TES-1"},
+		{openInAppOnMobile, mobileUserAgent, "jira://jira.example.com/browse/TES-1"},
+	} {
+		assert.Equal(t, tc.expected, p.issueRedirectTarget(ref, request(tc.open, tc.userAgent)), tc.open)
+	}
+}
+
+func TestAddIssueAppLink(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("https://mm.example.com")}})
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	attachment := &model.SlackAttachment{}
+	p.addIssueAppLink(attachment, "TES-1", "channel1")
+	assert.Empty(t, attachment.Title)
+	assert.Len(t, p.issuePostActions("TES-1", englishLocalizer), 2)
+
+	p.updateConfig(func(conf *config) {
+		conf.JiraAppLinkScheme = "jira"
+	})
+	p.addIssueAppLink(attachment, "TES-1", "channel1")
+	assert.Equal(t, "TES-1", attachment.Title)
+	assert.Equal(t, "https://mm.example.com/plugins/jira/issue/TES-1?channel_id=channel1&open=auto", attachment.TitleLink)
+
+	actions := p.issuePostActions("TES-1", englishLocalizer)
+	require.Len(t, actions, 3)
+	assert.Equal(t, "Open in app", actions[2].Name)
+	assert.Equal(t, "TES-1", actions[2].Integration.Context["issue_key"])
+}
+
+func TestHTTPAPIOpenInAppAction(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("https://mm.example.com")}})
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.JiraAppLinkScheme = "jira"
+	})
+
+	request := &model.PostActionIntegrationRequest{
+		ChannelId: "channel1",
+		Context:   map[string]interface{}{"issue_key": "TES-1"},
+	}
+	w := httptest.NewRecorder()
+	status, err := httpAPIOpenInAppAction(p, w, httptest.NewRequest(http.MethodPost, routeAPIOpenInAppAction, bytes.NewReader(request.ToJson())))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	response := model.PostActionIntegrationResponseFromJson(w.Body)
+	require.NotNil(t, response)
+	assert.Equal(t, "[Open TES-1 in the Jira app](https://mm.example.com/plugins/jira/issue/TES-1?channel_id=channel1&open=app)", response.EphemeralText)
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	prefixAvatar = "avatar_"
+
+	// How long the avatars fetched from Jira are cached.
+	avatarExpirySeconds = 24 * 60 * 60
+
+	avatarFetchTimeout = 10 * time.Second
+
+	// Larger avatars are not proxied, since the KV store values are meant
+	// to be small.
+	avatarMaxSize = 256 * 1024
+)
+
+// cachedAvatar is an avatar image fetched from Jira.
+type cachedAvatar struct {
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// jiraAvatarURLFunc returns the function rewriting the avatar URLs of the
+// current Jira instance to be loaded through the plugin, or nil if the Jira
+// avatars are not proxied.
+func (p *Plugin) jiraAvatarURLFunc() func(string) string {
+	if !p.getConfig().ProxyJiraAvatars {
+		return nil
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil
+	}
+	instanceURL := ji.GetURL()
+	pluginURL := p.GetPluginURL()
+	return func(avatarURL string) string {
+		// The avatars hosted outside of the Jira instance, like the Jira
+		// Cloud and Gravatar avatars, are loaded as is.
+		if !isJiraURL(instanceURL, avatarURL) {
+			return avatarURL
+		}
+		v := url.Values{}
+		v.Add("url", avatarURL)
+		return pluginURL + routeAvatar + "?" + v.Encode()
+	}
+}
+
+// isJiraURL returns true if u is a URL of the Jira instance.
+func isJiraURL(instanceURL, u string) bool {
+	instanceURL = strings.TrimRight(instanceURL, "/")
+	return instanceURL != "" && (u == instanceURL || strings.HasPrefix(u, instanceURL+"/"))
+}
+
+// httpAvatar serves an avatar of the current Jira instance to the Mattermost
+// users, for instance when Jira is only reachable from the Mattermost server.
+func httpAvatar(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET")
+	}
+	if !p.getConfig().ProxyJiraAvatars {
+		return http.StatusNotFound, errors.New("the Jira avatars are not proxied")
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+	avatarURL := r.URL.Query().Get("url")
+	if !isJiraURL(ji.GetURL(), avatarURL) {
+		return http.StatusBadRequest, errors.Errorf("%q is not a URL of Jira instance %s", avatarURL, ji.GetURL())
+	}
+
+	avatar, err := p.loadAvatar(ji.GetURL(), avatarURL)
+	if err != nil {
+		return http.StatusBadGateway, err
+	}
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	// SVG avatars can't run scripts on the Mattermost origin
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(avatarExpirySeconds))
+	_, err = w.Write(avatar.Data)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// loadAvatar returns the avatar from the cache, or fetches it from Jira.
+func (p *Plugin) loadAvatar(instanceURL, avatarURL string) (*cachedAvatar, error) {
+	key := hashkey(prefixAvatar, avatarURL)
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) != 0 {
+		avatar := &cachedAvatar{}
+		err := json.Unmarshal(data, avatar)
+		if err == nil {
+			return avatar, nil
+		}
+	}
+
+	avatar, err := fetchAvatar(instanceURL, avatarURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(avatar)
+	if err != nil {
+		return nil, err
+	}
+	appErr = p.API.KVSetWithExpiry(key, data, avatarExpirySeconds)
+	if appErr != nil {
+		p.errorf("loadAvatar: failed to cache avatar %s: %v", avatarURL, appErr)
+	}
+	return avatar, nil
+}
+
+// fetchAvatar fetches an avatar from Jira, where the avatars of users and
+// projects can be viewed anonymously. Redirects outside of the Jira instance
+// are not followed.
+func fetchAvatar(instanceURL, avatarURL string) (*cachedAvatar, error) {
+	client := &http.Client{
+		Timeout: avatarFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !isJiraURL(instanceURL, req.URL.String()) {
+				return errors.Errorf("redirected outside of Jira instance %s", instanceURL)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(avatarURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch avatar %s: unexpected response status %s", avatarURL, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, errors.Errorf("failed to fetch avatar %s: unexpected content type %q", avatarURL, contentType)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, avatarMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > avatarMaxSize {
+		return nil, errors.Errorf("failed to fetch avatar %s: larger than %d bytes", avatarURL, avatarMaxSize)
+	}
+	return &cachedAvatar{ContentType: contentType, Data: data}, nil
+}
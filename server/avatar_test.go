@@ -0,0 +1,97 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type avatarTestInstance struct {
+	jiraTestInstance
+	url string
+}
+
+func (ji avatarTestInstance) GetURL() string {
+	return ji.url
+}
+
+type avatarTestInstanceStore struct {
+	mockCurrentInstanceStore
+	url string
+}
+
+func (store avatarTestInstanceStore) LoadCurrentJIRAInstance() (Instance, error) {
+	return &avatarTestInstance{url: store.url}, nil
+}
+
+func TestHTTPAvatar(t *testing.T) {
+	fetched := 0
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/secure/useravatar":
+			fetched++
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		case "/redirect":
+			http.Redirect(w, r, "https://www.gravatar.com/avatar/1", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer jiraServer.Close()
+
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(avatarExpirySeconds)).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("https://mm.example.com")}})
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = avatarTestInstanceStore{url: jiraServer.URL}
+
+	assert.Nil(t, p.jiraAvatarURLFunc(), "the avatars are not proxied by default")
+	p.updateConfig(func(conf *config) {
+		conf.ProxyJiraAvatars = true
+	})
+	avatarURL := p.jiraAvatarURLFunc()
+	require.NotNil(t, avatarURL)
+	assert.Equal(t, "https://www.gravatar.com/avatar/1", avatarURL("https://www.gravatar.com/avatar/1"))
+	proxied, err := url.Parse(avatarURL(jiraServer.URL + "/secure/useravatar?avatarId=10122"))
+	require.NoError(t, err)
+	assert.Equal(t, "/plugins/jira/avatar", proxied.Path)
+
+	request := func(avatarURL string) (int, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, routeAvatar+"?"+url.Values{"url": {avatarURL}}.Encode(), nil)
+		r.Header.Set("Mattermost-User-Id", "userId")
+		status, _ := httpAvatar(p, w, r)
+		return status, w
+	}
+
+	for i := 0; i < 2; i++ {
+		status, w := request(proxied.Query().Get("url"))
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "png", w.Body.String())
+	}
+	assert.Equal(t, 1, fetched, "the avatar is cached")
+
+	status, _ := request("https://www.gravatar.com/avatar/1")
+	assert.Equal(t, http.StatusBadRequest, status, "only the avatars of the Jira instance are proxied")
+	status, _ = request(jiraServer.URL + "/redirect")
+	assert.Equal(t, http.StatusBadGateway, status, "redirects outside of Jira are not followed")
+	status, _ = request(jiraServer.URL + "/missing")
+	assert.Equal(t, http.StatusBadGateway, status)
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const keyChannelProject = "channel_project_"
+
+// starterSubscriptionEvents are the events of the subscription created when a
+// channel is linked to a project.
+var starterSubscriptionEvents = NewStringSet(
+	eventCreated,
+	eventUpdatedAssignee,
+	eventUpdatedResolved,
+	eventUpdatedReopened,
+)
+
+// ChannelProject is the Jira project a channel is linked to. The project is
+// the default of the create issue dialog and of the reports of the channel.
+type ChannelProject struct {
+	ProjectKey string `json:"project_key"`
+	LinkedBy   string `json:"linked_by"`
+
+	// SubscriptionId is the starter subscription created with the link, and
+	// removed with it.
+	SubscriptionId string `json:"subscription_id,omitempty"`
+}
+
+func (p *Plugin) loadChannelProject(ji Instance, channelId string) (*ChannelProject, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyChannelProject+channelId))
+	if appErr != nil {
+		return nil, appErr
+	}
+	link := ChannelProject{}
+	if len(data) == 0 {
+		return &link, nil
+	}
+	err := json.Unmarshal(data, &link)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load the Jira project of channel "+channelId)
+	}
+	return &link, nil
+}
+
+func (p *Plugin) storeChannelProject(ji Instance, channelId string, link *ChannelProject) error {
+	key := keyWithInstance(ji, keyChannelProject+channelId)
+	if link == nil {
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+	data, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSet(key, data)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// linkedProjectJQL is the default JQL query of the reports of a channel linked
+// to a project.
+func linkedProjectJQL(projectKey string) string {
+	return fmt.Sprintf("project = %s AND resolution = Unresolved ORDER BY updated DESC", projectKey)
+}
+
+// starterSubscription is the subscription of a channel to the issues of all
+// types of a project.
+func starterSubscription(channelId string, project *jira.Project, userId string) *ChannelSubscription {
+	issueTypes := NewStringSet()
+	issueTypeNames := map[string]string{}
+	for _, issueType := range project.IssueTypes {
+		issueTypes = issueTypes.Add(issueType.ID)
+		issueTypeNames[issueType.ID] = issueType.Name
+	}
+	return &ChannelSubscription{
+		ChannelId: channelId,
+		Name:      project.Key + " issues",
+		CreatorId: userId,
+		Filters: SubscriptionFilters{
+			Events:         starterSubscriptionEvents,
+			Projects:       NewStringSet(project.Key),
+			IssueTypes:     issueTypes,
+			IssueTypeNames: issueTypeNames,
+			Fields:         []FieldFilter{},
+		},
+	}
+}
+
+func executeLinkProject(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira link-project <project-key>`, or `/jira link-project clear`."
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeLinkProject: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	link, err := p.loadChannelProject(ji, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	if len(args) == 0 {
+		if link.ProjectKey == "" {
+			return p.responsef(header, "This channel is not linked to a Jira project. "+usage)
+		}
+		return p.responsef(header, "This channel is linked to Jira project **%s**.", link.ProjectKey)
+	}
+	if len(args) != 1 {
+		return p.responsef(header, usage)
+	}
+
+	err = p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to link this channel to a Jira project: %v", err)
+	}
+
+	if args[0] == "clear" {
+		if link.ProjectKey == "" {
+			return p.responsef(header, "This channel is not linked to a Jira project.")
+		}
+		if link.SubscriptionId != "" {
+			err = p.removeChannelSubscription(link.SubscriptionId)
+			if err != nil {
+				p.errorf("executeLinkProject: failed to remove starter subscription %s: %v", link.SubscriptionId, err)
+			}
+		}
+		err = p.storeChannelProject(ji, header.ChannelId, nil)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		return p.responsef(header, "Unlinked this channel from Jira project **%s**.", link.ProjectKey)
+	}
+
+	if link.ProjectKey != "" {
+		return p.responsef(header, "This channel is already linked to Jira project **%s**, run `/jira link-project clear` first.", link.ProjectKey)
+	}
+
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	projectKey := strings.ToUpper(args[0])
+	project, err := client.GetProject(projectKey)
+	if err != nil {
+		return p.responsef(header, "Failed to get Jira project %s: %v", projectKey, err)
+	}
+
+	link = &ChannelProject{ProjectKey: project.Key, LinkedBy: header.UserId}
+	lines := []string{
+		fmt.Sprintf("Linked this channel to Jira project **%s**:", project.Key),
+		"* New issues created in this channel default to the project, unless `/jira create defaults` sets another one.",
+		fmt.Sprintf("* `/jira report add <name> <schedule>` reports the unresolved issues of the project: `%s`.", linkedProjectJQL(project.Key)),
+	}
+
+	sub := starterSubscription(header.ChannelId, project, header.UserId)
+	err = p.addChannelSubscription(sub, client)
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("* Failed to subscribe the channel to the project: %v", err))
+	} else {
+		link.SubscriptionId = sub.Id
+		lines = append(lines, fmt.Sprintf("* Subscription %q posts the issues created, assigned, resolved and reopened in the project. Edit it with `/jira subscribe`.", sub.Name))
+	}
+
+	// The issue keys of Jira Cloud instances are already linked in all
+	// channels.
+	if _, isCloud := ji.(*jiraCloudInstance); !isCloud {
+		err = p.addChannelAutolinks(project.Key, ji.GetURL(), header.TeamId, header.ChannelId)
+		if err != nil {
+			p.errorf("executeLinkProject: failed to add the autolinks of project %s: %v", project.Key, err)
+			lines = append(lines, "* The issue keys of the project are not linked in this channel, since the Autolink plugin is not available.")
+		} else {
+			lines = append(lines, fmt.Sprintf("* The issue keys of the project, like `%s-123`, link to Jira in this channel.", project.Key))
+		}
+	}
+
+	err = p.storeChannelProject(ji, header.ChannelId, link)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	return p.responsef(header, "%s", strings.Join(lines, "\n"))
+}
+
+// addChannelAutolinks adds the autolinks of a project, limited to a channel.
+func (p *Plugin) addChannelAutolinks(projectKey, baseURL, teamId, channelId string) error {
+	team, appErr := p.API.GetTeam(teamId)
+	if appErr != nil {
+		return appErr
+	}
+	channel, appErr := p.API.GetChannel(channelId)
+	if appErr != nil {
+		return appErr
+	}
+	return p.AddAutolinks(projectKey, baseURL, team.Name+"/"+channel.Name)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelProject(t *testing.T) {
+	api := &plugintest.API{}
+	p := Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{&p}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+
+	kv := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte { return kv[key] },
+		func(key string) *model.AppError { return nil })
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	})
+
+	link, err := p.loadChannelProject(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, &ChannelProject{}, link)
+
+	expected := &ChannelProject{ProjectKey: "TES", LinkedBy: "userId", SubscriptionId: "subId"}
+	require.NoError(t, p.storeChannelProject(ji, "channelId", expected))
+	link, err = p.loadChannelProject(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, expected, link)
+
+	t.Run("create defaults", func(t *testing.T) {
+		defaults, err := p.loadCreateDefaults(ji, "channelId")
+		require.NoError(t, err)
+		assert.Equal(t, &CreateDefaults{ProjectKey: "TES"}, defaults)
+
+		stored := &CreateDefaults{ProjectKey: "OTHER", IssueTypeId: "10001", IssueTypeName: "Bug"}
+		require.NoError(t, p.storeCreateDefaults(ji, "channelId", stored))
+		defaults, err = p.loadCreateDefaults(ji, "channelId")
+		require.NoError(t, err)
+		assert.Equal(t, stored, defaults)
+		require.NoError(t, p.storeCreateDefaults(ji, "channelId", nil))
+	})
+
+	require.NoError(t, p.storeChannelProject(ji, "channelId", nil))
+	link, err = p.loadChannelProject(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, &ChannelProject{}, link)
+}
+
+func TestStarterSubscription(t *testing.T) {
+	sub := starterSubscription("channelId", &jira.Project{
+		Key:        "TES",
+		IssueTypes: []jira.IssueType{{ID: "10001", Name: "Bug"}, {ID: "10002", Name: "Story"}},
+	}, "userId")
+
+	assert.Equal(t, "TES issues", sub.Name)
+	assert.Equal(t, "channelId", sub.ChannelId)
+	assert.Equal(t, "userId", sub.CreatorId)
+	assert.Equal(t, NewStringSet("TES"), sub.Filters.Projects)
+	assert.Equal(t, NewStringSet("10001", "10002"), sub.Filters.IssueTypes)
+	assert.True(t, sub.Filters.Events.ContainsAny(eventCreated))
+	assert.Equal(t, "project = TES AND resolution = Unresolved ORDER BY updated DESC", linkedProjectJQL("TES"))
+}
@@ -0,0 +1,476 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyChannelStatus = "channel_status"
+
+	channelStatusHeader = "header"
+	channelStatusPinned = "pinned"
+	channelStatusOff    = "off"
+
+	channelStatusRefreshInterval = 15 * time.Minute
+
+	// Webhook events refresh a channel's status at most this often.
+	channelStatusMinRefreshInterval = time.Minute
+)
+
+// channelStatus is the configuration of the open issues indicator of a
+// channel, and the state needed to update it.
+type channelStatus struct {
+	Mode string `json:"mode"`
+
+	// CreatorId is the Mattermost user who enabled the indicator. Jira is
+	// queried with this user's credentials.
+	CreatorId string `json:"creator_id"`
+
+	// Segment is the text last added to the channel header.
+	Segment string `json:"segment,omitempty"`
+
+	// PostId is the pinned post.
+	PostId string `json:"post_id,omitempty"`
+}
+
+// channelStatusRefreshes records when channel statuses were last refreshed by
+// webhook events, and the channels waiting for their refresh. The refreshes
+// query Jira, so they run in a single goroutine rather than in the webhook
+// workers.
+type channelStatusRefreshes struct {
+	lock    sync.Mutex
+	last    map[string]time.Time
+	pending StringSet
+	running bool
+}
+
+// due returns true, and records the refresh, if the channel's status was not
+// refreshed within channelStatusMinRefreshInterval.
+func (r *channelStatusRefreshes) due(channelId string, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.last == nil {
+		r.last = map[string]time.Time{}
+	}
+	if now.Sub(r.last[channelId]) < channelStatusMinRefreshInterval {
+		return false
+	}
+	r.last[channelId] = now
+	return true
+}
+
+// queue adds the channels due for a refresh to the pending ones, and returns
+// true if the goroutine refreshing them needs to be started.
+func (r *channelStatusRefreshes) queue(channelIds []string, now time.Time) bool {
+	queued := []string{}
+	for _, channelId := range channelIds {
+		if r.due(channelId, now) {
+			queued = append(queued, channelId)
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.pending = r.pending.Add(queued...)
+	if r.running || r.pending.Len() == 0 {
+		return false
+	}
+	r.running = true
+	return true
+}
+
+// next returns the pending channels, and marks the refreshing goroutine as
+// stopped when there are none.
+func (r *channelStatusRefreshes) next() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	channelIds := r.pending.Elems()
+	r.pending = nil
+	if len(channelIds) == 0 {
+		r.running = false
+	}
+	return channelIds
+}
+
+func executeSubscribeStatus(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please use `/jira subscribe status <header|pinned|off>`.")
+	}
+	mode := strings.ToLower(args[0])
+	switch mode {
+	case channelStatusHeader, channelStatusPinned, channelStatusOff:
+	default:
+		return p.responsef(header, "%q is not a valid option. Please use `header`, `pinned` or `off`.", args[0])
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You don't have permission to manage the subscriptions of this channel.")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeStatus: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	if mode != channelStatusOff {
+		_, err = p.userStore.LoadJIRAUser(ji, header.UserId)
+		if err != nil {
+			return p.responseT(header, msgNotConnected)
+		}
+	}
+
+	previous, err := p.setChannelStatus(ji, header.ChannelId, nil)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if previous != nil {
+		err = p.clearChannelStatus(header.ChannelId, previous)
+		if err != nil {
+			return p.responsef(header, "Failed to remove the open issues indicator: %v", err)
+		}
+	}
+	if mode == channelStatusOff {
+		return p.responsef(header, "The open issues indicator was removed from this channel.")
+	}
+
+	status := &channelStatus{
+		Mode:      mode,
+		CreatorId: header.UserId,
+	}
+	err = p.refreshChannelStatus(ji, header.ChannelId, status)
+	if err != nil {
+		return p.responsef(header, "Failed to add the open issues indicator: %v", err)
+	}
+	_, err = p.setChannelStatus(ji, header.ChannelId, status)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	where := "channel header"
+	if mode == channelStatusPinned {
+		where = "pinned post"
+	}
+	return p.responsef(header, "The %s now shows the number of open issues matching this channel's subscriptions. "+
+		"It is updated on Jira events, and every %v.", where, channelStatusRefreshInterval)
+}
+
+func (p *Plugin) loadChannelStatuses(ji Instance) (map[string]*channelStatus, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyChannelStatus))
+	if appErr != nil {
+		return nil, appErr
+	}
+	statuses := map[string]*channelStatus{}
+	if data == nil {
+		return statuses, nil
+	}
+	err := json.Unmarshal(data, &statuses)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// setChannelStatus stores the status of a channel, or removes it if status is
+// nil, and returns the previous one.
+func (p *Plugin) setChannelStatus(ji Instance, channelId string, status *channelStatus) (*channelStatus, error) {
+	var previous *channelStatus
+	err := p.atomicModify(keyWithInstance(ji, keyChannelStatus), func(initialBytes []byte) ([]byte, error) {
+		statuses := map[string]*channelStatus{}
+		if initialBytes != nil {
+			err := json.Unmarshal(initialBytes, &statuses)
+			if err != nil {
+				return nil, err
+			}
+		}
+		previous = statuses[channelId]
+		if status == nil {
+			delete(statuses, channelId)
+		} else {
+			statuses[channelId] = status
+		}
+		return json.Marshal(statuses)
+	})
+	return previous, err
+}
+
+// refreshChannelStatusesForWebhook queues the refresh of the statuses of the
+// channels an event was posted to, without waiting for it.
+func (p *Plugin) refreshChannelStatusesForWebhook(channelIds StringSet) {
+	if channelIds.Len() == 0 {
+		return
+	}
+	if p.channelStatusRefreshes.queue(channelIds.Elems(), time.Now()) {
+		go p.runChannelStatusRefreshes()
+	}
+}
+
+// runChannelStatusRefreshes refreshes the queued channel statuses until there
+// are none left.
+func (p *Plugin) runChannelStatusRefreshes() {
+	for {
+		channelIds := p.channelStatusRefreshes.next()
+		if len(channelIds) == 0 {
+			return
+		}
+		ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+		if err != nil {
+			continue
+		}
+		statuses, err := p.loadChannelStatuses(ji)
+		if err != nil {
+			p.errorf("runChannelStatusRefreshes: failed to load channel statuses: %v", err)
+			continue
+		}
+		for _, channelId := range channelIds {
+			if status := statuses[channelId]; status != nil {
+				p.refreshAndStoreChannelStatus(ji, channelId, status)
+			}
+		}
+	}
+}
+
+func (p *Plugin) refreshAllChannelStatuses() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	statuses, err := p.loadChannelStatuses(ji)
+	if err != nil {
+		p.errorf("refreshAllChannelStatuses: failed to load channel statuses: %v", err)
+		return
+	}
+	for channelId, status := range statuses {
+		p.refreshAndStoreChannelStatus(ji, channelId, status)
+	}
+}
+
+func (p *Plugin) refreshAndStoreChannelStatus(ji Instance, channelId string, status *channelStatus) {
+	segment, postId := status.Segment, status.PostId
+	err := p.refreshChannelStatus(ji, channelId, status)
+	if err != nil {
+		p.errorf("failed to refresh the open issues indicator of channel %s: %v", channelId, err)
+		return
+	}
+	if status.Segment == segment && status.PostId == postId {
+		return
+	}
+	_, err = p.setChannelStatus(ji, channelId, status)
+	if err != nil {
+		p.errorf("failed to store the open issues indicator of channel %s: %v", channelId, err)
+	}
+}
+
+// refreshChannelStatus counts the open issues matching the channel's
+// subscriptions, and updates the channel header or the pinned post.
+func (p *Plugin) refreshChannelStatus(ji Instance, channelId string, status *channelStatus) error {
+	subs, err := p.getSubscriptionsForChannel(channelId)
+	if err != nil {
+		return err
+	}
+	jql := channelOpenIssuesJQL(subs)
+
+	text := "Jira: no subscriptions"
+	if jql != "" {
+		jiraUser, err := p.userStore.LoadJIRAUser(ji, status.CreatorId)
+		if err != nil {
+			return errors.WithMessage(err, "the user who added the indicator is not connected to Jira")
+		}
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return err
+		}
+		total, err := countJQLIssues(client, jql)
+		if err != nil {
+			return errors.WithMessage(err, "failed to count the open issues")
+		}
+		id := msgChannelStatusOpenIssues
+		if total == 1 {
+			id = msgChannelStatusOpenIssue
+		}
+		text = p.localize(p.channelLocale(channelId), id, total, ji.GetURL()+"/issues/?jql="+url.QueryEscape(jql))
+	}
+
+	switch status.Mode {
+	case channelStatusHeader:
+		return p.updateChannelHeaderStatus(channelId, status, text)
+	case channelStatusPinned:
+		return p.updatePinnedStatus(channelId, status, text)
+	}
+	return nil
+}
+
+func (p *Plugin) updateChannelHeaderStatus(channelId string, status *channelStatus, segment string) error {
+	if segment == status.Segment {
+		return nil
+	}
+	channel, appErr := p.API.GetChannel(channelId)
+	if appErr != nil {
+		return appErr
+	}
+	header := replaceHeaderSegment(channel.Header, status.Segment, segment)
+	if len([]rune(header)) > model.CHANNEL_HEADER_MAX_RUNES {
+		return errors.New("the channel header is too long to add the indicator")
+	}
+	channel.Header = header
+	_, appErr = p.API.UpdateChannel(channel)
+	if appErr != nil {
+		return appErr
+	}
+	status.Segment = segment
+	return nil
+}
+
+func (p *Plugin) updatePinnedStatus(channelId string, status *channelStatus, message string) error {
+	if status.PostId != "" {
+		post, appErr := p.API.GetPost(status.PostId)
+		if appErr == nil && post.DeleteAt == 0 {
+			if post.Message == message {
+				return nil
+			}
+			post.Message = message
+			_, appErr = p.API.UpdatePost(post)
+			return appErr
+		}
+	}
+
+	// The pinned post was deleted, or is yet to be created.
+	post, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelId,
+		Message:   message,
+		IsPinned:  true,
+	})
+	if appErr != nil {
+		return appErr
+	}
+	status.PostId = post.Id
+	return nil
+}
+
+// clearChannelStatus removes the indicator from the channel header, or deletes
+// the pinned post.
+func (p *Plugin) clearChannelStatus(channelId string, status *channelStatus) error {
+	switch status.Mode {
+	case channelStatusHeader:
+		if status.Segment == "" {
+			return nil
+		}
+		channel, appErr := p.API.GetChannel(channelId)
+		if appErr != nil {
+			return appErr
+		}
+		channel.Header = replaceHeaderSegment(channel.Header, status.Segment, "")
+		_, appErr = p.API.UpdateChannel(channel)
+		if appErr != nil {
+			return appErr
+		}
+	case channelStatusPinned:
+		if status.PostId == "" {
+			return nil
+		}
+		appErr := p.API.DeletePost(status.PostId)
+		if appErr != nil && appErr.StatusCode != 404 {
+			return appErr
+		}
+	}
+	return nil
+}
+
+const channelHeaderSeparator = " | "
+
+// replaceHeaderSegment replaces the previous segment of the header with the
+// new one, appending it if the previous one is no longer in the header. An
+// empty segment removes the previous one.
+func replaceHeaderSegment(header, previous, segment string) string {
+	if previous != "" && strings.Contains(header, previous) {
+		if segment != "" {
+			return strings.Replace(header, previous, segment, 1)
+		}
+		header = strings.Replace(header, channelHeaderSeparator+previous, "", 1)
+		header = strings.Replace(header, previous+channelHeaderSeparator, "", 1)
+		return strings.TrimSpace(strings.Replace(header, previous, "", 1))
+	}
+	if segment == "" {
+		return header
+	}
+	if strings.TrimSpace(header) == "" {
+		return segment
+	}
+	return header + channelHeaderSeparator + segment
+}
+
+// channelOpenIssuesJQL returns a JQL query for the unresolved issues matching
+// the subscriptions, by project and issue type, filter or issue key. Field
+// filters are not included, so the count may be higher than the issues
+// actually posted to the channel.
+func channelOpenIssuesJQL(subs []ChannelSubscription) string {
+	clauses := []string{}
+	for _, sub := range subs {
+		if clause := subscriptionIssuesClause(sub); clause != "" {
+			clauses = append(clauses, clause)
+		}
+	}
+	if len(clauses) == 0 {
+		return ""
+	}
+	sort.Strings(clauses)
+	return "((" + strings.Join(clauses, ") OR (") + ")) AND statusCategory != Done"
+}
+
+// subscriptionIssuesClause returns the JQL clause matching the issues of a
+// subscription, or "" for the subscriptions to project events.
+func subscriptionIssuesClause(sub ChannelSubscription) string {
+	switch {
+	case sub.ProjectEvents, sub.ProjectDeleted:
+		return ""
+	case sub.FilterId != "":
+		return "filter = " + sub.FilterId
+	case sub.IssueKey != "":
+		return "issuekey = " + sub.IssueKey
+	case sub.Filters.Projects.Len() > 0:
+		clause := "project in (" + jqlList(sub.Filters.Projects) + ")"
+		if sub.Filters.IssueTypes.Len() > 0 {
+			clause += " AND issuetype in (" + jqlList(sub.Filters.IssueTypes) + ")"
+		}
+		if sub.Filters.ParentKeys.Len() > 0 {
+			// Only the issues of epics set as their parent, as on Jira
+			// Cloud, are counted; Epic Link is not available everywhere.
+			keys := jqlList(sub.Filters.ParentKeys)
+			clause += " AND (issuekey in (" + keys + ") OR parent in (" + keys + "))"
+		}
+		if sub.Filters.ExcludeSubtasks {
+			clause += " AND issuetype not in subTaskIssueTypes()"
+		}
+		if sub.Filters.JQL != "" {
+			clause += " AND (" + sub.Filters.JQL + ")"
+		}
+		return clause
+	}
+	return ""
+}
+
+var jqlStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// jqlList returns the values as a list of JQL strings, with their
+// backslashes and quotes escaped.
+func jqlList(values StringSet) string {
+	quoted := []string{}
+	for _, v := range values.Elems() {
+		quoted = append(quoted, `"`+jqlStringEscaper.Replace(v)+`"`)
+	}
+	sort.Strings(quoted)
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChannelOpenIssuesJQL(t *testing.T) {
+	assert.Equal(t, "", channelOpenIssuesJQL(nil))
+	assert.Equal(t, "", channelOpenIssuesJQL([]ChannelSubscription{{ProjectEvents: true}}))
+
+	jql := channelOpenIssuesJQL([]ChannelSubscription{
+		{Filters: SubscriptionFilters{Projects: NewStringSet("TES"), IssueTypes: NewStringSet("10002", "10001")}},
+		{FilterId: "10100"},
+		{IssueKey: "OTHER-1"},
+		{Filters: SubscriptionFilters{Projects: NewStringSet("GONE")}, ProjectDeleted: true},
+	})
+	assert.Equal(t, `((filter = 10100) OR (issuekey = OTHER-1) OR (project in ("TES") AND issuetype in ("10001", "10002"))) AND statusCategory != Done`, jql)
+
+	jql = channelOpenIssuesJQL([]ChannelSubscription{
+		{Filters: SubscriptionFilters{Projects: NewStringSet("TES"), ParentKeys: NewStringSet("TES-1"), ExcludeSubtasks: true}},
+	})
+	assert.Equal(t, `((project in ("TES") AND (issuekey in ("TES-1") OR parent in ("TES-1")) AND issuetype not in subTaskIssueTypes())) AND statusCategory != Done`, jql)
+
+	jql = channelOpenIssuesJQL([]ChannelSubscription{
+		{Filters: SubscriptionFilters{Projects: NewStringSet("TES"), JQL: "labels = release"}},
+		{Filters: SubscriptionFilters{Projects: NewStringSet(`TE"S`, `TE\S`, `TE\"S`)}},
+	})
+	assert.Equal(t, `((project in ("TES") AND (labels = release)) OR (project in ("TE\"S", "TE\\S", "TE\\\"S"))) AND statusCategory != Done`, jql)
+}
+
+func TestReplaceHeaderSegment(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header, previous, segment, expected string
+	}{
+		"empty header":       {"", "", "Jira: 1", "Jira: 1"},
+		"append":             {"Team channel", "", "Jira: 1", "Team channel | Jira: 1"},
+		"replace":            {"Team channel | Jira: 1", "Jira: 1", "Jira: 2", "Team channel | Jira: 2"},
+		"edited away":        {"New header", "Jira: 1", "Jira: 2", "New header | Jira: 2"},
+		"remove last":        {"Team channel | Jira: 1", "Jira: 1", "", "Team channel"},
+		"remove first":       {"Jira: 1 | Team channel", "Jira: 1", "", "Team channel"},
+		"remove only":        {"Jira: 1", "Jira: 1", "", ""},
+		"remove edited away": {"New header", "Jira: 1", "", "New header"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, replaceHeaderSegment(tc.header, tc.previous, tc.segment))
+		})
+	}
+}
+
+func TestChannelStatusRefreshesDue(t *testing.T) {
+	r := channelStatusRefreshes{}
+	now := time.Now()
+	assert.True(t, r.due("channel1", now))
+	assert.False(t, r.due("channel1", now.Add(channelStatusMinRefreshInterval/2)))
+	assert.True(t, r.due("channel2", now))
+	assert.True(t, r.due("channel1", now.Add(channelStatusMinRefreshInterval)))
+}
+
+func TestChannelStatusRefreshesQueue(t *testing.T) {
+	r := &channelStatusRefreshes{}
+	now := time.Now()
+	assert.True(t, r.queue([]string{"channel1", "channel2"}, now), "starts the refreshes")
+	assert.False(t, r.queue([]string{"channel3"}, now), "already running")
+	assert.False(t, r.queue([]string{"channel1"}, now), "not due")
+
+	channelIds := r.next()
+	sort.Strings(channelIds)
+	assert.Equal(t, []string{"channel1", "channel2", "channel3"}, channelIds)
+	assert.Empty(t, r.next())
+	assert.False(t, r.running)
+
+	assert.False(t, r.queue([]string{"channel2"}, now), "nothing due")
+	assert.True(t, r.queue([]string{"channel4"}, now), "restarts the refreshes")
+}
+
+func TestExecuteSubscribeStatusNotConnected(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", "userid").Return(&model.User{Id: "userid"}, nil)
+	var message string
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(nil)
+	p := &Plugin{userStore: getMockUserStoreKV()}
+	p.updateConfig(func(conf *config) {
+		conf.RolesAllowedToEditJiraSubscriptions = "users"
+	})
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	// The indicator is left in place, the KV store is not mocked
+	executeSubscribeStatus(p, nil, &model.CommandArgs{UserId: "userid", ChannelId: "channel1"}, "header")
+	assert.Equal(t, defaultMessages[msgNotConnected], message)
+}
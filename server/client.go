@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -31,6 +32,7 @@ type Client interface {
 	ProjectService
 	SearchService
 	UserService
+	WebhookService
 }
 
 // RESTService is the low-level interface for invoking the upstream service.
@@ -53,10 +55,18 @@ type ProjectService interface {
 	GetAllProjectKeys() ([]string, error)
 }
 
+// WebhookService is the interface for the webhooks registered in Jira.
+type WebhookService interface {
+	GetWebhooks() ([]RegisteredWebhook, error)
+	RegisterWebhook(webhook *RegisteredWebhook) (*RegisteredWebhook, error)
+}
+
 // SearchService is the interface for search-related APIs.
 type SearchService interface {
 	SearchIssues(jql string, options *jira.SearchOptions) ([]jira.Issue, error)
 	SearchUsersAssignableToIssue(issueKey, query string, maxResults int) ([]jira.User, error)
+	GetFilter(filterId string) (*jira.Filter, error)
+	ValidateJQL(jql string) ([]JQLError, error)
 }
 
 // IssueService is the interface for issue-related APIs.
@@ -66,11 +76,17 @@ type IssueService interface {
 
 	AddAttachment(api plugin.API, issueKey, fileID string, maxSize utils.ByteSize) (mattermostName, jiraName string, err error)
 	AddComment(issueKey string, comment *jira.Comment) (*jira.Comment, error)
+	AddRemoteLink(issueKey string, link *RemoteLink) error
+	AddWorklog(issueKey string, record *jira.WorklogRecord) (*jira.WorklogRecord, error)
+	AddWatcher(issueKey string, user *jira.User) error
+	RemoveWatcher(issueKey string, user *jira.User) error
 	DoTransition(issueKey, transitionID string) error
 	GetCreateMeta(*jira.GetQueryOptions) (*jira.CreateMetaInfo, error)
+	GetDevelopmentSummary(issueID string) (*DevelopmentSummary, error)
 	GetTransitions(issueKey string) ([]jira.Transition, error)
 	UpdateAssignee(issueKey string, user *jira.User) error
 	UpdateComment(issueKey string, comment *jira.Comment) (*jira.Comment, error)
+	UpdateIssue(issueKey string, data map[string]interface{}) error
 }
 
 // JiraClient is the common implementation of most Jira APIs, except those that are
@@ -161,6 +177,47 @@ func (client JiraClient) RESTPostAttachment(issueID string, data []byte, name st
 	return attachments[0], nil
 }
 
+// RegisteredWebhook is a webhook of the webhooks API of Jira, which only Jira
+// administrators can register. Filters has the JQL query of the issue
+// events in "issue-related-events-section".
+type RegisteredWebhook struct {
+	Self        string            `json:"self,omitempty"`
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Events      []string          `json:"events"`
+	Filters     map[string]string `json:"filters,omitempty"`
+	ExcludeBody bool              `json:"excludeBody"`
+}
+
+// GetWebhooks returns the webhooks registered in Jira.
+func (client JiraClient) GetWebhooks() ([]RegisteredWebhook, error) {
+	req, err := client.Jira.NewRequest("GET", "/rest/webhooks/1.0/webhook", nil)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := []RegisteredWebhook{}
+	resp, err := client.Jira.Do(req, &webhooks)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	return webhooks, nil
+}
+
+// RegisterWebhook registers a webhook in Jira, and returns it with its Self
+// URL.
+func (client JiraClient) RegisterWebhook(webhook *RegisteredWebhook) (*RegisteredWebhook, error) {
+	req, err := client.Jira.NewRequest("POST", "/rest/webhooks/1.0/webhook", webhook)
+	if err != nil {
+		return nil, err
+	}
+	registered := &RegisteredWebhook{}
+	resp, err := client.Jira.Do(req, registered)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	return registered, nil
+}
+
 func (client JiraClient) GetAllProjectKeys() ([]string, error) {
 	projectlist, resp, err := client.Jira.Project.GetList()
 	if err != nil {
@@ -220,6 +277,16 @@ func (client JiraClient) UpdateAssignee(issueKey string, user *jira.User) error
 	return err
 }
 
+// UpdateIssue edits the fields of an issue, data being the body of the
+// request, with "fields" or "update" operations.
+func (client JiraClient) UpdateIssue(issueKey string, data map[string]interface{}) error {
+	resp, err := client.Jira.Issue.UpdateIssue(issueKey, data)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
 // AddComment adds a comment to an issue.
 func (client JiraClient) AddComment(issueKey string, comment *jira.Comment) (*jira.Comment, error) {
 	added, resp, err := client.Jira.Issue.AddComment(issueKey, comment)
@@ -229,6 +296,140 @@ func (client JiraClient) AddComment(issueKey string, comment *jira.Comment) (*ji
 	return added, err
 }
 
+// AddWorklog adds a worklog record to an issue.
+func (client JiraClient) AddWorklog(issueKey string, record *jira.WorklogRecord) (*jira.WorklogRecord, error) {
+	added, resp, err := client.Jira.Issue.AddWorklogRecord(issueKey, record)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	return added, nil
+}
+
+// RemoteLink is a link from an issue to an object in a remote application.
+type RemoteLink struct {
+	// GlobalId uniquely identifies the remote object; posting a link with an
+	// existing GlobalId updates that link instead of creating a new one.
+	GlobalId string           `json:"globalId,omitempty"`
+	Object   RemoteLinkObject `json:"object"`
+}
+
+// RemoteLinkObject describes the remote object a RemoteLink points to.
+type RemoteLinkObject struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// AddRemoteLink creates or updates a remote link on an issue.
+func (client JiraClient) AddRemoteLink(issueKey string, link *RemoteLink) error {
+	endpointURL, err := endpointURL(fmt.Sprintf("2/issue/%s/remotelink", issueKey))
+	if err != nil {
+		return err
+	}
+	req, err := client.Jira.NewRequest("POST", endpointURL, link)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Jira.Do(req, nil)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
+// AddWatcher adds a user to the watchers of an issue. The user is identified
+// by account ID on Jira Cloud, and by name on Jira Server.
+func (client JiraClient) AddWatcher(issueKey string, user *jira.User) error {
+	endpointURL, err := endpointURL(fmt.Sprintf("2/issue/%s/watchers", issueKey))
+	if err != nil {
+		return err
+	}
+	id := user.AccountID
+	if id == "" {
+		id = user.Name
+	}
+	req, err := client.Jira.NewRequest("POST", endpointURL, id)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Jira.Do(req, nil)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
+// RemoveWatcher removes a user from the watchers of an issue.
+func (client JiraClient) RemoveWatcher(issueKey string, user *jira.User) error {
+	endpointURL, err := endpointURL(fmt.Sprintf("2/issue/%s/watchers", issueKey))
+	if err != nil {
+		return err
+	}
+	req, err := client.Jira.NewRequest("DELETE", endpointURL, nil)
+	if err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	if user.AccountID != "" {
+		q.Add("accountId", user.AccountID)
+	} else {
+		q.Add("username", user.Name)
+	}
+	req.URL.RawQuery = q.Encode()
+	resp, err := client.Jira.Do(req, nil)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
+// DevelopmentSummary is the summary of the development information of an
+// issue, as returned by the dev-status API of the Development panel.
+type DevelopmentSummary struct {
+	PullRequest struct {
+		Overall struct {
+			Count   int    `json:"count"`
+			State   string `json:"state"`
+			Details struct {
+				OpenCount     int `json:"openCount"`
+				MergedCount   int `json:"mergedCount"`
+				DeclinedCount int `json:"declinedCount"`
+			} `json:"details"`
+		} `json:"overall"`
+	} `json:"pullrequest"`
+	Branch struct {
+		Overall struct {
+			Count int `json:"count"`
+		} `json:"overall"`
+	} `json:"branch"`
+	Repository struct {
+		Overall struct {
+			// Count is the number of commits
+			Count int `json:"count"`
+		} `json:"overall"`
+	} `json:"repository"`
+}
+
+// GetDevelopmentSummary returns the summary of the branches, commits and pull
+// requests linked to an issue by the development tools connected to Jira.
+func (client JiraClient) GetDevelopmentSummary(issueID string) (*DevelopmentSummary, error) {
+	req, err := client.Jira.NewRequest("GET", "/rest/dev-status/1.0/issue/summary", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Add("issueId", issueID)
+	req.URL.RawQuery = q.Encode()
+
+	result := struct {
+		Summary DevelopmentSummary `json:"summary"`
+	}{}
+	resp, err := client.Jira.Do(req, &result)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	return &result.Summary, nil
+}
+
 // UpdateComment changes a comment of an issue.
 func (client JiraClient) UpdateComment(issueKey string, comment *jira.Comment) (*jira.Comment, error) {
 	updated, resp, err := client.Jira.Issue.UpdateComment(issueKey, comment)
@@ -250,6 +451,57 @@ func (client JiraClient) SearchIssues(jql string, options *jira.SearchOptions) (
 	return found, nil
 }
 
+// GetFilter returns a saved filter by ID.
+func (client JiraClient) GetFilter(filterId string) (*jira.Filter, error) {
+	id, err := strconv.Atoi(filterId)
+	if err != nil {
+		return nil, errors.Errorf("invalid filter ID %q", filterId)
+	}
+	filter, resp, err := client.Jira.Filter.Get(id)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	return filter, nil
+}
+
+// ValidateJQL returns the errors of a JQL query, or none if it is valid.
+// Jira Server has no JQL parse API, so the query is validated by a search
+// without results.
+func (client JiraClient) ValidateJQL(jql string) ([]JQLError, error) {
+	req, err := client.Jira.NewRequest("GET", "/rest/api/2/search", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Add("jql", jql)
+	q.Add("maxResults", "0")
+	q.Add("fields", "id")
+	q.Add("validateQuery", "strict")
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := client.Jira.Do(req, nil)
+	if err == nil {
+		return nil, nil
+	}
+	if resp != nil && resp.StatusCode == http.StatusBadRequest {
+		if jerr, ok := jira.NewJiraError(resp, err).(*jira.Error); ok && len(jerr.ErrorMessages)+len(jerr.Errors) > 0 {
+			messages := append([]string{}, jerr.ErrorMessages...)
+			// The field errors are keyed by field, sort them by field to
+			// return them in a stable order
+			fields := make([]string, 0, len(jerr.Errors))
+			for field := range jerr.Errors {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				messages = append(messages, jerr.Errors[field])
+			}
+			return parseJQLErrors(messages), nil
+		}
+	}
+	return nil, userFriendlyJiraError(resp, err)
+}
+
 // DoTransition executes a transition on an issue.
 func (client JiraClient) DoTransition(issueKey, transitionID string) error {
 	resp, err := client.Jira.Issue.DoTransition(issueKey, transitionID)
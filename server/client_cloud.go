@@ -61,3 +61,35 @@ func (client jiraCloudClient) GetUserGroups(user JIRAUser) ([]*jira.UserGroup, e
 	}
 	return groups, nil
 }
+
+// ValidateJQL returns the errors of a JQL query reported by the JQL parse
+// API, or none if it is valid.
+func (client jiraCloudClient) ValidateJQL(jql string) ([]JQLError, error) {
+	req, err := client.Jira.NewRequest("POST", "/rest/api/3/jql/parse", map[string][]string{
+		"queries": {jql},
+	})
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Add("validation", "strict")
+	req.URL.RawQuery = q.Encode()
+
+	result := struct {
+		Queries []struct {
+			Errors []string `json:"errors"`
+		} `json:"queries"`
+	}{}
+	resp, err := client.Jira.Do(req, &result)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	messages := []string{}
+	for _, query := range result.Queries {
+		messages = append(messages, query.Errors...)
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return parseJQLErrors(messages), nil
+}
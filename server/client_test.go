@@ -4,9 +4,13 @@
 package main
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,6 +29,10 @@ func TestEndpointNameFromRequest(t *testing.T) {
 		{"GetTransitions", "https://hostname/2/issue/XYZ-1234/transitions", "GET", "api/jira/2/issue/transitions/GET"},
 		{"UpdateIssueAssignee", "https://hostname/2/issue/XYZ-1234/assignee", "PUT", "api/jira/2/issue/assignee/PUT"},
 		{"AddComment", "https://hostname/2/issue/XYZ-1234/comment", "POST", "api/jira/2/issue/comment/POST"},
+		{"AddRemoteLink", "https://hostname/2/issue/XYZ-1234/remotelink", "POST", "api/jira/2/issue/remotelink/POST"},
+		{"AddWorklog", "https://hostname/2/issue/XYZ-1234/worklog", "POST", "api/jira/2/issue/worklog/POST"},
+		{"AddWatcher", "https://hostname/2/issue/XYZ-1234/watchers", "POST", "api/jira/2/issue/watchers/POST"},
+		{"RemoveWatcher", "https://hostname/2/issue/XYZ-1234/watchers", "DELETE", "api/jira/2/issue/watchers/DELETE"},
 		{"UpdateComment", "https://hostname/2/issue/XYZ-1234/comment/XXX", "PUT", "api/jira/2/issue/comment/PUT"},
 		{"SearchIssues", "https://hostname/2/search", "GET", "api/jira/2/search/GET"},
 		{"DoTransition", "https://hostname/2/issue/XYZ-4321/transitions", "POST", "api/jira/2/issue/transitions/POST"},
@@ -41,3 +49,125 @@ func TestEndpointNameFromRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchers(t *testing.T) {
+	var method, query, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/2/issue/TEST-1/watchers", r.URL.Path)
+		bb, _ := ioutil.ReadAll(r.Body)
+		method, query, body = r.Method, r.URL.RawQuery, string(bb)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	jiraClient, err := jira.NewClient(nil, ts.URL)
+	require.NoError(t, err)
+	client := JiraClient{Jira: jiraClient}
+
+	cloudUser := &jira.User{AccountID: "5d1234", Name: "jdoe"}
+	serverUser := &jira.User{Name: "jdoe"}
+
+	require.NoError(t, client.AddWatcher("TEST-1", cloudUser))
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "\"5d1234\"\n", body)
+
+	require.NoError(t, client.AddWatcher("TEST-1", serverUser))
+	assert.Equal(t, "\"jdoe\"\n", body)
+
+	require.NoError(t, client.RemoveWatcher("TEST-1", cloudUser))
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "accountId=5d1234", query)
+
+	require.NoError(t, client.RemoveWatcher("TEST-1", serverUser))
+	assert.Equal(t, "username=jdoe", query)
+}
+
+func TestGetDevelopmentSummary(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/dev-status/1.0/issue/summary", r.URL.Path)
+		assert.Equal(t, "10001", r.URL.Query().Get("issueId"))
+		_, _ = w.Write([]byte(`{"errors":[],"summary":{
+			"pullrequest":{"overall":{"count":3,"state":"OPEN","details":{"openCount":1,"mergedCount":2,"declinedCount":0}}},
+			"branch":{"overall":{"count":1}},
+			"repository":{"overall":{"count":5}}}}`))
+	}))
+	defer ts.Close()
+
+	jiraClient, err := jira.NewClient(nil, ts.URL)
+	require.NoError(t, err)
+	client := JiraClient{Jira: jiraClient}
+
+	summary, err := client.GetDevelopmentSummary("10001")
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.PullRequest.Overall.Count)
+	assert.Equal(t, 2, summary.PullRequest.Overall.Details.MergedCount)
+	assert.Equal(t, 1, summary.Branch.Overall.Count)
+	assert.Equal(t, 5, summary.Repository.Overall.Count)
+
+	field := developmentField(summary)
+	require.NotNil(t, field)
+	assert.Equal(t, "Development", field.Title)
+	assert.Equal(t, "3 pull requests (1 open, 2 merged), 1 branch, 5 commits", field.Value)
+
+	assert.Nil(t, developmentField(&DevelopmentSummary{}))
+	assert.Nil(t, developmentField(nil))
+}
+
+func TestValidateJQL(t *testing.T) {
+	const syntaxError = "Error in the JQL Query: Expecting operator but got 'bar'. (line 1, character 19)"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/2/search":
+			assert.Equal(t, "strict", r.URL.Query().Get("validateQuery"))
+			assert.Equal(t, "0", r.URL.Query().Get("maxResults"))
+			switch r.URL.Query().Get("jql") {
+			case "project = TES":
+				_, _ = w.Write([]byte(`{"issues":[],"total":3}`))
+				return
+			case "status = Foo AND project = BAR":
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errorMessages":[],"errors":{"status":"No status Foo.","project":"No project BAR."}}`))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errorMessages":["` + syntaxError + `"],"errors":{}}`))
+		case "/rest/api/3/jql/parse":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "strict", r.URL.Query().Get("validation"))
+			bb, _ := ioutil.ReadAll(r.Body)
+			if string(bb) == "{\"queries\":[\"project = TES\"]}\n" {
+				_, _ = w.Write([]byte(`{"queries":[{"query":"project = TES"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"queries":[{"query":"project = TES foo bar","errors":["` + syntaxError + `"]}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	jiraClient, err := jira.NewClient(nil, ts.URL)
+	require.NoError(t, err)
+	expected := []JQLError{{Message: syntaxError, Line: 1, Character: 19}}
+
+	for name, client := range map[string]Client{
+		"server": newServerClient(jiraClient),
+		"cloud":  newCloudClient(jiraClient),
+	} {
+		t.Run(name, func(t *testing.T) {
+			jqlErrors, err := client.ValidateJQL("project = TES")
+			require.NoError(t, err)
+			assert.Empty(t, jqlErrors)
+
+			jqlErrors, err = client.ValidateJQL("project = TES foo bar")
+			require.NoError(t, err)
+			assert.Equal(t, expected, jqlErrors)
+		})
+	}
+
+	// The field errors are sorted by field
+	jqlErrors, err := newServerClient(jiraClient).ValidateJQL("status = Foo AND project = BAR")
+	require.NoError(t, err)
+	assert.Equal(t, []JQLError{{Message: "No project BAR."}, {Message: "No status Foo."}}, jqlErrors)
+}
@@ -15,27 +15,6 @@ import (
 
 const helpTextHeader = "###### Mattermost Jira Plugin - Slash Command Help\n"
 
-const commonHelpText = "\n* `/jira connect` - Connect your Mattermost account to your Jira account\n" +
-	"* `/jira disconnect` - Disconnect your Mattermost account from your Jira account\n" +
-	"* `/jira assign <issue-key> <assignee>` - Change the assignee of a Jira issue\n" +
-	"* `/jira unassign <issue-key>` - Unassign the Jira issue\n" +
-	"* `/jira create <text (optional)>` - Create a new Issue with 'text' inserted into the description field\n" +
-	"* `/jira transition <issue-key> <state>` - Change the state of a Jira issue\n" +
-	"* `/jira subscribe` - Configure the Jira notifications sent to this channel\n" +
-	"* `/jira view <issue-key>` - View the details of a specific Jira issue\n" +
-	"* `/jira settings [setting] [value]` - Update your user settings\n" +
-	"  * [setting] can be `notifications`\n" +
-	"  * [value] can be `on` or `off`\n"
-
-const sysAdminHelpText = "\n###### For System Administrators:\n" +
-	"Install:\n" +
-	"* `/jira install cloud <URL>` - Connect Mattermost to a Jira Cloud instance located at <URL>\n" +
-	"* `/jira install server <URL>` - Connect Mattermost to a Jira Server or Data Center instance located at <URL>\n" +
-	"Uninstall:\n" +
-	"* `/jira uninstall cloud <URL>` - Disconnect Mattermost from a Jira Cloud instance located at <URL>\n" +
-	"* `/jira uninstall server <URL>` - Disconnect Mattermost from a Jira Server or Data Center instance located at <URL>\n" +
-	"* `/jira subscribe list` - List of Jira Notification subscription rules across all channels\n"
-
 // Available settings
 const (
 	settingsNotifications = "notifications"
@@ -50,26 +29,77 @@ type CommandHandler struct {
 
 var jiraCommandHandler = CommandHandler{
 	handlers: map[string]CommandHandlerFunc{
-		"connect":            executeConnect,
-		"disconnect":         executeDisconnect,
-		"install/cloud":      executeInstallCloud,
-		"install/server":     executeInstallServer,
-		"view":               executeView,
-		"settings":           executeSettings,
-		"transition":         executeTransition,
-		"assign":             executeAssign,
-		"unassign":           executeUnassign,
-		"uninstall/cloud":    executeUninstallCloud,
-		"uninstall/server":   executeUninstallServer,
-		"webhook":            executeWebhookURL,
-		"stats":              executeStats,
-		"info":               executeInfo,
-		"help":               commandHelp,
-		"subscribe/list":     executeSubscribeList,
-		"debug/stats/reset":  executeDebugStatsReset,
-		"debug/stats/save":   executeDebugStatsSave,
-		"debug/stats/expvar": executeDebugStatsExpvar,
-		"debug/workflow":     executeDebugWorkflow,
+		"connect":                       executeConnect,
+		"connect/approve":               executeConnectApprove,
+		"disconnect":                    executeDisconnect,
+		"install/cloud":                 executeInstallCloud,
+		"install/server":                executeInstallServer,
+		"setup":                         executeSetup,
+		"instance/channel":              executeInstanceChannel,
+		"view":                          executeView,
+		"tree":                          executeTree,
+		"search":                        executeSearch,
+		"jql/validate":                  executeJQLValidate,
+		"create/defaults":               executeCreateDefaults,
+		"link-project":                  executeLinkProject,
+		"settings":                      executeSettings,
+		"transition":                    withWriteScope("transition", executeTransition),
+		"log":                           withWriteScope("log", executeLogWork),
+		"watch":                         withWriteScope("watch", executeWatch),
+		"unwatch":                       withWriteScope("unwatch", executeUnwatch),
+		"assign":                        withWriteScope("assign", executeAssign),
+		"unassign":                      withWriteScope("unassign", executeUnassign),
+		"uninstall/cloud":               executeUninstallCloud,
+		"uninstall/server":              executeUninstallServer,
+		"webhook":                       executeWebhookURL,
+		"webhook/instance":              executeWebhookInstance,
+		"webhook/replay":                executeWebhookReplay,
+		"stats":                         executeStats,
+		"info":                          executeInfo,
+		"me":                            executeMe,
+		"todo":                          executeTodo,
+		"todo/remind":                   executeTodoRemind,
+		"todo/unsnooze":                 executeTodoUnsnooze,
+		"diagnostics":                   executeDiagnostics,
+		"migrate/status":                executeMigrateStatus,
+		"kv/usage":                      executeKVUsage,
+		"kv/compact":                    executeKVCompact,
+		"help":                          commandHelp,
+		"subscribe/list":                executeSubscribeList,
+		"subscribe/test":                executeSubscribeTest,
+		"subscribe/delete":              executeSubscribeDelete,
+		"subscribe/issue":               executeSubscribeIssue,
+		"subscribe/status":              executeSubscribeStatus,
+		"subscribe/mention":             executeSubscribeMention,
+		"subscribe/parent":              executeSubscribeParent,
+		"subscribe/subtasks":            executeSubscribeSubtasks,
+		"subscribe/overlap":             executeSubscribeOverlap,
+		"subscribe/ignore":              executeSubscribeIgnore,
+		"subscribe/property":            executeSubscribeProperty,
+		"subscribe/change":              executeSubscribeChange,
+		"subscribe/jql":                 executeSubscribeJQL,
+		"subscribe/digest":              executeSubscribeDigest,
+		"subscribe/hide-authors":        executeSubscribeHideAuthors,
+		"subscribe/forward":             executeSubscribeForward,
+		"subscribe/backfill":            executeSubscribeBackfill,
+		"subscribe/restricted-comments": executeSubscribeRestrictedComments,
+		"unsubscribe/issue":             executeUnsubscribeIssue,
+		"subscribe/projects":            executeSubscribeProjects,
+		"unsubscribe/projects":          executeUnsubscribeProjects,
+		"locale/channel":                executeLocaleChannel,
+		"report/add":                    executeReportAdd,
+		"report/columns":                executeReportColumns,
+		"report/remove":                 executeReportRemove,
+		"report/run":                    executeReportRun,
+		"report/list":                   executeReportList,
+		"discussed":                     executeDiscussed,
+		"war-room":                      executeWarRoom,
+		"war-room/archive":              executeWarRoomArchive,
+		"debug/stats/reset":             executeDebugStatsReset,
+		"debug/stats/save":              executeDebugStatsSave,
+		"debug/stats/expvar":            executeDebugStatsExpvar,
+		"debug/workflow":                executeDebugWorkflow,
+		"debug/notify":                  executeDebugNotify,
 		// "debug/instance/list":   executeDebugInstanceList,
 		// "debug/instance/select": executeDebugInstanceSelect,
 		// "debug/instance/delete": executeDebugInstanceDelete,
@@ -77,14 +107,39 @@ var jiraCommandHandler = CommandHandler{
 	defaultHandler: executeJiraDefault,
 }
 
+// withWriteScope responds to the users connected with read-only access that
+// command needs write access, instead of running h.
+func withWriteScope(command string, h CommandHandlerFunc) CommandHandlerFunc {
+	return func(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+		ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+		if err == nil {
+			jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+			if err == nil && !jiraUser.HasScope(userScopeWrite) {
+				return p.responsef(header, "Your Jira account is connected with read-only access. "+
+					"Please use `/jira disconnect`, then `/jira connect` to connect with write access to use `/jira %s`.", command)
+			}
+		}
+		return h(p, c, header, args...)
+	}
+}
+
 func (ch CommandHandler) Handle(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if command, n := ch.find(args); command != "" {
+		return ch.handlers[command](p, c, header, args[n:]...)
+	}
+	return ch.defaultHandler(p, c, header, args...)
+}
+
+// find returns the longest command matching the first args, and how many
+// args it spans, or "" if none does.
+func (ch CommandHandler) find(args []string) (string, int) {
 	for n := len(args); n > 0; n-- {
-		h := ch.handlers[strings.Join(args[:n], "/")]
-		if h != nil {
-			return h(p, c, header, args[n:]...)
+		command := strings.Join(args[:n], "/")
+		if ch.handlers[command] != nil {
+			return command, n
 		}
 	}
-	return ch.defaultHandler(p, c, header, args...)
+	return "", 0
 }
 
 func commandHelp(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
@@ -92,23 +147,18 @@ func commandHelp(p *Plugin, c *plugin.Context, header *model.CommandArgs, args .
 }
 
 func (p *Plugin) help(args *model.CommandArgs) *model.CommandResponse {
-	authorized, _ := authorizedSysAdmin(p, args.UserId)
-
-	helpText := helpTextHeader
-	jiraAdminAdditionalHelpText := p.getConfig().JiraAdminAdditionalHelpText
+	locale := p.userLocale(args.UserId)
+	audiences := p.helpAudiences(args.UserId, args.ChannelId)
 
-	// Check if JIRA admin has provided additional help text to be shown up along with regular output
-	if jiraAdminAdditionalHelpText != "" {
-		helpText += "    " + jiraAdminAdditionalHelpText
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: args.ChannelId,
+		Message:   p.helpText(locale, audiences),
 	}
-
-	helpText += commonHelpText
-
-	if authorized {
-		helpText += sysAdminHelpText
+	if actions := p.helpActions(locale, args.ChannelId, audiences); len(actions) > 0 {
+		post.AddProp("attachments", []*model.SlackAttachment{{Actions: actions}})
 	}
-
-	p.postCommandResponse(args, helpText)
+	_ = p.API.SendEphemeralPost(args.UserId, post)
 	return &model.CommandResponse{}
 }
 
@@ -121,6 +171,15 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, commandArgs *model.CommandArg
 	if len(args) == 0 || args[0] != "/jira" {
 		return p.help(commandArgs), nil
 	}
+	if resp := p.jiraUnreachableResponse(commandArgs, args[1:]); resp != nil {
+		return resp, nil
+	}
+	if resp := p.teamInstanceResponse(commandArgs, args[1:]); resp != nil {
+		return resp, nil
+	}
+	if resp := p.issueRefResponse(commandArgs, args[1:]); resp != nil {
+		return resp, nil
+	}
 	return jiraCommandHandler.Handle(p, c, commandArgs, args[1:]...), nil
 }
 
@@ -132,46 +191,63 @@ func executeDisconnect(p *Plugin, c *plugin.Context, header *model.CommandArgs,
 	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
 		p.errorf("executeDisconnect: failed to load current Jira instance: %v", err)
-		return p.responsef(header, "Failed to load current Jira instance. Please contact your system administrator.")
+		return p.responseT(header, msgInstanceLoadFailed)
 	}
 
 	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
 	if err != nil {
-		return p.responsef(header, "Could not complete the **disconnection** request. You do not currently have a Jira account linked to your Mattermost account.")
+		// Users mapped without OAuth are "disconnected" by removing the mapping
+		mapping, mappingErr := p.loadUserMappingOf(ji, header.UserId)
+		if mappingErr != nil {
+			return p.responseT(header, msgDisconnectNotLinked)
+		}
+		mappingErr = p.deleteUserMapping(ji, header.UserId)
+		if mappingErr != nil {
+			return p.responseT(header, msgDisconnectFailed, mappingErr)
+		}
+		return p.responseT(header, msgDisconnected, mapping.JiraUser)
 	}
 
 	err = p.userDisconnect(ji, header.UserId)
 	if err != nil {
-		return p.responsef(header, "Could not complete the **disconnection** request. Error: %v", err)
+		return p.responseT(header, msgDisconnectFailed, err)
 	}
 
-	return p.responsef(header, "You have successfully disconnected your Jira account (**%s**).", jiraUser.DisplayName)
+	return p.responseT(header, msgDisconnected, jiraUser.DisplayName)
 }
 
 func executeConnect(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
-	if len(args) != 0 {
+	scope := ""
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "read-only":
+		scope = "?scope=" + userScopeRead
+	case len(args) == 2 && args[0] == "--as":
+		return executeConnectAs(p, header, args[1])
+	case len(args) == 2 && args[0] == "--verify":
+		return executeConnectVerify(p, header, args[1])
+	default:
 		return p.help(header)
 	}
 
 	instance, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
-		return p.responsef(header, "There is no Jira instance installed. Please contact your system administrator.")
+		return p.responseT(header, msgNoInstance)
 	}
 
 	jiraUser, err := p.userStore.LoadJIRAUser(instance, header.UserId)
 	if err == nil && len(jiraUser.Key()) != 0 {
-		return p.responsef(header, "You already have a Jira account linked to your Mattermost account. Please use `/jira disconnect` to disconnect.")
+		return p.responseT(header, msgAlreadyConnected)
 	}
 
-	return p.responsef(header, "[Click here to link your Jira account](%s%s)",
-		p.GetPluginURL(), routeUserConnect)
+	return p.responseT(header, msgConnectLink, p.GetPluginURL()+routeUserConnect+scope)
 }
 
 func executeSettings(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
 	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
 		p.errorf("executeSettings: failed to load current Jira instance: %v", err)
-		return p.responsef(header, "Failed to load current Jira instance. Please contact your system administrator.")
+		return p.responseT(header, msgInstanceLoadFailed)
 	}
 
 	mattermostUserId := header.UserId
@@ -181,7 +257,7 @@ func executeSettings(p *Plugin, c *plugin.Context, header *model.CommandArgs, ar
 	}
 
 	if len(args) == 0 {
-		return p.responsef(header, "Current settings:\n%s", jiraUser.Settings.String())
+		return p.responseT(header, msgCurrentSettings, jiraUser.Settings.String())
 	}
 
 	switch args[0] {
@@ -206,20 +282,23 @@ func executeView(p *Plugin, c *plugin.Context, header *model.CommandArgs, args .
 	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
 		p.errorf("executeView: failed to load current Jira instance: %v", err)
-		return p.responsef(header, "Failed to load current Jira instance. Please contact your system administrator.")
+		return p.responseT(header, msgInstanceLoadFailed)
 	}
 
 	mattermostUserId := header.UserId
 	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
 	if err != nil {
 		// v2.2: try to retrieve the issue anonymously
-		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+		return p.responseT(header, msgNotConnected)
 	}
 
-	attachment, err := p.getIssueAsSlackAttachment(ji, jiraUser, strings.ToUpper(args[0]))
+	issueKey := strings.ToUpper(args[0])
+	attachment, err := p.getIssueAsSlackAttachment(ji, jiraUser, issueKey)
 	if err != nil {
 		return p.responsef(header, err.Error())
 	}
+	attachment[0].Actions = append(attachment[0].Actions, p.issuePostActions(issueKey, englishLocalizer)...)
+	p.addIssueAppLink(attachment[0], issueKey, header.ChannelId)
 
 	post := &model.Post{
 		UserId:    p.getUserID(),
@@ -296,7 +375,20 @@ func executeSubscribeList(p *Plugin, c *plugin.Context, header *model.CommandArg
 		return p.responsef(header, "`/jira subscribe list` can only be run by a system administrator.")
 	}
 
-	msg, err := p.listChannelSubscriptions(header.TeamId)
+	counts := map[string]int{}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err == nil {
+		subs, err := p.getSubscriptions()
+		if err == nil {
+			list := []ChannelSubscription{}
+			for _, sub := range subs.Channel.ById {
+				list = append(list, sub)
+			}
+			counts = p.subscriptionOpenIssuesCounts(ji, header.UserId, list)
+		}
+	}
+
+	msg, err := p.listChannelSubscriptions(header.TeamId, counts)
 	if err != nil {
 		return p.responsef(header, "%v", err)
 	}
@@ -304,19 +396,89 @@ func executeSubscribeList(p *Plugin, c *plugin.Context, header *model.CommandArg
 	return p.responsef(header, msg)
 }
 
+func executeSubscribeRestrictedComments(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 || !restrictedCommentsPolicies.ContainsAny(args[0]) {
+		return p.responsef(header, "Please use `/jira subscribe restricted-comments <skip|private|stub> <subscription name>`.")
+	}
+	policy := args[0]
+	name := strings.Join(args[1:], " ")
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to edit Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeRestrictedComments: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.Name != name {
+			continue
+		}
+		sub.Filters.RestrictedComments = policy
+		err = p.editChannelSubscription(&sub, client)
+		if err != nil {
+			return p.responsef(header, "Failed to update subscription %q: %v", name, err)
+		}
+		return p.responsef(header, "Restricted comments policy of subscription %q set to `%s`.", name, policy)
+	}
+
+	return p.responsef(header, "There is no subscription named %q in this channel.", name)
+}
+
+// authorizedSysAdmin returns true if the user is a system administrator, or
+// a delegated admin of the plugin.
 func authorizedSysAdmin(p *Plugin, userId string) (bool, error) {
 	user, appErr := p.API.GetUser(userId)
 	if appErr != nil {
 		return false, appErr
 	}
 	if !strings.Contains(user.Roles, "system_admin") {
-		return false, nil
+		return p.getConfig().isDelegatedAdmin(user), nil
 	}
 	return true, nil
 }
 
+// authorizedSystemAdmin returns true only if the user is a system
+// administrator. Installing and uninstalling Jira instances is not delegated.
+func authorizedSystemAdmin(p *Plugin, userId string) (bool, error) {
+	user, appErr := p.API.GetUser(userId)
+	if appErr != nil {
+		return false, appErr
+	}
+	return strings.Contains(user.Roles, "system_admin"), nil
+}
+
+// isDelegatedAdmin returns true if the DelegatedAdmins setting lists the
+// user's ID, or one of the user's roles. Usernames are not matched, since
+// users can change them.
+func (conf config) isDelegatedAdmin(user *model.User) bool {
+	roles := NewStringSet(strings.Fields(user.Roles)...)
+	for _, admin := range conf.delegatedAdmins {
+		if admin == user.Id || roles.ContainsAny(admin) {
+			return true
+		}
+	}
+	return false
+}
+
 func executeInstallCloud(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
-	authorized, err := authorizedSysAdmin(p, header.UserId)
+	authorized, err := authorizedSystemAdmin(p, header.UserId)
 	if err != nil {
 		return p.responsef(header, "%v", err)
 	}
@@ -359,7 +521,7 @@ If you see an option to create a Jira issue, you're all set! If not, refer to ou
 }
 
 func executeInstallServer(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
-	authorized, err := authorizedSysAdmin(p, header.UserId)
+	authorized, err := authorizedSystemAdmin(p, header.UserId)
 	if err != nil {
 		return p.responsef(header, "%v", err)
 	}
@@ -418,10 +580,12 @@ If you see an option to create a Jira issue, you're all set! If not, refer to ou
 	return p.responsef(header, addResponseFormat, jiraURL, p.GetSiteURL(), ji.GetMattermostKey(), pkey)
 }
 
-// executeUninstallCloud will uninstall the jira cloud instance if the url matches, and then update all connected
-// clients so that their Jira-related menu options are removed.
+const uninstallConfirmation = "Disconnect Mattermost from the Jira instance at %s? The channel subscriptions will stop posting, and users will need to connect their Jira accounts again."
+
+// executeUninstallCloud will ask to confirm the uninstall of the jira cloud instance if the url matches, and then
+// update all connected clients so that their Jira-related menu options are removed.
 func executeUninstallCloud(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
-	authorized, err := authorizedSysAdmin(p, header.UserId)
+	authorized, err := authorizedSystemAdmin(p, header.UserId)
 	if err != nil {
 		return p.responsef(header, "%v", err)
 	}
@@ -451,28 +615,14 @@ func executeUninstallCloud(p *Plugin, c *plugin.Context, header *model.CommandAr
 		return p.responsef(header, "You have entered an incorrect URL. The current Jira instance URL is: `"+jci.GetURL()+"`. Please enter the URL correctly to confirm the uninstall command.")
 	}
 
-	err = p.instanceStore.DeleteJiraInstance(jci.GetURL())
-	if err != nil {
-		return p.responsef(header, "Failed to delete Jira instance "+ji.GetURL())
-	}
-
-	// Notify users we have uninstalled an instance
-	p.API.PublishWebSocketEvent(
-		wSEventInstanceStatus,
-		map[string]interface{}{
-			"instance_installed": false,
-			"instance_type":      "",
-		},
-		&model.WebsocketBroadcast{},
-	)
-
-	const uninstallInstructions = `Jira instance successfully disconnected. Go to **Settings > Apps > Manage Apps** to remove the application in your Jira Cloud instance.`
-
-	return p.responsef(header, uninstallInstructions)
+	return p.askConfirmation(header, &pendingConfirmation{
+		Action: confirmActionUninstall,
+		Target: jci.GetURL(),
+	}, fmt.Sprintf(uninstallConfirmation, jci.GetURL()))
 }
 
 func executeUninstallServer(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
-	authorized, err := authorizedSysAdmin(p, header.UserId)
+	authorized, err := authorizedSystemAdmin(p, header.UserId)
 	if err != nil {
 		return p.responsef(header, "%v", err)
 	}
@@ -502,9 +652,22 @@ func executeUninstallServer(p *Plugin, c *plugin.Context, header *model.CommandA
 		return p.responsef(header, "You have entered an incorrect URL. The current Jira instance URL is: `"+jsi.GetURL()+"`. Please enter the URL correctly to confirm the uninstall command.")
 	}
 
-	err = p.instanceStore.DeleteJiraInstance(jsi.GetURL())
+	return p.askConfirmation(header, &pendingConfirmation{
+		Action: confirmActionUninstall,
+		Target: jsi.GetURL(),
+	}, fmt.Sprintf(uninstallConfirmation, jsi.GetURL()))
+}
+
+// uninstallInstance deletes the Jira instance, and returns the instructions
+// to remove the application on the Jira side.
+func (p *Plugin) uninstallInstance(ji Instance) (string, error) {
+	err := p.instanceStore.DeleteJiraInstance(ji.GetURL())
+	if err != nil {
+		return "", fmt.Errorf("Failed to delete Jira instance %s", ji.GetURL())
+	}
+	err = p.deleteInstanceWebhookSecret(ji)
 	if err != nil {
-		return p.responsef(header, "Failed to delete Jira instance "+ji.GetURL())
+		p.errorf("uninstallInstance: failed to delete the webhook secret of %s: %v", ji.GetURL(), err)
 	}
 
 	// Notify users we have uninstalled an instance
@@ -517,9 +680,10 @@ func executeUninstallServer(p *Plugin, c *plugin.Context, header *model.CommandA
 		&model.WebsocketBroadcast{},
 	)
 
-	const uninstallInstructions = `Jira instance successfully disconnected. Go to **Settings > Applications > Application Links** to remove the application in your Jira Server or Data Center instance.`
-
-	return p.responsef(header, uninstallInstructions)
+	if ji.GetType() == JIRATypeCloud {
+		return `Jira instance successfully disconnected. Go to **Settings > Apps > Manage Apps** to remove the application in your Jira Cloud instance.`, nil
+	}
+	return `Jira instance successfully disconnected. Go to **Settings > Applications > Application Links** to remove the application in your Jira Server or Data Center instance.`, nil
 }
 
 func executeUnassign(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
@@ -742,7 +906,7 @@ func getCommand() *model.Command {
 		DisplayName:      "Jira",
 		Description:      "Integration with Jira.",
 		AutoComplete:     true,
-		AutoCompleteDesc: "Available commands: connect, assign, disconnect, create, transition, view, subscribe, settings, install cloud/server, uninstall cloud/server, help",
+		AutoCompleteDesc: "Available commands: connect, assign, disconnect, create, transition, log, view, watch, unwatch, subscribe, war-room, settings, install cloud/server, uninstall cloud/server, help",
 		AutoCompleteHint: "[command]",
 	}
 }
@@ -0,0 +1,274 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const wSEventHelpAction = "help_action"
+
+// Actions of the buttons of /jira help.
+const (
+	helpActionConnect   = "connect"
+	helpActionSubscribe = "subscribe"
+	helpActionCreate    = "create"
+)
+
+// helpAudience is who a command is listed to in /jira help.
+type helpAudience int
+
+const (
+	helpNotConnected       helpAudience = iota // users not connected to Jira
+	helpConnected                              // users connected to Jira
+	helpSubscriptionEditor                     // connected users allowed to edit the subscriptions of the channel
+	helpSysAdmin                               // system administrators
+)
+
+// helpEntry is a command listed in /jira help. Its description is localized
+// as the message jira.command.help.<command>, with "/" replaced by ".".
+type helpEntry struct {
+	command     string
+	usage       string
+	description string
+	audience    helpAudience
+}
+
+func (e helpEntry) messageID() string {
+	return "jira.command.help." + strings.Replace(e.command, "/", ".", -1)
+}
+
+// helpRegistry lists the commands in the order of /jira help.
+var helpRegistry = []helpEntry{
+	{"connect", "connect [read-only]", "Connect your Mattermost account to your Jira account, optionally with read-only access\n" +
+		"  * `/jira connect --as <Jira username or email>` only receives the notifications of a Jira user, without OAuth, once the email or an admin verifies it", helpNotConnected},
+	{"disconnect", "disconnect", "Disconnect your Mattermost account from your Jira account", helpConnected},
+	{"assign", "assign <issue-key> <assignee>", "Change the assignee of a Jira issue", helpConnected},
+	{"unassign", "unassign <issue-key>", "Unassign the Jira issue", helpConnected},
+	{"create", "create <text (optional)>", "Create a new Issue with 'text' inserted into the description field", helpConnected},
+	{"transition", "transition <issue-key> <state>", "Change the state of a Jira issue", helpConnected},
+	{"log", "log <issue-key> <time spent> [comment] [--post]", "Log work on a Jira issue, e.g. `2h 30m`, and with `--post` announce it in this channel", helpConnected},
+	{"me", "me [count]", "Show your Jira account, your top open issues and your notification settings", helpConnected},
+	{"todo", "todo", "List your open Jira issues, to transition, snooze or unwatch them", helpConnected},
+	{"todo/remind", "todo remind <schedule|off>", "Get your to-do list by direct message on a schedule, e.g. `weekdays@09:00` in UTC", helpConnected},
+	{"todo/unsnooze", "todo unsnooze <issue-key>", "Bring a snoozed issue back in your to-do list", helpConnected},
+	{"search", "search <JQL>", "Search Jira issues, and transition, assign or label several of them at once", helpConnected},
+	{"jql/validate", "jql validate <JQL>", "Check a JQL query before using it in a search, report or subscription, with the position of its errors", helpConnected},
+	{"view", "view <issue-key>", "View the details of a specific Jira issue", helpConnected},
+	{"tree", "tree <issue-key>", "Show the epic, stories and sub-tasks around a Jira issue", helpConnected},
+	{"watch", "watch <issue-key>", "Watch a Jira issue, to get the Jira notifications of its changes", helpConnected},
+	{"unwatch", "unwatch <issue-key>", "Stop watching a Jira issue", helpConnected},
+	{"settings", "settings [setting] [value]", "Update your user settings\n" +
+		"  * [setting] can be `notifications`\n" +
+		"  * [value] can be `on` or `off`", helpConnected},
+
+	{"subscribe", "subscribe", "Configure the Jira notifications sent to this channel", helpSubscriptionEditor},
+	{"subscribe/issue", "subscribe issue <issue-key>", "Post all events of a single Jira issue to this channel, or to this thread when run as a reply", helpSubscriptionEditor},
+	{"unsubscribe/issue", "unsubscribe issue <issue-key>", "Stop posting events of a single Jira issue to this channel or thread", helpSubscriptionEditor},
+	{"subscribe/mention", "subscribe mention <priority:name|label:label> <@mention> <subscription name>", "Mention someone in the posts of a subscription for issues with a priority or label, e.g. `priority:Blocker @here`\n" +
+		"  * `/jira subscribe mention remove <priority:name|label:label> <subscription name>` removes the rule", helpSubscriptionEditor},
+	{"subscribe/parent", "subscribe parent <issue-key|clear> <subscription name>", "Only post the events of an issue and of its sub-tasks, or of an epic and its issues, to a subscription", helpSubscriptionEditor},
+	{"subscribe/subtasks", "subscribe subtasks <include|exclude> <subscription name>", "Include or exclude the events of sub-tasks in a subscription", helpSubscriptionEditor},
+	{"subscribe/status", "subscribe status <header|pinned|off>", "Show the number of open issues matching this channel's subscriptions in the channel header or a pinned post", helpSubscriptionEditor},
+	{"subscribe/restricted-comments", "subscribe restricted-comments <policy> <subscription name>", "Set how a subscription handles comments restricted to a Jira role or group\n" +
+		"  * <policy> can be `skip` (default), `private` to post them in private channels only, or `stub` to post a notice without the content", helpSubscriptionEditor},
+	{"subscribe/ignore", "subscribe ignore <user[,user...]|clear> <subscription name>", "Don't post the changes made by some Jira users, like automation or sync tools, to a subscription", helpSubscriptionEditor},
+	{"subscribe/property", "subscribe property <key[=value[,value...]]|clear> <subscription name>", "Post to a subscription when an issue property is set, e.g. by a Jira automation rule, optionally to one of the values", helpSubscriptionEditor},
+	{"subscribe/change", "subscribe change <field>[=from,...]>to,...|priority:raised|priority:lowered|clear> <subscription name>", "Only post the issue updates of a subscription changing a field from or to some values, e.g. `status>Done`, or raising or lowering the priority", helpSubscriptionEditor},
+	{"subscribe/jql", "subscribe jql <\"JQL\"|clear> <subscription name>", "Only post the events of a subscription for the issues matching a JQL query, searched in Jira with your account", helpSubscriptionEditor},
+	{"subscribe/digest", "subscribe digest <on|off> <subscription name>", "Post the issue creations and deletions of a subscription right away, and the other events in an hourly digest", helpSubscriptionEditor},
+	{"subscribe/hide-authors", "subscribe hide-authors <on|off> <subscription name>", "Show the authors of the events and comments of a subscription as \"A Jira user\" in public channels", helpSubscriptionEditor},
+	{"subscribe/forward", "subscribe forward <URL|clear> <subscription name>", "Also forward the events of a subscription to an outgoing webhook, in signed requests", helpSubscriptionEditor},
+	{"subscribe/backfill", "subscribe backfill <subscription name>", "Post the open issues of a subscription to this channel, searched in Jira with your account", helpSubscriptionEditor},
+	{"subscribe/delete", "subscribe delete <subscription name>", "Delete a subscription of this channel, once confirmed", helpSubscriptionEditor},
+	{"subscribe/overlap", "subscribe overlap <all|first>", "Set whether all the subscriptions of this channel matching an event apply, or only the first one by name", helpSubscriptionEditor},
+	{"create/defaults", "create defaults <project-key> [issue type]", "Set the project and issue type that new issues created in this channel default to\n" +
+		"  * `/jira create defaults clear` removes the defaults", helpSubscriptionEditor},
+	{"link-project", "link-project <project-key>", "Link this channel to a Jira project, the default of new issues and reports, subscribed to its issues\n" +
+		"  * `/jira link-project clear` removes the link and its subscription", helpSubscriptionEditor},
+	{"instance/channel", "instance channel [alias|clear]", "Resolve the issue keys of this channel to another Jira instance, when several instances have the same project keys", helpSubscriptionEditor},
+	{"war-room", "war-room <issue-key>", "Create a channel dedicated to a Jira issue, subscribed to its events", helpSubscriptionEditor},
+	{"war-room/archive", "war-room archive <issue-key>", "Archive the dedicated channel of a Jira issue", helpSubscriptionEditor},
+	{"report/add", "report add <name> <schedule> [JQL]", "Post the issues matching a JQL query to this channel on a schedule, e.g. `daily@09:00`, `weekdays@09:00`, `monday@09:00` in UTC, or `hourly`. Without a query, the unresolved issues of the linked project are posted", helpSubscriptionEditor},
+	{"report/columns", "report columns <name> <column[,column...]|default>", "Set the columns of a report table, among key, summary, status, assignee, reporter, priority, type, created, updated, due and labels", helpSubscriptionEditor},
+	{"report/run", "report run <name>", "Post a report to this channel now", helpSubscriptionEditor},
+	{"report/remove", "report remove <name>", "Stop posting a report to this channel", helpSubscriptionEditor},
+	{"report/list", "report list", "List the reports of this channel", helpSubscriptionEditor},
+	{"discussed", "discussed <on|schedule|off>", "Post a summary of the Jira issues mentioned in this channel with their current state, weekly on Monday at 09:00 UTC, or on a schedule like `friday@16:00`", helpSubscriptionEditor},
+	{"locale/channel", "locale channel <locale>", "Set the locale of Jira notifications in this channel, or `default` to use the server locale", helpSubscriptionEditor},
+
+	{"install/cloud", "install cloud <URL>", "Connect Mattermost to a Jira Cloud instance located at <URL>", helpSysAdmin},
+	{"install/server", "install server <URL>", "Connect Mattermost to a Jira Server or Data Center instance located at <URL>", helpSysAdmin},
+	{"setup", "setup [project-key]", "Set up a Jira project step by step: the events, the channel to post them to and the Jira webhook", helpSysAdmin},
+	{"uninstall/cloud", "uninstall cloud <URL>", "Disconnect Mattermost from a Jira Cloud instance located at <URL>, once confirmed", helpSysAdmin},
+	{"uninstall/server", "uninstall server <URL>", "Disconnect Mattermost from a Jira Server or Data Center instance located at <URL>, once confirmed", helpSysAdmin},
+	{"connect/approve", "connect approve <@user>", "Map a Mattermost user to the Jira user they asked for with `/jira connect --as`, for their notifications", helpSysAdmin},
+	{"webhook/instance", "webhook instance [regenerate]", "Show the webhook URL with the secret of the current Jira instance, or regenerate the secret", helpSysAdmin},
+	{"webhook/replay", "webhook replay <duration> [--dry-run]", "Process the webhook events received in the last <duration>, like 2h, again through the current subscriptions", helpSysAdmin},
+	{"subscribe/list", "subscribe list", "List of Jira Notification subscription rules across all channels", helpSysAdmin},
+	{"subscribe/test", "subscribe test <project-key> [issue type]", "Post a test issue created event to the channels subscribed to it", helpSysAdmin},
+	{"debug/notify", "debug notify <@user> <issue-key>", "Explain which direct messages a user would get for the events of a Jira issue", helpSysAdmin},
+	{"diagnostics", "diagnostics", "Check the plugin configuration, and the connection to Jira and Mattermost services", helpSysAdmin},
+	{"migrate/status", "migrate status", "Show whether the data stored by older versions of the plugin was converted on upgrade", helpSysAdmin},
+	{"kv/usage", "kv usage", "Show the storage used by the plugin, including the size of the subscriptions and their stale entries", helpSysAdmin},
+	{"kv/compact", "kv compact", "Rebuild the subscription indexes, shard the subscriptions if they are too large, and delete the stale storage keys", helpSysAdmin},
+	{"subscribe/projects", "subscribe projects", "Post Jira project created and deleted events to this channel", helpSysAdmin},
+	{"unsubscribe/projects", "unsubscribe projects", "Stop posting Jira project events to this channel", helpSysAdmin},
+}
+
+// helpSectionMessages are the headings of the sections of /jira help, by
+// audience.
+var helpSectionMessages = map[helpAudience]string{
+	helpNotConnected:       msgHelpNotConnected,
+	helpSubscriptionEditor: msgHelpSubscriptions,
+	helpSysAdmin:           msgHelpSysAdmin,
+}
+
+func init() {
+	for _, e := range helpRegistry {
+		defaultMessages[e.messageID()] = e.description
+	}
+}
+
+// helpAudiences returns the audiences of /jira help the user belongs to in the
+// channel.
+func (p *Plugin) helpAudiences(mattermostUserId, channelId string) map[helpAudience]bool {
+	audiences := map[helpAudience]bool{}
+
+	connected := false
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err == nil {
+		jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+		connected = err == nil && len(jiraUser.Key()) != 0
+	}
+	if connected {
+		audiences[helpConnected] = true
+		audiences[helpSubscriptionEditor] = p.hasPermissionToManageSubscription(mattermostUserId, channelId) == nil
+	} else {
+		audiences[helpNotConnected] = true
+	}
+
+	authorized, _ := authorizedSysAdmin(p, mattermostUserId)
+	audiences[helpSysAdmin] = authorized
+	return audiences
+}
+
+// helpText renders the commands of helpRegistry for the audiences, by
+// section.
+func (p *Plugin) helpText(locale string, audiences map[helpAudience]bool) string {
+	text := p.localize(locale, msgHelpHeader)
+
+	// Check if JIRA admin has provided additional help text to be shown up along with regular output
+	if additional := p.getConfig().JiraAdminAdditionalHelpText; additional != "" {
+		text += "    " + additional
+	}
+
+	for _, audience := range []helpAudience{helpNotConnected, helpConnected, helpSubscriptionEditor, helpSysAdmin} {
+		if !audiences[audience] {
+			continue
+		}
+		if id, ok := helpSectionMessages[audience]; ok {
+			text += p.localize(locale, id)
+		} else {
+			text += "\n"
+		}
+		for _, e := range helpRegistry {
+			if e.audience == audience {
+				text += "* `/jira " + e.usage + "` - " + p.localize(locale, e.messageID()) + "\n"
+			}
+		}
+	}
+	return text
+}
+
+// helpActions returns the buttons of /jira help launching the flows available
+// to the audiences.
+func (p *Plugin) helpActions(locale, channelId string, audiences map[helpAudience]bool) []*model.PostAction {
+	_, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil
+	}
+
+	actionIds := []string{}
+	switch {
+	case audiences[helpNotConnected]:
+		actionIds = append(actionIds, helpActionConnect)
+	default:
+		if p.getConfig().EnableJiraUI {
+			actionIds = append(actionIds, helpActionCreate)
+		}
+		if audiences[helpSubscriptionEditor] {
+			actionIds = append(actionIds, helpActionSubscribe)
+		}
+	}
+
+	names := map[string]string{
+		helpActionConnect:   msgHelpButtonConnect,
+		helpActionCreate:    msgHelpButtonCreate,
+		helpActionSubscribe: msgHelpButtonSubscribe,
+	}
+	actions := []*model.PostAction{}
+	for _, id := range actionIds {
+		actions = append(actions, &model.PostAction{
+			Id:   id,
+			Name: p.localize(locale, names[id]),
+			Integration: &model.PostActionIntegration{
+				URL: p.GetPluginURLPath() + routeAPIHelpAction,
+				Context: map[string]interface{}{
+					"action":     id,
+					"channel_id": channelId,
+				},
+			},
+		})
+	}
+	return actions
+}
+
+// httpAPIHelpAction handles the buttons of /jira help. Connecting responds
+// with the connect link, the other actions open their dialog in the webapp.
+func httpAPIHelpAction(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	action, _ := request.Context["action"].(string)
+	channelId, _ := request.Context["channel_id"].(string)
+
+	response := model.PostActionIntegrationResponse{}
+	switch action {
+	case helpActionConnect:
+		response.EphemeralText = p.localize(p.userLocale(mattermostUserId), msgConnectLink, p.GetPluginURL()+routeUserConnect)
+	case helpActionSubscribe, helpActionCreate:
+		p.API.PublishWebSocketEvent(
+			wSEventHelpAction,
+			map[string]interface{}{
+				"action":     action,
+				"channel_id": channelId,
+			},
+			&model.WebsocketBroadcast{UserId: mattermostUserId},
+		)
+	default:
+		return http.StatusBadRequest, errors.Errorf("unknown action %q", action)
+	}
+
+	b, _ := json.Marshal(response)
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
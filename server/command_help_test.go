@@ -0,0 +1,66 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHelpRegistry(t *testing.T) {
+	// The webapp handles these commands, the server responds with help
+	webappCommands := NewStringSet("create", "subscribe")
+
+	for _, e := range helpRegistry {
+		if !webappCommands.ContainsAny(e.command) {
+			assert.NotNil(t, jiraCommandHandler.handlers[e.command], "no handler for %s", e.command)
+		}
+		assert.True(t, strings.HasPrefix(e.usage, strings.Replace(e.command, "/", " ", -1)),
+			"usage %q does not start with the command %s", e.usage, e.command)
+	}
+}
+
+func TestHelpText(t *testing.T) {
+	p := &Plugin{}
+
+	for name, tc := range map[string]struct {
+		audiences   map[helpAudience]bool
+		expected    []string
+		notExpected []string
+	}{
+		"not connected": {
+			audiences:   map[helpAudience]bool{helpNotConnected: true},
+			expected:    []string{"`/jira connect [read-only]`"},
+			notExpected: []string{"`/jira disconnect`", "`/jira subscribe`", "For System Administrators"},
+		},
+		"connected member": {
+			audiences:   map[helpAudience]bool{helpConnected: true},
+			expected:    []string{"`/jira disconnect`", "`/jira view <issue-key>`"},
+			notExpected: []string{"`/jira connect", "`/jira subscribe`", "Channel subscriptions", "`/jira install cloud <URL>`"},
+		},
+		"connected subscription editor": {
+			audiences:   map[helpAudience]bool{helpConnected: true, helpSubscriptionEditor: true},
+			expected:    []string{"Channel subscriptions", "`/jira subscribe`", "`/jira war-room <issue-key>`"},
+			notExpected: []string{"`/jira connect", "`/jira subscribe list`"},
+		},
+		"not connected sysadmin": {
+			audiences:   map[helpAudience]bool{helpNotConnected: true, helpSysAdmin: true},
+			expected:    []string{"`/jira connect [read-only]`", "For System Administrators", "`/jira subscribe list`"},
+			notExpected: []string{"`/jira disconnect`", "Channel subscriptions"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			text := p.helpText("en", tc.audiences)
+			assert.True(t, strings.HasPrefix(text, helpTextHeader))
+			for _, s := range tc.expected {
+				assert.Contains(t, text, s)
+			}
+			for _, s := range tc.notExpected {
+				assert.NotContains(t, text, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	meDefaultIssues = 5
+	meMaxIssues     = searchMaxResults
+
+	myIssuesJQL = "assignee = currentUser() AND resolution = Unresolved ORDER BY priority DESC, updated DESC"
+)
+
+// myIssueFields are the fields of the issues listed by /jira me and /jira
+// todo.
+var myIssueFields = []string{"summary", "status", "priority"}
+
+func executeMe(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) > 1 {
+		return p.help(header)
+	}
+	max := meDefaultIssues
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > meMaxIssues {
+			return p.responsef(header, "Please specify the number of issues to list, from 1 to %d, in the form `/jira me [count]`.", meMaxIssues)
+		}
+		max = n
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeMe: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	issues, err := client.SearchIssues(myIssuesJQL, &jira.SearchOptions{
+		MaxResults: max,
+		Fields:     myIssueFields,
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to search your Jira issues: %v", err)
+	}
+	return p.responsef(header, "%s", renderMe(strings.TrimRight(ji.GetURL(), "/"), jiraUser, issues, max))
+}
+
+// renderMe renders the personal dashboard of /jira me: the connected Jira
+// account, the user's open issues, and their notification settings.
+func renderMe(jiraURL string, jiraUser JIRAUser, issues []jira.Issue, max int) string {
+	account := jiraUser.DisplayName
+	if jiraUser.EmailAddress != "" {
+		account += " (" + jiraUser.EmailAddress + ")"
+	}
+	access := "read and write"
+	if !jiraUser.HasScope(userScopeWrite) {
+		access = "read-only"
+	}
+	lines := []string{
+		fmt.Sprintf("#### Jira: %s", jiraURL),
+		fmt.Sprintf("Connected as **%s**, with %s access.", account, access),
+		"",
+	}
+
+	searchURL := jiraURL + "/issues/?jql=" + url.QueryEscape(myIssuesJQL)
+	switch {
+	case len(issues) == 0:
+		lines = append(lines, "You have no open issues assigned to you.")
+	case len(issues) == max:
+		lines = append(lines, fmt.Sprintf("Your top %d open issues ([view all](%s)):", max, searchURL))
+	default:
+		lines = append(lines, fmt.Sprintf("Your open issues ([view in Jira](%s)):", searchURL))
+	}
+	for _, issue := range issues {
+		lines = append(lines, "* "+myIssueText(jiraURL, issue))
+	}
+
+	notifications := "off"
+	if jiraUser.Settings != nil && jiraUser.Settings.Notifications {
+		notifications = "on"
+	}
+	lines = append(lines, "",
+		fmt.Sprintf("Jira notifications by direct message: **%s**. "+
+			"Change them with `/jira settings notifications <on|off>`.", notifications))
+	return strings.Join(lines, "\n")
+}
+
+// myIssueText renders an issue of the user's lists as its link, summary,
+// priority and status.
+func myIssueText(jiraURL string, issue jira.Issue) string {
+	text := fmt.Sprintf("[%s](%s/browse/%s)", issue.Key, jiraURL, issue.Key)
+	if issue.Fields == nil {
+		return text
+	}
+	text += " " + issue.Fields.Summary
+	details := []string{}
+	if issue.Fields.Priority != nil && issue.Fields.Priority.Name != "" {
+		details = append(details, issue.Fields.Priority.Name)
+	}
+	if issue.Fields.Status != nil && issue.Fields.Status.Name != "" {
+		details = append(details, issue.Fields.Status.Name)
+	}
+	if len(details) > 0 {
+		text += " (" + strings.Join(details, ", ") + ")"
+	}
+	return text
+}
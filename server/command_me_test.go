@@ -0,0 +1,43 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMe(t *testing.T) {
+	jiraUser := JIRAUser{
+		User:     jira.User{DisplayName: "Jane Doe", EmailAddress: "jane@example.com"},
+		Settings: &UserSettings{Notifications: true},
+	}
+	issues := []jira.Issue{
+		{Key: "TEST-1", Fields: &jira.IssueFields{
+			Summary:  "Fix the build",
+			Status:   &jira.Status{Name: "In Progress"},
+			Priority: &jira.Priority{Name: "High"},
+		}},
+		{Key: "TEST-2", Fields: &jira.IssueFields{Summary: "Write the docs"}},
+	}
+
+	message := renderMe("https://jira.example.com", jiraUser, issues, 2)
+	assert.Contains(t, message, "Connected as **Jane Doe (jane@example.com)**, with read and write access.")
+	assert.Contains(t, message, "Your top 2 open issues ([view all](https://jira.example.com/issues/?jql=assignee")
+	assert.Contains(t, message, "* [TEST-1](https://jira.example.com/browse/TEST-1) Fix the build (High, In Progress)\n")
+	assert.Contains(t, message, "* [TEST-2](https://jira.example.com/browse/TEST-2) Write the docs\n")
+	assert.Contains(t, message, "Jira notifications by direct message: **on**.")
+
+	jiraUser.Scopes = []string{userScopeRead}
+	jiraUser.Settings = nil
+	message = renderMe("https://jira.example.com", jiraUser, nil, 5)
+	assert.Contains(t, message, "with read-only access.")
+	assert.Contains(t, message, "You have no open issues assigned to you.")
+	assert.Contains(t, message, "Jira notifications by direct message: **off**.")
+
+	message = renderMe("https://jira.example.com", jiraUser, issues, 5)
+	assert.Contains(t, message, "Your open issues ([view in Jira](")
+}
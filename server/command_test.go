@@ -20,6 +20,14 @@ const (
 	mockUserIDNonSysAdmin          = "5"
 )
 
+const (
+	helpTextNotConnected = "\nConnect your Jira account to use the other commands:\n" +
+		"* `/jira connect [read-only]` - Connect your Mattermost account to your Jira account, optionally with read-only access\n" +
+		"  * `/jira connect --as <Jira username or email>` only receives the notifications of a Jira user, without OAuth, once the email or an admin verifies it\n"
+	helpTextSysAdmin = "\n###### For System Administrators:\n" +
+		"* `/jira install cloud <URL>` - Connect Mattermost to a Jira Cloud instance located at <URL>\n"
+)
+
 type mockUserStoreKV struct {
 	mockUserStore
 	kv map[string]JIRAUser
@@ -176,19 +184,19 @@ func TestPlugin_ExecuteCommand_Installation(t *testing.T) {
 	}{
 		"no params - user is sys admin": {
 			commandArgs:       &model.CommandArgs{Command: "/jira install", UserId: mockUserIDSysAdmin},
-			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + commonHelpText + sysAdminHelpText),
+			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + helpTextNotConnected + helpTextSysAdmin),
 		},
 		"no params - user is not sys admin": {
 			commandArgs:       &model.CommandArgs{Command: "/jira install", UserId: mockUserIDNonSysAdmin},
-			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + commonHelpText),
+			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + helpTextNotConnected),
 		},
 		"install server without URL": {
 			commandArgs:       &model.CommandArgs{Command: "/jira install server", UserId: mockUserIDSysAdmin},
-			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + commonHelpText + sysAdminHelpText),
+			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + helpTextNotConnected + helpTextSysAdmin),
 		},
 		"install cloud instance without URL": {
 			commandArgs:       &model.CommandArgs{Command: "/jira install cloud", UserId: mockUserIDSysAdmin},
-			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + commonHelpText + sysAdminHelpText),
+			expectedMsgPrefix: strings.TrimSpace(helpTextHeader + helpTextNotConnected + helpTextSysAdmin),
 		},
 		"install cloud instance as server": {
 			commandArgs:       &model.CommandArgs{Command: "/jira install server https://mmtest.atlassian.net", UserId: mockUserIDSysAdmin},
@@ -235,3 +243,37 @@ func TestPlugin_ExecuteCommand_Installation(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorizedSysAdmin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		roles, username     string
+		delegatedAdmins     []string
+		expected            bool
+		expectedSystemAdmin bool
+	}{
+		"system admin":           {"system_user system_admin", "admin", nil, true, true},
+		"user":                   {"system_user", "alice", nil, false, false},
+		"delegated user ID":      {"system_user", "alice", []string{"userId"}, true, false},
+		"delegated username":     {"system_user", "alice", []string{"@alice", "alice"}, false, false},
+		"delegated role":         {"system_user team_admin", "alice", []string{"team_admin"}, true, false},
+		"other delegated admins": {"system_user", "alice", []string{"bob", "team_admin"}, false, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("GetUser", "userId").Return(&model.User{Id: "userId", Username: tc.username, Roles: tc.roles}, nil)
+			p := Plugin{}
+			p.SetAPI(api)
+			p.updateConfig(func(conf *config) {
+				conf.delegatedAdmins = tc.delegatedAdmins
+			})
+
+			authorized, err := authorizedSysAdmin(&p, "userId")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, authorized)
+
+			authorized, err = authorizedSystemAdmin(&p, "userId")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSystemAdmin, authorized)
+		})
+	}
+}
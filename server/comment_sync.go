@@ -0,0 +1,111 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// The activity on a comment synced to its threads is its edits. Jira doesn't
+// send webhook events for the reactions to a comment, and the votes are on
+// the issue, not the comment, without an event either.
+
+const (
+	prefixCommentPosts = "comment_posts_"
+
+	// How long the posts of a comment are remembered.
+	commentPostsExpirySeconds = 30 * 24 * 60 * 60
+)
+
+// commentPosts records the threads a Jira comment was posted to, by channel.
+type commentPosts struct {
+	RootIds map[string]string `json:"root_ids"`
+}
+
+// commentPostsKey identifies a comment by the URL of its REST resource, which
+// is unique across the Jira instances.
+func commentPostsKey(wh *webhook) string {
+	if wh.JiraWebhook == nil || wh.Comment.Self == "" {
+		return ""
+	}
+	return hashkey(prefixCommentPosts, wh.Comment.Self)
+}
+
+func (p *Plugin) loadCommentPosts(key string) (*commentPosts, error) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	cp := &commentPosts{}
+	err := json.Unmarshal(data, cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (p *Plugin) storeCommentPosts(key string, cp *commentPosts) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSetWithExpiry(key, data, commentPostsExpirySeconds)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// rememberCommentPosts records the posts of a new comment, so that the later
+// activity on the comment is posted to their threads.
+func (p *Plugin) rememberCommentPosts(wh *webhook, posts []*model.Post) {
+	key := commentPostsKey(wh)
+	if !wh.getConfig(p).SyncCommentActivity || key == "" || !wh.Events().ContainsAny(eventCreatedComment) || len(posts) == 0 {
+		return
+	}
+
+	cp := &commentPosts{RootIds: map[string]string{}}
+	for _, post := range posts {
+		rootId := post.RootId
+		if rootId == "" {
+			rootId = post.Id
+		}
+		cp.RootIds[post.ChannelId] = rootId
+	}
+	err := p.storeCommentPosts(key, cp)
+	if err != nil {
+		p.errorf("rememberCommentPosts: failed to store the posts of comment %s: %v", wh.Comment.ID, err)
+	}
+}
+
+// threadCommentActivity moves the posts of an edited comment to the threads
+// of the comment's posts. The posts are those of the subscriptions to comment
+// edits: the channels that received the comment but are not subscribed to its
+// edits don't get them.
+func (p *Plugin) threadCommentActivity(wh *webhook, posts []webhookPost) []webhookPost {
+	key := commentPostsKey(wh)
+	if !wh.getConfig(p).SyncCommentActivity || key == "" || !wh.Events().ContainsAny(eventUpdatedComment) {
+		return posts
+	}
+	cp, err := p.loadCommentPosts(key)
+	if err != nil {
+		p.errorf("threadCommentActivity: failed to load the posts of comment %s: %v", wh.Comment.ID, err)
+		return posts
+	}
+	if cp == nil {
+		return posts
+	}
+
+	for i := range posts {
+		if rootId, ok := cp.RootIds[posts[i].channelId]; ok && posts[i].rootId == "" {
+			posts[i].rootId = rootId
+		}
+	}
+	return posts
+}
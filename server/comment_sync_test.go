@@ -0,0 +1,61 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestThreadCommentActivity(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(commentPostsExpirySeconds)).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.SyncCommentActivity = true
+	})
+
+	comment := jira.Comment{ID: "10001", Self: "https://jira.example.com/rest/api/2/issue/10000/comment/10001"}
+	created := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Comment: comment}}, eventTypes: NewStringSet(eventCreatedComment)}
+	p.rememberCommentPosts(created, []*model.Post{
+		{Id: "post1", ChannelId: "channel1"},
+		{Id: "post2", ChannelId: "channel2", RootId: "root2"},
+		{Id: "post3", ChannelId: "channel3"},
+	})
+
+	edited := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Comment: comment}}, eventTypes: NewStringSet(eventUpdatedComment), headline: "edited"}
+	posts := p.threadCommentActivity(edited, []webhookPost{
+		{wh: *edited, channelId: "channel1"},
+		{wh: *edited, channelId: "other"},
+	})
+	require.Len(t, posts, 2, "the channels not subscribed to comment edits don't get them")
+	assert.Equal(t, "channel1", posts[0].channelId)
+	assert.Equal(t, "post1", posts[0].rootId, "the edit is moved to the thread of the comment")
+	assert.Equal(t, "other", posts[1].channelId)
+	assert.Equal(t, "", posts[1].rootId, "the channel did not receive the comment")
+
+	posts = p.threadCommentActivity(edited, []webhookPost{{wh: *edited, channelId: "channel2"}})
+	require.Len(t, posts, 1)
+	assert.Equal(t, "root2", posts[0].rootId, "the edit is posted to the thread the comment was posted to")
+
+	p.updateConfig(func(conf *config) {
+		conf.SyncCommentActivity = false
+	})
+	posts = p.threadCommentActivity(edited, []webhookPost{{wh: *edited, channelId: "channel1"}})
+	require.Len(t, posts, 1)
+	assert.Equal(t, "", posts[0].rootId)
+}
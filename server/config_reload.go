@@ -0,0 +1,53 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"time"
+)
+
+// applyConfigChanges updates the state derived from the settings when they
+// change, so that the changes apply without restarting the plugin. The
+// webhook workers and the periodic jobs take a snapshot of the configuration
+// for each event and run, and need nothing more.
+func (p *Plugin) applyConfigChanges(previous, conf config) {
+	if previous.externalConfig == conf.externalConfig {
+		return
+	}
+
+	// The cached instance was decrypted with the previous encryption keys,
+	// and the cached previews and options fetched for the previous teams and
+	// instances
+	p.updateConfig(func(conf *config) {
+		conf.currentInstance = nil
+		conf.currentInstanceExpires = time.Time{}
+	})
+	p.issuePreviews.reset()
+	p.subscriptionOptions.reset()
+
+	// The autolinks are added on activation otherwise
+	if conf.botUserID != "" && previous.InstanceAliases != conf.InstanceAliases {
+		go func() {
+			if err := p.addInstanceAliasAutolinks(); err != nil {
+				p.API.LogWarn("could not install autolinks for the instance aliases", "err", err)
+			}
+		}()
+	}
+
+	// The subscriptions are validated when created otherwise. One server of
+	// a cluster validates them.
+	if conf.botUserID != "" &&
+		(previous.ProjectChannelRestrictions != conf.ProjectChannelRestrictions ||
+			previous.TeamInstances != conf.TeamInstances ||
+			previous.TeamProjects != conf.TeamProjects) {
+		go func() {
+			if !p.claimJob("revalidate_subscriptions", time.Minute) {
+				return
+			}
+			if err := p.revalidateAllSubscriptions(); err != nil {
+				p.errorf("applyConfigChanges: failed to validate the subscriptions: %v", err)
+			}
+		}()
+	}
+}
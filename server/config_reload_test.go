@@ -0,0 +1,77 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSnapshots(t *testing.T) {
+	p := &Plugin{}
+	assert.Equal(t, 0, p.getConfig().maxTextLength)
+
+	snapshot := p.updateConfig(func(conf *config) {
+		conf.maxTextLength = 100
+	})
+	p.updateConfig(func(conf *config) {
+		conf.maxTextLength = 200
+	})
+	assert.Equal(t, 100, snapshot.maxTextLength)
+	assert.Equal(t, 200, p.getConfig().maxTextLength)
+
+	// The updates are serialized, the reads don't block them
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.updateConfig(func(conf *config) {
+				conf.maxTextLength++
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = p.getConfig().maxTextLength
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 250, p.getConfig().maxTextLength)
+
+	wh := webhook{}
+	assert.Equal(t, 250, wh.getConfig(p).maxTextLength)
+	wh.conf = &snapshot
+	assert.Equal(t, 100, wh.getConfig(p).maxTextLength)
+}
+
+func TestApplyConfigChanges(t *testing.T) {
+	now := time.Now()
+	p := &Plugin{}
+	ji, err := mockCurrentInstanceStore{p}.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+	previous := p.updateConfig(func(conf *config) {
+		conf.currentInstance = ji
+		conf.currentInstanceExpires = now.Add(time.Hour)
+	})
+	p.issuePreviews.set("preview", &IssuePreview{}, now)
+	p.subscriptionOptions.set("options", &SubscriptionOptions{}, now)
+
+	p.applyConfigChanges(previous, previous)
+	assert.NotNil(t, p.getConfig().currentInstance)
+	_, ok := p.issuePreviews.get("preview", now)
+	assert.True(t, ok)
+
+	conf := previous
+	conf.EncryptionKey = "new key"
+	p.applyConfigChanges(previous, conf)
+	assert.Nil(t, p.getConfig().currentInstance)
+	assert.True(t, p.getConfig().currentInstanceExpires.IsZero())
+	_, ok = p.issuePreviews.get("preview", now)
+	assert.False(t, ok)
+	assert.Nil(t, p.subscriptionOptions.get("options", now))
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const (
+	alertConfigProblems = "config_problems"
+
+	// Secrets shorter than this are reported as weak. The secrets generated
+	// in the System Console have webhookSecretLength characters.
+	minSecretLength = 16
+)
+
+// systemRoles are the roles of Mattermost users, which DelegatedAdmins can
+// list besides user IDs.
+var systemRoles = NewStringSet(
+	model.SYSTEM_ADMIN_ROLE_ID,
+	model.SYSTEM_USER_ROLE_ID,
+	model.SYSTEM_GUEST_ROLE_ID,
+	model.SYSTEM_POST_ALL_ROLE_ID,
+	model.SYSTEM_POST_ALL_PUBLIC_ROLE_ID,
+	model.SYSTEM_USER_ACCESS_TOKEN_ROLE_ID,
+)
+
+// configProblem is a plugin setting that is invalid, or inconsistent with
+// another setting, and how to fix it. Unlike the settings that fail to load,
+// these settings are applied, but fail later, e.g. when a webhook is received.
+type configProblem struct {
+	setting string
+	message string
+}
+
+func (cp configProblem) String() string {
+	return cp.setting + ": " + cp.message
+}
+
+// validateConfig returns the problems of the plugin settings.
+func (p *Plugin) validateConfig(ec externalConfig) []configProblem {
+	problems := []configProblem{}
+	add := func(setting, format string, args ...interface{}) {
+		problems = append(problems, configProblem{setting, fmt.Sprintf(format, args...)})
+	}
+
+	if err := p.CheckSiteURL(); err != nil {
+		add("Site URL", "%v Set it in **System Console > Environment > Web Server**, the webhook and OAuth URLs given to Jira are built from it.", err)
+	} else if scheme := siteURLScheme(p.GetSiteURL()); scheme != "http" && scheme != "https" {
+		add("Site URL", "%q is not an http or https URL. Set it in **System Console > Environment > Web Server**.", p.GetSiteURL())
+	}
+
+	secret, _ := url.QueryUnescape(ec.Secret)
+	if secret != "" && len(secret) < minSecretLength {
+		add("Webhook Secret", "is shorter than %d characters, regenerate it and update the webhook URLs in Jira.", minSecretLength)
+	}
+	if ec.StatsSecret != "" && len(ec.StatsSecret) < minSecretLength {
+		add("Stats API Secret", "is shorter than %d characters, regenerate it.", minSecretLength)
+	}
+	if key := strings.TrimSpace(ec.EncryptionKey); key != "" && len(key) < minSecretLength {
+		add("Encryption Key", "is shorter than %d characters, use a longer key and move this one to the previous encryption keys.", minSecretLength)
+	}
+
+	for _, key := range utils.ParseList(ec.PreviousEncryptionKeys) {
+		if key == strings.TrimSpace(ec.EncryptionKey) {
+			add("Previous Encryption Keys", "include the current encryption key, remove it from this setting.")
+			break
+		}
+	}
+
+	if scheme := normalizeURLScheme(ec.JiraAppLinkScheme); scheme != "" {
+		if !reURLScheme.MatchString(scheme) {
+			add("Jira App Link Scheme", "%q is not a URL scheme, like `jira`.", ec.JiraAppLinkScheme)
+		} else if scheme == "http" || scheme == "https" {
+			add("Jira App Link Scheme", "is the scheme of the Jira links, use the scheme of the Jira app links instead, or leave it empty.")
+		}
+	}
+
+	for _, label := range utils.ParseList(ec.QuickLabels) {
+		if strings.ContainsAny(label, " \t") {
+			add("Quick Labels", "the label %q contains a space, which Jira labels can't.", label)
+		}
+	}
+
+	for _, problem := range validateIssueEmoji(ec.IssueEmoji) {
+		add("Issue Emoji", "%s", problem)
+	}
+	if strings.TrimSpace(ec.IssueEmoji) != "" && !ec.ShowIssueEmoji {
+		add("Issue Emoji", "are only shown when Show Issue Emoji is true.")
+	}
+
+	for _, host := range utils.ParseList(ec.EventForwardingHosts) {
+		if strings.ContainsAny(host, "/:@ ") {
+			add("Event Forwarding Hosts", "%q is not a host name, like `hooks.example.com`.", host)
+		}
+	}
+
+	for _, admin := range utils.ParseList(ec.DelegatedAdmins) {
+		if systemRoles.ContainsAny(admin) {
+			continue
+		}
+		if model.IsValidId(admin) {
+			if _, appErr := p.API.GetUser(admin); appErr != nil {
+				add("Delegated Admins", "%q is not the ID of a Mattermost user.", admin)
+			}
+			continue
+		}
+		if user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(admin, "@")); appErr == nil {
+			add("Delegated Admins", "%q is a username, which can change: list the user ID `%s` instead.", admin, user.Id)
+			continue
+		}
+		add("Delegated Admins", "%q is neither a Mattermost user ID nor a system role.", admin)
+	}
+
+	for _, setting := range []struct {
+		name      string
+		channelId string
+	}{
+		{"Admin Alerts Channel ID", ec.AdminAlertsChannelId},
+		{"Fallback Channel ID", ec.FallbackChannelId},
+	} {
+		if setting.channelId == "" {
+			continue
+		}
+		if err := p.checkConfigChannel(setting.channelId); err != nil {
+			add(setting.name, "%v", err)
+		}
+	}
+
+	return problems
+}
+
+func siteURLScheme(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme
+}
+
+// checkConfigChannel returns an error if the plugin can't post to the channel
+// of a setting.
+func (p *Plugin) checkConfigChannel(channelId string) error {
+	if !model.IsValidId(channelId) {
+		return errors.Errorf("%q is not a channel ID. Copy the ID from **View Info** in the channel menu.", channelId)
+	}
+	channel, appErr := p.API.GetChannel(channelId)
+	if appErr != nil {
+		return errors.Errorf("channel %s was not found.", channelId)
+	}
+	if channel.DeleteAt != 0 {
+		return errors.Errorf("channel ~%s is archived, restore it or use another channel.", channel.Name)
+	}
+	return nil
+}
+
+// reportConfigProblems logs the problems of the plugin settings, and posts
+// them to the admin alerts channel, once the bot is created.
+func (p *Plugin) reportConfigProblems(problems []configProblem) {
+	if len(problems) == 0 || p.getUserID() == "" {
+		return
+	}
+
+	lines := []string{}
+	for _, problem := range problems {
+		p.errorf("Invalid plugin setting %s", problem)
+		lines = append(lines, "* "+problem.String())
+	}
+	p.alertAdmins(alertConfigProblems, msgAlertConfigProblems, strings.Join(lines, "\n"))
+}
+
+// checkConfig reports the problems of the plugin settings for /jira
+// diagnostics.
+func (p *Plugin) checkConfig() error {
+	problems := p.getConfig().problems
+	if len(problems) == 0 {
+		return nil
+	}
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
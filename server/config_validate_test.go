@@ -0,0 +1,134 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestValidateConfig(t *testing.T) {
+	alertsChannelId := model.NewId()
+	archivedChannelId := model.NewId()
+	missingChannelId := model.NewId()
+	jdoeId := model.NewId()
+	missingUserId := model.NewId()
+
+	for name, tc := range map[string]struct {
+		siteURL  string
+		ec       externalConfig
+		problems []string
+	}{
+		"valid": {
+			siteURL: "https://mm.example.com",
+			ec: externalConfig{
+				Secret:               "5JlVk56KPxX629ujeU3MOuxaiwsPzLwh",
+				EncryptionKey:        "a long enough encryption key",
+				DelegatedAdmins:      jdoeId + ", system_user",
+				AdminAlertsChannelId: alertsChannelId,
+			},
+		},
+		"site URL": {
+			siteURL:  "mm.example.com",
+			problems: []string{`Site URL: "mm.example.com" is not an http or https URL.`},
+		},
+		"weak secrets": {
+			siteURL: "https://mm.example.com",
+			ec:      externalConfig{Secret: "secret", StatsSecret: "stats", EncryptionKey: "key"},
+			problems: []string{
+				"Webhook Secret: is shorter than 16 characters",
+				"Stats API Secret: is shorter than 16 characters",
+				"Encryption Key: is shorter than 16 characters",
+			},
+		},
+		"previous keys without a key": {
+			siteURL: "https://mm.example.com",
+			ec:      externalConfig{PreviousEncryptionKeys: "a long enough encryption key"},
+		},
+		"previous keys with the key": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{EncryptionKey: "a long enough encryption key", PreviousEncryptionKeys: "older encryption key, a long enough encryption key"},
+			problems: []string{"Previous Encryption Keys: include the current encryption key"},
+		},
+		"unknown delegated admin": {
+			siteURL: "https://mm.example.com",
+			ec:      externalConfig{DelegatedAdmins: "@jdoe, nobody, team_admin, " + missingUserId},
+			problems: []string{
+				`Delegated Admins: "@jdoe" is a username, which can change: list the user ID ` + "`" + jdoeId + "`",
+				`Delegated Admins: "nobody" is neither`,
+				`Delegated Admins: "team_admin" is neither`,
+				`Delegated Admins: "` + missingUserId + `" is not the ID of a Mattermost user.`,
+			},
+		},
+		"app link scheme": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{JiraAppLinkScheme: "https://"},
+			problems: []string{"Jira App Link Scheme: is the scheme of the Jira links"},
+		},
+		"invalid app link scheme": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{JiraAppLinkScheme: "jira app"},
+			problems: []string{`Jira App Link Scheme: "jira app" is not a URL scheme`},
+		},
+		"reaction labels": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{QuickLabels: "urgent, needs triage"},
+			problems: []string{`Quick Labels: the label "needs triage" contains a space`},
+		},
+		"issue emoji": {
+			siteURL: "https://mm.example.com",
+			ec:      externalConfig{ShowIssueEmoji: true, IssueEmoji: "type:Bug=beetle, resolution:Done=tada, status:Done=:white check:, priority:Blocker"},
+			problems: []string{
+				`Issue Emoji: "resolution:Done" must start with type:, status: or priority:`,
+				`Issue Emoji: the emoji "white check" of "status:Done" is not an emoji name`,
+				`Issue Emoji: "priority:Blocker" is not a ` + "`kind:name=emoji`" + ` entry`,
+			},
+		},
+		"issue emoji not shown": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{IssueEmoji: "type:Bug=beetle"},
+			problems: []string{"Issue Emoji: are only shown when Show Issue Emoji is true."},
+		},
+		"event forwarding hosts": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{EventForwardingHosts: "hooks.example.com, https://events.example.com"},
+			problems: []string{`Event Forwarding Hosts: "https://events.example.com" is not a host name`},
+		},
+		"channels": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{AdminAlertsChannelId: "town-square", FallbackChannelId: archivedChannelId},
+			problems: []string{`Admin Alerts Channel ID: "town-square" is not a channel ID.`, "Fallback Channel ID: channel ~old is archived"},
+		},
+		"missing channel": {
+			siteURL:  "https://mm.example.com",
+			ec:       externalConfig{FallbackChannelId: missingChannelId},
+			problems: []string{"Fallback Channel ID: channel " + missingChannelId + " was not found."},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString(tc.siteURL)}})
+			api.On("GetUserByUsername", "jdoe").Return(&model.User{Id: jdoeId, Username: "jdoe"}, nil)
+			api.On("GetUser", jdoeId).Return(&model.User{Id: jdoeId, Username: "jdoe"}, nil)
+			api.On("GetUser", missingUserId).Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetUserByUsername", mock.AnythingOfType("string")).Return(nil, &model.AppError{Message: "not found"})
+			api.On("GetChannel", alertsChannelId).Return(&model.Channel{Id: alertsChannelId, Name: "alerts"}, nil)
+			api.On("GetChannel", archivedChannelId).Return(&model.Channel{Id: archivedChannelId, Name: "old", DeleteAt: 1}, nil)
+			api.On("GetChannel", missingChannelId).Return(nil, &model.AppError{Message: "not found"})
+			p := &Plugin{}
+			p.SetAPI(api)
+
+			problems := p.validateConfig(tc.ec)
+			if assert.Len(t, problems, len(tc.problems)) {
+				for i, problem := range problems {
+					assert.Contains(t, problem.String(), tc.problems[i])
+				}
+			}
+		})
+	}
+}
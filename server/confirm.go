@@ -0,0 +1,208 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	prefixPendingConfirmation = "confirm_"
+
+	// Destructive commands must be confirmed within 5 minutes.
+	confirmationExpirySeconds = 5 * 60
+)
+
+// Commands that only run once their confirmation button is clicked.
+const (
+	confirmActionSubscribeDelete = "subscribe_delete"
+	confirmActionUninstall       = "uninstall"
+)
+
+// pendingConfirmation is a destructive command waiting for the user who ran
+// it to confirm it. Target is the id of the subscription to delete, or the
+// URL of the instance to uninstall.
+type pendingConfirmation struct {
+	Id        string `json:"id"`
+	UserId    string `json:"user_id"`
+	ChannelId string `json:"channel_id"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Name      string `json:"name,omitempty"`
+}
+
+// askConfirmation stores the pending command, and responds with a prompt to
+// confirm or cancel it.
+func (p *Plugin) askConfirmation(header *model.CommandArgs, pending *pendingConfirmation, prompt string) *model.CommandResponse {
+	pending.Id = model.NewId()
+	pending.UserId = header.UserId
+	pending.ChannelId = header.ChannelId
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	appErr := p.API.KVSetWithExpiry(prefixPendingConfirmation+pending.Id, data, confirmationExpirySeconds)
+	if appErr != nil {
+		return p.responsef(header, "Failed to store the command to confirm: %v", appErr)
+	}
+
+	action := func(name string, confirm bool) *model.PostAction {
+		return &model.PostAction{
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL: p.GetPluginURLPath() + routeAPIConfirmAction,
+				Context: map[string]interface{}{
+					"confirmation_id": pending.Id,
+					"confirm":         confirm,
+				},
+			},
+		}
+	}
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: header.ChannelId,
+		Message:   prompt,
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			action("Confirm", true),
+			action("Cancel", false),
+		},
+	}})
+	_ = p.API.SendEphemeralPost(header.UserId, post)
+	return &model.CommandResponse{}
+}
+
+// httpAPIConfirmAction handles the buttons of a confirmation prompt. The
+// pending command is consumed by the first click, whether it confirms it or
+// not.
+func httpAPIConfirmAction(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	confirmationId, _ := request.Context["confirmation_id"].(string)
+	confirm, _ := request.Context["confirm"].(bool)
+
+	data, appErr := p.API.KVGet(prefixPendingConfirmation + confirmationId)
+	if appErr != nil {
+		return http.StatusInternalServerError, appErr
+	}
+	message := "This command has expired, please run it again."
+	if len(data) != 0 {
+		pending := pendingConfirmation{}
+		err := json.Unmarshal(data, &pending)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if pending.UserId != mattermostUserId {
+			return http.StatusForbidden, errors.New("not the user of the command")
+		}
+		_ = p.API.KVDelete(prefixPendingConfirmation + confirmationId)
+
+		message = "Cancelled."
+		if confirm {
+			message, err = p.runConfirmedCommand(pending)
+			if err != nil {
+				message = err.Error()
+			}
+		}
+	}
+
+	p.API.UpdateEphemeralPost(mattermostUserId, &model.Post{
+		Id:        request.PostId,
+		UserId:    p.getUserID(),
+		ChannelId: request.ChannelId,
+		Message:   message,
+	})
+
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{})
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// runConfirmedCommand runs a confirmed command, checking again that the user
+// is allowed to, and returns the text to respond with.
+func (p *Plugin) runConfirmedCommand(pending pendingConfirmation) (string, error) {
+	switch pending.Action {
+	case confirmActionSubscribeDelete:
+		err := p.hasPermissionToManageSubscription(pending.UserId, pending.ChannelId)
+		if err != nil {
+			return "", errors.WithMessage(err, "you are not allowed to delete Jira subscriptions")
+		}
+		err = p.removeChannelSubscription(pending.Target)
+		if err != nil {
+			return "", errors.WithMessagef(err, "failed to delete subscription %q", pending.Name)
+		}
+		by := pending.UserId
+		if user, appErr := p.API.GetUser(pending.UserId); appErr == nil {
+			by = "@" + user.Username
+		}
+		_, _ = p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: pending.ChannelId,
+			Message:   p.localize(p.channelLocale(pending.ChannelId), msgSubscriptionRemoved, pending.Name, by),
+		})
+		return fmt.Sprintf("Subscription %q was deleted.", pending.Name), nil
+
+	case confirmActionUninstall:
+		authorized, err := authorizedSystemAdmin(p, pending.UserId)
+		if err != nil {
+			return "", err
+		}
+		if !authorized {
+			return "", errors.New("`/jira uninstall` can only be run by a System Administrator.")
+		}
+		ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+		if err != nil || ji.GetURL() != pending.Target {
+			return "", errors.Errorf("%s is no longer the current Jira instance.", pending.Target)
+		}
+		return p.uninstallInstance(ji)
+	}
+	return "", errors.Errorf("unknown command %q", pending.Action)
+}
+
+func executeSubscribeDelete(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name := strings.Trim(strings.Join(args, " "), `"`)
+	if name == "" {
+		return p.responsef(header, "Please use `/jira subscribe delete <subscription name>`.")
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to delete Jira subscriptions: %v", err)
+	}
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.Name != name {
+			continue
+		}
+		return p.askConfirmation(header, &pendingConfirmation{
+			Action: confirmActionSubscribeDelete,
+			Target: sub.Id,
+			Name:   sub.Name,
+		}, fmt.Sprintf("Delete subscription %q? Its Jira events will no longer be posted to this channel.", sub.Name))
+	}
+	return p.responsef(header, "There is no subscription named %q in this channel.", name)
+}
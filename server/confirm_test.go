@@ -0,0 +1,74 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmAction(t *testing.T) {
+	kv := map[string][]byte{}
+	var prompt, updated *model.Post
+	api := &plugintest.API{}
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(confirmationExpirySeconds)).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	api.On("SendEphemeralPost", "userid", mock.Anything).Run(func(args mock.Arguments) {
+		prompt = args.Get(1).(*model.Post)
+	}).Return(nil)
+	api.On("UpdateEphemeralPost", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(1).(*model.Post)
+	}).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	header := &model.CommandArgs{UserId: "userid", ChannelId: "channelid"}
+	p.askConfirmation(header, &pendingConfirmation{Action: confirmActionSubscribeDelete, Target: "subid", Name: "Bugs"}, "Delete subscription \"Bugs\"?")
+	require.NotNil(t, prompt)
+	require.Len(t, kv, 1)
+	actions := prompt.Attachments()[0].Actions
+	require.Len(t, actions, 2)
+	assert.Equal(t, "Confirm", actions[0].Name)
+	assert.Equal(t, true, actions[0].Integration.Context["confirm"])
+
+	click := func(userId string, action *model.PostAction) int {
+		request := model.PostActionIntegrationRequest{
+			UserId:    userId,
+			PostId:    "postid",
+			ChannelId: "channelid",
+			Context:   action.Integration.Context,
+		}
+		r := httptest.NewRequest(http.MethodPost, routeAPIConfirmAction, bytes.NewReader(request.ToJson()))
+		r.Header.Set("Mattermost-User-Id", userId)
+		w := httptest.NewRecorder()
+		status, _ := httpAPIConfirmAction(p, w, r)
+		return status
+	}
+
+	assert.Equal(t, http.StatusForbidden, click("otheruserid", actions[0]))
+	assert.Len(t, kv, 1, "another user can't consume the command")
+	assert.Nil(t, updated)
+
+	assert.Equal(t, http.StatusOK, click("userid", actions[1]))
+	assert.Empty(t, kv)
+	require.NotNil(t, updated)
+	assert.Equal(t, "postid", updated.Id)
+	assert.Equal(t, "Cancelled.", updated.Message)
+
+	assert.Equal(t, http.StatusOK, click("userid", actions[0]))
+	assert.Contains(t, updated.Message, "expired")
+}
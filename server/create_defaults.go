@@ -0,0 +1,176 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const keyCreateDefaults = "create_defaults_"
+
+// CreateDefaults are the project and issue type that the create issue dialog
+// opens with in a channel. Without them, the dialog opens with the project
+// linked to the channel.
+type CreateDefaults struct {
+	ProjectKey    string `json:"project_key"`
+	IssueTypeId   string `json:"issue_type_id,omitempty"`
+	IssueTypeName string `json:"issue_type_name,omitempty"`
+}
+
+func (p *Plugin) loadCreateDefaults(ji Instance, channelId string) (*CreateDefaults, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyCreateDefaults+channelId))
+	if appErr != nil {
+		return nil, appErr
+	}
+	defaults := CreateDefaults{}
+	if len(data) == 0 {
+		// The project linked to the channel, if any
+		link, err := p.loadChannelProject(ji, channelId)
+		if err != nil {
+			return nil, err
+		}
+		defaults.ProjectKey = link.ProjectKey
+		return &defaults, nil
+	}
+	err := json.Unmarshal(data, &defaults)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load the create issue defaults of channel "+channelId)
+	}
+	return &defaults, nil
+}
+
+func (p *Plugin) storeCreateDefaults(ji Instance, channelId string, defaults *CreateDefaults) error {
+	key := keyWithInstance(ji, keyCreateDefaults+channelId)
+	if defaults == nil {
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSet(key, data)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func executeCreateDefaults(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira create defaults <project-key> [issue type]`, or `/jira create defaults clear`."
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeCreateDefaults: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+
+	if len(args) == 0 {
+		defaults, err := p.loadCreateDefaults(ji, header.ChannelId)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if defaults.ProjectKey == "" {
+			return p.responsef(header, "This channel has no defaults for new issues. "+usage)
+		}
+		if defaults.IssueTypeName == "" {
+			return p.responsef(header, "New issues created in this channel default to project **%s**.", defaults.ProjectKey)
+		}
+		return p.responsef(header, "New issues created in this channel default to project **%s**, issue type **%s**.",
+			defaults.ProjectKey, defaults.IssueTypeName)
+	}
+
+	err = p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to change the defaults of this channel: %v", err)
+	}
+
+	if len(args) == 1 && args[0] == "clear" {
+		err = p.storeCreateDefaults(ji, header.ChannelId, nil)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		return p.responsef(header, "Cleared the defaults for new issues in this channel.")
+	}
+
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	projectKey := strings.ToUpper(args[0])
+	project, err := client.GetProject(projectKey)
+	if err != nil {
+		return p.responsef(header, "Failed to get Jira project %s: %v", projectKey, err)
+	}
+
+	defaults := &CreateDefaults{ProjectKey: project.Key}
+	if len(args) > 1 {
+		name := strings.Join(args[1:], " ")
+		for _, issueType := range project.IssueTypes {
+			if strings.EqualFold(issueType.Name, name) {
+				defaults.IssueTypeId = issueType.ID
+				defaults.IssueTypeName = issueType.Name
+			}
+		}
+		if defaults.IssueTypeId == "" {
+			return p.responsef(header, "Project %s has no issue type %q.", project.Key, name)
+		}
+	}
+
+	err = p.storeCreateDefaults(ji, header.ChannelId, defaults)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if defaults.IssueTypeName == "" {
+		return p.responsef(header, "New issues created in this channel now default to project **%s**.", defaults.ProjectKey)
+	}
+	return p.responsef(header, "New issues created in this channel now default to project **%s**, issue type **%s**.",
+		defaults.ProjectKey, defaults.IssueTypeName)
+}
+
+func httpAPIGetCreateDefaults(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	channelId := r.FormValue("channel_id")
+	if channelId == "" {
+		return http.StatusBadRequest, errors.New("channel_id is required")
+	}
+	p := ji.GetPlugin()
+	_, appErr := p.API.GetChannelMember(channelId, mattermostUserId)
+	if appErr != nil {
+		return http.StatusForbidden, errors.New("Not a member of the channel specified")
+	}
+
+	defaults, err := p.loadCreateDefaults(ji, channelId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	b, _ := json.Marshal(defaults)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDefaults(t *testing.T) {
+	api := &plugintest.API{}
+	p := Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{&p}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+
+	kv := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte { return kv[key] },
+		func(key string) *model.AppError { return nil })
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	})
+
+	defaults, err := p.loadCreateDefaults(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, &CreateDefaults{}, defaults)
+
+	expected := &CreateDefaults{ProjectKey: "TES", IssueTypeId: "10001", IssueTypeName: "Bug"}
+	require.NoError(t, p.storeCreateDefaults(ji, "channelId", expected))
+	defaults, err = p.loadCreateDefaults(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, expected, defaults)
+
+	require.NoError(t, p.storeCreateDefaults(ji, "channelId", nil))
+	defaults, err = p.loadCreateDefaults(ji, "channelId")
+	require.NoError(t, err)
+	assert.Equal(t, &CreateDefaults{}, defaults)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// encryptedCredentialPrefix marks the credentials stored encrypted, other
+// values are plaintext stored before an encryption key was configured.
+// legacyEncryptedCredentialPrefix marks the credentials encrypted with the
+// keys of the first releases, a bare SHA-256 of the setting. They are still
+// read, and encrypted again the next time they are used.
+const (
+	encryptedCredentialPrefix       = "enc:v2:"
+	legacyEncryptedCredentialPrefix = "enc:v1:"
+)
+
+// credentialKeyInfo binds the keys derived from the settings to their use.
+const credentialKeyInfo = "mattermost-plugin-jira credentials"
+
+// credentialCipher encrypts the credentials stored in the KV store, like the
+// users' OAuth1 tokens, the Atlassian Connect shared secret, the RSA key of
+// the OAuth1 consumer and the secret of the auth tokens, with AES-GCM keys
+// derived from the EncryptionKey and PreviousEncryptionKeys settings.
+type credentialCipher struct {
+	// encryptKey is derived from EncryptionKey, and is nil when it's empty:
+	// the credentials are then stored as plaintext.
+	encryptKey []byte
+
+	// keys and legacyKeys are derived from EncryptionKey and
+	// PreviousEncryptionKeys, current first, and are all tried to decrypt.
+	keys       [][]byte
+	legacyKeys [][]byte
+}
+
+func newCredentialCipher(current string, previous []string) credentialCipher {
+	c := credentialCipher{}
+	for i, key := range append([]string{current}, previous...) {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		derived := deriveCredentialKey(key)
+		if i == 0 {
+			c.encryptKey = derived
+		}
+		c.keys = append(c.keys, derived)
+		legacy := sha256.Sum256([]byte(key))
+		c.legacyKeys = append(c.legacyKeys, legacy[:])
+	}
+	return c
+}
+
+// deriveCredentialKey derives an AES-256 key from a key setting with HKDF.
+func deriveCredentialKey(key string) []byte {
+	derived := make([]byte, 32)
+	_, err := io.ReadFull(hkdf.New(sha256.New, []byte(key), nil, []byte(credentialKeyInfo)), derived)
+	if err != nil {
+		// HKDF-SHA256 can derive up to 8160 bytes.
+		panic(err)
+	}
+	return derived
+}
+
+func (c credentialCipher) encrypt(plain string) (string, error) {
+	if c.encryptKey == nil || plain == "" {
+		return plain, nil
+	}
+	sealed, err := encrypt([]byte(plain), c.encryptKey)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to encrypt credentials")
+	}
+	encoded, err := encode(sealed)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to encrypt credentials")
+	}
+	return encryptedCredentialPrefix + encoded, nil
+}
+
+// decrypt returns the plaintext of value. stale is true if value is not
+// stored the way encrypt would store it, and should be stored again. Without
+// a current key, values encrypted with a previous key are stale: they are
+// stored again as plaintext.
+func (c credentialCipher) decrypt(value string) (plain string, stale bool, err error) {
+	keys, legacy := c.keys, false
+	encoded := strings.TrimPrefix(value, encryptedCredentialPrefix)
+	if encoded == value {
+		keys, legacy = c.legacyKeys, true
+		encoded = strings.TrimPrefix(value, legacyEncryptedCredentialPrefix)
+		if encoded == value {
+			return value, c.encryptKey != nil && value != "", nil
+		}
+	}
+	decoded, err := decode(encoded)
+	if err != nil {
+		return "", false, errors.WithMessage(err, "failed to decrypt credentials")
+	}
+	for i, key := range keys {
+		plainBytes, err := decrypt(decoded, key)
+		if err == nil {
+			return string(plainBytes), legacy || i > 0 || c.encryptKey == nil, nil
+		}
+	}
+	return "", false, errors.New("failed to decrypt credentials: no configured encryption key matches, " +
+		"please add the previous key to PreviousEncryptionKeys")
+}
+
+func (c credentialCipher) encryptUser(jiraUser JIRAUser) (JIRAUser, error) {
+	var err error
+	jiraUser.Oauth1AccessToken, err = c.encrypt(jiraUser.Oauth1AccessToken)
+	if err != nil {
+		return JIRAUser{}, err
+	}
+	jiraUser.Oauth1AccessSecret, err = c.encrypt(jiraUser.Oauth1AccessSecret)
+	if err != nil {
+		return JIRAUser{}, err
+	}
+	return jiraUser, nil
+}
+
+func (c credentialCipher) decryptUser(jiraUser JIRAUser) (JIRAUser, bool, error) {
+	token, staleToken, err := c.decrypt(jiraUser.Oauth1AccessToken)
+	if err != nil {
+		return JIRAUser{}, false, err
+	}
+	secret, staleSecret, err := c.decrypt(jiraUser.Oauth1AccessSecret)
+	if err != nil {
+		return JIRAUser{}, false, err
+	}
+	jiraUser.Oauth1AccessToken = token
+	jiraUser.Oauth1AccessSecret = secret
+	return jiraUser, staleToken || staleSecret, nil
+}
+
+// encryptInstance returns the value to store for ji, a copy of a Cloud
+// instance with its security context encrypted.
+func (c credentialCipher) encryptInstance(ji Instance) (interface{}, error) {
+	jci, ok := ji.(*jiraCloudInstance)
+	if !ok {
+		return ji, nil
+	}
+	encrypted := *jci
+	var err error
+	encrypted.RawAtlassianSecurityContext, err = c.encrypt(jci.RawAtlassianSecurityContext)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialCipher(t *testing.T) {
+	t.Run("no key", func(t *testing.T) {
+		c := newCredentialCipher("", nil)
+		encrypted, err := c.encrypt("token")
+		require.NoError(t, err)
+		assert.Equal(t, "token", encrypted)
+
+		plain, stale, err := c.decrypt("token")
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.False(t, stale)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		c := newCredentialCipher("key", nil)
+		encrypted, err := c.encrypt("token")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(encrypted, encryptedCredentialPrefix))
+		assert.NotContains(t, encrypted, "token")
+
+		plain, stale, err := c.decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.False(t, stale)
+	})
+
+	t.Run("plaintext is stale", func(t *testing.T) {
+		c := newCredentialCipher("key", nil)
+		plain, stale, err := c.decrypt("token")
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.True(t, stale)
+
+		_, stale, err = c.decrypt("")
+		require.NoError(t, err)
+		assert.False(t, stale)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		encrypted, err := newCredentialCipher("old", nil).encrypt("token")
+		require.NoError(t, err)
+
+		c := newCredentialCipher("new", []string{"other", " old "})
+		plain, stale, err := c.decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.True(t, stale)
+
+		_, _, err = newCredentialCipher("new", nil).decrypt(encrypted)
+		require.Error(t, err)
+	})
+
+	t.Run("previous keys without current key", func(t *testing.T) {
+		encrypted, err := newCredentialCipher("old", nil).encrypt("token")
+		require.NoError(t, err)
+
+		c := newCredentialCipher("", []string{"old"})
+		plain, stale, err := c.decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.True(t, stale)
+
+		stored, err := c.encrypt(plain)
+		require.NoError(t, err)
+		assert.Equal(t, "token", stored)
+	})
+
+	t.Run("legacy key derivation", func(t *testing.T) {
+		legacyKey := sha256.Sum256([]byte("key"))
+		sealed, err := encrypt([]byte("token"), legacyKey[:])
+		require.NoError(t, err)
+		encoded, err := encode(sealed)
+		require.NoError(t, err)
+
+		c := newCredentialCipher("key", nil)
+		plain, stale, err := c.decrypt(legacyEncryptedCredentialPrefix + encoded)
+		require.NoError(t, err)
+		assert.Equal(t, "token", plain)
+		assert.True(t, stale)
+
+		_, _, err = c.decrypt(encryptedCredentialPrefix + encoded)
+		require.Error(t, err)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		c := newCredentialCipher("key", nil)
+		jiraUser := JIRAUser{Oauth1AccessToken: "token", Oauth1AccessSecret: "secret"}
+		encrypted, err := c.encryptUser(jiraUser)
+		require.NoError(t, err)
+		assert.NotEqual(t, jiraUser.Oauth1AccessToken, encrypted.Oauth1AccessToken)
+		assert.NotEqual(t, jiraUser.Oauth1AccessSecret, encrypted.Oauth1AccessSecret)
+
+		decrypted, stale, err := c.decryptUser(encrypted)
+		require.NoError(t, err)
+		assert.False(t, stale)
+		assert.Equal(t, jiraUser, decrypted)
+	})
+}
+
+func TestStoredSecretsEncrypted(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return kv[key]
+	}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("LogDebug", mock.AnythingOfType("string")).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.credentials = newCredentialCipher("key", nil)
+	})
+	store := NewStore(p)
+
+	t.Run("new secrets are encrypted", func(t *testing.T) {
+		secret, err := store.EnsureAuthTokenEncryptSecret()
+		require.NoError(t, err)
+		require.Len(t, secret, 32)
+		assert.True(t, strings.HasPrefix(string(kv[keyTokenSecret]), encryptedCredentialPrefix))
+		again, err := store.EnsureAuthTokenEncryptSecret()
+		require.NoError(t, err)
+		assert.Equal(t, secret, again)
+
+		rsaKey, err := store.EnsureRSAKey()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(kv[keyRSAKey]), encryptedCredentialPrefix))
+		againKey, err := store.EnsureRSAKey()
+		require.NoError(t, err)
+		assert.Equal(t, rsaKey.D, againKey.D)
+	})
+
+	t.Run("plaintext secrets are encrypted on read", func(t *testing.T) {
+		kv[keyTokenSecret] = []byte("0123456789abcdef0123456789abcdef")
+		secret, err := store.EnsureAuthTokenEncryptSecret()
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789abcdef0123456789abcdef", string(secret))
+		assert.True(t, strings.HasPrefix(string(kv[keyTokenSecret]), encryptedCredentialPrefix))
+
+		rsaKey, err := store.EnsureRSAKey()
+		require.NoError(t, err)
+		kv[keyRSAKey], err = json.Marshal(rsaKey)
+		require.NoError(t, err)
+		migrated, err := store.EnsureRSAKey()
+		require.NoError(t, err)
+		assert.Equal(t, rsaKey.D, migrated.D)
+		assert.True(t, strings.HasPrefix(string(kv[keyRSAKey]), encryptedCredentialPrefix))
+	})
+
+	t.Run("a key that doesn't match is an error", func(t *testing.T) {
+		p.updateConfig(func(conf *config) {
+			conf.credentials = newCredentialCipher("other", nil)
+		})
+		_, err := store.EnsureAuthTokenEncryptSecret()
+		require.Error(t, err)
+		_, err = store.EnsureRSAKey()
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const headerCSRFToken = "X-Jira-CSRF-Token"
+
+// isCSRFProtectedRoute returns true for the routes that change state on
+// behalf of the webapp's user.
+func isCSRFProtectedRoute(path string) bool {
+	switch path {
+	case routeAPICreateIssue,
+		routeAPIAttachCommentToIssue,
+		routeAPISubscribeThread,
+		routeAPISubscriptionsBulk,
+		routeAPIWebhookSecret:
+		return true
+	}
+	return strings.HasPrefix(path, routeAPISubscriptionsChannel)
+}
+
+// csrfToken returns the CSRF token of a Mattermost session, derived from the
+// session ID so that it is only valid for that session.
+func (p *Plugin) csrfToken(sessionId string) (string, error) {
+	secret, err := p.secretsStore.EnsureAuthTokenEncryptSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte("csrf:" + sessionId))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// checkCSRFToken verifies that the state-changing requests authenticated with
+// a Mattermost session carry the session's CSRF token.
+func (p *Plugin) checkCSRFToken(c *plugin.Context, r *http.Request) (int, error) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return http.StatusOK, nil
+	}
+	if c == nil || c.SessionId == "" {
+		return http.StatusOK, nil
+	}
+
+	expected, err := p.csrfToken(c.SessionId)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to verify CSRF token")
+	}
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(headerCSRFToken))) {
+		return http.StatusForbidden, errors.New("invalid CSRF token")
+	}
+	return http.StatusOK, nil
+}
+
+func httpAPIGetCSRFToken(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET")
+	}
+
+	// The token is only issued to the sessions of the webapp
+	if c == nil || c.SessionId == "" {
+		return http.StatusUnauthorized, errors.New("not authorized")
+	}
+
+	token, err := p.csrfToken(c.SessionId)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to create CSRF token")
+	}
+
+	b, _ := json.Marshal(struct {
+		Token string `json:"token"`
+	}{
+		Token: token,
+	})
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFToken(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", keyTokenSecret).Return([]byte("0123456789abcdef0123456789abcdef"), nil)
+
+	p := Plugin{}
+	p.SetAPI(api)
+	p.secretsStore = NewStore(&p)
+
+	getToken := func(sessionId string) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, routeAPICSRFToken, nil)
+		r.Header.Set("Mattermost-User-Id", "userId")
+		status, err := httpAPIGetCSRFToken(&p, &plugin.Context{SessionId: sessionId}, w, r)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		resp := struct {
+			Token string `json:"token"`
+		}{}
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&resp))
+		require.NotEmpty(t, resp.Token)
+		return resp.Token
+	}
+	token := getToken("session1")
+	assert.Equal(t, token, getToken("session1"))
+	assert.NotEqual(t, token, getToken("session2"))
+
+	for name, tc := range map[string]struct {
+		method, path, sessionId, token string
+		expectedStatus                 int
+	}{
+		"valid token":         {http.MethodPost, routeAPICreateIssue, "session1", token, http.StatusOK},
+		"missing token":       {http.MethodPost, routeAPICreateIssue, "session1", "", http.StatusForbidden},
+		"other session token": {http.MethodDelete, routeAPISubscriptionsChannel + "/id", "session2", token, http.StatusForbidden},
+		"GET":                 {http.MethodGet, routeAPISubscriptionsChannel + "/id", "session1", "", http.StatusOK},
+		"no session":          {http.MethodPost, routeAPISubscriptionsBulk, "", "", http.StatusOK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader("{}"))
+			if tc.token != "" {
+				r.Header.Set(headerCSRFToken, tc.token)
+			}
+			status, _ := p.checkCSRFToken(&plugin.Context{SessionId: tc.sessionId}, r)
+			assert.Equal(t, tc.expectedStatus, status)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// notifyCheck is a condition of the direct messages of Jira events, as
+// checked for a user by /jira debug notify.
+type notifyCheck struct {
+	ok      bool
+	message string
+}
+
+// executeDebugNotify explains which direct messages a user would receive for
+// the events of an issue, following the checks of PostNotifications.
+func executeDebugNotify(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira debug notify` can only be run by a system administrator.")
+	}
+	if len(args) != 2 {
+		return p.responsef(header, "Please use `/jira debug notify @user <issue-key>`.")
+	}
+	username := strings.TrimPrefix(args[0], "@")
+	issueKey := strings.ToUpper(args[1])
+
+	user, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return p.responsef(header, "User @%s was not found.", username)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeDebugNotify: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+
+	checks := []notifyCheck{}
+	resp := fmt.Sprintf("#### Direct messages of %s for @%s\n", issueKey, username)
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, user.Id)
+	if err != nil {
+		checks = append(checks, notifyCheck{false, "@" + username + " is not connected to Jira, so they get no direct messages."})
+		return p.responsef(header, "%s%s", resp, formatNotifyChecks(checks))
+	}
+
+	// The Jira users of the events are mapped back to Mattermost users,
+	// preferably by account ID
+	jiraUserId := jiraUser.AccountID
+	if jiraUserId == "" {
+		jiraUserId = jiraUser.Name
+	}
+	mappedUserId, err := p.userStore.LoadMattermostUserId(ji, jiraUserId)
+	switch {
+	case err != nil:
+		checks = append(checks, notifyCheck{false, fmt.Sprintf("Jira user %s is not mapped back to @%s: %v. Reconnecting the account with `/jira connect` restores the mapping.", mdUser(&jiraUser.User), username, err)})
+	case mappedUserId != user.Id:
+		checks = append(checks, notifyCheck{false, fmt.Sprintf("Jira user %s is mapped to another Mattermost user, who gets the direct messages instead. Reconnecting the account with `/jira connect` restores the mapping.", mdUser(&jiraUser.User))})
+	default:
+		checks = append(checks, notifyCheck{true, fmt.Sprintf("Connected as Jira user %s.", mdUser(&jiraUser.User))})
+	}
+
+	if jiraUser.Settings == nil || !jiraUser.Settings.Notifications {
+		checks = append(checks, notifyCheck{false, "Notifications are turned off, run `/jira settings notifications on` as @" + username + " to turn them on."})
+	} else {
+		checks = append(checks, notifyCheck{true, "Notifications are turned on."})
+	}
+
+	var issue *jira.Issue
+	client, err := ji.GetClient(jiraUser)
+	if err == nil {
+		issue, err = client.GetIssue(issueKey, &jira.GetQueryOptions{Fields: "assignee"})
+	}
+	if err != nil {
+		checks = append(checks, notifyCheck{false, fmt.Sprintf("@%s can't view %s in Jira: %v", username, issueKey, err)})
+		return p.responsef(header, "%s%s", resp, formatNotifyChecks(checks))
+	}
+	checks = append(checks, notifyCheck{true, fmt.Sprintf("@%s can view %s in Jira.", username, issueKey)})
+	checks = append(checks, issueNotifyChecks(&jiraUser.User, issue)...)
+
+	return p.responsef(header, "%s%s", resp, formatNotifyChecks(checks))
+}
+
+// issueNotifyChecks explains which events of an issue are sent to a Jira user
+// as direct messages, given the current assignee of the issue.
+func issueNotifyChecks(user *jira.User, issue *jira.Issue) []notifyCheck {
+	var assignee *jira.User
+	if issue.Fields != nil {
+		assignee = issue.Fields.Assignee
+	}
+
+	checks := []notifyCheck{}
+	if sameJiraUser(user, assignee) {
+		checks = append(checks, notifyCheck{true, "Assigned to the issue, so they get the comments of the other users."})
+	} else {
+		checks = append(checks, notifyCheck{false, "Not assigned to the issue, so they only get the comments that mention them. They get a message when another user assigns them."})
+	}
+
+	mentions := []string{}
+	if user.AccountID != "" {
+		mentions = append(mentions, "`[~accountid:"+user.AccountID+"]`")
+	}
+	if user.Name != "" {
+		mentions = append(mentions, "`[~"+user.Name+"]`")
+	}
+	checks = append(checks, notifyCheck{true, "They get the comments and comment edits of the other users that mention them as " + strings.Join(mentions, " or ") + "."})
+	return checks
+}
+
+func formatNotifyChecks(checks []notifyCheck) string {
+	lines := []string{}
+	for _, check := range checks {
+		icon := ":white_check_mark:"
+		if !check.ok {
+			icon = ":x:"
+		}
+		lines = append(lines, "* "+icon+" "+check.message)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueNotifyChecks(t *testing.T) {
+	user := &jira.User{AccountID: "5c5f880629be9642ba529340", Name: "jdoe"}
+
+	checks := issueNotifyChecks(user, &jira.Issue{Fields: &jira.IssueFields{Assignee: &jira.User{AccountID: "5c5f880629be9642ba529340"}}})
+	require.Len(t, checks, 2)
+	assert.True(t, checks[0].ok)
+	assert.Contains(t, checks[0].message, "Assigned to the issue")
+	assert.Contains(t, checks[1].message, "`[~accountid:5c5f880629be9642ba529340]` or `[~jdoe]`")
+
+	checks = issueNotifyChecks(user, &jira.Issue{Fields: &jira.IssueFields{Assignee: &jira.User{AccountID: "other"}}})
+	require.Len(t, checks, 2)
+	assert.False(t, checks[0].ok)
+	assert.Contains(t, checks[0].message, "Not assigned to the issue")
+
+	checks = issueNotifyChecks(&jira.User{Name: "jdoe"}, &jira.Issue{})
+	assert.False(t, checks[0].ok)
+	assert.Equal(t, "* :x: "+checks[0].message+"\n* :white_check_mark: They get the comments and comment edits of the other users that mention them as `[~jdoe]`.",
+		formatNotifyChecks(checks))
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyDiagnostics     = "diagnostics_check"
+	diagnosticsTimeout = 10 * time.Second
+)
+
+type diagnosticResult struct {
+	Name string
+	Err  error
+}
+
+func executeDiagnostics(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira diagnostics` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.help(header)
+	}
+
+	return p.responsef(header, "%s", formatDiagnostics(p.runDiagnostics(header.UserId)))
+}
+
+// runDiagnostics checks the configuration and the services the plugin
+// depends on. Jira credentials are checked with the account of the
+// Mattermost user running the diagnostics.
+func (p *Plugin) runDiagnostics(mattermostUserId string) []diagnosticResult {
+	results := []diagnosticResult{
+		{"Mattermost Site URL", p.CheckSiteURL()},
+		{"Webhook secret", p.checkWebhookSecret()},
+		{"Plugin settings", p.checkConfig()},
+		{"Bot user", p.checkBotUser()},
+		{"KV store", p.checkKVStore()},
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		err = errors.WithMessage(err, "no Jira instance installed, use `/jira install`")
+		return append(results,
+			diagnosticResult{"Jira connectivity", err},
+			diagnosticResult{"Jira credentials", err})
+	}
+
+	return append(results,
+		diagnosticResult{"Jira connectivity (" + ji.GetURL() + ")", checkJiraConnectivity(ji)},
+		diagnosticResult{"Jira credentials", p.checkJiraCredentials(ji, mattermostUserId)})
+}
+
+func (p *Plugin) checkWebhookSecret() error {
+	if p.getConfig().Secret == "" {
+		return errors.New("not set, regenerate it in the plugin settings")
+	}
+	return nil
+}
+
+func (p *Plugin) checkBotUser() error {
+	botUserID := p.getUserID()
+	if botUserID == "" {
+		return errors.New("not created")
+	}
+	user, appErr := p.API.GetUser(botUserID)
+	if appErr != nil {
+		return appErr
+	}
+	if user.DeleteAt != 0 {
+		return errors.Errorf("@%s is deactivated", user.Username)
+	}
+	return nil
+}
+
+// checkKVStore writes, reads back and deletes a value.
+func (p *Plugin) checkKVStore() error {
+	value := []byte(model.NewId())
+	appErr := p.API.KVSet(keyDiagnostics, value)
+	if appErr != nil {
+		return errors.WithMessage(appErr, "failed to write")
+	}
+	stored, appErr := p.API.KVGet(keyDiagnostics)
+	if appErr != nil {
+		return errors.WithMessage(appErr, "failed to read")
+	}
+	if !bytes.Equal(stored, value) {
+		return errors.New("read a different value than written")
+	}
+	appErr = p.API.KVDelete(keyDiagnostics)
+	if appErr != nil {
+		return errors.WithMessage(appErr, "failed to delete")
+	}
+	return nil
+}
+
+// checkJiraConnectivity fetches the server info, which Jira serves without
+// authentication.
+func checkJiraConnectivity(ji Instance) error {
+	client := &http.Client{Timeout: diagnosticsTimeout}
+	resp, err := client.Get(strings.TrimRight(ji.GetURL(), "/") + "/rest/api/2/serverInfo")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *Plugin) checkJiraCredentials(ji Instance, mattermostUserId string) error {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return errors.New("your account is not connected to Jira, use `/jira connect`")
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err
+	}
+	_, err = client.GetSelf()
+	return err
+}
+
+func formatDiagnostics(results []diagnosticResult) string {
+	out := "###### Jira plugin diagnostics\n"
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			out += fmt.Sprintf("* :x: %s: %v\n", r.Name, r.Err)
+		} else {
+			out += fmt.Sprintf("* :white_check_mark: %s\n", r.Name)
+		}
+	}
+	if failed == 0 {
+		out += "\nAll checks passed."
+	} else {
+		out += fmt.Sprintf("\n%v of %v checks failed.", failed, len(results))
+	}
+	return out
+}
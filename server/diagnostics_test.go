@@ -0,0 +1,81 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type diagnosticsTestInstance struct {
+	jiraTestInstance
+	url string
+}
+
+func (ji diagnosticsTestInstance) GetURL() string {
+	return ji.url
+}
+
+func TestCheckJiraConnectivity(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/serverInfo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"version":"8.5.0"}`))
+	}))
+	defer ts.Close()
+
+	assert.NoError(t, checkJiraConnectivity(&diagnosticsTestInstance{url: ts.URL}))
+	assert.NoError(t, checkJiraConnectivity(&diagnosticsTestInstance{url: ts.URL + "/"}))
+	assert.Error(t, checkJiraConnectivity(&diagnosticsTestInstance{url: ts.URL + "/context"}))
+}
+
+func TestCheckKVStore(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		api := &plugintest.API{}
+		var stored []byte
+		api.On("KVSet", keyDiagnostics, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			stored = args.Get(1).([]byte)
+		})
+		api.On("KVGet", keyDiagnostics).Return(func(string) []byte { return stored }, nil)
+		api.On("KVDelete", keyDiagnostics).Return(nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		assert.NoError(t, p.checkKVStore())
+		api.AssertCalled(t, "KVDelete", keyDiagnostics)
+	})
+
+	t.Run("value lost", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVSet", keyDiagnostics, mock.Anything).Return(nil)
+		api.On("KVGet", keyDiagnostics).Return(nil, nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		assert.Error(t, p.checkKVStore())
+	})
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	out := formatDiagnostics([]diagnosticResult{
+		{"Webhook secret", nil},
+		{"KV store", errors.New("failed to write")},
+	})
+	require.Equal(t, "###### Jira plugin diagnostics\n"+
+		"* :white_check_mark: Webhook secret\n"+
+		"* :x: KV store: failed to write\n"+
+		"\n1 of 2 checks failed.", out)
+
+	out = formatDiagnostics([]diagnosticResult{{"Webhook secret", nil}})
+	require.Contains(t, out, "All checks passed.")
+}
@@ -0,0 +1,360 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyDiscussedChannels     = "discussed_channels"
+	prefixDiscussedIssues    = "discussed_issues_"
+	discussedIssuesMaxKeys   = 200
+	discussedIssuesMaxListed = 25
+)
+
+var defaultDiscussedIssuesSchedule = channelReportSchedule{Every: "monday", Hour: 9}
+
+// reDiscussedIssueKey matches the issue keys linked by the autolinks of the
+// project keys, also in the browse URLs of the issues.
+var reDiscussedIssueKey = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// discussedChannel is a channel whose mentions of Jira issues are summarized
+// on a schedule, with the current state of the issues queried with the
+// credentials of the user who turned the summary on.
+type discussedChannel struct {
+	ChannelId string                `json:"channel_id"`
+	CreatorId string                `json:"creator_id"`
+	Schedule  channelReportSchedule `json:"schedule"`
+
+	// NextRun is when the summary is posted next, in milliseconds.
+	NextRun int64 `json:"next_run"`
+}
+
+type discussedChannels struct {
+	ByChannelId map[string]discussedChannel `json:"by_channel_id"`
+}
+
+func discussedChannelsFromJson(data []byte) (*discussedChannels, error) {
+	channels := &discussedChannels{ByChannelId: map[string]discussedChannel{}}
+	if len(data) == 0 {
+		return channels, nil
+	}
+	err := json.Unmarshal(data, channels)
+	if err != nil {
+		return nil, err
+	}
+	if channels.ByChannelId == nil {
+		channels.ByChannelId = map[string]discussedChannel{}
+	}
+	return channels, nil
+}
+
+// discussedIssues counts the mentions of the issue keys in the messages of a
+// channel since its last summary.
+type discussedIssues struct {
+	Since  int64          `json:"since"`
+	Counts map[string]int `json:"counts"`
+}
+
+func discussedIssuesFromJson(data []byte) (*discussedIssues, error) {
+	issues := &discussedIssues{Counts: map[string]int{}}
+	if len(data) == 0 {
+		return issues, nil
+	}
+	err := json.Unmarshal(data, issues)
+	if err != nil {
+		return nil, err
+	}
+	if issues.Counts == nil {
+		issues.Counts = map[string]int{}
+	}
+	return issues, nil
+}
+
+// add counts a mention of each key, up to discussedIssuesMaxKeys distinct
+// keys.
+func (issues *discussedIssues) add(keys []string, now int64) {
+	if issues.Since == 0 {
+		issues.Since = now
+	}
+	for _, key := range keys {
+		if _, ok := issues.Counts[key]; !ok && len(issues.Counts) >= discussedIssuesMaxKeys {
+			continue
+		}
+		issues.Counts[key]++
+	}
+}
+
+// remove discounts the mentions of a summary, keeping those counted while it
+// was posted.
+func (issues *discussedIssues) remove(counts map[string]int, now int64) {
+	for key, count := range counts {
+		issues.Counts[key] -= count
+		if issues.Counts[key] <= 0 {
+			delete(issues.Counts, key)
+		}
+	}
+	issues.Since = now
+}
+
+// mostMentioned returns the keys by decreasing number of mentions.
+func (issues *discussedIssues) mostMentioned() []string {
+	keys := []string{}
+	for key := range issues.Counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if issues.Counts[keys[i]] != issues.Counts[keys[j]] {
+			return issues.Counts[keys[i]] > issues.Counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// discussedIssueKeys returns the distinct issue keys of a message.
+func discussedIssueKeys(message string) []string {
+	keys := []string{}
+	seen := NewStringSet()
+	for _, key := range reDiscussedIssueKey.FindAllString(message, -1) {
+		if !seen.ContainsAny(key) {
+			seen = seen.Add(key)
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (p *Plugin) loadDiscussedChannels(ji Instance) (*discussedChannels, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyDiscussedChannels))
+	if appErr != nil {
+		return nil, appErr
+	}
+	return discussedChannelsFromJson(data)
+}
+
+func (p *Plugin) modifyDiscussedChannels(ji Instance, modify func(channels *discussedChannels)) error {
+	return p.atomicModify(keyWithInstance(ji, keyDiscussedChannels), func(initialBytes []byte) ([]byte, error) {
+		channels, err := discussedChannelsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		modify(channels)
+		return json.Marshal(channels)
+	})
+}
+
+func (p *Plugin) modifyDiscussedIssues(ji Instance, channelId string, modify func(issues *discussedIssues)) error {
+	return p.atomicModify(keyWithInstance(ji, prefixDiscussedIssues+channelId), func(initialBytes []byte) ([]byte, error) {
+		issues, err := discussedIssuesFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		modify(issues)
+		return json.Marshal(issues)
+	})
+}
+
+// trackDiscussedIssues counts the issue keys mentioned in a message of a
+// channel with the discussed issues summary turned on. The posts of the bot
+// and of the webhooks are not counted.
+func (p *Plugin) trackDiscussedIssues(post *model.Post) error {
+	if post.UserId == p.getUserID() || post.Props["from_webhook"] == "true" {
+		return nil
+	}
+	keys := discussedIssueKeys(post.Message)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil
+	}
+	channels, err := p.loadDiscussedChannels(ji)
+	if err != nil {
+		return err
+	}
+	if _, ok := channels.ByChannelId[post.ChannelId]; !ok {
+		return nil
+	}
+	return p.modifyDiscussedIssues(ji, post.ChannelId, func(issues *discussedIssues) {
+		issues.add(keys, post.CreateAt)
+	})
+}
+
+// postDueDiscussedIssues posts the summaries whose scheduled time has come,
+// and schedules their next run.
+func (p *Plugin) postDueDiscussedIssues() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	channels, err := p.loadDiscussedChannels(ji)
+	if err != nil {
+		p.errorf("postDueDiscussedIssues: failed to load the channels: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for channelId, channel := range channels.ByChannelId {
+		if channel.NextRun > model.GetMillisForTime(now) {
+			continue
+		}
+		err = p.postDiscussedIssues(ji, channel, now)
+		if err != nil {
+			p.errorf("postDueDiscussedIssues: channel %s: %v", channelId, err)
+		}
+
+		id, nextRun := channelId, model.GetMillisForTime(channel.Schedule.next(now))
+		err = p.modifyDiscussedChannels(ji, func(channels *discussedChannels) {
+			if c, ok := channels.ByChannelId[id]; ok {
+				c.NextRun = nextRun
+				channels.ByChannelId[id] = c
+			}
+		})
+		if err != nil {
+			p.errorf("postDueDiscussedIssues: failed to schedule the summary of channel %s: %v", channelId, err)
+		}
+	}
+}
+
+// postDiscussedIssues posts the summary of the issues mentioned in the
+// channel, and discounts their mentions. Nothing is posted when no issues
+// were mentioned, and the keys not found in Jira are left out.
+func (p *Plugin) postDiscussedIssues(ji Instance, channel discussedChannel, now time.Time) error {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, prefixDiscussedIssues+channel.ChannelId))
+	if appErr != nil {
+		return appErr
+	}
+	mentions, err := discussedIssuesFromJson(data)
+	if err != nil {
+		return err
+	}
+	if len(mentions.Counts) == 0 {
+		return nil
+	}
+
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, channel.CreatorId)
+	if err != nil {
+		return errors.WithMessage(err, "summary creator is not connected to Jira")
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err
+	}
+
+	issues, more := []jira.Issue{}, false
+	for _, key := range mentions.mostMentioned() {
+		if len(issues) == discussedIssuesMaxListed {
+			more = true
+			break
+		}
+		issue, err := client.GetIssue(key, &jira.GetQueryOptions{Fields: strings.Join(myIssueFields, ",")})
+		if err != nil {
+			p.debugf("postDiscussedIssues: skipping %s: %v", key, err)
+			continue
+		}
+		issues = append(issues, *issue)
+	}
+
+	if len(issues) != 0 {
+		_, appErr = p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: channel.ChannelId,
+			Message: p.renderDiscussedIssues(p.channelLocale(channel.ChannelId),
+				strings.TrimRight(ji.GetURL(), "/"), mentions, issues, more),
+		})
+		if appErr != nil {
+			return appErr
+		}
+	}
+
+	return p.modifyDiscussedIssues(ji, channel.ChannelId, func(issues *discussedIssues) {
+		issues.remove(mentions.Counts, model.GetMillisForTime(now))
+	})
+}
+
+func (p *Plugin) renderDiscussedIssues(locale, jiraURL string, mentions *discussedIssues, issues []jira.Issue, more bool) string {
+	since := time.Unix(0, mentions.Since*int64(time.Millisecond)).UTC().Format("2006-01-02")
+	lines := []string{p.localize(locale, msgDiscussedHeader, since)}
+	for _, issue := range issues {
+		lines = append(lines, p.localize(locale, msgDiscussedIssue, myIssueText(jiraURL, issue), mentions.Counts[issue.Key]))
+	}
+	if more {
+		lines = append(lines, "", p.localize(locale, msgDiscussedMore, len(issues)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func executeDiscussed(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please use `/jira discussed <on|schedule|off>`, e.g. `/jira discussed friday@16:00`, in UTC.")
+	}
+	var schedule *channelReportSchedule
+	switch args[0] {
+	case "off":
+	case "on":
+		schedule = &defaultDiscussedIssuesSchedule
+	default:
+		s, err := parseChannelReportSchedule(args[0])
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if s.Every == channelReportHourly {
+			return p.responsef(header, "The summary can be posted once a day at most, e.g. `daily@09:00`.")
+		}
+		schedule = &s
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to manage the Jira summaries of this channel: %v", err)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeDiscussed: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+
+	if schedule == nil {
+		err = p.modifyDiscussedChannels(ji, func(channels *discussedChannels) {
+			delete(channels.ByChannelId, header.ChannelId)
+		})
+		if err != nil {
+			return p.responsef(header, "Failed to turn the summary off: %v", err)
+		}
+		_ = p.API.KVDelete(keyWithInstance(ji, prefixDiscussedIssues+header.ChannelId))
+		return p.responsef(header, "The summary of the Jira issues discussed in this channel is turned off.")
+	}
+
+	if _, err = p.userStore.LoadJIRAUser(ji, header.UserId); err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	err = p.modifyDiscussedChannels(ji, func(channels *discussedChannels) {
+		channels.ByChannelId[header.ChannelId] = discussedChannel{
+			ChannelId: header.ChannelId,
+			CreatorId: header.UserId,
+			Schedule:  *schedule,
+			NextRun:   model.GetMillisForTime(schedule.next(time.Now())),
+		}
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to turn the summary on: %v", err)
+	}
+	return p.responsef(header, "The Jira issues mentioned in this channel will be summarized with their current state %s UTC, next at %s. "+
+		"The states are looked up in Jira with your account.",
+		schedule, schedule.next(time.Now()).Format("2006-01-02 15:04"))
+}
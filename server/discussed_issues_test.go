@@ -0,0 +1,61 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestDiscussedIssueKeys(t *testing.T) {
+	assert.Equal(t, []string{"TES-41", "EXT-2"},
+		discussedIssueKeys("Is TES-41 related to https://jira.example.com/browse/EXT-2? See TES-41."))
+	assert.Empty(t, discussedIssueKeys("tes-41, TES-0, TES41 and a-1 are not issue keys"))
+}
+
+func TestDiscussedIssuesCounts(t *testing.T) {
+	issues := &discussedIssues{Counts: map[string]int{}}
+	issues.add([]string{"TES-1", "TES-2"}, 100)
+	issues.add([]string{"TES-2"}, 200)
+	assert.Equal(t, int64(100), issues.Since)
+	assert.Equal(t, []string{"TES-2", "TES-1"}, issues.mostMentioned())
+
+	// The mentions counted while a summary is posted are kept
+	posted := map[string]int{"TES-1": 1, "TES-2": 2}
+	issues.add([]string{"TES-2", "TES-3"}, 300)
+	issues.remove(posted, 400)
+	assert.Equal(t, map[string]int{"TES-2": 1, "TES-3": 1}, issues.Counts)
+	assert.Equal(t, int64(400), issues.Since)
+
+	full := &discussedIssues{Counts: map[string]int{}}
+	for i := 0; i < discussedIssuesMaxKeys; i++ {
+		full.Counts[model.NewId()] = 1
+	}
+	full.add([]string{"TES-1"}, 100)
+	assert.Len(t, full.Counts, discussedIssuesMaxKeys)
+}
+
+func TestRenderDiscussedIssues(t *testing.T) {
+	p := &Plugin{}
+	mentions := &discussedIssues{
+		Since:  model.GetMillisForTime(time.Date(2020, 5, 11, 9, 0, 0, 0, time.UTC)),
+		Counts: map[string]int{"TES-2": 3, "TES-1": 1, "TES-3": 1},
+	}
+	issues := []jira.Issue{
+		{Key: "TES-2", Fields: &jira.IssueFields{Summary: "Second", Status: &jira.Status{Name: "In Progress"}}},
+		{Key: "TES-1", Fields: &jira.IssueFields{Summary: "First", Status: &jira.Status{Name: "Done"}}},
+	}
+
+	assert.Equal(t, "#### Jira issues discussed in this channel since 2020-05-11\n"+
+		"* [TES-2](https://jira.example.com/browse/TES-2) Second (In Progress), mentions: 3\n"+
+		"* [TES-1](https://jira.example.com/browse/TES-1) First (Done), mentions: 1",
+		p.renderDiscussedIssues("en", "https://jira.example.com", mentions, issues, false))
+	assert.Contains(t, p.renderDiscussedIssues("en", "https://jira.example.com", mentions, issues, true),
+		"\n\n_Only the 2 most mentioned issues are listed._")
+}
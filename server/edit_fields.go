@@ -0,0 +1,350 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	editFieldPriority   = "priority"
+	editFieldLabels     = "labels"
+	editFieldFixVersion = "fix_version"
+	editFieldDueDate    = "due_date"
+
+	dueDateFormat = "2006-01-02"
+)
+
+// editFieldsAction returns the button of an issue post opening a dialog to
+// edit the fields used for triage.
+func (p *Plugin) editFieldsAction(issueKey string, t localizer) *model.PostAction {
+	return &model.PostAction{
+		Id:   "editfields",
+		Name: t(msgActionEditFields),
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPIEditFieldsAction,
+			Context: map[string]interface{}{
+				"issue_key": issueKey,
+			},
+		},
+	}
+}
+
+// issuePostActions returns the buttons of the posts of an issue, named by t.
+func (p *Plugin) issuePostActions(issueKey string, t localizer) []*model.PostAction {
+	actions := []*model.PostAction{
+		p.transitionAction(issueKey, t),
+		p.editFieldsAction(issueKey, t),
+	}
+	if action := p.quickTriageAction(issueKey, t); action != nil {
+		actions = append(actions, action)
+	}
+	if action := p.openInAppAction(issueKey, t); action != nil {
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// editFieldsDialog renders the dialog editing the fields of an issue, filled
+// with their current values. When the priorities can't be listed, the
+// priority is entered by name.
+func editFieldsDialog(issue *jira.Issue, priorities []jira.Priority) model.Dialog {
+	priority := model.DialogElement{
+		DisplayName: "Priority",
+		Name:        editFieldPriority,
+		Type:        "text",
+		Optional:    true,
+	}
+	if issue.Fields.Priority != nil {
+		priority.Default = issue.Fields.Priority.Name
+	}
+	if len(priorities) > 0 {
+		priority.Type = "select"
+		for _, p := range priorities {
+			priority.Options = append(priority.Options, &model.PostActionOptions{
+				Text:  p.Name,
+				Value: p.Name,
+			})
+		}
+	}
+
+	versions := []string{}
+	for _, v := range issue.Fields.FixVersions {
+		versions = append(versions, v.Name)
+	}
+	dueDate := ""
+	if !time.Time(issue.Fields.Duedate).IsZero() {
+		dueDate = time.Time(issue.Fields.Duedate).Format(dueDateFormat)
+	}
+
+	return model.Dialog{
+		CallbackId:  issue.Key,
+		Title:       "Edit " + issue.Key,
+		SubmitLabel: "Save",
+		Elements: []model.DialogElement{
+			priority,
+			{
+				DisplayName: "Labels",
+				Name:        editFieldLabels,
+				Type:        "text",
+				Default:     strings.Join(issue.Fields.Labels, ", "),
+				HelpText:    "Separate the labels with commas. Jira labels can't contain spaces.",
+				Optional:    true,
+			}, {
+				DisplayName: "Fix version",
+				Name:        editFieldFixVersion,
+				Type:        "text",
+				Default:     strings.Join(versions, ", "),
+				HelpText:    "Separate the versions with commas. The versions must exist in the project.",
+				Optional:    true,
+			}, {
+				DisplayName: "Due date",
+				Name:        editFieldDueDate,
+				Type:        "text",
+				Default:     dueDate,
+				Placeholder: "YYYY-MM-DD",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+// editFieldsUpdate returns the fields of the issue changed by a submission of
+// the dialog, or the errors of its elements.
+func editFieldsUpdate(issue *jira.Issue, submission map[string]interface{}) (map[string]interface{}, map[string]string) {
+	value := func(name string) string {
+		v, _ := submission[name].(string)
+		return strings.TrimSpace(v)
+	}
+	fields := map[string]interface{}{}
+	elementErrors := map[string]string{}
+
+	currentPriority := ""
+	if issue.Fields.Priority != nil {
+		currentPriority = issue.Fields.Priority.Name
+	}
+	if priority := value(editFieldPriority); priority != "" && priority != currentPriority {
+		fields["priority"] = map[string]string{"name": priority}
+	}
+
+	labels := splitFieldList(value(editFieldLabels))
+	for _, label := range labels {
+		if strings.ContainsAny(label, " \t") {
+			elementErrors[editFieldLabels] = fmt.Sprintf("The label %q contains a space.", label)
+		}
+	}
+	if !NewStringSet(labels...).Equals(NewStringSet(issue.Fields.Labels...)) {
+		fields["labels"] = labels
+	}
+
+	currentVersions := []string{}
+	for _, v := range issue.Fields.FixVersions {
+		currentVersions = append(currentVersions, v.Name)
+	}
+	versions := splitFieldList(value(editFieldFixVersion))
+	if !NewStringSet(versions...).Equals(NewStringSet(currentVersions...)) {
+		fixVersions := []map[string]string{}
+		for _, v := range versions {
+			fixVersions = append(fixVersions, map[string]string{"name": v})
+		}
+		fields["fixVersions"] = fixVersions
+	}
+
+	currentDueDate := ""
+	if !time.Time(issue.Fields.Duedate).IsZero() {
+		currentDueDate = time.Time(issue.Fields.Duedate).Format(dueDateFormat)
+	}
+	dueDate := value(editFieldDueDate)
+	if dueDate != "" {
+		if _, err := time.Parse(dueDateFormat, dueDate); err != nil {
+			elementErrors[editFieldDueDate] = "Enter the date as YYYY-MM-DD."
+		}
+	}
+	if dueDate != currentDueDate {
+		if dueDate == "" {
+			fields["duedate"] = nil
+		} else {
+			fields["duedate"] = dueDate
+		}
+	}
+
+	if len(elementErrors) > 0 {
+		return nil, elementErrors
+	}
+	return fields, nil
+}
+
+// splitFieldList splits a comma-separated list of values, skipping the empty
+// ones.
+func splitFieldList(list string) []string {
+	values := []string{}
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// httpAPIEditFieldsAction handles the Edit fields button of the issue posts,
+// opening the dialog for the user.
+func httpAPIEditFieldsAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	issueKey, _ := request.Context["issue_key"].(string)
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("missing issue key")
+	}
+
+	p := ji.GetPlugin()
+	if err := p.requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	err := p.openEditFieldsDialog(ji, mattermostUserId, request.TriggerId, issueKey)
+	if err != nil {
+		p.API.SendEphemeralPost(mattermostUserId, &model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: request.ChannelId,
+			Message:   err.Error(),
+		})
+	}
+
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{})
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func (p *Plugin) openEditFieldsDialog(ji Instance, mattermostUserId, triggerId, issueKey string) error {
+	client, err := p.transitionClient(ji, mattermostUserId)
+	if err != nil {
+		return err
+	}
+	issue, err := client.GetIssue(issueKey, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get issue "+issueKey)
+	}
+	priorities := []jira.Priority{}
+	err = client.RESTGet("2/priority", nil, &priorities)
+	if err != nil {
+		p.API.LogDebug("Failed to list the Jira priorities", "error", err.Error())
+		priorities = nil
+	}
+
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerId,
+		URL:       p.GetPluginURLPath() + routeAPIEditFieldsDialog,
+		Dialog:    editFieldsDialog(issue, priorities),
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// httpAPIEditFieldsDialog handles the submission of the dialog, updating the
+// changed fields as the user.
+func httpAPIEditFieldsDialog(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the dialog submission")
+	}
+	if request.Cancelled {
+		return http.StatusOK, nil
+	}
+	issueKey := request.CallbackId
+
+	p := ji.GetPlugin()
+	response := model.SubmitDialogResponse{}
+	message, err := "", p.requireTeamInstance(ji, mattermostUserId, request.ChannelId)
+	if err == nil {
+		message, err = p.editIssueFields(ji, mattermostUserId, issueKey, request.Submission)
+	}
+	switch err := err.(type) {
+	case nil:
+		p.API.SendEphemeralPost(mattermostUserId, &model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: request.ChannelId,
+			Message:   message,
+		})
+	case editFieldsErrors:
+		response.Errors = err
+	default:
+		response.Error = err.Error()
+	}
+
+	if response.Error == "" && len(response.Errors) == 0 {
+		return http.StatusOK, nil
+	}
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// editFieldsErrors are the errors of the elements of the dialog.
+type editFieldsErrors map[string]string
+
+func (e editFieldsErrors) Error() string {
+	return "invalid fields"
+}
+
+func (p *Plugin) editIssueFields(ji Instance, mattermostUserId, issueKey string, submission map[string]interface{}) (string, error) {
+	client, err := p.transitionClient(ji, mattermostUserId)
+	if err != nil {
+		return "", err
+	}
+	// Only the fields changed in the dialog are updated, not to overwrite
+	// the changes made in Jira since it was opened.
+	issue, err := client.GetIssue(issueKey, nil)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to get issue "+issueKey)
+	}
+	fields, elementErrors := editFieldsUpdate(issue, submission)
+	if len(elementErrors) > 0 {
+		return "", editFieldsErrors(elementErrors)
+	}
+
+	link := fmt.Sprintf("[%s](%v/browse/%v)", issueKey, ji.GetURL(), issueKey)
+	if len(fields) == 0 {
+		return "No fields of " + link + " were changed.", nil
+	}
+	err = p.requireWriteAllowed(mattermostUserId)
+	if err != nil {
+		return "", err
+	}
+	err = client.UpdateIssue(issueKey, map[string]interface{}{"fields": fields})
+	if err != nil {
+		return "", err
+	}
+	return "Updated the fields of " + link + ".", nil
+}
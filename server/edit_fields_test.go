@@ -0,0 +1,100 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditFieldsDialog(t *testing.T) {
+	issue := &jira.Issue{
+		Key: "TEST-1",
+		Fields: &jira.IssueFields{
+			Priority:    &jira.Priority{Name: "High"},
+			Labels:      []string{"backend", "urgent"},
+			FixVersions: []*jira.FixVersion{{Name: "1.0"}},
+			Duedate:     jira.Date(time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+
+	dialog := editFieldsDialog(issue, []jira.Priority{{Name: "High"}, {Name: "Low"}})
+	assert.Equal(t, "TEST-1", dialog.CallbackId)
+	require.Len(t, dialog.Elements, 4)
+	assert.Equal(t, "select", dialog.Elements[0].Type)
+	assert.Equal(t, "High", dialog.Elements[0].Default)
+	assert.Len(t, dialog.Elements[0].Options, 2)
+	assert.Equal(t, "backend, urgent", dialog.Elements[1].Default)
+	assert.Equal(t, "1.0", dialog.Elements[2].Default)
+	assert.Equal(t, "2020-03-04", dialog.Elements[3].Default)
+
+	dialog = editFieldsDialog(issue, nil)
+	assert.Equal(t, "text", dialog.Elements[0].Type)
+}
+
+func TestEditFieldsUpdate(t *testing.T) {
+	issue := &jira.Issue{
+		Key: "TEST-1",
+		Fields: &jira.IssueFields{
+			Priority: &jira.Priority{Name: "High"},
+			Labels:   []string{"backend", "urgent"},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		submission     map[string]interface{}
+		expectedFields map[string]interface{}
+		expectedErrors map[string]string
+	}{
+		"unchanged": {
+			submission: map[string]interface{}{
+				editFieldPriority: "High",
+				editFieldLabels:   "urgent, backend",
+			},
+			expectedFields: map[string]interface{}{},
+		},
+		"all fields": {
+			submission: map[string]interface{}{
+				editFieldPriority:   "Low",
+				editFieldLabels:     "backend,",
+				editFieldFixVersion: "1.0, 1.1",
+				editFieldDueDate:    "2020-03-04",
+			},
+			expectedFields: map[string]interface{}{
+				"priority":    map[string]string{"name": "Low"},
+				"labels":      []string{"backend"},
+				"fixVersions": []map[string]string{{"name": "1.0"}, {"name": "1.1"}},
+				"duedate":     "2020-03-04",
+			},
+		},
+		"cleared labels": {
+			submission: map[string]interface{}{
+				editFieldPriority: "High",
+			},
+			expectedFields: map[string]interface{}{
+				"labels": []string{},
+			},
+		},
+		"invalid": {
+			submission: map[string]interface{}{
+				editFieldLabels:  "needs triage",
+				editFieldDueDate: "03/04/2020",
+			},
+			expectedErrors: map[string]string{
+				editFieldLabels:  `The label "needs triage" contains a space.`,
+				editFieldDueDate: "Enter the date as YYYY-MM-DD.",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fields, errors := editFieldsUpdate(issue, tc.submission)
+			assert.Equal(t, tc.expectedFields, fields)
+			assert.Equal(t, tc.expectedErrors, errors)
+		})
+	}
+}
@@ -24,6 +24,42 @@ const (
 	eventUpdatedAffectsVersion = "event_updated_affects_version"
 	eventUpdatedReporter       = "event_updated_reporter"
 	eventUpdatedComponents     = "event_updated_components"
+
+	eventUpdatedOriginalEstimate = "event_updated_original_estimate"
+
+	// eventUpdatedMoved is an issue moved to another project, which changes
+	// its key.
+	eventUpdatedMoved = "event_updated_moved"
+
+	// eventUnrecognized is assigned to webhook events the plugin does not
+	// know how to render. Subscriptions opt in to receive them.
+	eventUnrecognized = "event_unrecognized"
+
+	eventProjectCreated = "event_project_created"
+	eventProjectDeleted = "event_project_deleted"
+
+	// eventPropertySet is an issue property set, e.g. by a Jira automation
+	// rule signaling that the issue should be posted.
+	eventPropertySet = "event_property_set"
+)
+
+// User lifecycle events are not posted to channels. They are used to keep
+// the stored user mappings up to date.
+const (
+	eventUserCreated = "event_user_created"
+	eventUserUpdated = "event_user_updated"
+	eventUserDeleted = "event_user_deleted"
+)
+
+var userEvents = NewStringSet(
+	eventUserCreated,
+	eventUserUpdated,
+	eventUserDeleted,
+)
+
+var projectEvents = NewStringSet(
+	eventProjectCreated,
+	eventProjectDeleted,
 )
 
 var legacyEvents = NewStringSet(
@@ -62,6 +98,7 @@ var allEvents = NewStringSet(
 	eventUpdatedSummary,
 	eventUpdatedIssuetype,
 	eventUpdatedFixVersion,
+	eventUpdatedMoved,
 )
 
 var updateEvents = NewStringSet(
@@ -79,4 +116,5 @@ var updateEvents = NewStringSet(
 	eventUpdatedSummary,
 	eventUpdatedIssuetype,
 	eventUpdatedFixVersion,
+	eventUpdatedMoved,
 )
@@ -0,0 +1,122 @@
+package expvar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonusllhist"
+)
+
+// PrometheusDurationBuckets are the upper bounds, in seconds, of the elapsed
+// time histogram buckets. They fall on the histogram's bin boundaries, so the
+// bucket counts are exact.
+var PrometheusDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// WritePrometheus writes the stats in the Prometheus text exposition format,
+// with metric names starting with prefix, and the endpoint name as the
+// "endpoint" label. Endpoints are written in name order.
+func (stats *Stats) WritePrometheus(w io.Writer, prefix string) error {
+	endpoints := map[string]Endpoint{}
+	var names []string
+	stats.Do(func(name string, e *Endpoint) {
+		endpoints[name] = e.Get()
+		names = append(names, name)
+	})
+	sort.Strings(names)
+
+	pw := &prometheusWriter{w: w}
+	counters := []struct {
+		name, help string
+		value      func(e Endpoint) int64
+	}{
+		{"requests_total", "Number of requests.", func(e Endpoint) int64 { return e.Total }},
+		{"errors_total", "Number of failed requests.", func(e Endpoint) int64 { return e.Errors }},
+		{"ignored_total", "Number of ignored requests.", func(e Endpoint) int64 { return e.Ignored }},
+	}
+	for _, c := range counters {
+		metric := prefix + "_endpoint_" + c.name
+		pw.header(metric, "counter", c.help)
+		for _, name := range names {
+			pw.printf("%s{endpoint=%s} %d\n", metric, quoteLabel(name), c.value(endpoints[name]))
+		}
+	}
+
+	metric := prefix + "_endpoint_duration_seconds"
+	pw.header(metric, "histogram", "Time taken to process requests.")
+	for _, name := range names {
+		e := endpoints[name]
+		label := "endpoint=" + quoteLabel(name)
+		counts := bucketCounts(e.Elapsed, PrometheusDurationBuckets, float64(time.Second))
+		for i, le := range PrometheusDurationBuckets {
+			pw.printf("%s_bucket{%s,le=\"%s\"} %d\n", metric, label, strconv.FormatFloat(le, 'g', -1, 64), counts[i])
+		}
+		pw.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", metric, label, e.Total)
+		pw.printf("%s_sum{%s} %g\n", metric, label, e.Elapsed.ApproxSum()/float64(time.Second))
+		pw.printf("%s_count{%s} %d\n", metric, label, e.Total)
+	}
+
+	return pw.err
+}
+
+// WritePrometheusGauge writes a single gauge in the Prometheus text
+// exposition format.
+func WritePrometheusGauge(w io.Writer, name, help string, value float64) error {
+	pw := &prometheusWriter{w: w}
+	pw.header(name, "gauge", help)
+	pw.printf("%s %g\n", name, value)
+	return pw.err
+}
+
+type prometheusWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *prometheusWriter) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
+
+func (pw *prometheusWriter) header(name, metricType, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func quoteLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// bucketCounts returns the cumulative number of values in h less than each
+// bound, the bounds expressed in units of scale.
+func bucketCounts(h *circonusllhist.Histogram, bounds []float64, scale float64) []int64 {
+	counts := make([]int64, len(bounds))
+	if h == nil {
+		return counts
+	}
+	for _, bin := range h.DecStrings() {
+		// Bins are formatted as "H[<lower bound>]=<count>"
+		parts := strings.SplitN(strings.TrimPrefix(bin, "H["), "]=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		lower, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		for i, bound := range bounds {
+			if lower < bound*scale {
+				counts[i] += count
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,44 @@
+package expvar
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	stats := NewUnpublishedStats(nil)
+	e := stats.EnsureEndpoint("api/jira/2/issue")
+	e.Record(10, 10, 20*time.Millisecond, false, false)
+	e.Record(10, 10, 300*time.Millisecond, true, false)
+	e.Record(10, 10, 2*time.Second, false, true)
+	stats.EnsureEndpoint(`odd"name`).Record(0, 0, time.Millisecond, false, false)
+
+	buf := &bytes.Buffer{}
+	err := stats.WritePrometheus(buf, "test")
+	require.NoError(t, err)
+	out := buf.String()
+
+	require.Contains(t, out, "# TYPE test_endpoint_requests_total counter\n")
+	require.Contains(t, out, `test_endpoint_requests_total{endpoint="api/jira/2/issue"} 3`+"\n")
+	require.Contains(t, out, `test_endpoint_errors_total{endpoint="api/jira/2/issue"} 1`+"\n")
+	require.Contains(t, out, `test_endpoint_ignored_total{endpoint="api/jira/2/issue"} 1`+"\n")
+	require.Contains(t, out, `test_endpoint_requests_total{endpoint="odd\"name"} 1`+"\n")
+
+	require.Contains(t, out, "# TYPE test_endpoint_duration_seconds histogram\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_bucket{endpoint="api/jira/2/issue",le="0.01"} 0`+"\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_bucket{endpoint="api/jira/2/issue",le="0.025"} 1`+"\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_bucket{endpoint="api/jira/2/issue",le="0.5"} 2`+"\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_bucket{endpoint="api/jira/2/issue",le="2.5"} 3`+"\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_bucket{endpoint="api/jira/2/issue",le="+Inf"} 3`+"\n")
+	require.Contains(t, out, `test_endpoint_duration_seconds_count{endpoint="api/jira/2/issue"} 3`+"\n")
+}
+
+func TestWritePrometheusGauge(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WritePrometheusGauge(buf, "test_queue_depth", "Queue depth.", 5)
+	require.NoError(t, err)
+	require.Equal(t, "# HELP test_queue_depth Queue depth.\n# TYPE test_queue_depth gauge\ntest_queue_depth 5\n", buf.String())
+}
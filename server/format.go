@@ -0,0 +1,109 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Jira's default time tracking units: a day is 8 hours, a week 5 days.
+const (
+	secondsPerMinute = 60
+	secondsPerHour   = 60 * secondsPerMinute
+	secondsPerDay    = 8 * secondsPerHour
+	secondsPerWeek   = 5 * secondsPerDay
+)
+
+// timeTrackingFields are the fields whose changelog values are durations in
+// seconds.
+var timeTrackingFields = NewStringSet(
+	"timeoriginalestimate",
+	"timeestimate",
+	"timespent",
+	"aggregatetimeoriginalestimate",
+	"aggregatetimeestimate",
+	"aggregatetimespent",
+)
+
+// reJiraNumber matches the numbers as Jira renders the values of number
+// fields in the changelog, like "3.0", "12.75" or "1.5E7". Plain integers
+// are already readable, and are left as they are since they may as well be
+// the values of text fields.
+var reJiraNumber = regexp.MustCompile(`^-?(0|[1-9][[:digit:]]*)(\.0|\.[[:digit:]]*[1-9])?(E-?[[:digit:]]+)?$`)
+
+// formatDuration renders a duration in seconds like Jira does, e.g.
+// "2w 3d 4h 30m".
+func formatDuration(seconds int) string {
+	if seconds <= 0 {
+		return "0m"
+	}
+	parts := []string{}
+	for _, unit := range []struct {
+		seconds int
+		suffix  string
+	}{
+		{secondsPerWeek, "w"},
+		{secondsPerDay, "d"},
+		{secondsPerHour, "h"},
+		{secondsPerMinute, "m"},
+	} {
+		if n := seconds / unit.seconds; n > 0 {
+			parts = append(parts, strconv.Itoa(n)+unit.suffix)
+			seconds -= n * unit.seconds
+		}
+	}
+	if len(parts) == 0 {
+		return "1m"
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatNumber renders a number with thousands separators and at most 2
+// decimals, e.g. story points as "3" rather than "3.0", and amounts as
+// "15,000,000" rather than "1.5E7".
+func formatNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	decimals := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, decimals = s[:i], s[i:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if sign == "-" && s == "0" && decimals == "" {
+		sign = ""
+	}
+	return sign + s + decimals
+}
+
+// formatFieldValue renders the changelog value of a field in human-friendly
+// form: the durations of the time tracking fields, and the numbers of the
+// number fields. Other values are returned as they are.
+func formatFieldValue(fieldId, value string) string {
+	if value == "" {
+		return value
+	}
+	if timeTrackingFields.ContainsAny(fieldId) {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return value
+		}
+		return formatDuration(seconds)
+	}
+	if strings.ContainsAny(value, ".E") && reJiraNumber.MatchString(value) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return value
+		}
+		return formatNumber(f)
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDuration(t *testing.T) {
+	for seconds, expected := range map[int]string{
+		0:                                  "0m",
+		30:                                 "1m",
+		60:                                 "1m",
+		5400:                               "1h 30m",
+		28800:                              "1d",
+		144000:                             "1w",
+		144000*2 + 28800*3 + 3600*4 + 1800: "2w 3d 4h 30m",
+	} {
+		assert.Equal(t, expected, formatDuration(seconds), "%d seconds", seconds)
+	}
+}
+
+func TestFormatFieldValue(t *testing.T) {
+	for _, tc := range []struct {
+		fieldId, value, expected string
+	}{
+		{"timeoriginalestimate", "7200", "2h"},
+		{"timespent", "", ""},
+		{"timeestimate", "soon", "soon"},
+		{"customfield_10016", "3.0", "3"},
+		{"customfield_10016", "0.5", "0.5"},
+		{"customfield_10100", "1234.567", "1,234.57"},
+		{"customfield_10100", "1.5E7", "15,000,000"},
+		{"customfield_10100", "-1234567.5", "-1,234,567.5"},
+		// Integers, and text that is not a number as Jira renders them
+		{"customfield_10100", "2021", "2021"},
+		{"customfield_10100", "1.10", "1.10"},
+		{"customfield_10100", "007.5", "007.5"},
+		{"customfield_10100", "v1.0", "v1.0"},
+	} {
+		assert.Equal(t, tc.expected, formatFieldValue(tc.fieldId, tc.value), "%s %q", tc.fieldId, tc.value)
+	}
+}
+
+func TestIssueEstimate(t *testing.T) {
+	issue := &jira.Issue{Fields: &jira.IssueFields{}}
+	assert.Equal(t, "", issueEstimate(issue, englishLocalizer))
+
+	issue.Fields.TimeOriginalEstimate = 57600
+	issue.Fields.TimeEstimate = 57600
+	assert.Equal(t, "2d", issueEstimate(issue, englishLocalizer))
+
+	issue.Fields.TimeEstimate = 43200
+	issue.Fields.TimeSpent = 14400
+	assert.Equal(t, "1d 4h remaining of 2d", issueEstimate(issue, englishLocalizer))
+}
@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"text/template"
 
@@ -24,12 +23,36 @@ const (
 	routeAPIGetCreateIssueMetadata = "/api/v2/get-create-issue-metadata-for-project"
 	routeAPIGetJiraProjectMetadata = "/api/v2/get-jira-project-metadata"
 	routeAPIGetSearchIssues        = "/api/v2/get-search-issues"
+	routeAPIGetCreateDefaults      = "/api/v2/create-defaults"
 	routeAPIAttachCommentToIssue   = "/api/v2/attach-comment-to-issue"
+	routeAPISubscribeThread        = "/api/v2/subscribe-thread"
 	routeAPIUserInfo               = "/api/v2/userinfo"
 	routeAPISubscribeWebhook       = "/api/v2/webhook"
+	routeAPIWebhookSecret          = "/api/v2/webhook-secret"
 	routeAPISubscriptionsChannel   = "/api/v2/subscriptions/channel"
+	routeAPISubscriptionsBulk      = "/api/v2/subscriptions/bulk"
+	routeAPISubscriptionsDryRun    = "/api/v2/subscriptions/dry-run"
+	routeAPISubscriptionsSimulate  = "/api/v2/subscriptions/simulate"
 	routeAPISettingsInfo           = "/api/v2/settingsinfo"
+	routeAPICSRFToken              = "/api/v2/csrf-token"
+	routeAPISubscriptionOptions    = "/api/v2/subscription-options"
 	routeAPIStats                  = "/api/v2/stats"
+	routeAPIMetrics                = "/api/v2/metrics"
+	routeAPIHelpAction             = "/api/v2/help-action"
+	routeAPISearchAction           = "/api/v2/search-action"
+	routeAPISearchDialog           = "/api/v2/search-dialog"
+	routeAPISetupAction            = "/api/v2/setup-action"
+	routeAPISetupDialog            = "/api/v2/setup-dialog"
+	routeAPIConfirmAction          = "/api/v2/confirm-action"
+	routeAPITransitionAction       = "/api/v2/transition-action"
+	routeAPIQuickTriageAction      = "/api/v2/quick-triage-action"
+	routeAPIEditFieldsAction       = "/api/v2/edit-fields-action"
+	routeAPIEditFieldsDialog       = "/api/v2/edit-fields-dialog"
+	routeAPIIssuePreview           = "/api/v2/issue-preview"
+	routeAPITodoAction             = "/api/v2/todo-action"
+	routeAPIOpenInAppAction        = "/api/v2/open-in-app-action"
+	routeAPIBackfillAction         = "/api/v2/backfill-action"
+	routeAPIValidateJQL            = "/api/v2/validate-jql"
 	routeACInstalled               = "/ac/installed"
 	routeACJSON                    = "/ac/atlassian-connect.json"
 	routeACUninstalled             = "/ac/uninstalled"
@@ -39,6 +62,8 @@ const (
 	routeACUserDisconnected        = "/ac/user_disconnected.html"
 	routeIncomingIssueEvent        = "/issue_event"
 	routeIncomingWebhook           = "/webhook"
+	routeIssueRedirect             = "/issue/"
+	routeAvatar                    = "/avatar"
 	routeOAuth1Complete            = "/oauth1/complete.html"
 	routeOAuth1PublicKey           = "/oauth1/public_key.html" // TODO remove, debugging?
 	routeUserConnect               = "/user/connect"
@@ -48,24 +73,25 @@ const (
 )
 
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
-	status, err := handleHTTPRequest(p, c, w, r)
-	if err != nil {
-		p.API.LogError("ERROR: ", "Status", strconv.Itoa(status), "Error", err.Error(), "Host", r.Host, "RequestURI", r.RequestURI, "Method", r.Method, "query", r.URL.Query().Encode())
-		http.Error(w, err.Error(), status)
-		return
-	}
-	switch status {
-	case http.StatusOK:
-		// pass through
-	case 0:
-		status = http.StatusOK
-	default:
-		w.WriteHeader(status)
+	handler := handleHTTPRequest
+	for i := len(httpMiddleware) - 1; i >= 0; i-- {
+		handler = httpMiddleware[i](handler)
 	}
-	p.API.LogDebug("OK: ", "Status", strconv.Itoa(status), "Host", r.Host, "RequestURI", r.RequestURI, "Method", r.Method, "query", r.URL.Query().Encode())
+	_, _ = handler(p, c, w, r)
 }
 
 func handleHTTPRequest(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+	status, err := checkMattermostUser(r)
+	if err != nil {
+		return status, err
+	}
+	if isCSRFProtectedRoute(r.URL.Path) {
+		status, err := p.checkCSRFToken(c, r)
+		if err != nil {
+			return status, err
+		}
+	}
+
 	switch r.URL.Path {
 	// Issue APIs
 	case routeAPICreateIssue:
@@ -76,18 +102,62 @@ func handleHTTPRequest(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *h
 		return withInstance(p.currentInstanceStore, w, r, httpAPIGetJiraProjectMetadata)
 	case routeAPIGetSearchIssues:
 		return withInstance(p.currentInstanceStore, w, r, httpAPIGetSearchIssues)
+	case routeAPISearchAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPISearchAction)
+	case routeAPISearchDialog:
+		return withInstance(p.currentInstanceStore, w, r, httpAPISearchDialog)
+	case routeAPISetupAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPISetupAction)
+	case routeAPISetupDialog:
+		return withInstance(p.currentInstanceStore, w, r, httpAPISetupDialog)
+	case routeAPIGetCreateDefaults:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIGetCreateDefaults)
 	case routeAPIAttachCommentToIssue:
 		return withInstance(p.currentInstanceStore, w, r, httpAPIAttachCommentToIssue)
+	case routeAPISubscribeThread:
+		return withInstance(p.currentInstanceStore, w, r, httpAPISubscribeThread)
 
 	// User APIs
 	case routeAPIUserInfo:
 		return httpAPIGetUserInfo(p, w, r)
+	case routeAPISubscriptionsBulk:
+		return httpChannelCreateSubscriptions(p, w, r)
+	case routeAPISubscriptionsDryRun:
+		return httpSubscriptionsDryRun(p, w, r)
+	case routeAPIValidateJQL:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIValidateJQL)
+	case routeAPISubscriptionOptions:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIGetSubscriptionOptions)
 	case routeAPISettingsInfo:
 		return httpAPIGetSettingsInfo(p, w, r)
+	case routeAPICSRFToken:
+		return httpAPIGetCSRFToken(p, c, w, r)
+	case routeAPIHelpAction:
+		return httpAPIHelpAction(p, w, r)
+	case routeAPIConfirmAction:
+		return httpAPIConfirmAction(p, w, r)
+	case routeAPITransitionAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPITransitionAction)
+	case routeAPIQuickTriageAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIQuickTriageAction)
+	case routeAPIEditFieldsAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIEditFieldsAction)
+	case routeAPIEditFieldsDialog:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIEditFieldsDialog)
+	case routeAPIIssuePreview:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIGetIssuePreview)
+	case routeAPITodoAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPITodoAction)
+	case routeAPIOpenInAppAction:
+		return httpAPIOpenInAppAction(p, w, r)
+	case routeAPIBackfillAction:
+		return withInstance(p.currentInstanceStore, w, r, httpAPIBackfillAction)
 
 	// Stats
 	case routeAPIStats:
 		return httpAPIStats(p, w, r)
+	case routeAPIMetrics:
+		return httpAPIMetrics(p, w, r)
 
 	// Atlassian Connect application
 	case routeACInstalled:
@@ -120,9 +190,17 @@ func handleHTTPRequest(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *h
 	// User connect/disconnect links
 	case routeUserConnect:
 		return withInstance(p.currentInstanceStore, w, r, httpUserConnect)
+	// Jira avatars loaded through the plugin
+	case routeAvatar:
+		return httpAvatar(p, w, r)
+
 	// Firehose webhook setup for channel subscriptions
 	case routeAPISubscribeWebhook:
 		return httpSubscribeWebhook(p, w, r)
+	case routeAPISubscriptionsSimulate:
+		return httpSubscriptionsSimulate(p, w, r)
+	case routeAPIWebhookSecret:
+		return httpAPIWebhookSecret(p, w, r)
 
 	// expvar
 	case "/debug/vars":
@@ -147,6 +225,9 @@ func handleHTTPRequest(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *h
 	if strings.HasPrefix(r.URL.Path, routeAPISubscriptionsChannel) {
 		return httpChannelSubscriptions(p, w, r)
 	}
+	if strings.HasPrefix(r.URL.Path, routeIssueRedirect) {
+		return withInstance(p.currentInstanceStore, w, r, httpIssueRedirect)
+	}
 	return http.StatusNotFound, errors.New("not found")
 }
 
@@ -0,0 +1,107 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// httpHandlerFunc handles a request to the plugin, returning the status of
+// the response, and the error to respond with if any.
+type httpHandlerFunc func(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error)
+
+// httpMiddleware wraps the handling of all the requests, the outermost
+// first. The Mattermost users are authenticated in handleHTTPRequest, by
+// route, together with their CSRF tokens.
+var httpMiddleware = []func(next httpHandlerFunc) httpHandlerFunc{
+	withHTTPLogging,
+	withHTTPRecovery,
+}
+
+// publicAPIRoutes are the API routes that are not requested by Mattermost
+// users, and authenticate the requests themselves, e.g. with a secret.
+var publicAPIRoutes = NewStringSet(
+	routeAPISubscribeWebhook,
+	routeAPISubscriptionsSimulate,
+	routeAPIStats,
+	routeAPIMetrics,
+)
+
+// isMattermostUserRoute returns true for the routes that are only requested
+// by logged in Mattermost users, whose ID Mattermost sets in the
+// Mattermost-User-Id header. The new API routes are such routes by default.
+func isMattermostUserRoute(path string) bool {
+	switch path {
+	case routeAvatar,
+		routeOAuth1Complete,
+		routeOAuth1PublicKey,
+		routeUserConnect,
+		routeUserDisconnect:
+		return true
+	}
+	if strings.HasPrefix(path, "/api/v2/") {
+		return !publicAPIRoutes.ContainsAny(path)
+	}
+	return strings.HasPrefix(path, routeIssueRedirect)
+}
+
+// checkMattermostUser verifies that the requests of the Mattermost user
+// routes are authenticated.
+func checkMattermostUser(r *http.Request) (int, error) {
+	if isMattermostUserRoute(r.URL.Path) && r.Header.Get("Mattermost-User-Id") == "" {
+		return http.StatusUnauthorized, errors.New("not authorized")
+	}
+	return http.StatusOK, nil
+}
+
+// withHTTPLogging writes the errors to the response, and logs the requests
+// with their status and latency.
+func withHTTPLogging(next httpHandlerFunc) httpHandlerFunc {
+	return func(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+		start := time.Now()
+		status, err := next(p, c, w, r)
+		latency := time.Since(start).String()
+		if err != nil {
+			p.API.LogError("ERROR: ", "Status", strconv.Itoa(status), "Error", err.Error(), "Latency", latency,
+				"Host", r.Host, "RequestURI", r.RequestURI, "Method", r.Method, "query", r.URL.Query().Encode())
+			http.Error(w, err.Error(), status)
+			return status, err
+		}
+		switch status {
+		case http.StatusOK:
+			// pass through
+		case 0:
+			status = http.StatusOK
+		default:
+			w.WriteHeader(status)
+		}
+		p.API.LogDebug("OK: ", "Status", strconv.Itoa(status), "Latency", latency,
+			"Host", r.Host, "RequestURI", r.RequestURI, "Method", r.Method, "query", r.URL.Query().Encode())
+		return status, nil
+	}
+}
+
+// withHTTPRecovery responds to the requests whose handler panics with a 500,
+// logging the panic with its stack trace.
+func withHTTPRecovery(next httpHandlerFunc) httpHandlerFunc {
+	return func(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (status int, err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				p.API.LogError("Recovered from a panic in an HTTP handler", "RequestURI", r.RequestURI,
+					"Method", r.Method, "panic", fmt.Sprintf("%v", x), "stack", string(debug.Stack()))
+				status, err = http.StatusInternalServerError, errors.New("internal server error")
+			}
+		}()
+		return next(p, c, w, r)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsMattermostUserRoute(t *testing.T) {
+	for path, expected := range map[string]bool{
+		routeAPICreateIssue:                   true,
+		routeAPITodoAction:                    true,
+		routeAPISubscriptionsChannel + "/abc": true,
+		"/api/v2/some-new-route":              true,
+		routeIssueRedirect + "TES-1":          true,
+		routeAvatar:                           true,
+		routeUserConnect:                      true,
+		routeOAuth1Complete:                   true,
+		routeAPISubscribeWebhook:              false,
+		routeAPIStats:                         false,
+		routeAPIMetrics:                       false,
+		routeIncomingWebhook:                  false,
+		routeACInstalled:                      false,
+		routeWorkflowRegister:                 false,
+	} {
+		assert.Equal(t, expected, isMattermostUserRoute(path), path)
+	}
+}
+
+func TestHandleHTTPRequestNotAuthenticated(t *testing.T) {
+	p := &Plugin{}
+	for _, path := range []string{routeAPITodoAction, routeAPIIssuePreview, routeAvatar, routeIssueRedirect + "TES-1"} {
+		w := httptest.NewRecorder()
+		status, err := handleHTTPRequest(p, &plugin.Context{}, w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, status, path)
+		assert.EqualError(t, err, "not authorized")
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	api := &plugintest.API{}
+	var logged []interface{}
+	api.On("LogDebug", mock.AnythingOfType("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { logged = args }).Return()
+	var recovered string
+	api.On("LogError", "Recovered from a panic in an HTTP handler", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		"panic", mock.AnythingOfType("string"), "stack", mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { recovered = args.String(6) }).Return()
+	api.On("LogError", "ERROR: ", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	handler := withHTTPLogging(withHTTPRecovery(func(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+		return http.StatusAccepted, nil
+	}))
+	w := httptest.NewRecorder()
+	status, err := handler(p, &plugin.Context{}, w, httptest.NewRequest(http.MethodGet, "/some-route", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	require.Len(t, logged, 13)
+	assert.Equal(t, "Latency", logged[3])
+	assert.NotEmpty(t, logged[4])
+
+	handler = withHTTPLogging(withHTTPRecovery(func(p *Plugin, c *plugin.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+		var m map[string]string
+		m["crash"] = "now"
+		return http.StatusOK, nil
+	}))
+	w = httptest.NewRecorder()
+	status, err = handler(p, &plugin.Context{}, w, httptest.NewRequest(http.MethodGet, "/some-route", nil))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.EqualError(t, err, "internal server error")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal server error\n", w.Body.String())
+	assert.Contains(t, recovered, "nil map")
+}
@@ -314,6 +314,7 @@ func TestSubscribe(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			api := &plugintest.API{}
+			api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
 			p := Plugin{}
 
 			api.On("LogDebug",
@@ -327,6 +328,8 @@ func TestSubscribe(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 			api.On("LogError",
 				mock.AnythingOfTypeArgument("string"),
@@ -352,6 +355,8 @@ func TestSubscribe(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 
 			api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
@@ -381,6 +386,142 @@ func TestSubscribe(t *testing.T) {
 	}
 }
 
+const bulkSubscription = `{"name": "%s", "channel_id": "%s", "filters": {"events": ["jira:issue_created"], "projects": ["myproject"], "issue_types": ["10001"]}}`
+
+func TestBulkSubscribe(t *testing.T) {
+	for name, tc := range map[string]struct {
+		subscriptions      string
+		expectedStatusCode int
+		skipAuthorize      bool
+		apiCalls           func(*plugintest.API)
+	}{
+		"Not Authorized": {
+			subscriptions:      "[]",
+			expectedStatusCode: http.StatusUnauthorized,
+			skipAuthorize:      true,
+		},
+		"Empty": {
+			subscriptions:      "[]",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Reject Ids": {
+			subscriptions:      `[{"name": "first", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab"}, {"id": "iamtryingtodosendid", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaac"}]`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"No Permissions": {
+			subscriptions:      `[{"name": "first", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab"}]`,
+			expectedStatusCode: http.StatusForbidden,
+			apiCalls: func(api *plugintest.API) {
+				api.On("HasPermissionTo", mock.AnythingOfType("string"), mock.Anything).Return(false)
+			},
+		},
+		"Two channels": {
+			subscriptions:      `[` + fmt.Sprintf(bulkSubscription, "first", "aaaaaaaaaaaaaaaaaaaaaaaaab") + `, ` + fmt.Sprintf(bulkSubscription, "second", "aaaaaaaaaaaaaaaaaaaaaaaaac") + `]`,
+			expectedStatusCode: http.StatusOK,
+			apiCalls: checkHasSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaab",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("jira:issue_created"),
+						Projects:   NewStringSet("myproject"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+				ChannelSubscription{
+					ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("jira:issue_created"),
+						Projects:   NewStringSet("myproject"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			}, nil, t),
+		},
+		"Same name twice in a channel": {
+			subscriptions:      `[` + fmt.Sprintf(bulkSubscription, "first", "aaaaaaaaaaaaaaaaaaaaaaaaab") + `, ` + fmt.Sprintf(bulkSubscription, "first", "aaaaaaaaaaaaaaaaaaaaaaaaab") + `]`,
+			expectedStatusCode: http.StatusInternalServerError,
+			apiCalls:           hasSubscriptions([]ChannelSubscription{}, t),
+		},
+		"One invalid": {
+			subscriptions:      `[` + fmt.Sprintf(bulkSubscription, "first", "aaaaaaaaaaaaaaaaaaaaaaaaab") + `, ` + fmt.Sprintf(bulkSubscription, "", "aaaaaaaaaaaaaaaaaaaaaaaaac") + `]`,
+			expectedStatusCode: http.StatusInternalServerError,
+			apiCalls:           hasSubscriptions([]ChannelSubscription{}, t),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
+			p := Plugin{}
+
+			api.On("LogDebug",
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string")).Return(nil)
+			api.On("LogError",
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string")).Return(nil)
+			api.On("LogError",
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string")).Return(nil)
+
+			api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, nil)
+
+			if tc.apiCalls != nil {
+				tc.apiCalls(api)
+			}
+
+			p.updateConfig(func(conf *config) {
+				conf.Secret = "somesecret"
+			})
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{&p}
+			p.userStore = mockUserStore{}
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest("POST", "/api/v2/subscriptions/bulk", ioutil.NopCloser(bytes.NewBufferString(tc.subscriptions)))
+			if !tc.skipAuthorize {
+				request.Header.Set("Mattermost-User-Id", model.NewId())
+			}
+			p.ServeHTTP(&plugin.Context{}, w, request)
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			t.Log(string(body))
+			assert.Equal(t, tc.expectedStatusCode, w.Result().StatusCode)
+		})
+	}
+}
+
 func TestDeleteSubscription(t *testing.T) {
 	for name, tc := range map[string]struct {
 		subscriptionId     string
@@ -460,6 +601,7 @@ func TestDeleteSubscription(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			api := &plugintest.API{}
+			api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
 			p := Plugin{}
 
 			api.On("LogDebug",
@@ -473,6 +615,8 @@ func TestDeleteSubscription(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 			api.On("LogError",
 				mock.AnythingOfTypeArgument("string"),
@@ -498,6 +642,8 @@ func TestDeleteSubscription(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 
 			api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
@@ -589,6 +735,52 @@ func TestEditSubscription(t *testing.T) {
 						},
 					}), t),
 		},
+		"Editing subscription, current version": {
+			subscription:       `{"name": "some name", "id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaac", "version": 2, "filters": {"events": ["jira:issue_created"], "projects": ["otherproject"], "issue_types": ["10001"]}}`,
+			expectedStatusCode: http.StatusOK,
+			apiCalls: checkHasSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+					ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("jira:issue_created"),
+						Projects:   NewStringSet("otherproject"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			},
+				withExistingChannelSubscriptions(
+					[]ChannelSubscription{
+						ChannelSubscription{
+							Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+							ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+							Version:   2,
+							Filters: SubscriptionFilters{
+								Events:     NewStringSet("jira:issue_created"),
+								Projects:   NewStringSet("myproject"),
+								IssueTypes: NewStringSet("10001"),
+							},
+						},
+					}), t),
+		},
+		"Editing subscription, stale version": {
+			subscription:       `{"name": "some name", "id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaac", "version": 1, "filters": {"events": ["jira:issue_created"], "projects": ["otherproject"], "issue_types": ["10001"]}}`,
+			expectedStatusCode: http.StatusConflict,
+			apiCalls: checkHasSubscriptions([]ChannelSubscription{},
+				withExistingChannelSubscriptions(
+					[]ChannelSubscription{
+						ChannelSubscription{
+							Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+							ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+							Version:   2,
+							Filters: SubscriptionFilters{
+								Events:     NewStringSet("jira:issue_created"),
+								Projects:   NewStringSet("myproject"),
+								IssueTypes: NewStringSet("10001"),
+							},
+						},
+					}), t),
+		},
 		"Editing subscription, no name provided": {
 			subscription:       `{"name": "", "id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaac", "filters": {"events": ["jira:issue_created"], "projects": ["otherproject"], "issue_types": ["10001"]}}`,
 			expectedStatusCode: http.StatusInternalServerError,
@@ -711,6 +903,7 @@ func TestEditSubscription(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			api := &plugintest.API{}
+			api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
 			p := Plugin{}
 
 			api.On("LogDebug",
@@ -724,6 +917,8 @@ func TestEditSubscription(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 			api.On("LogError",
 				mock.AnythingOfTypeArgument("string"),
@@ -749,6 +944,8 @@ func TestEditSubscription(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 
 			api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
@@ -781,7 +978,9 @@ func TestEditSubscription(t *testing.T) {
 func TestGetSubscriptionsForChannel(t *testing.T) {
 	for name, tc := range map[string]struct {
 		channelId             string
+		query                 string
 		expectedStatusCode    int
+		expectedTotal         string
 		skipAuthorize         bool
 		apiCalls              func(*plugintest.API)
 		returnedSubscriptions []ChannelSubscription
@@ -867,6 +1066,98 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 					},
 				}, t),
 		},
+		"Paginated": {
+			channelId:          "aaaaaaaaaaaaaaaaaaaaaaaaac",
+			query:              "?offset=1&limit=1",
+			expectedStatusCode: http.StatusOK,
+			expectedTotal:      "2",
+			returnedSubscriptions: []ChannelSubscription{
+				ChannelSubscription{
+					Id:        "aaaaaaaaaaaaaaaaaaaaaaaaac",
+					ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("jira:issue_created"),
+						Projects:   NewStringSet("things"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			},
+			apiCalls: hasSubscriptions(
+				[]ChannelSubscription{
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("myproject"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("things"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+				}, t),
+		},
+		"Offset past the end": {
+			channelId:             "aaaaaaaaaaaaaaaaaaaaaaaaac",
+			query:                 "?offset=5",
+			expectedStatusCode:    http.StatusOK,
+			expectedTotal:         "2",
+			returnedSubscriptions: []ChannelSubscription{},
+			apiCalls: hasSubscriptions(
+				[]ChannelSubscription{
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("myproject"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("things"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+				}, t),
+		},
+		"Invalid limit": {
+			channelId:          "aaaaaaaaaaaaaaaaaaaaaaaaac",
+			query:              "?limit=0",
+			expectedStatusCode: http.StatusBadRequest,
+			apiCalls: hasSubscriptions(
+				[]ChannelSubscription{
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaab",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("myproject"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+					ChannelSubscription{
+						Id:        "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						ChannelId: "aaaaaaaaaaaaaaaaaaaaaaaaac",
+						Filters: SubscriptionFilters{
+							Events:     NewStringSet("jira:issue_created"),
+							Projects:   NewStringSet("things"),
+							IssueTypes: NewStringSet("10001"),
+						},
+					},
+				}, t),
+		},
 		"Only in channel": {
 			channelId:          "aaaaaaaaaaaaaaaaaaaaaaaaac",
 			expectedStatusCode: http.StatusOK,
@@ -906,6 +1197,7 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			api := &plugintest.API{}
+			api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
 			p := Plugin{}
 
 			api.On("LogDebug",
@@ -919,6 +1211,8 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 			api.On("LogError",
 				mock.AnythingOfTypeArgument("string"),
@@ -944,6 +1238,8 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
+				mock.AnythingOfTypeArgument("string"),
 				mock.AnythingOfTypeArgument("string")).Return(nil)
 
 			api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
@@ -959,12 +1255,15 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 			p.currentInstanceStore = mockCurrentInstanceStore{&p}
 
 			w := httptest.NewRecorder()
-			request := httptest.NewRequest("GET", "/api/v2/subscriptions/channel/"+tc.channelId, nil)
+			request := httptest.NewRequest("GET", "/api/v2/subscriptions/channel/"+tc.channelId+tc.query, nil)
 			if !tc.skipAuthorize {
 				request.Header.Set("Mattermost-User-Id", model.NewId())
 			}
 			p.ServeHTTP(&plugin.Context{}, w, request)
 			assert.Equal(t, tc.expectedStatusCode, w.Result().StatusCode)
+			if tc.expectedTotal != "" {
+				assert.Equal(t, tc.expectedTotal, w.Result().Header.Get("X-Total-Count"))
+			}
 
 			if tc.returnedSubscriptions != nil {
 				subscriptions := []ChannelSubscription{}
@@ -976,3 +1275,37 @@ func TestGetSubscriptionsForChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestIssueRedirect(t *testing.T) {
+	for name, tc := range map[string]struct {
+		method         string
+		path           string
+		skipAuthorize  bool
+		expectedStatus int
+		expectedURL    string
+	}{
+		"redirects":            {method: "GET", path: "/issue/TES-12", expectedStatus: http.StatusFound, expectedURL: mockCurrentInstanceURL + "/browse/TES-12"},
+		"uppercases the key":   {method: "GET", path: "/issue/tes_2-12", expectedStatus: http.StatusFound, expectedURL: mockCurrentInstanceURL + "/browse/TES_2-12"},
+		"invalid key":          {method: "GET", path: "/issue/TES-12/../x", expectedStatus: http.StatusBadRequest},
+		"missing key":          {method: "GET", path: "/issue/", expectedStatus: http.StatusBadRequest},
+		"not authorized":       {method: "GET", path: "/issue/TES-12", skipAuthorize: true, expectedStatus: http.StatusUnauthorized},
+		"method not supported": {method: "POST", path: "/issue/TES-12", expectedStatus: http.StatusMethodNotAllowed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := Plugin{}
+			p.SetAPI(&plugintest.API{})
+			p.currentInstanceStore = mockCurrentInstanceStore{&p}
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(tc.method, tc.path, nil)
+			if !tc.skipAuthorize {
+				request.Header.Set("Mattermost-User-Id", model.NewId())
+			}
+			status, _ := handleHTTPRequest(&p, &plugin.Context{}, w, request)
+			assert.Equal(t, tc.expectedStatus, status)
+			if tc.expectedURL != "" {
+				assert.Equal(t, tc.expectedURL, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/go-i18n/i18n/bundle"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const defaultLocale = "en"
+
+const keyChannelLocale = "channel_locale_"
+
+// Message IDs of the localized user-facing strings. The translations are
+// printf formats, and are looked up in assets/i18n/<locale>.json.
+const (
+	msgHelpHeader            = "jira.command.help.header"
+	msgHelpNotConnected      = "jira.command.help.not_connected"
+	msgHelpSubscriptions     = "jira.command.help.subscriptions"
+	msgHelpSysAdmin          = "jira.command.help.sysadmin"
+	msgHelpButtonConnect     = "jira.command.help.button.connect"
+	msgHelpButtonCreate      = "jira.command.help.button.create"
+	msgHelpButtonSubscribe   = "jira.command.help.button.subscribe"
+	msgInstanceLoadFailed    = "jira.command.instance_load_failed"
+	msgNoInstance            = "jira.command.no_instance"
+	msgNotConnected          = "jira.command.not_connected"
+	msgJiraUnreachable       = "jira.command.jira_unreachable"
+	msgAlreadyConnected      = "jira.command.connect.already_connected"
+	msgConnectLink           = "jira.command.connect.link"
+	msgDisconnectNotLinked   = "jira.command.disconnect.not_linked"
+	msgDisconnectFailed      = "jira.command.disconnect.failed"
+	msgDisconnected          = "jira.command.disconnect.success"
+	msgCurrentSettings       = "jira.command.settings.current"
+	msgChannelLocaleUsage    = "jira.command.locale.usage"
+	msgChannelLocaleSet      = "jira.command.locale.set"
+	msgChannelLocaleReset    = "jira.command.locale.reset"
+	msgChannelLocaleUnknown  = "jira.command.locale.unknown"
+	msgFilterSubscriptionNew = "jira.post.filter_subscription.new_issue"
+	msgWarRoomSubscribed     = "jira.post.war_room.subscribed"
+	msgRestrictedComment     = "jira.post.restricted_comment"
+	msgIssueDeleted          = "jira.post.issue_deleted"
+	msgAnonymousJiraUser     = "jira.post.anonymous_user"
+	msgUpdatesDigestHeader   = "jira.post.updates_digest.header"
+	msgUpdatesDigestIssue    = "jira.post.updates_digest.issue"
+	msgUpdatesDigestMore     = "jira.post.updates_digest.more"
+	msgReportHeader          = "jira.post.report.header"
+	msgReportEmpty           = "jira.post.report.empty"
+	msgReportMore            = "jira.post.report.more"
+	msgDiscussedHeader       = "jira.post.discussed.header"
+	msgDiscussedIssue        = "jira.post.discussed.issue"
+	msgDiscussedMore         = "jira.post.discussed.more"
+	msgJiraUserDeleted       = "jira.dm.sysadmin.user_deleted"
+	msgJiraUserDeactivated   = "jira.dm.sysadmin.user_deactivated"
+	msgMappedUserActivity    = "jira.dm.mapped_user.activity"
+)
+
+// Message IDs of the webhook events posted to the channels, and of the
+// notifications sent to the users they concern. The headlines take the Jira
+// user first and the issue link last, translations may reorder them with
+// explicit argument indexes, e.g. %[2]s.
+const (
+	msgWebhookPropertySet                  = "jira.webhook.property_set"
+	msgWebhookPropertySetBy                = "jira.webhook.property_set_by"
+	msgWebhookProjectCreated               = "jira.webhook.project_created"
+	msgWebhookProjectDeleted               = "jira.webhook.project_deleted"
+	msgWebhookUserCreated                  = "jira.webhook.user_created"
+	msgWebhookUserUpdated                  = "jira.webhook.user_updated"
+	msgWebhookUserDeleted                  = "jira.webhook.user_deleted"
+	msgWebhookUnrecognized                 = "jira.webhook.unrecognized"
+	msgWebhookCreated                      = "jira.webhook.created"
+	msgWebhookDeleted                      = "jira.webhook.deleted"
+	msgWebhookCommented                    = "jira.webhook.commented"
+	msgWebhookCommentDeleted               = "jira.webhook.comment_deleted"
+	msgWebhookCommentEdited                = "jira.webhook.comment_edited"
+	msgWebhookAssigned                     = "jira.webhook.assigned"
+	msgWebhookReopened                     = "jira.webhook.reopened"
+	msgWebhookResolved                     = "jira.webhook.resolved"
+	msgWebhookUpdatedField                 = "jira.webhook.updated_field"
+	msgWebhookMoved                        = "jira.webhook.moved"
+	msgWebhookEditedDescription            = "jira.webhook.edited_description"
+	msgWebhookAttached                     = "jira.webhook.attached"
+	msgWebhookRemovedAttachments           = "jira.webhook.removed_attachments"
+	msgWebhookAttachedRemovedAttachments   = "jira.webhook.attached_removed_attachments"
+	msgWebhookAddedLabels                  = "jira.webhook.added_labels"
+	msgWebhookRemovedLabels                = "jira.webhook.removed_labels"
+	msgWebhookAddedRemovedLabels           = "jira.webhook.added_removed_labels"
+	msgWebhookUpdated                      = "jira.webhook.updated"
+	msgWebhookSimulated                    = "jira.webhook.simulated"
+	msgWebhookShowMore                     = "jira.webhook.show_more"
+	msgWebhookFieldValue                   = "jira.webhook.field.value"
+	msgWebhookFieldLead                    = "jira.webhook.field.lead"
+	msgWebhookFieldAssignee                = "jira.webhook.field.assignee"
+	msgWebhookFieldPriority                = "jira.webhook.field.priority"
+	msgWebhookFieldEstimate                = "jira.webhook.field.estimate"
+	msgWebhookFieldReporter                = "jira.webhook.field.reporter"
+	msgWebhookFieldVotes                   = "jira.webhook.field.votes"
+	msgWebhookFieldWatchers                = "jira.webhook.field.watchers"
+	msgEstimateRemaining                   = "jira.issue.estimate.remaining"
+	msgEstimateRemainingOf                 = "jira.issue.estimate.remaining_of"
+	msgNotificationMentionedNewComment     = "jira.dm.notification.mentioned_new_comment"
+	msgNotificationMentionedUpdatedComment = "jira.dm.notification.mentioned_updated_comment"
+	msgNotificationCommented               = "jira.dm.notification.commented"
+	msgNotificationAssigned                = "jira.dm.notification.assigned"
+)
+
+// Message IDs of the other posts and direct messages of the bot, and of the
+// buttons of its posts.
+const (
+	msgActionTransition          = "jira.post.action.transition"
+	msgActionEditFields          = "jira.post.action.edit_fields"
+	msgActionQuickTriage         = "jira.post.action.quick_triage"
+	msgActionOpenInApp           = "jira.post.action.open_in_app"
+	msgActionBackfill            = "jira.post.action.backfill"
+	msgActionSnooze              = "jira.post.action.snooze"
+	msgActionSnoozeDay           = "jira.post.action.snooze_day"
+	msgActionSnoozeDays          = "jira.post.action.snooze_days"
+	msgActionQuickTransition     = "jira.post.action.quick_transition"
+	msgActionQuickLabel          = "jira.post.action.quick_label"
+	msgActionUnwatch             = "jira.post.action.unwatch"
+	msgSubscriptionAdded         = "jira.post.subscription.added"
+	msgSubscriptionUpdated       = "jira.post.subscription.updated"
+	msgSubscriptionRemoved       = "jira.post.subscription.removed"
+	msgSubscriptionsIssueMoved   = "jira.post.subscription.issue_moved"
+	msgSubscriptionsUnarchived   = "jira.post.subscription.unarchived"
+	msgSubscriptionsRestricted   = "jira.post.subscription.restricted"
+	msgSubscriptionsUnrestricted = "jira.post.subscription.unrestricted"
+	msgBackfillNoIssues          = "jira.post.backfill.no_issues"
+	msgBackfillLastIssues        = "jira.post.backfill.last_issues"
+	msgBackfillIssues            = "jira.post.backfill.issues"
+	msgChannelStatusOpenIssue    = "jira.post.channel_status.open_issue"
+	msgChannelStatusOpenIssues   = "jira.post.channel_status.open_issues"
+	msgBlockedArchived           = "jira.post.blocked.archived"
+	msgBlockedReadOnly           = "jira.post.blocked.read_only"
+	msgBlockedBotMissing         = "jira.post.blocked.bot_missing"
+	msgBlockedBotDeactivated     = "jira.post.blocked.bot_deactivated"
+	msgBlockedEvent              = "jira.post.blocked.event"
+	msgBlockedEvents             = "jira.post.blocked.events"
+	msgIssueCreated              = "jira.post.issue.created"
+	msgMessageAttached           = "jira.post.issue.message_attached"
+	msgWorklogLogged             = "jira.post.worklog.logged"
+	msgTodoIssues                = "jira.post.todo.issues"
+	msgTodoNoIssues              = "jira.post.todo.no_issues"
+	msgTodoSnoozed               = "jira.post.todo.snoozed"
+	msgMappingRequested          = "jira.post.admin.mapping_requested"
+	msgAlertWebhookAuthFailures  = "jira.post.admin.webhook_auth_failures"
+	msgAlertConfigProblems       = "jira.post.admin.config_problems"
+	msgAlertJiraUnreachable      = "jira.post.admin.jira_unreachable"
+	msgAlertJiraReachable        = "jira.post.admin.jira_reachable"
+	msgAlertJiraAuthExpired      = "jira.post.admin.jira_auth_expired"
+	msgAlertWebhookQueueFull     = "jira.post.admin.webhook_queue_full"
+	msgBlockedSubscription       = "jira.dm.subscription.blocked"
+	msgBlockedDropped            = "jira.dm.subscription.blocked.dropped"
+	msgBlockedDroppedFallback    = "jira.dm.subscription.blocked.dropped_fallback"
+	msgBlockedDroppedNotified    = "jira.dm.subscription.blocked.dropped_notified"
+	msgMappingApproved           = "jira.dm.mapped_user.approved"
+	msgAttachmentFailed          = "jira.dm.attachment_failed"
+)
+
+// defaultMessages are the English strings, used when a translation is not
+// available in the requested locale.
+var defaultMessages = map[string]string{
+	msgHelpHeader:            helpTextHeader,
+	msgHelpNotConnected:      "\nConnect your Jira account to use the other commands:\n",
+	msgHelpSubscriptions:     "\n###### Channel subscriptions:\n",
+	msgHelpSysAdmin:          "\n###### For System Administrators:\n",
+	msgHelpButtonConnect:     "Connect to Jira",
+	msgHelpButtonCreate:      "Create an issue",
+	msgHelpButtonSubscribe:   "Create a subscription",
+	msgInstanceLoadFailed:    "Failed to load current Jira instance. Please contact your system administrator.",
+	msgNoInstance:            "There is no Jira instance installed. Please contact your system administrator.",
+	msgNotConnected:          "Your username is not connected to Jira. Please type `jira connect`.",
+	msgJiraUnreachable:       "Jira is currently unreachable. Please try again later, or contact your system administrator if the problem persists.",
+	msgAlreadyConnected:      "You already have a Jira account linked to your Mattermost account. Please use `/jira disconnect` to disconnect.",
+	msgConnectLink:           "[Click here to link your Jira account](%s)",
+	msgDisconnectNotLinked:   "Could not complete the **disconnection** request. You do not currently have a Jira account linked to your Mattermost account.",
+	msgDisconnectFailed:      "Could not complete the **disconnection** request. Error: %v",
+	msgDisconnected:          "You have successfully disconnected your Jira account (**%s**).",
+	msgCurrentSettings:       "Current settings:\n%s",
+	msgChannelLocaleUsage:    "Please specify a locale in the form `/jira locale channel <locale>`, or `default` to use the server locale.",
+	msgChannelLocaleSet:      "Jira notifications in this channel will be posted in `%s`.",
+	msgChannelLocaleReset:    "Jira notifications in this channel will be posted in the server locale.",
+	msgChannelLocaleUnknown:  "`%s` is not a locale of the plugin. The locales are: %s.",
+	msgFilterSubscriptionNew: "New issue matching filter subscription **%s**",
+	msgWarRoomSubscribed:     "This channel is subscribed to all events of %s.",
+	msgRestrictedComment:     "_This comment is restricted to the %s **%s**._",
+	msgIssueDeleted:          "**Deleted in Jira:** %s",
+	msgAnonymousJiraUser:     "A Jira user",
+	msgUpdatesDigestHeader:   "**Jira digest** of the last hour, events: %d, issues: %d",
+	msgUpdatesDigestIssue:    "* %s, events: %d, by: %s",
+	msgUpdatesDigestMore:     "* more issues: %d",
+	msgReportHeader:          "#### Jira report **%s**, issues: %d",
+	msgReportEmpty:           "#### Jira report **%s**\nNo issues match.",
+	msgReportMore:            "_Only the first %d issues are listed._",
+	msgDiscussedHeader:       "#### Jira issues discussed in this channel since %s",
+	msgDiscussedIssue:        "* %s, mentions: %d",
+	msgDiscussedMore:         "_Only the %d most mentioned issues are listed._",
+	msgJiraUserDeleted:       "Jira account **%s**, connected to Mattermost user %s, was deleted in Jira, and has been disconnected.",
+	msgJiraUserDeactivated:   "Jira account **%s**, connected to Mattermost user %s, was deactivated in Jira.",
+	msgMappedUserActivity:    "You were mentioned or assigned in Jira issue %s. Connect your Jira account with `/jira connect` to see the details here.",
+
+	msgWebhookPropertySet:                  "Property `%s` was **set** on %s",
+	msgWebhookPropertySetBy:                "%s **set** property `%s` on %s",
+	msgWebhookProjectCreated:               "Project %s was **created**",
+	msgWebhookProjectDeleted:               "Project %s (%s) was **deleted**",
+	msgWebhookUserCreated:                  "Jira user %s was **created**",
+	msgWebhookUserUpdated:                  "Jira user %s was **updated**",
+	msgWebhookUserDeleted:                  "Jira user %s was **deleted**",
+	msgWebhookUnrecognized:                 "%s triggered `%s` on %s",
+	msgWebhookCreated:                      "%s **created** %s",
+	msgWebhookDeleted:                      "%s **deleted** %s",
+	msgWebhookCommented:                    "%s **commented** on %s",
+	msgWebhookCommentDeleted:               "%s **deleted comment** in %s",
+	msgWebhookCommentEdited:                "%s **edited comment** in %s",
+	msgWebhookAssigned:                     "%s **assigned** %s to %s",
+	msgWebhookReopened:                     "%s **reopened** %s",
+	msgWebhookResolved:                     "%s **resolved** %s",
+	msgWebhookUpdatedField:                 "%s **updated** %s from %s to %s on %s",
+	msgWebhookMoved:                        "%s **moved** %s to %s",
+	msgWebhookEditedDescription:            "%s **edited** the description of %s",
+	msgWebhookAttached:                     "%s **attached** [%s] to %s",
+	msgWebhookRemovedAttachments:           "%s **removed** attachments [%s] from %s",
+	msgWebhookAttachedRemovedAttachments:   "%s **attached** [%s] to, **removed** attachments [%s] from %s",
+	msgWebhookAddedLabels:                  "%s **added** labels [%s] to %s",
+	msgWebhookRemovedLabels:                "%s **removed** labels [%s] from %s",
+	msgWebhookAddedRemovedLabels:           "%s **added** labels [%s] to, **removed** labels [%s] from %s",
+	msgWebhookUpdated:                      "%s **updated** %s",
+	msgWebhookSimulated:                    "**[TEST]** %s",
+	msgWebhookShowMore:                     "Show more",
+	msgWebhookFieldValue:                   "Value",
+	msgWebhookFieldLead:                    "Lead",
+	msgWebhookFieldAssignee:                "Assignee",
+	msgWebhookFieldPriority:                "Priority",
+	msgWebhookFieldEstimate:                "Estimate",
+	msgWebhookFieldReporter:                "Reporter",
+	msgWebhookFieldVotes:                   "Votes",
+	msgWebhookFieldWatchers:                "Watchers",
+	msgEstimateRemaining:                   "%s remaining",
+	msgEstimateRemainingOf:                 "%s remaining of %s",
+	msgNotificationMentionedNewComment:     "%s **mentioned** you in a new comment on %s:\n>%s",
+	msgNotificationMentionedUpdatedComment: "%s **mentioned** you in a comment update on %s:\n>%s",
+	msgNotificationCommented:               "%s **commented** on %s:\n>%s",
+	msgNotificationAssigned:                "%s **assigned** you to %s",
+
+	msgActionTransition:          "Transition",
+	msgActionEditFields:          "Edit fields",
+	msgActionQuickTriage:         "Quick triage",
+	msgActionOpenInApp:           "Open in app",
+	msgActionBackfill:            "Post open issues",
+	msgActionSnooze:              "Snooze",
+	msgActionSnoozeDay:           "Snooze for a day",
+	msgActionSnoozeDays:          "Snooze for %d days",
+	msgActionQuickTransition:     "Move to %s",
+	msgActionQuickLabel:          "Add label %s",
+	msgActionUnwatch:             "Unwatch",
+	msgSubscriptionAdded:         "Jira subscription, \"%v\", was added to this channel by %v",
+	msgSubscriptionUpdated:       "Jira subscription, \"%v\", was updated by %v",
+	msgSubscriptionRemoved:       "Jira subscription, \"%v\", was removed from this channel by %v",
+	msgSubscriptionsIssueMoved:   "Jira issue %s was moved to [%s](%s/browse/%s), the following subscriptions now follow it: %s.",
+	msgSubscriptionsUnarchived:   "The following Jira subscriptions were paused while this channel was archived, and resumed posting: %s.",
+	msgSubscriptionsRestricted:   "The following Jira subscriptions no longer comply with the project restrictions of this channel, and stopped posting: %s. Edit or delete them to fix this.",
+	msgSubscriptionsUnrestricted: "The following Jira subscriptions comply with the project restrictions of this channel again, and resumed posting: %s.",
+	msgBackfillNoIssues:          "Jira subscription %q has no open issues.",
+	msgBackfillLastIssues:        "Jira subscription %q has %d open issues, the %d last updated are ([view all](%s)):",
+	msgBackfillIssues:            "Open issues of Jira subscription %q ([view in Jira](%s)):",
+	msgChannelStatusOpenIssue:    "Jira: [%d open issue](%s)",
+	msgChannelStatusOpenIssues:   "Jira: [%d open issues](%s)",
+	msgBlockedArchived:           "the channel is archived",
+	msgBlockedReadOnly:           "the channel is read-only",
+	msgBlockedBotMissing:         "the Jira bot account is missing",
+	msgBlockedBotDeactivated:     "the Jira bot account is deactivated",
+	msgBlockedEvent:              "1 Jira event for ~%s could not be delivered, because %s.",
+	msgBlockedEvents:             "%d Jira events for ~%s could not be delivered, because %s.",
+	msgIssueCreated:              "Created a Jira issue %v/browse/%v",
+	msgMessageAttached:           "Message attached to [%v](%v/browse/%v)",
+	msgWorklogLogged:             "%s logged %s on %s",
+	msgTodoIssues:                "Your open Jira issues:",
+	msgTodoNoIssues:              "You have no open Jira issues to work on.",
+	msgTodoSnoozed:               "%d snoozed issues are not listed, `/jira todo unsnooze <issue-key>` brings one back.",
+	msgMappingRequested:          "@%s asks to receive the Jira notifications of Jira user `%s`. Run `/jira connect approve @%s` to approve.",
+	msgAlertWebhookAuthFailures:  "%d Jira webhook requests were rejected in the last %v because their secret did not match. If the webhook secret was regenerated, please update the webhook URLs in Jira.",
+	msgAlertConfigProblems:       "The Jira plugin settings have problems, please fix them in **System Console > Plugins > Jira**, or run `/jira diagnostics`:\n%s",
+	msgAlertJiraUnreachable:      "Jira at %s is unreachable: %v. Users are told to try again later until it responds.",
+	msgAlertJiraReachable:        "Jira at %s is reachable again.",
+	msgAlertJiraAuthExpired:      "Jira rejected the credentials of the plugin's app installation on %s. The app may have been uninstalled in Jira, please re-install it.",
+	msgAlertWebhookQueueFull:     "A Jira webhook event was dropped because the processing queue is full. Jira may be sending more events than the plugin can post.",
+	msgBlockedSubscription:       "Your Jira subscription %q could not post an event to ~%s, because %s. %s Please move or delete the subscription.",
+	msgBlockedDropped:            "The event was dropped.",
+	msgBlockedDroppedFallback:    "The event was dropped, and the fallback channel was notified.",
+	msgBlockedDroppedNotified:    "The event was dropped, and ~%s was notified.",
+	msgMappingApproved:           "Your account is now mapped to Jira user %s. You will get a direct message when you are mentioned or assigned in Jira.",
+	msgAttachmentFailed:          "Failed to attach to issue: %s, %s. Please notify your system administrator.\n%s",
+}
+
+// localizedText is a message id with its arguments, rendered once the locale
+// of the channel or user it is posted to is known. The arguments that are
+// localizedText are rendered in the same locale.
+type localizedText struct {
+	id   string
+	args []interface{}
+}
+
+func newLocalizedText(id string, args ...interface{}) *localizedText {
+	return &localizedText{id: id, args: args}
+}
+
+// String renders the text in English.
+func (t *localizedText) String() string {
+	return (&Plugin{}).render(defaultLocale, t)
+}
+
+// render formats text in locale.
+func (p *Plugin) render(locale string, text *localizedText) string {
+	if text == nil {
+		return ""
+	}
+	args := make([]interface{}, len(text.args))
+	for i, arg := range text.args {
+		if t, ok := arg.(*localizedText); ok {
+			arg = p.render(locale, t)
+		}
+		args[i] = arg
+	}
+	return p.localize(locale, text.id, args...)
+}
+
+// localizer formats message ids in the locale it was made for.
+type localizer func(id string, args ...interface{}) string
+
+// englishLocalizer formats message ids in English, for the texts that are
+// not localized.
+func englishLocalizer(id string, args ...interface{}) string {
+	return (&Plugin{}).localize(defaultLocale, id, args...)
+}
+
+// localizer returns the localizer of locale.
+func (p *Plugin) localizer(locale string) localizer {
+	return func(id string, args ...interface{}) string {
+		return p.localize(locale, id, args...)
+	}
+}
+
+// loadTranslations reads the translation files in dir, one <locale>.json per
+// locale in the go-i18n format. English translations default to
+// defaultMessages.
+func loadTranslations(dir string) (*bundle.Bundle, error) {
+	b := bundle.New()
+
+	defaults := []map[string]string{}
+	for id, message := range defaultMessages {
+		defaults = append(defaults, map[string]string{"id": id, "translation": message})
+	}
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return nil, err
+	}
+	err = b.ParseTranslationFileBytes(defaultLocale+".json", data)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read translations directory")
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		err = b.LoadTranslationFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load translation file %s", file.Name())
+		}
+	}
+	return b, nil
+}
+
+// localize formats the message id in locale, falling back to English.
+func (p *Plugin) localize(locale, id string, args ...interface{}) string {
+	format := defaultMessages[id]
+	if p.translations != nil {
+		if tfunc, err := p.translations.Tfunc(locale, defaultLocale); err == nil {
+			if translated := tfunc(id); translated != id {
+				format = translated
+			}
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// isLocalized is false when no translations other than English are loaded,
+// in which case looking up users' and channels' locales is unnecessary.
+func (p *Plugin) isLocalized() bool {
+	return p.translations != nil && len(p.translations.LanguageTags()) > 1
+}
+
+// bundledLocale returns the translations loaded for the locale, matching its
+// language, e.g. `es` for `es-ES`, or "" if there are none.
+func (p *Plugin) bundledLocale(locale string) string {
+	for _, tag := range p.bundledLocales() {
+		if tag == locale {
+			return tag
+		}
+	}
+	language := strings.SplitN(strings.Replace(locale, "_", "-", 1), "-", 2)[0]
+	for _, tag := range p.bundledLocales() {
+		if tag == language {
+			return tag
+		}
+	}
+	return ""
+}
+
+// bundledLocales returns the sorted locales of the loaded translations,
+// English included.
+func (p *Plugin) bundledLocales() []string {
+	locales := NewStringSet(defaultLocale)
+	if p.translations != nil {
+		locales = locales.Add(p.translations.LanguageTags()...)
+	}
+	sorted := locales.Elems()
+	sort.Strings(sorted)
+	return sorted
+}
+
+// serverLocale is the DefaultLocale plugin setting, or the server's default
+// locale when the setting is empty.
+func (p *Plugin) serverLocale() string {
+	if locale := p.getConfig().DefaultLocale; locale != "" {
+		return locale
+	}
+	config := p.API.GetConfig()
+	if config != nil && config.LocalizationSettings.DefaultServerLocale != nil &&
+		*config.LocalizationSettings.DefaultServerLocale != "" {
+		return *config.LocalizationSettings.DefaultServerLocale
+	}
+	return defaultLocale
+}
+
+// userLocale is the locale selected by the Mattermost user, or the server
+// locale.
+func (p *Plugin) userLocale(mattermostUserId string) string {
+	if !p.isLocalized() {
+		return defaultLocale
+	}
+	user, appErr := p.API.GetUser(mattermostUserId)
+	if appErr == nil && user.Locale != "" {
+		return user.Locale
+	}
+	return p.serverLocale()
+}
+
+// channelLocale is the locale set for the channel with `/jira locale channel`,
+// or the server locale.
+func (p *Plugin) channelLocale(channelId string) string {
+	if !p.isLocalized() {
+		return defaultLocale
+	}
+	data, appErr := p.API.KVGet(keyChannelLocale + channelId)
+	if appErr == nil && len(data) != 0 {
+		return string(data)
+	}
+	return p.serverLocale()
+}
+
+// responseT posts the localized message id as the response to a command. The
+// other responses, posted with responsef, are in English.
+func (p *Plugin) responseT(commandArgs *model.CommandArgs, id string, args ...interface{}) *model.CommandResponse {
+	p.postCommandResponse(commandArgs, p.localize(p.userLocale(commandArgs.UserId), id, args...))
+	return &model.CommandResponse{}
+}
+
+func executeLocaleChannel(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responseT(header, msgChannelLocaleUsage)
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to change the locale of this channel: %v", err)
+	}
+
+	locale := strings.ToLower(args[0])
+	if locale == "default" {
+		appErr := p.API.KVDelete(keyChannelLocale + header.ChannelId)
+		if appErr != nil {
+			return p.responsef(header, "%v", appErr)
+		}
+		return p.responseT(header, msgChannelLocaleReset)
+	}
+
+	bundled := p.bundledLocale(locale)
+	if bundled == "" {
+		return p.responseT(header, msgChannelLocaleUnknown, locale, "`"+strings.Join(p.bundledLocales(), "`, `")+"`")
+	}
+	locale = bundled
+
+	appErr := p.API.KVSet(keyChannelLocale+header.ChannelId, []byte(locale))
+	if appErr != nil {
+		return p.responsef(header, "%v", appErr)
+	}
+	return p.responseT(header, msgChannelLocaleSet, locale)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	translations, err := loadTranslations("../assets/i18n")
+	require.NoError(t, err)
+	p := &Plugin{translations: translations}
+
+	t.Run("english", func(t *testing.T) {
+		assert.Equal(t, "You have successfully disconnected your Jira account (**Jane**).",
+			p.localize("en", msgDisconnected, "Jane"))
+	})
+
+	t.Run("translated", func(t *testing.T) {
+		assert.Equal(t, "Has desconectado correctamente tu cuenta de Jira (**Jane**).",
+			p.localize("es", msgDisconnected, "Jane"))
+	})
+
+	t.Run("unsupported locale falls back to english", func(t *testing.T) {
+		assert.Equal(t, defaultMessages[msgNotConnected], p.localize("xx", msgNotConnected))
+	})
+
+	t.Run("no translations loaded", func(t *testing.T) {
+		assert.Equal(t, defaultMessages[msgNotConnected], (&Plugin{}).localize("es", msgNotConnected))
+	})
+
+	t.Run("bundled locales", func(t *testing.T) {
+		assert.Equal(t, []string{"en", "es"}, p.bundledLocales())
+		assert.Equal(t, "es", p.bundledLocale("es"))
+		assert.Equal(t, "es", p.bundledLocale("es-ES"))
+		assert.Equal(t, "en", p.bundledLocale("en_US"))
+		assert.Equal(t, "", p.bundledLocale("xx"))
+		assert.Equal(t, []string{"en"}, (&Plugin{}).bundledLocales())
+	})
+
+	t.Run("translations match the english formats", func(t *testing.T) {
+		for _, tag := range translations.LanguageTags() {
+			for _, id := range translations.LanguageTranslationIDs(tag) {
+				english, ok := defaultMessages[id]
+				require.True(t, ok, "unknown message id %s in %s", id, tag)
+				tfunc, err := translations.Tfunc(tag)
+				require.NoError(t, err)
+				assert.Equal(t, strings.Count(english, "%"), strings.Count(tfunc(id), "%"),
+					"mismatched format verbs in %s translation of %s", tag, id)
+			}
+		}
+	})
+}
+
+func TestLocalizedWebhookPost(t *testing.T) {
+	translations, err := loadTranslations("../assets/i18n")
+	require.NoError(t, err)
+	api := &plugintest.API{}
+	api.On("KVGet", keyChannelLocale+"channel_es").Return([]byte("es"), nil)
+	api.On("KVGet", keyChannelLocale+"channel_en").Return(nil, nil)
+	api.On("GetConfig").Return(&model.Config{})
+	posted := map[string]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		posted[post.ChannelId] = post
+	}).Return(nil, nil)
+	p := &Plugin{translations: translations}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {})
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.NoError(t, err)
+	parsed, err := ParseWebhook(data)
+	require.NoError(t, err)
+	wh := parsed.(*webhook)
+
+	t.Run("rendered in the channel locale", func(t *testing.T) {
+		_, _, err = wh.PostToChannel(p, "channel_es", "botUserId")
+		require.NoError(t, err)
+		attachment := posted["channel_es"].Attachments()[0]
+		assert.True(t, strings.HasPrefix(attachment.Pretext, "Test User **creó** "), attachment.Pretext)
+		assert.Equal(t, "Prioridad", attachment.Fields[0].Title)
+		assert.Equal(t, "High", attachment.Fields[0].Value)
+		assert.Equal(t, "Cambiar estado", attachment.Actions[0].Name)
+
+		_, _, err = wh.PostToChannel(p, "channel_en", "botUserId")
+		require.NoError(t, err)
+		attachment = posted["channel_en"].Attachments()[0]
+		assert.Equal(t, wh.headline, attachment.Pretext)
+		assert.Equal(t, "Priority", attachment.Fields[0].Title)
+		assert.Equal(t, "Transition", attachment.Actions[0].Name)
+		// The fields shared by the posts of the event are unchanged
+		assert.Equal(t, "Priority", wh.fields[0].Title)
+	})
+
+	t.Run("hidden author and simulated event", func(t *testing.T) {
+		hidden := wh.withHiddenAuthor(p.localize("es", msgAnonymousJiraUser))
+		hidden.simulated = true
+		_, _, err = hidden.PostToChannel(p, "channel_es", "botUserId")
+		require.NoError(t, err)
+		attachment := posted["channel_es"].Attachments()[0]
+		assert.True(t, strings.HasPrefix(attachment.Pretext, "**[PRUEBA]** Un usuario de Jira **creó** "), attachment.Pretext)
+		assert.NotContains(t, attachment.Pretext, "Test User")
+	})
+
+	t.Run("notifications rendered in the user locale", func(t *testing.T) {
+		jwh := &JiraWebhook{}
+		jwh.User = jira.User{DisplayName: "Test User", AccountID: "author"}
+		jwh.Issue = jira.Issue{Key: "TES-1", Fields: &jira.IssueFields{
+			Summary:  "Summary",
+			Assignee: &jira.User{AccountID: "assignee"},
+		}}
+		assigned := parseWebhookAssigned(jwh, "", "Assignee")
+		require.Len(t, assigned.notifications, 1)
+		message := assigned.notifications[0].message
+		assert.True(t, strings.HasPrefix(p.render("es", message), "Test User te **asignó** "), p.render("es", message))
+		assert.True(t, strings.HasPrefix(message.String(), "Test User **assigned** you to "), message.String())
+	})
+}
+
+func TestRenderLocalizedText(t *testing.T) {
+	translations, err := loadTranslations("../assets/i18n")
+	require.NoError(t, err)
+	p := &Plugin{translations: translations}
+
+	text := newLocalizedText(msgBlockedSubscription, "Bugs", "town-square",
+		newLocalizedText(msgBlockedReadOnly), newLocalizedText(msgBlockedDroppedNotified, "fallback"))
+	assert.Equal(t, `Your Jira subscription "Bugs" could not post an event to ~town-square, because the channel is read-only. `+
+		`The event was dropped, and ~fallback was notified. Please move or delete the subscription.`, text.String())
+	assert.Equal(t, `Tu suscripción de Jira "Bugs" no pudo publicar un evento en ~town-square, porque el canal es de solo lectura. `+
+		`Se descartó el evento, y se avisó a ~fallback. Mueve o elimina la suscripción.`, p.render("es", text))
+	assert.Equal(t, "", p.render("es", nil))
+}
@@ -16,9 +16,6 @@ import (
 	ajwt "github.com/rbriski/atlassian-jwt"
 	"golang.org/x/oauth2"
 	oauth2_jira "golang.org/x/oauth2/jira"
-
-	"github.com/mattermost/mattermost-plugin-jira/server/expvar"
-	"github.com/mattermost/mattermost-plugin-jira/server/utils"
 )
 
 type jiraCloudInstance struct {
@@ -141,13 +138,8 @@ func (jci jiraCloudInstance) getJIRAClientForUser(jiraUser JIRAUser) (*jira.Clie
 		},
 	}
 
-	conf := jci.GetPlugin().getConfig()
 	httpClient := oauth2Conf.Client(context.Background())
-	httpClient = utils.WrapHTTPClient(httpClient,
-		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
-		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
-	httpClient = expvar.WrapHTTPClient(httpClient,
-		conf.stats, endpointNameFromRequest)
+	httpClient = jci.GetPlugin().wrapJiraHTTPClient(httpClient, jci.GetURL())
 
 	jiraClient, err := jira.NewClient(httpClient, oauth2Conf.BaseURL)
 	return jiraClient, httpClient, err
@@ -155,7 +147,6 @@ func (jci jiraCloudInstance) getJIRAClientForUser(jiraUser JIRAUser) (*jira.Clie
 
 // Creates a "bot" client with a JWT
 func (jci jiraCloudInstance) getJIRAClientForServer() (*jira.Client, error) {
-	conf := jci.GetPlugin().getConfig()
 	jwtConf := &ajwt.Config{
 		Key:          jci.AtlassianSecurityContext.Key,
 		ClientKey:    jci.AtlassianSecurityContext.ClientKey,
@@ -164,11 +155,7 @@ func (jci jiraCloudInstance) getJIRAClientForServer() (*jira.Client, error) {
 	}
 
 	httpClient := jwtConf.Client()
-	httpClient = utils.WrapHTTPClient(httpClient,
-		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
-		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
-	httpClient = expvar.WrapHTTPClient(httpClient,
-		conf.stats, endpointNameFromRequest)
+	httpClient = jci.GetPlugin().wrapJiraHTTPClient(httpClient, jci.GetURL())
 
 	return jira.NewClient(httpClient, jwtConf.BaseURL)
 }
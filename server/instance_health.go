@@ -0,0 +1,145 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	instanceHealthCheckInterval = 2 * time.Minute
+
+	// An instance is unreachable after this many failed checks in a row, so
+	// that a single timeout doesn't block the commands.
+	instanceHealthFailureThreshold = 2
+)
+
+// Commands that don't call Jira, and still run while it is unreachable.
+var commandsWithoutJira = NewStringSet(
+	"help",
+	"info",
+	"diagnostics",
+	"install",
+	"instance",
+	"uninstall",
+	"webhook",
+	"stats",
+	"debug",
+	"locale",
+)
+
+// instanceHealth records the failed connectivity checks of the Jira
+// instances, by instance URL, on this server.
+type instanceHealth struct {
+	lock        sync.Mutex
+	failures    map[string]int
+	unreachable map[string]time.Time
+}
+
+// record records the outcome of a check, and returns true if the instance
+// became unreachable, or reachable again.
+func (h *instanceHealth) record(url string, err error, now time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failures == nil {
+		h.failures = map[string]int{}
+		h.unreachable = map[string]time.Time{}
+	}
+	_, wasUnreachable := h.unreachable[url]
+	if err == nil {
+		delete(h.failures, url)
+		delete(h.unreachable, url)
+		return wasUnreachable
+	}
+	h.failures[url]++
+	if wasUnreachable || h.failures[url] < instanceHealthFailureThreshold {
+		return false
+	}
+	h.unreachable[url] = now
+	return true
+}
+
+// isUnreachable returns true if the instance failed its last checks. Before
+// the first check, instances are reachable.
+func (h *instanceHealth) isUnreachable(url string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, ok := h.unreachable[url]
+	return ok
+}
+
+func (h *instanceHealth) anyUnreachable() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.unreachable) > 0
+}
+
+// forget drops the state of the instances that are no longer installed.
+func (h *instanceHealth) forget(known map[string]string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for url := range h.failures {
+		if _, ok := known[url]; !ok {
+			delete(h.failures, url)
+		}
+	}
+	for url := range h.unreachable {
+		if _, ok := known[url]; !ok {
+			delete(h.unreachable, url)
+		}
+	}
+}
+
+// checkInstancesHealth checks the connectivity of the installed Jira
+// instances, and alerts the admins when one becomes unreachable or reachable
+// again.
+func (p *Plugin) checkInstancesHealth() {
+	known, err := p.instanceStore.LoadKnownJIRAInstances()
+	if err != nil {
+		p.errorf("checkInstancesHealth: failed to load known Jira instances: %v", err)
+		return
+	}
+	p.instanceHealth.forget(known)
+
+	for url := range known {
+		ji, err := p.instanceStore.LoadJIRAInstance(url)
+		if err != nil {
+			p.errorf("checkInstancesHealth: %v", err)
+			continue
+		}
+		err = checkJiraConnectivity(ji)
+		if !p.instanceHealth.record(url, err, time.Now()) {
+			continue
+		}
+
+		// Every server runs the checks, only one of them alerts
+		if err != nil {
+			p.infof("Jira instance %s is unreachable: %v", url, err)
+			if p.claimJob(alertJiraUnreachable+"_"+url, instanceHealthCheckInterval) {
+				p.alertAdmins(alertJiraUnreachable+"_"+url, msgAlertJiraUnreachable, url, err)
+			}
+		} else {
+			p.infof("Jira instance %s is reachable again", url)
+			if p.claimJob(alertJiraReachable+"_"+url, instanceHealthCheckInterval) {
+				p.alertAdmins(alertJiraReachable+"_"+url, msgAlertJiraReachable, url)
+			}
+		}
+	}
+}
+
+// jiraUnreachableResponse returns the response to the commands calling Jira
+// while the current instance is unreachable, or nil.
+func (p *Plugin) jiraUnreachableResponse(header *model.CommandArgs, args []string) *model.CommandResponse {
+	if !p.instanceHealth.anyUnreachable() || len(args) == 0 || commandsWithoutJira.ContainsAny(args[0]) {
+		return nil
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil || !p.instanceHealth.isUnreachable(ji.GetURL()) {
+		return nil
+	}
+	return p.responseT(header, msgJiraUnreachable)
+}
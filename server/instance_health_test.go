@@ -0,0 +1,65 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceHealthRecord(t *testing.T) {
+	h := instanceHealth{}
+	now := time.Now()
+	failed := errors.New("timeout")
+
+	assert.False(t, h.record("https://jira.example.com", failed, now))
+	assert.False(t, h.isUnreachable("https://jira.example.com"), "a single failure is tolerated")
+	assert.True(t, h.record("https://jira.example.com", failed, now))
+	assert.True(t, h.isUnreachable("https://jira.example.com"))
+	assert.False(t, h.record("https://jira.example.com", failed, now), "already unreachable")
+	assert.False(t, h.isUnreachable("https://other.example.com"))
+
+	assert.True(t, h.record("https://jira.example.com", nil, now))
+	assert.False(t, h.isUnreachable("https://jira.example.com"))
+	assert.False(t, h.record("https://jira.example.com", failed, now), "the failures are counted again")
+
+	h.forget(map[string]string{})
+	assert.False(t, h.anyUnreachable())
+	assert.Empty(t, h.failures)
+}
+
+func TestJiraUnreachableResponse(t *testing.T) {
+	var message string
+	api := &plugintest.API{}
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(&model.Post{})
+	api.On("GetUser", "userid").Return(&model.User{Id: "userid"}, nil)
+	api.On("GetConfig").Return(&model.Config{})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	header := &model.CommandArgs{UserId: "userid", ChannelId: "channelid"}
+
+	assert.Nil(t, p.jiraUnreachableResponse(header, []string{"view", "TEST-1"}))
+
+	now := time.Now()
+	p.instanceHealth.record("https://other.example.com", errors.New("timeout"), now)
+	p.instanceHealth.record("https://other.example.com", errors.New("timeout"), now)
+	assert.Nil(t, p.jiraUnreachableResponse(header, []string{"view", "TEST-1"}), "another instance is unreachable")
+
+	p.instanceHealth.record(mockCurrentInstanceURL, errors.New("timeout"), now)
+	p.instanceHealth.record(mockCurrentInstanceURL, errors.New("timeout"), now)
+	assert.Nil(t, p.jiraUnreachableResponse(header, []string{"help"}))
+	require.NotNil(t, p.jiraUnreachableResponse(header, []string{"view", "TEST-1"}))
+	assert.Equal(t, defaultMessages[msgJiraUnreachable], message)
+}
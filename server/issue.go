@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -42,20 +43,27 @@ func httpAPICreateIssue(ji Instance, w http.ResponseWriter, r *http.Request) (in
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
+	err = ji.GetPlugin().requireTeamInstance(ji, mattermostUserId, create.ChannelId)
+	if err != nil {
+		return http.StatusForbidden, err
 	}
 
 	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	err = ji.GetPlugin().requireWriteAccess(mattermostUserId, jiraUser)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
 
 	client, err := ji.GetClient(jiraUser)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
+	create.Fields.Description = markdownToWikiMarkup(create.Fields.Description)
+
 	var post *model.Post
 	var appErr *model.AppError
 
@@ -142,6 +150,23 @@ func httpAPICreateIssue(ji Instance, w http.ResponseWriter, r *http.Request) (in
 		return http.StatusOK, nil
 	}
 
+	// Check the required fields here, Jira's errors about them name the
+	// fields by their IDs.
+	cimd, err := client.GetCreateMeta(&jira.GetQueryOptions{
+		Expand:      "projects.issuetypes.fields",
+		ProjectKeys: project.Key,
+	})
+	if err == nil {
+		missing, metaErr := missingRequiredFields(cimd, issue.Fields)
+		if metaErr == nil && len(missing) > 0 {
+			return http.StatusBadRequest, errors.Errorf("Please fill in the required fields: %s.", strings.Join(missing, ", "))
+		}
+	}
+
+	err = ji.GetPlugin().requireWriteAllowed(mattermostUserId)
+	if err != nil {
+		return http.StatusTooManyRequests, err
+	}
 	created, err := client.CreateIssue(issue)
 	if err != nil {
 		// if have an error and Jira tells us there are required fields send user
@@ -171,7 +196,7 @@ func httpAPICreateIssue(ji Instance, w http.ResponseWriter, r *http.Request) (in
 
 	// Reply to the post with the issue link that was created
 	reply := &model.Post{
-		Message:   fmt.Sprintf("Created a Jira issue %v/browse/%v", ji.GetURL(), created.Key),
+		Message:   ji.GetPlugin().localize(ji.GetPlugin().channelLocale(channelId), msgIssueCreated, ji.GetURL(), created.Key),
 		ChannelId: channelId,
 		RootId:    rootId,
 		ParentId:  rootId,
@@ -183,6 +208,10 @@ func httpAPICreateIssue(ji Instance, w http.ResponseWriter, r *http.Request) (in
 			errors.WithMessage(appErr, "failed to create notification post "+create.PostId)
 	}
 
+	if post != nil {
+		addPostRemoteLink(ji, client, created.Key, post, create.CurrentTeam)
+	}
+
 	if post != nil && len(post.FileIds) > 0 {
 		go func() {
 			conf := ji.GetPlugin().getConfig()
@@ -209,6 +238,72 @@ func httpAPICreateIssue(ji Instance, w http.ResponseWriter, r *http.Request) (in
 	return http.StatusOK, nil
 }
 
+// missingRequiredFields returns the names of the fields that the create
+// metadata of the issue's project and type requires, and that have no value
+// or default value.
+func missingRequiredFields(cimd *jira.CreateMetaInfo, fields *jira.IssueFields) ([]string, error) {
+	if fields == nil || fields.Project.Key == "" || fields.Type.ID == "" && fields.Type.Name == "" {
+		return nil, nil
+	}
+	project := cimd.GetProjectWithKey(fields.Project.Key)
+	if project == nil {
+		return nil, nil
+	}
+	var issueType *jira.MetaIssueType
+	for _, t := range project.IssueTypes {
+		if t.Id == fields.Type.ID || fields.Type.ID == "" && strings.EqualFold(t.Name, fields.Type.Name) {
+			issueType = t
+		}
+	}
+	if issueType == nil {
+		return nil, nil
+	}
+
+	bb, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	err = json.Unmarshal(bb, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	mandatory, err := issueType.GetMandatoryFields()
+	if err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	for name, key := range mandatory {
+		switch key {
+		case "project", "issuetype", "reporter":
+			continue
+		}
+		if hasDefault, _ := issueType.Fields.Bool(key + "/hasDefaultValue"); hasDefault {
+			continue
+		}
+		if isEmptyFieldValue(values[key]) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+func isEmptyFieldValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
 func httpAPIGetCreateIssueMetadataForProjects(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
 	if r.Method != http.MethodGet {
 		return http.StatusMethodNotAllowed,
@@ -216,9 +311,6 @@ func httpAPIGetCreateIssueMetadataForProjects(ji Instance, w http.ResponseWriter
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	projectKeys := r.FormValue("project-keys")
 	if projectKeys == "" {
@@ -273,9 +365,6 @@ func httpAPIGetSearchIssues(ji Instance, w http.ResponseWriter, r *http.Request)
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
 	if err != nil {
@@ -365,9 +454,6 @@ func httpAPIGetJiraProjectMetadata(ji Instance, w http.ResponseWriter, r *http.R
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
 	if err != nil {
@@ -438,6 +524,45 @@ func httpAPIGetJiraProjectMetadata(ji Instance, w http.ResponseWriter, r *http.R
 
 var reJiraIssueKey = regexp.MustCompile(`^([[:alpha:]]+)-([[:digit:]]+)$`)
 
+var reJiraIssueKeyLoose = regexp.MustCompile(`^[[:alpha:]][[:alnum:]_]*-[[:digit:]]+$`)
+
+// issueDeepLink returns the plugin URL that redirects to the issue in the
+// current Jira instance, so that it remains valid if the instance URL changes.
+func (p *Plugin) issueDeepLink(issueKey string) string {
+	return p.GetPluginURL() + routeIssueRedirect + issueKey
+}
+
+// httpIssueRedirect redirects /issue/<issue-key> to the issue in Jira, or in
+// the Jira app.
+func httpIssueRedirect(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	issueKey := strings.TrimPrefix(r.URL.Path, routeIssueRedirect)
+	if !reIssueRef.MatchString(issueKey) && !reJiraIssueKeyLoose.MatchString(strings.ToUpper(issueKey)) {
+		return http.StatusBadRequest, errors.Errorf("invalid issue key %q", issueKey)
+	}
+
+	// The keys linked from a channel resolve to its default instance
+	p := ji.GetPlugin()
+	channelId := r.URL.Query().Get("channel_id")
+	if channelId != "" {
+		if _, appErr := p.API.GetChannelMember(channelId, mattermostUserId); appErr != nil {
+			channelId = ""
+		}
+	}
+	ref, err := p.resolveIssueRef(issueKey, channelId, ji.GetURL())
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	http.Redirect(w, r, p.issueRedirectTarget(ref, r), http.StatusFound)
+	return http.StatusFound, nil
+}
+
 func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
 	if r.Method != http.MethodPost {
 		return http.StatusMethodNotAllowed,
@@ -458,14 +583,29 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
+
+	post, appErr := api.GetPost(attach.PostId)
+	if appErr != nil {
+		return http.StatusInternalServerError,
+			errors.WithMessage(appErr, "failed to load post "+attach.PostId)
+	}
+	if post == nil {
+		return http.StatusInternalServerError,
+			errors.New("failed to load post " + attach.PostId + ": not found")
+	}
+	err = ji.GetPlugin().requireTeamInstance(ji, mattermostUserId, post.ChannelId)
+	if err != nil {
+		return http.StatusForbidden, err
 	}
 
 	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	err = ji.GetPlugin().requireWriteAccess(mattermostUserId, jiraUser)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
 
 	client, err := ji.GetClient(jiraUser)
 	if err != nil {
@@ -473,15 +613,6 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 	}
 
 	// Lets add a permalink to the post in the Jira Description
-	post, appErr := api.GetPost(attach.PostId)
-	if appErr != nil {
-		return http.StatusInternalServerError,
-			errors.WithMessage(appErr, "failed to load post "+attach.PostId)
-	}
-	if post == nil {
-		return http.StatusInternalServerError,
-			errors.New("failed to load post " + attach.PostId + ": not found")
-	}
 
 	commentUser, appErr := api.GetUser(post.UserId)
 	if appErr != nil {
@@ -495,8 +626,12 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 
 	var jiraComment jira.Comment
 	jiraComment.Body = permalinkMessage
-	jiraComment.Body += post.Message
+	jiraComment.Body += markdownToWikiMarkup(post.Message)
 
+	err = ji.GetPlugin().requireWriteAllowed(mattermostUserId)
+	if err != nil {
+		return http.StatusTooManyRequests, err
+	}
 	commentAdded, err := client.AddComment(attach.IssueKey, &jiraComment)
 	if err != nil {
 		if strings.Contains(err.Error(), "you do not have the permission to comment on this issue") {
@@ -509,6 +644,8 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 			errors.WithMessage(err, "failed to attach the comment, postId: "+attach.PostId)
 	}
 
+	addPostRemoteLink(ji, client, attach.IssueKey, post, attach.CurrentTeam)
+
 	go func() {
 		conf := ji.GetPlugin().getConfig()
 		extraText := ""
@@ -540,7 +677,7 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 
 	// Reply to the post with the issue link that was created
 	reply := &model.Post{
-		Message:   fmt.Sprintf("Message attached to [%v](%v/browse/%v)", attach.IssueKey, ji.GetURL(), attach.IssueKey),
+		Message:   ji.GetPlugin().localize(ji.GetPlugin().channelLocale(post.ChannelId), msgMessageAttached, attach.IssueKey, ji.GetURL(), attach.IssueKey),
 		ChannelId: post.ChannelId,
 		RootId:    rootId,
 		ParentId:  rootId,
@@ -567,15 +704,49 @@ func httpAPIAttachCommentToIssue(ji Instance, w http.ResponseWriter, r *http.Req
 }
 
 func notifyOnFailedAttachment(ji Instance, mattermostUserId, issueKey string, err error, format string, args ...interface{}) {
-	msg := "Failed to attach to issue: " + issueKey + ", " + fmt.Sprintf(format, args...)
+	p := ji.GetPlugin()
+	detail := fmt.Sprintf(format, args...)
 
-	ji.GetPlugin().API.LogError(fmt.Sprintf("%s: %v", msg, err), "issue", issueKey)
+	p.API.LogError(fmt.Sprintf("Failed to attach to issue: %s, %s: %v", issueKey, detail, err), "issue", issueKey)
 	errMsg := err.Error()
 	if len(errMsg) > 2048 {
 		errMsg = errMsg[:2048]
 	}
-	_, _ = ji.GetPlugin().CreateBotDMtoMMUserId(mattermostUserId,
-		"%s. Please notify your system administrator.\n%s", msg, errMsg)
+	_, _ = p.CreateBotDMtoMMUserId(mattermostUserId, "%s",
+		p.localize(p.userLocale(mattermostUserId), msgAttachmentFailed, issueKey, detail, errMsg))
+}
+
+// addPostRemoteLink links the issue back to the Mattermost post it was
+// created from or attached to. Failures are logged and otherwise ignored,
+// since the issue itself already carries the permalink.
+func addPostRemoteLink(ji Instance, client Client, issueKey string, post *model.Post, currentTeam string) {
+	p := ji.GetPlugin()
+	var channel *model.Channel
+	if c, appErr := p.API.GetChannel(post.ChannelId); appErr == nil {
+		channel = c
+	}
+	title := remoteLinkTitle(channel)
+
+	err := client.AddRemoteLink(issueKey, &RemoteLink{
+		GlobalId: "mattermost-post=" + post.Id,
+		Object: RemoteLinkObject{
+			URL:   getPermaLink(ji, post.Id, currentTeam),
+			Title: title,
+		},
+	})
+	if err != nil {
+		p.debugf("addPostRemoteLink: failed to link %s to post %s: %v", issueKey, post.Id, err)
+	}
+}
+
+// remoteLinkTitle returns the title of the remote link of a post. Only the
+// public channels are named, since the Jira users seeing the link may not
+// be allowed to know the private channels, direct and group messages.
+func remoteLinkTitle(channel *model.Channel) string {
+	if channel == nil || channel.Type != model.CHANNEL_OPEN || channel.DisplayName == "" {
+		return "Message in Mattermost"
+	}
+	return fmt.Sprintf("Message in Mattermost ~%s", channel.DisplayName)
 }
 
 func getPermaLink(ji Instance, postId string, currentTeam string) string {
@@ -600,7 +771,11 @@ func (p *Plugin) getIssueDataForCloudWebhook(ji Instance, issueKey string) (*jir
 			return nil, errors.WithMessage(userFriendlyJiraError(nil, err),
 				"request to Jira failed")
 
-		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized:
+		case resp.StatusCode == http.StatusUnauthorized:
+			p.alertAdmins(alertJiraAuthExpired, msgAlertJiraAuthExpired, ji.GetURL())
+			fallthrough
+
+		case resp.StatusCode == http.StatusNotFound:
 			return nil, errors.New(`We couldn't find the issue key, or the cloud "bot" client does not have the appropriate permissions to view the issue.`)
 		}
 	}
@@ -654,40 +829,51 @@ func getIssueCustomFieldValue(issue *jira.Issue, key string) StringSet {
 	return nil
 }
 
-func getIssueFieldValue(issue *jira.Issue, key string) StringSet {
-	key = strings.ToLower(key)
-	switch key {
-	case "status":
-		return NewStringSet(issue.Fields.Status.ID)
-	case "labels":
-		return NewStringSet(issue.Fields.Labels...)
-	case "priority":
-		return NewStringSet(issue.Fields.Priority.ID)
-	case "fixversions":
+// issueFieldValues are the functions returning the values of the issue
+// fields that the Fields filters of the subscriptions match, by lowercase
+// key. The other keys are custom fields.
+var issueFieldValues = map[string]func(fields *jira.IssueFields) StringSet{
+	"status": func(fields *jira.IssueFields) StringSet {
+		return NewStringSet(fields.Status.ID)
+	},
+	"labels": func(fields *jira.IssueFields) StringSet {
+		return NewStringSet(fields.Labels...)
+	},
+	"priority": func(fields *jira.IssueFields) StringSet {
+		return NewStringSet(fields.Priority.ID)
+	},
+	"fixversions": func(fields *jira.IssueFields) StringSet {
 		result := NewStringSet()
-		for _, v := range issue.Fields.FixVersions {
+		for _, v := range fields.FixVersions {
 			result = result.Add(v.ID)
 		}
 		return result
-	case "versions":
+	},
+	"versions": func(fields *jira.IssueFields) StringSet {
 		result := NewStringSet()
-		for _, v := range issue.Fields.AffectsVersions {
+		for _, v := range fields.AffectsVersions {
 			result = result.Add(v.ID)
 		}
 		return result
-	case "components":
+	},
+	"components": func(fields *jira.IssueFields) StringSet {
 		result := NewStringSet()
-		for _, v := range issue.Fields.Components {
+		for _, v := range fields.Components {
 			result = result.Add(v.ID)
 		}
 		return result
-	default:
-		value := getIssueCustomFieldValue(issue, key)
-		if value != nil {
-			return value
-		}
-	}
+	},
+}
 
+func getIssueFieldValue(issue *jira.Issue, key string) StringSet {
+	key = strings.ToLower(key)
+	if values, ok := issueFieldValues[key]; ok {
+		return values(issue.Fields)
+	}
+	value := getIssueCustomFieldValue(issue, key)
+	if value != nil {
+		return value
+	}
 	return NewStringSet()
 }
 
@@ -712,7 +898,20 @@ func (p *Plugin) getIssueAsSlackAttachment(ji Instance, jiraUser JIRAUser, issue
 		}
 	}
 
-	return parseIssue(issue), nil
+	conf := p.getConfig()
+	// The issue is shown in the response to /jira view, which is English
+	attachments := parseIssue(issue, conf.maxTextLength, conf.webhookParseOptions.issueEmoji, p.jiraAvatarURLFunc(), englishLocalizer)
+	if conf.ShowDevelopmentInfo {
+		summary, err := client.GetDevelopmentSummary(issue.ID)
+		if err != nil {
+			// Jira instances without connected development tools may not
+			// have the dev-status API, the issue is shown without it.
+			p.debugf("getIssueAsSlackAttachment: failed to get the development information of %s: %v", issueKey, err)
+		} else if field := developmentField(summary); field != nil {
+			attachments[0].Fields = append(attachments[0].Fields, field)
+		}
+	}
+	return attachments, nil
 }
 
 func (p *Plugin) unassignJiraIssue(mmUserId, issueKey string) (string, error) {
@@ -725,6 +924,10 @@ func (p *Plugin) unassignJiraIssue(mmUserId, issueKey string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	err = p.requireWriteAccess(mmUserId, jiraUser)
+	if err != nil {
+		return "", err
+	}
 
 	client, err := ji.GetClient(jiraUser)
 	if err != nil {
@@ -738,6 +941,9 @@ func (p *Plugin) unassignJiraIssue(mmUserId, issueKey string) (string, error) {
 		return errorMsg, nil
 	}
 
+	if err := p.requireWriteAllowed(mmUserId); err != nil {
+		return "", err
+	}
 	if err := client.UpdateAssignee(issueKey, &jira.User{}); err != nil {
 		if StatusCode(err) == http.StatusForbidden {
 			return "You do not have the appropriate permissions to perform this action. Please contact your Jira administrator.", nil
@@ -763,6 +969,10 @@ func (p *Plugin) assignJiraIssue(mmUserId, issueKey, userSearch string) (string,
 	if err != nil {
 		return "", err
 	}
+	err = p.requireWriteAccess(mmUserId, jiraUser)
+	if err != nil {
+		return "", err
+	}
 
 	client, err := ji.GetClient(jiraUser)
 	if err != nil {
@@ -782,8 +992,7 @@ func (p *Plugin) assignJiraIssue(mmUserId, issueKey, userSearch string) (string,
 		return errorMsg, nil
 	}
 
-	// Get list of assignable users
-	jiraUsers, err := client.SearchUsersAssignableToIssue(issueKey, userSearch, 10)
+	user, err := findAssignableUser(client, issueKey, userSearch)
 	if StatusCode(err) == 401 {
 		return "You do not have the appropriate permissions to perform this action. Please contact your Jira administrator.", nil
 	}
@@ -791,43 +1000,10 @@ func (p *Plugin) assignJiraIssue(mmUserId, issueKey, userSearch string) (string,
 		return "", err
 	}
 
-	// handle number of returned jira users
-	if len(jiraUsers) == 0 {
-		errorMsg := fmt.Sprintf("We couldn't find the assignee. Please use a Jira member and try again.")
-		return "", fmt.Errorf(errorMsg)
-	}
-
-	if len(jiraUsers) > 1 {
-		errorMsg := fmt.Sprintf("`%s` matches %d or more users.  Please specify a unique assignee.\n", userSearch, len(jiraUsers))
-		for i := range jiraUsers {
-			name := jiraUsers[i].DisplayName
-			extra := jiraUsers[i].Name
-			if jiraUsers[i].EmailAddress != "" {
-				if extra != "" {
-					extra += ", "
-				}
-				extra += jiraUsers[i].EmailAddress
-			}
-			if extra != "" {
-				name += " (" + extra + ")"
-			}
-			errorMsg += fmt.Sprintf("* %+v\n", name)
-		}
-		return "", fmt.Errorf(errorMsg)
-	}
-
-	// user is array of one object
-	user := jiraUsers[0]
-
-	// From Jira error: query parameters 'accountId' and 'username' are mutually exclusive.
-	// Here, we must choose one and one only and nil the other user field.
-	// Choosing user.AccountID over user.Name, but check if AccountId is empty.
-	// For server instances, AccountID is empty
-	if user.AccountID != "" {
-		user.Name = ""
+	if err := p.requireWriteAllowed(mmUserId); err != nil {
+		return "", err
 	}
-
-	if err := client.UpdateAssignee(issueKey, &user); err != nil {
+	if err := client.UpdateAssignee(issueKey, user); err != nil {
 		return "", err
 	}
 
@@ -848,6 +1024,10 @@ func (p *Plugin) transitionJiraIssue(mmUserId, issueKey, toState string) (string
 	if err != nil {
 		return "", err
 	}
+	err = p.requireWriteAccess(mmUserId, jiraUser)
+	if err != nil {
+		return "", err
+	}
 
 	client, err := ji.GetClient(jiraUser)
 	if err != nil {
@@ -862,6 +1042,73 @@ func (p *Plugin) transitionJiraIssue(mmUserId, issueKey, toState string) (string
 		return "", errors.New("You do not have the appropriate permissions to perform this action. Please contact your Jira administrator.")
 	}
 
+	transition, err := findTransition(transitions, toState)
+	if err != nil {
+		return "", err
+	}
+
+	if err := p.requireWriteAllowed(mmUserId); err != nil {
+		return "", err
+	}
+	if err := client.DoTransition(issueKey, transition.ID); err != nil {
+		return "", err
+	}
+
+	msg := fmt.Sprintf("[%s](%v/browse/%v) transitioned to `%s`",
+		issueKey, ji.GetURL(), issueKey, transition.To.Name)
+	return msg, nil
+}
+
+// findAssignableUser returns the only user assignable to the issue matching
+// userSearch.
+func findAssignableUser(client Client, issueKey, userSearch string) (*jira.User, error) {
+	// Get list of assignable users
+	jiraUsers, err := client.SearchUsersAssignableToIssue(issueKey, userSearch, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle number of returned jira users
+	if len(jiraUsers) == 0 {
+		errorMsg := fmt.Sprintf("We couldn't find the assignee. Please use a Jira member and try again.")
+		return nil, fmt.Errorf(errorMsg)
+	}
+
+	if len(jiraUsers) > 1 {
+		errorMsg := fmt.Sprintf("`%s` matches %d or more users.  Please specify a unique assignee.\n", userSearch, len(jiraUsers))
+		for i := range jiraUsers {
+			name := jiraUsers[i].DisplayName
+			extra := jiraUsers[i].Name
+			if jiraUsers[i].EmailAddress != "" {
+				if extra != "" {
+					extra += ", "
+				}
+				extra += jiraUsers[i].EmailAddress
+			}
+			if extra != "" {
+				name += " (" + extra + ")"
+			}
+			errorMsg += fmt.Sprintf("* %+v\n", name)
+		}
+		return nil, fmt.Errorf(errorMsg)
+	}
+
+	// user is array of one object
+	user := jiraUsers[0]
+
+	// From Jira error: query parameters 'accountId' and 'username' are mutually exclusive.
+	// Here, we must choose one and one only and nil the other user field.
+	// Choosing user.AccountID over user.Name, but check if AccountId is empty.
+	// For server instances, AccountID is empty
+	if user.AccountID != "" {
+		user.Name = ""
+	}
+	return &user, nil
+}
+
+// findTransition returns the transition to the state matching toState,
+// ignoring case and spaces.
+func findTransition(transitions []jira.Transition, toState string) (jira.Transition, error) {
 	var transition jira.Transition
 	matchingStates := []string{}
 	availableStates := []string{}
@@ -878,22 +1125,16 @@ func (p *Plugin) transitionJiraIssue(mmUserId, issueKey, toState string) (string
 
 	switch len(matchingStates) {
 	case 0:
-		return "", errors.Errorf("%q is not a valid state. Please use one of: %q",
+		return jira.Transition{}, errors.Errorf("%q is not a valid state. Please use one of: %q",
 			toState, strings.Join(availableStates, ", "))
 
 	case 1:
 		// proceed
 
 	default:
-		return "", errors.Errorf("please be more specific, %q matched several states: %q",
+		return jira.Transition{}, errors.Errorf("please be more specific, %q matched several states: %q",
 			toState, strings.Join(matchingStates, ", "))
 	}
 
-	if err := client.DoTransition(issueKey, transition.ID); err != nil {
-		return "", err
-	}
-
-	msg := fmt.Sprintf("[%s](%v/browse/%v) transitioned to `%s`",
-		issueKey, ji.GetURL(), issueKey, transition.To.Name)
-	return msg, nil
+	return transition, nil
 }
@@ -0,0 +1,166 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	prefixIssuePosts = "issue_posts_"
+
+	// How long the posts of a new issue are remembered.
+	issuePostsExpirySeconds = 30 * 24 * 60 * 60
+
+	// The color of the attachments of a deleted issue's posts.
+	deletedIssueColor = "#cccccc"
+)
+
+// issuePosts records the posts of a Jira issue when it was created or added
+// to a channel, by channel, so that they can be marked when it is deleted.
+type issuePosts struct {
+	PostIds map[string]string `json:"post_ids"`
+}
+
+// issuePostsKey identifies an issue by the URL of its REST resource, which is
+// unique across the Jira instances, and is kept when the issue is moved.
+func issuePostsKey(issue *jira.Issue) string {
+	if issue == nil || issue.Self == "" {
+		return ""
+	}
+	return hashkey(prefixIssuePosts, issue.Self)
+}
+
+func (p *Plugin) loadIssuePosts(key string) (*issuePosts, error) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	ip := &issuePosts{}
+	err := json.Unmarshal(data, ip)
+	if err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+// rememberIssuePosts records the channel posts of an issue, so that they are
+// marked if the issue is deleted. They are recorded even when
+// MarkDeletedIssuePosts is off, so that turning it on applies to the issues
+// posted before. Concurrent events of the issue are merged atomically.
+func (p *Plugin) rememberIssuePosts(issue *jira.Issue, posts []*model.Post) {
+	key := issuePostsKey(issue)
+	if key == "" || len(posts) == 0 {
+		return
+	}
+
+	err := p.atomicModifyWithExpiry(key, issuePostsExpirySeconds, func(initial []byte) ([]byte, error) {
+		ip := &issuePosts{}
+		if len(initial) > 0 {
+			err := json.Unmarshal(initial, ip)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if ip.PostIds == nil {
+			ip.PostIds = map[string]string{}
+		}
+		for _, post := range posts {
+			if post.RootId != "" {
+				continue
+			}
+			ip.PostIds[post.ChannelId] = post.Id
+		}
+		return json.Marshal(ip)
+	})
+	if err != nil {
+		p.errorf("rememberIssuePosts: failed to store the posts of issue %s: %v", issue.Key, err)
+	}
+}
+
+// cleanupDeletedIssue forgets the posts of a deleted issue and of its
+// comments, marks the posts of the issue as deleted if configured, and
+// removes the single-issue subscriptions to it, once the deletion was posted.
+func (p *Plugin) cleanupDeletedIssue(wh *webhook) {
+	if !wh.Events().ContainsAny(eventDeleted) || wh.Issue.Key == "" {
+		return
+	}
+
+	key := issuePostsKey(&wh.Issue)
+	if key != "" {
+		ip, err := p.loadIssuePosts(key)
+		if err != nil {
+			p.errorf("cleanupDeletedIssue: failed to load the posts of issue %s: %v", wh.Issue.Key, err)
+		}
+		if ip != nil && wh.getConfig(p).MarkDeletedIssuePosts {
+			for channelId, postId := range ip.PostIds {
+				p.markDeletedIssuePost(channelId, postId)
+			}
+		}
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			p.errorf("cleanupDeletedIssue: failed to delete the posts of issue %s: %v", wh.Issue.Key, appErr)
+		}
+	}
+
+	if wh.Issue.Fields != nil && wh.Issue.Fields.Comments != nil {
+		for _, comment := range wh.Issue.Fields.Comments.Comments {
+			if comment == nil || comment.Self == "" {
+				continue
+			}
+			appErr := p.API.KVDelete(hashkey(prefixCommentPosts, comment.Self))
+			if appErr != nil {
+				p.errorf("cleanupDeletedIssue: failed to delete the posts of comment %s: %v", comment.ID, appErr)
+			}
+		}
+	}
+
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("cleanupDeletedIssue: failed to load subscriptions: %v", err)
+		return
+	}
+	for _, sub := range subs.Channel.ById {
+		if sub.IssueKey != wh.Issue.Key {
+			continue
+		}
+		err = p.removeChannelSubscription(sub.Id)
+		if err != nil {
+			p.errorf("cleanupDeletedIssue: failed to remove subscription %q: %v", sub.Name, err)
+		}
+	}
+}
+
+// markDeletedIssuePost greys out the attachment of the post of a deleted
+// issue, and removes its actions, which no longer apply.
+func (p *Plugin) markDeletedIssuePost(channelId, postId string) {
+	post, appErr := p.API.GetPost(postId)
+	if appErr != nil {
+		p.debugf("markDeletedIssuePost: failed to get post %s: %v", postId, appErr)
+		return
+	}
+
+	attachments := post.Attachments()
+	if len(attachments) == 0 {
+		post.Message = p.localize(p.channelLocale(channelId), msgIssueDeleted, post.Message)
+	} else {
+		attachments[0].Color = deletedIssueColor
+		attachments[0].Pretext = p.localize(p.channelLocale(channelId), msgIssueDeleted, attachments[0].Pretext)
+		attachments[0].Fallback = attachments[0].Pretext
+		attachments[0].Actions = nil
+		model.ParseSlackAttachment(post, attachments)
+	}
+
+	_, appErr = p.API.UpdatePost(post)
+	if appErr != nil {
+		p.errorf("markDeletedIssuePost: failed to update post %s: %v", postId, appErr)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestCleanupDeletedIssue(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if !bytes.Equal(kv[key], options.OldValue) || options.ExpireInSeconds != issuePostsExpirySeconds {
+				return false
+			}
+			kv[key] = value
+			return true
+		}, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	post := &model.Post{Id: "post1", ChannelId: "channel1"}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Pretext: "Test User **created** story TES-41",
+		Actions: []*model.PostAction{{Name: "Transition"}},
+	}})
+	api.On("GetPost", "post1").Return(post, nil)
+	var updated *model.Post
+	api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(*model.Post)
+	}).Return(&model.Post{}, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "TES-41", IssueKey: "TES-41"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel2", Name: "TES-41 (thread root2)", IssueKey: "TES-41", RootId: "root2"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel2", Name: "TES-1", IssueKey: "TES-1"})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+	kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)] = stored
+
+	issue := jira.Issue{Key: "TES-41", Self: "https://jira.example.com/rest/api/2/issue/10000"}
+	p.rememberIssuePosts(&issue, []*model.Post{
+		{Id: "post1", ChannelId: "channel1"},
+		{Id: "reply2", ChannelId: "channel2", RootId: "root2"},
+	})
+	ip, err := p.loadIssuePosts(issuePostsKey(&issue))
+	require.NoError(t, err)
+	require.NotNil(t, ip)
+	assert.Equal(t, map[string]string{"channel1": "post1"}, ip.PostIds, "the replies are not issue cards")
+
+	p.rememberIssuePosts(&issue, []*model.Post{{Id: "post3", ChannelId: "channel3"}})
+	ip, err = p.loadIssuePosts(issuePostsKey(&issue))
+	require.NoError(t, err)
+	require.NotNil(t, ip)
+	assert.Equal(t, map[string]string{"channel1": "post1", "channel3": "post3"}, ip.PostIds)
+	api.On("GetPost", "post3").Return(nil, &model.AppError{Message: "not found"})
+	api.On("LogDebug", mock.AnythingOfType("string")).Return()
+
+	p.updateConfig(func(conf *config) {
+		conf.MarkDeletedIssuePosts = true
+	})
+
+	commentKey := hashkey(prefixCommentPosts, "https://jira.example.com/rest/api/2/issue/10000/comment/10001")
+	kv[commentKey] = []byte(`{}`)
+	issue.Fields = &jira.IssueFields{Comments: &jira.Comments{Comments: []*jira.Comment{
+		{ID: "10001", Self: "https://jira.example.com/rest/api/2/issue/10000/comment/10001"},
+	}}}
+	p.cleanupDeletedIssue(&webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Issue: issue}}, eventTypes: NewStringSet(eventDeleted)})
+
+	assert.Nil(t, kv[issuePostsKey(&issue)])
+	assert.Nil(t, kv[commentKey])
+	require.NotNil(t, updated)
+	attachments := updated.Attachments()
+	require.Len(t, attachments, 1)
+	assert.Equal(t, "**Deleted in Jira:** Test User **created** story TES-41", attachments[0].Pretext)
+	assert.Equal(t, deletedIssueColor, attachments[0].Color)
+	assert.Empty(t, attachments[0].Actions)
+
+	remaining, err := p.getSubscriptions()
+	require.NoError(t, err)
+	require.Len(t, remaining.Channel.ById, 1)
+	assert.Contains(t, remaining.Channel.ById, "sub3")
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const (
+	emojiKindType     = "type"
+	emojiKindStatus   = "status"
+	emojiKindPriority = "priority"
+
+	// noIssueEmoji in IssueEmoji removes the default emoji of a name.
+	noIssueEmoji = "none"
+)
+
+var issueEmojiKinds = NewStringSet(emojiKindType, emojiKindStatus, emojiKindPriority)
+
+// reEmojiName matches the names of the Mattermost emoji, without the colons.
+var reEmojiName = regexp.MustCompile(`^[a-z0-9_+-]+$`)
+
+// defaultIssueEmoji are the emoji of the issue types, statuses and priorities
+// of the default Jira schemes, by lowercase "kind:name".
+var defaultIssueEmoji = map[string]string{
+	"type:bug":         "bug",
+	"type:story":       "blue_book",
+	"type:task":        "ballot_box_with_check",
+	"type:sub-task":    "small_blue_diamond",
+	"type:subtask":     "small_blue_diamond",
+	"type:epic":        "zap",
+	"type:new feature": "sparkles",
+	"type:improvement": "arrow_up",
+
+	"status:to do":       "white_circle",
+	"status:in progress": "large_blue_circle",
+	"status:done":        "white_check_mark",
+
+	"priority:highest":  "arrow_double_up",
+	"priority:high":     "arrow_up_small",
+	"priority:low":      "arrow_down_small",
+	"priority:lowest":   "arrow_double_down",
+	"priority:blocker":  "no_entry",
+	"priority:critical": "rotating_light",
+}
+
+// issueEmoji are the emoji shown before the issue types, statuses and
+// priorities, by lowercase "kind:name". A nil issueEmoji shows no emoji.
+type issueEmoji map[string]string
+
+// parseIssueEmoji returns the default emoji with the IssueEmoji overrides,
+// e.g. "type:Bug=beetle, priority:Blocker=none", or nil if the emoji are not
+// enabled. The invalid overrides are skipped, validateConfig reports them.
+func parseIssueEmoji(enabled bool, overrides string) issueEmoji {
+	if !enabled {
+		return nil
+	}
+	emoji := issueEmoji{}
+	for key, name := range defaultIssueEmoji {
+		emoji[key] = name
+	}
+	for key, name := range utils.ParseKeyValueList(overrides) {
+		key, name, err := parseIssueEmojiOverride(key, name)
+		if err != nil {
+			continue
+		}
+		if name == noIssueEmoji {
+			delete(emoji, key)
+			continue
+		}
+		emoji[key] = name
+	}
+	return emoji
+}
+
+// parseIssueEmojiOverride normalizes an entry of IssueEmoji.
+func parseIssueEmojiOverride(key, name string) (string, string, error) {
+	kv := strings.SplitN(key, ":", 2)
+	if len(kv) != 2 || !issueEmojiKinds.ContainsAny(strings.ToLower(strings.TrimSpace(kv[0]))) {
+		return "", "", errors.Errorf("%q must start with type:, status: or priority:", key)
+	}
+	kind, value := strings.ToLower(strings.TrimSpace(kv[0])), strings.ToLower(strings.TrimSpace(kv[1]))
+	if value == "" {
+		return "", "", errors.Errorf("%q has no name after %s:", key, kind)
+	}
+	name = strings.Trim(name, ":")
+	if !reEmojiName.MatchString(name) {
+		return "", "", errors.Errorf("the emoji %q of %q is not an emoji name, like `beetle`", name, key)
+	}
+	return kind + ":" + value, name, nil
+}
+
+// get returns the emoji of the name of the kind, like ":bug:", or "".
+func (emoji issueEmoji) get(kind, name string) string {
+	if emoji == nil {
+		return ""
+	}
+	if e := emoji[kind+":"+strings.ToLower(strings.TrimSpace(name))]; e != "" {
+		return ":" + e + ":"
+	}
+	return ""
+}
+
+// prefix returns the name of the kind after its emoji, if it has one.
+func (emoji issueEmoji) prefix(kind, name string) string {
+	if e := emoji.get(kind, name); e != "" {
+		return e + " " + name
+	}
+	return name
+}
+
+// validateIssueEmoji returns the problems of the IssueEmoji overrides, in
+// their order.
+func validateIssueEmoji(overrides string) []string {
+	problems := []string{}
+	for _, entry := range utils.ParseList(overrides) {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			problems = append(problems, fmt.Sprintf("%q is not a `kind:name=emoji` entry, like `type:Bug=beetle`.", entry))
+			continue
+		}
+		if _, _, err := parseIssueEmojiOverride(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])); err != nil {
+			problems = append(problems, err.Error()+".")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIssueEmoji(t *testing.T) {
+	assert.Nil(t, parseIssueEmoji(false, "type:Bug=beetle"))
+
+	emoji := parseIssueEmoji(true, "type:Bug=:beetle:, Priority:Blocker=none, status:In Review=eyes, resolution:Done=tada")
+	assert.Equal(t, ":beetle:", emoji.get(emojiKindType, "Bug"))
+	assert.Equal(t, ":blue_book:", emoji.get(emojiKindType, "story"))
+	assert.Equal(t, ":eyes:", emoji.get(emojiKindStatus, "In review"))
+	assert.Equal(t, "", emoji.get(emojiKindPriority, "Blocker"))
+	assert.Equal(t, "", emoji.get(emojiKindType, "Incident"))
+	// In Review is added, Blocker removed and resolution:Done skipped
+	assert.Len(t, emoji, len(defaultIssueEmoji))
+
+	assert.Equal(t, ":rotating_light: Critical", emoji.prefix(emojiKindPriority, "Critical"))
+	assert.Equal(t, "Medium", emoji.prefix(emojiKindPriority, "Medium"))
+	assert.Equal(t, "Bug", issueEmoji(nil).prefix(emojiKindType, "Bug"))
+}
+
+func TestParseWebhookIssueEmoji(t *testing.T) {
+	options := webhookParseOptions{issueEmoji: parseIssueEmoji(true, "")}
+	data, err := getJiraTestData("webhook-issue-updated-raised-priority.json")
+	require.NoError(t, err)
+
+	wh, err := ParseWebhookWithOptions(data, options)
+	require.NoError(t, err)
+	assert.Equal(t, `Test User **updated** priority from :arrow_down_small: "Low" to :arrow_up_small: "High" on :blue_book: story [TES-41: Unit test summary 1](https://some-instance-test.atlassian.net/browse/TES-41)`,
+		wh.(*webhook).headline)
+
+	wh, err = ParseWebhook(data)
+	require.NoError(t, err)
+	assert.Equal(t, `Test User **updated** priority from "Low" to "High" on story [TES-41: Unit test summary 1](https://some-instance-test.atlassian.net/browse/TES-41)`,
+		wh.(*webhook).headline)
+}
+
+func TestParseIssueWithEmoji(t *testing.T) {
+	issue := &jira.Issue{
+		Key:  "TES-41",
+		Self: "https://jira.example.com/rest/api/2/issue/10041",
+		Fields: &jira.IssueFields{
+			Summary:  "Crash on save",
+			Type:     jira.IssueType{Name: "Bug"},
+			Priority: &jira.Priority{Name: "Blocker"},
+		},
+	}
+
+	attachment := parseIssue(issue, defaultMaxTextLength, parseIssueEmoji(true, ""), nil, englishLocalizer)[0]
+	assert.True(t, strings.HasPrefix(attachment.Text, ":bug: [TES-41"), attachment.Text)
+	require.Len(t, attachment.Fields, 1)
+	assert.Equal(t, ":no_entry: Blocker", attachment.Fields[0].Value)
+
+	attachment = parseIssue(issue, defaultMaxTextLength, nil, nil, englishLocalizer)[0]
+	assert.NotContains(t, attachment.Text, ":bug:")
+	assert.Equal(t, "Blocker", attachment.Fields[0].Value)
+}
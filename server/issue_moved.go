@@ -0,0 +1,96 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// issueProjectKey returns the project key of an issue key, e.g. "MM" for
+// "MM-1234".
+func issueProjectKey(issueKey string) string {
+	i := strings.LastIndex(issueKey, "-")
+	if i <= 0 {
+		return ""
+	}
+	return issueKey[:i]
+}
+
+// updateMovedIssueReferences renames a moved issue in the subscriptions that
+// reference it by key, so that the single issue subscriptions and their
+// threads, and the subscriptions to the issue and its sub-tasks or epic
+// issues, keep following it. The channels of the latter are told about the
+// rename, since they may not be subscribed to the moves.
+func (p *Plugin) updateMovedIssueReferences(wh *webhook) error {
+	oldKey := wh.JiraWebhook.MovedFromKey()
+	newKey := wh.JiraWebhook.Issue.Key
+	if oldKey == "" || newKey == "" {
+		return nil
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+
+	// The names of the renamed subscriptions with parent keys, by channel
+	var renamed map[string][]string
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		renamed = map[string][]string{}
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		changed := false
+		for id, sub := range subs.Channel.ById {
+			switch {
+			case sub.IssueKey == oldKey:
+				sub.IssueKey = newKey
+			case sub.Filters.ParentKeys.ContainsAny(oldKey):
+				sub.Filters.ParentKeys = sub.Filters.ParentKeys.Subtract(oldKey).Add(newKey)
+				if !sub.Paused {
+					renamed[sub.ChannelId] = append(renamed[sub.ChannelId], fmt.Sprintf("%q", sub.Name))
+				}
+			default:
+				continue
+			}
+			sub.Version++
+			subs.Channel.ById[id] = sub
+			changed = true
+		}
+		if !changed {
+			return initialBytes, nil
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		return modifiedBytes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for channelId, names := range renamed {
+		sort.Strings(names)
+		_, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: channelId,
+			Message: p.localize(p.channelLocale(channelId), msgSubscriptionsIssueMoved,
+				oldKey, newKey, ji.GetURL(), newKey, strings.Join(names, ", ")),
+		})
+		if appErr != nil {
+			p.errorf("updateMovedIssueReferences: failed to post the rename of %s to channel %s: %v", oldKey, channelId, appErr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueProjectKey(t *testing.T) {
+	assert.Equal(t, "MM", issueProjectKey("MM-1234"))
+	assert.Equal(t, "MY-PROJ", issueProjectKey("MY-PROJ-5"))
+	assert.Equal(t, "", issueProjectKey("MM"))
+	assert.Equal(t, "", issueProjectKey(""))
+}
+
+func TestUpdateMovedIssueReferences(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	var posted []*model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		posted = append(posted, args.Get(0).(*model.Post))
+	}).Return(&model.Post{}, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "issue", IssueKey: "TES-41"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel2", Name: "epic", Filters: SubscriptionFilters{Events: NewStringSet(eventCreated), Projects: NewStringSet("TES"), ParentKeys: NewStringSet("TES-41", "TES-1")}})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel3", Name: "other", Filters: SubscriptionFilters{Events: NewStringSet(eventCreated), Projects: NewStringSet("TES"), ParentKeys: NewStringSet("TES-1")}})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+	kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)] = stored
+
+	data, err := getJiraTestData("webhook-cloud-issue-updated-moved.json")
+	require.NoError(t, err)
+	w, err := ParseWebhook(data)
+	require.NoError(t, err)
+	wh := w.(*webhook)
+	assert.Equal(t, NewStringSet(eventUpdatedMoved), wh.eventTypes)
+	assert.Contains(t, wh.headline, "**moved** TES-41 to")
+	assert.Equal(t, "TES-41", wh.JiraWebhook.MovedFromKey())
+
+	// The move is posted to the subscriptions of the project the issue left
+	assert.True(t, p.matchesSubsciptionFilters(wh, SubscriptionFilters{Events: NewStringSet(eventUpdatedMoved), Projects: NewStringSet("TES")}))
+	assert.True(t, p.matchesSubsciptionFilters(wh, SubscriptionFilters{Events: NewStringSet(eventUpdatedMoved), Projects: NewStringSet("NEW")}))
+	assert.False(t, p.matchesSubsciptionFilters(wh, SubscriptionFilters{Events: NewStringSet(eventUpdatedMoved), Projects: NewStringSet("OTHER")}))
+
+	err = p.updateMovedIssueReferences(wh)
+	require.NoError(t, err)
+	updated, err := p.getSubscriptions()
+	require.NoError(t, err)
+	assert.Equal(t, "NEW-5", updated.Channel.ById["sub1"].IssueKey)
+	assert.Equal(t, NewStringSet("NEW-5", "TES-1"), updated.Channel.ById["sub2"].Filters.ParentKeys)
+	assert.Equal(t, NewStringSet("TES-1"), updated.Channel.ById["sub3"].Filters.ParentKeys)
+
+	require.Len(t, posted, 1)
+	assert.Equal(t, "channel2", posted[0].ChannelId)
+	assert.Contains(t, posted[0].Message, "TES-41 was moved to [NEW-5]")
+	assert.Contains(t, posted[0].Message, `"epic"`)
+}
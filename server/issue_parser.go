@@ -20,23 +20,38 @@ func parseJiraLinksToMarkdown(text string) string {
 }
 
 func mdKeySummaryLink(issue *jira.Issue) string {
+	return mdIssueLink(issue, issue.Key+": "+issue.Fields.Summary)
+}
+
+func mdIssueLink(issue *jira.Issue, title string) string {
 	// Use Self URL only to extract the full hostname from it
 	pos := strings.LastIndex(issue.Self, "/rest/api")
 	if pos < 0 {
 		return ""
 	}
-	return fmt.Sprintf("[%s](%s%s)", issue.Key+": "+issue.Fields.Summary, issue.Self[:pos], "/browse/"+issue.Key)
+	return fmt.Sprintf("[%s](%s%s)", title, issue.Self[:pos], "/browse/"+issue.Key)
 }
 
-func reporterSummary(issue *jira.Issue) string {
-	avatarLink := fmt.Sprintf("![avatar](%s =30x30)", issue.Fields.Reporter.AvatarUrls.One6X16)
+func reporterSummary(issue *jira.Issue, avatarURL func(string) string) string {
+	avatar := issue.Fields.Reporter.AvatarUrls.One6X16
+	if avatarURL != nil {
+		avatar = avatarURL(avatar)
+	}
+	avatarLink := fmt.Sprintf("![avatar](%s =30x30)", avatar)
 	reporterSummary := avatarLink + " " + issue.Fields.Reporter.Name
 	return reporterSummary
 }
 
-func parseIssue(issue *jira.Issue) []*model.SlackAttachment {
+// parseIssue renders an issue as an attachment, truncating the description
+// at maxTextLength characters. If avatarURL is set, the avatars are loaded
+// through the URLs it returns, and the project is shown as the author. The
+// texts of the plugin are translated by t.
+func parseIssue(issue *jira.Issue, maxTextLength int, emoji issueEmoji, avatarURL func(string) string, t localizer) []*model.SlackAttachment {
 	text := mdKeySummaryLink(issue)
-	desc := truncate(issue.Fields.Description, 3000)
+	if e := emoji.get(emojiKindType, issue.Fields.Type.Name); e != "" {
+		text = e + " " + text
+	}
+	desc := truncateWithLink(issue.Fields.Description, maxTextLength, mdIssueLink(issue, t(msgWebhookShowMore)))
 	desc = parseJiraLinksToMarkdown(desc)
 	if desc != "" {
 		text += "\n\n" + desc + "\n"
@@ -45,31 +60,114 @@ func parseIssue(issue *jira.Issue) []*model.SlackAttachment {
 	var fields []*model.SlackAttachmentField
 	if issue.Fields.Assignee != nil {
 		fields = append(fields, &model.SlackAttachmentField{
-			Title: "Assignee",
+			Title: t(msgWebhookFieldAssignee),
 			Value: issue.Fields.Assignee.DisplayName,
 			Short: true,
 		})
 	}
 	if issue.Fields.Priority != nil {
 		fields = append(fields, &model.SlackAttachmentField{
-			Title: "Priority",
-			Value: issue.Fields.Priority.Name,
+			Title: t(msgWebhookFieldPriority),
+			Value: emoji.prefix(emojiKindPriority, issue.Fields.Priority.Name),
 			Short: true,
 		})
 	}
 
-	fields = append(fields, &model.SlackAttachmentField{
-		Title: "Reporter",
-		Value: reporterSummary(issue),
-		Short: true,
-	})
+	if estimate := issueEstimate(issue, t); estimate != "" {
+		fields = append(fields, &model.SlackAttachmentField{
+			Title: t(msgWebhookFieldEstimate),
+			Value: estimate,
+			Short: true,
+		})
+	}
+
+	if issue.Fields.Reporter != nil {
+		fields = append(fields, &model.SlackAttachmentField{
+			Title: t(msgWebhookFieldReporter),
+			Value: reporterSummary(issue, avatarURL),
+			Short: true,
+		})
+	}
+
+	attachment := &model.SlackAttachment{
+		// TODO is this supposed to be themed?
+		Color:  "#95b7d0",
+		Text:   text,
+		Fields: fields,
+	}
+	if avatarURL != nil && issue.Fields.Project.AvatarUrls.Four8X48 != "" {
+		attachment.AuthorName = issue.Fields.Project.Name
+		attachment.AuthorIcon = avatarURL(issue.Fields.Project.AvatarUrls.Four8X48)
+	}
+	return []*model.SlackAttachment{attachment}
+}
+
+// issueEstimate renders the time tracking of an issue, e.g. "1d 4h
+// remaining of 2d" translated by t, or returns "" if the issue is not
+// estimated.
+func issueEstimate(issue *jira.Issue, t localizer) string {
+	original := issue.Fields.TimeOriginalEstimate
+	remaining := issue.Fields.TimeEstimate
+	switch {
+	case original == 0 && remaining == 0:
+		return ""
+	case original == 0:
+		return t(msgEstimateRemaining, formatDuration(remaining))
+	case remaining == original && issue.Fields.TimeSpent == 0:
+		return formatDuration(original)
+	default:
+		return t(msgEstimateRemainingOf, formatDuration(remaining), formatDuration(original))
+	}
+}
+
+// developmentField renders the development summary of an issue as an
+// attachment field, or returns nil if no development information is linked.
+func developmentField(summary *DevelopmentSummary) *model.SlackAttachmentField {
+	if summary == nil {
+		return nil
+	}
+	count := func(n int, singular, plural string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, singular)
+		}
+		return fmt.Sprintf("%d %s", n, plural)
+	}
+
+	var parts []string
+	pr := summary.PullRequest.Overall
+	if pr.Count > 0 {
+		var states []string
+		for _, s := range []struct {
+			count int
+			name  string
+		}{
+			{pr.Details.OpenCount, "open"},
+			{pr.Details.MergedCount, "merged"},
+			{pr.Details.DeclinedCount, "declined"},
+		} {
+			if s.count > 0 {
+				states = append(states, fmt.Sprintf("%d %s", s.count, s.name))
+			}
+		}
+		part := count(pr.Count, "pull request", "pull requests")
+		if len(states) > 0 {
+			part += " (" + strings.Join(states, ", ") + ")"
+		}
+		parts = append(parts, part)
+	}
+	if n := summary.Branch.Overall.Count; n > 0 {
+		parts = append(parts, count(n, "branch", "branches"))
+	}
+	if n := summary.Repository.Overall.Count; n > 0 {
+		parts = append(parts, count(n, "commit", "commits"))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
 
-	return []*model.SlackAttachment{
-		{
-			// TODO is this supposed to be themed?
-			Color:  "#95b7d0",
-			Text:   text,
-			Fields: fields,
-		},
+	return &model.SlackAttachmentField{
+		Title: "Development",
+		Value: strings.Join(parts, ", "),
+		Short: false,
 	}
 }
@@ -0,0 +1,175 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// The previews are requested as the users type, so they are kept for a
+// while, and so are the keys that don't resolve to issues the user can see.
+const (
+	issuePreviewCacheTTL         = 2 * time.Minute
+	issuePreviewNotFoundCacheTTL = 30 * time.Second
+
+	issuePreviewFields = "summary,status,issuetype,assignee"
+)
+
+// IssuePreview is the summary suggested for an issue key typed in the
+// message box.
+type IssuePreview struct {
+	Key           string `json:"key"`
+	Summary       string `json:"summary"`
+	Status        string `json:"status,omitempty"`
+	IssueType     string `json:"issue_type,omitempty"`
+	IssueTypeIcon string `json:"issue_type_icon,omitempty"`
+	Assignee      string `json:"assignee,omitempty"`
+	URL           string `json:"url"`
+}
+
+// issuePreviewCache keeps the previews fetched from Jira for a while, by
+// Jira instance, user and issue key. A nil preview records an issue that
+// doesn't exist or that the user is not allowed to see.
+type issuePreviewCache struct {
+	lock    sync.Mutex
+	entries map[string]issuePreviewCacheEntry
+}
+
+type issuePreviewCacheEntry struct {
+	preview *IssuePreview
+	expires time.Time
+}
+
+func (c *issuePreviewCache) get(key string, now time.Time) (*IssuePreview, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.preview, true
+}
+
+func (c *issuePreviewCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+}
+
+func (c *issuePreviewCache) set(key string, preview *IssuePreview, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]issuePreviewCacheEntry{}
+	}
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	ttl := issuePreviewCacheTTL
+	if preview == nil {
+		ttl = issuePreviewNotFoundCacheTTL
+	}
+	c.entries[key] = issuePreviewCacheEntry{
+		preview: preview,
+		expires: now.Add(ttl),
+	}
+}
+
+func newIssuePreview(ji Instance, issue *jira.Issue) *IssuePreview {
+	preview := &IssuePreview{
+		Key: issue.Key,
+		URL: strings.TrimRight(ji.GetURL(), "/") + "/browse/" + issue.Key,
+	}
+	if issue.Fields == nil {
+		return preview
+	}
+	preview.Summary = issue.Fields.Summary
+	if issue.Fields.Status != nil {
+		preview.Status = issue.Fields.Status.Name
+	}
+	preview.IssueType = issue.Fields.Type.Name
+	preview.IssueTypeIcon = issue.Fields.Type.IconURL
+	if issue.Fields.Assignee != nil {
+		preview.Assignee = issue.Fields.Assignee.DisplayName
+	}
+	return preview
+}
+
+// httpAPIGetIssuePreview resolves an issue key typed in the message box to
+// the issue's summary. The issue is fetched as the user, so Jira only
+// returns the issues the user is allowed to see.
+func httpAPIGetIssuePreview(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("Request: " + r.Method + " is not allowed, must be GET")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	issueKey := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("issue_key")))
+	if !reJiraIssueKeyLoose.MatchString(issueKey) {
+		return http.StatusBadRequest, errors.New("invalid issue key")
+	}
+
+	p := ji.GetPlugin()
+	err := p.requireTeamInstance(ji, mattermostUserId, r.URL.Query().Get("channel_id"))
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err == ErrUserNotFound {
+		return http.StatusUnauthorized, errors.New("not connected to Jira")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	cacheKey := ji.GetURL() + "/" + jiraUser.AccountID + jiraUser.Name + "/" + issueKey
+	now := time.Now()
+	preview, ok := p.issuePreviews.get(cacheKey, now)
+	if !ok {
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		issue, err := client.GetIssue(issueKey, &jira.GetQueryOptions{Fields: issuePreviewFields})
+		switch {
+		case err == nil:
+			preview = newIssuePreview(ji, issue)
+		case StatusCode(err) == http.StatusNotFound || StatusCode(err) == http.StatusForbidden:
+			// Jira doesn't tell the issues that don't exist from the ones
+			// the user can't see.
+			preview = nil
+		default:
+			return http.StatusInternalServerError,
+				errors.WithMessage(err, "failed to get the issue")
+		}
+		p.issuePreviews.set(cacheKey, preview, now)
+	}
+	if preview == nil {
+		return http.StatusNotFound, errors.New("issue not found")
+	}
+
+	bb, err := json.Marshal(preview)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to marshal response")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(bb)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuePreviewCache(t *testing.T) {
+	c := issuePreviewCache{}
+	now := time.Now()
+	_, ok := c.get("key", now)
+	assert.False(t, ok)
+
+	preview := &IssuePreview{Key: "TEST-1", Summary: "Fix the build"}
+	c.set("key", preview, now)
+	cached, ok := c.get("key", now.Add(issuePreviewCacheTTL-time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, preview, cached)
+	_, ok = c.get("key", now.Add(issuePreviewCacheTTL+time.Second))
+	assert.False(t, ok)
+
+	// The issues that are not found are cached for a shorter while
+	c.set("missing", nil, now)
+	cached, ok = c.get("missing", now.Add(issuePreviewNotFoundCacheTTL-time.Second))
+	assert.True(t, ok)
+	assert.Nil(t, cached)
+	_, ok = c.get("missing", now.Add(issuePreviewNotFoundCacheTTL+time.Second))
+	assert.False(t, ok)
+}
+
+func TestNewIssuePreview(t *testing.T) {
+	ji := &jiraTestInstance{}
+	preview := newIssuePreview(ji, &jira.Issue{
+		Key: "TEST-1",
+		Fields: &jira.IssueFields{
+			Summary:  "Fix the build",
+			Status:   &jira.Status{Name: "In Progress"},
+			Type:     jira.IssueType{Name: "Bug"},
+			Assignee: &jira.User{DisplayName: "Jane Doe"},
+		},
+	})
+	assert.Equal(t, &IssuePreview{
+		Key:       "TEST-1",
+		Summary:   "Fix the build",
+		Status:    "In Progress",
+		IssueType: "Bug",
+		Assignee:  "Jane Doe",
+		URL:       ji.GetURL() + "/browse/TEST-1",
+	}, preview)
+
+	preview = newIssuePreview(ji, &jira.Issue{Key: "TEST-2"})
+	assert.Equal(t, "TEST-2", preview.Key)
+	assert.Empty(t, preview.Summary)
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-autolink/server/autolink"
+	"github.com/mattermost/mattermost-plugin-autolink/server/autolinkclient"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+// The default Jira instance of a channel is stored by channel, for all the
+// instances, as its URL.
+const keyChannelInstance = "channel_instance_"
+
+// reIssueRef matches the issue keys prefixed with the alias of a Jira
+// instance, like primary:PROJ-1.
+var reIssueRef = regexp.MustCompile(`^([[:alnum:]_-]+):([[:alpha:]][[:alnum:]_]*-[[:digit:]]+)$`)
+
+// issueKeyCommands are the commands whose first argument is an issue key,
+// which is resolved with the instance aliases before they run.
+var issueKeyCommands = NewStringSet(
+	"view",
+	"tree",
+	"transition",
+	"assign",
+	"unassign",
+	"watch",
+	"unwatch",
+	"log",
+	"subscribe/issue",
+	"unsubscribe/issue",
+	"todo/unsnooze",
+)
+
+// parseInstanceAliases parses the InstanceAliases setting, a comma-separated
+// list of alias=URL pairs, into the instance URL by lowercase alias.
+func parseInstanceAliases(s string) map[string]string {
+	aliases := map[string]string{}
+	for alias, instanceURL := range utils.ParseKeyValueList(s) {
+		aliases[strings.ToLower(alias)] = strings.TrimRight(instanceURL, "/")
+	}
+	return aliases
+}
+
+// instanceAlias returns the alias of a Jira instance, or "" if it has none.
+func (conf config) instanceAlias(instanceURL string) string {
+	instanceURL = strings.TrimRight(instanceURL, "/")
+	for alias, u := range conf.instanceAliases {
+		if strings.EqualFold(u, instanceURL) {
+			return alias
+		}
+	}
+	return ""
+}
+
+func (conf config) sortedInstanceAliases() []string {
+	aliases := []string{}
+	for alias := range conf.instanceAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// issueRef is an issue key, with the URL of the Jira instance it belongs to.
+type issueRef struct {
+	InstanceURL string
+	Key         string
+}
+
+func (ref issueRef) browseURL() string {
+	return ref.InstanceURL + "/browse/" + ref.Key
+}
+
+// resolveIssueRef resolves an issue key to the Jira instance it belongs to:
+// the instance of its alias prefix, like primary:PROJ-1, or else the default
+// instance of the channel, or else the current instance.
+func (p *Plugin) resolveIssueRef(s, channelId, currentURL string) (issueRef, error) {
+	conf := p.getConfig()
+	if m := reIssueRef.FindStringSubmatch(s); m != nil {
+		instanceURL, ok := conf.instanceAliases[strings.ToLower(m[1])]
+		if !ok {
+			return issueRef{}, errors.Errorf("%q is not the alias of a Jira instance. The aliases are: %s.",
+				m[1], strings.Join(conf.sortedInstanceAliases(), ", "))
+		}
+		return issueRef{InstanceURL: instanceURL, Key: strings.ToUpper(m[2])}, nil
+	}
+
+	ref := issueRef{InstanceURL: strings.TrimRight(currentURL, "/"), Key: strings.ToUpper(s)}
+	if channelId != "" && len(conf.instanceAliases) > 0 {
+		instanceURL, err := p.loadChannelInstance(channelId)
+		if err != nil {
+			return issueRef{}, err
+		}
+		if instanceURL != "" {
+			ref.InstanceURL = instanceURL
+		}
+	}
+	return ref, nil
+}
+
+func (p *Plugin) loadChannelInstance(channelId string) (string, error) {
+	data, appErr := p.API.KVGet(keyChannelInstance + channelId)
+	if appErr != nil {
+		return "", appErr
+	}
+	return string(data), nil
+}
+
+func (p *Plugin) storeChannelInstance(channelId, instanceURL string) error {
+	if instanceURL == "" {
+		appErr := p.API.KVDelete(keyChannelInstance + channelId)
+		if appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+	appErr := p.API.KVSet(keyChannelInstance+channelId, []byte(instanceURL))
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// issueRefResponse resolves the issue key argument of the commands in
+// issueKeyCommands, leaving the bare key of the current instance in args. It
+// returns the response to the issue keys of another instance, or nil.
+func (p *Plugin) issueRefResponse(header *model.CommandArgs, args []string) *model.CommandResponse {
+	if len(p.getConfig().instanceAliases) == 0 {
+		return nil
+	}
+	command, n := jiraCommandHandler.find(args)
+	if !issueKeyCommands.ContainsAny(command) || len(args) <= n {
+		return nil
+	}
+	arg := args[n]
+	if !reIssueRef.MatchString(arg) && !reJiraIssueKeyLoose.MatchString(strings.ToUpper(arg)) {
+		return nil
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil
+	}
+
+	ref, err := p.resolveIssueRef(arg, header.ChannelId, ji.GetURL())
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if strings.EqualFold(ref.InstanceURL, strings.TrimRight(ji.GetURL(), "/")) {
+		args[n] = ref.Key
+		return nil
+	}
+	name := ref.InstanceURL
+	if alias := p.getConfig().instanceAlias(ref.InstanceURL); alias != "" {
+		name = fmt.Sprintf("**%s** (%s)", alias, ref.InstanceURL)
+	}
+	return p.responsef(header, "%s is an issue of the Jira instance %s, [view it in Jira](%s). "+
+		"Mattermost is connected to %s, so the commands only work with its issues.",
+		ref.Key, name, ref.browseURL(), ji.GetURL())
+}
+
+func executeInstanceChannel(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira instance channel <alias>`, or `/jira instance channel clear`."
+
+	conf := p.getConfig()
+	if len(conf.instanceAliases) == 0 {
+		return p.responsef(header, "No Jira instance aliases are configured. Please ask your system administrator to set **Jira Instance Aliases** in the plugin settings.")
+	}
+	instanceURL, err := p.loadChannelInstance(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	if len(args) == 0 {
+		if instanceURL == "" {
+			return p.responsef(header, "The issue keys of this channel are resolved to the current Jira instance. "+usage)
+		}
+		return p.responsef(header, "The issue keys of this channel are resolved to the Jira instance **%s** (%s).",
+			conf.instanceAlias(instanceURL), instanceURL)
+	}
+	if len(args) != 1 {
+		return p.responsef(header, usage)
+	}
+
+	err = p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to change the Jira instance of this channel: %v", err)
+	}
+
+	if args[0] == "clear" {
+		err = p.storeChannelInstance(header.ChannelId, "")
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		return p.responsef(header, "The issue keys of this channel are now resolved to the current Jira instance.")
+	}
+
+	instanceURL, ok := conf.instanceAliases[strings.ToLower(args[0])]
+	if !ok {
+		return p.responsef(header, "%q is not the alias of a Jira instance. The aliases are: %s.",
+			args[0], strings.Join(conf.sortedInstanceAliases(), ", "))
+	}
+	err = p.storeChannelInstance(header.ChannelId, instanceURL)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	return p.responsef(header, "The issue keys of this channel, in the commands and the issue links of the plugin, are now resolved to the Jira instance **%s** (%s). "+
+		"Prefix the keys with the alias of another instance to use its issues, like `%s:PROJ-1`.",
+		strings.ToLower(args[0]), instanceURL, strings.ToLower(args[0]))
+}
+
+// addInstanceAliasAutolinks adds the autolinks of the issue keys prefixed
+// with the instance aliases, in all channels.
+func (p *Plugin) addInstanceAliasAutolinks() error {
+	conf := p.getConfig()
+	links := []autolink.Autolink{}
+	for _, alias := range conf.sortedInstanceAliases() {
+		instanceURL := conf.instanceAliases[alias]
+		links = append(links, autolink.Autolink{
+			Name:     alias + " alias to link for " + instanceURL,
+			Pattern:  `(?i)\b(` + regexp.QuoteMeta(alias) + `):(?P<jira_key>[[:alpha:]][[:alnum:]_]*-[[:digit:]]+)`,
+			Template: `[` + alias + `:${jira_key}](` + instanceURL + `/browse/${jira_key})`,
+		})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	client := autolinkclient.NewClientPlugin(p.API)
+	if err := client.Add(links...); err != nil {
+		return fmt.Errorf("Unable to add autolinks: %w", err)
+	}
+	return nil
+}
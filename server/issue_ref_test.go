@@ -0,0 +1,82 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func issueRefTestPlugin() (*Plugin, *string) {
+	api := &plugintest.API{}
+	api.On("KVGet", keyChannelInstance+"legacy-channel").Return([]byte("https://legacy.atlassian.net"), nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	var message string
+	api.On("SendEphemeralPost", "user", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(&model.Post{})
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.instanceAliases = parseInstanceAliases("Primary=" + mockCurrentInstanceURL + "/, legacy=https://legacy.atlassian.net")
+	})
+	return p, &message
+}
+
+func TestResolveIssueRef(t *testing.T) {
+	p, _ := issueRefTestPlugin()
+
+	for _, tc := range []struct {
+		ref, channelId string
+		expected       issueRef
+	}{
+		{"TES-1", "", issueRef{mockCurrentInstanceURL, "TES-1"}},
+		{"tes-1", "channel", issueRef{mockCurrentInstanceURL, "TES-1"}},
+		{"TES-1", "legacy-channel", issueRef{"https://legacy.atlassian.net", "TES-1"}},
+		{"legacy:tes-1", "", issueRef{"https://legacy.atlassian.net", "TES-1"}},
+		{"primary:TES-1", "legacy-channel", issueRef{mockCurrentInstanceURL, "TES-1"}},
+		{"PRIMARY:TES-1", "", issueRef{mockCurrentInstanceURL, "TES-1"}},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			ref, err := p.resolveIssueRef(tc.ref, tc.channelId, mockCurrentInstanceURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+
+	_, err := p.resolveIssueRef("other:TES-1", "", mockCurrentInstanceURL)
+	assert.EqualError(t, err, `"other" is not the alias of a Jira instance. The aliases are: legacy, primary.`)
+}
+
+func TestIssueRefResponse(t *testing.T) {
+	p, message := issueRefTestPlugin()
+	header := &model.CommandArgs{UserId: "user", ChannelId: "channel"}
+
+	args := []string{"view", "primary:TES-1"}
+	assert.Nil(t, p.issueRefResponse(header, args))
+	assert.Equal(t, []string{"view", "TES-1"}, args)
+
+	args = []string{"subscribe", "issue", "TES-2"}
+	assert.Nil(t, p.issueRefResponse(header, args))
+	assert.Equal(t, []string{"subscribe", "issue", "TES-2"}, args)
+
+	// Only the issue key arguments are resolved
+	args = []string{"search", "legacy:TES-1"}
+	assert.Nil(t, p.issueRefResponse(header, args))
+	assert.Equal(t, []string{"search", "legacy:TES-1"}, args)
+
+	require.NotNil(t, p.issueRefResponse(header, []string{"transition", "legacy:TES-1", "done"}))
+	assert.Contains(t, *message, "TES-1 is an issue of the Jira instance **legacy** (https://legacy.atlassian.net), [view it in Jira](https://legacy.atlassian.net/browse/TES-1).")
+
+	header.ChannelId = "legacy-channel"
+	require.NotNil(t, p.issueRefResponse(header, []string{"view", "TES-3"}))
+	assert.Contains(t, *message, "TES-3 is an issue of the Jira instance **legacy**")
+	assert.Nil(t, p.issueRefResponse(header, []string{"view", "primary:TES-3"}))
+}
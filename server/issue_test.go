@@ -5,8 +5,13 @@ import (
 	"testing"
 
 	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/trivago/tgo/tcontainer"
 )
 
 const (
@@ -24,6 +29,7 @@ type testClient struct {
 	ProjectService
 	SearchService
 	IssueService
+	WebhookService
 }
 
 func (client testClient) GetProject(key string) (*jira.Project, error) {
@@ -33,6 +39,21 @@ func (client testClient) GetProject(key string) (*jira.Project, error) {
 	return nil, nil
 }
 
+func (client testClient) GetIssue(key string, options *jira.GetQueryOptions) (*jira.Issue, error) {
+	if key == nonExistantIssueKey {
+		return nil, errors.New(noIssueFoundError)
+	}
+	return &jira.Issue{
+		Key:    key,
+		Self:   "https://jira.example.com/rest/api/2/issue/10001",
+		Fields: &jira.IssueFields{Summary: "Checkout fails", Type: jira.IssueType{Name: "Bug"}},
+	}, nil
+}
+
+func (client testClient) GetAllProjectKeys() ([]string, error) {
+	return []string{"TEST", "ABC"}, nil
+}
+
 func (client testClient) GetTransitions(issueKey string) ([]jira.Transition, error) {
 	if issueKey == nonExistantIssueKey {
 		return []jira.Transition{}, errors.New(noIssueFoundError)
@@ -41,9 +62,9 @@ func (client testClient) GetTransitions(issueKey string) ([]jira.Transition, err
 	}
 
 	return []jira.Transition{
-		jira.Transition{To: jira.Status{Name: "To Do"}},
-		jira.Transition{To: jira.Status{Name: "In Progress"}},
-		jira.Transition{To: jira.Status{Name: "In Testing"}},
+		jira.Transition{ID: "11", Name: "Reopen", To: jira.Status{Name: "To Do"}},
+		jira.Transition{ID: "21", Name: "Start", To: jira.Status{Name: "In Progress"}},
+		jira.Transition{ID: "31", Name: "In Testing", To: jira.Status{Name: "In Testing"}},
 	}, nil
 }
 
@@ -51,8 +72,18 @@ func (client testClient) DoTransition(issueKey string, transitionID string) erro
 	return nil
 }
 
+func (client testClient) UpdateIssue(issueKey string, data map[string]interface{}) error {
+	if issueKey == nonExistantIssueKey {
+		return errors.New(noIssueFoundError)
+	}
+	return nil
+}
+
 func TestTransitionJiraIssue(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
 	p := Plugin{currentInstanceStore: mockCurrentInstanceStore{}}
+	p.SetAPI(api)
 	tests := map[string]struct {
 		issueKey    string
 		toState     string
@@ -101,3 +132,82 @@ func TestTransitionJiraIssue(t *testing.T) {
 		})
 	}
 }
+
+func TestMissingRequiredFields(t *testing.T) {
+	cimd := &jira.CreateMetaInfo{
+		Projects: []*jira.MetaProject{{
+			Key: "TES",
+			IssueTypes: []*jira.MetaIssueType{{
+				Id:   "10001",
+				Name: "Bug",
+				Fields: tcontainer.MarshalMap{
+					"project":           map[string]interface{}{"name": "Project", "required": true},
+					"issuetype":         map[string]interface{}{"name": "Issue Type", "required": true},
+					"summary":           map[string]interface{}{"name": "Summary", "required": true},
+					"components":        map[string]interface{}{"name": "Components", "required": true},
+					"priority":          map[string]interface{}{"name": "Priority", "required": true, "hasDefaultValue": true},
+					"customfield_10100": map[string]interface{}{"name": "Severity", "required": true},
+					"description":       map[string]interface{}{"name": "Description", "required": false},
+				},
+			}},
+		}},
+	}
+
+	for name, tc := range map[string]struct {
+		fields   *jira.IssueFields
+		expected []string
+	}{
+		"all missing": {
+			fields:   &jira.IssueFields{Project: jira.Project{Key: "TES"}, Type: jira.IssueType{ID: "10001"}},
+			expected: []string{"Components", "Severity", "Summary"},
+		},
+		"all set": {
+			fields: &jira.IssueFields{
+				Project:    jira.Project{Key: "TES"},
+				Type:       jira.IssueType{ID: "10001"},
+				Summary:    "summary",
+				Components: []*jira.Component{{ID: "10200"}},
+				Unknowns:   tcontainer.MarshalMap{"customfield_10100": map[string]interface{}{"id": "10300"}},
+			},
+			expected: []string{},
+		},
+		"blank summary": {
+			fields: &jira.IssueFields{
+				Project:    jira.Project{Key: "TES"},
+				Type:       jira.IssueType{Name: "bug"},
+				Summary:    "  ",
+				Components: []*jira.Component{{ID: "10200"}},
+				Unknowns:   tcontainer.MarshalMap{"customfield_10100": map[string]interface{}{"id": "10300"}},
+			},
+			expected: []string{"Summary"},
+		},
+		"unknown issue type": {
+			fields: &jira.IssueFields{Project: jira.Project{Key: "TES"}, Type: jira.IssueType{ID: "10002"}},
+		},
+		"unknown project": {
+			fields: &jira.IssueFields{Project: jira.Project{Key: "OTHER"}, Type: jira.IssueType{ID: "10001"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			missing, err := missingRequiredFields(cimd, tc.fields)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, missing)
+		})
+	}
+}
+
+func TestRemoteLinkTitle(t *testing.T) {
+	for _, tc := range []struct {
+		channel  *model.Channel
+		expected string
+	}{
+		{nil, "Message in Mattermost"},
+		{&model.Channel{Type: model.CHANNEL_OPEN, DisplayName: "Town Square"}, "Message in Mattermost ~Town Square"},
+		{&model.Channel{Type: model.CHANNEL_OPEN}, "Message in Mattermost"},
+		{&model.Channel{Type: model.CHANNEL_PRIVATE, DisplayName: "Acquisition"}, "Message in Mattermost"},
+		{&model.Channel{Type: model.CHANNEL_DIRECT, DisplayName: "alice, bob"}, "Message in Mattermost"},
+		{&model.Channel{Type: model.CHANNEL_GROUP, DisplayName: "alice, bob, carol"}, "Message in Mattermost"},
+	} {
+		assert.Equal(t, tc.expected, remoteLinkTitle(tc.channel))
+	}
+}
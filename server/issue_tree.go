@@ -0,0 +1,178 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	issueTreeFields = "summary,status,issuetype,subtasks"
+
+	// The issues of an epic listed by /jira tree.
+	issueTreeMaxIssues = 50
+)
+
+// epicIssues is the response of the Jira Agile API listing the issues of an
+// epic.
+type epicIssues struct {
+	Issues []jira.Issue `json:"issues"`
+	Total  int          `json:"total"`
+}
+
+func executeTree(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira tree <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeTree: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	tree, err := renderIssueTree(ji.GetURL(), client, issueKey)
+	if err != nil {
+		return p.responsef(header, "Failed to get the hierarchy of %s: %v", issueKey, err)
+	}
+	return p.responsef(header, "%s", tree)
+}
+
+// renderIssueTree renders the epic, stories and sub-tasks around an issue as
+// a Markdown list, from the epic of the issue, or from the issue if it has
+// none.
+func renderIssueTree(instanceURL string, client Client, issueKey string) (string, error) {
+	issue, err := client.GetIssue(issueKey, nil)
+	if err != nil {
+		return "", err
+	}
+	if issue.Fields == nil {
+		return "", errors.Errorf("issue %s has no fields", issueKey)
+	}
+
+	root := issue
+	if issue.Fields.Type.Subtask && issue.Fields.Parent != nil {
+		root, err = client.GetIssue(issue.Fields.Parent.Key, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	if !isEpic(root) {
+		epicLinkField, err := getEpicLinkField(client)
+		if err != nil {
+			return "", err
+		}
+		if epic := findEpic(client, root, epicLinkField); epic != nil {
+			root = epic
+		}
+	}
+
+	lines := []string{issueTreeLine(root, 0, issueKey)}
+	if !isEpic(root) {
+		lines = append(lines, subtaskTreeLines(root, 1, issueKey)...)
+		return strings.Join(lines, "\n"), nil
+	}
+
+	children, total, err := listEpicIssues(instanceURL, client, root.Key)
+	if err != nil {
+		return "", err
+	}
+	for i := range children {
+		lines = append(lines, issueTreeLine(&children[i], 1, issueKey))
+		lines = append(lines, subtaskTreeLines(&children[i], 2, issueKey)...)
+	}
+	if total > len(children) {
+		lines = append(lines, "  * _and "+strconv.Itoa(total-len(children))+" more issues_")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func isEpic(issue *jira.Issue) bool {
+	return issue.Fields != nil && strings.EqualFold(issue.Fields.Type.Name, "Epic")
+}
+
+// findEpic returns the epic of an issue, or nil if it has none.
+func findEpic(client Client, issue *jira.Issue, epicLinkField string) *jira.Issue {
+	keys := issueParentKeys(issue, epicLinkField).Elems()
+	sort.Strings(keys)
+	for _, key := range keys {
+		parent, err := client.GetIssue(key, &jira.GetQueryOptions{Fields: issueTreeFields})
+		if err == nil && isEpic(parent) {
+			return parent
+		}
+	}
+	return nil
+}
+
+// listEpicIssues lists the issues of an epic with the Jira Agile API, or with
+// a search for the children of the epic if Jira Software is not available.
+func listEpicIssues(instanceURL string, client Client, epicKey string) ([]jira.Issue, int, error) {
+	resp := epicIssues{}
+	err := client.RESTGet(strings.TrimRight(instanceURL, "/")+"/rest/agile/1.0/epic/"+epicKey+"/issue",
+		map[string]string{"fields": issueTreeFields, "maxResults": strconv.Itoa(issueTreeMaxIssues)}, &resp)
+	if err == nil {
+		return resp.Issues, resp.Total, nil
+	}
+
+	issues, err := client.SearchIssues(fmt.Sprintf("parent = %s ORDER BY rank", epicKey),
+		&jira.SearchOptions{MaxResults: issueTreeMaxIssues, Fields: strings.Split(issueTreeFields, ",")})
+	if err != nil {
+		return nil, 0, err
+	}
+	return issues, len(issues), nil
+}
+
+func subtaskTreeLines(issue *jira.Issue, depth int, highlightKey string) []string {
+	lines := []string{}
+	if issue.Fields == nil {
+		return lines
+	}
+	for _, subtask := range issue.Fields.Subtasks {
+		if subtask == nil {
+			continue
+		}
+		fields := subtask.Fields
+		lines = append(lines, issueTreeLine(&jira.Issue{Key: subtask.Key, Self: subtask.Self, Fields: &fields}, depth, highlightKey))
+	}
+	return lines
+}
+
+// issueTreeLine renders an issue as an item of the hierarchy, in bold if it is
+// the issue the hierarchy was asked for.
+func issueTreeLine(issue *jira.Issue, depth int, highlightKey string) string {
+	link := mdKeySummaryLink(issue)
+	if link == "" {
+		link = issue.Key + ": " + issue.Fields.Summary
+	}
+	if issue.Key == highlightKey {
+		link = "**" + link + "**"
+	}
+	line := strings.Repeat("  ", depth) + "* "
+	if issue.Fields.Type.Name != "" {
+		line += issue.Fields.Type.Name + " "
+	}
+	line += link
+	if issue.Fields.Status != nil && issue.Fields.Status.Name != "" {
+		line += " _" + issue.Fields.Status.Name + "_"
+	}
+	return line
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const treeTestSelf = "http://jira.some/rest/api/2/issue/"
+
+const treeTestFields = `[
+	{"id": "customfield_10007", "schema": {"type": "string", "custom": "com.pyxis.greenhopper.jira:gh-epic-label"}},
+	{"id": "customfield_10008", "schema": {"type": "any", "custom": "com.pyxis.greenhopper.jira:gh-epic-link"}}
+]`
+
+type treeTestClient struct {
+	testClient
+	issues      map[string]*jira.Issue
+	epicIssues  *epicIssues
+	searchedJQL string
+}
+
+func (client *treeTestClient) GetIssue(key string, options *jira.GetQueryOptions) (*jira.Issue, error) {
+	issue, ok := client.issues[key]
+	if !ok {
+		return nil, errors.New("issue " + key + " not found")
+	}
+	return issue, nil
+}
+
+func (client *treeTestClient) RESTGet(endpoint string, params map[string]string, dest interface{}) error {
+	if endpoint == "2/field" {
+		return json.Unmarshal([]byte(treeTestFields), dest)
+	}
+	if client.epicIssues == nil {
+		return errors.New("not found")
+	}
+	*dest.(*epicIssues) = *client.epicIssues
+	return nil
+}
+
+func (client *treeTestClient) SearchIssues(jql string, options *jira.SearchOptions) ([]jira.Issue, error) {
+	client.searchedJQL = jql
+	return []jira.Issue{*client.issues["TEST-2"]}, nil
+}
+
+func treeTestIssue(key, typeName, summary, status string) *jira.Issue {
+	return &jira.Issue{
+		Key:  key,
+		Self: treeTestSelf + key,
+		Fields: &jira.IssueFields{
+			Type:    jira.IssueType{Name: typeName, Subtask: typeName == "Sub-task"},
+			Summary: summary,
+			Status:  &jira.Status{Name: status},
+		},
+	}
+}
+
+func TestRenderIssueTree(t *testing.T) {
+	epic := treeTestIssue("TEST-1", "Epic", "Checkout", "In Progress")
+	story := treeTestIssue("TEST-2", "Story", "Pay by card", "To Do")
+	story.Fields.Unknowns = map[string]interface{}{"customfield_10007": "TEST-4", "customfield_10008": "TEST-1"}
+	story.Fields.Subtasks = []*jira.Subtasks{{
+		Key:    "TEST-3",
+		Self:   treeTestSelf + "TEST-3",
+		Fields: jira.IssueFields{Type: jira.IssueType{Name: "Sub-task"}, Summary: "Card form", Status: &jira.Status{Name: "Done"}},
+	}}
+	subtask := treeTestIssue("TEST-3", "Sub-task", "Card form", "Done")
+	subtask.Fields.Parent = &jira.Parent{Key: "TEST-2"}
+	other := treeTestIssue("TEST-4", "Task", "Refactor", "To Do")
+	issues := map[string]*jira.Issue{"TEST-1": epic, "TEST-2": story, "TEST-3": subtask, "TEST-4": other}
+
+	expected := "* Epic [TEST-1: Checkout](http://jira.some/browse/TEST-1) _In Progress_\n" +
+		"  * Story [TEST-2: Pay by card](http://jira.some/browse/TEST-2) _To Do_\n" +
+		"    * Sub-task **[TEST-3: Card form](http://jira.some/browse/TEST-3)** _Done_\n" +
+		"  * _and 1 more issues_"
+
+	t.Run("from a sub-task, with the Agile API", func(t *testing.T) {
+		client := &treeTestClient{issues: issues, epicIssues: &epicIssues{Issues: []jira.Issue{*story}, Total: 2}}
+		tree, err := renderIssueTree("http://jira.some", client, "TEST-3")
+		require.NoError(t, err)
+		assert.Equal(t, expected, tree)
+	})
+
+	t.Run("from an epic, with a search", func(t *testing.T) {
+		client := &treeTestClient{issues: issues}
+		tree, err := renderIssueTree("http://jira.some", client, "TEST-1")
+		require.NoError(t, err)
+		assert.Equal(t, "parent = TEST-1 ORDER BY rank", client.searchedJQL)
+		assert.Equal(t, "* Epic **[TEST-1: Checkout](http://jira.some/browse/TEST-1)** _In Progress_\n"+
+			"  * Story [TEST-2: Pay by card](http://jira.some/browse/TEST-2) _To Do_\n"+
+			"    * Sub-task [TEST-3: Card form](http://jira.some/browse/TEST-3) _Done_", tree)
+	})
+
+	t.Run("without an epic", func(t *testing.T) {
+		client := &treeTestClient{issues: issues}
+		tree, err := renderIssueTree("http://jira.some", client, "TEST-4")
+		require.NoError(t, err)
+		assert.Equal(t, "* Task **[TEST-4: Refactor](http://jira.some/browse/TEST-4)** _To Do_", tree)
+	})
+
+	t.Run("unknown issue", func(t *testing.T) {
+		client := &treeTestClient{issues: issues}
+		_, err := renderIssueTree("http://jira.some", client, "TEST-5")
+		require.Error(t, err)
+	})
+}
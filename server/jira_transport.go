@@ -0,0 +1,195 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/expvar"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const (
+	// A failed Jira request is retried at most this many times.
+	jiraMaxRetries = 3
+
+	// The delay before the first retry, doubled on every retry, with a
+	// jitter of +/-50%.
+	jiraRetryBaseDelay = 500 * time.Millisecond
+
+	// Rate limited requests are not retried if Jira asks to wait longer.
+	jiraMaxRetryAfter = 30 * time.Second
+
+	// Maximum number of concurrent requests to a Jira instance, across the
+	// users of this server.
+	jiraMaxConcurrentRequests = 10
+)
+
+// jiraRequestLimiters bounds the concurrent requests to each Jira instance.
+type jiraRequestLimiters struct {
+	lock     sync.Mutex
+	limiters map[string]chan struct{}
+}
+
+func (l *jiraRequestLimiters) get(instanceURL string) chan struct{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.limiters == nil {
+		l.limiters = map[string]chan struct{}{}
+	}
+	limiter, ok := l.limiters[instanceURL]
+	if !ok {
+		limiter = make(chan struct{}, jiraMaxConcurrentRequests)
+		l.limiters[instanceURL] = limiter
+	}
+	return limiter
+}
+
+// wrapJiraHTTPClient wraps the http client authenticating to a Jira instance
+// with the size limits, stats, retries and concurrency limit that apply to
+// all the Jira REST calls.
+func (p *Plugin) wrapJiraHTTPClient(httpClient *http.Client, instanceURL string) *http.Client {
+	conf := p.getConfig()
+	httpClient = utils.WrapHTTPClient(httpClient,
+		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
+		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
+	httpClient = expvar.WrapHTTPClient(httpClient,
+		conf.stats, endpointNameFromRequest)
+
+	client := *httpClient
+	client.Transport = &jiraTransport{
+		RoundTripper: httpClient.Transport,
+		limiter:      p.jiraRequestLimiters.get(instanceURL),
+		stats:        conf.stats,
+		sleep:        sleepContext,
+	}
+	return &client
+}
+
+// jiraTransport retries the requests that failed because Jira was rate
+// limiting or briefly unavailable, and limits the concurrent requests. Each
+// attempt is recorded in the stats of its endpoint by the wrapped transport.
+type jiraTransport struct {
+	http.RoundTripper
+	limiter chan struct{}
+	stats   *expvar.Stats
+	sleep   func(req *http.Request, d time.Duration) error
+}
+
+func (t *jiraTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			retry := *req
+			retry.Body = body
+			req = &retry
+		}
+
+		select {
+		case t.limiter <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		resp, err := t.RoundTripper.RoundTrip(req)
+		<-t.limiter
+
+		delay, retry := retryDelay(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.recordRetry(resp)
+		if err = t.sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *jiraTransport) recordRetry(resp *http.Response) {
+	if t.stats == nil {
+		return
+	}
+	name := "api/jira/_retried"
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		name = "api/jira/_rate_limited"
+	}
+	t.stats.EnsureEndpoint(name).Record(0, 0, 0, false, false)
+}
+
+// retryDelay returns how long to wait before retrying the request, and false
+// if it should not be retried. Rate limited requests were not processed, and
+// are retried whatever their method. Other failures are retried for the
+// idempotent methods only.
+func retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= jiraMaxRetries || (req.Body != nil && req.GetBody == nil) {
+		return 0, false
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return after, after <= jiraMaxRetryAfter
+		}
+		return backoffDelay(attempt), true
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return backoffDelay(attempt), req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoffDelay(attempt), true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as a date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func backoffDelay(attempt int) time.Duration {
+	d := jiraRetryBaseDelay << uint(attempt)
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// sleepContext waits for d, or until the request is canceled.
+func sleepContext(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJiraTransport(t *testing.T) {
+	for name, tc := range map[string]struct {
+		method        string
+		statuses      []int
+		retryAfter    string
+		expectedCalls int
+		expectedCode  int
+		expectedSleep time.Duration
+	}{
+		"no retry on success": {
+			method:        http.MethodGet,
+			statuses:      []int{200},
+			expectedCalls: 1,
+			expectedCode:  200,
+		},
+		"rate limited with Retry-After": {
+			method:        http.MethodPost,
+			statuses:      []int{429, 201},
+			retryAfter:    "2",
+			expectedCalls: 2,
+			expectedCode:  201,
+			expectedSleep: 2 * time.Second,
+		},
+		"Retry-After too long": {
+			method:        http.MethodGet,
+			statuses:      []int{429, 200},
+			retryAfter:    "120",
+			expectedCalls: 1,
+			expectedCode:  429,
+		},
+		"unavailable GET is retried": {
+			method:        http.MethodGet,
+			statuses:      []int{503, 502, 200},
+			expectedCalls: 3,
+			expectedCode:  200,
+		},
+		"unavailable POST is not retried": {
+			method:        http.MethodPost,
+			statuses:      []int{503, 200},
+			expectedCalls: 1,
+			expectedCode:  503,
+		},
+		"retries are limited": {
+			method:        http.MethodPut,
+			statuses:      []int{504, 504, 504, 504, 200},
+			expectedCalls: 4,
+			expectedCode:  504,
+		},
+		"client errors are not retried": {
+			method:        http.MethodGet,
+			statuses:      []int{404, 200},
+			expectedCalls: 1,
+			expectedCode:  404,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			bodies := []string{}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.WriteHeader(tc.statuses[calls])
+				calls++
+			}))
+			defer ts.Close()
+
+			var slept time.Duration
+			client := &http.Client{
+				Transport: &jiraTransport{
+					RoundTripper: http.DefaultTransport,
+					limiter:      make(chan struct{}, 1),
+					sleep: func(req *http.Request, d time.Duration) error {
+						slept += d
+						return nil
+					},
+				},
+			}
+			req, err := http.NewRequest(tc.method, ts.URL, strings.NewReader("payload"))
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tc.expectedCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedCalls, calls)
+			for _, body := range bodies {
+				assert.Equal(t, "payload", body, "the body is sent again on retries")
+			}
+			if tc.expectedSleep != 0 {
+				assert.Equal(t, tc.expectedSleep, slept)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("10", now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(5*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package jiraevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// normalizePayload converts the rich text fields of a webhook payload sent by
+// Jira Cloud in the Atlassian Document Format (ADF) to wiki markup, as sent
+// by Jira Server, so that they unmarshal into the string fields of Event.
+// Payloads without ADF documents are returned unchanged.
+func normalizePayload(bb []byte) ([]byte, error) {
+	if !bytes.Contains(bb, []byte(`"doc"`)) {
+		return bb, nil
+	}
+
+	payload := map[string]interface{}{}
+	err := json.Unmarshal(bb, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := false
+	convert := func(parent map[string]interface{}, key string) {
+		if parent == nil {
+			return
+		}
+		doc, ok := parent[key].(map[string]interface{})
+		if !ok || doc["type"] != "doc" {
+			return
+		}
+		parent[key] = adfToWikiMarkup(doc)
+		converted = true
+	}
+
+	issue, _ := payload["issue"].(map[string]interface{})
+	fields, _ := issue["fields"].(map[string]interface{})
+	convert(fields, "description")
+	convert(fields, "environment")
+	if comments, ok := fields["comment"].(map[string]interface{}); ok {
+		list, _ := comments["comments"].([]interface{})
+		for _, c := range list {
+			comment, _ := c.(map[string]interface{})
+			convert(comment, "body")
+		}
+	}
+	comment, _ := payload["comment"].(map[string]interface{})
+	convert(comment, "body")
+
+	if !converted {
+		return bb, nil
+	}
+	return json.Marshal(payload)
+}
+
+// adfToWikiMarkup renders an ADF document in Jira wiki markup. Unsupported
+// nodes are rendered as their text content.
+func adfToWikiMarkup(doc map[string]interface{}) string {
+	w := &adfWriter{}
+	w.nodes(doc["content"], "")
+	return strings.TrimRight(w.String(), "\n")
+}
+
+type adfWriter struct {
+	bytes.Buffer
+}
+
+func (w *adfWriter) nodes(content interface{}, listPrefix string) {
+	for _, node := range asNodes(content) {
+		w.node(node, listPrefix)
+	}
+}
+
+func (w *adfWriter) node(node map[string]interface{}, listPrefix string) {
+	attrs, _ := node["attrs"].(map[string]interface{})
+	switch node["type"] {
+	case "text":
+		text, _ := node["text"].(string)
+		w.WriteString(adfMarks(text, node["marks"]))
+	case "hardBreak":
+		w.WriteString("\n")
+	case "mention":
+		id, _ := attrs["id"].(string)
+		fmt.Fprintf(w, "[~accountid:%s]", id)
+	case "emoji":
+		text, _ := attrs["text"].(string)
+		if text == "" {
+			text, _ = attrs["shortName"].(string)
+		}
+		w.WriteString(text)
+	case "inlineCard", "blockCard":
+		url, _ := attrs["url"].(string)
+		fmt.Fprintf(w, "[%s]", url)
+	case "paragraph":
+		w.nodes(node["content"], listPrefix)
+		w.WriteString("\n\n")
+	case "heading":
+		level, _ := attrs["level"].(float64)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		fmt.Fprintf(w, "h%d. ", int(level))
+		w.nodes(node["content"], listPrefix)
+		w.WriteString("\n\n")
+	case "bulletList":
+		w.list(node["content"], listPrefix+"*")
+	case "orderedList":
+		w.list(node["content"], listPrefix+"#")
+	case "codeBlock":
+		w.WriteString("{code}\n")
+		w.nodes(node["content"], listPrefix)
+		w.WriteString("\n{code}\n\n")
+	case "blockquote":
+		w.WriteString("{quote}\n")
+		w.nodes(node["content"], listPrefix)
+		w.WriteString("{quote}\n\n")
+	case "rule":
+		w.WriteString("----\n\n")
+	default:
+		w.nodes(node["content"], listPrefix)
+	}
+}
+
+func (w *adfWriter) list(content interface{}, prefix string) {
+	for _, item := range asNodes(content) {
+		w.WriteString(prefix + " ")
+		for _, c := range asNodes(item["content"]) {
+			if c["type"] == "paragraph" {
+				// List item paragraphs are on a single line
+				w.nodes(c["content"], prefix)
+				w.WriteString("\n")
+				continue
+			}
+			w.node(c, prefix)
+		}
+	}
+	// A blank line after the outermost list
+	if len(prefix) == 1 {
+		w.WriteString("\n")
+	}
+}
+
+func asNodes(content interface{}) []map[string]interface{} {
+	list, _ := content.([]interface{})
+	nodes := []map[string]interface{}{}
+	for _, n := range list {
+		if node, ok := n.(map[string]interface{}); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func adfMarks(text string, marks interface{}) string {
+	for _, mark := range asNodes(marks) {
+		switch mark["type"] {
+		case "strong":
+			text = "*" + text + "*"
+		case "em":
+			text = "_" + text + "_"
+		case "strike":
+			text = "-" + text + "-"
+		case "code":
+			text = "{{" + text + "}}"
+		case "link":
+			attrs, _ := mark["attrs"].(map[string]interface{})
+			if href, _ := attrs["href"].(string); href != "" {
+				text = "[" + text + "|" + href + "]"
+			}
+		}
+	}
+	return text
+}
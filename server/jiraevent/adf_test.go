@@ -0,0 +1,30 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package jiraevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePayloadADF(t *testing.T) {
+	e := parseTestData(t, "webhook-cloud-comment-created-adf.json")
+	assert.Equal(t, "Thanks [~accountid:5c5f880629be9642ba529341], see [the docs|https://example.com/docs] for *details*\n\n"+
+		"* first\n"+
+		"* second\n"+
+		"*# nested\n"+
+		"\n"+
+		"{code}\nx := 1\n{code}", e.Comment.Body)
+}
+
+func TestNormalizePayloadUnchanged(t *testing.T) {
+	data, err := getTestData("webhook-cloud-comment-created.json")
+	require.Nil(t, err)
+
+	normalized, err := normalizePayload(data)
+	require.Nil(t, err)
+	assert.Equal(t, data, normalized)
+}
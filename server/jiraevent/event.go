@@ -0,0 +1,202 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+// Package jiraevent models the events that Jira Cloud and Jira Server send
+// to the webhooks, and parses their payloads into one model regardless of
+// the variant that sent them.
+package jiraevent
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// Event is the payload of a Jira webhook request.
+type Event struct {
+	WebhookEvent       string       `json:"webhookEvent,omitempty"`
+	IssueEventTypeName string       `json:"issue_event_type_name"`
+	Issue              jira.Issue   `json:"issue,omitempty"`
+	User               jira.User    `json:"user,omitempty"`
+	Comment            jira.Comment `json:"comment,omitempty"`
+	ChangeLog          ChangeLog    `json:"changelog,omitempty"`
+
+	// Project is only set in project lifecycle events.
+	Project Project `json:"project,omitempty"`
+
+	// Property is only set in issue_property_set events.
+	Property Property `json:"property,omitempty"`
+
+	// raw is the complete payload, for the fields that are not modeled above.
+	raw *rawPayload
+}
+
+// rawPayload is the complete payload of an event, only unmarshaled when the
+// fields that are not modeled are used.
+type rawPayload struct {
+	data  []byte
+	once  sync.Once
+	value interface{}
+}
+
+// ChangeLog lists the changes of the fields of the issue in issue updates.
+type ChangeLog struct {
+	Items []ChangeItem `json:"items,omitempty"`
+}
+
+// ChangeItem is the change of an issue field. From and To are the IDs of
+// the values, for the fields that have some, and FromString and ToString
+// their names.
+type ChangeItem struct {
+	From       string `json:"from,omitempty"`
+	FromString string `json:"fromString,omitempty"`
+	To         string `json:"to,omitempty"`
+	ToString   string `json:"toString,omitempty"`
+	Field      string `json:"field,omitempty"`
+	FieldId    string `json:"fieldId,omitempty"`
+	FieldType  string `json:"fieldtype,omitempty"`
+}
+
+// Project is the project of a project_created or project_deleted event. The
+// Jira payload has a numeric ID, so jira.Project can't be used.
+type Project struct {
+	Self        string     `json:"self,omitempty"`
+	Key         string     `json:"key,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	ProjectLead *jira.User `json:"projectLead,omitempty"`
+}
+
+// Property is the entity property of an issue_property_set event.
+type Property struct {
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Parse parses a webhook payload. The rich text fields that Jira Cloud sends
+// in the Atlassian Document Format are converted to wiki markup, as sent by
+// Jira Server, and the comment events of Jira Cloud, which have no user,
+// get the comment author as their user.
+func Parse(data []byte) (*Event, error) {
+	normalized, err := normalizePayload(data)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{}
+	err = json.Unmarshal(normalized, e)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal the webhook payload")
+	}
+	e.raw = &rawPayload{data: normalized}
+	e.normalize()
+	return e, nil
+}
+
+// normalize fills in the fields of an event that only one of Jira Cloud and
+// Jira Server sets.
+func (e *Event) normalize() {
+	if !hasUserId(&e.Comment.UpdateAuthor) {
+		e.Comment.UpdateAuthor = e.Comment.Author
+	}
+	if !hasUserId(&e.User) && hasUserId(&e.Comment.UpdateAuthor) {
+		e.User = e.Comment.UpdateAuthor
+	}
+}
+
+func hasUserId(u *jira.User) bool {
+	return u != nil && (u.AccountID != "" || u.Name != "" || u.Key != "")
+}
+
+// Raw returns the complete payload, as unmarshaled into interface{}. It is
+// unmarshaled on the first call.
+func (e *Event) Raw() interface{} {
+	if e.raw == nil {
+		return nil
+	}
+	e.raw.once.Do(func() {
+		_ = json.Unmarshal(e.raw.data, &e.raw.value)
+	})
+	return e.raw.value
+}
+
+// HasIssue returns true if the event carries an issue with its fields.
+func (e *Event) HasIssue() bool {
+	return e.Issue.Fields != nil
+}
+
+// IssueKey returns the key of the issue, or "" if the event has none.
+func (e *Event) IssueKey() string {
+	return e.Issue.Key
+}
+
+// ProjectKey returns the key of the project of the issue, or of the project
+// of a project event.
+func (e *Event) ProjectKey() string {
+	if e.Issue.Fields != nil && e.Issue.Fields.Project.Key != "" {
+		return e.Issue.Fields.Project.Key
+	}
+	return e.Project.Key
+}
+
+// IssueType returns the type of the issue.
+func (e *Event) IssueType() jira.IssueType {
+	if e.Issue.Fields == nil {
+		return jira.IssueType{}
+	}
+	return e.Issue.Fields.Type
+}
+
+// Actor returns the user who triggered the event, or nil if Jira didn't
+// tell, e.g. for the changes made by some automation.
+func (e *Event) Actor() *jira.User {
+	if !hasUserId(&e.User) && e.User.DisplayName == "" {
+		return nil
+	}
+	return &e.User
+}
+
+// IsCommentEvent returns true for the comment events of Jira Cloud, whose
+// issue only has a few fields.
+func (e *Event) IsCommentEvent() bool {
+	switch e.WebhookEvent {
+	case "comment_created", "comment_updated", "comment_deleted":
+		return true
+	}
+	return false
+}
+
+// Change returns the change of a field, by name or ID, case-insensitively.
+func (e *Event) Change(field string) (ChangeItem, bool) {
+	for _, item := range e.ChangeLog.Items {
+		if strings.EqualFold(item.Field, field) || strings.EqualFold(item.FieldId, field) {
+			return item, true
+		}
+	}
+	return ChangeItem{}, false
+}
+
+// DropChanges removes the changelog items drop returns true for, and
+// returns false if there are no items left.
+func (e *Event) DropChanges(drop func(item ChangeItem) bool) bool {
+	items := e.ChangeLog.Items[:0]
+	for _, item := range e.ChangeLog.Items {
+		if !drop(item) {
+			items = append(items, item)
+		}
+	}
+	e.ChangeLog.Items = items
+	return len(items) > 0
+}
+
+// MovedFromKey returns the key the issue had before it was moved to another
+// project, or "" if the event is not a move.
+func (e *Event) MovedFromKey() string {
+	for _, item := range e.ChangeLog.Items {
+		if item.Field == "Key" && item.FromString != "" && item.FromString != e.Issue.Key {
+			return item.FromString
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package jiraevent
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestData(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join("..", "testdata", filename))
+}
+
+func parseTestData(t *testing.T, filename string) *Event {
+	data, err := getTestData(filename)
+	require.Nil(t, err)
+	e, err := Parse(data)
+	require.Nil(t, err)
+	return e
+}
+
+// TestParseFixtures parses every webhook payload of the test data, sent by
+// Jira Cloud or Jira Server, and checks the fields used to filter and render
+// the events. A new fixture must be added to the table.
+func TestParseFixtures(t *testing.T) {
+	for _, tc := range []struct {
+		filename           string
+		webhookEvent       string
+		issueEventTypeName string
+		issueKey           string
+		projectKey         string
+		actorAccountID     string
+		actorName          string
+		changes            int
+	}{
+		{"webhook-cloud-comment-created-adf.json", "comment_created", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-cloud-comment-created-restricted.json", "comment_created", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-cloud-comment-created.json", "comment_created", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-cloud-comment-deleted.json", "comment_deleted", "", "KT-7", "KT", "5cedcb4355a88a0fc86388ba", "", 0},
+		{"webhook-cloud-comment-updated.json", "comment_updated", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-cloud-issue-created-many-fields.json", "jira:issue_created", "issue_created", "KT-22", "KT", "5cedcb4355a88a0fc86388ba", "sample.user", 9},
+		{"webhook-cloud-issue-property-set.json", "issue_property_set", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-cloud-issue-updated-custom-field.json", "jira:issue_updated", "issue_updated", "TES-14", "TES", "5cedcb4355a88a0fc86388ba", "test", 1},
+		{"webhook-cloud-issue-updated-moved.json", "jira:issue_updated", "issue_moved", "NEW-5", "NEW", "5c5f880629be9642ba529340", "admin", 2},
+		{"webhook-issue-archived-unrecognized.json", "jira:issue_archived", "issue_archived", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 5},
+		{"webhook-issue-created-no-description-nor-relevant-fields.json", "jira:issue_created", "issue_created", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 5},
+		{"webhook-issue-created-no-description.json", "jira:issue_created", "issue_created", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 5},
+		{"webhook-issue-created-no-relevant-fields.json", "jira:issue_created", "issue_created", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 5},
+		{"webhook-issue-created.json", "jira:issue_created", "issue_created", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 5},
+		{"webhook-issue-deleted.json", "jira:issue_deleted", "", "IDT-19", "IDT", "5c5f880629be9642ba529340", "admin", 0},
+		{"webhook-issue-updated-assigned-nobody.json", "jira:issue_updated", "issue_assigned", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-assigned-on-server.json", "jira:issue_updated", "issue_updated", "PRJA-37", "PRJA", "", "admin", 1},
+		{"webhook-issue-updated-assigned.json", "jira:issue_updated", "issue_assigned", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-attachments.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-components.json", "jira:issue_updated", "issue_updated", "TES-10", "TES", "5cedcb4355a88a0fc86388ba", "", 1},
+		{"webhook-issue-updated-edited.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-fix-version.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-issue-type.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-labels.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-lowered-priority.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-multiple-custom-fields.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 2},
+		{"webhook-issue-updated-multiple-values.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 3},
+		{"webhook-issue-updated-raised-priority.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-rank.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-renamed.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-reopened-one-changelog.json", "jira:issue_updated", "issue_generic", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-reopened.json", "jira:issue_updated", "issue_generic", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 2},
+		{"webhook-issue-updated-resolved-one-changelog.json", "jira:issue_updated", "issue_generic", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-resolved.json", "jira:issue_updated", "issue_generic", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 2},
+		{"webhook-issue-updated-sprint.json", "jira:issue_updated", "issue_updated", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-issue-updated-started-working.json", "jira:issue_updated", "issue_generic", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-project-created.json", "project_created", "", "", "NEW", "", "", 0},
+		{"webhook-project-deleted.json", "project_deleted", "", "", "TES", "", "", 0},
+		{"webhook-server-comment-created.json", "comment_created", "", "", "", "", "levbrouk", 0},
+		{"webhook-server-comment-deleted.json", "comment_deleted", "", "", "", "", "levbrouk", 0},
+		{"webhook-server-comment-updated.json", "comment_updated", "", "", "", "", "levbrouk", 0},
+		{"webhook-server-issue-updated-closed.json", "jira:issue_updated", "issue_closed", "TES-4", "TES", "", "admin", 1},
+		{"webhook-server-issue-updated-comment-deleted.json", "jira:issue_updated", "issue_comment_deleted", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-issue-updated-comment-edited.json", "jira:issue_updated", "issue_comment_edited", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-issue-updated-commented-1.json", "jira:issue_updated", "issue_commented", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-issue-updated-commented-2.json", "jira:issue_updated", "issue_commented", "PRJA-42", "PRJA", "", "TestUser", 0},
+		{"webhook-server-issue-updated-commented-3.json", "jira:issue_updated", "issue_commented", "PRJA-42", "PRJA", "", "TestUser", 0},
+		{"webhook-server-issue-updated-in-progress.json", "jira:issue_updated", "issue_work_started", "TES-4", "TES", "", "admin", 1},
+		{"webhook-server-issue-updated-many-fields.json", "jira:issue_updated", "issue_updated", "HEY-15", "HEY", "", "mickmister", 1},
+		{"webhook-server-issue-updated-reopened.json", "jira:issue_updated", "issue_reopened", "TES-4", "TES", "", "admin", 2},
+		{"webhook-server-issue-updated-resolved.json", "jira:issue_updated", "issue_resolved", "TES-4", "TES", "", "admin", 2},
+		{"webhook-server-old-issue-updated-no-event-type-assigned.json", "jira:issue_updated", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-server-old-issue-updated-no-event-type-comment-deleted.json", "jira:issue_updated", "", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-old-issue-updated-no-event-type-comment-edited.json", "jira:issue_updated", "", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-old-issue-updated-no-event-type-commented.json", "jira:issue_updated", "", "PRJX-14", "PRJX", "", "levbrouk", 0},
+		{"webhook-server-old-issue-updated-no-event-type-edited.json", "jira:issue_updated", "", "TES-41", "TES", "5c5f880629be9642ba529340", "admin", 1},
+		{"webhook-server-updated-custom-field.json", "jira:issue_updated", "issue_updated", "TES-8", "TES", "", "test", 1},
+		{"webhook-user-updated.json", "user_updated", "", "", "", "5c5f880629be9642ce1e0cd6", "", 0},
+	} {
+		t.Run(tc.filename, func(t *testing.T) {
+			e := parseTestData(t, tc.filename)
+			assert.Equal(t, tc.webhookEvent, e.WebhookEvent)
+			assert.Equal(t, tc.issueEventTypeName, e.IssueEventTypeName)
+			assert.Equal(t, tc.issueKey, e.IssueKey())
+			assert.Equal(t, tc.projectKey, e.ProjectKey())
+			assert.Len(t, e.ChangeLog.Items, tc.changes)
+			assert.Nil(t, e.raw.value, "the raw payload is only unmarshaled when used")
+			assert.NotNil(t, e.Raw())
+			copied := *e
+			assert.Equal(t, e.Raw(), copied.Raw())
+
+			actor := e.Actor()
+			if tc.actorAccountID == "" && tc.actorName == "" {
+				assert.Nil(t, actor)
+				return
+			}
+			require.NotNil(t, actor)
+			assert.Equal(t, tc.actorAccountID, actor.AccountID)
+			assert.Equal(t, tc.actorName, actor.Name)
+		})
+	}
+}
+
+func TestParseFixturesListed(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "webhook-*.json"))
+	require.Nil(t, err)
+	assert.Len(t, files, 58)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte(`{"webhookEvent": 1}`))
+	assert.NotNil(t, err)
+	_, err = Parse([]byte(`not json`))
+	assert.NotNil(t, err)
+}
+
+func TestEventIssueAccessors(t *testing.T) {
+	e := parseTestData(t, "webhook-cloud-issue-created-many-fields.json")
+	assert.True(t, e.HasIssue())
+	assert.False(t, e.IsCommentEvent())
+	assert.NotEmpty(t, e.IssueType().Name)
+
+	e = parseTestData(t, "webhook-project-created.json")
+	assert.False(t, e.HasIssue())
+	assert.Equal(t, "", e.IssueType().Name)
+	assert.Equal(t, "NEW", e.Project.Key)
+
+	e = parseTestData(t, "webhook-cloud-comment-created.json")
+	assert.True(t, e.IsCommentEvent())
+	// Jira Cloud comment events have no user, the comment author is used
+	assert.Equal(t, e.Comment.Author.AccountID, e.User.AccountID)
+	assert.Equal(t, e.Comment.Author.AccountID, e.Comment.UpdateAuthor.AccountID)
+
+	e = parseTestData(t, "webhook-cloud-issue-property-set.json")
+	assert.NotEmpty(t, e.Property.Key)
+}
+
+func TestEventChange(t *testing.T) {
+	e := parseTestData(t, "webhook-issue-updated-multiple-values.json")
+
+	item, ok := e.Change("fix version")
+	require.True(t, ok)
+	assert.Equal(t, ChangeItem{
+		From:       "10000",
+		FromString: "v1",
+		To:         "10001",
+		ToString:   "v2",
+		Field:      "Fix Version",
+		FieldId:    "fixVersions",
+		FieldType:  "jira",
+	}, item)
+
+	item, ok = e.Change("customfield_10072")
+	require.True(t, ok)
+	assert.Equal(t, "QA Steps", item.Field)
+	assert.Equal(t, "custom", item.FieldType)
+	assert.Equal(t, "", item.FromString)
+
+	_, ok = e.Change("priority")
+	assert.False(t, ok)
+}
+
+func TestEventDropChanges(t *testing.T) {
+	e := parseTestData(t, "webhook-issue-updated-multiple-values.json")
+
+	assert.True(t, e.DropChanges(func(item ChangeItem) bool { return item.FieldType == "custom" }))
+	require.Len(t, e.ChangeLog.Items, 2)
+	assert.Equal(t, "fixVersions", e.ChangeLog.Items[0].FieldId)
+	assert.Equal(t, "assignee", e.ChangeLog.Items[1].FieldId)
+
+	assert.False(t, e.DropChanges(func(item ChangeItem) bool { return true }))
+	assert.Empty(t, e.ChangeLog.Items)
+}
+
+func TestEventMovedFromKey(t *testing.T) {
+	e := parseTestData(t, "webhook-cloud-issue-updated-moved.json")
+	assert.Equal(t, "TES-41", e.MovedFromKey())
+
+	e = parseTestData(t, "webhook-issue-updated-renamed.json")
+	assert.Equal(t, "", e.MovedFromKey())
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// JQLError is an error of a JQL query, at Line and Character, from 1, when
+// Jira reports its position.
+type JQLError struct {
+	Message   string `json:"message"`
+	Line      int    `json:"line,omitempty"`
+	Character int    `json:"character,omitempty"`
+}
+
+// reJQLErrorPosition matches the position at the end of the messages of the
+// JQL syntax errors, like "(line 1, character 9)".
+var reJQLErrorPosition = regexp.MustCompile(`\(line (\d+), character (\d+)\)\.?$`)
+
+func parseJQLErrors(messages []string) []JQLError {
+	jqlErrors := []JQLError{}
+	for _, message := range messages {
+		jqlError := JQLError{Message: strings.TrimSpace(message)}
+		if m := reJQLErrorPosition.FindStringSubmatch(jqlError.Message); m != nil {
+			jqlError.Line, _ = strconv.Atoi(m[1])
+			jqlError.Character, _ = strconv.Atoi(m[2])
+		}
+		jqlErrors = append(jqlErrors, jqlError)
+	}
+	return jqlErrors
+}
+
+// formatJQLErrors renders the errors of a query as a list, with the line of
+// the first error that has a position, marked under the character.
+func formatJQLErrors(jql string, jqlErrors []JQLError) string {
+	lines := []string{}
+	marked := false
+	for _, jqlError := range jqlErrors {
+		lines = append(lines, "* "+jqlError.Message)
+		if marked || jqlError.Line == 0 {
+			continue
+		}
+		queryLines := strings.Split(jql, "\n")
+		if jqlError.Line > len(queryLines) {
+			continue
+		}
+		line := []rune(queryLines[jqlError.Line-1])
+		character := jqlError.Character
+		if character < 1 {
+			character = 1
+		}
+		if character > len(line)+1 {
+			character = len(line) + 1
+		}
+		lines = append(lines, "```", string(line), strings.Repeat(" ", character-1)+"^", "```")
+		marked = true
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateJQL returns an error listing the errors of the query, if any.
+func validateJQL(client Client, jql string) error {
+	jqlErrors, err := client.ValidateJQL(jql)
+	if err != nil {
+		return errors.WithMessage(err, "failed to validate the JQL query")
+	}
+	if len(jqlErrors) != 0 {
+		return errors.New("the JQL query is not valid:\n" + formatJQLErrors(jql, jqlErrors))
+	}
+	return nil
+}
+
+type validateJQLResponse struct {
+	Valid  bool       `json:"valid"`
+	Errors []JQLError `json:"errors,omitempty"`
+}
+
+// httpAPIValidateJQL validates the JQL query of the request with the Jira
+// account of the user, for the JQL filters of the subscriptions to be checked
+// before they are saved.
+func httpAPIValidateJQL(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := struct {
+		JQL string `json:"jql"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to decode incoming request")
+	}
+	if strings.TrimSpace(request.JQL) == "" {
+		return http.StatusBadRequest, errors.New("jql is required")
+	}
+
+	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	jqlErrors, err := client.ValidateJQL(request.JQL)
+	if err != nil {
+		return http.StatusBadGateway, errors.WithMessage(err, "failed to validate the JQL query")
+	}
+
+	b, _ := json.Marshal(validateJQLResponse{Valid: len(jqlErrors) == 0, Errors: jqlErrors})
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func executeJQLValidate(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) == 0 {
+		return p.responsef(header, "Please use `/jira jql validate <JQL>`, e.g. `/jira jql validate project = EXT AND labels = release`.")
+	}
+	jql := strings.Join(args, " ")
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeJQLValidate: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	jqlErrors, err := client.ValidateJQL(jql)
+	if err != nil {
+		return p.responsef(header, "Failed to validate the JQL query: %v", err)
+	}
+	if len(jqlErrors) == 0 {
+		return p.responsef(header, "`%s` is a valid JQL query.", jql)
+	}
+	return p.responsef(header, "`%s` is not a valid JQL query:\n%s", jql, formatJQLErrors(jql, jqlErrors))
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJQLErrors(t *testing.T) {
+	assert.Equal(t, []JQLError{
+		{Message: "Error in the JQL Query: Expecting operator but got 'bar'. (line 1, character 19)", Line: 1, Character: 19},
+		{Message: "Field 'sprnt' does not exist or you do not have permission to view it."},
+	}, parseJQLErrors([]string{
+		"Error in the JQL Query: Expecting operator but got 'bar'. (line 1, character 19)",
+		" Field 'sprnt' does not exist or you do not have permission to view it.",
+	}))
+}
+
+func TestFormatJQLErrors(t *testing.T) {
+	jql := "project = TES foo bar"
+	assert.Equal(t, "* Expecting operator (line 1, character 15)\n"+
+		"```\n"+
+		"project = TES foo bar\n"+
+		"              ^\n"+
+		"```\n"+
+		"* Expecting field (line 1, character 19)",
+		formatJQLErrors(jql, []JQLError{
+			{Message: "Expecting operator (line 1, character 15)", Line: 1, Character: 15},
+			{Message: "Expecting field (line 1, character 19)", Line: 1, Character: 19},
+		}))
+
+	// Positions out of the query are not marked
+	assert.Equal(t, "* Unexpected end (line 2, character 1)",
+		formatJQLErrors(jql, []JQLError{{Message: "Unexpected end (line 2, character 1)", Line: 2, Character: 1}}))
+	assert.Equal(t, "* Field 'sprnt' does not exist",
+		formatJQLErrors(jql, []JQLError{{Message: "Field 'sprnt' does not exist"}}))
+}
+
+type invalidJQLTestClient struct {
+	testClient
+}
+
+func (client invalidJQLTestClient) ValidateJQL(jql string) ([]JQLError, error) {
+	return []JQLError{{Message: "Expecting operator (line 1, character 15)", Line: 1, Character: 15}}, nil
+}
+
+func TestValidateJQLHelper(t *testing.T) {
+	err := validateJQL(invalidJQLTestClient{}, "project = TES foo")
+	assert.EqualError(t, err, "the JQL query is not valid:\n"+
+		"* Expecting operator (line 1, character 15)\n"+
+		"```\n"+
+		"project = TES foo\n"+
+		"              ^\n"+
+		"```")
+}
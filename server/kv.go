@@ -151,7 +151,11 @@ func (store store) StoreJIRAInstance(ji Instance) (returnErr error) {
 			fmt.Sprintf("failed to store Jira instance:%s", ji.GetURL()))
 	}()
 
-	err := store.set(hashkey(prefixJIRAInstance, ji.GetURL()), ji)
+	v, err := store.plugin.getConfig().credentials.encryptInstance(ji)
+	if err != nil {
+		return err
+	}
+	err = store.set(hashkey(prefixJIRAInstance, ji.GetURL()), v)
 	if err != nil {
 		return err
 	}
@@ -207,7 +211,11 @@ func (store store) StoreCurrentJIRAInstance(ji Instance) (returnErr error) {
 		returnErr = errors.WithMessage(returnErr,
 			fmt.Sprintf("failed to store current Jira instance:%s", ji.GetURL()))
 	}()
-	err := store.set(keyCurrentJIRAInstance, ji)
+	v, err := store.plugin.getConfig().credentials.encryptInstance(ji)
+	if err != nil {
+		return err
+	}
+	err = store.set(keyCurrentJIRAInstance, v)
 	if err != nil {
 		return err
 	}
@@ -341,6 +349,27 @@ func (store store) loadJIRAInstance(fullkey string) (Instance, error) {
 		if err != nil {
 			return nil, errors.WithMessage(err, "failed to unmarshal stored Instance "+fullkey)
 		}
+		credentials := store.plugin.getConfig().credentials
+		raw, stale, err := credentials.decrypt(jci.RawAtlassianSecurityContext)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to decrypt stored Instance "+fullkey)
+		}
+		if stale && jci.Installed {
+			// Store again, encrypted with the current key
+			var v interface{}
+			v, err = credentials.encryptInstance(&jiraCloudInstance{
+				JIRAInstance:                jci.JIRAInstance,
+				Installed:                   jci.Installed,
+				RawAtlassianSecurityContext: raw,
+			})
+			if err == nil {
+				err = store.set(fullkey, v)
+			}
+			if err != nil {
+				store.plugin.errorf("failed to re-encrypt stored Instance %s: %v", fullkey, err)
+			}
+		}
+		jci.RawAtlassianSecurityContext = raw
 		if len(jci.RawAtlassianSecurityContext) > 0 {
 			err = json.Unmarshal([]byte(jci.RawAtlassianSecurityContext), &jci.AtlassianSecurityContext)
 			if err != nil {
@@ -390,7 +419,11 @@ func (store store) StoreUserInfo(ji Instance, mattermostUserId string, jiraUser
 			fmt.Sprintf("failed to store user, mattermostUserId:%s, Jira user:%s", mattermostUserId, jiraUser.DisplayName))
 	}()
 
-	err := store.set(keyWithInstance(ji, mattermostUserId), jiraUser)
+	jiraUser, err := store.plugin.getConfig().credentials.encryptUser(jiraUser)
+	if err != nil {
+		return err
+	}
+	err = store.set(keyWithInstance(ji, mattermostUserId), jiraUser)
 	if err != nil {
 		return err
 	}
@@ -426,6 +459,22 @@ func (store store) LoadJIRAUser(ji Instance, mattermostUserId string) (JIRAUser,
 	if len(jiraUser.Key()) == 0 {
 		return JIRAUser{}, ErrUserNotFound
 	}
+	credentials := store.plugin.getConfig().credentials
+	jiraUser, stale, err := credentials.decryptUser(jiraUser)
+	if err != nil {
+		return JIRAUser{}, errors.WithMessage(err,
+			fmt.Sprintf("failed to load Jira user for mattermostUserId:%s", mattermostUserId))
+	}
+	if stale {
+		// Store again, encrypted with the current key
+		encrypted, encryptErr := credentials.encryptUser(jiraUser)
+		if encryptErr == nil {
+			encryptErr = store.set(keyWithInstance(ji, mattermostUserId), encrypted)
+		}
+		if encryptErr != nil {
+			store.plugin.errorf("failed to re-encrypt Jira user for mattermostUserId:%s: %v", mattermostUserId, encryptErr)
+		}
+	}
 	jiraUser.PluginVersion = manifest.Version
 	return jiraUser, nil
 }
@@ -523,6 +572,46 @@ func (store store) CountUsers() (int, error) {
 	return count, nil
 }
 
+// loadSecret returns the plaintext of the secret stored at key, or nil if
+// there is none. A secret stored as plaintext, or encrypted with a previous
+// key, is stored again encrypted with the current key.
+func (store store) loadSecret(key string) ([]byte, error) {
+	data, appErr := store.plugin.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	credentials := store.plugin.getConfig().credentials
+	plain, stale, err := credentials.decrypt(string(data))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt stored "+key)
+	}
+	if stale {
+		// Store again, encrypted with the current key
+		err = store.storeSecret(key, []byte(plain))
+		if err != nil {
+			store.plugin.errorf("failed to re-encrypt stored %s: %v", key, err)
+		}
+	}
+	return []byte(plain), nil
+}
+
+// storeSecret stores secret at key, encrypted with the current key.
+func (store store) storeSecret(key string, secret []byte) error {
+	encrypted, err := store.plugin.getConfig().credentials.encrypt(string(secret))
+	if err != nil {
+		return err
+	}
+	appErr := store.plugin.API.KVSet(key, []byte(encrypted))
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
 func (store store) EnsureAuthTokenEncryptSecret() (secret []byte, returnErr error) {
 	defer func() {
 		if returnErr == nil {
@@ -532,21 +621,21 @@ func (store store) EnsureAuthTokenEncryptSecret() (secret []byte, returnErr erro
 	}()
 
 	// nil, nil == NOT_FOUND, if we don't already have a key, try to generate one.
-	secret, appErr := store.plugin.API.KVGet(keyTokenSecret)
-	if appErr != nil {
-		return nil, appErr
+	secret, err := store.loadSecret(keyTokenSecret)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(secret) == 0 {
 		newSecret := make([]byte, 32)
-		_, err := rand.Reader.Read(newSecret)
+		_, err = rand.Reader.Read(newSecret)
 		if err != nil {
 			return nil, err
 		}
 
-		appErr = store.plugin.API.KVSet(keyTokenSecret, newSecret)
-		if appErr != nil {
-			return nil, appErr
+		err = store.storeSecret(keyTokenSecret, newSecret)
+		if err != nil {
+			return nil, err
 		}
 		secret = newSecret
 		store.plugin.debugf("Stored: auth token secret")
@@ -555,9 +644,9 @@ func (store store) EnsureAuthTokenEncryptSecret() (secret []byte, returnErr erro
 	// If we weren't able to save a new key above, another server must have beat us to it. Get the
 	// key from the database, and if that fails, error out.
 	if secret == nil {
-		secret, appErr = store.plugin.API.KVGet(keyTokenSecret)
-		if appErr != nil {
-			return nil, appErr
+		secret, err = store.loadSecret(keyTokenSecret)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -572,9 +661,9 @@ func (store store) EnsureRSAKey() (rsaKey *rsa.PrivateKey, returnErr error) {
 		returnErr = errors.WithMessage(returnErr, "failed to ensure RSA key")
 	}()
 
-	appErr := store.get(keyRSAKey, &rsaKey)
-	if appErr != nil {
-		return nil, appErr
+	rsaKey, err := store.loadRSAKey()
+	if err != nil {
+		return nil, err
 	}
 
 	if rsaKey == nil {
@@ -583,9 +672,13 @@ func (store store) EnsureRSAKey() (rsaKey *rsa.PrivateKey, returnErr error) {
 			return nil, err
 		}
 
-		appErr = store.set(keyRSAKey, newRSAKey)
-		if appErr != nil {
-			return nil, appErr
+		data, err := json.Marshal(newRSAKey)
+		if err != nil {
+			return nil, err
+		}
+		err = store.storeSecret(keyRSAKey, data)
+		if err != nil {
+			return nil, err
 		}
 		rsaKey = newRSAKey
 		store.plugin.debugf("Stored: RSA key")
@@ -594,15 +687,30 @@ func (store store) EnsureRSAKey() (rsaKey *rsa.PrivateKey, returnErr error) {
 	// If we weren't able to save a new key above, another server must have beat us to it. Get the
 	// key from the database, and if that fails, error out.
 	if rsaKey == nil {
-		appErr = store.get(keyRSAKey, &rsaKey)
-		if appErr != nil {
-			return nil, appErr
+		rsaKey, err = store.loadRSAKey()
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return rsaKey, nil
 }
 
+// loadRSAKey returns the stored RSA key, or nil if there is none. The key is
+// stored as JSON, encrypted like the credentials.
+func (store store) loadRSAKey() (*rsa.PrivateKey, error) {
+	data, err := store.loadSecret(keyRSAKey)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var rsaKey *rsa.PrivateKey
+	err = json.Unmarshal(data, &rsaKey)
+	if err != nil {
+		return nil, err
+	}
+	return rsaKey, nil
+}
+
 func (store store) StoreOneTimeSecret(token, secret string) error {
 	// Expire in 15 minutes
 	appErr := store.plugin.API.KVSetWithExpiry(
@@ -0,0 +1,224 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// kvShardSize is the size of the shards of the values stored with the sharded
+// layout, and the size beyond which values are sharded. Made a variable so
+// tests can lower it.
+var kvShardSize = 256 * 1024
+
+// The shards of a value are stored at keys starting with prefixKVShards and a
+// hash of its key, followed by the version of the value and the index of the
+// shard, to fit the length limit of the KV keys.
+const prefixKVShards = "kvs_"
+
+// How long the shards of a write in progress may be unreferenced.
+const kvShardsStaleAfter = time.Minute
+
+// How many manifests a read of a sharded value tries, when its shards are
+// replaced by writes while it is read.
+const kvShardsReadAttempts = 3
+
+// kvShardsPrefix starts the manifests stored at the keys of the sharded
+// values, which the JSON values stored otherwise don't start with.
+var kvShardsPrefix = []byte(`{"kv_shards":`)
+
+// kvShards describes the shards of a value.
+type kvShards struct {
+	// Version is new for each write, so that the shards of a value are never
+	// overwritten while it is read. It is the time of the write in base 36.
+	Version string `json:"version"`
+	Count   int    `json:"count"`
+	Size    int    `json:"size"`
+}
+
+type kvShardsManifest struct {
+	Shards kvShards `json:"kv_shards"`
+}
+
+// kvShardsKeyPrefix starts the keys of all the shards of the value at key.
+func kvShardsKeyPrefix(key string) string {
+	return hashkey(prefixKVShards, key)[:len(prefixKVShards)+8] + "_"
+}
+
+func kvShardKey(key, version string, i int) string {
+	return kvShardsKeyPrefix(key) + version + "_" + strconv.Itoa(i)
+}
+
+// parseKVShards returns the shards described by a stored value, or false if
+// the value is not sharded.
+func parseKVShards(stored []byte) (kvShards, bool) {
+	if !bytes.HasPrefix(stored, kvShardsPrefix) {
+		return kvShards{}, false
+	}
+	manifest := kvShardsManifest{}
+	if err := json.Unmarshal(stored, &manifest); err != nil || manifest.Shards.Version == "" {
+		return kvShards{}, false
+	}
+	return manifest.Shards, true
+}
+
+// kvGetSharded returns the value stored at the key, and the value reassembled
+// from its shards if it is sharded. The shards of a manifest are deleted once
+// a write replaced it, so the manifest is read again when shards are missing,
+// up to kvShardsReadAttempts times.
+func (p *Plugin) kvGetSharded(key string) (stored, value []byte, err error) {
+	stored, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+	for attempt := 1; ; attempt++ {
+		shards, ok := parseKVShards(stored)
+		if !ok {
+			return stored, stored, nil
+		}
+		value = make([]byte, 0, shards.Size)
+		for i := 0; i < shards.Count; i++ {
+			data, appErr := p.API.KVGet(kvShardKey(key, shards.Version, i))
+			if appErr != nil {
+				return nil, nil, appErr
+			}
+			value = append(value, data...)
+		}
+		if len(value) == shards.Size {
+			return stored, value, nil
+		}
+
+		current, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, nil, appErr
+		}
+		if bytes.Equal(current, stored) || attempt >= kvShardsReadAttempts {
+			return nil, nil, errors.Errorf("the shards of %s are incomplete, %d of %d bytes", key, len(value), shards.Size)
+		}
+		stored = current
+	}
+}
+
+// kvSetShards stores the shards of a value under a new version, and returns
+// the manifest to store at the key.
+func (p *Plugin) kvSetShards(key string, value []byte) ([]byte, error) {
+	shards := kvShards{Version: strconv.FormatInt(time.Now().UnixNano(), 36), Size: len(value)}
+	for start := 0; start < len(value); start += kvShardSize {
+		end := start + kvShardSize
+		if end > len(value) {
+			end = len(value)
+		}
+		appErr := p.API.KVSet(kvShardKey(key, shards.Version, shards.Count), value[start:end])
+		if appErr != nil {
+			p.deleteKVShards(key, shards)
+			return nil, appErr
+		}
+		shards.Count++
+	}
+	return json.Marshal(kvShardsManifest{Shards: shards})
+}
+
+func (p *Plugin) deleteKVShards(key string, shards kvShards) {
+	for i := 0; i < shards.Count; i++ {
+		appErr := p.API.KVDelete(kvShardKey(key, shards.Version, i))
+		if appErr != nil {
+			p.errorf("deleteKVShards: failed to delete shard %d of %s: %v", i, key, appErr)
+		}
+	}
+}
+
+// atomicModifySharded is atomicModify for the values that may outgrow a single
+// KV entry, like the subscriptions: the values larger than kvShardSize are
+// stored in shards, and the manifest of the shards at the key.
+func (p *Plugin) atomicModifySharded(key string, modify func(initialValue []byte) ([]byte, error)) error {
+	var (
+		retryLimit = 5
+		retryWait  = 30 * time.Millisecond
+	)
+	for currentAttempt := 0; ; currentAttempt++ {
+		stored, initialValue, err := p.kvGetSharded(key)
+		if err != nil {
+			return errors.Wrap(err, "unable to read inital value")
+		}
+		newValue, err := modify(initialValue)
+		if err != nil {
+			return errors.Wrap(err, "modification error")
+		}
+		_, isSharded := parseKVShards(stored)
+		if currentAttempt == 0 && bytes.Equal(initialValue, newValue) && isSharded == (len(newValue) > kvShardSize) {
+			return nil
+		}
+
+		newStored := newValue
+		if len(newValue) > kvShardSize {
+			newStored, err = p.kvSetShards(key, newValue)
+			if err != nil {
+				return errors.Wrap(err, "problem writing shards")
+			}
+		}
+
+		success, setError := p.API.KVCompareAndSet(key, stored, newStored)
+		if newShards, ok := parseKVShards(newStored); ok && !success {
+			p.deleteKVShards(key, newShards)
+		}
+		if setError != nil {
+			return errors.Wrap(setError, "problem writing value")
+		}
+		if success {
+			if oldShards, ok := parseKVShards(stored); ok {
+				p.deleteKVShards(key, oldShards)
+			}
+			return nil
+		}
+
+		if currentAttempt+1 >= retryLimit {
+			return errors.New("reached write attempt limit")
+		}
+		time.Sleep(retryWait)
+	}
+}
+
+// staleKVShards returns the keys of the shards of the value at key that its
+// current manifest doesn't reference, left by interrupted writes. The shards
+// written within kvShardsStaleAfter may belong to a write in progress, and
+// are not stale yet.
+func (p *Plugin) staleKVShards(key string) ([]string, error) {
+	stored, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	current, _ := parseKVShards(stored)
+
+	prefix := kvShardsKeyPrefix(key)
+	stale := []string{}
+	for i := 0; ; i++ {
+		keys, appErr := p.API.KVList(i, listPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, k := range keys {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			version := strings.SplitN(strings.TrimPrefix(k, prefix), "_", 2)[0]
+			if version == current.Version {
+				continue
+			}
+			if written, err := strconv.ParseInt(version, 36, 64); err == nil && time.Since(time.Unix(0, written)) < kvShardsStaleAfter {
+				continue
+			}
+			stale = append(stale, k)
+		}
+		if len(keys) < listPerPage {
+			break
+		}
+	}
+	return stale, nil
+}
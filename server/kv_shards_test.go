@@ -0,0 +1,175 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupKVShardsTest returns a plugin storing in a KV map, with small shards
+// until the returned function restores their size.
+func setupKVShardsTest() (*Plugin, map[string][]byte, func()) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(func(key string, oldValue, newValue []byte) bool {
+		if !bytes.Equal(kv[key], oldValue) {
+			return false
+		}
+		kv[key] = newValue
+		return true
+	}, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(func(page, perPage int) []string {
+		keys := []string{}
+		for key := range kv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if page*perPage >= len(keys) {
+			return []string{}
+		}
+		keys = keys[page*perPage:]
+		if len(keys) > perPage {
+			keys = keys[:perPage]
+		}
+		return keys
+	}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	shardSize := kvShardSize
+	kvShardSize = 64
+	return p, kv, func() { kvShardSize = shardSize }
+}
+
+func TestAtomicModifySharded(t *testing.T) {
+	p, kv, restore := setupKVShardsTest()
+	defer restore()
+	value := []byte(strings.Repeat("0123456789", 20))
+
+	require.NoError(t, p.atomicModifySharded("key", func(initial []byte) ([]byte, error) {
+		assert.Empty(t, initial)
+		return value, nil
+	}))
+	shards, ok := parseKVShards(kv["key"])
+	require.True(t, ok)
+	assert.Equal(t, 4, shards.Count)
+	assert.Equal(t, 200, shards.Size)
+	assert.Len(t, kv, 5)
+
+	_, read, err := p.kvGetSharded("key")
+	require.NoError(t, err)
+	assert.Equal(t, value, read)
+
+	// A smaller value is stored in the key, and the shards are deleted
+	require.NoError(t, p.atomicModifySharded("key", func(initial []byte) ([]byte, error) {
+		assert.Equal(t, value, initial)
+		return []byte("small"), nil
+	}))
+	assert.Equal(t, map[string][]byte{"key": []byte("small")}, kv)
+}
+
+func TestKVGetShardedReplaced(t *testing.T) {
+	p, kv, restore := setupKVShardsTest()
+	defer restore()
+	require.NoError(t, p.atomicModifySharded("key", func(initial []byte) ([]byte, error) {
+		return []byte(strings.Repeat("a", 100)), nil
+	}))
+	replaced := kv["key"]
+	value := []byte(strings.Repeat("b", 100))
+	require.NoError(t, p.atomicModifySharded("key", func(initial []byte) ([]byte, error) {
+		return value, nil
+	}))
+
+	// The manifest was read before the write, whose shards are deleted
+	api := &plugintest.API{}
+	api.On("KVGet", "key").Return(replaced, nil).Once()
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	p.SetAPI(api)
+	stored, read, err := p.kvGetSharded("key")
+	require.NoError(t, err)
+	assert.Equal(t, kv["key"], stored)
+	assert.Equal(t, value, read)
+
+	// The shards of the current manifest are missing
+	for k := range kv {
+		if k != "key" {
+			delete(kv, k)
+		}
+	}
+	_, _, err = p.kvGetSharded("key")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the shards of key are incomplete, 0 of 100 bytes")
+}
+
+func TestCompactSubscriptions(t *testing.T) {
+	p, kv, restore := setupKVShardsTest()
+	defer restore()
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+
+	// An index entry of a removed subscription, an empty one, and the shard
+	// of an interrupted write
+	kv[subKey] = []byte(`{"Channel": {"by_id": {"sub1": {"id": "sub1", "channel_id": "channel1", "filters": {"events": ["event_created"]}}},
+		"id_by_channel_id": {"channel1": ["sub1", "sub2"], "channel2": []},
+		"id_by_event": {"event_created": ["sub1"]}}}`)
+	staleVersion := strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 36)
+	kv[kvShardKey(subKey, staleVersion, 0)] = []byte("stale")
+	kv["other"] = []byte("value")
+
+	usage, err := p.loadKVUsage(ji)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.Subscriptions)
+	assert.Equal(t, 2, usage.ChannelIndexEntries)
+	assert.Equal(t, 1, usage.EventIndexEntries)
+	assert.Equal(t, 2, usage.StaleIndexEntries)
+	assert.Equal(t, []string{kvShardKey(subKey, staleVersion, 0)}, usage.StaleShardKeys)
+	assert.Contains(t, usage.String(), "Run `/jira kv compact`")
+
+	staleShards, err := p.compactSubscriptions(ji)
+	require.NoError(t, err)
+	assert.Equal(t, 1, staleShards)
+	_, ok := kv[kvShardKey(subKey, staleVersion, 0)]
+	assert.False(t, ok)
+
+	// The subscriptions are larger than the shards
+	shards, ok := parseKVShards(kv[subKey])
+	require.True(t, ok)
+	assert.True(t, shards.Count > 1)
+
+	subs, err := p.getSubscriptions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]StringSet{"channel1": NewStringSet("sub1")}, subs.Channel.IdByChannelId)
+
+	usage, err = p.loadKVUsage(ji)
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage.StaleIndexEntries)
+	assert.Empty(t, usage.StaleShardKeys)
+	assert.Equal(t, shards.Count, usage.SubscriptionsShards)
+	assert.NotContains(t, usage.String(), "Run `/jira kv compact`")
+
+	data, err := json.Marshal(subs)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), usage.SubscriptionsSize)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// kvUsage is the storage used by the plugin, as reported by /jira kv usage.
+type kvUsage struct {
+	Keys int
+	Size int
+
+	SubscriptionsSize   int
+	SubscriptionsShards int
+	Subscriptions       int
+
+	// The entries of the subscription indexes, by channel and by event, and
+	// those referencing removed subscriptions or empty.
+	ChannelIndexEntries int
+	EventIndexEntries   int
+	StaleIndexEntries   int
+
+	StaleShardKeys []string
+}
+
+func (p *Plugin) loadKVUsage(ji Instance) (*kvUsage, error) {
+	usage := &kvUsage{}
+	for i := 0; ; i++ {
+		keys, appErr := p.API.KVList(i, listPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, key := range keys {
+			data, appErr := p.API.KVGet(key)
+			if appErr != nil {
+				return nil, appErr
+			}
+			usage.Keys++
+			usage.Size += len(data)
+		}
+		if len(keys) < listPerPage {
+			break
+		}
+	}
+
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	stored, data, err := p.kvGetSharded(subKey)
+	if err != nil {
+		return nil, err
+	}
+	usage.SubscriptionsSize = len(data)
+	if shards, ok := parseKVShards(stored); ok {
+		usage.SubscriptionsShards = shards.Count
+	}
+	subs, err := SubscriptionsFromJson(data)
+	if err != nil {
+		return nil, err
+	}
+	if subs.Channel != nil {
+		usage.Subscriptions = len(subs.Channel.ById)
+		usage.ChannelIndexEntries, usage.StaleIndexEntries = countIndexEntries(subs.Channel.IdByChannelId, subs.Channel.ById)
+		events, stale := countIndexEntries(subs.Channel.IdByEvent, subs.Channel.ById)
+		usage.EventIndexEntries = events
+		usage.StaleIndexEntries += stale
+	}
+
+	usage.StaleShardKeys, err = p.staleKVShards(subKey)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// countIndexEntries returns the number of entries of a subscription index, and
+// of those that are empty or reference removed subscriptions.
+func countIndexEntries(index map[string]StringSet, byId map[string]ChannelSubscription) (entries, stale int) {
+	for _, ids := range index {
+		if ids.Len() == 0 {
+			stale++
+		}
+		for id := range ids {
+			entries++
+			if _, ok := byId[id]; !ok {
+				stale++
+			}
+		}
+	}
+	return entries, stale
+}
+
+func (usage kvUsage) String() string {
+	out := "###### Jira plugin storage\n"
+	out += fmt.Sprintf("* Keys: %d, %v in total\n", usage.Keys, utils.ByteSize(usage.Size))
+	layout := "in a single key"
+	if usage.SubscriptionsShards > 0 {
+		layout = fmt.Sprintf("in %d shards", usage.SubscriptionsShards)
+	}
+	out += fmt.Sprintf("* Subscriptions: %d, %v %s\n", usage.Subscriptions, utils.ByteSize(usage.SubscriptionsSize), layout)
+	out += fmt.Sprintf("* Subscription indexes: %d entries by channel, %d by event\n", usage.ChannelIndexEntries, usage.EventIndexEntries)
+	out += fmt.Sprintf("* Stale: %d index entries, %d shard keys\n", usage.StaleIndexEntries, len(usage.StaleShardKeys))
+	if usage.StaleIndexEntries > 0 || len(usage.StaleShardKeys) > 0 ||
+		usage.SubscriptionsShards == 0 && usage.SubscriptionsSize > kvShardSize {
+		out += "\nRun `/jira kv compact` to remove the stale entries and keys, and to shard the subscriptions if they are too large for a single key."
+	}
+	return out
+}
+
+// compactSubscriptions rebuilds the indexes of the subscriptions, stores them
+// in shards if they are too large for a single key, and deletes the shards
+// left by interrupted writes.
+func (p *Plugin) compactSubscriptions(ji Instance) (staleShards int, err error) {
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		reindexSubscriptions(subs)
+		return json.Marshal(subs)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	stale, err := p.staleKVShards(subKey)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range stale {
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			return 0, appErr
+		}
+	}
+	return len(stale), nil
+}
+
+func executeKVUsage(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira kv usage` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.help(header)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeKVUsage: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	usage, err := p.loadKVUsage(ji)
+	if err != nil {
+		return p.responsef(header, "Failed to load the storage usage: %v", err)
+	}
+	return p.responsef(header, "%s", usage.String())
+}
+
+func executeKVCompact(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira kv compact` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.help(header)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeKVCompact: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	staleShards, err := p.compactSubscriptions(ji)
+	if err != nil {
+		return p.responsef(header, "Failed to compact the storage: %v", err)
+	}
+	usage, err := p.loadKVUsage(ji)
+	if err != nil {
+		return p.responsef(header, "Compacted the subscriptions, and deleted %d stale shard keys. Failed to load the storage usage: %v", staleShards, err)
+	}
+	return p.responsef(header, "Compacted the subscriptions, and deleted %d stale shard keys.\n\n%s", staleShards, usage.String())
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reMarkdownFence       = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+-]*)\\s*$")
+	reMarkdownHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	reMarkdownBullet      = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	reMarkdownNumbered    = regexp.MustCompile(`^(\s*)\d+[.)]\s+(.*)$`)
+	reMarkdownQuote       = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	reMarkdownRule        = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	reMarkdownTableSep    = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	reMarkdownImage       = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)\)`)
+	reMarkdownLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	reMarkdownBold        = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	reMarkdownItalic      = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	reMarkdownStrike      = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	reMarkdownBoldPending = regexp.MustCompile("\x00([^\x00]*)\x00")
+)
+
+// markdownToWikiMarkup converts the Markdown of a Mattermost message to Jira
+// wiki markup, which the REST API v2 used by the plugin renders on both Jira
+// Server and Jira Cloud. Code blocks, headings, lists, quotes, tables, links
+// and emphasis are converted, anything else is passed through unchanged.
+func markdownToWikiMarkup(text string) string {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if m := reMarkdownFence.FindStringSubmatch(line); m != nil && m[1] == fence && m[2] == "" {
+				out = append(out, "{code}")
+				fence = ""
+				continue
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if m := reMarkdownFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			if m[2] != "" {
+				out = append(out, "{code:"+m[2]+"}")
+			} else {
+				out = append(out, "{code}")
+			}
+			continue
+		}
+
+		if i+1 < len(lines) && strings.Contains(line, "|") && reMarkdownTableSep.MatchString(lines[i+1]) {
+			out = append(out, wikiTableRow(line, "||"))
+			i++
+			for ; i+1 < len(lines) && strings.Contains(lines[i+1], "|"); i++ {
+				out = append(out, wikiTableRow(lines[i+1], "|"))
+			}
+			continue
+		}
+
+		switch m := []string(nil); {
+		case reMarkdownRule.MatchString(line):
+			out = append(out, "----")
+		case matchInto(reMarkdownHeading, line, &m):
+			out = append(out, "h"+strconv.Itoa(len(m[1]))+". "+markdownInline(m[2]))
+		case matchInto(reMarkdownBullet, line, &m):
+			out = append(out, wikiListPrefix(m[1], "*")+" "+markdownInline(m[2]))
+		case matchInto(reMarkdownNumbered, line, &m):
+			out = append(out, wikiListPrefix(m[1], "#")+" "+markdownInline(m[2]))
+		case matchInto(reMarkdownQuote, line, &m):
+			out = append(out, "bq. "+markdownInline(m[1]))
+		default:
+			out = append(out, markdownInline(line))
+		}
+	}
+	if fence != "" {
+		// An unterminated code block runs to the end of the message
+		out = append(out, "{code}")
+	}
+	return strings.Join(out, "\n")
+}
+
+func matchInto(re *regexp.Regexp, s string, m *[]string) bool {
+	*m = re.FindStringSubmatch(s)
+	return *m != nil
+}
+
+// wikiListPrefix returns the list markers for an item indented by indent,
+// nesting one level for every 2 spaces or tab.
+func wikiListPrefix(indent, marker string) string {
+	depth := 1 + len(strings.Replace(indent, "\t", "  ", -1))/2
+	return strings.Repeat(marker, depth)
+}
+
+func wikiTableRow(line, sep string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = markdownInline(strings.TrimSpace(cell))
+	}
+	return sep + strings.Join(cells, sep) + sep
+}
+
+// markdownInline converts the inline Markdown of a line, leaving code spans
+// as monospaced text without converting their content.
+func markdownInline(line string) string {
+	parts := strings.Split(line, "`")
+	if len(parts)%2 == 0 {
+		// An unmatched backtick is literal
+		parts[len(parts)-2] += "`" + parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+	for i, part := range parts {
+		if i%2 == 1 {
+			parts[i] = "{{" + part + "}}"
+			continue
+		}
+		part = reMarkdownImage.ReplaceAllString(part, "!$1!")
+		part = reMarkdownLink.ReplaceAllString(part, "[$1|$2]")
+		// Bold is marked out while italics are converted, as both use "*"
+		part = reMarkdownBold.ReplaceAllString(part, "\x00$1$2\x00")
+		part = reMarkdownItalic.ReplaceAllString(part, "_${1}_")
+		part = reMarkdownBoldPending.ReplaceAllString(part, "*$1*")
+		part = reMarkdownStrike.ReplaceAllString(part, "-$1-")
+		parts[i] = part
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToWikiMarkup(t *testing.T) {
+	for name, tc := range map[string]struct {
+		markdown, expected string
+	}{
+		"plain":         {"Just some text.", "Just some text."},
+		"bold":          {"This is **important** and __so is this__.", "This is *important* and *so is this*."},
+		"italic":        {"This is *emphasized* and _so is this_.", "This is _emphasized_ and _so is this_."},
+		"bold italic":   {"**bold** and *italic*", "*bold* and _italic_"},
+		"strikethrough": {"~~gone~~", "-gone-"},
+		"link":          {"See [the docs](https://example.com/docs).", "See [the docs|https://example.com/docs]."},
+		"image":         {"![screenshot](https://example.com/a.png)", "!https://example.com/a.png!"},
+		"inline code":   {"Run `go test **all**` now", "Run {{go test **all**}} now"},
+		"lone backtick": {"a ` b **c**", "a ` b *c*"},
+		"heading":       {"## Steps to reproduce ##", "h2. Steps to reproduce"},
+		"bullets":       {"- one\n- two\n  - nested\n* three", "* one\n* two\n** nested\n* three"},
+		"numbered":      {"1. first\n2. second\n   1. nested", "# first\n# second\n## nested"},
+		"quote":         {"> quoted **text**", "bq. quoted *text*"},
+		"rule":          {"above\n\n---\n\nbelow", "above\n\n----\n\nbelow"},
+		"code block": {
+			"Before\n```go\nfunc main() {\n\t**x** := `y`\n}\n```\nAfter",
+			"Before\n{code:go}\nfunc main() {\n\t**x** := `y`\n}\n{code}\nAfter",
+		},
+		"unterminated code block": {"```\ncode", "{code}\ncode\n{code}"},
+		"table": {
+			"| Name | Value |\n|------|:-----:|\n| a | **b** |\n| c | d |\nafter",
+			"||Name||Value||\n|a|*b*|\n|c|d|\nafter",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, markdownToWikiMarkup(tc.markdown))
+		})
+	}
+}
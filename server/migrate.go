@@ -0,0 +1,313 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyMigrations = "migrations"
+
+	// The subscriptions were stored under this key, not prefixed with the
+	// Jira instance, by the versions that predate the instance prefixes.
+	keyLegacySubscriptions = JIRA_SUBSCRIPTIONS_KEY
+
+	// A single server of a cluster runs the migrations in this interval.
+	migrationsLockInterval = time.Minute
+)
+
+const (
+	migrationApplied = "applied"
+	migrationPending = "pending"
+	migrationFailed  = "failed"
+)
+
+// errMigrationPending is returned by the migrations that can't run yet, e.g.
+// before a Jira instance is installed. They are tried again the next time the
+// plugin is activated.
+var errMigrationPending = errors.New("no Jira instance installed")
+
+type migration struct {
+	id          string
+	description string
+	migrate     func(p *Plugin) (string, error)
+}
+
+// migrations convert the KV layouts of the older versions of the plugin to
+// the current ones, in order. Each migration is applied once, but must be
+// idempotent, since it runs again if the server stops before its completion
+// is recorded.
+var migrations = []migration{
+	{"subscriptions_instance_key", "Subscriptions stored without the Jira instance prefix", migrateLegacySubscriptionsKey},
+	{"subscriptions_indexes", "Subscription indexes by channel and event", migrateSubscriptionIndexes},
+	{"subscriptions_names", "Subscriptions created without a name", migrateSubscriptionNames},
+}
+
+type migrationRecord struct {
+	Status        string `json:"status"`
+	Result        string `json:"result,omitempty"`
+	PluginVersion string `json:"plugin_version"`
+	At            int64  `json:"at"`
+}
+
+type migrationStatus struct {
+	Migrations map[string]migrationRecord `json:"migrations"`
+}
+
+func (p *Plugin) loadMigrationStatus() (*migrationStatus, error) {
+	status := &migrationStatus{}
+	data, appErr := p.API.KVGet(keyMigrations)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) != 0 {
+		err := json.Unmarshal(data, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if status.Migrations == nil {
+		status.Migrations = map[string]migrationRecord{}
+	}
+	return status, nil
+}
+
+func (p *Plugin) storeMigrationStatus(status *migrationStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSet(keyMigrations, data)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// runMigrations applies the migrations that were not applied yet, stopping at
+// the first failure since the later migrations may depend on it. In a
+// cluster, only one server runs them.
+func (p *Plugin) runMigrations() {
+	if !p.claimJob("migrations", migrationsLockInterval) {
+		return
+	}
+
+	status, err := p.loadMigrationStatus()
+	if err != nil {
+		p.errorf("runMigrations: failed to load the migration status: %v", err)
+		return
+	}
+
+	for _, m := range migrations {
+		if status.Migrations[m.id].Status == migrationApplied {
+			continue
+		}
+
+		record := migrationRecord{
+			Status:        migrationApplied,
+			PluginVersion: manifest.Version,
+			At:            time.Now().Unix(),
+		}
+		record.Result, err = m.migrate(p)
+		switch {
+		case err == errMigrationPending:
+			record.Status, record.Result = migrationPending, err.Error()
+		case err != nil:
+			record.Status, record.Result = migrationFailed, err.Error()
+			p.errorf("runMigrations: migration %s failed: %v", m.id, err)
+		default:
+			p.infof("runMigrations: applied migration %s: %s", m.id, record.Result)
+		}
+		status.Migrations[m.id] = record
+
+		storeErr := p.storeMigrationStatus(status)
+		if storeErr != nil {
+			p.errorf("runMigrations: failed to store the migration status: %v", storeErr)
+			return
+		}
+		if record.Status == migrationFailed {
+			return
+		}
+	}
+}
+
+// modifyCurrentSubscriptions atomically modifies the subscriptions of the
+// current Jira instance, and returns the result of modify.
+func (p *Plugin) modifyCurrentSubscriptions(modify func(subs *Subscriptions) (string, error)) (string, error) {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return "", errMigrationPending
+	}
+
+	result := ""
+	err = p.atomicModifySharded(keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY), func(initialBytes []byte) ([]byte, error) {
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		result, err = modify(subs)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(subs)
+	})
+	return result, err
+}
+
+// reindexSubscriptions rebuilds the indexes of the subscriptions, which the
+// older versions didn't always store.
+func reindexSubscriptions(subs *Subscriptions) {
+	if subs.Channel == nil {
+		subs.Channel = NewChannelSubscriptions()
+	}
+	byId := subs.Channel.ById
+	subs.Channel.ById = map[string]ChannelSubscription{}
+	subs.Channel.IdByChannelId = map[string]StringSet{}
+	subs.Channel.IdByEvent = map[string]StringSet{}
+	for id := range byId {
+		sub := byId[id]
+		if sub.Id == "" {
+			sub.Id = id
+		}
+		subs.Channel.add(&sub)
+	}
+}
+
+// migrateLegacySubscriptionsKey merges the subscriptions stored without the
+// Jira instance prefix into those of the current instance, then removes the
+// legacy key.
+func migrateLegacySubscriptionsKey(p *Plugin) (string, error) {
+	data, appErr := p.API.KVGet(keyLegacySubscriptions)
+	if appErr != nil {
+		return "", appErr
+	}
+	if len(data) == 0 {
+		return "nothing to migrate", nil
+	}
+	legacy, err := SubscriptionsFromJson(data)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to read the legacy subscriptions")
+	}
+	reindexSubscriptions(legacy)
+
+	result, err := p.modifyCurrentSubscriptions(func(subs *Subscriptions) (string, error) {
+		reindexSubscriptions(subs)
+		moved := 0
+		for id := range legacy.Channel.ById {
+			// Moved by an earlier run that didn't delete the legacy key
+			if _, ok := subs.Channel.ById[id]; ok {
+				continue
+			}
+			sub := legacy.Channel.ById[id]
+			subs.Channel.add(&sub)
+			moved++
+		}
+		return fmt.Sprintf("moved %v of %v subscriptions", moved, len(legacy.Channel.ById)), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	appErr = p.API.KVDelete(keyLegacySubscriptions)
+	if appErr != nil {
+		return "", appErr
+	}
+	return result, nil
+}
+
+func migrateSubscriptionIndexes(p *Plugin) (string, error) {
+	return p.modifyCurrentSubscriptions(func(subs *Subscriptions) (string, error) {
+		reindexSubscriptions(subs)
+		return fmt.Sprintf("indexed %v subscriptions", len(subs.Channel.ById)), nil
+	})
+}
+
+// migrateSubscriptionNames names the subscriptions created before the names
+// were required, since the commands find the subscriptions by name.
+func migrateSubscriptionNames(p *Plugin) (string, error) {
+	return p.modifyCurrentSubscriptions(func(subs *Subscriptions) (string, error) {
+		reindexSubscriptions(subs)
+
+		ids := []string{}
+		for id, sub := range subs.Channel.ById {
+			if sub.Name == "" {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			sub := subs.Channel.ById[id]
+			taken := NewStringSet()
+			for _, channelSubId := range subs.Channel.IdByChannelId[sub.ChannelId].Elems() {
+				taken[subs.Channel.ById[channelSubId].Name] = true
+			}
+			for i := 1; sub.Name == ""; i++ {
+				name := fmt.Sprintf("Subscription %v", i)
+				if !taken.ContainsAny(name) {
+					sub.Name = name
+				}
+			}
+			subs.Channel.ById[id] = sub
+		}
+		return fmt.Sprintf("named %v subscriptions", len(ids)), nil
+	})
+}
+
+func executeMigrateStatus(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira migrate status` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.help(header)
+	}
+
+	status, err := p.loadMigrationStatus()
+	if err != nil {
+		return p.responsef(header, "Failed to load the migration status: %v", err)
+	}
+	return p.responsef(header, "%s", formatMigrationStatus(status))
+}
+
+func formatMigrationStatus(status *migrationStatus) string {
+	out := "###### Jira plugin migrations\n"
+	incomplete := 0
+	for _, m := range migrations {
+		record, ok := status.Migrations[m.id]
+		switch {
+		case !ok:
+			incomplete++
+			out += fmt.Sprintf("* :hourglass: %s: not run yet\n", m.description)
+		case record.Status == migrationApplied:
+			out += fmt.Sprintf("* :white_check_mark: %s: %s, with version %s on %s\n",
+				m.description, record.Result, record.PluginVersion, time.Unix(record.At, 0).UTC().Format(time.RFC1123))
+		case record.Status == migrationPending:
+			incomplete++
+			out += fmt.Sprintf("* :hourglass: %s: %s\n", m.description, record.Result)
+		default:
+			incomplete++
+			out += fmt.Sprintf("* :x: %s: %s, with version %s on %s\n",
+				m.description, record.Result, record.PluginVersion, time.Unix(record.At, 0).UTC().Format(time.RFC1123))
+		}
+	}
+	if incomplete == 0 {
+		out += "\nAll migrations were applied."
+	} else {
+		out += fmt.Sprintf("\n%v of %v migrations were not applied, they run again when the plugin is activated.", incomplete, len(migrations))
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMigrations(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVSetWithOptions", "job_lock_migrations", mock.Anything, mock.Anything).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	api.On("LogInfo", mock.AnythingOfType("string")).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStoreNoInstance{p}
+
+	// Stored without the instance prefix nor indexes, some without a name
+	kv[keyLegacySubscriptions] = []byte(`{"Channel": {"by_id": {
+		"sub1": {"id": "sub1", "channel_id": "channel1", "filters": {"events": ["event_created"], "projects": ["TES"]}},
+		"sub2": {"id": "sub2", "channel_id": "channel1", "name": "Subscription 1", "filters": {"events": ["event_created"], "projects": ["TES"]}},
+		"sub3": {"id": "sub3", "channel_id": "channel2", "filters": {"events": ["event_deleted"], "projects": ["TES"]}}
+	}}}`)
+
+	p.runMigrations()
+	status, err := p.loadMigrationStatus()
+	require.NoError(t, err)
+	for _, m := range migrations {
+		assert.Equal(t, migrationPending, status.Migrations[m.id].Status, "there is no Jira instance yet")
+	}
+	assert.NotNil(t, kv[keyLegacySubscriptions])
+
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.runMigrations()
+	status, err = p.loadMigrationStatus()
+	require.NoError(t, err)
+	for _, m := range migrations {
+		assert.Equal(t, migrationApplied, status.Migrations[m.id].Status)
+	}
+	assert.Equal(t, "moved 3 of 3 subscriptions", status.Migrations["subscriptions_instance_key"].Result)
+	assert.Equal(t, "named 2 subscriptions", status.Migrations["subscriptions_names"].Result)
+	assert.Nil(t, kv[keyLegacySubscriptions])
+
+	subs, err := SubscriptionsFromJson(kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)])
+	require.NoError(t, err)
+	require.Len(t, subs.Channel.ById, 3)
+	assert.Equal(t, "Subscription 2", subs.Channel.ById["sub1"].Name, "the names are unique in the channel")
+	assert.Equal(t, "Subscription 1", subs.Channel.ById["sub2"].Name)
+	assert.Equal(t, "Subscription 1", subs.Channel.ById["sub3"].Name)
+	assert.ElementsMatch(t, []string{"sub1", "sub2"}, subs.Channel.IdByChannelId["channel1"].Elems())
+	assert.ElementsMatch(t, []string{"sub3"}, subs.Channel.IdByEvent[eventDeleted].Elems())
+
+	// The applied migrations don't run again
+	before, _ := json.Marshal(status)
+	p.runMigrations()
+	status, err = p.loadMigrationStatus()
+	require.NoError(t, err)
+	after, _ := json.Marshal(status)
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestMigrateLegacySubscriptionsKeyIdempotent(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, (*model.AppError)(nil))
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	kv[keyLegacySubscriptions] = []byte(`{"Channel": {"by_id": {"sub1": {"id": "sub1", "channel_id": "channel1", "name": "bugs"}}}}`)
+
+	// The legacy key is not deleted, e.g. the server stopped, so the
+	// migration runs again
+	result, err := migrateLegacySubscriptionsKey(p)
+	require.NoError(t, err)
+	assert.Equal(t, "moved 1 of 1 subscriptions", result)
+	result, err = migrateLegacySubscriptionsKey(p)
+	require.NoError(t, err)
+	assert.Equal(t, "moved 0 of 1 subscriptions", result)
+
+	subs, err := SubscriptionsFromJson(kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)])
+	require.NoError(t, err)
+	assert.Len(t, subs.Channel.ById, 1)
+}
+
+func TestFormatMigrationStatus(t *testing.T) {
+	status := &migrationStatus{Migrations: map[string]migrationRecord{
+		"subscriptions_instance_key": {Status: migrationApplied, Result: "nothing to migrate", PluginVersion: "3.0.0", At: 1580000000},
+		"subscriptions_indexes":      {Status: migrationFailed, Result: "modification error", PluginVersion: "3.0.0", At: 1580000000},
+	}}
+	out := formatMigrationStatus(status)
+	assert.Contains(t, out, ":white_check_mark: Subscriptions stored without the Jira instance prefix: nothing to migrate, with version 3.0.0 on Sun, 26 Jan 2020 00:53:20 UTC")
+	assert.Contains(t, out, ":x: Subscription indexes by channel and event: modification error")
+	assert.Contains(t, out, ":hourglass: Subscriptions created without a name: not run yet")
+	assert.Contains(t, out, "2 of 3 migrations were not applied")
+}
@@ -9,11 +9,14 @@ import (
 	"io/ioutil"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
+	"github.com/mattermost/go-i18n/i18n/bundle"
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -61,17 +64,152 @@ type externalConfig struct {
 	// number, optionally followed by one of [b, kb, mb, gb, tb]
 	MaxAttachmentSize string
 
+	// Number of characters of issue descriptions and comments posted to
+	// channels, beyond which they are truncated with a link to the issue.
+	MaxTextLength string
+
 	// Disable statistics gathering
 	DisableStats bool `json:"disable_stats"`
 
+	// Allow the Mattermost guest accounts to manage subscriptions and to
+	// change Jira issues, like the other users.
+	AllowGuestsToEditJira bool
+
+	// Comma separated list of Mattermost usernames and roles allowed to run
+	// the admin commands of the plugin, in addition to the system admins.
+	DelegatedAdmins string
+
 	// Additional Help Text to be shown in the output of '/jira help' command
 	JiraAdminAdditionalHelpText string
+
+	// Comma separated list of Jira states offered in the Quick triage select
+	// of the issue posts. Empty disables.
+	QuickTransitions string
+
+	// Comma separated list of Jira labels offered in the Quick triage select
+	// of the issue posts. Empty disables.
+	QuickLabels string
+
+	// Number of days after an issue is resolved before its single-issue
+	// subscriptions are removed. 0 keeps them indefinitely.
+	IssueSubscriptionRetentionDays string
+
+	// Number of changes a user can make in Jira through the plugin, like
+	// comments, transitions and new issues, per minute. 0 is no limit.
+	MaxJiraWritesPerMinute string
+
+	// Number of hours the accepted webhook payloads are kept, to be replayed
+	// with `/jira webhook replay`. 0 disables the replay window.
+	WebhookReplayHours string
+
+	// Maximum size of the webhook payloads kept for the replay window, can
+	// be a number, optionally followed by one of [b, kb, mb, gb, tb]
+	WebhookReplayMaxSize string
+
+	// Number of minutes after which the webhook events sent by Jira are
+	// dropped when they are received, like the retries Jira sends after an
+	// outage. 0 never drops them.
+	WebhookMaxEventAgeMinutes string
+
+	// Comma separated list of the Jira users, like automation or sync tools,
+	// whose webhook events are not posted to subscribed channels.
+	IgnoredActors string
+
+	// Comma separated list of variant=event pairs, renaming the events sent
+	// by Jira automation or add-ons to events the plugin handles.
+	EventAliases string
+
+	// Comma separated list of the names or IDs of the fields, like Rank,
+	// whose changes are not posted. Updates of only these fields are ignored.
+	IgnoredFields string
+
+	// Comma separated list of project=restriction pairs, limiting the
+	// channels subscribed to a project to public or private channels, or to
+	// the channels of a team.
+	ProjectChannelRestrictions string
+
+	// Comma separated list of team=URL pairs, assigning teams to the Jira
+	// instance they use. The teams assigned to another instance than the
+	// current one can't use Jira.
+	TeamInstances string
+
+	// Comma separated list of team=projects pairs, restricting the
+	// subscriptions of the channels of a team to the projects listed,
+	// separated by spaces.
+	TeamProjects string
+
+	// Comma separated list of alias=URL pairs naming the Jira instances, for
+	// the issue keys prefixed with an alias, like primary:PROJ-1.
+	InstanceAliases string
+
+	// Key from which the key encrypting the stored Jira credentials is
+	// derived. Empty stores them as plaintext.
+	EncryptionKey string
+
+	// Comma separated list of the keys EncryptionKey had before it was
+	// rotated, to read the credentials not yet encrypted with the new key.
+	PreviousEncryptionKeys string
+
+	// ID of the channel where the plugin posts operational alerts, like
+	// webhook authentication failures. Empty disables the alerts.
+	AdminAlertsChannelId string
+
+	// ID of the channel where the webhook events that can't be posted to
+	// their subscribed channel, like a read-only or archived channel, are
+	// posted instead. Empty drops them.
+	FallbackChannelId string
+
+	// Add the pull requests, branches and commits linked to an issue to its
+	// attachment in '/jira view'.
+	ShowDevelopmentInfo bool
+
+	// Post the edits of a Jira comment to the threads of the posts of the
+	// comment, in the channels subscribed to comment edits.
+	SyncCommentActivity bool
+
+	// Add the votes and watchers counts of the issue to the attachments of
+	// issue events.
+	ShowVotesAndWatchers bool
+
+	// Show emoji before the issue types, statuses and priorities in the
+	// notifications and the issue attachments.
+	ShowIssueEmoji bool
+
+	// Comma separated list of kind:name=emoji overrides of the default
+	// issue emoji, like type:Bug=beetle. The emoji none removes a default.
+	IssueEmoji string
+
+	// Mark the posts of a new issue as deleted when the issue is deleted in
+	// Jira.
+	MarkDeletedIssuePosts bool
+
+	// Load the avatars of the Jira instance through the plugin, and show
+	// them as the author icons of the attachments.
+	ProxyJiraAvatars bool
+
+	// URL scheme of the links opening the issues in the Jira mobile and
+	// desktop apps, like jira. Empty doesn't link to the apps.
+	JiraAppLinkScheme string
+
+	// Comma separated list of the hosts the subscriptions can forward their
+	// events to. Empty disables the forwarding.
+	EventForwardingHosts string
+
+	// Locale of the plugin's posts and messages when neither the user nor
+	// the channel selects one. Empty uses the server's default locale.
+	DefaultLocale string
 }
 
 const currentInstanceTTL = 1 * time.Second
 
 const defaultMaxAttachmentSize = utils.ByteSize(10 * 1024 * 1024) // 10Mb
 
+const defaultIssueSubscriptionRetention = 7 * 24 * time.Hour
+
+const defaultMaxTextLength = 3000
+
+const defaultWebhookReplayMaxSize = utils.ByteSize(20 * 1024 * 1024) // 20Mb
+
 type config struct {
 	// externalConfig caches values from the plugin's settings in the server's config.json
 	externalConfig
@@ -87,6 +225,57 @@ type config struct {
 	// Maximum attachment size allowed to be uploaded to Jira
 	maxAttachmentSize utils.ByteSize
 
+	// Parsed MaxTextLength, 0 if not set
+	maxTextLength int
+
+	// Parsed QuickTransitions
+	quickTransitions []string
+
+	// Parsed QuickLabels
+	quickLabels []string
+
+	// Parsed ProjectChannelRestrictions, uppercase project key to restriction
+	projectChannelRestrictions map[string]string
+
+	// Parsed TeamInstances and TeamProjects, by lowercase team name
+	teamInstances map[string]string
+	teamProjects  map[string]StringSet
+
+	// Parsed InstanceAliases, instance URL by lowercase alias
+	instanceAliases map[string]string
+
+	// Parsed DelegatedAdmins
+	delegatedAdmins []string
+
+	// Parsed IgnoredActors
+	ignoredActors []string
+
+	// Parsed EventForwardingHosts, lowercase
+	eventForwardingHosts StringSet
+
+	// Parsed EventAliases, IgnoredFields and IssueEmoji
+	webhookParseOptions webhookParseOptions
+
+	// Encrypts the stored Jira credentials with the EncryptionKey settings
+	credentials credentialCipher
+
+	// How long single-issue subscriptions are kept after the issue is resolved
+	issueSubscriptionRetention time.Duration
+
+	// Parsed MaxJiraWritesPerMinute, 0 if there is no limit
+	maxJiraWritesPerMinute int
+
+	// How long the accepted webhook payloads are kept for replays, 0 if
+	// they are not, and the maximum size they take
+	webhookReplayWindow  time.Duration
+	webhookReplayMaxSize utils.ByteSize
+
+	// Parsed WebhookMaxEventAgeMinutes, 0 if the events are never dropped
+	webhookMaxEventAge time.Duration
+
+	// The problems of the settings that were applied
+	problems []configProblem
+
 	stats             *expvar.Stats
 	statsStopAutosave chan bool
 }
@@ -94,9 +283,11 @@ type config struct {
 type Plugin struct {
 	plugin.MattermostPlugin
 
-	// configuration and a muttex to control concurrent access
-	conf     config
-	confLock sync.RWMutex
+	// configuration, a *config replaced as a whole on updates so that the
+	// readers get consistent snapshots without locking, and a mutex
+	// serializing the updates
+	conf     atomic.Value
+	confLock sync.Mutex
 
 	currentInstanceStore CurrentInstanceStore
 	instanceStore        InstanceStore
@@ -113,22 +304,68 @@ type Plugin struct {
 	// templates are loaded on startup
 	templates map[string]*template.Template
 
-	// channel to distribute work to the webhook processors
-	webhookQueue chan []byte
+	// translations of the user-facing strings, loaded on startup
+	translations *bundle.Bundle
+
+	// filter options fetched from Jira for the subscription modal
+	subscriptionOptions subscriptionOptionsCache
+
+	// issue previews for the keys typed in the message box
+	issuePreviews issuePreviewCache
+
+	// recent results of the JQL filters of the subscriptions
+	jqlMatches jqlMatchCache
+
+	// when webhook events last refreshed the channels' open issues indicators
+	channelStatusRefreshes channelStatusRefreshes
+
+	// when operational alerts were last posted to the admin channel
+	adminAlerts adminAlerts
+
+	// the Jira instances that failed their connectivity checks
+	instanceHealth instanceHealth
+
+	// the channels webhook events can't be posted to, and when their
+	// subscription creators were last warned
+	blockedChannels blockedChannels
+
+	// bounds the concurrent requests to each Jira instance
+	jiraRequestLimiters jiraRequestLimiters
+
+	// the last webhook events processed, to dry-run the subscriptions
+	recentEvents recentEvents
+
+	// when the users last changed Jira, to limit their changes
+	writeLimits userWriteLimits
+
+	// channels to distribute work to the webhook processors, by issue
+	webhookQueues webhookQueues
+
+	// channel to distribute the forwarded events to the forward workers
+	forwardQueue chan forwardRequest
 }
 
+// getConfig returns a snapshot of the configuration. The snapshot doesn't
+// change when the configuration is updated, use it for all the settings of a
+// request or an event.
 func (p *Plugin) getConfig() config {
-	p.confLock.RLock()
-	defer p.confLock.RUnlock()
-	return p.conf
+	if conf, ok := p.conf.Load().(*config); ok {
+		return *conf
+	}
+	return config{}
 }
 
+// updateConfig applies f to a copy of the configuration, and swaps it in.
+// f must not modify the maps and slices of the configuration in place, as
+// the previous snapshots share them.
 func (p *Plugin) updateConfig(f func(conf *config)) config {
 	p.confLock.Lock()
 	defer p.confLock.Unlock()
 
-	f(&p.conf)
-	return p.conf
+	conf := p.getConfig()
+	f(&conf)
+	p.conf.Store(&conf)
+	return conf
 }
 
 // OnConfigurationChange is invoked when configuration changes may have been made.
@@ -149,10 +386,105 @@ func (p *Plugin) OnConfigurationChange() error {
 		}
 	}
 
-	p.updateConfig(func(conf *config) {
+	ec.MaxTextLength = strings.TrimSpace(ec.MaxTextLength)
+	maxTextLength := 0
+	if len(ec.MaxTextLength) > 0 {
+		maxTextLength, err = strconv.Atoi(ec.MaxTextLength)
+		if err != nil || maxTextLength <= 0 {
+			return errors.Errorf("failed to load plugin configuration: invalid MaxTextLength %q", ec.MaxTextLength)
+		}
+	}
+
+	projectChannelRestrictions := map[string]string{}
+	for key, restriction := range utils.ParseKeyValueList(ec.ProjectChannelRestrictions) {
+		projectChannelRestrictions[strings.ToUpper(key)] = restriction
+	}
+	webhookParseOptions := webhookParseOptions{
+		eventAliases:         utils.ParseKeyValueList(ec.EventAliases),
+		ignoredFields:        NewStringSet(),
+		showVotesAndWatchers: ec.ShowVotesAndWatchers,
+		issueEmoji:           parseIssueEmoji(ec.ShowIssueEmoji, ec.IssueEmoji),
+	}
+	for _, field := range utils.ParseList(ec.IgnoredFields) {
+		webhookParseOptions.ignoredFields = webhookParseOptions.ignoredFields.Add(strings.ToLower(field))
+	}
+	delegatedAdmins := utils.ParseList(ec.DelegatedAdmins)
+	ignoredActors := utils.ParseList(ec.IgnoredActors)
+	credentials := newCredentialCipher(ec.EncryptionKey, strings.Split(ec.PreviousEncryptionKeys, ","))
+
+	ec.IssueSubscriptionRetentionDays = strings.TrimSpace(ec.IssueSubscriptionRetentionDays)
+	issueSubscriptionRetention := defaultIssueSubscriptionRetention
+	if len(ec.IssueSubscriptionRetentionDays) > 0 {
+		days, atoiErr := strconv.Atoi(ec.IssueSubscriptionRetentionDays)
+		if atoiErr != nil || days < 0 {
+			return errors.Errorf("failed to load plugin configuration: invalid IssueSubscriptionRetentionDays %q", ec.IssueSubscriptionRetentionDays)
+		}
+		issueSubscriptionRetention = time.Duration(days) * 24 * time.Hour
+	}
+
+	ec.MaxJiraWritesPerMinute = strings.TrimSpace(ec.MaxJiraWritesPerMinute)
+	maxJiraWritesPerMinute := defaultMaxJiraWritesPerMinute
+	if len(ec.MaxJiraWritesPerMinute) > 0 {
+		maxJiraWritesPerMinute, err = strconv.Atoi(ec.MaxJiraWritesPerMinute)
+		if err != nil || maxJiraWritesPerMinute < 0 {
+			return errors.Errorf("failed to load plugin configuration: invalid MaxJiraWritesPerMinute %q", ec.MaxJiraWritesPerMinute)
+		}
+	}
+
+	ec.WebhookReplayHours = strings.TrimSpace(ec.WebhookReplayHours)
+	webhookReplayWindow := time.Duration(0)
+	if len(ec.WebhookReplayHours) > 0 {
+		hours, atoiErr := strconv.Atoi(ec.WebhookReplayHours)
+		if atoiErr != nil || hours < 0 {
+			return errors.Errorf("failed to load plugin configuration: invalid WebhookReplayHours %q", ec.WebhookReplayHours)
+		}
+		webhookReplayWindow = time.Duration(hours) * time.Hour
+	}
+	ec.WebhookMaxEventAgeMinutes = strings.TrimSpace(ec.WebhookMaxEventAgeMinutes)
+	webhookMaxEventAge := time.Duration(0)
+	if len(ec.WebhookMaxEventAgeMinutes) > 0 {
+		minutes, atoiErr := strconv.Atoi(ec.WebhookMaxEventAgeMinutes)
+		if atoiErr != nil || minutes < 0 {
+			return errors.Errorf("failed to load plugin configuration: invalid WebhookMaxEventAgeMinutes %q", ec.WebhookMaxEventAgeMinutes)
+		}
+		webhookMaxEventAge = time.Duration(minutes) * time.Minute
+	}
+	ec.WebhookReplayMaxSize = strings.TrimSpace(ec.WebhookReplayMaxSize)
+	webhookReplayMaxSize := defaultWebhookReplayMaxSize
+	if len(ec.WebhookReplayMaxSize) > 0 {
+		webhookReplayMaxSize, err = utils.ParseByteSize(ec.WebhookReplayMaxSize)
+		if err != nil {
+			return errors.WithMessage(err, "failed to load plugin configuration")
+		}
+	}
+
+	problems := p.validateConfig(ec)
+
+	previous := p.getConfig()
+	conf := p.updateConfig(func(conf *config) {
 		conf.externalConfig = ec
 		conf.maxAttachmentSize = maxAttachmentSize
+		conf.maxTextLength = maxTextLength
+		conf.quickTransitions = utils.ParseList(ec.QuickTransitions)
+		conf.quickLabels = utils.ParseList(ec.QuickLabels)
+		conf.projectChannelRestrictions = projectChannelRestrictions
+		conf.teamInstances = parseTeamInstances(ec.TeamInstances)
+		conf.teamProjects = parseTeamProjects(ec.TeamProjects)
+		conf.instanceAliases = parseInstanceAliases(ec.InstanceAliases)
+		conf.webhookParseOptions = webhookParseOptions
+		conf.delegatedAdmins = delegatedAdmins
+		conf.ignoredActors = ignoredActors
+		conf.eventForwardingHosts = NewStringSet(utils.ParseList(strings.ToLower(ec.EventForwardingHosts))...)
+		conf.credentials = credentials
+		conf.issueSubscriptionRetention = issueSubscriptionRetention
+		conf.maxJiraWritesPerMinute = maxJiraWritesPerMinute
+		conf.webhookReplayWindow = webhookReplayWindow
+		conf.webhookReplayMaxSize = webhookReplayMaxSize
+		conf.webhookMaxEventAge = webhookMaxEventAge
+		conf.problems = problems
 	})
+	p.applyConfigChanges(previous, conf)
+	p.reportConfigProblems(problems)
 	return nil
 }
 
@@ -166,9 +498,10 @@ func (p *Plugin) OnActivate() error {
 		return errors.Wrap(err, "failed to ensure bot account")
 	}
 
-	p.updateConfig(func(conf *config) {
+	conf := p.updateConfig(func(conf *config) {
 		conf.botUserID = botUserID
 	})
+	p.reportConfigProblems(conf.problems)
 
 	bundlePath, err := p.API.GetBundlePath()
 	if err != nil {
@@ -191,27 +524,49 @@ func (p *Plugin) OnActivate() error {
 	p.secretsStore = store
 	p.otsStore = store
 
+	p.runMigrations()
+
 	templates, err := p.loadTemplates(filepath.Join(bundlePath, "assets", "templates"))
 	if err != nil {
 		return errors.WithMessage(err, "OnActivate: failed to load templates")
 	}
 	p.templates = templates
 
+	translations, err := loadTranslations(filepath.Join(bundlePath, "assets", "i18n"))
+	if err != nil {
+		return errors.WithMessage(err, "OnActivate: failed to load translations")
+	}
+	p.translations = translations
+
 	err = p.API.RegisterCommand(getCommand())
 	if err != nil {
 		return errors.WithMessage(err, "OnActivate: failed to register command")
 	}
 
-	// Create our queue of webhook events waiting to be processed.
-	p.webhookQueue = make(chan []byte, WebhookBufferSize)
+	// Create our queues of webhook events waiting to be processed, one per
+	// worker, and spin up our webhook workers.
+	p.webhookQueues = newWebhookQueues(WebhookMaxProcsPerServer, WebhookBufferSize)
+	for i, queue := range p.webhookQueues {
+		go webhookWorker{i, p, queue}.work()
+	}
 
-	// Spin up our webhook workers.
-	for i := 0; i < WebhookMaxProcsPerServer; i++ {
-		go webhookWorker{i, p, p.webhookQueue}.work()
+	p.forwardQueue = make(chan forwardRequest, forwardBufferSize)
+	for i := 0; i < forwardMaxProcsPerServer; i++ {
+		go p.forwardWorker(p.forwardQueue)
 	}
 
 	p.workflowTriggerStore = NewTriggerStore()
 
+	p.startPeriodicJob("filter_subscriptions", filterSubscriptionPollInterval, p.pollFilterSubscriptions)
+	p.startPeriodicJob("issue_subscriptions_cleanup", issueSubscriptionCleanupInterval, p.cleanupIssueSubscriptions)
+	p.startPeriodicJob("channel_status", channelStatusRefreshInterval, p.refreshAllChannelStatuses)
+	p.startPeriodicJob("updates_digest", updatesDigestInterval, p.postUpdatesDigests)
+	p.startPeriodicJob("todo_reminders", todoReminderPollInterval, p.postTodoReminders)
+	p.startPeriodicJob("channel_reports", channelReportPollInterval, p.postDueChannelReports)
+	p.startPeriodicJob("discussed_issues", channelReportPollInterval, p.postDueDiscussedIssues)
+	p.startPeriodicJob("archived_channels", archivedChannelsCheckInterval, p.syncArchivedChannelSubscriptions)
+	p.startLocalPeriodicJob(instanceHealthCheckInterval, p.checkInstancesHealth)
+
 	go p.initStats()
 	go func() {
 		time.Sleep(time.Second * 10)
@@ -222,6 +577,10 @@ func (p *Plugin) OnActivate() error {
 			return
 		}
 
+		if err := p.addInstanceAliasAutolinks(); err != nil {
+			p.API.LogWarn("could not install autolinks for the instance aliases", "err", err)
+		}
+
 		for url := range instances {
 			instance, err := p.instanceStore.LoadJIRAInstance(url)
 			if err != nil {
@@ -265,18 +624,26 @@ func (p *Plugin) AddAutolinksForCloudInstance(jci *jiraCloudInstance) error {
 	return nil
 }
 
-func (p *Plugin) AddAutolinks(key, baseURL string) error {
+// AddAutolinks adds the autolinks of a project key, in all channels, or in the
+// team/channel scopes if any.
+func (p *Plugin) AddAutolinks(key, baseURL string, scope ...string) error {
 	baseURL = strings.TrimRight(baseURL, "/")
+	nameSuffix := ""
+	if len(scope) != 0 {
+		nameSuffix = " in " + strings.Join(scope, ",")
+	}
 	installList := []autolink.Autolink{
 		{
-			Name:     key + " key to link for " + baseURL,
+			Name:     key + " key to link for " + baseURL + nameSuffix,
 			Pattern:  `(` + key + `)(-)(?P<jira_id>\d+)`,
 			Template: `[` + key + `-${jira_id}](` + baseURL + `/browse/` + key + `-${jira_id})`,
+			Scope:    scope,
 		},
 		{
-			Name:     key + " link to key for " + baseURL,
+			Name:     key + " link to key for " + baseURL + nameSuffix,
 			Pattern:  `(` + strings.ReplaceAll(baseURL, ".", `\.`) + `/browse/)(` + key + `)(-)(?P<jira_id>\d+)`,
 			Template: `[` + key + `-${jira_id}](` + baseURL + `/browse/` + key + `-${jira_id})`,
+			Scope:    scope,
 		},
 	}
 
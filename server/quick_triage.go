@@ -0,0 +1,141 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// The values of the options of the Quick triage select are the kind of the
+// change, followed by its target.
+const (
+	quickTriageTransition = "transition:"
+	quickTriageLabel      = "label:"
+)
+
+// quickTriageAction returns the select of an issue post applying the changes
+// of the QuickTransitions and QuickLabels settings to the issue, or nil if
+// there are none.
+func (p *Plugin) quickTriageAction(issueKey string, t localizer) *model.PostAction {
+	conf := p.getConfig()
+	if len(conf.quickTransitions) == 0 && len(conf.quickLabels) == 0 {
+		return nil
+	}
+	action := &model.PostAction{
+		Id:   "quicktriage",
+		Name: t(msgActionQuickTriage),
+		Type: model.POST_ACTION_TYPE_SELECT,
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPIQuickTriageAction,
+			Context: map[string]interface{}{
+				"issue_key": issueKey,
+			},
+		},
+	}
+	for _, state := range conf.quickTransitions {
+		action.Options = append(action.Options, &model.PostActionOptions{
+			Text:  t(msgActionQuickTransition, state),
+			Value: quickTriageTransition + state,
+		})
+	}
+	for _, label := range conf.quickLabels {
+		action.Options = append(action.Options, &model.PostActionOptions{
+			Text:  t(msgActionQuickLabel, label),
+			Value: quickTriageLabel + label,
+		})
+	}
+	return action
+}
+
+// httpAPIQuickTriageAction handles the Quick triage select of the issue
+// posts, responding to the user with the outcome.
+func httpAPIQuickTriageAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	issueKey, _ := request.Context["issue_key"].(string)
+	selected, _ := request.Context["selected_option"].(string)
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("missing issue key")
+	}
+
+	if err := ji.GetPlugin().requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	message, err := ji.GetPlugin().applyQuickTriage(mattermostUserId, issueKey, selected)
+	if err != nil {
+		message = err.Error()
+	}
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{EphemeralText: message})
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// applyQuickTriage applies the selected option of the Quick triage select to
+// the issue, on behalf of the user. Only the options of the current settings
+// are applied, the select of an older post may offer removed ones.
+func (p *Plugin) applyQuickTriage(mattermostUserId, issueKey, selected string) (string, error) {
+	conf := p.getConfig()
+	switch {
+	case strings.HasPrefix(selected, quickTriageTransition):
+		state := strings.TrimPrefix(selected, quickTriageTransition)
+		if !NewStringSet(conf.quickTransitions...).ContainsAny(state) {
+			return "", errors.Errorf("Moving issues to %q is not offered anymore.", state)
+		}
+		return p.transitionJiraIssue(mattermostUserId, issueKey, state)
+
+	case strings.HasPrefix(selected, quickTriageLabel):
+		label := strings.TrimPrefix(selected, quickTriageLabel)
+		if !NewStringSet(conf.quickLabels...).ContainsAny(label) {
+			return "", errors.Errorf("Adding the label %q is not offered anymore.", label)
+		}
+		return p.addJiraIssueLabel(mattermostUserId, issueKey, label)
+	}
+	return "", errors.Errorf("Unknown option %q.", selected)
+}
+
+// addJiraIssueLabel adds the label to the issue on behalf of the user.
+func (p *Plugin) addJiraIssueLabel(mmUserId, issueKey, label string) (string, error) {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("addJiraIssueLabel: failed to load current Jira instance: %v", err)
+		return "", errors.New("Failed to load current Jira instance. Please contact your system administrator.")
+	}
+	client, err := p.transitionClient(ji, mmUserId)
+	if err != nil {
+		return "", err
+	}
+	err = p.requireWriteAllowed(mmUserId)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.UpdateIssue(issueKey, map[string]interface{}{
+		"update": map[string]interface{}{
+			"labels": []map[string]string{{"add": label}},
+		},
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to add label `"+label+"` to "+issueKey)
+	}
+	return fmt.Sprintf("Added label `%s` to [%s](%v/browse/%v).",
+		label, issueKey, ji.GetURL(), issueKey), nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickTriage(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
+	p := &Plugin{currentInstanceStore: mockCurrentInstanceStore{}, userStore: mockUserStore{}}
+	p.SetAPI(api)
+
+	assert.Nil(t, p.quickTriageAction(existingIssueKey, englishLocalizer))
+
+	p.updateConfig(func(conf *config) {
+		conf.quickTransitions = []string{"inprog", "Done"}
+		conf.quickLabels = []string{"urgent"}
+	})
+	action := p.quickTriageAction(existingIssueKey, englishLocalizer)
+	require.NotNil(t, action)
+	assert.Equal(t, model.POST_ACTION_TYPE_SELECT, action.Type)
+	assert.Equal(t, existingIssueKey, action.Integration.Context["issue_key"])
+	require.Len(t, action.Options, 3)
+	assert.Equal(t, "Move to inprog", action.Options[0].Text)
+	assert.Equal(t, "Add label urgent", action.Options[2].Text)
+
+	msg, err := p.applyQuickTriage("user", existingIssueKey, action.Options[0].Value)
+	require.NoError(t, err)
+	assert.Equal(t, "[REAL-1]("+mockCurrentInstanceURL+"/browse/REAL-1) transitioned to `In Progress`", msg)
+
+	msg, err = p.applyQuickTriage("user", existingIssueKey, action.Options[2].Value)
+	require.NoError(t, err)
+	assert.Equal(t, "Added label `urgent` to [REAL-1]("+mockCurrentInstanceURL+"/browse/REAL-1).", msg)
+
+	_, err = p.applyQuickTriage("user", nonExistantIssueKey, action.Options[2].Value)
+	assert.Contains(t, err.Error(), "failed to add label `urgent` to FAKE-1")
+
+	_, err = p.applyQuickTriage("user", existingIssueKey, quickTriageTransition+"Closed")
+	assert.Error(t, err)
+	_, err = p.applyQuickTriage("user", existingIssueKey, quickTriageLabel+"wontfix")
+	assert.Error(t, err)
+	_, err = p.applyQuickTriage("user", existingIssueKey, "delete")
+	assert.Error(t, err)
+}
@@ -0,0 +1,557 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const (
+	keyChannelReports = "channel_reports"
+
+	channelReportPollInterval = 5 * time.Minute
+	channelReportMaxResults   = 50
+)
+
+// Schedules of channel reports, other than the names of weekdays.
+const (
+	channelReportHourly   = "hourly"
+	channelReportDaily    = "daily"
+	channelReportWeekdays = "weekdays"
+)
+
+var defaultChannelReportColumns = []string{"key", "summary", "status", "assignee"}
+
+// channelReportColumns render the cells of the columns of a report table, by
+// column name.
+var channelReportColumns = map[string]func(issue *jira.Issue, jiraURL string) string{
+	"key": func(issue *jira.Issue, jiraURL string) string {
+		return fmt.Sprintf("[%s](%s/browse/%s)", issue.Key, jiraURL, issue.Key)
+	},
+	"summary": func(issue *jira.Issue, jiraURL string) string { return issue.Fields.Summary },
+	"status": func(issue *jira.Issue, jiraURL string) string {
+		if issue.Fields.Status == nil {
+			return ""
+		}
+		return issue.Fields.Status.Name
+	},
+	"assignee": func(issue *jira.Issue, jiraURL string) string { return mdUser(issue.Fields.Assignee) },
+	"reporter": func(issue *jira.Issue, jiraURL string) string { return mdUser(issue.Fields.Reporter) },
+	"priority": func(issue *jira.Issue, jiraURL string) string {
+		if issue.Fields.Priority == nil {
+			return ""
+		}
+		return issue.Fields.Priority.Name
+	},
+	"type":    func(issue *jira.Issue, jiraURL string) string { return issue.Fields.Type.Name },
+	"created": func(issue *jira.Issue, jiraURL string) string { return formatJiraDate(time.Time(issue.Fields.Created)) },
+	"updated": func(issue *jira.Issue, jiraURL string) string { return formatJiraDate(time.Time(issue.Fields.Updated)) },
+	"due":     func(issue *jira.Issue, jiraURL string) string { return formatJiraDate(time.Time(issue.Fields.Duedate)) },
+	"labels":  func(issue *jira.Issue, jiraURL string) string { return strings.Join(issue.Fields.Labels, ", ") },
+}
+
+// channelReportFields are the Jira fields needed by a report column.
+var channelReportFields = map[string]string{
+	"key":      "",
+	"type":     "issuetype",
+	"due":      "duedate",
+	"summary":  "summary",
+	"status":   "status",
+	"assignee": "assignee",
+	"reporter": "reporter",
+	"priority": "priority",
+	"created":  "created",
+	"updated":  "updated",
+	"labels":   "labels",
+}
+
+func formatJiraDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// channelReportSchedule is when a report is posted, in UTC: every hour, or
+// at Hour:Minute every day, on weekdays, or on a single day of the week.
+type channelReportSchedule struct {
+	Every  string `json:"every"`
+	Hour   int    `json:"hour,omitempty"`
+	Minute int    `json:"minute,omitempty"`
+}
+
+// parseChannelReportSchedule parses "hourly", or "<daily|weekdays|monday...>@HH:MM".
+func parseChannelReportSchedule(s string) (channelReportSchedule, error) {
+	s = strings.ToLower(s)
+	if s == channelReportHourly {
+		return channelReportSchedule{Every: channelReportHourly}, nil
+	}
+
+	parts := strings.Split(s, "@")
+	if len(parts) != 2 {
+		return channelReportSchedule{}, errors.Errorf("%q is not a valid schedule, use `hourly`, or e.g. `daily@09:00`, `weekdays@09:00` or `monday@09:00`, in UTC", s)
+	}
+	schedule := channelReportSchedule{Every: parts[0]}
+	if schedule.Every != channelReportDaily && schedule.Every != channelReportWeekdays {
+		if _, ok := parseWeekday(schedule.Every); !ok {
+			return channelReportSchedule{}, errors.Errorf("%q is not `daily`, `weekdays` or the name of a day of the week", parts[0])
+		}
+	}
+	t, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return channelReportSchedule{}, errors.Errorf("%q is not a valid time, use HH:MM in UTC", parts[1])
+	}
+	schedule.Hour, schedule.Minute = t.Hour(), t.Minute()
+	return schedule, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func (s channelReportSchedule) String() string {
+	if s.Every == channelReportHourly {
+		return s.Every
+	}
+	return fmt.Sprintf("%s@%02d:%02d", s.Every, s.Hour, s.Minute)
+}
+
+// next returns the first scheduled time after after.
+func (s channelReportSchedule) next(after time.Time) time.Time {
+	after = after.UTC()
+	if s.Every == channelReportHourly {
+		return after.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	t := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, s.Minute, 0, 0, time.UTC)
+	if !t.After(after) {
+		t = t.AddDate(0, 0, 1)
+	}
+	weekday, isWeekday := parseWeekday(s.Every)
+	for {
+		switch {
+		case isWeekday && t.Weekday() != weekday,
+			s.Every == channelReportWeekdays && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday):
+			t = t.AddDate(0, 0, 1)
+		default:
+			return t
+		}
+	}
+}
+
+// channelReport is a JQL query posted to a channel on a schedule, as a table
+// of the matching issues. Unlike subscriptions, reports don't depend on
+// webhook events, and are queried with the credentials of their creator.
+type channelReport struct {
+	Id        string                `json:"id"`
+	Name      string                `json:"name"`
+	ChannelId string                `json:"channel_id"`
+	CreatorId string                `json:"creator_id"`
+	JQL       string                `json:"jql"`
+	Schedule  channelReportSchedule `json:"schedule"`
+	Columns   []string              `json:"columns,omitempty"`
+
+	// NextRun is when the report is posted next, in milliseconds.
+	NextRun int64 `json:"next_run"`
+}
+
+func (r *channelReport) columns() []string {
+	if len(r.Columns) == 0 {
+		return defaultChannelReportColumns
+	}
+	return r.Columns
+}
+
+type channelReports struct {
+	ById map[string]channelReport `json:"by_id"`
+}
+
+func (reports *channelReports) forChannel(channelId string) []channelReport {
+	result := []channelReport{}
+	for _, report := range reports.ById {
+		if report.ChannelId == channelId {
+			result = append(result, report)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+func (reports *channelReports) findByName(channelId, name string) (channelReport, bool) {
+	for _, report := range reports.ById {
+		if report.ChannelId == channelId && strings.EqualFold(report.Name, name) {
+			return report, true
+		}
+	}
+	return channelReport{}, false
+}
+
+func channelReportsFromJson(data []byte) (*channelReports, error) {
+	reports := &channelReports{ById: map[string]channelReport{}}
+	if len(data) == 0 {
+		return reports, nil
+	}
+	err := json.Unmarshal(data, reports)
+	if err != nil {
+		return nil, err
+	}
+	if reports.ById == nil {
+		reports.ById = map[string]channelReport{}
+	}
+	return reports, nil
+}
+
+func (p *Plugin) loadChannelReports(ji Instance) (*channelReports, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyChannelReports))
+	if appErr != nil {
+		return nil, appErr
+	}
+	return channelReportsFromJson(data)
+}
+
+func (p *Plugin) modifyChannelReports(ji Instance, modify func(reports *channelReports) error) error {
+	return p.atomicModify(keyWithInstance(ji, keyChannelReports), func(initialBytes []byte) ([]byte, error) {
+		reports, err := channelReportsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		err = modify(reports)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(reports)
+	})
+}
+
+// postDueChannelReports posts the reports whose scheduled time has come, and
+// schedules their next run.
+func (p *Plugin) postDueChannelReports() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	reports, err := p.loadChannelReports(ji)
+	if err != nil {
+		p.errorf("postDueChannelReports: failed to load reports: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, report := range reports.ById {
+		if report.NextRun > model.GetMillisForTime(now) {
+			continue
+		}
+		err = p.postChannelReport(ji, report)
+		if err != nil {
+			p.errorf("postDueChannelReports: report %q of channel %s: %v", report.Name, report.ChannelId, err)
+		}
+
+		id, nextRun := report.Id, model.GetMillisForTime(report.Schedule.next(now))
+		err = p.modifyChannelReports(ji, func(reports *channelReports) error {
+			if r, ok := reports.ById[id]; ok {
+				r.NextRun = nextRun
+				reports.ById[id] = r
+			}
+			return nil
+		})
+		if err != nil {
+			p.errorf("postDueChannelReports: failed to schedule report %q: %v", report.Name, err)
+		}
+	}
+}
+
+// postChannelReport queries the issues of the report, and posts them as a
+// table to its channel.
+func (p *Plugin) postChannelReport(ji Instance, report channelReport) error {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, report.CreatorId)
+	if err != nil {
+		return errors.WithMessage(err, "report creator is not connected to Jira")
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{}
+	for _, column := range report.columns() {
+		if field := channelReportFields[column]; field != "" {
+			fields = append(fields, field)
+		}
+	}
+	issues, err := client.SearchIssues(report.JQL, &jira.SearchOptions{
+		MaxResults: channelReportMaxResults,
+		Fields:     fields,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: report.ChannelId,
+		Message:   p.renderChannelReport(p.channelLocale(report.ChannelId), ji.GetURL(), report, issues),
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) renderChannelReport(locale, jiraURL string, report channelReport, issues []jira.Issue) string {
+	if len(issues) == 0 {
+		return p.localize(locale, msgReportEmpty, report.Name)
+	}
+
+	columns := report.columns()
+	lines := []string{
+		p.localize(locale, msgReportHeader, report.Name, len(issues)),
+		"",
+		"| " + strings.Join(columns, " | ") + " |",
+		strings.Repeat("|---", len(columns)) + "|",
+	}
+	for i := range issues {
+		if issues[i].Fields == nil {
+			issues[i].Fields = &jira.IssueFields{}
+		}
+		cells := []string{}
+		for _, column := range columns {
+			cells = append(cells, mdTableCell(channelReportColumns[column](&issues[i], jiraURL)))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+	if len(issues) == channelReportMaxResults {
+		lines = append(lines, "", p.localize(locale, msgReportMore, channelReportMaxResults))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func mdTableCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+func parseChannelReportColumns(s string) ([]string, error) {
+	columns := []string{}
+	for _, column := range utils.ParseList(strings.ToLower(s)) {
+		if channelReportColumns[column] == nil {
+			known := []string{}
+			for name := range channelReportColumns {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, errors.Errorf("%q is not a valid column, please use: %s", column, strings.Join(known, ", "))
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("please specify at least one column")
+	}
+	return columns, nil
+}
+
+// channelReportClient checks that the user may manage the reports of the
+// channel, and returns the Jira client to validate them with.
+func (p *Plugin) channelReportClient(header *model.CommandArgs) (Instance, Client, *model.CommandResponse) {
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return nil, nil, p.responsef(header, "You are not allowed to manage the Jira reports of this channel: %v", err)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("channelReportClient: failed to load current Jira instance: %v", err)
+		return nil, nil, p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return nil, nil, p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return nil, nil, p.responsef(header, "%v", err)
+	}
+	return ji, client, nil
+}
+
+func executeReportAdd(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name, args := splitQuotedArg(args)
+	if name == "" || len(args) < 1 {
+		return p.responsef(header, "Please use `/jira report add <name> <schedule> <JQL>`, e.g. `/jira report add \"Open bugs\" weekdays@09:00 project = EXT AND type = Bug AND resolution = Unresolved`.")
+	}
+	schedule, err := parseChannelReportSchedule(args[0])
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	jql := strings.Join(args[1:], " ")
+
+	ji, client, resp := p.channelReportClient(header)
+	if resp != nil {
+		return resp
+	}
+	if jql == "" {
+		// The unresolved issues of the project linked to the channel
+		link, err := p.loadChannelProject(ji, header.ChannelId)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if link.ProjectKey == "" {
+			return p.responsef(header, "Please specify the JQL query of the report, or link this channel to a Jira project with `/jira link-project <project-key>`.")
+		}
+		jql = linkedProjectJQL(link.ProjectKey)
+	}
+	_, err = client.SearchIssues(jql, &jira.SearchOptions{MaxResults: 1, Fields: []string{"summary"}})
+	if err != nil {
+		return p.responsef(header, "Failed to run the JQL query: %v", err)
+	}
+
+	report := channelReport{
+		Id:        model.NewId(),
+		Name:      name,
+		ChannelId: header.ChannelId,
+		CreatorId: header.UserId,
+		JQL:       jql,
+		Schedule:  schedule,
+		NextRun:   model.GetMillisForTime(schedule.next(time.Now())),
+	}
+	err = p.modifyChannelReports(ji, func(reports *channelReports) error {
+		if _, exists := reports.findByName(header.ChannelId, name); exists {
+			return errors.Errorf("there is already a report named %q in this channel", name)
+		}
+		reports.ById[report.Id] = report
+		return nil
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to add the report: %v", err)
+	}
+
+	return p.responsef(header, "Report %q will be posted to this channel %s UTC, next at %s.",
+		name, schedule, schedule.next(time.Now()).Format("2006-01-02 15:04"))
+}
+
+func executeReportColumns(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name, args := splitQuotedArg(args)
+	if name == "" || len(args) != 1 {
+		return p.responsef(header, "Please use `/jira report columns <name> <column[,column...]|default>`.")
+	}
+	var columns []string
+	if args[0] != "default" {
+		var err error
+		columns, err = parseChannelReportColumns(args[0])
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+	}
+
+	ji, _, resp := p.channelReportClient(header)
+	if resp != nil {
+		return resp
+	}
+	err := p.modifyChannelReports(ji, func(reports *channelReports) error {
+		report, ok := reports.findByName(header.ChannelId, name)
+		if !ok {
+			return errors.Errorf("there is no report named %q in this channel", name)
+		}
+		report.Columns = columns
+		reports.ById[report.Id] = report
+		return nil
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to update the report: %v", err)
+	}
+
+	if columns == nil {
+		columns = defaultChannelReportColumns
+	}
+	return p.responsef(header, "Report %q now lists the columns: %s.", name, strings.Join(columns, ", "))
+}
+
+func executeReportRemove(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name := strings.Trim(strings.Join(args, " "), `"`)
+	if name == "" {
+		return p.responsef(header, "Please use `/jira report remove <name>`.")
+	}
+
+	ji, _, resp := p.channelReportClient(header)
+	if resp != nil {
+		return resp
+	}
+	err := p.modifyChannelReports(ji, func(reports *channelReports) error {
+		report, ok := reports.findByName(header.ChannelId, name)
+		if !ok {
+			return errors.Errorf("there is no report named %q in this channel", name)
+		}
+		delete(reports.ById, report.Id)
+		return nil
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to remove the report: %v", err)
+	}
+	return p.responsef(header, "Report %q was removed.", name)
+}
+
+func executeReportRun(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name := strings.Trim(strings.Join(args, " "), `"`)
+	if name == "" {
+		return p.responsef(header, "Please use `/jira report run <name>`.")
+	}
+
+	ji, _, resp := p.channelReportClient(header)
+	if resp != nil {
+		return resp
+	}
+	reports, err := p.loadChannelReports(ji)
+	if err != nil {
+		return p.responsef(header, "Failed to load the reports: %v", err)
+	}
+	report, ok := reports.findByName(header.ChannelId, name)
+	if !ok {
+		return p.responsef(header, "There is no report named %q in this channel.", name)
+	}
+	err = p.postChannelReport(ji, report)
+	if err != nil {
+		return p.responsef(header, "Failed to post the report: %v", err)
+	}
+	return &model.CommandResponse{}
+}
+
+func executeReportList(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeReportList: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	reports, err := p.loadChannelReports(ji)
+	if err != nil {
+		return p.responsef(header, "Failed to load the reports: %v", err)
+	}
+
+	list := reports.forChannel(header.ChannelId)
+	if len(list) == 0 {
+		return p.responsef(header, "This channel has no Jira reports. Use `/jira report add` to add one.")
+	}
+	lines := []string{"Jira reports of this channel:"}
+	for _, report := range list {
+		lines = append(lines, fmt.Sprintf("* **%s**, %s UTC, columns: %s, JQL: `%s`",
+			report.Name, report.Schedule, strings.Join(report.columns(), ", "), report.JQL))
+	}
+	return p.responsef(header, "%s", strings.Join(lines, "\n"))
+}
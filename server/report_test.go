@@ -0,0 +1,69 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelReportSchedule(t *testing.T) {
+	// A Friday
+	now := time.Date(2020, 5, 15, 10, 30, 0, 0, time.UTC)
+
+	for schedule, expected := range map[string]time.Time{
+		"hourly":          time.Date(2020, 5, 15, 11, 0, 0, 0, time.UTC),
+		"daily@09:00":     time.Date(2020, 5, 16, 9, 0, 0, 0, time.UTC),
+		"daily@18:45":     time.Date(2020, 5, 15, 18, 45, 0, 0, time.UTC),
+		"weekdays@09:00":  time.Date(2020, 5, 18, 9, 0, 0, 0, time.UTC),
+		"weekdays@11:00":  time.Date(2020, 5, 15, 11, 0, 0, 0, time.UTC),
+		"Monday@08:00":    time.Date(2020, 5, 18, 8, 0, 0, 0, time.UTC),
+		"friday@10:30":    time.Date(2020, 5, 22, 10, 30, 0, 0, time.UTC),
+		"wednesday@23:59": time.Date(2020, 5, 20, 23, 59, 0, 0, time.UTC),
+	} {
+		t.Run(schedule, func(t *testing.T) {
+			s, err := parseChannelReportSchedule(schedule)
+			require.NoError(t, err)
+			assert.Equal(t, expected, s.next(now))
+		})
+	}
+
+	for _, invalid := range []string{"", "daily", "monthly@09:00", "daily@25:00", "weekdays@9am"} {
+		_, err := parseChannelReportSchedule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRenderChannelReport(t *testing.T) {
+	p := &Plugin{}
+	report := channelReport{Name: "Open bugs", Columns: []string{"key", "summary", "assignee"}}
+
+	assert.Equal(t, "#### Jira report **Open bugs**\nNo issues match.",
+		p.renderChannelReport("en", "https://jira.example.com", report, nil))
+
+	issues := []jira.Issue{
+		{Key: "EXT-1", Fields: &jira.IssueFields{Summary: "Crash on | start", Assignee: &jira.User{DisplayName: "Jane"}}},
+		{Key: "EXT-2", Fields: &jira.IssueFields{Summary: "Typo\nin title"}},
+	}
+	assert.Equal(t, "#### Jira report **Open bugs**, issues: 2\n"+
+		"\n"+
+		"| key | summary | assignee |\n"+
+		"|---|---|---|\n"+
+		"| [EXT-1](https://jira.example.com/browse/EXT-1) | Crash on \\| start | Jane |\n"+
+		"| [EXT-2](https://jira.example.com/browse/EXT-2) | Typo in title |  |",
+		p.renderChannelReport("en", "https://jira.example.com", report, issues))
+}
+
+func TestParseChannelReportColumns(t *testing.T) {
+	columns, err := parseChannelReportColumns("Key, priority,due")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key", "priority", "due"}, columns)
+
+	_, err = parseChannelReportColumns("key,sprint")
+	assert.Error(t, err)
+}
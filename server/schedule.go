@@ -0,0 +1,64 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const prefixJobLock = "job_lock_"
+
+// startPeriodicJob runs f every interval in the background. When running in a
+// cluster, each tick is claimed through an expiring KV lock so that only one
+// server executes the job per interval.
+func (p *Plugin) startPeriodicJob(name string, interval time.Duration, f func()) {
+	runPeriodically(interval, func() {
+		if p.claimJob(name, interval) {
+			f()
+		}
+	})
+}
+
+// startLocalPeriodicJob runs f every interval in the background on every
+// server of a cluster, for jobs maintaining the state of each server.
+func (p *Plugin) startLocalPeriodicJob(interval time.Duration, f func()) {
+	runPeriodically(interval, f)
+}
+
+func runPeriodically(interval time.Duration, f func()) {
+	go func() {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		dither := time.Duration(r.Int63n(int64(interval)/10 + 1))
+		time.Sleep(dither)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			f()
+		}
+	}()
+}
+
+// claimJob returns true if this server acquired the lock for the next
+// run of the named job.
+func (p *Plugin) claimJob(name string, interval time.Duration) bool {
+	expire := int64(interval.Seconds()) - 1
+	if expire < 1 {
+		expire = 1
+	}
+	ok, appErr := p.API.KVSetWithOptions(prefixJobLock+name, []byte(time.Now().UTC().Format(time.RFC3339)),
+		model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        nil,
+			ExpireInSeconds: expire,
+		})
+	if appErr != nil {
+		p.errorf("claimJob: failed to acquire lock for %s: %v", name, appErr)
+		return false
+	}
+	return ok
+}
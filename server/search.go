@@ -0,0 +1,550 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	prefixSearchResults = "search_results_"
+
+	// The search results, and their selection, expire after an hour.
+	searchResultsExpirySeconds = 60 * 60
+
+	searchMaxResults = 20
+
+	// Bulk actions run the Jira calls of this many issues at once, and
+	// report their progress after each batch.
+	searchBulkBatchSize = 5
+)
+
+// Actions of the search results buttons.
+const (
+	searchActionToggle     = "toggle"
+	searchActionSelectAll  = "select_all"
+	searchActionClear      = "clear"
+	searchActionTransition = "transition"
+	searchActionAssign     = "assign"
+	searchActionLabel      = "label"
+)
+
+// searchResults are the issues found by /jira search, with the ones selected
+// for a bulk action, kept while the user interacts with their ephemeral post.
+type searchResults struct {
+	Id        string               `json:"id"`
+	UserId    string               `json:"user_id"`
+	ChannelId string               `json:"channel_id"`
+	PostId    string               `json:"post_id"`
+	JQL       string               `json:"jql"`
+	Issues    []searchResultsIssue `json:"issues"`
+	Selected  StringSet            `json:"selected,omitempty"`
+	Progress  string               `json:"progress,omitempty"`
+	Running   bool                 `json:"running,omitempty"`
+}
+
+type searchResultsIssue struct {
+	Key     string `json:"key"`
+	Summary string `json:"summary"`
+	Status  string `json:"status"`
+}
+
+// selectedKeys returns the keys of the selected issues, in the order of the
+// results.
+func (results *searchResults) selectedKeys() []string {
+	keys := []string{}
+	for _, issue := range results.Issues {
+		if results.Selected.ContainsAny(issue.Key) {
+			keys = append(keys, issue.Key)
+		}
+	}
+	return keys
+}
+
+func executeSearch(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) == 0 {
+		return p.responsef(header, "Please specify a JQL query in the form `/jira search <JQL>`, e.g. `/jira search assignee = currentUser() AND resolution = Unresolved`.")
+	}
+	jql := strings.Join(args, " ")
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSearch: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	found, err := client.SearchIssues(jql, &jira.SearchOptions{
+		MaxResults: searchMaxResults,
+		Fields:     []string{"summary", "status"},
+	})
+	if err != nil {
+		if jqlErrors, _ := client.ValidateJQL(jql); len(jqlErrors) != 0 {
+			return p.responsef(header, "`%s` is not a valid JQL query:\n%s", jql, formatJQLErrors(jql, jqlErrors))
+		}
+		return p.responsef(header, "Failed to search Jira issues: %v", err)
+	}
+	if len(found) == 0 {
+		return p.responsef(header, "No Jira issues match `%s`.", jql)
+	}
+
+	results := &searchResults{
+		Id:        model.NewId(),
+		UserId:    header.UserId,
+		ChannelId: header.ChannelId,
+		JQL:       jql,
+		Selected:  NewStringSet(),
+	}
+	for _, issue := range found {
+		result := searchResultsIssue{Key: issue.Key}
+		if issue.Fields != nil {
+			result.Summary = issue.Fields.Summary
+			if issue.Fields.Status != nil {
+				result.Status = issue.Fields.Status.Name
+			}
+		}
+		results.Issues = append(results.Issues, result)
+	}
+
+	post := p.API.SendEphemeralPost(header.UserId, p.searchResultsPost(ji, results))
+	if post == nil {
+		return &model.CommandResponse{}
+	}
+	results.PostId = post.Id
+	err = p.storeSearchResults(ji, results)
+	if err != nil {
+		p.errorf("executeSearch: failed to store the search results: %v", err)
+	}
+	return &model.CommandResponse{}
+}
+
+// searchResultsPost renders the ephemeral post of the search results, with
+// the selection of issues and the bulk actions.
+func (p *Plugin) searchResultsPost(ji Instance, results *searchResults) *model.Post {
+	lines := []string{fmt.Sprintf("Jira issues matching `%s`:", results.JQL)}
+	for _, issue := range results.Issues {
+		mark := ":white_large_square:"
+		if results.Selected.ContainsAny(issue.Key) {
+			mark = ":white_check_mark:"
+		}
+		line := fmt.Sprintf("%s [%s](%s/browse/%s) %s", mark, issue.Key, ji.GetURL(), issue.Key, issue.Summary)
+		if issue.Status != "" {
+			line += " (" + issue.Status + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(results.Issues) == searchMaxResults {
+		lines = append(lines, fmt.Sprintf("_Only the first %d issues are listed._", searchMaxResults))
+	}
+	if results.Progress != "" {
+		lines = append(lines, "", results.Progress)
+	}
+
+	post := &model.Post{
+		Id:        results.PostId,
+		UserId:    p.getUserID(),
+		ChannelId: results.ChannelId,
+		Message:   strings.Join(lines, "\n"),
+	}
+	if results.Running {
+		return post
+	}
+
+	action := func(id, name string) *model.PostAction {
+		return &model.PostAction{
+			Id:   strings.Replace(id, "_", "", -1),
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL: p.GetPluginURLPath() + routeAPISearchAction,
+				Context: map[string]interface{}{
+					"search_id": results.Id,
+					"action":    id,
+				},
+			},
+		}
+	}
+
+	toggle := action(searchActionToggle, "Select or unselect an issue")
+	toggle.Type = model.POST_ACTION_TYPE_SELECT
+	for _, issue := range results.Issues {
+		toggle.Options = append(toggle.Options, &model.PostActionOptions{
+			Text:  issue.Key + " " + issue.Summary,
+			Value: issue.Key,
+		})
+	}
+	actions := []*model.PostAction{toggle, action(searchActionSelectAll, "Select all")}
+	if results.Selected.Len() > 0 {
+		actions = append(actions,
+			action(searchActionClear, "Clear selection"),
+			action(searchActionTransition, fmt.Sprintf("Transition %d", results.Selected.Len())),
+			action(searchActionAssign, fmt.Sprintf("Assign %d", results.Selected.Len())),
+			action(searchActionLabel, fmt.Sprintf("Add label to %d", results.Selected.Len())),
+		)
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{{Actions: actions}})
+	return post
+}
+
+func (p *Plugin) loadSearchResults(ji Instance, id string) (*searchResults, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, prefixSearchResults+id))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, errors.New("the search results have expired, please search again")
+	}
+	results := &searchResults{}
+	err := json.Unmarshal(data, results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (p *Plugin) storeSearchResults(ji Instance, results *searchResults) error {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSetWithExpiry(keyWithInstance(ji, prefixSearchResults+results.Id), data, searchResultsExpirySeconds)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// updateSearchResults stores the search results and re-renders their post.
+func (p *Plugin) updateSearchResults(ji Instance, results *searchResults) error {
+	err := p.storeSearchResults(ji, results)
+	if err != nil {
+		return err
+	}
+	p.API.UpdateEphemeralPost(results.UserId, p.searchResultsPost(ji, results))
+	return nil
+}
+
+// httpAPISearchAction handles the buttons and the select of the search
+// results post. Bulk actions open a dialog asking for the state, assignee or
+// label to apply.
+func httpAPISearchAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	searchId, _ := request.Context["search_id"].(string)
+	action, _ := request.Context["action"].(string)
+
+	p := ji.GetPlugin()
+	if err := p.requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	response := model.PostActionIntegrationResponse{}
+	results, err := p.loadSearchResults(ji, searchId)
+	switch {
+	case err != nil:
+		response.EphemeralText = err.Error()
+	case results.UserId != mattermostUserId:
+		return http.StatusForbidden, errors.New("not the user of the search")
+	case results.Running:
+		response.EphemeralText = "Please wait for the running bulk action to complete."
+	default:
+		response.EphemeralText, err = p.handleSearchAction(ji, results, action, request)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// handleSearchAction applies an action to the search results, and returns
+// the text to respond to the user with, if any.
+func (p *Plugin) handleSearchAction(ji Instance, results *searchResults, action string, request *model.PostActionIntegrationRequest) (string, error) {
+	switch action {
+	case searchActionToggle:
+		key, _ := request.Context["selected_option"].(string)
+		if results.Selected.ContainsAny(key) {
+			results.Selected = results.Selected.Subtract(key)
+		} else {
+			results.Selected = results.Selected.Add(key)
+		}
+	case searchActionSelectAll:
+		for _, issue := range results.Issues {
+			results.Selected = results.Selected.Add(issue.Key)
+		}
+	case searchActionClear:
+		results.Selected = NewStringSet()
+	case searchActionTransition, searchActionAssign, searchActionLabel:
+		if results.Selected.Len() == 0 {
+			return "Please select the issues first.", nil
+		}
+		return "", p.openSearchBulkDialog(results, action, request.TriggerId)
+	default:
+		return "", errors.Errorf("unknown action %q", action)
+	}
+	results.Progress = ""
+	return "", p.updateSearchResults(ji, results)
+}
+
+func (p *Plugin) openSearchBulkDialog(results *searchResults, action, triggerId string) error {
+	n := results.Selected.Len()
+	dialog := model.Dialog{
+		CallbackId: results.Id,
+		State:      action,
+	}
+	element := model.DialogElement{Name: "value", Type: "text"}
+	switch action {
+	case searchActionTransition:
+		dialog.Title = fmt.Sprintf("Transition %d issues", n)
+		dialog.SubmitLabel = "Transition"
+		element.DisplayName = "State"
+		element.Placeholder = "e.g. In Progress"
+	case searchActionAssign:
+		dialog.Title = fmt.Sprintf("Assign %d issues", n)
+		dialog.SubmitLabel = "Assign"
+		element.DisplayName = "Assignee"
+		element.HelpText = "The name or email of a Jira user."
+		element.MinLength = MinUserSearchQueryLength
+	case searchActionLabel:
+		dialog.Title = fmt.Sprintf("Add a label to %d issues", n)
+		dialog.SubmitLabel = "Add label"
+		element.DisplayName = "Label"
+		element.HelpText = "Jira labels can't contain spaces."
+	}
+	dialog.Elements = []model.DialogElement{element}
+
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerId,
+		URL:       p.GetPluginURLPath() + routeAPISearchDialog,
+		Dialog:    dialog,
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// httpAPISearchDialog handles the submission of a bulk action dialog. The
+// action runs in the background, reporting its progress in the search
+// results post.
+func httpAPISearchDialog(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the dialog submission")
+	}
+	if request.Cancelled {
+		return http.StatusOK, nil
+	}
+	value, _ := request.Submission["value"].(string)
+	value = strings.TrimSpace(value)
+
+	p := ji.GetPlugin()
+	response := model.SubmitDialogResponse{}
+	results, err := p.loadSearchResults(ji, request.CallbackId)
+	switch {
+	case err != nil:
+		response.Error = err.Error()
+	case results.UserId != mattermostUserId:
+		return http.StatusForbidden, errors.New("not the user of the search")
+	case results.Running:
+		response.Error = "Please wait for the running bulk action to complete."
+	default:
+		var operation searchBulkOperation
+		operation, err = p.searchBulkOperation(ji, results, request.State, value)
+		if err != nil {
+			response.Errors = map[string]string{"value": err.Error()}
+			break
+		}
+		results.Running = true
+		results.Progress = operation.progress(0, 0, len(results.selectedKeys()))
+		err = p.updateSearchResults(ji, results)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		go p.runSearchBulkOperation(ji, results, operation)
+	}
+
+	if response.Error == "" && len(response.Errors) == 0 {
+		return http.StatusOK, nil
+	}
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// searchBulkOperation is a bulk action, ready to be applied to each selected
+// issue.
+type searchBulkOperation struct {
+	verb  string
+	apply func(issueKey string) error
+}
+
+func (op searchBulkOperation) progress(done, failed, total int) string {
+	return fmt.Sprintf("%s: %d of %d issues done, %d failed.", op.verb, done, total, failed)
+}
+
+// searchBulkOperation validates the value of a bulk action, and returns the
+// operation applying it.
+func (p *Plugin) searchBulkOperation(ji Instance, results *searchResults, action, value string) (searchBulkOperation, error) {
+	if value == "" {
+		return searchBulkOperation{}, errors.New("Please enter a value.")
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, results.UserId)
+	if err != nil {
+		return searchBulkOperation{}, errors.New(p.localize(p.userLocale(results.UserId), msgNotConnected))
+	}
+	err = p.requireWriteAccess(results.UserId, jiraUser)
+	if err != nil {
+		return searchBulkOperation{}, err
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return searchBulkOperation{}, err
+	}
+
+	switch action {
+	case searchActionTransition:
+		return searchBulkOperation{
+			verb: fmt.Sprintf("Transitioning to `%s`", value),
+			apply: func(issueKey string) error {
+				transitions, err := client.GetTransitions(issueKey)
+				if err != nil {
+					return err
+				}
+				transition, err := findTransition(transitions, value)
+				if err != nil {
+					return err
+				}
+				return client.DoTransition(issueKey, transition.ID)
+			},
+		}, nil
+
+	case searchActionAssign:
+		if len(value) < MinUserSearchQueryLength {
+			return searchBulkOperation{}, errors.Errorf("`%s` contains less than %v characters.", value, MinUserSearchQueryLength)
+		}
+		keys := results.selectedKeys()
+		user, err := findAssignableUser(client, keys[0], value)
+		if err != nil {
+			return searchBulkOperation{}, err
+		}
+		return searchBulkOperation{
+			verb: fmt.Sprintf("Assigning to `%s`", user.DisplayName),
+			apply: func(issueKey string) error {
+				return client.UpdateAssignee(issueKey, user)
+			},
+		}, nil
+
+	case searchActionLabel:
+		if strings.ContainsAny(value, " \t") {
+			return searchBulkOperation{}, errors.New("Jira labels can't contain spaces.")
+		}
+		return searchBulkOperation{
+			verb: fmt.Sprintf("Adding label `%s`", value),
+			apply: func(issueKey string) error {
+				return client.UpdateIssue(issueKey, map[string]interface{}{
+					"update": map[string]interface{}{
+						"labels": []map[string]string{{"add": value}},
+					},
+				})
+			},
+		}, nil
+	}
+	return searchBulkOperation{}, errors.Errorf("unknown action %q", action)
+}
+
+// runSearchBulkOperation applies the operation to the selected issues, by
+// batches of searchBulkBatchSize, updating the progress in the search results
+// post after each batch.
+func (p *Plugin) runSearchBulkOperation(ji Instance, results *searchResults, op searchBulkOperation) {
+	keys := results.selectedKeys()
+	failures := map[string]error{}
+	lock := sync.Mutex{}
+
+	for start := 0; start < len(keys); start += searchBulkBatchSize {
+		end := start + searchBulkBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		wg := sync.WaitGroup{}
+		for _, key := range keys[start:end] {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				// Each issue is a change of the user in Jira
+				err := p.requireWriteAllowed(results.UserId)
+				if err == nil {
+					err = op.apply(key)
+				}
+				if err != nil {
+					lock.Lock()
+					failures[key] = err
+					lock.Unlock()
+				}
+			}(key)
+		}
+		wg.Wait()
+
+		if end < len(keys) {
+			results.Progress = op.progress(end-len(failures), len(failures), len(keys))
+			err := p.updateSearchResults(ji, results)
+			if err != nil {
+				p.errorf("runSearchBulkOperation: failed to update the search results: %v", err)
+			}
+		}
+	}
+
+	results.Running = false
+	results.Progress = op.progress(len(keys)-len(failures), len(failures), len(keys))
+	failed := []string{}
+	for key, err := range failures {
+		failed = append(failed, fmt.Sprintf("* %s: %v", key, err))
+	}
+	sort.Strings(failed)
+	if len(failed) > 0 {
+		results.Progress += "\n" + strings.Join(failed, "\n")
+	}
+	err := p.updateSearchResults(ji, results)
+	if err != nil {
+		p.errorf("runSearchBulkOperation: failed to update the search results: %v", err)
+	}
+}
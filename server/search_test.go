@@ -0,0 +1,111 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testSearchResults(n int) *searchResults {
+	results := &searchResults{Id: "searchid", UserId: "userid", ChannelId: "channelid", PostId: "postid", JQL: "project = TEST", Selected: NewStringSet()}
+	for i := 1; i <= n; i++ {
+		results.Issues = append(results.Issues, searchResultsIssue{Key: fmt.Sprintf("TEST-%d", i), Summary: "Issue", Status: "Open"})
+	}
+	return results
+}
+
+func TestSearchResultsPost(t *testing.T) {
+	p := &Plugin{}
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+	results := testSearchResults(3)
+
+	actionNames := func(post *model.Post) []string {
+		names := []string{}
+		for _, attachment := range post.Attachments() {
+			for _, action := range attachment.Actions {
+				names = append(names, action.Name)
+			}
+		}
+		return names
+	}
+
+	post := p.searchResultsPost(ji, results)
+	assert.Equal(t, "postid", post.Id)
+	assert.Contains(t, post.Message, ":white_large_square: [TEST-2]")
+	assert.Equal(t, []string{"Select or unselect an issue", "Select all"}, actionNames(post))
+
+	results.Selected = NewStringSet("TEST-3", "TEST-1")
+	assert.Equal(t, []string{"TEST-1", "TEST-3"}, results.selectedKeys())
+	post = p.searchResultsPost(ji, results)
+	assert.Contains(t, post.Message, ":white_check_mark: [TEST-3]")
+	assert.Equal(t, []string{"Select or unselect an issue", "Select all", "Clear selection", "Transition 2", "Assign 2", "Add label to 2"}, actionNames(post))
+
+	results.Running = true
+	assert.Empty(t, p.searchResultsPost(ji, results).Attachments())
+}
+
+func TestRunSearchBulkOperation(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(searchResultsExpirySeconds)).Return(nil)
+	messages := []string{}
+	api.On("UpdateEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		messages = append(messages, args.Get(1).(*model.Post).Message)
+	}).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.maxJiraWritesPerMinute = 100
+	})
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+
+	results := testSearchResults(7)
+	for _, issue := range results.Issues {
+		results.Selected = results.Selected.Add(issue.Key)
+	}
+	results.Running = true
+	applied := NewStringSet()
+	lock := sync.Mutex{}
+	op := searchBulkOperation{
+		verb: "Testing",
+		apply: func(issueKey string) error {
+			if issueKey == "TEST-6" {
+				return errors.New("no permission")
+			}
+			lock.Lock()
+			applied = applied.Add(issueKey)
+			lock.Unlock()
+			return nil
+		},
+	}
+
+	p.runSearchBulkOperation(ji, results, op)
+
+	assert.False(t, results.Running)
+	assert.Equal(t, 6, applied.Len())
+	require.Len(t, messages, 2)
+	assert.True(t, strings.HasSuffix(messages[0], "Testing: 5 of 7 issues done, 0 failed."))
+	assert.True(t, strings.HasSuffix(messages[1], "Testing: 6 of 7 issues done, 1 failed.\n* TEST-6: no permission"))
+
+	// Each issue counts as a change of the user in Jira, the 7 of the first
+	// run leave 1 for the next
+	p.updateConfig(func(conf *config) {
+		conf.maxJiraWritesPerMinute = 8
+	})
+	results.Running = true
+	results.Selected = NewStringSet("TEST-1", "TEST-2")
+	p.runSearchBulkOperation(ji, results, op)
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[2], "Testing: 1 of 2 issues done, 1 failed.\n* TEST-")
+	assert.Contains(t, messages[2], "You are making changes in Jira too quickly.")
+}
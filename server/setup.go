@@ -0,0 +1,608 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	prefixSetupSession = "setup_"
+
+	// A setup left unfinished expires after a day.
+	setupSessionExpirySeconds = 24 * 60 * 60
+
+	// How many projects the project step lists, the others are set up with
+	// /jira setup <project-key>.
+	setupMaxProjects = 100
+)
+
+// Steps of /jira setup, in order.
+const (
+	setupStepInstance = "instance"
+	setupStepProject  = "project"
+	setupStepEvents   = "events"
+	setupStepChannel  = "channel"
+	setupStepWebhook  = "webhook"
+	setupStepDone     = "done"
+)
+
+// Actions of the buttons and selects of the setup post.
+const (
+	setupActionContinue = "continue"
+	setupActionProject  = "project"
+	setupActionEvents   = "events"
+	setupActionChannel  = "channel"
+	setupActionWebhook  = "webhook"
+	setupActionSkip     = "skip"
+	setupActionRestart  = "restart"
+	setupActionCancel   = "cancel"
+)
+
+// setupEventPreset is a choice of the events posted by the subscription of
+// /jira setup.
+type setupEventPreset struct {
+	id     string
+	name   string
+	events StringSet
+}
+
+var setupEventPresets = []setupEventPreset{
+	{"created", "New issues", NewStringSet(eventCreated)},
+	{"workflow", "New, transitioned and resolved issues", NewStringSet(eventCreated, eventUpdatedStatus, eventUpdatedResolved, eventUpdatedReopened)},
+	{"comments", "New and transitioned issues, and comments", NewStringSet(eventCreated, eventUpdatedStatus, eventUpdatedResolved, eventUpdatedReopened, eventCreatedComment)},
+	{"all", "All issue and comment events", NewStringSet(eventCreated, eventUpdatedAny, eventDeleted, eventCreatedComment, eventUpdatedComment, eventDeletedComment)},
+}
+
+func findSetupEventPreset(id string) (setupEventPreset, bool) {
+	for _, preset := range setupEventPresets {
+		if preset.id == id {
+			return preset, true
+		}
+	}
+	return setupEventPreset{}, false
+}
+
+// setupWebhookEvents are the events of the Jira webhook created by /jira
+// setup.
+var setupWebhookEvents = []string{
+	"jira:issue_created",
+	"jira:issue_updated",
+	"jira:issue_deleted",
+	"comment_created",
+	"comment_updated",
+	"comment_deleted",
+}
+
+// setupSession is the progress of an admin through /jira setup, kept while
+// they interact with its ephemeral post.
+type setupSession struct {
+	Id        string `json:"id"`
+	UserId    string `json:"user_id"`
+	ChannelId string `json:"channel_id"`
+	PostId    string `json:"post_id"`
+	Step      string `json:"step"`
+
+	ProjectKey string `json:"project_key,omitempty"`
+	Events     string `json:"events,omitempty"`
+
+	// The subscription created by the channel step
+	SubscriptionId        string `json:"subscription_id,omitempty"`
+	SubscriptionName      string `json:"subscription_name,omitempty"`
+	SubscriptionChannelId string `json:"subscription_channel_id,omitempty"`
+
+	// Done lists what was set up, one line per project.
+	Done []string `json:"done,omitempty"`
+}
+
+// executeSetup starts the guided setup of a Jira project: the instance, the
+// project, the events and the channel of a new subscription, and the Jira
+// webhook sending the events of the project.
+func executeSetup(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira setup` can only be run by a system administrator.")
+	}
+	if len(args) > 1 {
+		return p.responsef(header, "Please use `/jira setup [project-key]`.")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return p.responsef(header, "There is no Jira instance to set up yet. Please use `/jira install cloud <URL>` or `/jira install server <URL>` first.")
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+
+	session := &setupSession{
+		Id:        model.NewId(),
+		UserId:    header.UserId,
+		ChannelId: header.ChannelId,
+		Step:      setupStepInstance,
+	}
+	if len(args) == 1 {
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		projectKey := strings.ToUpper(args[0])
+		if _, err = client.GetProject(projectKey); err != nil {
+			return p.responsef(header, "Failed to get project %s: %v", projectKey, err)
+		}
+		session.ProjectKey = projectKey
+		session.Step = setupStepEvents
+	}
+
+	post, err := p.setupPost(ji, jiraUser, session)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	post = p.API.SendEphemeralPost(header.UserId, post)
+	if post == nil {
+		return &model.CommandResponse{}
+	}
+	session.PostId = post.Id
+	err = p.storeSetupSession(ji, session)
+	if err != nil {
+		p.errorf("executeSetup: failed to store the setup session: %v", err)
+	}
+	return &model.CommandResponse{}
+}
+
+// setupPost renders the ephemeral post of the current step of a setup.
+func (p *Plugin) setupPost(ji Instance, jiraUser JIRAUser, session *setupSession) (*model.Post, error) {
+	post := &model.Post{
+		Id:        session.PostId,
+		UserId:    p.getUserID(),
+		ChannelId: session.ChannelId,
+		Message:   "#### Jira setup",
+	}
+	if len(session.Done) > 0 {
+		post.Message += "\n" + strings.Join(session.Done, "\n")
+	}
+
+	action := func(id, name string) *model.PostAction {
+		return &model.PostAction{
+			Id:   id,
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL: p.GetPluginURLPath() + routeAPISetupAction,
+				Context: map[string]interface{}{
+					"setup_id": session.Id,
+					"action":   id,
+				},
+			},
+		}
+	}
+	cancel := action(setupActionCancel, "Cancel")
+
+	attachment := &model.SlackAttachment{}
+	switch session.Step {
+	case setupStepInstance:
+		attachment.Title = "Step 1 of 5: Jira instance"
+		attachment.Text = fmt.Sprintf("The project is set up with the Jira instance %s, as %s. "+
+			"Jira instances are installed with `/jira install cloud <URL>` or `/jira install server <URL>`.", ji.GetURL(), jiraUser.DisplayName)
+		attachment.Actions = []*model.PostAction{action(setupActionContinue, "Continue"), cancel}
+
+	case setupStepProject:
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := client.GetAllProjectKeys()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to list the Jira projects")
+		}
+		sort.Strings(keys)
+		attachment.Title = "Step 2 of 5: Project"
+		attachment.Text = "Pick the Jira project whose events to post."
+		if len(keys) > setupMaxProjects {
+			keys = keys[:setupMaxProjects]
+			attachment.Text += fmt.Sprintf(" Only the first %d projects are listed, use `/jira setup <project-key>` to set up another one.", setupMaxProjects)
+		}
+		project := action(setupActionProject, "Select a project")
+		project.Type = model.POST_ACTION_TYPE_SELECT
+		for _, key := range keys {
+			project.Options = append(project.Options, &model.PostActionOptions{Text: key, Value: key})
+		}
+		attachment.Actions = []*model.PostAction{project, cancel}
+
+	case setupStepEvents:
+		attachment.Title = "Step 3 of 5: Events"
+		attachment.Text = fmt.Sprintf("Pick the events of %s to post. The subscription can be refined later with `/jira subscribe`.", session.ProjectKey)
+		events := action(setupActionEvents, "Select the events")
+		events.Type = model.POST_ACTION_TYPE_SELECT
+		for _, preset := range setupEventPresets {
+			events.Options = append(events.Options, &model.PostActionOptions{Text: preset.name, Value: preset.id})
+		}
+		attachment.Actions = []*model.PostAction{events, cancel}
+
+	case setupStepChannel:
+		preset, _ := findSetupEventPreset(session.Events)
+		attachment.Title = "Step 4 of 5: Channel"
+		attachment.Text = fmt.Sprintf("Choose the channel to post %s of %s to, and name the subscription.", strings.ToLower(preset.name), session.ProjectKey)
+		attachment.Actions = []*model.PostAction{action(setupActionChannel, "Choose the channel"), cancel}
+
+	case setupStepWebhook:
+		attachment.Title = "Step 5 of 5: Jira webhook"
+		attachment.Text = fmt.Sprintf("Jira sends the events to Mattermost through a webhook, which only Jira administrators can create. "+
+			"Create a webhook sending the events of %s, or skip this step if Jira already sends the events of all its projects to Mattermost.", session.ProjectKey)
+		attachment.Actions = []*model.PostAction{action(setupActionWebhook, "Create the Jira webhook"), action(setupActionSkip, "Skip")}
+
+	case setupStepDone:
+		attachment.Title = "Jira setup is complete"
+		attachment.Text = "Test the subscription with `/jira subscribe test " + session.ProjectKey + "`."
+		attachment.Actions = []*model.PostAction{action(setupActionRestart, "Set up another project")}
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{attachment})
+	return post, nil
+}
+
+// httpAPISetupAction handles the buttons and the selects of the setup post.
+// The channel step opens a dialog asking for the channel and the name of the
+// subscription.
+func httpAPISetupAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	setupId, _ := request.Context["setup_id"].(string)
+	action, _ := request.Context["action"].(string)
+
+	p := ji.GetPlugin()
+	response := model.PostActionIntegrationResponse{}
+	session, err := p.loadSetupSession(ji, setupId)
+	switch {
+	case err != nil:
+		response.EphemeralText = err.Error()
+	case session.UserId != mattermostUserId:
+		return http.StatusForbidden, errors.New("not the user of the setup")
+	default:
+		var text string
+		text, err = p.handleSetupAction(ji, session, action, request)
+		if err != nil {
+			text = err.Error()
+		}
+		response.EphemeralText = text
+	}
+
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// handleSetupAction applies an action to the setup, and returns the text to
+// respond to the user with, if any.
+func (p *Plugin) handleSetupAction(ji Instance, session *setupSession, action string, request *model.PostActionIntegrationRequest) (string, error) {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, session.UserId)
+	if err != nil {
+		return p.localize(p.userLocale(session.UserId), msgNotConnected), nil
+	}
+
+	switch action {
+	case setupActionContinue:
+		session.Step = setupStepProject
+
+	case setupActionProject:
+		projectKey, _ := request.Context["selected_option"].(string)
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return "", err
+		}
+		if _, err = client.GetProject(projectKey); err != nil {
+			return "", errors.WithMessagef(err, "failed to get project %s", projectKey)
+		}
+		session.ProjectKey = projectKey
+		session.Step = setupStepEvents
+
+	case setupActionEvents:
+		presetId, _ := request.Context["selected_option"].(string)
+		if _, ok := findSetupEventPreset(presetId); !ok {
+			return "", errors.Errorf("unknown events %q", presetId)
+		}
+		session.Events = presetId
+		session.Step = setupStepChannel
+
+	case setupActionChannel:
+		return "", p.openSetupChannelDialog(session, request.TriggerId)
+
+	case setupActionWebhook:
+		message := p.registerSetupWebhook(ji, jiraUser, session)
+		session.Done = append(session.Done, message)
+		session.Step = setupStepDone
+
+	case setupActionSkip:
+		session.Done = append(session.Done, fmt.Sprintf(":white_check_mark: %s is posted to ~%s by the subscription %q.",
+			session.ProjectKey, p.channelName(session.SubscriptionChannelId), session.SubscriptionName))
+		session.Step = setupStepDone
+
+	case setupActionRestart:
+		// The subscription of the project is kept, tell where it is
+		text := ""
+		if session.SubscriptionId != "" {
+			text = fmt.Sprintf("The subscription %q of %s is kept in ~%s. Use `/jira subscribe delete %s` in that channel if it is not needed.",
+				session.SubscriptionName, session.ProjectKey, p.channelName(session.SubscriptionChannelId), session.SubscriptionName)
+		}
+		session.ProjectKey = ""
+		session.Events = ""
+		session.SubscriptionId = ""
+		session.SubscriptionName = ""
+		session.SubscriptionChannelId = ""
+		session.Step = setupStepProject
+		return text, p.updateSetupSession(ji, jiraUser, session)
+
+	case setupActionCancel:
+		p.API.KVDelete(keyWithInstance(ji, prefixSetupSession+session.Id))
+		p.API.UpdateEphemeralPost(session.UserId, &model.Post{
+			Id:        session.PostId,
+			UserId:    p.getUserID(),
+			ChannelId: session.ChannelId,
+			Message:   "The Jira setup was cancelled.",
+		})
+		return "", nil
+
+	default:
+		return "", errors.Errorf("unknown action %q", action)
+	}
+	return "", p.updateSetupSession(ji, jiraUser, session)
+}
+
+func (p *Plugin) openSetupChannelDialog(session *setupSession, triggerId string) error {
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerId,
+		URL:       p.GetPluginURLPath() + routeAPISetupDialog,
+		Dialog: model.Dialog{
+			CallbackId:  session.Id,
+			Title:       "Post the events of " + session.ProjectKey,
+			SubmitLabel: "Subscribe",
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Channel",
+					Name:        "channel_id",
+					Type:        "select",
+					DataSource:  "channels",
+					Default:     session.ChannelId,
+				},
+				{
+					DisplayName: "Subscription name",
+					Name:        "name",
+					Type:        "text",
+					Default:     session.ProjectKey + " events",
+					MaxLength:   MAX_SUBSCRIPTION_NAME_LENGTH,
+				},
+			},
+		},
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// httpAPISetupDialog handles the submission of the channel dialog of the
+// setup, subscribing the channel to the events of the project.
+func httpAPISetupDialog(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the dialog submission")
+	}
+	if request.Cancelled {
+		return http.StatusOK, nil
+	}
+	channelId, _ := request.Submission["channel_id"].(string)
+	name, _ := request.Submission["name"].(string)
+	name = strings.TrimSpace(name)
+
+	p := ji.GetPlugin()
+	response := model.SubmitDialogResponse{}
+	session, err := p.loadSetupSession(ji, request.CallbackId)
+	switch {
+	case err != nil:
+		response.Error = err.Error()
+	case session.UserId != mattermostUserId:
+		return http.StatusForbidden, errors.New("not the user of the setup")
+	case session.Step != setupStepChannel:
+		response.Error = "The subscription of this project was already created."
+	default:
+		var field string
+		field, err = p.createSetupSubscription(ji, session, channelId, name)
+		if err != nil {
+			response.Errors = map[string]string{field: err.Error()}
+		}
+	}
+
+	if response.Error == "" && len(response.Errors) == 0 {
+		return http.StatusOK, nil
+	}
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// createSetupSubscription subscribes the channel to the events of the project
+// of the setup, for all its issue types, and moves the setup to the webhook
+// step. It returns the dialog field an error is about.
+func (p *Plugin) createSetupSubscription(ji Instance, session *setupSession, channelId, name string) (string, error) {
+	if _, appErr := p.API.GetChannelMember(channelId, session.UserId); appErr != nil {
+		return "channel_id", errors.New("You are not a member of this channel.")
+	}
+	if err := p.hasPermissionToManageSubscription(session.UserId, channelId); err != nil {
+		return "channel_id", errors.New("You are not allowed to manage the Jira subscriptions of this channel.")
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, session.UserId)
+	if err != nil {
+		return "channel_id", errors.New(p.localize(p.userLocale(session.UserId), msgNotConnected))
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return "channel_id", err
+	}
+	project, err := client.GetProject(session.ProjectKey)
+	if err != nil {
+		return "channel_id", errors.WithMessagef(err, "failed to get project %s", session.ProjectKey)
+	}
+	issueTypes := NewStringSet()
+	for _, issueType := range project.IssueTypes {
+		issueTypes = issueTypes.Add(issueType.ID)
+	}
+	preset, _ := findSetupEventPreset(session.Events)
+
+	sub := ChannelSubscription{
+		ChannelId: channelId,
+		Name:      name,
+		CreatorId: session.UserId,
+		Filters: SubscriptionFilters{
+			Events:     preset.events,
+			Projects:   NewStringSet(session.ProjectKey),
+			IssueTypes: issueTypes,
+		},
+	}
+	err = p.addChannelSubscription(&sub, client)
+	if err != nil {
+		return "name", err
+	}
+	p.announceChannelSubscription(sub, jiraUser.DisplayName)
+
+	session.SubscriptionId = sub.Id
+	session.SubscriptionName = sub.Name
+	session.SubscriptionChannelId = channelId
+	session.Step = setupStepWebhook
+	return "", p.updateSetupSession(ji, jiraUser, session)
+}
+
+// registerSetupWebhook creates the Jira webhook sending the events of the
+// project of the setup to the subscriptions, unless Jira already has one, and
+// returns the outcome. If Jira refuses, the webhook URL to set up by hand is
+// returned instead.
+func (p *Plugin) registerSetupWebhook(ji Instance, jiraUser JIRAUser, session *setupSession) string {
+	subscribed := fmt.Sprintf("%s is posted to ~%s by the subscription %q", session.ProjectKey,
+		p.channelName(session.SubscriptionChannelId), session.SubscriptionName)
+	secret, err := p.ensureInstanceWebhookSecret(ji, false)
+	if err != nil {
+		return fmt.Sprintf(":warning: %s, but the webhook secret failed to load: %v", subscribed, err)
+	}
+	webhookURL := p.instanceWebhookURL(secret)
+	jql := "project = " + session.ProjectKey
+
+	client, err := ji.GetClient(jiraUser)
+	if err == nil {
+		var webhooks []RegisteredWebhook
+		webhooks, err = client.GetWebhooks()
+		if err == nil {
+			if existing := findSetupWebhook(webhooks, webhookURL, jql); existing != nil {
+				return fmt.Sprintf(":white_check_mark: %s, and Jira already sends its events to Mattermost through the webhook %q.", subscribed, existing.Name)
+			}
+			_, err = client.RegisterWebhook(&RegisteredWebhook{
+				Name:    "Mattermost - " + session.ProjectKey,
+				URL:     webhookURL,
+				Events:  setupWebhookEvents,
+				Filters: map[string]string{"issue-related-events-section": jql},
+			})
+		}
+	}
+	if err != nil {
+		p.infof("registerSetupWebhook: failed to create the Jira webhook of %s: %v", session.ProjectKey, err)
+		return fmt.Sprintf(":warning: %s, but the Jira webhook could not be created: %v. "+
+			"Please ask a Jira administrator to create it in **Jira > System > WebHooks**, with the URL `%s`, "+
+			"the issue and comment events, and the JQL `%s`.", subscribed, err, webhookURL, jql)
+	}
+	return fmt.Sprintf(":white_check_mark: %s, and Jira sends its events to Mattermost.", subscribed)
+}
+
+// findSetupWebhook returns the webhook sending the events of the JQL query, or
+// of all the projects, to the webhook URL, or nil.
+func findSetupWebhook(webhooks []RegisteredWebhook, webhookURL, jql string) *RegisteredWebhook {
+	for i, webhook := range webhooks {
+		if webhook.URL != webhookURL {
+			continue
+		}
+		filter := strings.TrimSpace(webhook.Filters["issue-related-events-section"])
+		if filter == "" || strings.EqualFold(filter, jql) {
+			return &webhooks[i]
+		}
+	}
+	return nil
+}
+
+// channelName returns the name of a channel, or its ID if it failed to load.
+func (p *Plugin) channelName(channelId string) string {
+	if channel, appErr := p.API.GetChannel(channelId); appErr == nil {
+		return channel.Name
+	}
+	return channelId
+}
+
+func (p *Plugin) loadSetupSession(ji Instance, id string) (*setupSession, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, prefixSetupSession+id))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, errors.New("this setup has expired, please run `/jira setup` again")
+	}
+	session := &setupSession{}
+	err := json.Unmarshal(data, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (p *Plugin) storeSetupSession(ji Instance, session *setupSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSetWithExpiry(keyWithInstance(ji, prefixSetupSession+session.Id), data, setupSessionExpirySeconds)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// updateSetupSession stores the setup and re-renders its post.
+func (p *Plugin) updateSetupSession(ji Instance, jiraUser JIRAUser, session *setupSession) error {
+	post, err := p.setupPost(ji, jiraUser, session)
+	if err != nil {
+		return err
+	}
+	err = p.storeSetupSession(ji, session)
+	if err != nil {
+		return err
+	}
+	p.API.UpdateEphemeralPost(session.UserId, post)
+	return nil
+}
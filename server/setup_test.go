@@ -0,0 +1,130 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupActionNames(post *model.Post) []string {
+	names := []string{}
+	for _, attachment := range post.Attachments() {
+		for _, action := range attachment.Actions {
+			names = append(names, action.Name)
+		}
+	}
+	return names
+}
+
+func TestSetupPost(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	api.On("GetChannel", "subchannelid").Return(&model.Channel{Id: "subchannelid", Name: "town-square"}, nil)
+	p.SetAPI(api)
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+	session := &setupSession{Id: "setupid", UserId: "userid", ChannelId: "channelid", PostId: "postid", Step: setupStepInstance}
+
+	post, err := p.setupPost(ji, JIRAUser{}, session)
+	require.NoError(t, err)
+	assert.Equal(t, "postid", post.Id)
+	assert.Equal(t, []string{"Continue", "Cancel"}, setupActionNames(post))
+	assert.Contains(t, post.Attachments()[0].Text, mockCurrentInstanceURL)
+
+	session.Step = setupStepEvents
+	session.ProjectKey = "TEST"
+	post, err = p.setupPost(ji, JIRAUser{}, session)
+	require.NoError(t, err)
+	require.Len(t, post.Attachments()[0].Actions, 2)
+	assert.Len(t, post.Attachments()[0].Actions[0].Options, len(setupEventPresets))
+
+	session.Step = setupStepWebhook
+	post, err = p.setupPost(ji, JIRAUser{}, session)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Create the Jira webhook", "Skip"}, setupActionNames(post))
+
+	session.Done = []string{"TEST is set up"}
+	session.Step = setupStepDone
+	post, err = p.setupPost(ji, JIRAUser{}, session)
+	require.NoError(t, err)
+	assert.Contains(t, post.Message, "TEST is set up")
+	assert.Equal(t, []string{"Set up another project"}, setupActionNames(post))
+}
+
+func TestHandleSetupAction(t *testing.T) {
+	p := &Plugin{userStore: mockUserStore{}}
+	api := &plugintest.API{}
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(setupSessionExpirySeconds)).Return(nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetChannel", "subchannelid").Return(&model.Channel{Id: "subchannelid", Name: "town-square"}, nil)
+	var updated *model.Post
+	api.On("UpdateEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		updated = args.Get(1).(*model.Post)
+	}).Return(nil)
+	p.SetAPI(api)
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+	session := &setupSession{Id: "setupid", UserId: "userid", ChannelId: "channelid", PostId: "postid", Step: setupStepEvents, ProjectKey: "TEST"}
+
+	selected := func(value string) *model.PostActionIntegrationRequest {
+		return &model.PostActionIntegrationRequest{Context: map[string]interface{}{"selected_option": value}}
+	}
+
+	_, err := p.handleSetupAction(ji, session, setupActionEvents, selected("unknown"))
+	assert.Error(t, err)
+	assert.Equal(t, setupStepEvents, session.Step)
+
+	_, err = p.handleSetupAction(ji, session, setupActionEvents, selected("workflow"))
+	require.NoError(t, err)
+	assert.Equal(t, setupStepChannel, session.Step)
+	assert.Equal(t, "workflow", session.Events)
+	require.NotNil(t, updated)
+	assert.Equal(t, []string{"Choose the channel", "Cancel"}, setupActionNames(updated))
+
+	session.Step = setupStepWebhook
+	session.SubscriptionChannelId = "subchannelid"
+	session.SubscriptionName = "TEST events"
+	_, err = p.handleSetupAction(ji, session, setupActionSkip, selected(""))
+	require.NoError(t, err)
+	assert.Equal(t, setupStepDone, session.Step)
+	assert.Equal(t, []string{`:white_check_mark: TEST is posted to ~town-square by the subscription "TEST events".`}, session.Done)
+
+	session.SubscriptionId = "subid"
+	text, err := p.handleSetupAction(ji, session, setupActionRestart, selected(""))
+	require.NoError(t, err)
+	assert.Equal(t, "The subscription \"TEST events\" of TEST is kept in ~town-square. Use `/jira subscribe delete TEST events` in that channel if it is not needed.", text)
+	assert.Empty(t, session.SubscriptionId)
+	assert.Equal(t, setupStepProject, session.Step)
+	assert.Empty(t, session.ProjectKey)
+	assert.Len(t, session.Done, 1)
+	options := updated.Attachments()[0].Actions[0].Options
+	require.Len(t, options, 2)
+	assert.Equal(t, "ABC", options[0].Value)
+
+	_, err = p.handleSetupAction(ji, session, setupActionCancel, selected(""))
+	require.NoError(t, err)
+	assert.Equal(t, "The Jira setup was cancelled.", updated.Message)
+	api.AssertCalled(t, "KVDelete", keyWithMockInstance(prefixSetupSession+"setupid"))
+}
+
+func TestFindSetupWebhook(t *testing.T) {
+	const webhookURL = "https://mattermost.example.com/plugins/jira/api/v2/webhook?secret=abc"
+	webhooks := []RegisteredWebhook{
+		{Name: "Other URL", URL: "https://other.example.com/webhook"},
+		{Name: "Other project", URL: webhookURL, Filters: map[string]string{"issue-related-events-section": "project = ABC"}},
+	}
+	assert.Nil(t, findSetupWebhook(webhooks, webhookURL, "project = TEST"))
+
+	webhooks = append(webhooks, RegisteredWebhook{Name: "Project", URL: webhookURL, Filters: map[string]string{"issue-related-events-section": "PROJECT = TEST "}})
+	require.NotNil(t, findSetupWebhook(webhooks, webhookURL, "project = TEST"))
+	assert.Equal(t, "Project", findSetupWebhook(webhooks, webhookURL, "project = TEST").Name)
+
+	global := []RegisteredWebhook{{Name: "All projects", URL: webhookURL}}
+	require.NotNil(t, findSetupWebhook(global, webhookURL, "project = TEST"))
+	assert.Equal(t, "All projects", findSetupWebhook(global, webhookURL, "project = TEST").Name)
+}
@@ -19,6 +19,7 @@ import (
 const statsKeyExpiration = 30 * 24 * time.Hour // 30 days
 const statsAutosaveInterval = 10 * time.Minute
 const statsAutosaveMaxDither = 60 // seconds
+const metricsPrefix = "mattermost_plugin_jira"
 
 var initStatsOnce sync.Once
 
@@ -55,16 +56,9 @@ func httpAPIStats(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error
 	}
 	conf := p.getConfig()
 
-	isAdmin, err := authorizedSysAdmin(p, r.Header.Get("Mattermost-User-Id"))
-	if !isAdmin {
-		if conf.StatsSecret == "" {
-			return http.StatusForbidden, errors.New("Access forbidden: must be authenticated as an admin, or provide the stats API secret.")
-		}
-		var status int
-		status, err = verifyHTTPSecret(conf.StatsSecret, r.FormValue("secret"))
-		if err != nil {
-			return status, err
-		}
+	status, err := authorizeStatsRequest(p, conf, r)
+	if err != nil {
+		return status, err
 	}
 	if conf.stats == nil {
 		return http.StatusNotFound, errors.New("No stats available")
@@ -88,6 +82,52 @@ func httpAPIStats(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error
 	return http.StatusOK, nil
 }
 
+// httpAPIMetrics serves the stats, and the state of the webhook queue, in the
+// Prometheus text exposition format.
+func httpAPIMetrics(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET")
+	}
+	conf := p.getConfig()
+
+	status, err := authorizeStatsRequest(p, conf, r)
+	if err != nil {
+		return status, err
+	}
+	if conf.stats == nil {
+		return http.StatusNotFound, errors.New("No stats available")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	err = conf.stats.WritePrometheus(w, metricsPrefix)
+	if err == nil {
+		err = expvar.WritePrometheusGauge(w, metricsPrefix+"_webhook_queue_depth",
+			"Number of webhook events waiting to be processed.", float64(p.webhookQueues.len()))
+	}
+	if err == nil {
+		err = expvar.WritePrometheusGauge(w, metricsPrefix+"_webhook_queue_capacity",
+			"Maximum number of webhook events waiting to be processed.", float64(p.webhookQueues.cap()))
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// authorizeStatsRequest allows system admins, and requests with the stats API
+// secret, if one is configured.
+func authorizeStatsRequest(p *Plugin, conf config, r *http.Request) (int, error) {
+	isAdmin, _ := authorizedSysAdmin(p, r.Header.Get("Mattermost-User-Id"))
+	if isAdmin {
+		return http.StatusOK, nil
+	}
+	if conf.StatsSecret == "" {
+		return http.StatusForbidden, errors.New("Access forbidden: must be authenticated as an admin, or provide the stats API secret.")
+	}
+	return verifyHTTPSecret(conf.StatsSecret, r.FormValue("secret"))
+}
+
 func (p *Plugin) startAutosaveStats() {
 	stop := make(chan bool)
 	go func() {
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,17 +33,82 @@ const (
 	MAX_SUBSCRIPTION_NAME_LENGTH = 100
 )
 
+// The subscription listings return at most this many subscriptions per page,
+// and their total count in the X-Total-Count header.
+const (
+	maxSubscriptionsPerPage = 200
+	headerTotalCount        = "X-Total-Count"
+)
+
 type FieldFilter struct {
 	Key       string    `json:"key"`
 	Inclusion string    `json:"inclusion"`
 	Values    StringSet `json:"values"`
 }
 
+// Policies for comments whose visibility is restricted to a Jira role or group
+const (
+	restrictedCommentsSkip    = "skip"
+	restrictedCommentsPrivate = "private"
+	restrictedCommentsStub    = "stub"
+)
+
+var restrictedCommentsPolicies = NewStringSet(
+	restrictedCommentsSkip,
+	restrictedCommentsPrivate,
+	restrictedCommentsStub,
+)
+
 type SubscriptionFilters struct {
 	Events     StringSet     `json:"events"`
 	Projects   StringSet     `json:"projects"`
 	IssueTypes StringSet     `json:"issue_types"`
 	Fields     []FieldFilter `json:"fields"`
+
+	// IssueTypeNames are the names of the IssueTypes by ID, keeping the
+	// filters readable, and matching on the instances where the IDs differ.
+	IssueTypeNames map[string]string `json:"issue_type_names,omitempty"`
+
+	// ParentKeys restricts the subscription to the listed issues, and to
+	// their sub-tasks or the issues of the listed epics.
+	ParentKeys StringSet `json:"parent_keys,omitempty"`
+
+	// EpicLinkField is the ID of the Epic Link custom field of the instance
+	// when ParentKeys was set, holding the epics of the issues on Jira
+	// Server.
+	EpicLinkField string `json:"epic_link_field,omitempty"`
+
+	// ExcludeSubtasks skips the events of sub-tasks.
+	ExcludeSubtasks bool `json:"exclude_subtasks,omitempty"`
+
+	// IgnoredActors skips the events triggered by these Jira users, in
+	// addition to those of the IgnoredActors setting.
+	IgnoredActors []string `json:"ignored_actors,omitempty"`
+
+	// UpdatesDigest rolls the events other than issue creations and
+	// deletions into an hourly digest of the channel.
+	UpdatesDigest bool `json:"updates_digest,omitempty"`
+
+	// HideAuthors shows the authors of the events and comments as "A Jira
+	// user" in public channels.
+	HideAuthors bool `json:"hide_authors,omitempty"`
+
+	// RestrictedComments is the policy for restricted comments. Empty
+	// is the same as restrictedCommentsSkip.
+	RestrictedComments string `json:"restricted_comments,omitempty"`
+
+	// PropertyKey restricts the issue property events to the properties
+	// with this key, and PropertyValues to those set to one of the values.
+	PropertyKey    string    `json:"property_key,omitempty"`
+	PropertyValues StringSet `json:"property_values,omitempty"`
+
+	// Changes restricts the issue updated events to those changing a field
+	// as one of the filters, e.g. the status to Done.
+	Changes []ChangeFilter `json:"changes,omitempty"`
+
+	// JQL restricts the subscription to the issues matching the query, run
+	// with the Jira account of the subscription creator.
+	JQL string `json:"jql,omitempty"`
 }
 
 type ChannelSubscription struct {
@@ -49,12 +116,65 @@ type ChannelSubscription struct {
 	ChannelId string              `json:"channel_id"`
 	Filters   SubscriptionFilters `json:"filters"`
 	Name      string              `json:"name"`
+
+	// CreatorId is the Mattermost user who created the subscription. Polled
+	// subscriptions query Jira with this user's credentials.
+	CreatorId string `json:"creator_id,omitempty"`
+
+	// FilterId references a saved Jira filter. Filter subscriptions are not
+	// matched against webhook events; instead the filter is evaluated
+	// periodically and newly matching issues are posted to the channel.
+	FilterId string `json:"filter_id,omitempty"`
+
+	// IssueKey restricts the subscription to a single issue. All events for
+	// that issue are posted to the channel, regardless of Filters.
+	IssueKey string `json:"issue_key,omitempty"`
+
+	// RootId, set only together with IssueKey, posts the issue's events as
+	// replies in a thread instead of to the channel.
+	RootId string `json:"root_id,omitempty"`
+
+	// ProjectEvents subscribes the channel to project lifecycle events
+	// (project created or deleted) instead of issue events.
+	ProjectEvents bool `json:"project_events,omitempty"`
+
+	// ProjectDeleted is set when the project the subscription filters on
+	// was deleted in Jira, so that admins can find and fix the subscription.
+	ProjectDeleted bool `json:"project_deleted,omitempty"`
+
+	// NonCompliant is the reason the subscription breaks the project channel
+	// restrictions, since its channel was converted or moved to another
+	// team. Non-compliant subscriptions don't post events.
+	NonCompliant string `json:"non_compliant,omitempty"`
+
+	// Paused is set while the channel of the subscription is archived. Paused
+	// subscriptions don't post events, and resume when the channel is
+	// restored.
+	Paused bool `json:"paused,omitempty"`
+
+	// MentionRules add mentions to the posts of the events of issues with
+	// some priorities or labels.
+	MentionRules []MentionRule `json:"mention_rules,omitempty"`
+
+	// Forward is where the events matched by the subscription are also
+	// forwarded, like an outgoing webhook.
+	Forward *ForwardTarget `json:"forward,omitempty"`
+
+	// Version is incremented on every change. An edit must be based on the
+	// current version, so that concurrent edits don't overwrite each other.
+	Version int `json:"version"`
 }
 
+var ErrSubscriptionVersionConflict = errors.New("The subscription was modified by someone else. Please reload it and try again.")
+
 type ChannelSubscriptions struct {
 	ById          map[string]ChannelSubscription `json:"by_id"`
 	IdByChannelId map[string]StringSet           `json:"id_by_channel_id"`
 	IdByEvent     map[string]StringSet           `json:"id_by_event"`
+
+	// FirstMatchChannelIds are the channels where only the first matching
+	// subscription, by name, applies to an event.
+	FirstMatchChannelIds StringSet `json:"first_match_channel_ids,omitempty"`
 }
 
 func NewChannelSubscriptions() *ChannelSubscriptions {
@@ -114,76 +234,104 @@ func (p *Plugin) getUserID() string {
 	return p.getConfig().botUserID
 }
 
-func (p *Plugin) matchesSubsciptionFilters(wh *webhook, filters SubscriptionFilters) bool {
-	webhookEvents := wh.Events()
-	foundEvent := false
-	eventTypes := filters.Events
-	if eventTypes.Intersection(webhookEvents).Len() > 0 {
-		foundEvent = true
-	} else if eventTypes.ContainsAny(eventUpdatedAny) {
-		for _, eventType := range webhookEvents.Elems() {
-			if strings.HasPrefix(eventType, "event_updated") {
-				foundEvent = true
-			}
-		}
-	}
+func (p *Plugin) getChannelsSubscribed(wh *webhook) (StringSet, error) {
+	channelIds, _, err := p.getChannelsSubscribedWithStubs(wh)
+	return channelIds, err
+}
 
-	if !foundEvent {
-		return false
+// getChannelsSubscribedWithStubs returns the channels the webhook is posted
+// to, and separately those that only get a stub of a restricted comment.
+func (p *Plugin) getChannelsSubscribedWithStubs(wh *webhook) (StringSet, StringSet, error) {
+	subscriptionIds, stubSubscriptionIds, err := p.getChannelSubscriptionIds(wh)
+	if err != nil {
+		return nil, nil, err
 	}
+	return channelIdSet(subscriptionIds), channelIdSet(stubSubscriptionIds), nil
+}
 
-	if filters.IssueTypes.Len() != 0 && !filters.IssueTypes.ContainsAny(wh.JiraWebhook.Issue.Fields.Type.ID) {
-		return false
+// channelIdSet returns the channels of the subscription IDs by channel.
+func channelIdSet(subscriptionIds map[string]string) StringSet {
+	channelIds := NewStringSet()
+	for channelId := range subscriptionIds {
+		channelIds = channelIds.Add(channelId)
 	}
+	return channelIds
+}
 
-	if filters.Projects.Len() != 0 && !filters.Projects.ContainsAny(wh.JiraWebhook.Issue.Fields.Project.Key) {
-		return false
+// getChannelSubscriptionIds returns the subscriptions the webhook is posted
+// for, by channel, and separately those that only post a stub of a
+// restricted comment. A channel with several matching subscriptions is
+// mapped to the first one by name.
+func (p *Plugin) getChannelSubscriptionIds(wh *webhook) (map[string]string, map[string]string, error) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	validFilter := true
-
-	for _, field := range filters.Fields {
-		// Broken filter, values must be provided
-		if field.Inclusion == "" || (field.Values.Len() == 0 && field.Inclusion != FILTER_EMPTY) {
-			validFilter = false
-			break
+	subscriptionIds := map[string]string{}
+	stubSubscriptionIds := map[string]string{}
+	add := func(ids map[string]string, sub ChannelSubscription) {
+		if _, ok := ids[sub.ChannelId]; !ok {
+			ids[sub.ChannelId] = sub.Id
+		}
+	}
+	for _, sub := range p.matchingChannelSubscriptions(subs, wh) {
+		if sub.ProjectEvents {
+			add(subscriptionIds, sub)
+			continue
 		}
 
-		value := getIssueFieldValue(&wh.JiraWebhook.Issue, field.Key)
-		containsAny := value.ContainsAny(field.Values.Elems()...)
-		containsAll := value.ContainsAll(field.Values.Elems()...)
-
-		if (field.Inclusion == FILTER_INCLUDE_ANY && !containsAny) ||
-			(field.Inclusion == FILTER_INCLUDE_ALL && !containsAll) ||
-			(field.Inclusion == FILTER_EXCLUDE_ANY && containsAny) ||
-			(field.Inclusion == FILTER_EMPTY && value.Len() > 0) {
-			validFilter = false
-			break
+		switch p.restrictedCommentAction(wh, sub) {
+		case restrictedCommentsStub:
+			add(stubSubscriptionIds, sub)
+		case "":
+			add(subscriptionIds, sub)
 		}
 	}
 
-	if !validFilter {
-		return false
+	// A channel with several matching subscriptions gets the full post if
+	// any of them allows it.
+	for channelId := range subscriptionIds {
+		delete(stubSubscriptionIds, channelId)
 	}
-
-	return true
+	return subscriptionIds, stubSubscriptionIds, nil
 }
 
-func (p *Plugin) getChannelsSubscribed(wh *webhook) (StringSet, error) {
-	subs, err := p.getSubscriptions()
-	if err != nil {
-		return nil, err
+// matchesChannelSubscription returns true if the webhook is posted to the
+// channel of the subscription. Filter subscriptions and thread subscriptions
+// are handled separately, and never match.
+func (p *Plugin) matchesChannelSubscription(wh *webhook, sub ChannelSubscription, isProjectEvent bool) bool {
+	switch {
+	case sub.NonCompliant != "", sub.Paused, p.isIgnoredActor(wh, sub):
+		return false
+	case sub.ProjectEvents:
+		return isProjectEvent
+	case isProjectEvent || sub.FilterId != "":
+		return false
+	case sub.IssueKey != "":
+		return sub.RootId == "" && sub.IssueKey == wh.JiraWebhook.Issue.Key
 	}
+	return p.matchesSubscription(wh, &sub)
+}
 
-	channelIds := NewStringSet()
-	subIds := subs.Channel.ById
-	for _, sub := range subIds {
-		if p.matchesSubsciptionFilters(wh, sub.Filters) {
-			channelIds = channelIds.Add(sub.ChannelId)
+// restrictedCommentAction returns "" if the webhook should be posted for the
+// subscription as is, restrictedCommentsStub if only a stub should be posted,
+// or restrictedCommentsSkip if nothing should be posted.
+func (p *Plugin) restrictedCommentAction(wh *webhook, sub ChannelSubscription) string {
+	if !wh.isRestrictedComment() {
+		return ""
+	}
+
+	switch sub.Filters.RestrictedComments {
+	case restrictedCommentsStub:
+		return restrictedCommentsStub
+	case restrictedCommentsPrivate:
+		channel, appErr := p.API.GetChannel(sub.ChannelId)
+		if appErr == nil && channel.Type == model.CHANNEL_PRIVATE {
+			return ""
 		}
 	}
-
-	return channelIds, nil
+	return restrictedCommentsSkip
 }
 
 func (p *Plugin) getSubscriptions() (*Subscriptions, error) {
@@ -193,9 +341,9 @@ func (p *Plugin) getSubscriptions() (*Subscriptions, error) {
 	}
 
 	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
-	data, appErr := p.API.KVGet(subKey)
-	if appErr != nil {
-		return nil, appErr
+	_, data, err := p.kvGetSharded(subKey)
+	if err != nil {
+		return nil, err
 	}
 	return SubscriptionsFromJson(data)
 }
@@ -234,8 +382,9 @@ func (p *Plugin) removeChannelSubscription(subscriptionId string) error {
 		return err
 	}
 
+	var removed ChannelSubscription
 	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
-	return p.atomicModify(subKey, func(initialBytes []byte) ([]byte, error) {
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
 		subs, err := SubscriptionsFromJson(initialBytes)
 		if err != nil {
 			return nil, err
@@ -247,6 +396,7 @@ func (p *Plugin) removeChannelSubscription(subscriptionId string) error {
 		}
 
 		subs.Channel.remove(&subscription)
+		removed = subscription
 
 		modifiedBytes, marshalErr := json.Marshal(&subs)
 		if marshalErr != nil {
@@ -255,6 +405,14 @@ func (p *Plugin) removeChannelSubscription(subscriptionId string) error {
 
 		return modifiedBytes, nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if removed.FilterId != "" {
+		_ = p.API.KVDelete(keyWithInstance(ji, prefixFilterSubscription+removed.Id))
+	}
+	return nil
 }
 
 func (p *Plugin) addChannelSubscription(newSubscription *ChannelSubscription, client Client) error {
@@ -264,7 +422,7 @@ func (p *Plugin) addChannelSubscription(newSubscription *ChannelSubscription, cl
 	}
 
 	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
-	return p.atomicModify(subKey, func(initialBytes []byte) ([]byte, error) {
+	return p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
 		subs, err := SubscriptionsFromJson(initialBytes)
 		if err != nil {
 			return nil, err
@@ -276,6 +434,7 @@ func (p *Plugin) addChannelSubscription(newSubscription *ChannelSubscription, cl
 		}
 
 		newSubscription.Id = model.NewId()
+		newSubscription.Version = 1
 		subs.Channel.add(newSubscription)
 
 		modifiedBytes, marshalErr := json.Marshal(&subs)
@@ -296,6 +455,38 @@ func (p *Plugin) validateSubscription(subscription *ChannelSubscription, client
 		return errors.Errorf("Please provide a name less than %d characters.", MAX_SUBSCRIPTION_NAME_LENGTH)
 	}
 
+	for _, rule := range subscription.MentionRules {
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := p.validateProjectChannelRestrictions(subscription)
+	if err != nil {
+		return err
+	}
+
+	if subscription.FilterId != "" {
+		return p.validateFilterSubscription(subscription, client)
+	}
+
+	if subscription.ProjectEvents {
+		return p.validateSubscriptionName(subscription)
+	}
+
+	if subscription.IssueKey != "" {
+		err := p.validateSubscriptionName(subscription)
+		if err != nil {
+			return err
+		}
+		_, err = client.GetIssue(subscription.IssueKey, nil)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get issue %q", subscription.IssueKey)
+		}
+		return nil
+	}
+
 	if len(subscription.Filters.Events) == 0 {
 		return errors.New("Please provide at least one event type.")
 	}
@@ -308,24 +499,57 @@ func (p *Plugin) validateSubscription(subscription *ChannelSubscription, client
 		return errors.New("Please provide a project identifier.")
 	}
 
-	channelId := subscription.ChannelId
-	subs, err := p.getSubscriptionsForChannel(channelId)
-	if err != nil {
-		return err
+	for _, field := range subscription.Filters.Fields {
+		if isRawFieldSelector(field.Key) {
+			if _, err := utils.SelectJSON(nil, field.Key); err != nil {
+				return err
+			}
+		}
 	}
 
-	for subID := range subs {
-		if subs[subID].Name == subscription.Name && subs[subID].Id != subscription.Id {
-			return errors.Errorf("Subscription name, '%s', already exists. Please choose another name.", subs[subID].Name)
+	if subscription.Filters.JQL != "" {
+		err = validateJQL(client, subscription.Filters.JQL)
+		if err != nil {
+			return err
 		}
+		_, err = client.SearchIssues(subscription.Filters.JQL, &jira.SearchOptions{MaxResults: 1, Fields: []string{"summary"}})
+		if err != nil {
+			return errors.WithMessage(err, "failed to run the JQL filter")
+		}
+	}
+
+	err = p.validateSubscriptionName(subscription)
+	if err != nil {
+		return err
 	}
 
 	projectKey := subscription.Filters.Projects.Elems()[0]
-	_, err = client.GetProject(projectKey)
+	project, err := client.GetProject(projectKey)
 	if err != nil {
 		return errors.WithMessagef(err, "failed to get project %q", projectKey)
 	}
+	projects := []*jira.Project{project}
+	for _, key := range subscription.Filters.Projects.Elems()[1:] {
+		project, err = client.GetProject(key)
+		if err == nil {
+			projects = append(projects, project)
+		}
+	}
+
+	return subscription.Filters.normalizeIssueTypes(projects)
+}
+
+func (p *Plugin) validateSubscriptionName(subscription *ChannelSubscription) error {
+	subs, err := p.getSubscriptionsForChannel(subscription.ChannelId)
+	if err != nil {
+		return err
+	}
 
+	for subID := range subs {
+		if subs[subID].Name == subscription.Name && subs[subID].Id != subscription.Id {
+			return errors.Errorf("Subscription name, '%s', already exists. Please choose another name.", subs[subID].Name)
+		}
+	}
 	return nil
 }
 
@@ -336,7 +560,7 @@ func (p *Plugin) editChannelSubscription(modifiedSubscription *ChannelSubscripti
 	}
 
 	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
-	return p.atomicModify(subKey, func(initialBytes []byte) ([]byte, error) {
+	return p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
 		subs, err := SubscriptionsFromJson(initialBytes)
 		if err != nil {
 			return nil, err
@@ -347,11 +571,36 @@ func (p *Plugin) editChannelSubscription(modifiedSubscription *ChannelSubscripti
 			return nil, errors.New("Existing subscription does not exist.")
 		}
 
+		if modifiedSubscription.Version != oldSub.Version {
+			return nil, ErrSubscriptionVersionConflict
+		}
+
+		if modifiedSubscription.CreatorId == "" {
+			modifiedSubscription.CreatorId = oldSub.CreatorId
+		}
+
+		// The subscription modal doesn't edit the mention rules
+		if modifiedSubscription.MentionRules == nil {
+			modifiedSubscription.MentionRules = oldSub.MentionRules
+		}
+		// Nor the forward target
+		if modifiedSubscription.Forward == nil {
+			modifiedSubscription.Forward = oldSub.Forward
+		} else if modifiedSubscription.Forward.URL == "" {
+			modifiedSubscription.Forward = nil
+		}
+
+		// Saving the subscription validates it against the project channel
+		// restrictions again
+		modifiedSubscription.NonCompliant = ""
+		modifiedSubscription.Paused = oldSub.Paused
+
 		err = p.validateSubscription(modifiedSubscription, client)
 		if err != nil {
 			return nil, err
 		}
 
+		modifiedSubscription.Version = oldSub.Version + 1
 		subs.Channel.remove(&oldSub)
 		subs.Channel.add(modifiedSubscription)
 
@@ -375,7 +624,13 @@ type SubsGroupedByChannel struct {
 	SubIds    []string
 }
 
-func (p *Plugin) listChannelSubscriptions(teamId string) (string, error) {
+// listChannelSubscriptions lists the subscriptions of all channels, with the
+// number of their open issues from counts, by subscription ID, if any.
+func (p *Plugin) listChannelSubscriptions(teamId string, counts map[string]int) (string, error) {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return "", err
+	}
 	subs, err := p.getSubscriptions()
 	if err != nil {
 		return "", err
@@ -421,6 +676,33 @@ func (p *Plugin) listChannelSubscriptions(teamId string) (string, error) {
 				if sub.Name != "" {
 					subName = sub.Name
 				}
+				if count, ok := counts[sub.Id]; ok {
+					subName += fmt.Sprintf(" `%d open`", count)
+				}
+				if sub.FilterId != "" {
+					rows = append(rows, fmt.Sprintf("  * Filter %s - %s", sub.FilterId, subName))
+					continue
+				}
+				if sub.IssueKey != "" {
+					rows = append(rows, fmt.Sprintf("  * Issue %s - %s", sub.IssueKey, subName))
+					continue
+				}
+				if sub.ProjectEvents {
+					rows = append(rows, fmt.Sprintf("  * Project events - %s", subName))
+					continue
+				}
+				if sub.ProjectDeleted {
+					subName += " (project deleted)"
+				}
+				if sub.NonCompliant != "" {
+					subName += " (non-compliant: " + sub.NonCompliant + ")"
+				}
+				if sub.Paused {
+					subName += " (paused, the channel is archived)"
+				}
+				if p.jqlCreatorDisconnected(ji, &sub) {
+					subName += " (posts nothing, its creator is not connected to Jira for the JQL filter)"
+				}
 				rows = append(rows, fmt.Sprintf("  * %s - %s", sub.Filters.Projects.Elems()[0], subName))
 
 			}
@@ -562,6 +844,11 @@ func inAllowedGroup(inGroups []*jira.UserGroup, allowedGroups []string) bool {
 func (p *Plugin) hasPermissionToManageSubscription(userId, channelId string) error {
 	cfg := p.getConfig()
 
+	err := p.requireNotGuest(userId)
+	if err != nil {
+		return err
+	}
+
 	switch cfg.RolesAllowedToEditJiraSubscriptions {
 	case "team_admin":
 		if !p.API.HasPermissionToChannel(userId, channelId, model.PERMISSION_MANAGE_TEAM) {
@@ -685,11 +972,11 @@ func httpSubscribeWebhook(p *Plugin, w http.ResponseWriter, r *http.Request) (st
 		return http.StatusMethodNotAllowed,
 			fmt.Errorf("Request: " + r.Method + " is not allowed, must be POST")
 	}
-	if conf.Secret == "" {
-		return http.StatusForbidden, fmt.Errorf("JIRA plugin not configured correctly; must provide Secret")
-	}
-	status, err = verifyHTTPSecret(conf.Secret, r.FormValue("secret"))
+	instanceId, status, err := p.resolveWebhookInstance(r.FormValue("secret"))
 	if err != nil {
+		if status == http.StatusForbidden {
+			p.recordWebhookAuthFailure()
+		}
 		return status, err
 	}
 
@@ -701,12 +988,19 @@ func httpSubscribeWebhook(p *Plugin, w http.ResponseWriter, r *http.Request) (st
 
 	// If there is space in the queue, immediately return a 200; we will process the webhook event async.
 	// If the queue is full, return a 503; we will not process that webhook event.
-	select {
-	case p.webhookQueue <- bb:
+	msg := webhookMessage{instanceId: instanceId, deliveryId: webhookDeliveryId(r), data: bb}
+	if err = p.storeWebhookPayload(start, msg); err != nil {
+		p.errorf("httpSubscribeWebhook: failed to keep the webhook event for replays: %v", err)
+	}
+	if p.isStaleWebhookEvent(bb, start) {
 		return http.StatusOK, nil
-	default:
+	}
+
+	if !p.webhookQueues.enqueue(msg) {
+		p.alertAdmins(alertWebhookQueueFull, msgAlertWebhookQueueFull)
 		return http.StatusServiceUnavailable, nil
 	}
+	return http.StatusOK, nil
 }
 
 func httpChannelCreateSubscription(p *Plugin, w http.ResponseWriter, r *http.Request, mattermostUserId string) (int, error) {
@@ -731,6 +1025,10 @@ func httpChannelCreateSubscription(p *Plugin, w http.ResponseWriter, r *http.Req
 		return http.StatusForbidden, errors.Wrap(err, "you don't have permission to manage subscriptions")
 	}
 
+	if err = p.checkSubscriptionForward(&subscription); err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "can't forward the events")
+	}
+
 	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
 		return http.StatusInternalServerError, err
@@ -746,27 +1044,46 @@ func httpChannelCreateSubscription(p *Plugin, w http.ResponseWriter, r *http.Req
 		return http.StatusInternalServerError, err
 	}
 
+	subscription.CreatorId = mattermostUserId
 	err = p.addChannelSubscription(&subscription, client)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
+	warnings, err := p.subscriptionConflictWarnings(&subscription)
+	if err != nil {
+		p.API.LogWarn("Failed to check the overlapping subscriptions", "error", err.Error())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	b, _ := json.Marshal(&subscription)
+	b, _ := json.Marshal(channelSubscriptionResponse{&subscription, warnings})
 	_, err = w.Write(b)
 	if err != nil {
 		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
 	}
 
+	p.announceChannelSubscription(subscription, jiraUser.DisplayName)
+
+	return http.StatusOK, nil
+}
+
+// announceChannelSubscription posts to the channel of a new subscription who
+// added it.
+func (p *Plugin) announceChannelSubscription(subscription ChannelSubscription, displayName string) {
+	t := p.localizer(p.channelLocale(subscription.ChannelId))
 	post := &model.Post{
 		UserId:    p.getConfig().botUserID,
 		ChannelId: subscription.ChannelId,
-		Message:   fmt.Sprintf("Jira subscription, \"%v\", was added to this channel by %v", subscription.Name, jiraUser.DisplayName),
+		Message:   t(msgSubscriptionAdded, subscription.Name, displayName),
+	}
+	// Offer to start the channel with the open issues of the subscription
+	if action := p.backfillAction(subscription, t); action != nil {
+		post.AddProp("attachments", []*model.SlackAttachment{{
+			Actions: []*model.PostAction{action},
+		}})
 	}
 
 	p.API.CreatePost(post)
-
-	return http.StatusOK, nil
 }
 
 func httpChannelEditSubscription(p *Plugin, w http.ResponseWriter, r *http.Request, mattermostUserId string) (int, error) {
@@ -791,6 +1108,10 @@ func httpChannelEditSubscription(p *Plugin, w http.ResponseWriter, r *http.Reque
 		return http.StatusForbidden, errors.New("Not a member of the channel specified")
 	}
 
+	if err = p.checkSubscriptionForward(&subscription); err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "can't forward the events")
+	}
+
 	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 	if err != nil {
 		return http.StatusInternalServerError, err
@@ -807,12 +1128,20 @@ func httpChannelEditSubscription(p *Plugin, w http.ResponseWriter, r *http.Reque
 	}
 
 	err = p.editChannelSubscription(&subscription, client)
+	if errors.Cause(err) == ErrSubscriptionVersionConflict {
+		return http.StatusConflict, ErrSubscriptionVersionConflict
+	}
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
+	warnings, err := p.subscriptionConflictWarnings(&subscription)
+	if err != nil {
+		p.API.LogWarn("Failed to check the overlapping subscriptions", "error", err.Error())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	b, _ := json.Marshal(&subscription)
+	b, _ := json.Marshal(channelSubscriptionResponse{&subscription, warnings})
 	_, err = w.Write(b)
 	if err != nil {
 		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
@@ -821,7 +1150,7 @@ func httpChannelEditSubscription(p *Plugin, w http.ResponseWriter, r *http.Reque
 	post := &model.Post{
 		UserId:    p.getConfig().botUserID,
 		ChannelId: subscription.ChannelId,
-		Message:   fmt.Sprintf("Jira subscription, \"%v\", was updated by %v", subscription.Name, jiraUser.DisplayName),
+		Message:   p.localize(p.channelLocale(subscription.ChannelId), msgSubscriptionUpdated, subscription.Name, jiraUser.DisplayName),
 	}
 
 	p.API.CreatePost(post)
@@ -871,7 +1200,7 @@ func httpChannelDeleteSubscription(p *Plugin, w http.ResponseWriter, r *http.Req
 	post := &model.Post{
 		UserId:    p.getConfig().botUserID,
 		ChannelId: subscription.ChannelId,
-		Message:   fmt.Sprintf("Jira subscription, \"%v\", was removed from this channel by %v", subscription.Name, jiraUser.DisplayName),
+		Message:   p.localize(p.channelLocale(subscription.ChannelId), msgSubscriptionRemoved, subscription.Name, jiraUser.DisplayName),
 	}
 
 	p.API.CreatePost(post)
@@ -898,7 +1227,23 @@ func httpChannelGetSubscriptions(p *Plugin, w http.ResponseWriter, r *http.Reque
 		return http.StatusInternalServerError, errors.Wrap(err, "unable to get channel subscriptions")
 	}
 
-	bytes, err := json.Marshal(subscriptions)
+	total := len(subscriptions)
+	subscriptions, err = paginateSubscriptions(subscriptions, r.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	w.Header().Set(headerTotalCount, strconv.Itoa(total))
+
+	var result interface{} = subscriptions
+	if r.URL.Query().Get("counts") == "true" {
+		withCounts, err := p.withOpenIssuesCounts(mattermostUserId, subscriptions)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(err, "unable to count open issues")
+		}
+		result = withCounts
+	}
+
+	bytes, err := json.Marshal(result)
 	if err != nil {
 		return http.StatusInternalServerError, errors.Wrap(err, "unable to marshal subscriptions")
 	}
@@ -909,11 +1254,44 @@ func httpChannelGetSubscriptions(p *Plugin, w http.ResponseWriter, r *http.Reque
 	return http.StatusOK, nil
 }
 
+// paginateSubscriptions returns the page of the subscriptions selected by the
+// offset and limit query parameters, sorted by name. Without a limit, all the
+// subscriptions from the offset are returned.
+func paginateSubscriptions(subscriptions []ChannelSubscription, query url.Values) ([]ChannelSubscription, error) {
+	offset, limit := 0, len(subscriptions)
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, errors.New("offset must be a positive integer")
+		}
+		offset = n
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSubscriptionsPerPage {
+			return nil, errors.Errorf("limit must be an integer between 1 and %v", maxSubscriptionsPerPage)
+		}
+		limit = n
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].Name != subscriptions[j].Name {
+			return subscriptions[i].Name < subscriptions[j].Name
+		}
+		return subscriptions[i].Id < subscriptions[j].Id
+	})
+	if offset >= len(subscriptions) {
+		return []ChannelSubscription{}, nil
+	}
+	end := offset + limit
+	if end > len(subscriptions) {
+		end = len(subscriptions)
+	}
+	return subscriptions[offset:end], nil
+}
+
 func httpChannelSubscriptions(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	switch r.Method {
 	case http.MethodPost:
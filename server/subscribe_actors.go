@@ -0,0 +1,76 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+// matchesActor returns true if one of actors is the username, display name,
+// email or account ID of the user, ignoring case.
+func matchesActor(user jira.User, actors []string) bool {
+	for _, actor := range actors {
+		for _, id := range []string{user.Name, user.Key, user.DisplayName, user.EmailAddress, user.AccountID} {
+			if id != "" && strings.EqualFold(id, actor) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIgnoredActor returns true if the webhook event was triggered by a user
+// ignored by the IgnoredActors setting, or by the subscription.
+func (p *Plugin) isIgnoredActor(wh *webhook, sub ChannelSubscription) bool {
+	actor := wh.JiraWebhook.User
+	return matchesActor(actor, wh.getConfig(p).ignoredActors) || matchesActor(actor, sub.Filters.IgnoredActors)
+}
+
+func executeSubscribeIgnore(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira subscribe ignore <user[,user...]|clear> <subscription name>`, " +
+		"quoting the users if they have spaces, e.g. `/jira subscribe ignore \"Automation for Jira,svc-sync\" Team bugs`."
+
+	list, args := splitQuotedArg(args)
+	if list == "" || len(args) == 0 {
+		return p.responsef(header, usage)
+	}
+	actors := []string{}
+	if list != "clear" {
+		actors = utils.ParseList(list)
+	}
+	name := strings.Join(args, " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		sub.Filters.IgnoredActors = actors
+		if len(actors) == 0 {
+			return "Subscription %q no longer ignores any Jira user."
+		}
+		return "Subscription %q now ignores the changes made by " + strings.Join(actors, ", ") + "."
+	})
+}
+
+// splitQuotedArg returns the first argument, or the arguments up to the one
+// ending the double quote that the first starts, and the remaining ones.
+func splitQuotedArg(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	if !strings.HasPrefix(args[0], `"`) {
+		return args[0], args[1:]
+	}
+	for i := range args {
+		if (i > 0 || len(args[0]) > 1) && strings.HasSuffix(args[i], `"`) {
+			quoted := strings.Join(args[:i+1], " ")
+			return quoted[1 : len(quoted)-1], args[i+1:]
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// The type of the system message of a restored channel, which the
+	// server model the plugin is built with doesn't define.
+	postChannelRestored = "system_channel_restored"
+
+	// How often the subscriptions are paused or resumed for the channels
+	// archived or restored without a system message, or while the plugin was
+	// disabled.
+	archivedChannelsCheckInterval = 10 * time.Minute
+)
+
+// pauseArchivedChannelSubscriptions pauses the subscriptions of the channel
+// when it is archived, and resumes them when it is restored. Only the
+// resumption is posted to the channel, since archived channels can't be
+// posted to.
+func (p *Plugin) pauseArchivedChannelSubscriptions(channelId string, archived bool) error {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+
+	var changed []ChannelSubscription
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		changed = nil
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range subs.Channel.IdByChannelId[channelId].Elems() {
+			sub := subs.Channel.ById[id]
+			if sub.Paused == archived {
+				continue
+			}
+			sub.Paused = archived
+			sub.Version++
+			subs.Channel.ById[id] = sub
+			changed = append(changed, sub)
+		}
+		if len(changed) == 0 {
+			return initialBytes, nil
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		return modifiedBytes, nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	names := []string{}
+	for _, sub := range changed {
+		names = append(names, fmt.Sprintf("%q", sub.Name))
+
+		// The filter subscriptions record a new baseline when they resume,
+		// instead of posting all the issues created in the meantime
+		if archived && sub.FilterId != "" {
+			appErr := p.API.KVDelete(keyWithInstance(ji, prefixFilterSubscription+sub.Id))
+			if appErr != nil {
+				p.errorf("pauseArchivedChannelSubscriptions: failed to reset filter subscription %q: %v", sub.Name, appErr)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	if archived {
+		p.infof("pauseArchivedChannelSubscriptions: paused the subscriptions of archived channel %s: %s", channelId, strings.Join(names, ", "))
+		return nil
+	}
+	_, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelId,
+		Message:   p.localize(p.channelLocale(channelId), msgSubscriptionsUnarchived, strings.Join(names, ", ")),
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// syncArchivedChannelSubscriptions pauses or resumes the subscriptions of the
+// channels whose archival was not seen through their system message.
+func (p *Plugin) syncArchivedChannelSubscriptions() {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("syncArchivedChannelSubscriptions: failed to load subscriptions: %v", err)
+		return
+	}
+
+	for channelId, ids := range subs.Channel.IdByChannelId {
+		if len(ids) == 0 {
+			continue
+		}
+		channel, appErr := p.API.GetChannel(channelId)
+		if appErr != nil {
+			continue
+		}
+		archived := channel.DeleteAt != 0
+		outdated := false
+		for _, id := range ids.Elems() {
+			if subs.Channel.ById[id].Paused != archived {
+				outdated = true
+				break
+			}
+		}
+		if !outdated {
+			continue
+		}
+		err = p.pauseArchivedChannelSubscriptions(channelId, archived)
+		if err != nil {
+			p.errorf("syncArchivedChannelSubscriptions: failed to update the subscriptions of channel %s: %v", channelId, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseArchivedChannelSubscriptions(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	api.On("LogInfo", mock.AnythingOfType("string")).Return()
+	var posted *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		posted = args.Get(0).(*model.Post)
+	}).Return(&model.Post{}, nil)
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1"}, nil)
+	api.On("GetChannel", "channel2").Return(&model.Channel{Id: "channel2"}, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "bugs", Filters: SubscriptionFilters{Events: NewStringSet(eventCreated), Projects: NewStringSet("TES")}})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel1", Name: "filter", FilterId: "10000"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel2", Name: "other", Filters: SubscriptionFilters{Events: NewStringSet(eventCreated), Projects: NewStringSet("TES")}})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+	kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)] = stored
+	filterStateKey := keyWithMockInstance(prefixFilterSubscription + "sub2")
+	kv[filterStateKey] = []byte(`{}`)
+
+	wh := &webhook{JiraWebhook: &JiraWebhook{}, eventTypes: NewStringSet(eventCreated)}
+	wh.Issue.Fields = &jira.IssueFields{Project: jira.Project{Key: "TES"}}
+
+	p.MessageHasBeenPosted(nil, &model.Post{ChannelId: "channel1", Type: model.POST_CHANNEL_DELETED})
+	updated, err := p.getSubscriptions()
+	require.NoError(t, err)
+	assert.True(t, updated.Channel.ById["sub1"].Paused)
+	assert.True(t, updated.Channel.ById["sub2"].Paused)
+	assert.False(t, updated.Channel.ById["sub3"].Paused)
+	assert.False(t, p.matchesChannelSubscription(wh, updated.Channel.ById["sub1"], false))
+	assert.True(t, p.matchesChannelSubscription(wh, updated.Channel.ById["sub3"], false))
+	assert.Nil(t, kv[filterStateKey], "the filter subscription records a new baseline when it resumes")
+	assert.Nil(t, posted, "nothing is posted to the archived channel")
+
+	// Restored while the plugin was disabled
+	p.syncArchivedChannelSubscriptions()
+	updated, err = p.getSubscriptions()
+	require.NoError(t, err)
+	assert.False(t, updated.Channel.ById["sub1"].Paused)
+	assert.False(t, updated.Channel.ById["sub2"].Paused)
+	assert.True(t, p.matchesChannelSubscription(wh, updated.Channel.ById["sub1"], false))
+	require.NotNil(t, posted)
+	assert.Equal(t, "channel1", posted.ChannelId)
+	assert.Contains(t, posted.Message, `"bugs", "filter"`)
+}
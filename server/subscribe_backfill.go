@@ -0,0 +1,189 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// A backfill lists up to backfillMaxIssues of the open issues of a
+// subscription, the last updated first.
+const backfillMaxIssues = 20
+
+// subscriptionBackfillJQL returns the query of the open issues of the
+// subscription, or "" if its issues can't be queried, like those of the
+// project events.
+func subscriptionBackfillJQL(sub ChannelSubscription) string {
+	clause := subscriptionIssuesClause(sub)
+	if clause == "" {
+		return ""
+	}
+	return "(" + clause + ") AND statusCategory != Done ORDER BY updated DESC"
+}
+
+// backfillSubscription posts the open issues of the subscription to its
+// channel, searched with the Jira account of the user, and returns the text
+// to respond to the user with.
+func (p *Plugin) backfillSubscription(ji Instance, mattermostUserId string, sub ChannelSubscription) (string, error) {
+	jql := subscriptionBackfillJQL(sub)
+	if jql == "" {
+		return "", errors.Errorf("The issues of subscription %q can't be searched in Jira.", sub.Name)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return "", errors.New(p.localize(p.userLocale(mattermostUserId), msgNotConnected))
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return "", err
+	}
+
+	total, err := countJQLIssues(client, jql)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to search the open issues")
+	}
+	issues, err := client.SearchIssues(jql, &jira.SearchOptions{
+		MaxResults: backfillMaxIssues,
+		Fields:     myIssueFields,
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to search the open issues")
+	}
+
+	_, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: sub.ChannelId,
+		Message:   p.renderBackfill(p.channelLocale(sub.ChannelId), strings.TrimRight(ji.GetURL(), "/"), sub, jql, issues, total),
+	})
+	if appErr != nil {
+		return "", errors.WithMessage(appErr, "failed to post the open issues")
+	}
+	return fmt.Sprintf("Posted the open issues of subscription %q.", sub.Name), nil
+}
+
+// renderBackfill renders the summary of the open issues of a subscription.
+// The issues are those of its projects, issue types, epics and JQL filter,
+// the other filters need the events.
+func (p *Plugin) renderBackfill(locale, jiraURL string, sub ChannelSubscription, jql string, issues []jira.Issue, total int) string {
+	searchURL := jiraURL + "/issues/?jql=" + url.QueryEscape(jql)
+	lines := []string{}
+	switch {
+	case len(issues) == 0:
+		lines = append(lines, p.localize(locale, msgBackfillNoIssues, sub.Name))
+	case total > len(issues):
+		lines = append(lines, p.localize(locale, msgBackfillLastIssues, sub.Name, total, len(issues), searchURL))
+	default:
+		lines = append(lines, p.localize(locale, msgBackfillIssues, sub.Name, searchURL))
+	}
+	for _, issue := range issues {
+		lines = append(lines, "* "+myIssueText(jiraURL, issue))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// backfillAction returns the button of the post of a new subscription that
+// posts its open issues, or nil if they can't be searched.
+func (p *Plugin) backfillAction(sub ChannelSubscription, t localizer) *model.PostAction {
+	if subscriptionBackfillJQL(sub) == "" {
+		return nil
+	}
+	return &model.PostAction{
+		Name: t(msgActionBackfill),
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPIBackfillAction,
+			Context: map[string]interface{}{
+				"subscription_id": sub.Id,
+			},
+		},
+	}
+}
+
+// httpAPIBackfillAction handles the button of the post of a new subscription.
+// The button is removed once the open issues are posted.
+func httpAPIBackfillAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	subscriptionId, _ := request.Context["subscription_id"].(string)
+	if subscriptionId == "" {
+		return http.StatusBadRequest, errors.New("missing subscription id")
+	}
+
+	p := ji.GetPlugin()
+	if err := p.requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	response := model.PostActionIntegrationResponse{}
+	sub, err := p.getChannelSubscription(subscriptionId)
+	switch {
+	case err != nil:
+		response.EphemeralText = "This subscription no longer exists."
+	case p.hasPermissionToManageSubscription(mattermostUserId, sub.ChannelId) != nil:
+		response.EphemeralText = "You are not allowed to manage the Jira subscriptions of this channel."
+	default:
+		message, err := p.backfillSubscription(ji, mattermostUserId, *sub)
+		if err != nil {
+			message = err.Error()
+		} else if post, appErr := p.API.GetPost(request.PostId); appErr == nil {
+			response.Update = &model.Post{Message: post.Message}
+		}
+		response.EphemeralText = message
+	}
+
+	b, _ := json.Marshal(response)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func executeSubscribeBackfill(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	name := strings.Trim(strings.Join(args, " "), `"`)
+	if name == "" {
+		return p.responsef(header, "Please use `/jira subscribe backfill <subscription name>`.")
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to manage Jira subscriptions: %v", err)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeBackfill: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.Name != name {
+			continue
+		}
+		message, err := p.backfillSubscription(ji, header.UserId, sub)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		return p.responsef(header, "%s", message)
+	}
+	return p.responsef(header, "There is no subscription named %q in this channel.", name)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionBackfillJQL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		sub      ChannelSubscription
+		expected string
+	}{
+		"projects and issue types": {
+			sub: ChannelSubscription{Filters: SubscriptionFilters{
+				Projects:   NewStringSet("TES"),
+				IssueTypes: NewStringSet("10001"),
+			}},
+			expected: `(project in ("TES") AND issuetype in ("10001")) AND statusCategory != Done ORDER BY updated DESC`,
+		},
+		"JQL filter": {
+			sub: ChannelSubscription{Filters: SubscriptionFilters{
+				Projects: NewStringSet("TES"),
+				JQL:      "labels = release",
+			}},
+			expected: `(project in ("TES") AND (labels = release)) AND statusCategory != Done ORDER BY updated DESC`,
+		},
+		"issue":          {sub: ChannelSubscription{IssueKey: "TES-1"}, expected: `(issuekey = TES-1) AND statusCategory != Done ORDER BY updated DESC`},
+		"project events": {sub: ChannelSubscription{ProjectEvents: true}, expected: ""},
+		"no projects":    {sub: ChannelSubscription{Filters: SubscriptionFilters{Events: NewStringSet(eventCreated)}}, expected: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, subscriptionBackfillJQL(tc.sub))
+		})
+	}
+
+	p := &Plugin{}
+	assert.Nil(t, p.backfillAction(ChannelSubscription{ProjectEvents: true}, englishLocalizer))
+}
+
+func TestRenderBackfill(t *testing.T) {
+	p := &Plugin{}
+	sub := ChannelSubscription{Name: "Bugs"}
+	issues := []jira.Issue{
+		{Key: "TES-2", Fields: &jira.IssueFields{Summary: "Second", Status: &jira.Status{Name: "In Progress"}}},
+		{Key: "TES-1", Fields: &jira.IssueFields{Summary: "First", Status: &jira.Status{Name: "To Do"}}},
+	}
+
+	assert.Equal(t, `Jira subscription "Bugs" has no open issues.`,
+		p.renderBackfill("en", "https://jira.example.com", sub, "project = TES", nil, 0))
+	assert.Equal(t, "Open issues of Jira subscription \"Bugs\" ([view in Jira](https://jira.example.com/issues/?jql=project+%3D+TES)):\n"+
+		"* [TES-2](https://jira.example.com/browse/TES-2) Second (In Progress)\n"+
+		"* [TES-1](https://jira.example.com/browse/TES-1) First (To Do)",
+		p.renderBackfill("en", "https://jira.example.com", sub, "project = TES", issues, 2))
+	assert.Contains(t, p.renderBackfill("en", "https://jira.example.com", sub, "project = TES", issues, 30),
+		`Jira subscription "Bugs" has 30 open issues, the 2 last updated are ([view all](https://jira.example.com/issues/?jql=project+%3D+TES)):`)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const maxBulkSubscriptions = 100
+
+// httpChannelCreateSubscriptions creates a list of subscriptions, possibly in
+// different channels, all at once. If any of them is invalid, none is created.
+func httpChannelCreateSubscriptions(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("Request: " + r.Method + " is not allowed, must be POST")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	subscriptions := []*ChannelSubscription{}
+	err := json.NewDecoder(r.Body).Decode(&subscriptions)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to decode incoming request")
+	}
+	if len(subscriptions) == 0 {
+		return http.StatusBadRequest, errors.New("No subscriptions provided")
+	}
+	if len(subscriptions) > maxBulkSubscriptions {
+		return http.StatusBadRequest, errors.Errorf("No more than %d subscriptions can be created at once", maxBulkSubscriptions)
+	}
+
+	for i, subscription := range subscriptions {
+		if subscription == nil ||
+			len(subscription.ChannelId) != 26 ||
+			len(subscription.Id) != 0 {
+			return http.StatusBadRequest, errors.Errorf("Channel subscription %d invalid", i+1)
+		}
+	}
+
+	checkedChannels := NewStringSet()
+	for i, subscription := range subscriptions {
+		if checkedChannels.ContainsAny(subscription.ChannelId) {
+			continue
+		}
+
+		_, appErr := p.API.GetChannelMember(subscription.ChannelId, mattermostUserId)
+		if appErr != nil {
+			return http.StatusForbidden, errors.Errorf("Not a member of the channel specified in subscription %d", i+1)
+		}
+
+		err = p.hasPermissionToManageSubscription(mattermostUserId, subscription.ChannelId)
+		if err != nil {
+			return http.StatusForbidden, errors.Wrap(err, "you don't have permission to manage subscriptions")
+		}
+		checkedChannels = checkedChannels.Add(subscription.ChannelId)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	for _, subscription := range subscriptions {
+		subscription.CreatorId = mattermostUserId
+	}
+	err = p.addChannelSubscriptions(subscriptions, client)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	b, _ := json.Marshal(subscriptions)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+
+	for _, subscription := range subscriptions {
+		post := &model.Post{
+			UserId:    p.getConfig().botUserID,
+			ChannelId: subscription.ChannelId,
+			Message:   p.localize(p.channelLocale(subscription.ChannelId), msgSubscriptionAdded, subscription.Name, jiraUser.DisplayName),
+		}
+
+		p.API.CreatePost(post)
+	}
+
+	return http.StatusOK, nil
+}
+
+// addChannelSubscriptions validates and stores the subscriptions in a single
+// modification of the subscriptions record.
+func (p *Plugin) addChannelSubscriptions(newSubscriptions []*ChannelSubscription, client Client) error {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	return p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		names := map[string]bool{}
+		for i, newSubscription := range newSubscriptions {
+			err = p.validateSubscription(newSubscription, client)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "subscription %d", i+1)
+			}
+
+			// validateSubscription only checks the stored subscriptions
+			name := newSubscription.ChannelId + "/" + newSubscription.Name
+			if names[name] {
+				return nil, errors.Errorf("subscription %d: Subscription name, '%s', is used more than once in the channel.", i+1, newSubscription.Name)
+			}
+			names[name] = true
+		}
+
+		for _, newSubscription := range newSubscriptions {
+			newSubscription.Id = model.NewId()
+			newSubscription.Version = 1
+			subs.Channel.add(newSubscription)
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		return modifiedBytes, nil
+	})
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// Directions of the changes of ordered fields, like the priority.
+const (
+	changeRaised  = "raised"
+	changeLowered = "lowered"
+)
+
+// defaultPriorities are the priorities of Jira, from the highest. Other
+// priorities are ordered by ID, which Jira assigns in the same order.
+var defaultPriorities = []string{"blocker", "highest", "critical", "high", "major", "medium", "low", "minor", "lowest", "trivial"}
+
+// changeEvents are the events of the changes of the fields, which are added
+// to a subscription with a change filter.
+var changeEvents = map[string]string{
+	"status":    eventUpdatedStatus,
+	"priority":  eventUpdatedPriority,
+	"assignee":  eventUpdatedAssignee,
+	"summary":   eventUpdatedSummary,
+	"issuetype": eventUpdatedIssuetype,
+	"reporter":  eventUpdatedReporter,
+}
+
+// ChangeFilter restricts the issue updated events to the changes of a field
+// from or to some values, or in a direction for the priority, as found in
+// the changelog of the events.
+type ChangeFilter struct {
+	Field     string    `json:"field"`
+	From      StringSet `json:"from,omitempty"`
+	To        StringSet `json:"to,omitempty"`
+	Direction string    `json:"direction,omitempty"`
+}
+
+func (cf ChangeFilter) String() string {
+	if cf.Direction != "" {
+		return cf.Field + " " + cf.Direction
+	}
+	s := cf.Field + " changed"
+	if cf.From.Len() != 0 {
+		s += " from " + sortedJoin(cf.From, " or ")
+	}
+	if cf.To.Len() != 0 {
+		s += " to " + sortedJoin(cf.To, " or ")
+	}
+	return s
+}
+
+func sortedJoin(set StringSet, sep string) string {
+	elems := set.Elems()
+	sort.Strings(elems)
+	return strings.Join(elems, sep)
+}
+
+// parseChangeFilter parses a change filter, like `status>Done`,
+// `status=In Progress>Done`, `status=Done>` or `priority:raised`.
+func parseChangeFilter(s string) (ChangeFilter, error) {
+	if i := strings.Index(s, ":"); i >= 0 {
+		cf := ChangeFilter{Field: strings.TrimSpace(s[:i]), Direction: strings.ToLower(strings.TrimSpace(s[i+1:]))}
+		if cf.Direction != changeRaised && cf.Direction != changeLowered {
+			return ChangeFilter{}, errors.Errorf("%q is not `raised` or `lowered`", cf.Direction)
+		}
+		if !strings.EqualFold(cf.Field, "priority") {
+			return ChangeFilter{}, errors.New("only the priority can be raised or lowered")
+		}
+		cf.Field = "priority"
+		return cf, nil
+	}
+
+	i := strings.Index(s, ">")
+	if i < 0 {
+		return ChangeFilter{}, errors.Errorf("%q is not a change, use e.g. `status>Done`, `status=In Progress>Done` or `priority:raised`", s)
+	}
+	cf := ChangeFilter{Field: strings.TrimSpace(s[:i]), To: parseChangeValues(s[i+1:])}
+	if j := strings.Index(cf.Field, "="); j >= 0 {
+		cf.From = parseChangeValues(cf.Field[j+1:])
+		cf.Field = strings.TrimSpace(cf.Field[:j])
+	}
+	if cf.Field == "" {
+		return ChangeFilter{}, errors.New("please provide the field of the change")
+	}
+	if cf.From.Len() == 0 && cf.To.Len() == 0 {
+		return ChangeFilter{}, errors.Errorf("please provide the values %s is changed from or to", cf.Field)
+	}
+	return cf, nil
+}
+
+func parseChangeValues(s string) StringSet {
+	values := NewStringSet()
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = values.Add(value)
+		}
+	}
+	return values
+}
+
+// matchesChanges returns true if the event has no changelog, like a comment,
+// or if it changes a field as one of the Changes filters.
+func (filters SubscriptionFilters) matchesChanges(wh *webhook) bool {
+	if len(filters.Changes) == 0 || len(wh.JiraWebhook.ChangeLog.Items) == 0 {
+		return true
+	}
+	for _, cf := range filters.Changes {
+		for _, item := range wh.JiraWebhook.ChangeLog.Items {
+			if !strings.EqualFold(item.Field, cf.Field) && !strings.EqualFold(item.FieldId, cf.Field) {
+				continue
+			}
+			if cf.matches(item.From, item.FromString, item.To, item.ToString) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (cf ChangeFilter) matches(from, fromString, to, toString string) bool {
+	switch cf.Direction {
+	case changeRaised:
+		return comparePriorities(from, fromString, to, toString) > 0
+	case changeLowered:
+		return comparePriorities(from, fromString, to, toString) < 0
+	}
+	return (cf.From.Len() == 0 || containsFold(cf.From, from, fromString)) &&
+		(cf.To.Len() == 0 || containsFold(cf.To, to, toString))
+}
+
+// containsFold returns true if the set has one of the values, ignoring case.
+func containsFold(set StringSet, values ...string) bool {
+	for elem := range set {
+		for _, value := range values {
+			if value != "" && strings.EqualFold(elem, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// comparePriorities returns a positive number if the priority is raised, a
+// negative one if it is lowered, and 0 if it is unknown.
+func comparePriorities(fromId, fromName, toId, toName string) int {
+	fromRank, toRank := priorityRank(fromName), priorityRank(toName)
+	if fromRank < 0 || toRank < 0 {
+		from, err := strconv.Atoi(fromId)
+		if err != nil {
+			return 0
+		}
+		to, err := strconv.Atoi(toId)
+		if err != nil {
+			return 0
+		}
+		fromRank, toRank = from, to
+	}
+	return fromRank - toRank
+}
+
+func priorityRank(name string) int {
+	for i, priority := range defaultPriorities {
+		if strings.EqualFold(name, priority) {
+			return i
+		}
+	}
+	return -1
+}
+
+func executeSubscribeChange(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira subscribe change <field>[=from,...]>to,...|priority:raised|priority:lowered|clear> <subscription name>`, e.g. `/jira subscribe change status>Done Releases`."
+
+	change, args := splitQuotedArg(args)
+	if change == "" || len(args) == 0 {
+		return p.responsef(header, usage)
+	}
+	var cf ChangeFilter
+	if change != "clear" {
+		var err error
+		cf, err = parseChangeFilter(change)
+		if err != nil {
+			return p.responsef(header, "%v. "+usage, err)
+		}
+	}
+	name := strings.Join(args, " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		if change == "clear" {
+			sub.Filters.Changes = nil
+			return "Subscription %q now posts all the changes of the events it is subscribed to."
+		}
+
+		// A filter replaces the one of the same field
+		changes := []ChangeFilter{}
+		for _, existing := range sub.Filters.Changes {
+			if !strings.EqualFold(existing.Field, cf.Field) {
+				changes = append(changes, existing)
+			}
+		}
+		sub.Filters.Changes = append(changes, cf)
+
+		if event, ok := changeEvents[strings.ToLower(cf.Field)]; ok {
+			sub.Filters.Events = sub.Filters.Events.Add(event)
+		}
+		rules := []string{}
+		for _, changeFilter := range sub.Filters.Changes {
+			rules = append(rules, strings.ReplaceAll(changeFilter.String(), "%", "%%"))
+		}
+		return "Subscription %q now only posts the issue updates where " + strings.Join(rules, ", or ") + "."
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChangeFilter(t *testing.T) {
+	for s, expected := range map[string]ChangeFilter{
+		"status>Done,Closed":      {Field: "status", To: NewStringSet("Done", "Closed")},
+		"status=In Progress>Done": {Field: "status", From: NewStringSet("In Progress"), To: NewStringSet("Done")},
+		"status=Done>":            {Field: "status", From: NewStringSet("Done"), To: NewStringSet()},
+		"Priority:Raised":         {Field: "priority", Direction: changeRaised},
+	} {
+		t.Run(s, func(t *testing.T) {
+			cf, err := parseChangeFilter(s)
+			require.NoError(t, err)
+			assert.Equal(t, expected, cf)
+		})
+	}
+
+	for _, s := range []string{"status", "status>", ">Done", "priority:up", "status:raised"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := parseChangeFilter(s)
+			assert.Error(t, err)
+		})
+	}
+
+	cf, _ := parseChangeFilter("status=To Do>Done,Closed")
+	assert.Equal(t, "status changed from To Do to Closed or Done", cf.String())
+}
+
+func TestSubscriptionFiltersMatchesChanges(t *testing.T) {
+	changed := func(field, from, fromString, to, toString string) *webhook {
+		jwh := &JiraWebhook{}
+		err := json.Unmarshal([]byte(`{"changelog": {"items": [{"field": "`+field+`", "from": "`+from+`", "fromString": "`+fromString+`", "to": "`+to+`", "toString": "`+toString+`"}]}}`), jwh)
+		require.NoError(t, err)
+		return &webhook{JiraWebhook: jwh, eventTypes: NewStringSet(eventUpdatedStatus)}
+	}
+	toDone := ChangeFilter{Field: "status", To: NewStringSet("done")}
+	fromReview := ChangeFilter{Field: "status", From: NewStringSet("In Review"), To: NewStringSet("Done")}
+	raised := ChangeFilter{Field: "priority", Direction: changeRaised}
+	lowered := ChangeFilter{Field: "priority", Direction: changeLowered}
+
+	for name, tc := range map[string]struct {
+		changes  []ChangeFilter
+		wh       *webhook
+		expected bool
+	}{
+		"no change filter":      {nil, changed("status", "1", "To Do", "3", "In Progress"), true},
+		"no changelog":          {[]ChangeFilter{toDone}, &webhook{JiraWebhook: &JiraWebhook{}, eventTypes: NewStringSet(eventCreatedComment)}, true},
+		"changed to":            {[]ChangeFilter{toDone}, changed("status", "3", "In Progress", "10001", "Done"), true},
+		"changed to other":      {[]ChangeFilter{toDone}, changed("status", "1", "To Do", "3", "In Progress"), false},
+		"changed from and to":   {[]ChangeFilter{fromReview}, changed("status", "4", "In Review", "10001", "Done"), true},
+		"changed from other":    {[]ChangeFilter{fromReview}, changed("status", "3", "In Progress", "10001", "Done"), false},
+		"other field":           {[]ChangeFilter{toDone}, changed("resolution", "", "", "10000", "Done"), false},
+		"any filter":            {[]ChangeFilter{raised, toDone}, changed("status", "3", "In Progress", "10001", "Done"), true},
+		"priority raised":       {[]ChangeFilter{raised}, changed("priority", "3", "Medium", "2", "High"), true},
+		"priority lowered":      {[]ChangeFilter{lowered}, changed("priority", "3", "Medium", "2", "High"), false},
+		"custom priority by ID": {[]ChangeFilter{lowered}, changed("priority", "10001", "P1", "10002", "P2"), true},
+		"unknown priority":      {[]ChangeFilter{raised}, changed("priority", "", "", "2", "High"), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, SubscriptionFilters{Changes: tc.changes}.matchesChanges(tc.wh))
+		})
+	}
+}
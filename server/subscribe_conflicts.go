@@ -0,0 +1,157 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// channelSubscriptionResponse is a subscription as returned by the API when
+// it is created or edited, with the warnings about the other subscriptions of
+// its channel posting the same events.
+type channelSubscriptionResponse struct {
+	*ChannelSubscription
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// subscriptionConflictWarnings returns the warnings about the other
+// subscriptions of the channel of sub that post all the events of sub, or
+// whose events sub posts all of, ordered by subscription name.
+func (p *Plugin) subscriptionConflictWarnings(sub *ChannelSubscription) ([]string, error) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	firstMatch := subs.Channel.FirstMatchChannelIds.ContainsAny(sub.ChannelId)
+
+	others := []ChannelSubscription{}
+	for _, other := range subs.Channel.ById {
+		if other.ChannelId == sub.ChannelId && other.Id != sub.Id && !other.Paused && other.NonCompliant == "" {
+			others = append(others, other)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return strings.ToLower(others[i].Name) < strings.ToLower(others[j].Name)
+	})
+
+	hint := "Delete or narrow one of them, or run `/jira subscribe overlap first` to only post the first matching subscription by name."
+	if firstMatch {
+		hint = "Only the first matching subscription by name is posted in this channel, so the other one never applies to these events."
+	}
+	warnings := []string{}
+	for _, other := range others {
+		switch {
+		case subscriptionCovers(&other, sub) && subscriptionCovers(sub, &other):
+			warnings = append(warnings, fmt.Sprintf("Subscription %q posts the same events as %q. %s", other.Name, sub.Name, hint))
+		case subscriptionCovers(&other, sub):
+			warnings = append(warnings, fmt.Sprintf("Subscription %q already posts all the events of %q. %s", other.Name, sub.Name, hint))
+		case subscriptionCovers(sub, &other):
+			warnings = append(warnings, fmt.Sprintf("Subscription %q posts all the events of %q. %s", sub.Name, other.Name, hint))
+		}
+	}
+	return warnings, nil
+}
+
+// subscriptionCovers returns true if a posts all the events that b posts, as
+// far as their filters tell. The filter subscriptions are matched by Jira, so
+// they never cover nor are covered.
+func subscriptionCovers(a, b *ChannelSubscription) bool {
+	switch {
+	case a.FilterId != "" || b.FilterId != "":
+		return false
+	case a.ProjectEvents || b.ProjectEvents:
+		return a.ProjectEvents && b.ProjectEvents
+	case a.IssueKey != "" || b.IssueKey != "":
+		return a.IssueKey == b.IssueKey && a.RootId == b.RootId
+	}
+	return a.Filters.covers(b.Filters)
+}
+
+// covers returns true if the filters let through all the events that other
+// lets through.
+func (filters SubscriptionFilters) covers(other SubscriptionFilters) bool {
+	if !filters.coversEvents(other.Events) ||
+		!filters.Projects.ContainsAll(other.Projects.Elems()...) ||
+		!filters.coversIssueTypes(other) ||
+		!filters.coversHierarchy(other) ||
+		!fieldFiltersContain(other.Fields, filters.Fields) {
+		return false
+	}
+
+	// The filters that only one of the subscriptions has restrict it
+	if !NewStringSet(other.IgnoredActors...).ContainsAll(filters.IgnoredActors...) {
+		return false
+	}
+	if filters.PropertyKey != "" {
+		if filters.PropertyKey != other.PropertyKey {
+			return false
+		}
+		if filters.PropertyValues.Len() > 0 &&
+			(other.PropertyValues.Len() == 0 || !filters.PropertyValues.ContainsAll(other.PropertyValues.Elems()...)) {
+			return false
+		}
+	}
+	if len(filters.Changes) > 0 && !reflect.DeepEqual(filters.Changes, other.Changes) {
+		return false
+	}
+	return filters.JQL == "" || filters.JQL == other.JQL
+}
+
+func (filters SubscriptionFilters) coversEvents(events StringSet) bool {
+	for _, event := range events.Elems() {
+		if filters.Events.ContainsAny(event) {
+			continue
+		}
+		if !filters.Events.ContainsAny(eventUpdatedAny) || !strings.HasPrefix(event, "event_updated") {
+			return false
+		}
+	}
+	return true
+}
+
+// coversIssueTypes compares the issue types by ID, and by name for the IDs
+// of another instance or project.
+func (filters SubscriptionFilters) coversIssueTypes(other SubscriptionFilters) bool {
+	for _, id := range other.IssueTypes.Elems() {
+		if filters.IssueTypes.ContainsAny(id) {
+			continue
+		}
+		if !filters.matchesIssueType(jira.IssueType{ID: id, Name: other.IssueTypeNames[id]}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (filters SubscriptionFilters) coversHierarchy(other SubscriptionFilters) bool {
+	if filters.ExcludeSubtasks && !other.ExcludeSubtasks {
+		return false
+	}
+	return filters.ParentKeys.Len() == 0 ||
+		(other.ParentKeys.Len() > 0 && filters.ParentKeys.ContainsAll(other.ParentKeys.Elems()...))
+}
+
+// fieldFiltersContain returns true if all the field filters of subset are
+// also in fields, with the same inclusion and values.
+func fieldFiltersContain(fields, subset []FieldFilter) bool {
+	for _, f := range subset {
+		found := false
+		for _, field := range fields {
+			if strings.EqualFold(field.Key, f.Key) && field.Inclusion == f.Inclusion &&
+				field.Values.Len() == f.Values.Len() && field.Values.ContainsAll(f.Values.Elems()...) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionCovers(t *testing.T) {
+	base := SubscriptionFilters{
+		Events:     NewStringSet(eventCreated, eventUpdatedStatus),
+		Projects:   NewStringSet("TES"),
+		IssueTypes: NewStringSet("10001"),
+	}
+	with := func(f func(*SubscriptionFilters)) SubscriptionFilters {
+		filters := base
+		f(&filters)
+		return filters
+	}
+
+	for name, tc := range map[string]struct {
+		a, b     ChannelSubscription
+		expected bool
+	}{
+		"same filters": {
+			a:        ChannelSubscription{Filters: base},
+			b:        ChannelSubscription{Filters: base},
+			expected: true,
+		},
+		"more events": {
+			a: ChannelSubscription{Filters: with(func(f *SubscriptionFilters) {
+				f.Events = NewStringSet(eventCreated, eventUpdatedStatus, eventCreatedComment)
+			})},
+			b:        ChannelSubscription{Filters: base},
+			expected: true,
+		},
+		"fewer events": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.Events = NewStringSet(eventCreated) })},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+		"any update": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.Events = NewStringSet(eventCreated, eventUpdatedAny) })},
+			b:        ChannelSubscription{Filters: base},
+			expected: true,
+		},
+		"other project": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.Projects = NewStringSet("OTHER") })},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+		"issue type by name": {
+			a: ChannelSubscription{Filters: with(func(f *SubscriptionFilters) {
+				f.IssueTypes = NewStringSet("20001")
+				f.IssueTypeNames = map[string]string{"20001": "Bug"}
+			})},
+			b: ChannelSubscription{Filters: with(func(f *SubscriptionFilters) {
+				f.IssueTypeNames = map[string]string{"10001": "Bug"}
+			})},
+			expected: true,
+		},
+		"field filter": {
+			a: ChannelSubscription{Filters: base},
+			b: ChannelSubscription{Filters: with(func(f *SubscriptionFilters) {
+				f.Fields = []FieldFilter{{Key: "priority", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("1")}}
+			})},
+			expected: true,
+		},
+		"narrowed by a field filter": {
+			a: ChannelSubscription{Filters: with(func(f *SubscriptionFilters) {
+				f.Fields = []FieldFilter{{Key: "priority", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("1")}}
+			})},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+		"narrowed by ignored actors": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.IgnoredActors = []string{"bot"} })},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+		"narrowed by a JQL filter": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.JQL = "labels = release" })},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+		"same JQL filter": {
+			a:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.JQL = "labels = release" })},
+			b:        ChannelSubscription{Filters: with(func(f *SubscriptionFilters) { f.JQL = "labels = release" })},
+			expected: true,
+		},
+		"filter subscription": {
+			a:        ChannelSubscription{FilterId: "10000"},
+			b:        ChannelSubscription{FilterId: "10000"},
+			expected: false,
+		},
+		"same issue": {
+			a:        ChannelSubscription{IssueKey: "TES-1"},
+			b:        ChannelSubscription{IssueKey: "TES-1"},
+			expected: true,
+		},
+		"other issue": {
+			a:        ChannelSubscription{IssueKey: "TES-1"},
+			b:        ChannelSubscription{IssueKey: "TES-2"},
+			expected: false,
+		},
+		"project events": {
+			a:        ChannelSubscription{ProjectEvents: true},
+			b:        ChannelSubscription{Filters: base},
+			expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, subscriptionCovers(&tc.a, &tc.b))
+		})
+	}
+}
+
+func TestSubscriptionConflictWarnings(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	bugs := SubscriptionFilters{Events: NewStringSet(eventCreated), Projects: NewStringSet("TES"), IssueTypes: NewStringSet("10001")}
+	all := SubscriptionFilters{Events: NewStringSet(eventCreated, eventCreatedComment), Projects: NewStringSet("TES"), IssueTypes: NewStringSet("10001")}
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "Bugs", Filters: bugs})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel1", Name: "All", Filters: all})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel1", Name: "Paused", Filters: bugs, Paused: true})
+	subs.Channel.add(&ChannelSubscription{Id: "sub4", ChannelId: "channel2", Name: "Other channel", Filters: bugs})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+	kv[keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)] = stored
+
+	warnings, err := p.subscriptionConflictWarnings(&ChannelSubscription{Id: "sub5", ChannelId: "channel1", Name: "New bugs", Filters: bugs})
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], `Subscription "All" already posts all the events of "New bugs".`)
+	assert.Contains(t, warnings[0], "/jira subscribe overlap first")
+	assert.Contains(t, warnings[1], `Subscription "Bugs" posts the same events as "New bugs".`)
+
+	// Editing a subscription doesn't compare it with itself
+	warnings, err = p.subscriptionConflictWarnings(&ChannelSubscription{Id: "sub2", ChannelId: "channel1", Name: "All", Filters: all})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `Subscription "All" posts all the events of "Bugs".`)
+
+	warnings, err = p.subscriptionConflictWarnings(&ChannelSubscription{Id: "sub5", ChannelId: "channel1", Name: "Comments",
+		Filters: SubscriptionFilters{Events: NewStringSet(eventCreatedComment), Projects: NewStringSet("OTHER")}})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
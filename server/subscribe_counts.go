@@ -0,0 +1,146 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// channelSubscriptionWithCount is a subscription listed with the number of
+// its open issues.
+type channelSubscriptionWithCount struct {
+	ChannelSubscription
+	OpenIssues *int `json:"open_issues,omitempty"`
+}
+
+const (
+	prefixJQLCount = "jql_count_"
+
+	// Listings show open issue counts up to this old.
+	jqlCountCacheSeconds = 10 * 60
+
+	// The open issue counts of a listing are queried this many at a time,
+	// and the listing leaves out those not counted within
+	// subscriptionCountsTimeout.
+	subscriptionCountsConcurrency = 4
+	subscriptionCountsTimeout     = 10 * time.Second
+)
+
+// countJQLIssues returns the number of issues matching jql, without fetching
+// them.
+func countJQLIssues(client Client, jql string) (int, error) {
+	result := struct {
+		Total int `json:"total"`
+	}{}
+	err := client.RESTGet("2/search", map[string]string{"jql": jql, "maxResults": "0", "fields": "key"}, &result)
+	if err != nil {
+		return 0, err
+	}
+	return result.Total, nil
+}
+
+// cachedJQLCount returns the number of issues matching jql, as visible to the
+// user, from the cache if it was counted in the last jqlCountCacheSeconds.
+func (p *Plugin) cachedJQLCount(ji Instance, client Client, mattermostUserId, jql string) (int, error) {
+	key := keyWithInstance(ji, hashkey(prefixJQLCount, mattermostUserId+"/"+jql))
+	data, appErr := p.API.KVGet(key)
+	if appErr == nil && len(data) > 0 {
+		if count, err := strconv.Atoi(string(data)); err == nil {
+			return count, nil
+		}
+	}
+
+	count, err := countJQLIssues(client, jql)
+	if err != nil {
+		return 0, err
+	}
+	appErr = p.API.KVSetWithExpiry(key, []byte(strconv.Itoa(count)), jqlCountCacheSeconds)
+	if appErr != nil {
+		p.debugf("cachedJQLCount: failed to cache the count: %v", appErr)
+	}
+	return count, nil
+}
+
+// subscriptionOpenIssuesCounts returns the numbers of unresolved issues
+// matching the subscriptions' projects and issue types, filters or issue
+// keys, by subscription ID. Counts are queried as the user, and left out if
+// the user is not connected, they can't be counted, or they take more than
+// subscriptionCountsTimeout. The subscriptions with the same issues are
+// counted once.
+func (p *Plugin) subscriptionOpenIssuesCounts(ji Instance, mattermostUserId string, subs []ChannelSubscription) map[string]int {
+	counts := map[string]int{}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return counts
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return counts
+	}
+
+	subIdsByJQL := map[string][]string{}
+	for _, sub := range subs {
+		clause := subscriptionIssuesClause(sub)
+		if clause == "" {
+			continue
+		}
+		jql := "(" + clause + ") AND statusCategory != Done"
+		subIdsByJQL[jql] = append(subIdsByJQL[jql], sub.Id)
+	}
+
+	type jqlCount struct {
+		jql   string
+		count int
+		err   error
+	}
+	// Buffered for all the queries, so that those finishing after the
+	// timeout don't block.
+	results := make(chan jqlCount, len(subIdsByJQL))
+	limiter := make(chan struct{}, subscriptionCountsConcurrency)
+	for jql := range subIdsByJQL {
+		go func(jql string) {
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+			count, err := p.cachedJQLCount(ji, client, mattermostUserId, jql)
+			results <- jqlCount{jql, count, err}
+		}(jql)
+	}
+
+	timeout := time.After(subscriptionCountsTimeout)
+	for counted := 0; counted < len(subIdsByJQL); counted++ {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				p.debugf("subscriptionOpenIssuesCounts: failed to count the open issues of %q: %v", result.jql, result.err)
+				continue
+			}
+			for _, subId := range subIdsByJQL[result.jql] {
+				counts[subId] = result.count
+			}
+		case <-timeout:
+			p.debugf("subscriptionOpenIssuesCounts: counted %d of %d queries in %v", counted, len(subIdsByJQL), subscriptionCountsTimeout)
+			return counts
+		}
+	}
+	return counts
+}
+
+func (p *Plugin) withOpenIssuesCounts(mattermostUserId string, subs []ChannelSubscription) ([]channelSubscriptionWithCount, error) {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil, err
+	}
+	counts := p.subscriptionOpenIssuesCounts(ji, mattermostUserId, subs)
+
+	result := []channelSubscriptionWithCount{}
+	for _, sub := range subs {
+		withCount := channelSubscriptionWithCount{ChannelSubscription: sub}
+		if count, ok := counts[sub.Id]; ok {
+			withCount.OpenIssues = &count
+		}
+		result = append(result, withCount)
+	}
+	return result, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type countingClient struct {
+	testClient
+	total   int
+	queries *[]string
+}
+
+func (client countingClient) RESTGet(endpoint string, params map[string]string, dest interface{}) error {
+	*client.queries = append(*client.queries, params["jql"])
+	return json.Unmarshal([]byte(fmt.Sprintf(`{"total":%d}`, client.total)), dest)
+}
+
+func TestCachedJQLCount(t *testing.T) {
+	cache := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return cache[key] }, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(jqlCountCacheSeconds)).Run(func(args mock.Arguments) {
+		cache[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+
+	queries := []string{}
+	client := countingClient{total: 12, queries: &queries}
+
+	count, err := p.cachedJQLCount(ji, client, "user1", "project = EXT")
+	require.NoError(t, err)
+	assert.Equal(t, 12, count)
+
+	client.total = 13
+	count, err = p.cachedJQLCount(ji, client, "user1", "project = EXT")
+	require.NoError(t, err)
+	assert.Equal(t, 12, count, "the cached count is returned")
+
+	count, err = p.cachedJQLCount(ji, client, "user2", "project = EXT")
+	require.NoError(t, err)
+	assert.Equal(t, 13, count, "counts are cached by user")
+
+	assert.Equal(t, []string{"project = EXT", "project = EXT"}, queries)
+}
+
+func TestChannelSubscriptionWithCountJSON(t *testing.T) {
+	count := 3
+	data, err := json.Marshal([]channelSubscriptionWithCount{
+		{ChannelSubscription: ChannelSubscription{Id: "sub1", Name: "Bugs"}, OpenIssues: &count},
+		{ChannelSubscription: ChannelSubscription{Id: "sub2", Name: "Project events", ProjectEvents: true}},
+	})
+	require.NoError(t, err)
+
+	decoded := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "sub1", decoded[0]["id"])
+	assert.Equal(t, float64(3), decoded[0]["open_issues"])
+	assert.NotContains(t, decoded[1], "open_issues")
+}
+
+type concurrentCountingClient struct {
+	testClient
+	lock     *sync.Mutex
+	running  *int
+	max      *int
+	queries  *[]string
+	duration time.Duration
+}
+
+func (client concurrentCountingClient) RESTGet(endpoint string, params map[string]string, dest interface{}) error {
+	client.lock.Lock()
+	*client.queries = append(*client.queries, params["jql"])
+	*client.running++
+	if *client.running > *client.max {
+		*client.max = *client.running
+	}
+	client.lock.Unlock()
+
+	time.Sleep(client.duration)
+
+	client.lock.Lock()
+	*client.running--
+	client.lock.Unlock()
+	return json.Unmarshal([]byte(`{"total":7}`), dest)
+}
+
+type countsTestInstance struct {
+	jiraTestInstance
+	client Client
+}
+
+func (ji countsTestInstance) GetClient(jiraUser JIRAUser) (Client, error) {
+	return ji.client, nil
+}
+
+func TestSubscriptionOpenIssuesCounts(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(jqlCountCacheSeconds)).Return(nil)
+	p := &Plugin{userStore: mockUserStore{}}
+	p.SetAPI(api)
+
+	queries := []string{}
+	running, max := 0, 0
+	ji := countsTestInstance{
+		jiraTestInstance: jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")},
+		client: concurrentCountingClient{
+			lock:     &sync.Mutex{},
+			running:  &running,
+			max:      &max,
+			queries:  &queries,
+			duration: 10 * time.Millisecond,
+		},
+	}
+
+	subs := []ChannelSubscription{
+		{Id: "same1", IssueKey: "TEST-1"},
+		{Id: "same2", IssueKey: "TEST-1"},
+		{Id: "project", ProjectEvents: true},
+	}
+	for i := 0; i < 3*subscriptionCountsConcurrency; i++ {
+		subs = append(subs, ChannelSubscription{Id: fmt.Sprintf("sub%d", i), FilterId: fmt.Sprintf("%d", 10000+i)})
+	}
+
+	counts := p.subscriptionOpenIssuesCounts(&ji, "userid", subs)
+	assert.Len(t, counts, len(subs)-1)
+	assert.Equal(t, 7, counts["same1"])
+	assert.Equal(t, 7, counts["same2"])
+	assert.NotContains(t, counts, "project")
+	assert.Len(t, queries, 1+3*subscriptionCountsConcurrency, "the subscriptions with the same issues are counted once")
+	assert.LessOrEqual(t, max, subscriptionCountsConcurrency)
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	prefixUpdatesDigest = "updates_digest_"
+
+	updatesDigestInterval = time.Hour
+
+	// A digest lists at most this many issues, and counts the others.
+	updatesDigestMaxIssues = 50
+)
+
+// digestImmediateEvents are posted right away even in the channels where the
+// subscriptions roll the other events into a digest.
+var digestImmediateEvents = NewStringSet(eventCreated, eventDeleted)
+
+// updatesDigest is the summary of the events of a channel not posted yet, by
+// issue in the order of their first event.
+type updatesDigest struct {
+	Issues     []updatesDigestIssue `json:"issues"`
+	MoreIssues StringSet            `json:"more_issues,omitempty"`
+	Events     int                  `json:"events"`
+}
+
+type updatesDigestIssue struct {
+	Key    string   `json:"key"`
+	Link   string   `json:"link"`
+	Events int      `json:"events"`
+	Actors []string `json:"actors"`
+}
+
+func (d *updatesDigest) add(wh *webhook) {
+	d.Events++
+	key := wh.JiraWebhook.Issue.Key
+	actor := wh.JiraWebhook.User.DisplayName
+	for i := range d.Issues {
+		if d.Issues[i].Key != key {
+			continue
+		}
+		d.Issues[i].Events++
+		if actor != "" && !NewStringSet(d.Issues[i].Actors...).ContainsAny(actor) {
+			d.Issues[i].Actors = append(d.Issues[i].Actors, actor)
+		}
+		return
+	}
+	if len(d.Issues) >= updatesDigestMaxIssues {
+		d.MoreIssues = d.MoreIssues.Add(key)
+		return
+	}
+	issue := updatesDigestIssue{Key: key, Link: wh.JiraWebhook.mdKeySummaryLink(), Events: 1}
+	if actor != "" {
+		issue.Actors = []string{actor}
+	}
+	d.Issues = append(d.Issues, issue)
+}
+
+// subtract removes the events of posted, an earlier state of the digest,
+// leaving the events added since then. The issues without events left are
+// removed.
+func (d *updatesDigest) subtract(posted *updatesDigest) {
+	d.Events -= posted.Events
+	postedEvents := map[string]int{}
+	for _, issue := range posted.Issues {
+		postedEvents[issue.Key] = issue.Events
+	}
+	issues := []updatesDigestIssue{}
+	for _, issue := range d.Issues {
+		issue.Events -= postedEvents[issue.Key]
+		if issue.Events > 0 {
+			issues = append(issues, issue)
+		}
+	}
+	d.Issues = issues
+	d.MoreIssues = d.MoreIssues.Subtract(posted.MoreIssues.Elems()...)
+}
+
+// getDigestChannels returns the channels where all the subscriptions matching
+// the webhook roll its event into the updates digest.
+func (p *Plugin) getDigestChannels(wh *webhook) (StringSet, error) {
+	if wh.Events().Intersection(digestImmediateEvents).Len() > 0 || wh.JiraWebhook.Issue.Key == "" {
+		return NewStringSet(), nil
+	}
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, immediate := NewStringSet(), NewStringSet()
+	for _, sub := range p.matchingChannelSubscriptions(subs, wh) {
+		if sub.Filters.UpdatesDigest {
+			digest = digest.Add(sub.ChannelId)
+		} else {
+			immediate = immediate.Add(sub.ChannelId)
+		}
+	}
+	return digest.Subtract(immediate.Elems()...), nil
+}
+
+// addToUpdatesDigest records the webhook event in the updates digest of the
+// channel, to be posted by postUpdatesDigests.
+func (p *Plugin) addToUpdatesDigest(channelId string, wh *webhook) error {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+	return p.atomicModify(keyWithInstance(ji, prefixUpdatesDigest+channelId), func(initialBytes []byte) ([]byte, error) {
+		digest := updatesDigest{}
+		if len(initialBytes) != 0 {
+			err := json.Unmarshal(initialBytes, &digest)
+			if err != nil {
+				return nil, err
+			}
+		}
+		digest.add(wh)
+		return json.Marshal(&digest)
+	})
+}
+
+// postUpdatesDigests posts, and clears, the updates digests of the channels
+// with subscriptions rolling their events into a digest. A digest is only
+// cleared once posted, a digest that failed to post is posted with the
+// events of the next hour.
+func (p *Plugin) postUpdatesDigests() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("postUpdatesDigests: failed to load subscriptions: %v", err)
+		return
+	}
+
+	channelIds := NewStringSet()
+	for _, sub := range subs.Channel.ById {
+		if sub.Filters.UpdatesDigest {
+			channelIds = channelIds.Add(sub.ChannelId)
+		}
+	}
+	for _, channelId := range channelIds.Elems() {
+		key := keyWithInstance(ji, prefixUpdatesDigest+channelId)
+		postedBytes, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			p.errorf("postUpdatesDigests: failed to load the digest of channel %s: %v", channelId, appErr)
+			continue
+		}
+		if len(postedBytes) == 0 {
+			continue
+		}
+		digest := &updatesDigest{}
+		err = json.Unmarshal(postedBytes, digest)
+		if err != nil {
+			p.errorf("postUpdatesDigests: failed to load the digest of channel %s: %v", channelId, err)
+			continue
+		}
+		if digest.Events == 0 {
+			continue
+		}
+
+		_, appErr = p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: channelId,
+			Message:   p.renderUpdatesDigest(p.channelLocale(channelId), digest),
+		})
+		if appErr != nil {
+			p.errorf("postUpdatesDigests: failed to post the digest to channel %s: %v", channelId, appErr)
+			continue
+		}
+
+		err = p.clearPostedUpdatesDigest(key, postedBytes, digest)
+		if err != nil {
+			p.errorf("postUpdatesDigests: failed to clear the digest of channel %s: %v", channelId, err)
+		}
+	}
+}
+
+// clearPostedUpdatesDigest clears the digest stored at key, posted when it was
+// postedBytes. The events added while it was posted are kept for the next
+// digest.
+func (p *Plugin) clearPostedUpdatesDigest(key string, postedBytes []byte, posted *updatesDigest) error {
+	cleared, appErr := p.API.KVCompareAndSet(key, postedBytes, nil)
+	if appErr != nil {
+		return appErr
+	}
+	if cleared {
+		return nil
+	}
+	return p.atomicModify(key, func(initialBytes []byte) ([]byte, error) {
+		if len(initialBytes) == 0 {
+			return nil, nil
+		}
+		digest := updatesDigest{}
+		err := json.Unmarshal(initialBytes, &digest)
+		if err != nil {
+			return nil, err
+		}
+		digest.subtract(posted)
+		if digest.Events <= 0 {
+			return nil, nil
+		}
+		return json.Marshal(&digest)
+	})
+}
+
+func (p *Plugin) renderUpdatesDigest(locale string, digest *updatesDigest) string {
+	lines := []string{p.localize(locale, msgUpdatesDigestHeader, digest.Events, len(digest.Issues)+digest.MoreIssues.Len())}
+	for _, issue := range digest.Issues {
+		lines = append(lines, p.localize(locale, msgUpdatesDigestIssue, issue.Link, issue.Events, strings.Join(issue.Actors, ", ")))
+	}
+	if n := digest.MoreIssues.Len(); n > 0 {
+		lines = append(lines, p.localize(locale, msgUpdatesDigestMore, n))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func executeSubscribeDigest(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 || (args[0] != "on" && args[0] != "off") {
+		return p.responsef(header, "Please use `/jira subscribe digest <on|off> <subscription name>`.")
+	}
+	on := args[0] == "on"
+	name := strings.Join(args[1:], " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		sub.Filters.UpdatesDigest = on
+		if on {
+			return "Subscription %q now posts issue creations and deletions right away, and the other events in an hourly digest."
+		}
+		return "Subscription %q now posts all events right away."
+	})
+}
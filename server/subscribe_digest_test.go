@@ -0,0 +1,187 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestUpdatesDigest(t *testing.T) {
+	event := func(key, actor string) *webhook {
+		return &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{
+			Issue: jira.Issue{
+				Key:    key,
+				Self:   "https://jira.example.com/rest/api/2/issue/1",
+				Fields: &jira.IssueFields{Summary: "Summary of " + key, Type: jira.IssueType{Name: "Bug"}},
+			},
+			User: jira.User{DisplayName: actor},
+		}}}
+	}
+
+	digest := updatesDigest{}
+	digest.add(event("TES-1", "Alice"))
+	digest.add(event("TES-2", "Bob"))
+	digest.add(event("TES-1", "Bob"))
+	digest.add(event("TES-1", "Alice"))
+	for i := 0; i < updatesDigestMaxIssues; i++ {
+		digest.add(event(fmt.Sprintf("MORE-%d", i), "Carol"))
+	}
+	digest.add(event("MORE-0", "Carol"))
+
+	assert.Equal(t, 4+updatesDigestMaxIssues+1, digest.Events)
+	require.Len(t, digest.Issues, updatesDigestMaxIssues)
+	assert.Equal(t, updatesDigestIssue{
+		Key:    "TES-1",
+		Link:   "bug [TES-1: Summary of TES-1](https://jira.example.com/browse/TES-1)",
+		Events: 3,
+		Actors: []string{"Alice", "Bob"},
+	}, digest.Issues[0])
+	assert.ElementsMatch(t, []string{"MORE-48", "MORE-49"}, digest.MoreIssues.Elems())
+
+	p := &Plugin{}
+	rendered := p.renderUpdatesDigest("en", &updatesDigest{
+		Issues: digest.Issues[:2],
+		Events: 4,
+	})
+	assert.Equal(t, "**Jira digest** of the last hour, events: 4, issues: 2\n"+
+		"* bug [TES-1: Summary of TES-1](https://jira.example.com/browse/TES-1), events: 3, by: Alice, Bob\n"+
+		"* bug [TES-2: Summary of TES-2](https://jira.example.com/browse/TES-2), events: 1, by: Bob", rendered)
+}
+
+func TestGetDigestChannels(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	filters := SubscriptionFilters{
+		Events:     NewStringSet(eventCreated, eventUpdatedAny),
+		Projects:   NewStringSet("TES"),
+		IssueTypes: NewStringSet("10001"),
+	}
+	digestFilters := filters
+	digestFilters.UpdatesDigest = true
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		{Id: model.NewId(), ChannelId: "digest", Filters: digestFilters},
+		{Id: model.NewId(), ChannelId: "mixed", Filters: digestFilters},
+		{Id: model.NewId(), ChannelId: "mixed", Filters: filters},
+		{Id: model.NewId(), ChannelId: "immediate", Filters: filters},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	data, err := getJiraTestData("webhook-issue-updated-labels.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+	digestChannelIds, err := p.getDigestChannels(wh.(*webhook))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"digest"}, digestChannelIds.Elems())
+
+	data, err = getJiraTestData("webhook-issue-created.json")
+	require.Nil(t, err)
+	wh, err = ParseWebhook(data)
+	require.Nil(t, err)
+	digestChannelIds, err = p.getDigestChannels(wh.(*webhook))
+	require.Nil(t, err)
+	assert.Empty(t, digestChannelIds.Elems())
+}
+
+func TestPostUpdatesDigests(t *testing.T) {
+	event := func(key string) *webhook {
+		return &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{
+			Issue: jira.Issue{
+				Key:    key,
+				Self:   "https://jira.example.com/rest/api/2/issue/1",
+				Fields: &jira.IssueFields{Summary: "Summary of " + key, Type: jira.IssueType{Name: "Bug"}},
+			},
+			User: jira.User{DisplayName: "Alice"},
+		}}}
+	}
+	digestKey := keyWithMockInstance(prefixUpdatesDigest + "digest")
+
+	for name, tc := range map[string]struct {
+		postErr        *model.AppError
+		addWhilePosted bool
+		expectedEvents int
+		expectedIssues []string
+	}{
+		"posted":                     {expectedEvents: 0},
+		"failed to post":             {postErr: &model.AppError{Message: "failed"}, expectedEvents: 2, expectedIssues: []string{"TES-1", "TES-2"}},
+		"events added while posting": {addWhilePosted: true, expectedEvents: 2, expectedIssues: []string{"TES-1", "TES-3"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := &Plugin{}
+			api := &plugintest.API{}
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+			filters := SubscriptionFilters{Events: NewStringSet(eventUpdatedAny), UpdatesDigest: true}
+			subscriptionBytes, err := json.Marshal(withExistingChannelSubscriptions([]ChannelSubscription{
+				{Id: model.NewId(), ChannelId: "digest", Filters: filters},
+			}))
+			require.NoError(t, err)
+			digest := updatesDigest{}
+			digest.add(event("TES-1"))
+			digest.add(event("TES-2"))
+			digestBytes, err := json.Marshal(&digest)
+			require.NoError(t, err)
+			kv := map[string][]byte{
+				keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY): subscriptionBytes,
+				digestKey: digestBytes,
+			}
+
+			api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+			api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(
+				func(key string, oldValue, newValue []byte) bool {
+					if string(kv[key]) != string(oldValue) {
+						return false
+					}
+					kv[key] = newValue
+					return true
+				}, nil)
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				if !tc.addWhilePosted {
+					return
+				}
+				current := updatesDigest{}
+				require.NoError(t, json.Unmarshal(kv[digestKey], &current))
+				current.add(event("TES-1"))
+				current.add(event("TES-3"))
+				kv[digestKey], err = json.Marshal(&current)
+				require.NoError(t, err)
+			}).Return(&model.Post{}, tc.postErr)
+			api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			p.postUpdatesDigests()
+
+			api.AssertNumberOfCalls(t, "CreatePost", 1)
+			if tc.expectedEvents == 0 {
+				assert.Empty(t, kv[digestKey])
+				return
+			}
+			remaining := updatesDigest{}
+			require.NoError(t, json.Unmarshal(kv[digestKey], &remaining))
+			assert.Equal(t, tc.expectedEvents, remaining.Events)
+			keys := []string{}
+			for _, issue := range remaining.Issues {
+				keys = append(keys, issue.Key)
+			}
+			assert.Equal(t, tc.expectedIssues, keys)
+		})
+	}
+}
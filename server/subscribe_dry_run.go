@@ -0,0 +1,297 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// How many of the last webhook events are kept to dry-run the subscriptions.
+const maxRecentEvents = 50
+
+// The fields of the events that are blanked before the events are kept, since
+// their content is not needed to match the subscriptions.
+var redactedEventFields = NewStringSet("description", "environment", "body", "emailAddress")
+
+// recentEvent is a redacted webhook event, with the issue as it was expanded
+// when the event was processed.
+type recentEvent struct {
+	receivedAt time.Time
+	instanceId string
+	data       []byte
+	issue      jira.Issue
+}
+
+// recentEvents is a ring buffer of the last webhook events processed by this
+// server.
+type recentEvents struct {
+	lock   sync.Mutex
+	events []recentEvent
+	next   int
+}
+
+func (re *recentEvents) add(event recentEvent) {
+	re.lock.Lock()
+	defer re.lock.Unlock()
+	if len(re.events) < maxRecentEvents {
+		re.events = append(re.events, event)
+		return
+	}
+	re.events[re.next] = event
+	re.next = (re.next + 1) % maxRecentEvents
+}
+
+// list returns the events, oldest first.
+func (re *recentEvents) list() []recentEvent {
+	re.lock.Lock()
+	defer re.lock.Unlock()
+	events := make([]recentEvent, 0, len(re.events))
+	events = append(events, re.events[re.next:]...)
+	return append(events, re.events[:re.next]...)
+}
+
+// recordRecentEvent keeps a redacted copy of a processed webhook event.
+func (p *Plugin) recordRecentEvent(receivedAt time.Time, rawData []byte, wh *webhook) {
+	data, err := redactEvent(rawData)
+	if err != nil {
+		p.debugf("recordRecentEvent: failed to redact the event: %v", err)
+		return
+	}
+	p.recentEvents.add(recentEvent{
+		receivedAt: receivedAt,
+		instanceId: wh.instanceId,
+		data:       data,
+		issue:      redactIssue(wh.Issue),
+	})
+}
+
+func redactEvent(rawData []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(rawData))
+	// Keep the numbers as they are, the timestamps don't fit in a float64
+	decoder.UseNumber()
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(v))
+}
+
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if redactedEventFields.ContainsAny(key) {
+				value[key] = redactedValue(child)
+				continue
+			}
+			value[key] = redactValue(child)
+		}
+
+		// The changelog items of the redacted fields
+		if field, _ := value["field"].(string); redactedEventFields.ContainsAny(field) {
+			for _, key := range []string{"from", "fromString", "to", "toString"} {
+				if _, ok := value[key]; ok {
+					value[key] = redactedValue(value[key])
+				}
+			}
+		}
+
+	case []interface{}:
+		for i := range value {
+			value[i] = redactValue(value[i])
+		}
+	}
+	return v
+}
+
+func redactedValue(v interface{}) interface{} {
+	if _, ok := v.(string); ok {
+		return ""
+	}
+	return nil
+}
+
+func redactIssue(issue jira.Issue) jira.Issue {
+	issue.RenderedFields = nil
+	issue.Changelog = nil
+	if issue.Fields != nil {
+		fields := *issue.Fields
+		fields.Description = ""
+		fields.Comments = nil
+		if _, ok := fields.Unknowns["environment"]; ok {
+			unknowns := map[string]interface{}{}
+			for key, value := range fields.Unknowns {
+				unknowns[key] = value
+			}
+			delete(unknowns, "environment")
+			fields.Unknowns = unknowns
+		}
+		issue.Fields = &fields
+	}
+	return issue
+}
+
+type dryRunMatch struct {
+	ReceivedAt time.Time `json:"received_at"`
+	IssueKey   string    `json:"issue_key,omitempty"`
+	Headline   string    `json:"headline"`
+	Events     []string  `json:"events"`
+}
+
+type dryRunResult struct {
+	// Evaluated is how many of the recent events the subscription was
+	// matched against.
+	Evaluated int           `json:"evaluated"`
+	Matched   []dryRunMatch `json:"matched"`
+}
+
+// httpSubscriptionsDryRun matches a subscription that is not saved yet with
+// the recent webhook events, and returns the events that the subscription
+// would have posted, newest first. Only the events of the issues the user can
+// see in Jira, in one search honoring the issue security levels, and the
+// project events of the projects they can see are returned.
+func httpSubscriptionsDryRun(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("Request: " + r.Method + " is not allowed, must be POST")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	subscription := ChannelSubscription{}
+	err := json.NewDecoder(r.Body).Decode(&subscription)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to decode incoming request")
+	}
+	if len(subscription.ChannelId) != 26 {
+		return http.StatusBadRequest, errors.New("Channel subscription invalid")
+	}
+	if subscription.FilterId != "" {
+		return http.StatusBadRequest, errors.New("The events of a filter subscription are matched by Jira, they can't be tested")
+	}
+
+	_, appErr := p.API.GetChannelMember(subscription.ChannelId, mattermostUserId)
+	if appErr != nil {
+		return http.StatusForbidden, errors.New("Not a member of the channel specified")
+	}
+
+	err = p.hasPermissionToManageSubscription(mattermostUserId, subscription.ChannelId)
+	if err != nil {
+		return http.StatusForbidden, errors.Wrap(err, "you don't have permission to manage subscriptions")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// The draft is matched as a new subscription
+	subscription.Id = ""
+	subscription.NonCompliant = ""
+	subscription.Paused = false
+	subscription.CreatorId = mattermostUserId
+
+	// The JQL filter is searched once for all the events, with the check of
+	// the issues the user can see, below
+	jql := subscription.Filters.JQL
+	subscription.Filters.JQL = ""
+
+	conf := p.getConfig()
+	visibleProjects := map[string]bool{}
+	result := dryRunResult{Matched: []dryRunMatch{}}
+	events := p.recentEvents.list()
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if event.instanceId != "" && event.instanceId != ji.GetURL() {
+			continue
+		}
+		parsed, err := ParseWebhookWithOptions(event.data, conf.webhookParseOptions)
+		if err != nil {
+			continue
+		}
+		wh := parsed.(*webhook)
+		wh.instanceId = event.instanceId
+		wh.Issue = event.issue
+		result.Evaluated++
+
+		isProjectEvent := wh.Events().Intersection(projectEvents).Len() > 0
+		if !p.matchesChannelSubscription(wh, subscription, isProjectEvent) {
+			continue
+		}
+		if jql != "" && wh.JiraWebhook.IssueKey() == "" {
+			continue
+		}
+
+		projectKey := wh.Project.Key
+		if wh.Issue.Fields != nil {
+			projectKey = wh.Issue.Fields.Project.Key
+		}
+		if projectKey == "" {
+			continue
+		}
+		visible, ok := visibleProjects[projectKey]
+		if !ok {
+			_, err = client.GetProject(projectKey)
+			visible = err == nil
+			visibleProjects[projectKey] = visible
+		}
+		if !visible {
+			continue
+		}
+
+		result.Matched = append(result.Matched, dryRunMatch{
+			ReceivedAt: event.receivedAt,
+			IssueKey:   wh.Issue.Key,
+			Headline:   wh.headline,
+			Events:     wh.Events().Elems(),
+		})
+	}
+
+	keys := NewStringSet()
+	for _, match := range result.Matched {
+		if match.IssueKey != "" {
+			keys = keys.Add(match.IssueKey)
+		}
+	}
+	issueKeys := keys.Elems()
+	sort.Strings(issueKeys)
+	matchedKeys, err := issuesMatchingJQL(client, issueKeys, jql)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	matched := []dryRunMatch{}
+	for _, match := range result.Matched {
+		if match.IssueKey == "" || matchedKeys.ContainsAny(match.IssueKey) {
+			matched = append(matched, match)
+		}
+	}
+	result.Matched = matched
+
+	w.Header().Set("Content-Type", "application/json")
+	b, _ := json.Marshal(result)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
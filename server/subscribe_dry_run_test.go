@@ -0,0 +1,151 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentEvents(t *testing.T) {
+	re := recentEvents{}
+	for i := 0; i < maxRecentEvents+2; i++ {
+		re.add(recentEvent{data: []byte(strconv.Itoa(i))})
+	}
+	events := re.list()
+	require.Len(t, events, maxRecentEvents)
+	assert.Equal(t, "2", string(events[0].data), "the oldest events are dropped")
+	assert.Equal(t, strconv.Itoa(maxRecentEvents+1), string(events[maxRecentEvents-1].data))
+}
+
+func TestRedactEvent(t *testing.T) {
+	data, err := getJiraTestData("webhook-issue-updated-edited.json")
+	require.NoError(t, err)
+	redacted, err := redactEvent(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(redacted), "some-instance-test@gmail.com")
+
+	parsed, err := ParseWebhook(redacted)
+	require.NoError(t, err)
+	wh := parsed.(*webhook)
+	assert.Equal(t, "", wh.Issue.Fields.Description)
+	for _, item := range wh.ChangeLog.Items {
+		if item.Field == "description" {
+			assert.Equal(t, "", item.FromString)
+			assert.Equal(t, "", item.ToString)
+		}
+	}
+
+	original, err := ParseWebhook(data)
+	require.NoError(t, err)
+	assert.Equal(t, original.Events(), wh.Events())
+	assert.Equal(t, original.(*webhook).Issue.Key, wh.Issue.Key)
+}
+
+func TestSubscriptionsDryRun(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, (*model.AppError)(nil))
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), mock.Anything).Return(true)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
+	p.SetAPI(api)
+	client := &jqlTestClient{}
+	p.currentInstanceStore = jqlTestInstanceStore{&countsTestInstance{
+		jiraTestInstance: jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")},
+		client:           client,
+	}}
+	p.userStore = mockUserStore{}
+
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.NoError(t, err)
+	parsed, err := ParseWebhook(data)
+	require.NoError(t, err)
+	receivedAt := time.Unix(1550286113, 0)
+	p.recordRecentEvent(receivedAt, data, parsed.(*webhook))
+
+	for name, tc := range map[string]struct {
+		method             string
+		skipAuthorize      bool
+		hiddenIssue        bool
+		subscription       string
+		expectedStatusCode int
+		expectedMatched    int
+	}{
+		"Not a POST": {
+			method:             http.MethodGet,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		"Not authorized": {
+			skipAuthorize:      true,
+			subscription:       `{"channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "filters": {"events": ["event_created"], "projects": ["TES"]}}`,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		"Invalid channel": {
+			subscription:       `{"channel_id": "aaa", "filters": {"events": ["event_created"], "projects": ["TES"]}}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Filter subscription": {
+			subscription:       `{"channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "filter_id": "10000"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Matched": {
+			subscription:       `{"channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "filters": {"events": ["event_created"], "projects": ["TES"], "issue_types": ["10001"]}}`,
+			expectedStatusCode: http.StatusOK,
+			expectedMatched:    1,
+		},
+		"Issue hidden by its security level": {
+			hiddenIssue:        true,
+			subscription:       `{"channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "filters": {"events": ["event_created"], "projects": ["TES"], "issue_types": ["10001"]}}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		"Not matched": {
+			subscription:       `{"channel_id": "aaaaaaaaaaaaaaaaaaaaaaaaab", "filters": {"events": ["event_deleted"], "projects": ["TES"]}}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			client.results = []jira.Issue{{Key: "TES-41"}}
+			if tc.hiddenIssue {
+				client.results = nil
+			}
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(method, routeAPISubscriptionsDryRun, bytes.NewBufferString(tc.subscription))
+			if !tc.skipAuthorize {
+				request.Header.Set("Mattermost-User-Id", model.NewId())
+			}
+			status, err := handleHTTPRequest(p, &plugin.Context{}, w, request)
+			require.Equal(t, tc.expectedStatusCode, status, "error: %v", err)
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			result := dryRunResult{}
+			require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&result))
+			assert.Equal(t, 1, result.Evaluated)
+			require.Len(t, result.Matched, tc.expectedMatched)
+			if tc.expectedMatched > 0 {
+				assert.Equal(t, "TES-41", result.Matched[0].IssueKey)
+				assert.Equal(t, []string{eventCreated}, result.Matched[0].Events)
+				assert.True(t, receivedAt.Equal(result.Matched[0].ReceivedAt))
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	prefixFilterSubscription = "filtersub_"
+
+	filterSubscriptionPollInterval = 5 * time.Minute
+	filterSubscriptionMaxResults   = 50
+)
+
+// filterSubscriptionState records the issues that matched a filter subscription
+// on its last evaluation, so that only newly matching issues are posted.
+type filterSubscriptionState struct {
+	IssueKeys StringSet `json:"issue_keys"`
+}
+
+func (p *Plugin) validateFilterSubscription(subscription *ChannelSubscription, client Client) error {
+	err := p.validateSubscriptionName(subscription)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.GetFilter(subscription.FilterId)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get filter %q", subscription.FilterId)
+	}
+	return nil
+}
+
+func (p *Plugin) pollFilterSubscriptions() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("pollFilterSubscriptions: failed to load subscriptions: %v", err)
+		return
+	}
+
+	for _, sub := range subs.Channel.ById {
+		if sub.FilterId == "" || sub.Paused {
+			continue
+		}
+		err = p.pollFilterSubscription(ji, sub)
+		if err != nil {
+			p.errorf("pollFilterSubscriptions: subscription %q (filter %s): %v", sub.Name, sub.FilterId, err)
+		}
+	}
+}
+
+func (p *Plugin) pollFilterSubscription(ji Instance, sub ChannelSubscription) error {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, sub.CreatorId)
+	if err != nil {
+		return errors.WithMessage(err, "subscription creator is not connected to Jira")
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err
+	}
+
+	issues, err := client.SearchIssues(fmt.Sprintf("filter = %s ORDER BY created DESC", sub.FilterId),
+		&jira.SearchOptions{MaxResults: filterSubscriptionMaxResults})
+	if err != nil {
+		return err
+	}
+
+	stateKey := keyWithInstance(ji, prefixFilterSubscription+sub.Id)
+	data, appErr := p.API.KVGet(stateKey)
+	if appErr != nil {
+		return appErr
+	}
+
+	current := NewStringSet()
+	for _, issue := range issues {
+		current = current.Add(issue.Key)
+	}
+
+	// The first evaluation only records the baseline, so that subscribing
+	// to a large filter doesn't flood the channel with existing issues.
+	if data != nil {
+		var state filterSubscriptionState
+		err = json.Unmarshal(data, &state)
+		if err != nil {
+			return err
+		}
+		for i := range issues {
+			if state.IssueKeys.ContainsAny(issues[i].Key) {
+				continue
+			}
+			p.postFilterSubscriptionIssue(sub, &issues[i])
+		}
+	}
+
+	data, err = json.Marshal(filterSubscriptionState{IssueKeys: current})
+	if err != nil {
+		return err
+	}
+	appErr = p.API.KVSet(stateKey, data)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) postFilterSubscriptionIssue(sub ChannelSubscription, issue *jira.Issue) {
+	conf := p.getConfig()
+	locale := p.channelLocale(sub.ChannelId)
+	attachments := parseIssue(issue, conf.maxTextLength, conf.webhookParseOptions.issueEmoji, p.jiraAvatarURLFunc(), p.localizer(locale))
+	attachments[0].Pretext = p.localize(locale, msgFilterSubscriptionNew, sub.Name)
+	attachments[0].Fallback = attachments[0].Pretext
+	p.addIssueAppLink(attachments[0], issue.Key, sub.ChannelId)
+
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: sub.ChannelId,
+	}
+	post.AddProp(postPropIssueKey, issue.Key)
+	post.AddProp(postPropSubscriptionId, sub.Id)
+	model.ParseSlackAttachment(post, attachments)
+
+	created, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		p.errorf("postFilterSubscriptionIssue: failed to post %s to channel %s: %v", issue.Key, sub.ChannelId, appErr)
+		return
+	}
+	p.rememberIssuePosts(issue, []*model.Post{created})
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/expvar"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const (
+	forwardTargetWebhook = "webhook"
+
+	forwardTimeout = 10 * time.Second
+
+	// The forwards are sent by their own workers, so that slow targets don't
+	// hold up the webhook workers posting the events.
+	forwardMaxProcsPerServer = 4
+	forwardBufferSize        = 1000
+
+	headerForwardSignature = "X-Jira-Plugin-Signature"
+)
+
+// ForwardTarget is where a subscription forwards the events it matches,
+// besides posting them to its channel. An edit with an empty target removes
+// the subscription's target.
+type ForwardTarget struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// forwardedEvent is the payload forwarded for an event matched by a
+// subscription. Jira is the webhook request received from Jira.
+type forwardedEvent struct {
+	SubscriptionId   string          `json:"subscription_id"`
+	SubscriptionName string          `json:"subscription_name"`
+	ChannelId        string          `json:"channel_id"`
+	DeliveryId       string          `json:"delivery_id,omitempty"`
+	Timestamp        int64           `json:"timestamp"`
+	WebhookEvent     string          `json:"webhook_event"`
+	EventTypes       []string        `json:"event_types"`
+	IssueKey         string          `json:"issue_key,omitempty"`
+	Headline         string          `json:"headline"`
+	Text             string          `json:"text,omitempty"`
+	Jira             json.RawMessage `json:"jira,omitempty"`
+}
+
+// forwardRequest is a forward waiting in the forward queue.
+type forwardRequest struct {
+	forward eventForwarder
+	sub     ChannelSubscription
+	secret  string
+	payload []byte
+	stats   *expvar.Stats
+}
+
+// eventForwarder sends the payload of an event to a kind of forward target.
+type eventForwarder func(p *Plugin, sub *ChannelSubscription, secret string, payload []byte) error
+
+// eventForwarders are the kinds of forward targets, by ForwardTarget.Type. A
+// new kind of target only needs a forwarder here.
+var eventForwarders = map[string]eventForwarder{
+	forwardTargetWebhook: forwardToWebhook,
+}
+
+// forwardEvent queues the forwards of the webhook event to the targets of the
+// subscriptions matching it. Restricted comments are never forwarded. The
+// forwards are dropped and logged when the forward queue is full.
+func (p *Plugin) forwardEvent(wh *webhook, rawData []byte) error {
+	if wh.isRestrictedComment() {
+		return nil
+	}
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range p.matchingChannelSubscriptions(subs, wh) {
+		if sub.Forward == nil {
+			continue
+		}
+		forward, ok := eventForwarders[sub.Forward.Type]
+		if !ok {
+			p.errorf("forwardEvent: subscription %q has an unknown forward target type %q", sub.Name, sub.Forward.Type)
+			continue
+		}
+		if err = p.checkForwardURL(sub.Forward.URL); err != nil {
+			p.debugf("forwardEvent: not forwarding the events of subscription %q: %v", sub.Name, err)
+			continue
+		}
+		secret, err := p.forwardSecret(&sub)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(forwardedEvent{
+			SubscriptionId:   sub.Id,
+			SubscriptionName: sub.Name,
+			ChannelId:        sub.ChannelId,
+			DeliveryId:       wh.deliveryId,
+			Timestamp:        model.GetMillis(),
+			WebhookEvent:     wh.JiraWebhook.WebhookEvent,
+			EventTypes:       wh.Events().Elems(),
+			IssueKey:         wh.JiraWebhook.IssueKey(),
+			Headline:         wh.headline,
+			Text:             wh.text,
+			Jira:             json.RawMessage(rawData),
+		})
+		if err != nil {
+			return err
+		}
+
+		request := forwardRequest{
+			forward: forward,
+			sub:     sub,
+			secret:  secret,
+			payload: payload,
+			stats:   wh.getConfig(p).stats,
+		}
+		select {
+		case p.forwardQueue <- request:
+		default:
+			p.errorf("forwardEvent: dropped an event of subscription %q, the forward queue is full", sub.Name)
+		}
+	}
+	return nil
+}
+
+// forwardWorker sends the forwards of the forward queue.
+func (p *Plugin) forwardWorker(queue <-chan forwardRequest) {
+	for request := range queue {
+		p.sendForward(request)
+	}
+}
+
+// sendForward sends a queued forward. A failed forward is logged and not
+// retried.
+func (p *Plugin) sendForward(request forwardRequest) {
+	start := time.Now()
+	err := request.forward(p, &request.sub, request.secret, request.payload)
+	if request.stats != nil {
+		request.stats.EnsureEndpoint("jira/subscribe/forward").Record(utils.ByteSize(len(request.payload)), 0, time.Since(start), err != nil, false)
+	}
+	if err != nil {
+		p.errorf("sendForward: failed to forward an event of subscription %q: %v", request.sub.Name, err)
+	}
+}
+
+// forwardToWebhook posts the payload to the URL of the forward target, signed
+// with the secret of the subscription. Redirects are not followed.
+func forwardToWebhook(p *Plugin, sub *ChannelSubscription, secret string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, sub.Forward.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerForwardSignature, signForwardPayload(secret, payload))
+
+	client := &http.Client{
+		Timeout: forwardTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// signForwardPayload returns the signature of a forwarded payload, the hex
+// HMAC-SHA256 of the payload with the secret.
+func signForwardPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// forwardSecret returns the secret signing the events forwarded by the
+// subscription, derived from its ID and forward URL so that it is not stored,
+// and changes with the URL.
+func (p *Plugin) forwardSecret(sub *ChannelSubscription) (string, error) {
+	secret, err := p.secretsStore.EnsureAuthTokenEncryptSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte("forward:" + sub.Id + ":" + sub.Forward.URL))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// checkForwardURL returns an error unless the URL is an http or https URL of
+// one of the hosts of the EventForwardingHosts setting.
+func (p *Plugin) checkForwardURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("%q is not an http or https URL", rawURL)
+	}
+	hosts := p.getConfig().eventForwardingHosts
+	if hosts.Len() == 0 {
+		return errors.New("event forwarding is not enabled, see the Event Forwarding Hosts setting")
+	}
+	if !hosts.ContainsAny(strings.ToLower(u.Hostname())) {
+		return errors.Errorf("host %s is not one of the Event Forwarding Hosts", u.Hostname())
+	}
+	return nil
+}
+
+// checkSubscriptionForward returns an error if the subscription saved by the
+// subscription API forwards its events to a target that can't receive them.
+// An edit keeping the forward URL of the saved subscription is not checked, so
+// that a subscription forwarding to a host since removed from the Event
+// Forwarding Hosts can still be edited.
+func (p *Plugin) checkSubscriptionForward(sub *ChannelSubscription) error {
+	if sub.Forward == nil || sub.Forward.URL == "" {
+		return nil
+	}
+	if _, ok := eventForwarders[sub.Forward.Type]; !ok {
+		return errors.Errorf("unknown forward target type %q", sub.Forward.Type)
+	}
+	if sub.Id != "" {
+		saved, err := p.getChannelSubscription(sub.Id)
+		if err == nil && saved.Forward != nil && saved.Forward.URL == sub.Forward.URL {
+			return nil
+		}
+	}
+	return p.checkForwardURL(sub.Forward.URL)
+}
+
+func executeSubscribeForward(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 {
+		return p.responsef(header, "Please use `/jira subscribe forward <URL|clear> <subscription name>`.")
+	}
+	target := args[0]
+	name := strings.Join(args[1:], " ")
+
+	if target != "clear" {
+		if err := p.checkForwardURL(target); err != nil {
+			return p.responsef(header, "Can't forward the events to this URL: %v.", err)
+		}
+	}
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		if target == "clear" {
+			sub.Forward = &ForwardTarget{}
+			return "Subscription %q no longer forwards its events."
+		}
+		sub.Forward = &ForwardTarget{Type: forwardTargetWebhook, URL: target}
+		secret, err := p.forwardSecret(sub)
+		if err != nil {
+			p.errorf("executeSubscribeForward: failed to derive the forward secret: %v", err)
+			return "Subscription %q now forwards its events to `" + strings.ReplaceAll(target, "%", "%%") + "`."
+		}
+		return "Subscription %q now forwards its events to `" + strings.ReplaceAll(target, "%", "%%") + "`. " +
+			"The requests are signed in the `" + headerForwardSignature + "` header with the secret `" + secret + "`, " +
+			"as `sha256=` followed by the hex HMAC-SHA256 of the request body."
+	})
+}
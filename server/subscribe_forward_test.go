@@ -0,0 +1,149 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckForwardURL(t *testing.T) {
+	p := &Plugin{}
+	assert.Error(t, p.checkForwardURL("https://hooks.example.com/jira"), "forwarding is disabled")
+
+	p.updateConfig(func(conf *config) {
+		conf.eventForwardingHosts = NewStringSet("hooks.example.com")
+	})
+	assert.NoError(t, p.checkForwardURL("https://hooks.example.com/jira"))
+	assert.NoError(t, p.checkForwardURL("http://Hooks.Example.com:8080/jira"))
+	assert.Error(t, p.checkForwardURL("https://other.example.com/jira"))
+	assert.Error(t, p.checkForwardURL("ftp://hooks.example.com/jira"))
+	assert.Error(t, p.checkForwardURL("hooks.example.com/jira"))
+}
+
+func TestCheckSubscriptionForward(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.eventForwardingHosts = NewStringSet("hooks.example.com")
+	})
+
+	saved := ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "Forwarded",
+		Forward: &ForwardTarget{Type: forwardTargetWebhook, URL: "https://removed.example.com/jira"}}
+	subscriptionBytes, err := json.Marshal(withExistingChannelSubscriptions([]ChannelSubscription{saved}))
+	require.NoError(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	forward := func(id, forwardType, url string) *ChannelSubscription {
+		return &ChannelSubscription{Id: id, ChannelId: "channel1", Forward: &ForwardTarget{Type: forwardType, URL: url}}
+	}
+	assert.NoError(t, p.checkSubscriptionForward(&ChannelSubscription{ChannelId: "channel1"}))
+	assert.NoError(t, p.checkSubscriptionForward(forward("", forwardTargetWebhook, "https://hooks.example.com/jira")))
+	assert.NoError(t, p.checkSubscriptionForward(forward("sub1", "", "")), "an empty target removes the forward")
+	assert.Error(t, p.checkSubscriptionForward(forward("", forwardTargetWebhook, "https://other.example.com/jira")))
+	assert.Error(t, p.checkSubscriptionForward(forward("", "", "https://hooks.example.com/jira")))
+	assert.Error(t, p.checkSubscriptionForward(forward("", forwardTargetWebhook, "hooks.example.com/jira")))
+
+	assert.NoError(t, p.checkSubscriptionForward(forward("sub1", forwardTargetWebhook, "https://removed.example.com/jira")),
+		"the saved forward URL is not checked again")
+	assert.Error(t, p.checkSubscriptionForward(forward("sub1", forwardTargetWebhook, "https://other.example.com/jira")))
+}
+
+func TestForwardEvent(t *testing.T) {
+	type received struct {
+		signature string
+		body      []byte
+	}
+	requests := make(chan received, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- received{r.Header.Get(headerForwardSignature), body}
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.secretsStore = NewStore(p)
+	p.updateConfig(func(conf *config) {
+		conf.eventForwardingHosts = NewStringSet(serverURL.Hostname())
+	})
+
+	filters := SubscriptionFilters{
+		Events:     NewStringSet(eventCreated),
+		Projects:   NewStringSet("TES"),
+		IssueTypes: NewStringSet("10001"),
+	}
+	forwarded := ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "Forwarded", Filters: filters,
+		Forward: &ForwardTarget{Type: forwardTargetWebhook, URL: server.URL + "/jira"}}
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		forwarded,
+		{Id: "sub2", ChannelId: "channel2", Name: "Posted", Filters: filters},
+		{Id: "sub3", ChannelId: "channel3", Name: "Other host", Filters: filters,
+			Forward: &ForwardTarget{Type: forwardTargetWebhook, URL: "https://other.example.com/jira"}},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.NoError(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+	api.On("KVGet", keyTokenSecret).Return([]byte("0123456789abcdef0123456789abcdef"), nil)
+	api.On("LogDebug", mock.AnythingOfType("string")).Return(nil)
+
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.NoError(t, err)
+	wh, err := ParseWebhook(data)
+	require.NoError(t, err)
+	wh.(*webhook).deliveryId = "delivery1"
+
+	p.forwardQueue = make(chan forwardRequest, 2)
+	require.NoError(t, p.forwardEvent(wh.(*webhook), data))
+	require.Len(t, p.forwardQueue, 1)
+	require.Len(t, requests, 0, "the forwards are sent by the forward workers")
+	p.sendForward(<-p.forwardQueue)
+	require.Len(t, requests, 1)
+	request := <-requests
+
+	secret, err := p.forwardSecret(&forwarded)
+	require.NoError(t, err)
+	assert.Equal(t, signForwardPayload(secret, request.body), request.signature)
+
+	event := forwardedEvent{}
+	require.NoError(t, json.Unmarshal(request.body, &event))
+	assert.Equal(t, "sub1", event.SubscriptionId)
+	assert.Equal(t, "Forwarded", event.SubscriptionName)
+	assert.Equal(t, "channel1", event.ChannelId)
+	assert.Equal(t, "delivery1", event.DeliveryId)
+	assert.Equal(t, "jira:issue_created", event.WebhookEvent)
+	assert.Equal(t, []string{eventCreated}, event.EventTypes)
+	assert.Equal(t, "TES-41", event.IssueKey)
+	assert.Equal(t, wh.(*webhook).headline, event.Headline)
+	assert.JSONEq(t, string(data), string(event.Jira))
+
+	// The secret changes with the forward URL
+	other := forwarded
+	other.Forward = &ForwardTarget{Type: forwardTargetWebhook, URL: server.URL + "/other"}
+	otherSecret, err := p.forwardSecret(&other)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, otherSecret)
+
+	// The forwards are dropped when the forward queue is full
+	api.On("LogError", mock.AnythingOfType("string")).Return(nil)
+	p.forwardQueue = make(chan forwardRequest)
+	require.NoError(t, p.forwardEvent(wh.(*webhook), data))
+	api.AssertCalled(t, "LogError", mock.AnythingOfType("string"))
+	assert.Len(t, requests, 0)
+}
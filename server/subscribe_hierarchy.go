@@ -0,0 +1,173 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// matchesHierarchy returns true if the issue passes the ParentKeys and
+// ExcludeSubtasks filters.
+func (filters SubscriptionFilters) matchesHierarchy(issue *jira.Issue) bool {
+	if filters.ExcludeSubtasks && issue.Fields.Type.Subtask {
+		return false
+	}
+	if filters.ParentKeys.Len() == 0 {
+		return true
+	}
+	return filters.ParentKeys.ContainsAny(issue.Key) ||
+		filters.ParentKeys.ContainsAny(issueParentKeys(issue, filters.EpicLinkField).Elems()...)
+}
+
+// epicLinkFieldSchema is the schema of the Epic Link custom field of Jira
+// Software.
+const epicLinkFieldSchema = "com.pyxis.greenhopper.jira:gh-epic-link"
+
+// issueParentKeys returns the keys of the parent of a sub-task, or of the
+// parent epic of an issue. Jira Cloud sets the epic as the parent, Jira Server
+// in the Epic Link custom field epicLinkField, if not empty.
+func issueParentKeys(issue *jira.Issue, epicLinkField string) StringSet {
+	keys := NewStringSet()
+	if issue.Fields.Parent != nil && issue.Fields.Parent.Key != "" {
+		keys = keys.Add(issue.Fields.Parent.Key)
+	}
+	if epicLinkField != "" {
+		s, ok := issue.Fields.Unknowns[epicLinkField].(string)
+		if ok && reJiraIssueKeyLoose.MatchString(s) {
+			keys = keys.Add(s)
+		}
+	}
+	return keys
+}
+
+// getEpicLinkField returns the ID of the Epic Link custom field, whose ID
+// differs across instances, or "" if Jira Software is not installed.
+func getEpicLinkField(client Client) (string, error) {
+	fields := []struct {
+		ID     string `json:"id"`
+		Schema struct {
+			Custom string `json:"custom"`
+		} `json:"schema"`
+	}{}
+	err := client.RESTGet("2/field", nil, &fields)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to list the Jira fields")
+	}
+	for _, field := range fields {
+		if field.Schema.Custom == epicLinkFieldSchema {
+			return field.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func executeSubscribeParent(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 {
+		return p.responsef(header, "Please use `/jira subscribe parent <issue-key[,issue-key...]|clear> <subscription name>`.")
+	}
+	keys := NewStringSet()
+	if args[0] != "clear" {
+		for _, key := range strings.Split(args[0], ",") {
+			key = strings.ToUpper(strings.TrimSpace(key))
+			if !reJiraIssueKeyLoose.MatchString(key) {
+				return p.responsef(header, "%q is not a valid issue key.", key)
+			}
+			keys = keys.Add(key)
+		}
+	}
+	name := strings.Join(args[1:], " ")
+
+	return p.updateChannelSubscriptionByNameWithClient(header, name, func(sub *ChannelSubscription, client Client) (string, error) {
+		sub.Filters.ParentKeys = keys
+		sub.Filters.EpicLinkField = ""
+		if keys.Len() == 0 {
+			return "Subscription %q no longer filters on a parent issue.", nil
+		}
+		epicLinkField, err := getEpicLinkField(client)
+		if err != nil {
+			return "", err
+		}
+		sub.Filters.EpicLinkField = epicLinkField
+		elems := keys.Elems()
+		sort.Strings(elems)
+		return "Subscription %q now only posts the events of " + strings.Join(elems, ", ") + ", and of their sub-tasks or epic issues.", nil
+	})
+}
+
+func executeSubscribeSubtasks(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 || (args[0] != "include" && args[0] != "exclude") {
+		return p.responsef(header, "Please use `/jira subscribe subtasks <include|exclude> <subscription name>`.")
+	}
+	exclude := args[0] == "exclude"
+	name := strings.Join(args[1:], " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		sub.Filters.ExcludeSubtasks = exclude
+		if exclude {
+			return "Subscription %q no longer posts the events of sub-tasks."
+		}
+		return "Subscription %q posts the events of sub-tasks."
+	})
+}
+
+// updateChannelSubscriptionByName applies update to the subscription of the
+// channel named name, and saves it. update returns the format of the response,
+// given the subscription name.
+func (p *Plugin) updateChannelSubscriptionByName(header *model.CommandArgs, name string, update func(*ChannelSubscription) string) *model.CommandResponse {
+	return p.updateChannelSubscriptionByNameWithClient(header, name, func(sub *ChannelSubscription, _ Client) (string, error) {
+		return update(sub), nil
+	})
+}
+
+// updateChannelSubscriptionByNameWithClient is updateChannelSubscriptionByName
+// for the updates that need Jira, with the client of the user. The
+// subscription is not saved if update fails.
+func (p *Plugin) updateChannelSubscriptionByNameWithClient(header *model.CommandArgs, name string, update func(*ChannelSubscription, Client) (string, error)) *model.CommandResponse {
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to edit Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("updateChannelSubscriptionByName: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.Name != name {
+			continue
+		}
+		format, err := update(&sub, client)
+		if err != nil {
+			return p.responsef(header, "Failed to update subscription %q: %v", name, err)
+		}
+		err = p.editChannelSubscription(&sub, client)
+		if err != nil {
+			return p.responsef(header, "Failed to update subscription %q: %v", name, err)
+		}
+		return p.responsef(header, format, name)
+	}
+
+	return p.responsef(header, "There is no subscription named %q in this channel.", name)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const issueSubscriptionCleanupInterval = 24 * time.Hour
+
+func issueSubscriptionName(issueKey, rootId string) string {
+	if rootId == "" {
+		return issueKey
+	}
+	return fmt.Sprintf("%s (thread %s)", issueKey, rootId)
+}
+
+func executeSubscribeIssue(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira subscribe issue <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to create Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeIssue: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	err = p.subscribeToIssue(client, header.UserId, header.ChannelId, header.RootId, issueKey)
+	if err != nil {
+		return p.responsef(header, "Failed to subscribe to %s: %v", issueKey, err)
+	}
+
+	if header.RootId != "" {
+		return p.responsef(header, "Events of %s will be posted to this thread.", issueKey)
+	}
+	return p.responsef(header, "Events of %s will be posted to this channel.", issueKey)
+}
+
+// subscribeToIssue subscribes the channel, or its thread if rootId is set, to
+// the events of an issue on behalf of the user.
+func (p *Plugin) subscribeToIssue(client Client, userId, channelId, rootId, issueKey string) error {
+	subs, err := p.getSubscriptionsForChannel(channelId)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if sub.IssueKey == issueKey && sub.RootId == rootId {
+			if rootId != "" {
+				return errors.New("this thread is already subscribed to the issue")
+			}
+			return errors.New("this channel is already subscribed to the issue")
+		}
+	}
+
+	return p.addChannelSubscription(&ChannelSubscription{
+		ChannelId: channelId,
+		Name:      issueSubscriptionName(issueKey, rootId),
+		IssueKey:  issueKey,
+		RootId:    rootId,
+		CreatorId: userId,
+	}, client)
+}
+
+func executeUnsubscribeIssue(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira unsubscribe issue <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to remove Jira subscriptions: %v", err)
+	}
+
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.IssueKey != issueKey || sub.RootId != header.RootId {
+			continue
+		}
+		err = p.removeChannelSubscription(sub.Id)
+		if err != nil {
+			return p.responsef(header, "Failed to remove subscription %q: %v", sub.Name, err)
+		}
+		return p.responsef(header, "Unsubscribed from %s.", issueKey)
+	}
+
+	return p.responsef(header, "There is no subscription to %s here.", issueKey)
+}
+
+// getThreadsSubscribed returns the single-issue subscriptions that post the
+// webhook's issue to a thread.
+func (p *Plugin) getThreadsSubscribed(wh *webhook) ([]ChannelSubscription, error) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	threadSubs := []ChannelSubscription{}
+	for _, sub := range subs.Channel.ById {
+		if sub.RootId == "" || sub.IssueKey != wh.JiraWebhook.Issue.Key || sub.Paused || p.isIgnoredActor(wh, sub) {
+			continue
+		}
+		if p.restrictedCommentAction(wh, sub) == "" {
+			threadSubs = append(threadSubs, sub)
+		}
+	}
+	return threadSubs, nil
+}
+
+// cleanupIssueSubscriptions removes single-issue subscriptions whose issue has
+// been resolved for longer than the configured retention, or no longer exists.
+func (p *Plugin) cleanupIssueSubscriptions() {
+	retention := p.getConfig().issueSubscriptionRetention
+	if retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("cleanupIssueSubscriptions: failed to load subscriptions: %v", err)
+		return
+	}
+
+	expired := map[string]bool{}
+	for _, sub := range subs.Channel.ById {
+		if sub.IssueKey == "" {
+			continue
+		}
+		isExpired, checked := expired[sub.IssueKey]
+		if !checked {
+			isExpired, err = p.issueResolvedBefore(ji, sub, cutoff)
+			if err != nil {
+				p.debugf("cleanupIssueSubscriptions: failed to check %s: %v", sub.IssueKey, err)
+				continue
+			}
+			expired[sub.IssueKey] = isExpired
+		}
+		if !isExpired {
+			continue
+		}
+
+		err = p.removeChannelSubscription(sub.Id)
+		if err != nil {
+			p.errorf("cleanupIssueSubscriptions: failed to remove subscription %q: %v", sub.Name, err)
+		}
+	}
+}
+
+func (p *Plugin) issueResolvedBefore(ji Instance, sub ChannelSubscription, cutoff time.Time) (bool, error) {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, sub.CreatorId)
+	if err != nil {
+		return false, err
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return false, err
+	}
+
+	issue, err := client.GetIssue(sub.IssueKey, &jira.GetQueryOptions{Fields: "resolutiondate"})
+	if StatusCode(err) == http.StatusNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if issue.Fields == nil {
+		return false, nil
+	}
+
+	resolved := time.Time(issue.Fields.Resolutiondate)
+	return !resolved.IsZero() && resolved.Before(cutoff), nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+)
+
+// matchesIssueType returns true if the issue type passes the IssueTypes
+// filter, by ID or, for the IDs of another instance or project, by name.
+func (filters SubscriptionFilters) matchesIssueType(issueType jira.IssueType) bool {
+	if filters.IssueTypes.Len() == 0 || filters.IssueTypes.ContainsAny(issueType.ID) {
+		return true
+	}
+	if issueType.Name == "" {
+		return false
+	}
+	for _, name := range filters.IssueTypeNames {
+		if name == issueType.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeIssueTypes resolves the issue types of the filters, given by ID or
+// by name, to the issue types of the projects, storing both their IDs and
+// their names. An ID unknown to the projects is resolved by the name stored
+// with it, e.g. for a subscription copied from another instance.
+func (filters *SubscriptionFilters) normalizeIssueTypes(projects []*jira.Project) error {
+	issueTypes := []jira.IssueType{}
+	for _, project := range projects {
+		if project != nil {
+			issueTypes = append(issueTypes, project.IssueTypes...)
+		}
+	}
+	if len(issueTypes) == 0 {
+		// No metadata to resolve the issue types with
+		return nil
+	}
+	find := func(value string) (jira.IssueType, bool) {
+		for _, t := range issueTypes {
+			if t.ID == value {
+				return t, true
+			}
+		}
+		for _, t := range issueTypes {
+			if strings.EqualFold(t.Name, value) {
+				return t, true
+			}
+		}
+		return jira.IssueType{}, false
+	}
+
+	ids := NewStringSet()
+	names := map[string]string{}
+	for _, value := range filters.IssueTypes.Elems() {
+		t, ok := find(value)
+		if !ok && filters.IssueTypeNames[value] != "" {
+			t, ok = find(filters.IssueTypeNames[value])
+		}
+		if !ok {
+			return errors.Errorf("Issue type %q doesn't exist in the project.", value)
+		}
+		ids = ids.Add(t.ID)
+		names[t.ID] = t.Name
+	}
+	filters.IssueTypes = ids
+	filters.IssueTypeNames = names
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeIssueTypes(t *testing.T) {
+	projects := []*jira.Project{{
+		Key:        "TES",
+		IssueTypes: []jira.IssueType{{ID: "10001", Name: "Bug"}, {ID: "10002", Name: "Story"}},
+	}}
+
+	// IDs and names
+	filters := SubscriptionFilters{IssueTypes: NewStringSet("10001", "story")}
+	require.NoError(t, filters.normalizeIssueTypes(projects))
+	assert.Equal(t, NewStringSet("10001", "10002"), filters.IssueTypes)
+	assert.Equal(t, map[string]string{"10001": "Bug", "10002": "Story"}, filters.IssueTypeNames)
+
+	// The ID of another instance is resolved by its name, and the names of
+	// the removed issue types are dropped
+	filters = SubscriptionFilters{
+		IssueTypes:     NewStringSet("20002"),
+		IssueTypeNames: map[string]string{"20001": "Bug", "20002": "Story"},
+	}
+	require.NoError(t, filters.normalizeIssueTypes(projects))
+	assert.Equal(t, NewStringSet("10002"), filters.IssueTypes)
+	assert.Equal(t, map[string]string{"10002": "Story"}, filters.IssueTypeNames)
+
+	filters = SubscriptionFilters{IssueTypes: NewStringSet("Epic")}
+	assert.EqualError(t, filters.normalizeIssueTypes(projects), `Issue type "Epic" doesn't exist in the project.`)
+
+	// Without metadata, the issue types are kept
+	filters = SubscriptionFilters{IssueTypes: NewStringSet("Epic")}
+	require.NoError(t, filters.normalizeIssueTypes([]*jira.Project{nil}))
+	assert.Equal(t, NewStringSet("Epic"), filters.IssueTypes)
+}
+
+func TestMatchesIssueType(t *testing.T) {
+	filters := SubscriptionFilters{
+		IssueTypes:     NewStringSet("10001"),
+		IssueTypeNames: map[string]string{"10001": "Bug"},
+	}
+	assert.True(t, filters.matchesIssueType(jira.IssueType{ID: "10001", Name: "Bug"}))
+	assert.True(t, filters.matchesIssueType(jira.IssueType{ID: "20001", Name: "Bug"}))
+	assert.False(t, filters.matchesIssueType(jira.IssueType{ID: "10002", Name: "Story"}))
+	assert.True(t, SubscriptionFilters{}.matchesIssueType(jira.IssueType{ID: "10002"}))
+}
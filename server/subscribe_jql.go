@@ -0,0 +1,190 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// How long the result of a JQL filter for an issue is reused, for the
+// subscriptions of the same query and Jira account, and for the other
+// webhooks Jira sends for the same change. The issue must also not have been
+// updated since.
+const jqlMatchCacheTTL = time.Minute
+
+// jqlMatchCache keeps the recent results of the JQL filters by issue.
+type jqlMatchCache struct {
+	lock    sync.Mutex
+	entries map[string]jqlMatchCacheEntry
+}
+
+type jqlMatchCacheEntry struct {
+	matched bool
+	expires time.Time
+}
+
+func (c *jqlMatchCache) get(key string, now time.Time) (bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		delete(c.entries, key)
+		return false, false
+	}
+	return entry.matched, true
+}
+
+func (c *jqlMatchCache) set(key string, matched bool, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]jqlMatchCacheEntry{}
+	}
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = jqlMatchCacheEntry{
+		matched: matched,
+		expires: now.Add(jqlMatchCacheTTL),
+	}
+}
+
+// jqlMatchKey identifies the result of the JQL query for the issue as it was
+// when last updated, searched as the Jira user, or returns "" if the webhook
+// doesn't tell when the issue was updated.
+func jqlMatchKey(ji Instance, jiraUser JIRAUser, wh *webhook, jql string) string {
+	if wh.Issue.Fields == nil || time.Time(wh.Issue.Fields.Updated).IsZero() {
+		return ""
+	}
+	return strings.Join([]string{
+		ji.GetURL(),
+		jiraUser.AccountID + jiraUser.Name,
+		wh.JiraWebhook.IssueKey(),
+		time.Time(wh.Issue.Fields.Updated).UTC().Format(time.RFC3339Nano),
+		jql,
+	}, "\n")
+}
+
+// matchesSubscriptionJQL returns true if the issue matches the JQL filter of
+// the subscription, searched with the Jira account of its creator. The issues
+// Jira can't find, like deleted ones, don't match. The subscriptions sharing
+// the query and the account are searched for once per event.
+func (p *Plugin) matchesSubscriptionJQL(wh *webhook, sub *ChannelSubscription) bool {
+	if sub.Filters.JQL == "" {
+		return true
+	}
+	if sub.CreatorId == "" || wh.JiraWebhook.IssueKey() == "" {
+		return false
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return false
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, sub.CreatorId)
+	if err != nil {
+		p.debugf("matchesSubscriptionJQL: the creator of subscription %q is not connected to Jira", sub.Name)
+		return false
+	}
+	now := time.Now()
+	key := jqlMatchKey(ji, jiraUser, wh, sub.Filters.JQL)
+	if key != "" {
+		if matched, ok := p.jqlMatches.get(key, now); ok {
+			return matched
+		}
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return false
+	}
+	matched, err := issueMatchesJQL(client, wh.JiraWebhook.IssueKey(), sub.Filters.JQL)
+	if err != nil {
+		p.errorf("matchesSubscriptionJQL: subscription %q: %v", sub.Name, err)
+		return false
+	}
+	if key != "" {
+		p.jqlMatches.set(key, matched, now)
+	}
+	return matched
+}
+
+// issuesMatchingJQL returns the keys of the issues that are results of the
+// JQL query, searched at once. Without a query, it returns the issues the
+// client can see.
+func issuesMatchingJQL(client Client, issueKeys []string, jql string) (StringSet, error) {
+	matched := NewStringSet()
+	if len(issueKeys) == 0 {
+		return matched, nil
+	}
+	query := fmt.Sprintf("issuekey in (%s)", strings.Join(issueKeys, ", "))
+	if jql != "" {
+		query += fmt.Sprintf(" AND (%s)", jql)
+	}
+	issues, err := client.SearchIssues(query, &jira.SearchOptions{MaxResults: len(issueKeys), Fields: []string{"summary"}})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to run the JQL filter")
+	}
+	for _, issue := range issues {
+		matched = matched.Add(issue.Key)
+	}
+	return matched, nil
+}
+
+// jqlCreatorDisconnected returns true if the subscription filters the issues
+// with a JQL query, and its creator is not connected to Jira anymore, so that
+// it posts nothing.
+func (p *Plugin) jqlCreatorDisconnected(ji Instance, sub *ChannelSubscription) bool {
+	if sub.Filters.JQL == "" {
+		return false
+	}
+	if sub.CreatorId == "" {
+		return true
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, sub.CreatorId)
+	return err != nil || len(jiraUser.Key()) == 0
+}
+
+// issueMatchesJQL returns true if the issue is one of the results of the JQL
+// query.
+func issueMatchesJQL(client Client, issueKey, jql string) (bool, error) {
+	issues, err := client.SearchIssues(fmt.Sprintf("issuekey = %s AND (%s)", issueKey, jql),
+		&jira.SearchOptions{MaxResults: 1, Fields: []string{"summary"}})
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to run the JQL filter")
+	}
+	return len(issues) > 0, nil
+}
+
+func executeSubscribeJQL(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira subscribe jql <\"JQL\"|clear> <subscription name>`, e.g. `/jira subscribe jql \"labels = release\" Releases`."
+
+	jql, args := splitQuotedArg(args)
+	if jql == "" || len(args) == 0 {
+		return p.responsef(header, usage)
+	}
+	name := strings.Join(args, " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		if jql == "clear" {
+			sub.Filters.JQL = ""
+			return "Subscription %q no longer filters the issues with a JQL query."
+		}
+		sub.Filters.JQL = jql
+		if sub.CreatorId == "" {
+			sub.CreatorId = header.UserId
+		}
+		return "Subscription %q now only posts the events of the issues matching `" + strings.ReplaceAll(jql, "%", "%%") + "`, searched in Jira for every event."
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+)
+
+// subscriptionMatcher matches the webhooks against one kind of the filters of
+// a subscription. A matcher returns true if its filters are not set.
+type subscriptionMatcher struct {
+	name    string
+	matches func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool
+}
+
+// subscriptionMatchers are run in order until one of them fails, so the
+// cheap ones come first, and those calling Jira last. A new kind of filter
+// only needs a matcher here.
+var subscriptionMatchers = []subscriptionMatcher{
+	{"events", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesEvents(wh.Events())
+	}},
+	{"issue_type", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesIssueType(wh.JiraWebhook.IssueType())
+	}},
+	{"project", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesProject(wh.JiraWebhook)
+	}},
+	{"hierarchy", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesHierarchy(&wh.JiraWebhook.Issue)
+	}},
+	{"property", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesProperty(wh)
+	}},
+	{"changes", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesChanges(wh)
+	}},
+	{"fields", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return sub.Filters.matchesFields(wh.JiraWebhook)
+	}},
+	{"jql", func(p *Plugin, wh *webhook, sub *ChannelSubscription) bool {
+		return p.matchesSubscriptionJQL(wh, sub)
+	}},
+}
+
+// matchesSubscription returns true if the webhook passes all the filters of
+// the subscription.
+func (p *Plugin) matchesSubscription(wh *webhook, sub *ChannelSubscription) bool {
+	return p.rejectingSubscriptionMatcher(wh, sub) == ""
+}
+
+// rejectingSubscriptionMatcher returns the name of the first matcher the
+// webhook fails, or "" if it passes all the filters of the subscription.
+func (p *Plugin) rejectingSubscriptionMatcher(wh *webhook, sub *ChannelSubscription) string {
+	for _, m := range subscriptionMatchers {
+		if !m.matches(p, wh, sub) {
+			return m.name
+		}
+	}
+	return ""
+}
+
+// matchesSubsciptionFilters returns true if the webhook passes the filters.
+// Without a subscription creator, the JQL filter never matches.
+func (p *Plugin) matchesSubsciptionFilters(wh *webhook, filters SubscriptionFilters) bool {
+	return p.matchesSubscription(wh, &ChannelSubscription{Filters: filters})
+}
+
+// matchesEvents returns true if one of the webhook events is one of the
+// Events filters, any update matching eventUpdatedAny.
+func (filters SubscriptionFilters) matchesEvents(webhookEvents StringSet) bool {
+	if filters.Events.Intersection(webhookEvents).Len() > 0 {
+		return true
+	}
+	if !filters.Events.ContainsAny(eventUpdatedAny) {
+		return false
+	}
+	for _, eventType := range webhookEvents.Elems() {
+		if strings.HasPrefix(eventType, "event_updated") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesProject returns true if the issue is in one of the Projects filters.
+// The moves of issues are also posted to the subscriptions of the project
+// they left.
+func (filters SubscriptionFilters) matchesProject(jwh *JiraWebhook) bool {
+	return filters.Projects.Len() == 0 ||
+		filters.Projects.ContainsAny(jwh.ProjectKey()) ||
+		filters.Projects.ContainsAny(issueProjectKey(jwh.MovedFromKey()))
+}
+
+// matchesFields returns true if the issue passes all the Fields filters. A
+// broken filter, without an inclusion or values, never matches.
+func (filters SubscriptionFilters) matchesFields(jwh *JiraWebhook) bool {
+	for _, field := range filters.Fields {
+		if field.Inclusion == "" || (field.Values.Len() == 0 && field.Inclusion != FILTER_EMPTY) {
+			return false
+		}
+
+		var value StringSet
+		if isRawFieldSelector(field.Key) {
+			value = jwh.rawFieldValues(field.Key)
+		} else {
+			value = getIssueFieldValue(&jwh.Issue, field.Key)
+		}
+		containsAny := value.ContainsAny(field.Values.Elems()...)
+		containsAll := value.ContainsAll(field.Values.Elems()...)
+
+		if (field.Inclusion == FILTER_INCLUDE_ANY && !containsAny) ||
+			(field.Inclusion == FILTER_INCLUDE_ALL && !containsAll) ||
+			(field.Inclusion == FILTER_EXCLUDE_ANY && containsAny) ||
+			(field.Inclusion == FILTER_EMPTY && value.Len() > 0) {
+			return false
+		}
+	}
+	return true
+}
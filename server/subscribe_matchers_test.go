@@ -0,0 +1,227 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestRejectingSubscriptionMatcher(t *testing.T) {
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.Nil(t, err)
+	w, err := ParseWebhook(data)
+	require.Nil(t, err)
+	wh := w.(*webhook)
+
+	base := func() SubscriptionFilters {
+		return SubscriptionFilters{
+			Events:     NewStringSet(eventCreated),
+			Projects:   NewStringSet("TES"),
+			IssueTypes: NewStringSet("10001"),
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		modify   func(filters *SubscriptionFilters)
+		rejected string
+	}{
+		"all match": {
+			modify: func(filters *SubscriptionFilters) {},
+		},
+		"other event": {
+			modify:   func(filters *SubscriptionFilters) { filters.Events = NewStringSet(eventDeleted) },
+			rejected: "events",
+		},
+		"other issue type": {
+			modify:   func(filters *SubscriptionFilters) { filters.IssueTypes = NewStringSet("10002") },
+			rejected: "issue_type",
+		},
+		"other issue type with the same name": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.IssueTypes = NewStringSet("20001")
+				filters.IssueTypeNames = map[string]string{"20001": "Story"}
+			},
+		},
+		"other project": {
+			modify:   func(filters *SubscriptionFilters) { filters.Projects = NewStringSet("OTHER") },
+			rejected: "project",
+		},
+		"sub-tasks only": {
+			modify:   func(filters *SubscriptionFilters) { filters.ParentKeys = NewStringSet("TES-1") },
+			rejected: "hierarchy",
+		},
+		"label and status": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{
+					{Key: "labels", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("test-label")},
+					{Key: "status", Inclusion: FILTER_INCLUDE_ALL, Values: NewStringSet("10001")},
+				}
+			},
+		},
+		"label but not status": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{
+					{Key: "labels", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("test-label")},
+					{Key: "status", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("10002")},
+				}
+			},
+			rejected: "fields",
+		},
+		"excluded priority": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{{Key: "priority", Inclusion: FILTER_EXCLUDE_ANY, Values: NewStringSet("2")}}
+			},
+			rejected: "fields",
+		},
+		"empty custom field": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{{Key: "customfield_10010", Inclusion: FILTER_EMPTY}}
+			},
+		},
+		"custom field value": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{{Key: "customfield_10022", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("0|i00067:")}}
+			},
+		},
+		"broken field filter": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{{Key: "labels", Inclusion: FILTER_INCLUDE_ANY}}
+			},
+			rejected: "fields",
+		},
+		"raw selector": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Fields = []FieldFilter{{Key: "$.issue.fields.issuetype.name", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("Story")}}
+			},
+		},
+		"other project and label": {
+			modify: func(filters *SubscriptionFilters) {
+				filters.Projects = NewStringSet("OTHER")
+				filters.Fields = []FieldFilter{{Key: "labels", Inclusion: FILTER_INCLUDE_ANY, Values: NewStringSet("other-label")}}
+			},
+			rejected: "project",
+		},
+		"JQL without a creator": {
+			modify:   func(filters *SubscriptionFilters) { filters.JQL = "labels = test-label" },
+			rejected: "jql",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := &Plugin{}
+			filters := base()
+			tc.modify(&filters)
+			assert.Equal(t, tc.rejected, p.rejectingSubscriptionMatcher(wh, &ChannelSubscription{Filters: filters}))
+			assert.Equal(t, tc.rejected == "", p.matchesSubsciptionFilters(wh, filters))
+		})
+	}
+}
+
+type jqlTestClient struct {
+	testClient
+	jql      string
+	results  []jira.Issue
+	searches int
+}
+
+func (client *jqlTestClient) SearchIssues(jql string, options *jira.SearchOptions) ([]jira.Issue, error) {
+	client.jql = jql
+	client.searches++
+	return client.results, nil
+}
+
+func TestIssueMatchesJQL(t *testing.T) {
+	client := &jqlTestClient{}
+	matched, err := issueMatchesJQL(client, "TES-41", "labels = release OR priority = High")
+	require.NoError(t, err)
+	assert.False(t, matched)
+	assert.Equal(t, "issuekey = TES-41 AND (labels = release OR priority = High)", client.jql)
+
+	client.results = []jira.Issue{{Key: "TES-41"}}
+	matched, err = issueMatchesJQL(client, "TES-41", "labels = release")
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
+
+func TestIssuesMatchingJQL(t *testing.T) {
+	client := &jqlTestClient{results: []jira.Issue{{Key: "TES-2"}}}
+	matched, err := issuesMatchingJQL(client, []string{"TES-1", "TES-2"}, "labels = release")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TES-2"}, matched.Elems())
+	assert.Equal(t, "issuekey in (TES-1, TES-2) AND (labels = release)", client.jql)
+
+	matched, err = issuesMatchingJQL(client, nil, "labels = release")
+	require.NoError(t, err)
+	assert.Equal(t, 0, matched.Len())
+	assert.Equal(t, 1, client.searches, "no search without issues")
+
+	_, err = issuesMatchingJQL(client, []string{"TES-1"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "issuekey in (TES-1)", client.jql)
+}
+
+type jqlTestInstanceStore struct {
+	ji Instance
+}
+
+func (store jqlTestInstanceStore) StoreCurrentJIRAInstance(ji Instance) error {
+	return nil
+}
+
+func (store jqlTestInstanceStore) LoadCurrentJIRAInstance() (Instance, error) {
+	return store.ji, nil
+}
+
+func TestMatchesSubscriptionJQL(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mock.AnythingOfType("string")).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	client := &jqlTestClient{results: []jira.Issue{{Key: "TES-41"}}}
+	ji := &countsTestInstance{
+		jiraTestInstance: jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")},
+		client:           client,
+	}
+	p.currentInstanceStore = jqlTestInstanceStore{ji}
+	p.userStore = mockUserStoreKV{kv: map[string]JIRAUser{
+		"creator1": {User: jira.User{AccountID: "account1"}},
+		"creator2": {User: jira.User{AccountID: "account2"}},
+	}}
+
+	updated := jira.Time(time.Now().Truncate(time.Second))
+	newWebhook := func() *webhook {
+		return &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Issue: jira.Issue{Key: "TES-41", Fields: &jira.IssueFields{Updated: updated}}}}}
+	}
+	sub := func(creatorId, jql string) *ChannelSubscription {
+		return &ChannelSubscription{CreatorId: creatorId, Filters: SubscriptionFilters{JQL: jql}}
+	}
+
+	wh := newWebhook()
+	assert.True(t, p.matchesSubscriptionJQL(wh, sub("creator1", "labels = release")))
+	assert.True(t, p.matchesSubscriptionJQL(wh, sub("creator1", "labels = release")))
+	assert.True(t, p.matchesSubscriptionJQL(newWebhook(), sub("creator1", "labels = release")))
+	assert.Equal(t, 1, client.searches, "the same query, account and issue update are searched once")
+
+	assert.True(t, p.matchesSubscriptionJQL(wh, sub("creator2", "labels = release")))
+	assert.True(t, p.matchesSubscriptionJQL(wh, sub("creator1", "priority = High")))
+	assert.Equal(t, 3, client.searches, "the results depend on the account and the query")
+
+	updated = jira.Time(time.Time(updated).Add(time.Second))
+	assert.True(t, p.matchesSubscriptionJQL(newWebhook(), sub("creator1", "labels = release")))
+	assert.Equal(t, 4, client.searches, "the issue was updated")
+
+	assert.False(t, p.matchesSubscriptionJQL(wh, sub("disconnected", "labels = release")))
+	assert.True(t, p.jqlCreatorDisconnected(ji, sub("disconnected", "labels = release")))
+	assert.True(t, p.jqlCreatorDisconnected(ji, sub("", "labels = release")))
+	assert.False(t, p.jqlCreatorDisconnected(ji, sub("creator1", "labels = release")))
+	assert.False(t, p.jqlCreatorDisconnected(ji, sub("disconnected", "")))
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	mentionMatchPriority = "priority:"
+	mentionMatchLabel    = "label:"
+)
+
+// MentionRule adds Mention, like "@here" or "@secteam", to the posts of a
+// subscription for the issues that match Match, either "priority:<name>" or
+// "label:<label>".
+type MentionRule struct {
+	Match   string `json:"match"`
+	Mention string `json:"mention"`
+}
+
+func (rule MentionRule) validate() error {
+	if !strings.HasPrefix(rule.Mention, "@") || len(rule.Mention) < 2 || strings.ContainsAny(rule.Mention, " \t\n") {
+		return errors.Errorf("Invalid mention %q, it must be like `@here` or `@username`.", rule.Mention)
+	}
+	lower := strings.ToLower(rule.Match)
+	switch {
+	case strings.HasPrefix(lower, mentionMatchPriority) && len(lower) > len(mentionMatchPriority),
+		strings.HasPrefix(lower, mentionMatchLabel) && len(lower) > len(mentionMatchLabel):
+		return nil
+	}
+	return errors.Errorf("Invalid mention rule %q, it must be like `priority:<name>` or `label:<label>`.", rule.Match)
+}
+
+// matches returns true if the issue of the webhook has the priority, by name
+// or ID, or the label of the rule.
+func (rule MentionRule) matches(jwh *JiraWebhook) bool {
+	if jwh == nil || jwh.Issue.Fields == nil {
+		return false
+	}
+	fields := jwh.Issue.Fields
+	lower := strings.ToLower(rule.Match)
+	switch {
+	case strings.HasPrefix(lower, mentionMatchPriority):
+		value := rule.Match[len(mentionMatchPriority):]
+		return fields.Priority != nil &&
+			(strings.EqualFold(fields.Priority.Name, value) || fields.Priority.ID == value)
+	case strings.HasPrefix(lower, mentionMatchLabel):
+		value := rule.Match[len(mentionMatchLabel):]
+		for _, label := range fields.Labels {
+			if label == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getChannelMentions returns the mentions to add to the posts of the webhook,
+// by channel, from the mention rules of the matching subscriptions.
+func (p *Plugin) getChannelMentions(wh *webhook) (map[string][]string, error) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := map[string]StringSet{}
+	for _, sub := range p.matchingChannelSubscriptions(subs, wh) {
+		for _, rule := range sub.MentionRules {
+			if rule.matches(wh.JiraWebhook) {
+				mentions[sub.ChannelId] = mentions[sub.ChannelId].Add(rule.Mention)
+			}
+		}
+	}
+
+	result := map[string][]string{}
+	for channelId, set := range mentions {
+		elems := set.Elems()
+		sort.Strings(elems)
+		result[channelId] = elems
+	}
+	return result, nil
+}
+
+func executeSubscribeMention(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	const usage = "Please use `/jira subscribe mention <priority:name|label:label> <@mention> <subscription name>`, " +
+		"or `/jira subscribe mention remove <priority:name|label:label> <subscription name>`."
+
+	remove := len(args) > 0 && args[0] == "remove"
+	if remove {
+		args = args[1:]
+	}
+	nargs := 3
+	if remove {
+		nargs = 2
+	}
+	if len(args) < nargs {
+		return p.responsef(header, usage)
+	}
+	rule := MentionRule{Match: args[0]}
+	if !remove {
+		rule.Mention = args[1]
+		err := rule.validate()
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+	}
+	name := strings.Join(args[nargs-1:], " ")
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to edit Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeMention: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.Name != name {
+			continue
+		}
+
+		rules := []MentionRule{}
+		for _, existing := range sub.MentionRules {
+			if !strings.EqualFold(existing.Match, rule.Match) {
+				rules = append(rules, existing)
+			}
+		}
+		if remove && len(rules) == len(sub.MentionRules) {
+			return p.responsef(header, "Subscription %q has no mention rule for `%s`.", name, rule.Match)
+		}
+		if !remove {
+			rules = append(rules, rule)
+		}
+		sub.MentionRules = rules
+
+		err = p.editChannelSubscription(&sub, client)
+		if err != nil {
+			return p.responsef(header, "Failed to update subscription %q: %v", name, err)
+		}
+		if remove {
+			return p.responsef(header, "Removed the mention rule for `%s` from subscription %q.", rule.Match, name)
+		}
+		return p.responsef(header, "Events of issues matching `%s` in subscription %q will mention %s.", rule.Match, name, rule.Mention)
+	}
+
+	return p.responsef(header, "There is no subscription named %q in this channel.", name)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+const subscriptionOptionsCacheTTL = 5 * time.Minute
+
+// SubscriptionOptions are the values a subscription filter can be set to, as
+// options for the subscription modal's dropdowns. The option values are the
+// Jira IDs (keys for projects) stored in the subscription filters. Only
+// Projects and Priorities are set if no project is requested.
+type SubscriptionOptions struct {
+	Projects   []utils.ReactSelectOption `json:"projects"`
+	IssueTypes []utils.ReactSelectOption `json:"issue_types"`
+	Statuses   []utils.ReactSelectOption `json:"statuses"`
+	Priorities []utils.ReactSelectOption `json:"priorities"`
+	Components []utils.ReactSelectOption `json:"components"`
+}
+
+// subscriptionOptionsCache keeps the options fetched from Jira for a while,
+// by Jira user and project, since the modal requests them each time it opens.
+type subscriptionOptionsCache struct {
+	lock    sync.Mutex
+	entries map[string]subscriptionOptionsCacheEntry
+}
+
+type subscriptionOptionsCacheEntry struct {
+	options *SubscriptionOptions
+	expires time.Time
+}
+
+func (c *subscriptionOptionsCache) get(key string, now time.Time) *SubscriptionOptions {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.options
+}
+
+func (c *subscriptionOptionsCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+}
+
+func (c *subscriptionOptionsCache) set(key string, options *SubscriptionOptions, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]subscriptionOptionsCacheEntry{}
+	}
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = subscriptionOptionsCacheEntry{
+		options: options,
+		expires: now.Add(subscriptionOptionsCacheTTL),
+	}
+}
+
+func httpAPIGetSubscriptionOptions(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed,
+			errors.New("Request: " + r.Method + " is not allowed, must be GET")
+	}
+
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	p := ji.GetPlugin()
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	projectKey := strings.ToUpper(r.URL.Query().Get("project_key"))
+	cacheKey := ji.GetURL() + "/" + jiraUser.AccountID + jiraUser.Name + "/" + projectKey
+	now := time.Now()
+	options := p.subscriptionOptions.get(cacheKey, now)
+	if options == nil {
+		client, err := ji.GetClient(jiraUser)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		options, err = fetchSubscriptionOptions(client, projectKey)
+		if err != nil {
+			return http.StatusInternalServerError,
+				errors.WithMessage(err, "failed to get subscription options")
+		}
+		p.subscriptionOptions.set(cacheKey, options, now)
+	}
+
+	bb, err := json.Marshal(options)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to marshal response")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(bb)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func fetchSubscriptionOptions(client Client, projectKey string) (*SubscriptionOptions, error) {
+	options := &SubscriptionOptions{
+		Projects:   []utils.ReactSelectOption{},
+		IssueTypes: []utils.ReactSelectOption{},
+		Statuses:   []utils.ReactSelectOption{},
+		Priorities: []utils.ReactSelectOption{},
+		Components: []utils.ReactSelectOption{},
+	}
+
+	projects := jira.ProjectList{}
+	err := client.RESTGet("2/project", nil, &projects)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get projects")
+	}
+	for _, prj := range projects {
+		options.Projects = append(options.Projects, utils.ReactSelectOption{Value: prj.Key, Label: prj.Name})
+	}
+
+	priorities := []jira.Priority{}
+	err = client.RESTGet("2/priority", nil, &priorities)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get priorities")
+	}
+	for _, priority := range priorities {
+		options.Priorities = append(options.Priorities, utils.ReactSelectOption{Value: priority.ID, Label: priority.Name})
+	}
+
+	if projectKey == "" {
+		return options, nil
+	}
+
+	project, err := client.GetProject(projectKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project "+projectKey)
+	}
+	for _, issueType := range project.IssueTypes {
+		options.IssueTypes = append(options.IssueTypes, utils.ReactSelectOption{Value: issueType.ID, Label: issueType.Name})
+	}
+	for _, component := range project.Components {
+		options.Components = append(options.Components, utils.ReactSelectOption{Value: component.ID, Label: component.Name})
+	}
+
+	// Statuses are listed by issue type, with the statuses shared by several
+	// issue types repeated.
+	statusesByIssueType := []issueTypeStatuses{}
+	err = client.RESTGet("2/project/"+project.Key+"/statuses", nil, &statusesByIssueType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get statuses for project "+projectKey)
+	}
+	options.Statuses = uniqueStatusOptions(statusesByIssueType)
+
+	return options, nil
+}
+
+// issueTypeStatuses is an element of the project statuses response.
+type issueTypeStatuses struct {
+	Statuses []jira.Status `json:"statuses"`
+}
+
+func uniqueStatusOptions(statusesByIssueType []issueTypeStatuses) []utils.ReactSelectOption {
+	seen := NewStringSet()
+	statuses := []utils.ReactSelectOption{}
+	for _, it := range statusesByIssueType {
+		for _, status := range it.Statuses {
+			if seen.ContainsAny(status.ID) {
+				continue
+			}
+			seen = seen.Add(status.ID)
+			statuses = append(statuses, utils.ReactSelectOption{Value: status.ID, Label: status.Name})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Label < statuses[j].Label
+	})
+	return statuses
+}
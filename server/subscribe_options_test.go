@@ -0,0 +1,38 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+func TestSubscriptionOptionsCache(t *testing.T) {
+	c := subscriptionOptionsCache{}
+	now := time.Now()
+	assert.Nil(t, c.get("key", now))
+
+	options := &SubscriptionOptions{Projects: []utils.ReactSelectOption{{Value: "TES", Label: "Test"}}}
+	c.set("key", options, now)
+	assert.Equal(t, options, c.get("key", now.Add(subscriptionOptionsCacheTTL-time.Second)))
+	assert.Nil(t, c.get("other", now))
+	assert.Nil(t, c.get("key", now.Add(subscriptionOptionsCacheTTL+time.Second)))
+}
+
+func TestUniqueStatusOptions(t *testing.T) {
+	statuses := uniqueStatusOptions([]issueTypeStatuses{
+		{Statuses: []jira.Status{{ID: "1", Name: "To Do"}, {ID: "3", Name: "Done"}}},
+		{Statuses: []jira.Status{{ID: "1", Name: "To Do"}, {ID: "2", Name: "In Progress"}}},
+	})
+	assert.Equal(t, []utils.ReactSelectOption{
+		{Value: "3", Label: "Done"},
+		{Value: "2", Label: "In Progress"},
+		{Value: "1", Label: "To Do"},
+	}, statuses)
+}
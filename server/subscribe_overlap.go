@@ -0,0 +1,97 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	subscriptionOverlapAll   = "all"
+	subscriptionOverlapFirst = "first"
+)
+
+// matchingChannelSubscriptions returns the channel subscriptions matching the
+// webhook, ordered by channel and by subscription name. In the channels set
+// to subscriptionOverlapFirst, only the first matching subscription is
+// returned, so that its mention rules and restricted comments policy apply
+// alone.
+func (p *Plugin) matchingChannelSubscriptions(subs *Subscriptions, wh *webhook) []ChannelSubscription {
+	isProjectEvent := wh.Events().Intersection(projectEvents).Len() > 0
+	matching := []ChannelSubscription{}
+	for _, sub := range subs.Channel.ById {
+		if p.matchesChannelSubscription(wh, sub, isProjectEvent) {
+			matching = append(matching, sub)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		a, b := matching[i], matching[j]
+		if a.ChannelId != b.ChannelId {
+			return a.ChannelId < b.ChannelId
+		}
+		if !strings.EqualFold(a.Name, b.Name) {
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+		return a.Id < b.Id
+	})
+
+	result := []ChannelSubscription{}
+	for i, sub := range matching {
+		if i > 0 && matching[i-1].ChannelId == sub.ChannelId && subs.Channel.FirstMatchChannelIds.ContainsAny(sub.ChannelId) {
+			continue
+		}
+		result = append(result, sub)
+	}
+	return result
+}
+
+func executeSubscribeOverlap(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 || (args[0] != subscriptionOverlapAll && args[0] != subscriptionOverlapFirst) {
+		return p.responsef(header, "Please use `/jira subscribe overlap <all|first>`.")
+	}
+	first := args[0] == subscriptionOverlapFirst
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to edit Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeOverlap: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			subs.Channel.FirstMatchChannelIds = subs.Channel.FirstMatchChannelIds.Add(header.ChannelId)
+		} else {
+			subs.Channel.FirstMatchChannelIds = subs.Channel.FirstMatchChannelIds.Subtract(header.ChannelId)
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		return modifiedBytes, nil
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to update the subscriptions of this channel: %v", err)
+	}
+
+	if first {
+		return p.responsef(header, "When several subscriptions of this channel match an event, only the first one by name now applies.")
+	}
+	return p.responsef(header, "When several subscriptions of this channel match an event, all of them now apply.")
+}
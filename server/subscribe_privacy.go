@@ -0,0 +1,83 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// getAuthorHidingChannels returns the public channels where a subscription
+// matching the webhook, including the thread subscriptions, hides the authors
+// of the events. The channels that can't be loaded are assumed public.
+func (p *Plugin) getAuthorHidingChannels(wh *webhook) (StringSet, error) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	isProjectEvent := wh.Events().Intersection(projectEvents).Len() > 0
+	channelIds := NewStringSet()
+	for _, sub := range subs.Channel.ById {
+		if !sub.Filters.HideAuthors || channelIds.ContainsAny(sub.ChannelId) {
+			continue
+		}
+		isThread := sub.RootId != "" && sub.IssueKey == wh.JiraWebhook.Issue.Key
+		if !isThread && !p.matchesChannelSubscription(wh, sub, isProjectEvent) {
+			continue
+		}
+		channel, appErr := p.API.GetChannel(sub.ChannelId)
+		if appErr == nil && channel.Type != model.CHANNEL_OPEN {
+			continue
+		}
+		channelIds = channelIds.Add(sub.ChannelId)
+	}
+	return channelIds, nil
+}
+
+// withHiddenAuthor returns the webhook with its author, and the author of its
+// comment, shown as name.
+func (wh webhook) withHiddenAuthor(name string) webhook {
+	if wh.JiraWebhook == nil {
+		return wh
+	}
+	for _, author := range []string{wh.Comment.UpdateAuthor.DisplayName, wh.Comment.Author.DisplayName, wh.User.DisplayName} {
+		if author != "" && strings.HasPrefix(wh.headline, author+" ") {
+			wh.headline = name + strings.TrimPrefix(wh.headline, author)
+			// The localized headlines take the author first
+			if wh.localizedHeadline != nil && len(wh.localizedHeadline.args) > 0 && wh.localizedHeadline.args[0] == author {
+				args := append([]interface{}{name}, wh.localizedHeadline.args[1:]...)
+				wh.localizedHeadline = newLocalizedText(wh.localizedHeadline.id, args...)
+			}
+			break
+		}
+	}
+
+	jwh := *wh.JiraWebhook
+	jwh.User = jira.User{DisplayName: name}
+	jwh.Comment.Author = jira.User{DisplayName: name}
+	jwh.Comment.UpdateAuthor = jira.User{DisplayName: name}
+	wh.JiraWebhook = &jwh
+	return wh
+}
+
+func executeSubscribeHideAuthors(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 || (args[0] != "on" && args[0] != "off") {
+		return p.responsef(header, "Please use `/jira subscribe hide-authors <on|off> <subscription name>`.")
+	}
+	on := args[0] == "on"
+	name := strings.Join(args[1:], " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		sub.Filters.HideAuthors = on
+		if on {
+			return "Subscription %q now shows the authors of the events and comments as \"A Jira user\" in public channels."
+		}
+		return "Subscription %q now shows the authors of the events and comments."
+	})
+}
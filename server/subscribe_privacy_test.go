@@ -0,0 +1,83 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHiddenAuthor(t *testing.T) {
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+	created := wh.(*webhook)
+	require.True(t, strings.HasPrefix(created.headline, "Test User "))
+
+	hidden := created.withHiddenAuthor("A Jira user")
+	assert.True(t, strings.HasPrefix(hidden.headline, "A Jira user **created** "), hidden.headline)
+	assert.Equal(t, "A Jira user", hidden.User.DisplayName)
+	assert.Empty(t, hidden.User.Name)
+	// The original webhook, posted to the other channels, is unchanged
+	assert.Equal(t, "Test User", created.User.DisplayName)
+	assert.True(t, strings.HasPrefix(created.headline, "Test User "))
+
+	data, err = getJiraTestData("webhook-cloud-comment-created.json")
+	require.Nil(t, err)
+	wh, err = ParseWebhook(data)
+	require.Nil(t, err)
+	comment := wh.(*webhook)
+	require.True(t, strings.HasPrefix(comment.headline, "Test User "))
+
+	hidden = comment.withHiddenAuthor("A Jira user")
+	assert.True(t, strings.HasPrefix(hidden.headline, "A Jira user "), hidden.headline)
+	assert.NotContains(t, hidden.headline, "Test User")
+	assert.Equal(t, "A Jira user", hidden.Comment.Author.DisplayName)
+	assert.Equal(t, "A Jira user", hidden.Comment.UpdateAuthor.DisplayName)
+	assert.Equal(t, comment.text, hidden.text)
+	assert.Equal(t, "Test User", comment.Comment.Author.DisplayName)
+}
+
+func TestGetAuthorHidingChannels(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	filters := SubscriptionFilters{
+		Events:     NewStringSet(eventCreated),
+		Projects:   NewStringSet("TES"),
+		IssueTypes: NewStringSet("10001"),
+	}
+	hidingFilters := filters
+	hidingFilters.HideAuthors = true
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		{Id: model.NewId(), ChannelId: "public", Filters: hidingFilters},
+		{Id: model.NewId(), ChannelId: "private", Filters: hidingFilters},
+		{Id: model.NewId(), ChannelId: "shown", Filters: filters},
+		{Id: model.NewId(), ChannelId: "thread", RootId: "root", IssueKey: "TES-41", Filters: SubscriptionFilters{HideAuthors: true}},
+		{Id: model.NewId(), ChannelId: "other-thread", RootId: "root", IssueKey: "TES-42", Filters: SubscriptionFilters{HideAuthors: true}},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+	api.On("GetChannel", "private").Return(&model.Channel{Id: "private", Type: model.CHANNEL_PRIVATE}, nil)
+	api.On("GetChannel", mock.AnythingOfType("string")).Return(&model.Channel{Type: model.CHANNEL_OPEN}, nil)
+
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+	channelIds, err := p.getAuthorHidingChannels(wh.(*webhook))
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"public", "thread"}, channelIds.Elems())
+}
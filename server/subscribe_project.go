@@ -0,0 +1,126 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const projectEventsSubscriptionName = "Project events"
+
+func executeSubscribeProjects(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira subscribe projects` can only be run by a system administrator.")
+	}
+
+	err = p.addChannelSubscription(&ChannelSubscription{
+		ChannelId:     header.ChannelId,
+		Name:          projectEventsSubscriptionName,
+		ProjectEvents: true,
+		CreatorId:     header.UserId,
+	}, nil)
+	if err != nil {
+		return p.responsef(header, "Failed to subscribe to project events: %v", err)
+	}
+
+	return p.responsef(header, "Jira project created and deleted events will be posted to this channel. "+
+		"Make sure the Jira webhook is configured to send project events.")
+}
+
+func executeUnsubscribeProjects(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira unsubscribe projects` can only be run by a system administrator.")
+	}
+
+	subs, err := p.getSubscriptionsForChannel(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if !sub.ProjectEvents {
+			continue
+		}
+		err = p.removeChannelSubscription(sub.Id)
+		if err != nil {
+			return p.responsef(header, "Failed to remove subscription %q: %v", sub.Name, err)
+		}
+		return p.responsef(header, "Unsubscribed from Jira project events.")
+	}
+
+	return p.responsef(header, "This channel is not subscribed to Jira project events.")
+}
+
+// flagSubscriptionsForProjectEvent marks the subscriptions filtering on a
+// deleted project, and clears the mark if a project with the same key is
+// created again. The affected subscriptions are listed in the webhook text.
+func (p *Plugin) flagSubscriptionsForProjectEvent(wh *webhook) error {
+	var deleted bool
+	switch {
+	case wh.Events().ContainsAny(eventProjectDeleted):
+		deleted = true
+	case wh.Events().ContainsAny(eventProjectCreated):
+		deleted = false
+	default:
+		return nil
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+
+	projectKey := wh.JiraWebhook.Project.Key
+	var names []string
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		names = nil
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		for id, sub := range subs.Channel.ById {
+			if sub.ProjectDeleted == deleted || !sub.Filters.Projects.ContainsAny(projectKey) {
+				continue
+			}
+			sub.ProjectDeleted = deleted
+			sub.Version++
+			subs.Channel.ById[id] = sub
+			names = append(names, sub.Name)
+		}
+		if len(names) == 0 {
+			return initialBytes, nil
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		return modifiedBytes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(names) > 0 && deleted {
+		sort.Strings(names)
+		wh.text = fmt.Sprintf("The following subscriptions filter on this project and no longer receive events: %s",
+			strings.Join(names, ", "))
+	}
+	return nil
+}
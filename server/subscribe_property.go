@@ -0,0 +1,100 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// matchesProperty returns true if the event is not an issue property event,
+// or if it sets the property of the PropertyKey and PropertyValues filters.
+func (filters SubscriptionFilters) matchesProperty(wh *webhook) bool {
+	if filters.PropertyKey == "" || !wh.Events().ContainsAny(eventPropertySet) {
+		return true
+	}
+	if wh.Property.Key != filters.PropertyKey {
+		return false
+	}
+	return filters.PropertyValues.Len() == 0 ||
+		filters.PropertyValues.ContainsAny(propertyValues(wh.Property.Value).Elems()...)
+}
+
+// propertyValues returns the scalar values of a property, which are matched
+// against the PropertyValues filter.
+func propertyValues(value interface{}) StringSet {
+	switch v := value.(type) {
+	case []interface{}:
+		values := NewStringSet()
+		for _, elem := range v {
+			values = values.Union(propertyValues(elem))
+		}
+		return values
+	case map[string]interface{}, nil:
+		return NewStringSet()
+	}
+	return NewStringSet(propertyValueText(value))
+}
+
+// propertyValueText renders a property value, as the value itself if it is a
+// scalar, or as JSON.
+func propertyValueText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func executeSubscribeProperty(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) < 2 {
+		return p.responsef(header, "Please use `/jira subscribe property <key[=value[,value...]]|clear> <subscription name>`.")
+	}
+	key, values := "", NewStringSet()
+	if args[0] != "clear" {
+		key = args[0]
+		if i := strings.Index(key, "="); i >= 0 {
+			for _, value := range strings.Split(key[i+1:], ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					values = values.Add(value)
+				}
+			}
+			key = key[:i]
+		}
+		if key == "" {
+			return p.responsef(header, "Please provide the key of the issue property.")
+		}
+	}
+	name := strings.Join(args[1:], " ")
+
+	return p.updateChannelSubscriptionByName(header, name, func(sub *ChannelSubscription) string {
+		sub.Filters.PropertyKey = key
+		sub.Filters.PropertyValues = values
+		if key == "" {
+			return "Subscription %q now posts all the issue property events it is subscribed to."
+		}
+		sub.Filters.Events = sub.Filters.Events.Add(eventPropertySet)
+		if values.Len() == 0 {
+			return "Subscription %q now posts when the issue property `" + key + "` is set."
+		}
+		elems := values.Elems()
+		sort.Strings(elems)
+		return "Subscription %q now posts when the issue property `" + key + "` is set to " + strings.Join(elems, ", ") + "."
+	})
+}
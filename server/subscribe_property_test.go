@@ -0,0 +1,62 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestParseWebhookPropertySet(t *testing.T) {
+	data, err := getJiraTestData("webhook-cloud-issue-property-set.json")
+	require.NoError(t, err)
+	wh, err := ParseWebhook(data)
+	require.NoError(t, err)
+	w := wh.(*webhook)
+	assert.True(t, w.Events().ContainsAny(eventPropertySet))
+	assert.Equal(t, "Test User **set** property `notify-chat` on story [TES-41: Unit test summary](https://some-instance-test.atlassian.net/browse/TES-41)", w.headline)
+	require.Len(t, w.fields, 1)
+	assert.Equal(t, "escalate", w.fields[0].Value)
+}
+
+func TestPropertyValues(t *testing.T) {
+	assert.Equal(t, NewStringSet("on"), propertyValues("on"))
+	assert.Equal(t, NewStringSet("3"), propertyValues(float64(3)))
+	assert.Equal(t, NewStringSet("true"), propertyValues(true))
+	assert.Equal(t, NewStringSet("support", "oncall"), propertyValues([]interface{}{"support", "oncall"}))
+	assert.Equal(t, NewStringSet(), propertyValues(map[string]interface{}{"a": "b"}))
+	assert.Equal(t, `{"a":"b"}`, propertyValueText(map[string]interface{}{"a": "b"}))
+}
+
+func TestSubscriptionFiltersMatchesProperty(t *testing.T) {
+	property := func(key string, value interface{}) *webhook {
+		return &webhook{
+			JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Property: JiraWebhookProperty{Key: key, Value: value}}},
+			eventTypes:  NewStringSet(eventPropertySet),
+		}
+	}
+	created := &webhook{JiraWebhook: &JiraWebhook{}, eventTypes: NewStringSet(eventCreated)}
+
+	for name, tc := range map[string]struct {
+		filters  SubscriptionFilters
+		wh       *webhook
+		expected bool
+	}{
+		"no property filter":   {SubscriptionFilters{}, property("other", "x"), true},
+		"matching key":         {SubscriptionFilters{PropertyKey: "notify-chat"}, property("notify-chat", "x"), true},
+		"other key":            {SubscriptionFilters{PropertyKey: "notify-chat"}, property("other", "x"), false},
+		"matching value":       {SubscriptionFilters{PropertyKey: "notify-chat", PropertyValues: NewStringSet("escalate")}, property("notify-chat", "escalate"), true},
+		"matching array value": {SubscriptionFilters{PropertyKey: "notify-chat", PropertyValues: NewStringSet("support")}, property("notify-chat", []interface{}{"oncall", "support"}), true},
+		"other value":          {SubscriptionFilters{PropertyKey: "notify-chat", PropertyValues: NewStringSet("escalate")}, property("notify-chat", "resolved"), false},
+		"not a property event": {SubscriptionFilters{PropertyKey: "notify-chat"}, created, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filters.matchesProperty(tc.wh))
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// Restrictions of ProjectChannelRestrictions other than a team name.
+const (
+	channelRestrictionPublic  = "public"
+	channelRestrictionPrivate = "private"
+)
+
+// subscriptionProjectKeys returns the keys of the projects whose events the
+// subscription posts. Filter subscriptions and project events subscriptions
+// are not bound to known projects.
+func subscriptionProjectKeys(sub *ChannelSubscription) []string {
+	switch {
+	case sub.IssueKey != "":
+		return []string{strings.ToUpper(strings.SplitN(sub.IssueKey, "-", 2)[0])}
+	case sub.FilterId != "", sub.ProjectEvents:
+		return nil
+	}
+	keys := []string{}
+	for _, key := range sub.Filters.Projects.Elems() {
+		keys = append(keys, strings.ToUpper(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// projectChannelViolation returns why the subscription is not allowed in the
+// channel by the project channel restrictions, or "" if it is.
+func projectChannelViolation(restrictions map[string]string, sub *ChannelSubscription, channel *model.Channel, team *model.Team) string {
+	for _, key := range subscriptionProjectKeys(sub) {
+		restriction, ok := restrictions[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.EqualFold(restriction, channelRestrictionPublic):
+			if channel.Type != model.CHANNEL_OPEN {
+				return fmt.Sprintf("project %s is restricted to public channels", key)
+			}
+		case strings.EqualFold(restriction, channelRestrictionPrivate):
+			if channel.Type != model.CHANNEL_PRIVATE {
+				return fmt.Sprintf("project %s is restricted to private channels", key)
+			}
+		default:
+			if team == nil || !strings.EqualFold(restriction, team.Name) {
+				return fmt.Sprintf("project %s is restricted to the channels of team %s", key, restriction)
+			}
+		}
+	}
+	return ""
+}
+
+// loadChannelWithTeam returns the channel, and its team if it has one.
+func (p *Plugin) loadChannelWithTeam(channelId string) (*model.Channel, *model.Team, error) {
+	channel, appErr := p.API.GetChannel(channelId)
+	if appErr != nil {
+		return nil, nil, errors.WithMessagef(appErr, "failed to get channel %s", channelId)
+	}
+	if channel.TeamId == "" {
+		return channel, nil, nil
+	}
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		return nil, nil, errors.WithMessagef(appErr, "failed to get team %s", channel.TeamId)
+	}
+	return channel, team, nil
+}
+
+// validateProjectChannelRestrictions returns an error if the subscription is
+// not allowed in its channel by the project channel restrictions, or by the
+// team settings.
+func (p *Plugin) validateProjectChannelRestrictions(sub *ChannelSubscription) error {
+	conf := p.getConfig()
+	if len(conf.projectChannelRestrictions) == 0 && len(conf.teamInstances) == 0 && len(conf.teamProjects) == 0 {
+		return nil
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+	channel, team, err := p.loadChannelWithTeam(sub.ChannelId)
+	if err != nil {
+		return err
+	}
+	if violation := conf.subscriptionViolation(sub, channel, team, ji.GetURL()); violation != "" {
+		return errors.Errorf("This subscription is not allowed in this channel: %s.", violation)
+	}
+	return nil
+}
+
+// MessageHasBeenPosted re-validates the subscriptions of a channel when the
+// system message of its conversion to a private channel, or of its move to
+// another team, is posted, and pauses or resumes them when the channel is
+// archived or restored. The issue keys of the messages are counted for the
+// discussed issues summary.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	switch post.Type {
+	case "":
+		err := p.trackDiscussedIssues(post)
+		if err != nil {
+			p.errorf("MessageHasBeenPosted: failed to count the issues mentioned in channel %s: %v", post.ChannelId, err)
+		}
+	case model.POST_CHANGE_CHANNEL_PRIVACY, model.POST_MOVE_CHANNEL:
+		err := p.revalidateChannelSubscriptions(post.ChannelId)
+		if err != nil {
+			p.errorf("MessageHasBeenPosted: failed to validate the subscriptions of channel %s: %v", post.ChannelId, err)
+		}
+	case model.POST_CHANNEL_DELETED, postChannelRestored:
+		err := p.pauseArchivedChannelSubscriptions(post.ChannelId, post.Type == model.POST_CHANNEL_DELETED)
+		if err != nil {
+			p.errorf("MessageHasBeenPosted: failed to pause or resume the subscriptions of channel %s: %v", post.ChannelId, err)
+		}
+	}
+}
+
+// revalidateChannelSubscriptions marks the subscriptions of the channel that
+// break the project channel restrictions or the team settings, and clears the
+// mark of those that comply again. The channel is notified of the
+// subscriptions that changed.
+func (p *Plugin) revalidateChannelSubscriptions(channelId string) error {
+	conf := p.getConfig()
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+	channel, team, err := p.loadChannelWithTeam(channelId)
+	if err != nil {
+		return err
+	}
+
+	var stopped, resumed []string
+	subKey := keyWithInstance(ji, JIRA_SUBSCRIPTIONS_KEY)
+	err = p.atomicModifySharded(subKey, func(initialBytes []byte) ([]byte, error) {
+		stopped, resumed = nil, nil
+		subs, err := SubscriptionsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range subs.Channel.IdByChannelId[channelId].Elems() {
+			sub := subs.Channel.ById[id]
+			violation := conf.subscriptionViolation(&sub, channel, team, ji.GetURL())
+			if violation == sub.NonCompliant {
+				continue
+			}
+			if violation != "" {
+				stopped = append(stopped, fmt.Sprintf("%q (%s)", sub.Name, violation))
+			} else {
+				resumed = append(resumed, fmt.Sprintf("%q", sub.Name))
+			}
+			sub.NonCompliant = violation
+			sub.Version++
+			subs.Channel.ById[id] = sub
+		}
+		if len(stopped) == 0 && len(resumed) == 0 {
+			return initialBytes, nil
+		}
+
+		modifiedBytes, marshalErr := json.Marshal(&subs)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+		return modifiedBytes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	lines := []string{}
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		lines = append(lines, p.localize(p.channelLocale(channelId), msgSubscriptionsRestricted, strings.Join(stopped, ", ")))
+	}
+	if len(resumed) > 0 {
+		sort.Strings(resumed)
+		lines = append(lines, p.localize(p.channelLocale(channelId), msgSubscriptionsUnrestricted, strings.Join(resumed, ", ")))
+	}
+	if len(lines) > 0 {
+		_, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: channelId,
+			Message:   strings.Join(lines, "\n\n"),
+		})
+		if appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// revalidateAllSubscriptions re-validates the subscriptions of all the
+// channels, after the restrictions changed. A channel that fails to validate
+// doesn't stop the others.
+func (p *Plugin) revalidateAllSubscriptions() error {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		return err
+	}
+	for channelId := range subs.Channel.IdByChannelId {
+		err = p.revalidateChannelSubscriptions(channelId)
+		if err != nil {
+			p.errorf("revalidateAllSubscriptions: failed to validate the subscriptions of channel %s: %v", channelId, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectChannelViolation(t *testing.T) {
+	restrictions := map[string]string{"SEC": "private", "WEB": "public", "HR": "people"}
+	public := &model.Channel{Type: model.CHANNEL_OPEN}
+	private := &model.Channel{Type: model.CHANNEL_PRIVATE}
+	people := &model.Team{Name: "people"}
+	other := &model.Team{Name: "eng"}
+
+	for name, tc := range map[string]struct {
+		sub       ChannelSubscription
+		channel   *model.Channel
+		team      *model.Team
+		violation string
+	}{
+		"unrestricted project": {
+			sub:     ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("EXT")}},
+			channel: public,
+		},
+		"private project in a private channel": {
+			sub:     ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("sec")}},
+			channel: private,
+		},
+		"private project in a public channel": {
+			sub:       ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}},
+			channel:   public,
+			violation: "project SEC is restricted to private channels",
+		},
+		"public project in a private channel": {
+			sub:       ChannelSubscription{IssueKey: "WEB-12"},
+			channel:   private,
+			violation: "project WEB is restricted to public channels",
+		},
+		"team project in its team": {
+			sub:     ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("HR")}},
+			channel: private,
+			team:    people,
+		},
+		"team project in another team": {
+			sub:       ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("HR")}},
+			channel:   public,
+			team:      other,
+			violation: "project HR is restricted to the channels of team people",
+		},
+		"project events": {
+			sub:     ChannelSubscription{ProjectEvents: true},
+			channel: public,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.violation, projectChannelViolation(restrictions, &tc.sub, tc.channel, tc.team))
+		})
+	}
+}
+
+func TestRevalidateChannelSubscriptions(t *testing.T) {
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "Security", Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel1", Name: "Website", Filters: SubscriptionFilters{Projects: NewStringSet("WEB")}, NonCompliant: "project WEB is restricted to private channels"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel2", Name: "Other", Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+
+	var message string
+	api := &plugintest.API{}
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.CHANNEL_OPEN}, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(string) []byte { return stored }, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(0).(*model.Post).Message
+	}).Return(&model.Post{}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.projectChannelRestrictions = map[string]string{"SEC": "private"}
+	})
+
+	p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{ChannelId: "channel1", Type: model.POST_CHANGE_CHANNEL_PRIVACY})
+
+	updated, err := SubscriptionsFromJson(stored)
+	require.NoError(t, err)
+	assert.Equal(t, "project SEC is restricted to private channels", updated.Channel.ById["sub1"].NonCompliant)
+	assert.Equal(t, "", updated.Channel.ById["sub2"].NonCompliant)
+	assert.Equal(t, "", updated.Channel.ById["sub3"].NonCompliant, "subscriptions of other channels are not validated")
+	assert.Contains(t, message, `stopped posting: "Security" (project SEC is restricted to private channels)`)
+	assert.Contains(t, message, `resumed posting: "Website"`)
+}
+
+func TestRevalidateAllSubscriptions(t *testing.T) {
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "channel1", Name: "Security", Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "channel2", Name: "Private", Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}})
+	subs.Channel.add(&ChannelSubscription{Id: "sub3", ChannelId: "channel3", Name: "Deleted", Filters: SubscriptionFilters{Projects: NewStringSet("SEC")}})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+
+	posted := map[string]string{}
+	api := &plugintest.API{}
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetChannel", "channel2").Return(&model.Channel{Id: "channel2", Type: model.CHANNEL_PRIVATE}, nil)
+	api.On("GetChannel", "channel3").Return(nil, &model.AppError{Message: "not found"})
+	api.On("LogError", mock.AnythingOfType("string")).Return()
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(string) []byte { return stored }, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		posted[post.ChannelId] = post.Message
+	}).Return(&model.Post{}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.projectChannelRestrictions = map[string]string{"SEC": "private"}
+	})
+
+	require.NoError(t, p.revalidateAllSubscriptions())
+	updated, err := SubscriptionsFromJson(stored)
+	require.NoError(t, err)
+	assert.Equal(t, "project SEC is restricted to private channels", updated.Channel.ById["sub1"].NonCompliant)
+	assert.Equal(t, "", updated.Channel.ById["sub2"].NonCompliant)
+	assert.Contains(t, posted["channel1"], `stopped posting: "Security"`)
+	assert.Len(t, posted, 1)
+}
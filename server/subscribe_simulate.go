@@ -0,0 +1,302 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+const simulatedIssueSummary = "Test issue created by /jira subscribe test"
+
+// executeSubscribeTest posts a simulated issue created event for a project
+// to the channels whose subscriptions match it, so that the routing can be
+// verified without creating an issue in Jira.
+func executeSubscribeTest(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira subscribe test` can only be run by a system administrator.")
+	}
+	if len(args) < 1 {
+		return p.responsef(header, "Please use `/jira subscribe test <project-key> [issue type]`.")
+	}
+	projectKey := strings.ToUpper(args[0])
+	issueTypeName := strings.Join(args[1:], " ")
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeSubscribeTest: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	project, err := client.GetProject(projectKey)
+	if err != nil {
+		return p.responsef(header, "Failed to get project %s: %v", projectKey, err)
+	}
+	issueType, ok := findIssueType(project, issueTypeName)
+	if !ok {
+		return p.responsef(header, "Project %s has no issue type %q.", projectKey, issueTypeName)
+	}
+
+	wh := newSimulatedIssueCreatedWebhook(ji.GetURL(), project, issueType, jiraUser.User)
+	channelIds, err := p.getChannelsSubscribed(wh)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if channelIds.Len() == 0 {
+		return p.responsef(header, "No subscription matches a new %s in %s.", issueType.Name, projectKey)
+	}
+
+	botUserId := p.getUserID()
+	var posted, failed []string
+	for _, channelId := range channelIds.Elems() {
+		name := channelId
+		if channel, appErr := p.API.GetChannel(channelId); appErr == nil {
+			name = "~" + channel.Name
+		}
+		_, _, err = wh.PostToChannel(p, channelId, botUserId)
+		if err != nil {
+			failed = append(failed, name+": "+err.Error())
+			continue
+		}
+		posted = append(posted, name)
+	}
+	sort.Strings(posted)
+
+	resp := "Posted a test " + issueType.Name + " created event to: " + strings.Join(posted, ", ")
+	if len(posted) == 0 {
+		resp = "Failed to post the test event to any of the matching channels."
+	}
+	for _, f := range failed {
+		resp += "\n* Failed to post to " + f
+	}
+	return p.responsef(header, "%s", resp)
+}
+
+// findIssueType returns the project's issue type by name, or its first
+// issue type if name is empty.
+func findIssueType(project *jira.Project, name string) (jira.IssueType, bool) {
+	for _, issueType := range project.IssueTypes {
+		if name == "" || strings.EqualFold(issueType.Name, name) {
+			return issueType, true
+		}
+	}
+	return jira.IssueType{}, false
+}
+
+// newSimulatedIssueCreatedWebhook returns an issue created webhook for a
+// non-existent issue, with its headline marked as a test.
+func newSimulatedIssueCreatedWebhook(jiraURL string, project *jira.Project, issueType jira.IssueType, user jira.User) *webhook {
+	jwh := &JiraWebhook{Event: jiraevent.Event{
+		WebhookEvent: "jira:issue_created",
+		User:         user,
+		Issue: jira.Issue{
+			Key:  project.Key + "-0",
+			Self: strings.TrimRight(jiraURL, "/") + "/rest/api/2/issue/0",
+			Fields: &jira.IssueFields{
+				Summary:     simulatedIssueSummary,
+				Description: "This issue does not exist in Jira.",
+				Type:        issueType,
+				Project: jira.Project{
+					ID:   project.ID,
+					Key:  project.Key,
+					Name: project.Name,
+				},
+			},
+		},
+	}}
+
+	wh := parseWebhookCreated(jwh).(*webhook)
+	wh.markSimulated()
+	return wh
+}
+
+// markSimulated marks the headline of a test event, which notifies no one.
+func (wh *webhook) markSimulated() {
+	wh.simulated = true
+	wh.headline = "**[TEST]** " + wh.headline
+	wh.notifications = nil
+}
+
+type simulateResult struct {
+	// Ignored is true if the event is not posted by any subscription, e.g.
+	// an update of the ignored fields only.
+	Ignored  bool     `json:"ignored,omitempty"`
+	Headline string   `json:"headline,omitempty"`
+	Events   []string `json:"events,omitempty"`
+
+	// Matched is true if SubscriptionId, a subscription of the channel,
+	// matches the event. PostId is the post of the event, unless the channel
+	// can't be posted to, which Blocked explains.
+	Matched        bool   `json:"matched"`
+	SubscriptionId string `json:"subscription_id,omitempty"`
+	PostId         string `json:"post_id,omitempty"`
+	Blocked        string `json:"blocked,omitempty"`
+}
+
+// httpSubscriptionsSimulate processes a webhook event sent by a test, e.g. in
+// the CI of a Jira automation, through the subscriptions of a channel, and
+// posts it marked as a test if one of them matches. The requests are
+// authenticated with a subscriptions webhook secret, or as a system
+// administrator. Unlike the events sent by Jira, the simulated events notify
+// no one, skip the digests, thread subscriptions and event forwarding, and
+// their issues are used as given.
+func httpSubscriptionsSimulate(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("Request: " + r.Method + " is not allowed, must be POST")
+	}
+	instanceId, status, err := authorizeSimulateRequest(p, r)
+	if err != nil {
+		return status, err
+	}
+	channelId, status, err := simulateChannelId(p, r)
+	if err != nil {
+		return status, err
+	}
+
+	bb, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	conf := p.getConfig()
+	result := simulateResult{}
+	parsed, err := ParseWebhookWithOptions(bb, conf.webhookParseOptions)
+	switch err {
+	case nil:
+	case ErrWebhookIgnored:
+		result.Ignored = true
+		return writeSimulateResult(w, result)
+	default:
+		return http.StatusBadRequest, err
+	}
+	wh := parsed.(*webhook)
+	if wh.Events().Intersection(userEvents).Len() > 0 {
+		return http.StatusBadRequest, errors.New("user events can't be simulated")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if instanceId != "" && instanceId != ji.GetURL() {
+		return http.StatusBadRequest,
+			errors.Errorf("the secret is the webhook secret of Jira instance %s, which is not the current instance", instanceId)
+	}
+	wh.instanceId = instanceId
+	wh.deliveryId = webhookDeliveryId(r)
+	wh.conf = &conf
+	wh.markSimulated()
+	result.Headline = wh.headline
+	result.Events = wh.Events().Elems()
+	sort.Strings(result.Events)
+
+	subscriptionIds, stubSubscriptionIds, err := p.getChannelSubscriptionIds(wh)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	post := wh
+	switch {
+	case subscriptionIds[channelId] != "":
+		post.subscriptionId = subscriptionIds[channelId]
+	case stubSubscriptionIds[channelId] != "":
+		post = wh.restrictedCommentStub(p, p.channelLocale(channelId))
+		post.subscriptionId = stubSubscriptionIds[channelId]
+	default:
+		return writeSimulateResult(w, result)
+	}
+	result.Matched = true
+	result.SubscriptionId = post.subscriptionId
+
+	hidingChannelIds, err := p.getAuthorHidingChannels(wh)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if hidingChannelIds.ContainsAny(channelId) {
+		hidden := post.withHiddenAuthor(p.localize(p.channelLocale(channelId), msgAnonymousJiraUser))
+		post = &hidden
+	}
+	if blocked := p.blockedChannelsOf([]string{channelId})[channelId]; blocked.reason != "" {
+		result.Blocked = englishLocalizer(blocked.reason)
+		return writeSimulateResult(w, result)
+	}
+
+	mmPost, status, err := post.postToThread(p, channelId, "", p.getUserID())
+	if err != nil {
+		return status, err
+	}
+	result.PostId = mmPost.Id
+	return writeSimulateResult(w, result)
+}
+
+// authorizeSimulateRequest returns the Jira instance of the webhook secret of
+// the request, or "" for the subscriptions webhook secret and the system
+// administrators.
+func authorizeSimulateRequest(p *Plugin, r *http.Request) (string, int, error) {
+	if userId := r.Header.Get("Mattermost-User-Id"); userId != "" {
+		if isAdmin, _ := authorizedSysAdmin(p, userId); isAdmin {
+			return "", http.StatusOK, nil
+		}
+	}
+	instanceId, status, err := p.resolveWebhookInstance(r.FormValue("secret"))
+	if err != nil {
+		if status == http.StatusForbidden {
+			p.recordWebhookAuthFailure()
+		}
+		return "", status, err
+	}
+	return instanceId, http.StatusOK, nil
+}
+
+// simulateChannelId returns the channel of the channel_id parameter, or of the
+// team and channel names.
+func simulateChannelId(p *Plugin, r *http.Request) (string, int, error) {
+	if channelId := r.FormValue("channel_id"); channelId != "" {
+		channel, appErr := p.API.GetChannel(channelId)
+		if appErr != nil {
+			return "", appErr.StatusCode, appErr
+		}
+		return channel.Id, http.StatusOK, nil
+	}
+	teamName, channelName := r.FormValue("team"), r.FormValue("channel")
+	if teamName == "" || channelName == "" {
+		return "", http.StatusBadRequest, errors.New("Request URL: must provide channel_id, or team and channel")
+	}
+	channel, appErr := p.API.GetChannelByNameForTeamName(teamName, channelName, false)
+	if appErr != nil {
+		return "", appErr.StatusCode, appErr
+	}
+	return channel.Id, http.StatusOK, nil
+}
+
+func writeSimulateResult(w http.ResponseWriter, result simulateResult) (int, error) {
+	w.Header().Set("Content-Type", "application/json")
+	b, _ := json.Marshal(result)
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionsSimulate(t *testing.T) {
+	subscribedChannelId := model.NewId()
+	otherChannelId := model.NewId()
+	subscriptionId := model.NewId()
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{{
+		Id:        subscriptionId,
+		ChannelId: subscribedChannelId,
+		Filters: SubscriptionFilters{
+			Events:     NewStringSet(eventCreated),
+			Projects:   NewStringSet("TES"),
+			IssueTypes: NewStringSet("10001"),
+		},
+	}})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		method             string
+		query              string
+		filename           string
+		expectedStatusCode int
+		expectedMatched    bool
+		expectedPost       bool
+	}{
+		"Not a POST": {
+			method:             http.MethodGet,
+			query:              "secret=thesecret&channel_id=" + subscribedChannelId,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		"Wrong secret": {
+			query:              "secret=wrong&channel_id=" + subscribedChannelId,
+			filename:           "webhook-issue-created.json",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		"No channel": {
+			query:              "secret=thesecret",
+			filename:           "webhook-issue-created.json",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Matched": {
+			query:              "secret=thesecret&channel_id=" + subscribedChannelId,
+			filename:           "webhook-issue-created.json",
+			expectedStatusCode: http.StatusOK,
+			expectedMatched:    true,
+			expectedPost:       true,
+		},
+		"Not subscribed channel": {
+			query:              "secret=thesecret&channel_id=" + otherChannelId,
+			filename:           "webhook-issue-created.json",
+			expectedStatusCode: http.StatusOK,
+		},
+		"Not matched event": {
+			query:              "secret=thesecret&channel_id=" + subscribedChannelId,
+			filename:           "webhook-issue-deleted.json",
+			expectedStatusCode: http.StatusOK,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := &Plugin{}
+			p.updateConfig(func(conf *config) {
+				conf.Secret = "thesecret"
+				conf.botUserID = "botid"
+			})
+			api := &plugintest.API{}
+			api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+			api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+			api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			api.On("GetConfig").Return(&model.Config{})
+			api.On("GetChannelMember", mock.AnythingOfType("string"), "botid").Return(&model.ChannelMember{}, nil)
+			api.On("GetUser", "botid").Return(&model.User{Id: "botid"}, nil)
+			api.On("GetChannel", mock.AnythingOfType("string")).Return(
+				func(channelId string) *model.Channel {
+					return &model.Channel{Id: channelId, Name: "thechannel", Type: model.CHANNEL_OPEN}
+				}, nil)
+			var posted *model.Post
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(
+				func(post *model.Post) *model.Post {
+					post.Id = model.NewId()
+					posted = post
+					return post
+				}, nil)
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			var body []byte
+			if tc.filename != "" {
+				body, err = getJiraTestData(tc.filename)
+				require.NoError(t, err)
+			}
+			request := httptest.NewRequest(method, routeAPISubscriptionsSimulate+"?"+tc.query, bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			status, err := handleHTTPRequest(p, &plugin.Context{}, w, request)
+			require.Equal(t, tc.expectedStatusCode, status, "error: %v", err)
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			result := simulateResult{}
+			require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&result))
+			assert.Equal(t, tc.expectedMatched, result.Matched)
+			if !tc.expectedPost {
+				assert.Empty(t, result.PostId)
+				assert.Nil(t, posted)
+				return
+			}
+			assert.Equal(t, subscriptionId, result.SubscriptionId)
+			assert.Equal(t, []string{eventCreated}, result.Events)
+			require.NotNil(t, posted)
+			assert.Equal(t, posted.Id, result.PostId)
+			assert.Equal(t, subscribedChannelId, posted.ChannelId)
+			assert.Equal(t, "true", posted.Props[postPropSimulated])
+			assert.Equal(t, subscriptionId, posted.Props[postPropSubscriptionId])
+			assert.Contains(t, result.Headline, "**[TEST]** Test User **created**")
+		})
+	}
+}
@@ -11,12 +11,15 @@ import (
 	"strings"
 	"testing"
 
+	jira "github.com/andygrunwald/go-jira"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/mattermost/mattermost-server/v5/plugin/plugintest/mock"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
 )
 
 func TestListChannelSubscriptions(t *testing.T) {
@@ -254,7 +257,7 @@ func TestListChannelSubscriptions(t *testing.T) {
 				return true
 			})).Return(nil)
 
-			actual, err := p.listChannelSubscriptions(team1.Id)
+			actual, err := p.listChannelSubscriptions(team1.Id, nil)
 			assert.Nil(t, err)
 			assert.NotNil(t, actual)
 
@@ -304,6 +307,117 @@ func TestGetChannelsSubscribed(t *testing.T) {
 			}),
 			ChannelIds: []string{"sampleChannelId"},
 		},
+		"filter subscription is not matched against webhooks": {
+			WebhookTestData: "webhook-issue-created.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					FilterId:  "10000",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("event_created"),
+						Projects:   NewStringSet("TES"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			}),
+			ChannelIds: []string{},
+		},
+		"issue subscription matches its issue": {
+			WebhookTestData: "webhook-issue-created.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					IssueKey:  "TES-41",
+				},
+			}),
+			ChannelIds: []string{"sampleChannelId"},
+		},
+		"issue subscription does not match other issues": {
+			WebhookTestData: "webhook-issue-created.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					IssueKey:  "TES-42",
+				},
+			}),
+			ChannelIds: []string{},
+		},
+		"thread issue subscription is not posted to the channel": {
+			WebhookTestData: "webhook-issue-created.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					IssueKey:  "TES-41",
+					RootId:    "sampleRootId",
+				},
+			}),
+			ChannelIds: []string{},
+		},
+		"unrecognized event matches catch-all subscription": {
+			WebhookTestData: "webhook-issue-archived-unrecognized.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet(eventUnrecognized),
+						Projects:   NewStringSet("TES"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			}),
+			ChannelIds: []string{"sampleChannelId"},
+		},
+		"unrecognized event does not match other subscriptions": {
+			WebhookTestData: "webhook-issue-archived-unrecognized.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet(eventUpdatedAny, eventCreated),
+						Projects:   NewStringSet("TES"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			}),
+			ChannelIds: []string{},
+		},
+		"project event matches project events subscription only": {
+			WebhookTestData: "webhook-project-deleted.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:            model.NewId(),
+					ChannelId:     "sampleChannelId",
+					ProjectEvents: true,
+				},
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "otherChannelId",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet(eventUpdatedAny, eventDeleted),
+						Projects:   NewStringSet("TES"),
+						IssueTypes: NewStringSet("10001"),
+					},
+				},
+			}),
+			ChannelIds: []string{"sampleChannelId"},
+		},
+		"issue event does not match project events subscription": {
+			WebhookTestData: "webhook-issue-created.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:            model.NewId(),
+					ChannelId:     "sampleChannelId",
+					ProjectEvents: true,
+				},
+			}),
+			ChannelIds: []string{},
+		},
 		"project does not match": {
 			WebhookTestData: "webhook-issue-created.json",
 			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
@@ -642,6 +756,42 @@ func TestGetChannelsSubscribed(t *testing.T) {
 			}),
 			ChannelIds: []string{},
 		},
+		"raw field selector filter configured, matches": {
+			WebhookTestData: "webhook-cloud-issue-created-many-fields.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("event_created"),
+						Projects:   NewStringSet("KT"),
+						IssueTypes: NewStringSet("10002"),
+						Fields: []FieldFilter{
+							{Key: "$.issue.fields.status.statusCategory.key", Values: NewStringSet("new"), Inclusion: FILTER_INCLUDE_ANY},
+						},
+					},
+				},
+			}),
+			ChannelIds: []string{"sampleChannelId"},
+		},
+		"raw field selector filter configured, does not match": {
+			WebhookTestData: "webhook-cloud-issue-created-many-fields.json",
+			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					Filters: SubscriptionFilters{
+						Events:     NewStringSet("event_created"),
+						Projects:   NewStringSet("KT"),
+						IssueTypes: NewStringSet("10002"),
+						Fields: []FieldFilter{
+							{Key: "$.user.timeZone", Values: NewStringSet("Europe/Paris"), Inclusion: FILTER_INCLUDE_ANY},
+						},
+					},
+				},
+			}),
+			ChannelIds: []string{},
+		},
 		"status field filter configured to include all values, all are present": {
 			WebhookTestData: "webhook-cloud-issue-created-many-fields.json",
 			Subs: withExistingChannelSubscriptions([]ChannelSubscription{
@@ -1043,3 +1193,357 @@ func TestGetChannelsSubscribed(t *testing.T) {
 		})
 	}
 }
+
+func TestGetThreadsSubscribed(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel1", IssueKey: "TES-41", RootId: "root1"},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel2", IssueKey: "TES-41"},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel3", IssueKey: "TES-42", RootId: "root3"},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+
+	actual, err := p.getThreadsSubscribed(wh.(*webhook))
+	require.Nil(t, err)
+	require.Len(t, actual, 1)
+	assert.Equal(t, "channel1", actual[0].ChannelId)
+	assert.Equal(t, "root1", actual[0].RootId)
+}
+
+func TestFlagSubscriptionsForProjectEvent(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		ChannelSubscription{
+			Id:        "sub1",
+			ChannelId: "channel1",
+			Name:      "TES issues",
+			Filters:   SubscriptionFilters{Projects: NewStringSet("TES")},
+		},
+		ChannelSubscription{
+			Id:        "sub2",
+			ChannelId: "channel2",
+			Name:      "Other issues",
+			Filters:   SubscriptionFilters{Projects: NewStringSet("OTHER")},
+		},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+
+	subKey := keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)
+	var saved []byte
+	api.On("KVGet", subKey).Return(subscriptionBytes, nil)
+	api.On("KVCompareAndSet", subKey, subscriptionBytes, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(2).([]byte)
+	}).Return(true, nil)
+
+	data, err := getJiraTestData("webhook-project-deleted.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+
+	err = p.flagSubscriptionsForProjectEvent(wh.(*webhook))
+	require.Nil(t, err)
+	assert.Contains(t, wh.(*webhook).text, "TES issues")
+	assert.NotContains(t, wh.(*webhook).text, "Other issues")
+
+	updated, err := SubscriptionsFromJson(saved)
+	require.Nil(t, err)
+	assert.True(t, updated.Channel.ById["sub1"].ProjectDeleted)
+	assert.False(t, updated.Channel.ById["sub2"].ProjectDeleted)
+}
+
+func TestGetChannelsSubscribedRestrictedComments(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policy      string
+		channelType string
+		full        []string
+		stub        []string
+	}{
+		"default skips":                 {policy: "", channelType: model.CHANNEL_OPEN},
+		"skip":                          {policy: restrictedCommentsSkip, channelType: model.CHANNEL_OPEN},
+		"private posts in private":      {policy: restrictedCommentsPrivate, channelType: model.CHANNEL_PRIVATE, full: []string{"sampleChannelId"}},
+		"private skips public channels": {policy: restrictedCommentsPrivate, channelType: model.CHANNEL_OPEN},
+		"stub":                          {policy: restrictedCommentsStub, channelType: model.CHANNEL_OPEN, stub: []string{"sampleChannelId"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+			subs := withExistingChannelSubscriptions([]ChannelSubscription{
+				ChannelSubscription{
+					Id:        model.NewId(),
+					ChannelId: "sampleChannelId",
+					Filters: SubscriptionFilters{
+						Events:             NewStringSet(eventCreatedComment),
+						Projects:           NewStringSet("TES"),
+						IssueTypes:         NewStringSet("10001"),
+						RestrictedComments: tc.policy,
+					},
+				},
+			})
+			subscriptionBytes, err := json.Marshal(subs)
+			require.Nil(t, err)
+			api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+			api.On("GetChannel", "sampleChannelId").Return(&model.Channel{Id: "sampleChannelId", Type: tc.channelType}, nil)
+
+			data, err := getJiraTestData("webhook-cloud-comment-created-restricted.json")
+			require.Nil(t, err)
+			wh, err := ParseWebhook(data)
+			require.Nil(t, err)
+			require.True(t, wh.(*webhook).isRestrictedComment())
+
+			full, stub, err := p.getChannelsSubscribedWithStubs(wh.(*webhook))
+			require.Nil(t, err)
+			assert.ElementsMatch(t, tc.full, full.Elems())
+			assert.ElementsMatch(t, tc.stub, stub.Elems())
+		})
+	}
+}
+
+func TestSimulatedIssueCreatedWebhook(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		ChannelSubscription{
+			Id:        model.NewId(),
+			ChannelId: "sampleChannelId",
+			Filters: SubscriptionFilters{
+				Events:     NewStringSet(eventCreated),
+				Projects:   NewStringSet("TES"),
+				IssueTypes: NewStringSet("10001"),
+			},
+		},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	project := &jira.Project{
+		Key:  "TES",
+		Name: "Test",
+		IssueTypes: []jira.IssueType{
+			{ID: "10001", Name: "Bug"},
+			{ID: "10002", Name: "Story"},
+		},
+	}
+
+	issueType, ok := findIssueType(project, "")
+	require.True(t, ok)
+	wh := newSimulatedIssueCreatedWebhook(mockCurrentInstanceURL, project, issueType, jira.User{DisplayName: "Test User"})
+	assert.True(t, strings.HasPrefix(wh.headline, "**[TEST]** "))
+	assert.Contains(t, wh.headline, "[TES-0: "+simulatedIssueSummary+"]("+mockCurrentInstanceURL+"/browse/TES-0)")
+	assert.Empty(t, wh.notifications)
+
+	channelIds, err := p.getChannelsSubscribed(wh)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"sampleChannelId"}, channelIds.Elems())
+
+	issueType, ok = findIssueType(project, "story")
+	require.True(t, ok)
+	channelIds, err = p.getChannelsSubscribed(newSimulatedIssueCreatedWebhook(mockCurrentInstanceURL, project, issueType, jira.User{}))
+	require.Nil(t, err)
+	assert.Equal(t, 0, channelIds.Len())
+
+	_, ok = findIssueType(project, "Epic")
+	assert.False(t, ok)
+}
+
+func TestGetChannelMentions(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	filters := SubscriptionFilters{
+		Events:     NewStringSet("event_created"),
+		Projects:   NewStringSet("KT"),
+		IssueTypes: NewStringSet("10002"),
+	}
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel1", Filters: filters, MentionRules: []MentionRule{
+			{Match: "priority:medium", Mention: "@here"},
+			{Match: "label:Label2", Mention: "@qa"},
+			{Match: "label:security", Mention: "@secteam"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel1", Filters: filters, MentionRules: []MentionRule{
+			{Match: "priority:3", Mention: "@here"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel2", Filters: filters, MentionRules: []MentionRule{
+			{Match: "priority:Blocker", Mention: "@here"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel3", IssueKey: "OTHER-1", MentionRules: []MentionRule{
+			{Match: "priority:Medium", Mention: "@here"},
+		}},
+	})
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	data, err := getJiraTestData("webhook-cloud-issue-created-many-fields.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+
+	mentions, err := p.getChannelMentions(wh.(*webhook))
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]string{"channel1": {"@here", "@qa"}}, mentions)
+}
+
+func TestMentionRuleValidate(t *testing.T) {
+	assert.NoError(t, MentionRule{Match: "priority:Blocker", Mention: "@here"}.validate())
+	assert.NoError(t, MentionRule{Match: "Label:security", Mention: "@secteam"}.validate())
+	assert.Error(t, MentionRule{Match: "priority:", Mention: "@here"}.validate())
+	assert.Error(t, MentionRule{Match: "status:Done", Mention: "@here"}.validate())
+	assert.Error(t, MentionRule{Match: "label:security", Mention: "secteam"}.validate())
+	assert.Error(t, MentionRule{Match: "label:security", Mention: "@"}.validate())
+}
+
+func TestSubscriptionFiltersMatchesHierarchy(t *testing.T) {
+	epic := &jira.Issue{Key: "TES-1", Fields: &jira.IssueFields{Type: jira.IssueType{Name: "Epic"}}}
+	cloudStory := &jira.Issue{Key: "TES-2", Fields: &jira.IssueFields{
+		Type:   jira.IssueType{Name: "Story"},
+		Parent: &jira.Parent{Key: "TES-1"},
+	}}
+	serverStory := &jira.Issue{Key: "TES-3", Fields: &jira.IssueFields{
+		Type:     jira.IssueType{Name: "Story"},
+		Unknowns: map[string]interface{}{"customfield_10008": "TES-1", "customfield_10010": "not a key", "customfield_10011": "TES-5"},
+	}}
+	subtask := &jira.Issue{Key: "TES-4", Fields: &jira.IssueFields{
+		Type:   jira.IssueType{Name: "Sub-task", Subtask: true},
+		Parent: &jira.Parent{Key: "TES-2"},
+	}}
+	other := &jira.Issue{Key: "TES-5", Fields: &jira.IssueFields{Type: jira.IssueType{Name: "Bug"}}}
+
+	all := SubscriptionFilters{}
+	for _, issue := range []*jira.Issue{epic, cloudStory, serverStory, subtask, other} {
+		assert.True(t, all.matchesHierarchy(issue), issue.Key)
+	}
+
+	underEpic := SubscriptionFilters{ParentKeys: NewStringSet("TES-1"), EpicLinkField: "customfield_10008"}
+	assert.True(t, underEpic.matchesHierarchy(epic))
+	assert.True(t, underEpic.matchesHierarchy(cloudStory))
+	assert.True(t, underEpic.matchesHierarchy(serverStory))
+	assert.False(t, underEpic.matchesHierarchy(subtask))
+	assert.False(t, underEpic.matchesHierarchy(other))
+
+	withoutEpicLink := SubscriptionFilters{ParentKeys: NewStringSet("TES-1")}
+	assert.True(t, withoutEpicLink.matchesHierarchy(cloudStory))
+	assert.False(t, withoutEpicLink.matchesHierarchy(serverStory))
+
+	underOther := SubscriptionFilters{ParentKeys: NewStringSet("TES-5"), EpicLinkField: "customfield_10008"}
+	assert.False(t, underOther.matchesHierarchy(serverStory), "other custom fields holding an issue key are not epic links")
+
+	underStory := SubscriptionFilters{ParentKeys: NewStringSet("TES-2")}
+	assert.True(t, underStory.matchesHierarchy(subtask))
+	underStory.ExcludeSubtasks = true
+	assert.False(t, underStory.matchesHierarchy(subtask))
+	assert.True(t, underStory.matchesHierarchy(cloudStory))
+
+	noSubtasks := SubscriptionFilters{ExcludeSubtasks: true}
+	assert.False(t, noSubtasks.matchesHierarchy(subtask))
+	assert.True(t, noSubtasks.matchesHierarchy(other))
+}
+
+func TestGetChannelMentionsFirstMatch(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	filters := SubscriptionFilters{
+		Events:     NewStringSet("event_created"),
+		Projects:   NewStringSet("KT"),
+		IssueTypes: NewStringSet("10002"),
+	}
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel1", Name: "b team", Filters: filters, MentionRules: []MentionRule{
+			{Match: "priority:medium", Mention: "@here"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel1", Name: "A team", Filters: filters, MentionRules: []MentionRule{
+			{Match: "label:Label2", Mention: "@qa"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel2", Name: "b team", Filters: filters, MentionRules: []MentionRule{
+			{Match: "priority:medium", Mention: "@here"},
+		}},
+		ChannelSubscription{Id: model.NewId(), ChannelId: "channel2", Name: "A team", Filters: filters, MentionRules: []MentionRule{
+			{Match: "label:Label2", Mention: "@qa"},
+		}},
+	})
+	subs.Channel.FirstMatchChannelIds = NewStringSet("channel1")
+	subscriptionBytes, err := json.Marshal(subs)
+	require.Nil(t, err)
+	api.On("KVGet", keyWithMockInstance(JIRA_SUBSCRIPTIONS_KEY)).Return(subscriptionBytes, nil)
+
+	data, err := getJiraTestData("webhook-cloud-issue-created-many-fields.json")
+	require.Nil(t, err)
+	wh, err := ParseWebhook(data)
+	require.Nil(t, err)
+
+	mentions, err := p.getChannelMentions(wh.(*webhook))
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]string{"channel1": {"@qa"}, "channel2": {"@here", "@qa"}}, mentions)
+
+	channelIds, err := p.getChannelsSubscribed(wh.(*webhook))
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"channel1", "channel2"}, channelIds.Elems())
+}
+
+func TestIsIgnoredActor(t *testing.T) {
+	p := &Plugin{}
+	p.updateConfig(func(conf *config) {
+		conf.ignoredActors = []string{"Automation for Jira"}
+	})
+
+	automation := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{User: jira.User{DisplayName: "Automation for Jira", AccountID: "557058:f581"}}}}
+	sync := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{User: jira.User{Name: "svc-sync", DisplayName: "Sync"}}}}
+	human := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{User: jira.User{Name: "jdoe", DisplayName: "John Doe", EmailAddress: "jdoe@example.com"}}}}
+	noActor := &webhook{JiraWebhook: &JiraWebhook{}}
+
+	sub := ChannelSubscription{Filters: SubscriptionFilters{IgnoredActors: []string{"SVC-SYNC", "jdoe@example.com"}}}
+	assert.True(t, p.isIgnoredActor(automation, ChannelSubscription{}))
+	assert.False(t, p.isIgnoredActor(sync, ChannelSubscription{}))
+	assert.True(t, p.isIgnoredActor(sync, sub))
+	assert.True(t, p.isIgnoredActor(human, sub))
+	assert.False(t, p.isIgnoredActor(noActor, sub))
+}
+
+func TestSplitQuotedArg(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args     []string
+		expected string
+		rest     []string
+	}{
+		"empty":       {nil, "", nil},
+		"unquoted":    {[]string{"svc-sync,bot", "Team", "bugs"}, "svc-sync,bot", []string{"Team", "bugs"}},
+		"quoted":      {[]string{`"Automation`, `for`, `Jira,svc-sync"`, "Team"}, "Automation for Jira,svc-sync", []string{"Team"}},
+		"single word": {[]string{`"svc-sync"`, "Team"}, "svc-sync", []string{"Team"}},
+		"unclosed":    {[]string{`"Automation`, "for", "Jira"}, "", nil},
+		"lone quote":  {[]string{`"`, "Team"}, "", nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			quoted, rest := splitQuotedArg(tc.args)
+			assert.Equal(t, tc.expected, quoted)
+			assert.Equal(t, tc.rest, rest)
+		})
+	}
+}
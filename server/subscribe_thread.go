@@ -0,0 +1,112 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// reIssueBrowseLink matches the issue keys of the links to Jira issues.
+var reIssueBrowseLink = regexp.MustCompile(`/browse/([[:alpha:]][[:alnum:]_]*-[[:digit:]]+)`)
+
+// postIssueKey returns the Jira issue of a post: the issue of the event it was
+// posted for, or the first issue of the Jira instance it links to.
+func postIssueKey(post *model.Post, instanceURL string) string {
+	if issueKey, ok := post.Props[postPropIssueKey].(string); ok && issueKey != "" {
+		return issueKey
+	}
+	prefix := strings.TrimRight(instanceURL, "/")
+	for _, match := range reIssueBrowseLink.FindAllStringSubmatchIndex(post.Message, -1) {
+		if strings.HasSuffix(post.Message[:match[0]], prefix) {
+			return strings.ToUpper(post.Message[match[2]:match[3]])
+		}
+	}
+	return ""
+}
+
+// httpAPISubscribeThread subscribes the thread of a post to the events of the
+// issue of the post, for the "Subscribe thread to Jira issue" post menu action.
+func httpAPISubscribeThread(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := &struct {
+		PostId string `json:"post_id"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to decode incoming request")
+	}
+
+	p := ji.GetPlugin()
+	post, appErr := p.API.GetPost(request.PostId)
+	if appErr != nil || post == nil {
+		return http.StatusBadRequest, errors.New("failed to load the post " + request.PostId)
+	}
+	_, appErr = p.API.GetChannelMember(post.ChannelId, mattermostUserId)
+	if appErr != nil {
+		return http.StatusForbidden, errors.New("not a member of the channel of the post")
+	}
+	err = p.requireTeamInstance(ji, mattermostUserId, post.ChannelId)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	issueKey := postIssueKey(post, ji.GetURL())
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("the post is not about a Jira issue")
+	}
+
+	err = p.hasPermissionToManageSubscription(mattermostUserId, post.ChannelId)
+	if err != nil {
+		return http.StatusForbidden, errors.WithMessage(err, "you are not allowed to create Jira subscriptions")
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return http.StatusUnauthorized, errors.New(p.localize(p.userLocale(mattermostUserId), msgNotConnected))
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	// The issue must be visible to the user
+	_, err = client.GetIssue(issueKey, nil)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to get issue "+issueKey)
+	}
+
+	rootId := post.RootId
+	if rootId == "" {
+		rootId = post.Id
+	}
+	err = p.subscribeToIssue(client, mattermostUserId, post.ChannelId, rootId, issueKey)
+	if err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to subscribe to "+issueKey)
+	}
+
+	message := fmt.Sprintf("Events of %s will be posted to this thread. Use `/jira unsubscribe issue %s` in the thread to stop.", issueKey, issueKey)
+	p.API.SendEphemeralPost(mattermostUserId, &model.Post{
+		ChannelId: post.ChannelId,
+		RootId:    rootId,
+		UserId:    p.getUserID(),
+		Message:   message,
+	})
+
+	b, _ := json.Marshal(map[string]string{"issue_key": issueKey, "root_id": rootId})
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
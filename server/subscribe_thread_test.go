@@ -0,0 +1,37 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostIssueKey(t *testing.T) {
+	for name, tc := range map[string]struct {
+		post     *model.Post
+		expected string
+	}{
+		"event post": {
+			post:     &model.Post{Message: "See https://jira.example.com/browse/OTHER-1", Props: model.StringInterface{postPropIssueKey: "TEST-1"}},
+			expected: "TEST-1",
+		},
+		"link to the instance": {
+			post:     &model.Post{Message: "See https://other.example.com/browse/OTHER-1 and https://jira.example.com/browse/test-2."},
+			expected: "TEST-2",
+		},
+		"link to another instance": {
+			post: &model.Post{Message: "See https://other.example.com/browse/OTHER-1"},
+		},
+		"no link": {
+			post: &model.Post{Message: "TEST-3 is done"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, postIssueKey(tc.post, "https://jira.example.com/"))
+		})
+	}
+}
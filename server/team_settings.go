@@ -0,0 +1,152 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+// parseTeamInstances parses the TeamInstances setting, a comma-separated
+// list of team=URL pairs, into the Jira instance URL by lowercase team
+// name.
+func parseTeamInstances(s string) map[string]string {
+	teamInstances := map[string]string{}
+	for team, instanceURL := range utils.ParseKeyValueList(s) {
+		teamInstances[strings.ToLower(team)] = strings.TrimRight(instanceURL, "/")
+	}
+	return teamInstances
+}
+
+// parseTeamProjects parses the TeamProjects setting, a comma-separated list
+// of team=projects pairs with the project keys separated by spaces, into the
+// uppercase project keys by lowercase team name.
+func parseTeamProjects(s string) map[string]StringSet {
+	teamProjects := map[string]StringSet{}
+	for team, projects := range utils.ParseKeyValueList(s) {
+		keys := []string{}
+		for _, key := range strings.Fields(projects) {
+			keys = append(keys, strings.ToUpper(key))
+		}
+		teamProjects[strings.ToLower(team)] = NewStringSet(keys...)
+	}
+	return teamProjects
+}
+
+// teamInstanceViolation returns why the team can't use the Jira instance, it
+// being assigned to another one by the TeamInstances setting, or "" if it
+// can.
+func (conf config) teamInstanceViolation(team *model.Team, instanceURL string) string {
+	if team == nil {
+		return ""
+	}
+	teamInstanceURL, ok := conf.teamInstances[strings.ToLower(team.Name)]
+	if !ok || strings.EqualFold(teamInstanceURL, strings.TrimRight(instanceURL, "/")) {
+		return ""
+	}
+	return fmt.Sprintf("team %s uses the Jira instance %s", team.Name, teamInstanceURL)
+}
+
+// teamViolation returns why the subscription is not allowed in the channels
+// of the team by the TeamInstances and TeamProjects settings, or "" if it is.
+// The filter and project events subscriptions are not bound to known
+// projects, so they are not allowed in the teams restricted to some projects.
+func (conf config) teamViolation(sub *ChannelSubscription, team *model.Team, instanceURL string) string {
+	if team == nil {
+		return ""
+	}
+	if violation := conf.teamInstanceViolation(team, instanceURL); violation != "" {
+		return violation
+	}
+	allowed, ok := conf.teamProjects[strings.ToLower(team.Name)]
+	if !ok {
+		return ""
+	}
+	if sub.FilterId != "" || sub.ProjectEvents {
+		return fmt.Sprintf("team %s is restricted to the projects %s", team.Name, sortedJoin(allowed, ", "))
+	}
+	for _, key := range subscriptionProjectKeys(sub) {
+		if !allowed.ContainsAny(key) {
+			return fmt.Sprintf("project %s is not allowed in team %s", key, team.Name)
+		}
+	}
+	return ""
+}
+
+// subscriptionViolation returns why the subscription is not allowed in the
+// channel, by the project channel restrictions or by the team settings, or ""
+// if it is.
+func (conf config) subscriptionViolation(sub *ChannelSubscription, channel *model.Channel, team *model.Team, instanceURL string) string {
+	if violation := projectChannelViolation(conf.projectChannelRestrictions, sub, channel, team); violation != "" {
+		return violation
+	}
+	return conf.teamViolation(sub, team, instanceURL)
+}
+
+// teamInstanceError returns an error if the team is assigned to another Jira
+// instance than the current one, unless the user is a system administrator,
+// e.g. to install the team's instance.
+func (p *Plugin) teamInstanceError(ji Instance, mattermostUserId string, team *model.Team) error {
+	violation := p.getConfig().teamInstanceViolation(team, ji.GetURL())
+	if violation == "" {
+		return nil
+	}
+	if authorized, _ := authorizedSysAdmin(p, mattermostUserId); authorized {
+		return nil
+	}
+	return errors.Errorf("Jira is not available in this team: %s, and Mattermost is connected to %s.", violation, ji.GetURL())
+}
+
+// teamInstanceResponse returns the response to the commands calling Jira in
+// a team assigned to another Jira instance than the current one, or nil.
+func (p *Plugin) teamInstanceResponse(header *model.CommandArgs, args []string) *model.CommandResponse {
+	conf := p.getConfig()
+	if len(conf.teamInstances) == 0 || header.TeamId == "" || len(args) == 0 || commandsWithoutJira.ContainsAny(args[0]) {
+		return nil
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return nil
+	}
+	team, appErr := p.API.GetTeam(header.TeamId)
+	if appErr != nil {
+		return nil
+	}
+	if err := p.teamInstanceError(ji, header.UserId, team); err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	return nil
+}
+
+// requireTeamInstance is the check of teamInstanceResponse for the HTTP APIs
+// and the post actions, by the channel they are used in. The direct and group
+// messages are in no team.
+func (p *Plugin) requireTeamInstance(ji Instance, mattermostUserId, channelId string) error {
+	if len(p.getConfig().teamInstances) == 0 || channelId == "" {
+		return nil
+	}
+	_, team, err := p.loadChannelWithTeam(channelId)
+	if err != nil {
+		return err
+	}
+	return p.teamInstanceError(ji, mattermostUserId, team)
+}
+
+// writePostActionText responds to a post action with an ephemeral message.
+func writePostActionText(w http.ResponseWriter, text string) (int, error) {
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{EphemeralText: text})
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamViolation(t *testing.T) {
+	conf := config{
+		teamInstances: parseTeamInstances("Engineering=https://eng.atlassian.net/, support=" + mockCurrentInstanceURL),
+		teamProjects:  parseTeamProjects("support=sup help"),
+	}
+	engineering := &model.Team{Name: "engineering"}
+	support := &model.Team{Name: "support"}
+	other := &model.Team{Name: "other"}
+	sup := &ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("SUP")}}
+	web := &ChannelSubscription{Filters: SubscriptionFilters{Projects: NewStringSet("WEB")}}
+
+	assert.Equal(t, "team engineering uses the Jira instance https://eng.atlassian.net", conf.teamViolation(sup, engineering, mockCurrentInstanceURL))
+	assert.Equal(t, "", conf.teamViolation(sup, engineering, "https://eng.atlassian.net"))
+	assert.Equal(t, "", conf.teamViolation(sup, support, mockCurrentInstanceURL))
+	assert.Equal(t, "", conf.teamViolation(&ChannelSubscription{IssueKey: "HELP-12"}, support, mockCurrentInstanceURL))
+	assert.Equal(t, "project WEB is not allowed in team support", conf.teamViolation(web, support, mockCurrentInstanceURL))
+	assert.Equal(t, "team support is restricted to the projects HELP, SUP", conf.teamViolation(&ChannelSubscription{FilterId: "10000"}, support, mockCurrentInstanceURL))
+	assert.Equal(t, "", conf.teamViolation(web, other, mockCurrentInstanceURL))
+	assert.Equal(t, "", conf.teamViolation(web, nil, mockCurrentInstanceURL))
+}
+
+func TestTeamInstanceResponse(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetTeam", "engineering").Return(&model.Team{Id: "engineering", Name: "engineering"}, nil)
+	api.On("GetTeam", "support").Return(&model.Team{Id: "support", Name: "support"}, nil)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: model.SYSTEM_USER_ROLE_ID}, nil)
+	api.On("GetUser", "admin").Return(&model.User{Id: "admin", Roles: model.SYSTEM_ADMIN_ROLE_ID}, nil)
+	var message string
+	api.On("SendEphemeralPost", "user", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(&model.Post{})
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.teamInstances = parseTeamInstances("engineering=https://eng.atlassian.net")
+	})
+
+	resp := p.teamInstanceResponse(&model.CommandArgs{UserId: "user", TeamId: "engineering"}, []string{"view", "TES-1"})
+	require.NotNil(t, resp)
+	assert.Contains(t, message, "Jira is not available in this team: team engineering uses the Jira instance https://eng.atlassian.net")
+	assert.Nil(t, p.teamInstanceResponse(&model.CommandArgs{UserId: "user", TeamId: "engineering"}, []string{"help"}))
+	assert.Nil(t, p.teamInstanceResponse(&model.CommandArgs{UserId: "admin", TeamId: "engineering"}, []string{"view", "TES-1"}))
+	assert.Nil(t, p.teamInstanceResponse(&model.CommandArgs{UserId: "user", TeamId: "support"}, []string{"view", "TES-1"}))
+}
+
+func TestRequireTeamInstance(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannel", "engineering-channel").Return(&model.Channel{Id: "engineering-channel", TeamId: "engineering"}, nil)
+	api.On("GetChannel", "dm").Return(&model.Channel{Id: "dm", Type: model.CHANNEL_DIRECT}, nil)
+	api.On("GetTeam", "engineering").Return(&model.Team{Id: "engineering", Name: "engineering"}, nil)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: model.SYSTEM_USER_ROLE_ID}, nil)
+	api.On("GetUser", "admin").Return(&model.User{Id: "admin", Roles: model.SYSTEM_ADMIN_ROLE_ID}, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+
+	assert.NoError(t, p.requireTeamInstance(ji, "user", "engineering-channel"), "no team instances")
+
+	p.updateConfig(func(conf *config) {
+		conf.teamInstances = parseTeamInstances("engineering=https://eng.atlassian.net")
+	})
+	err = p.requireTeamInstance(ji, "user", "engineering-channel")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Jira is not available in this team")
+	assert.NoError(t, p.requireTeamInstance(ji, "admin", "engineering-channel"))
+	assert.NoError(t, p.requireTeamInstance(ji, "user", "dm"))
+	assert.NoError(t, p.requireTeamInstance(ji, "user", ""))
+}
@@ -0,0 +1,448 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyTodoLists = "todo_lists"
+
+	// The to-do list shows this many of the open issues assigned to the
+	// user that are not snoozed.
+	todoMaxIssues = 10
+
+	todoReminderPollInterval = channelReportPollInterval
+)
+
+// Actions of the to-do list buttons.
+const (
+	todoActionSnooze  = "snooze"
+	todoActionUnwatch = "unwatch"
+)
+
+// todoSnoozeDays are the options of the snooze select, in days.
+var todoSnoozeDays = []int{1, 3, 7}
+
+// todoList is the state of the /jira todo list of a user: the issues
+// snoozed, and when the list is sent to them as a reminder.
+type todoList struct {
+	// Snoozed are the issues hidden from the list, with when they come
+	// back in milliseconds, by issue key.
+	Snoozed map[string]int64 `json:"snoozed,omitempty"`
+
+	Reminder *channelReportSchedule `json:"reminder,omitempty"`
+
+	// NextReminder is when the reminder is sent next, in milliseconds.
+	NextReminder int64 `json:"next_reminder,omitempty"`
+}
+
+func (list todoList) isSnoozed(issueKey string, now time.Time) bool {
+	return list.Snoozed[issueKey] > model.GetMillisForTime(now)
+}
+
+type todoLists struct {
+	ByUser map[string]todoList `json:"by_user"`
+}
+
+func todoListsFromJson(data []byte) (*todoLists, error) {
+	lists := &todoLists{ByUser: map[string]todoList{}}
+	if len(data) == 0 {
+		return lists, nil
+	}
+	err := json.Unmarshal(data, lists)
+	if err != nil {
+		return nil, err
+	}
+	if lists.ByUser == nil {
+		lists.ByUser = map[string]todoList{}
+	}
+	return lists, nil
+}
+
+func (p *Plugin) loadTodoLists(ji Instance) (*todoLists, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyTodoLists))
+	if appErr != nil {
+		return nil, appErr
+	}
+	return todoListsFromJson(data)
+}
+
+func (p *Plugin) loadTodoList(ji Instance, mattermostUserId string) (todoList, error) {
+	lists, err := p.loadTodoLists(ji)
+	if err != nil {
+		return todoList{}, err
+	}
+	return lists.ByUser[mattermostUserId], nil
+}
+
+// modifyTodoList modifies the to-do list of a user, forgetting the snoozes
+// that are over, and the lists left with nothing to remember.
+func (p *Plugin) modifyTodoList(ji Instance, mattermostUserId string, modify func(list *todoList)) error {
+	return p.atomicModify(keyWithInstance(ji, keyTodoLists), func(initialBytes []byte) ([]byte, error) {
+		lists, err := todoListsFromJson(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		list := lists.ByUser[mattermostUserId]
+		modify(&list)
+
+		now := model.GetMillis()
+		for issueKey, until := range list.Snoozed {
+			if until <= now {
+				delete(list.Snoozed, issueKey)
+			}
+		}
+		if len(list.Snoozed) == 0 && list.Reminder == nil {
+			delete(lists.ByUser, mattermostUserId)
+		} else {
+			lists.ByUser[mattermostUserId] = list
+		}
+		return json.Marshal(lists)
+	})
+}
+
+// todoIssues returns the open issues assigned to the user that are not
+// snoozed, and how many are snoozed.
+func todoIssues(client Client, list todoList, now time.Time) ([]jira.Issue, int, error) {
+	max := todoMaxIssues + len(list.Snoozed)
+	if max > searchMaxResults {
+		max = searchMaxResults
+	}
+	found, err := client.SearchIssues(myIssuesJQL, &jira.SearchOptions{
+		MaxResults: max,
+		Fields:     myIssueFields,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	issues, snoozed := []jira.Issue{}, 0
+	for _, issue := range found {
+		switch {
+		case list.isSnoozed(issue.Key, now):
+			snoozed++
+		case len(issues) < todoMaxIssues:
+			issues = append(issues, issue)
+		}
+	}
+	return issues, snoozed, nil
+}
+
+// todoPost renders the to-do list, with the buttons to transition, snooze or
+// unwatch each issue, translated by t.
+func (p *Plugin) todoPost(jiraURL, channelId string, issues []jira.Issue, snoozed int, t localizer) *model.Post {
+	message := t(msgTodoIssues)
+	if len(issues) == 0 {
+		message = t(msgTodoNoIssues)
+	}
+	if snoozed > 0 {
+		message += " " + t(msgTodoSnoozed, snoozed)
+	}
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelId,
+		Message:   message,
+	}
+	if len(issues) == 0 {
+		return post
+	}
+
+	attachments := []*model.SlackAttachment{}
+	for _, issue := range issues {
+		action := func(id, name string) *model.PostAction {
+			return &model.PostAction{
+				Id:   id,
+				Name: name,
+				Integration: &model.PostActionIntegration{
+					URL: p.GetPluginURLPath() + routeAPITodoAction,
+					Context: map[string]interface{}{
+						"issue_key": issue.Key,
+						"action":    id,
+					},
+				},
+			}
+		}
+		snooze := action(todoActionSnooze, t(msgActionSnooze))
+		snooze.Type = model.POST_ACTION_TYPE_SELECT
+		for _, days := range todoSnoozeDays {
+			text := t(msgActionSnoozeDays, days)
+			if days == 1 {
+				text = t(msgActionSnoozeDay)
+			}
+			snooze.Options = append(snooze.Options, &model.PostActionOptions{
+				Text:  text,
+				Value: strconv.Itoa(days),
+			})
+		}
+		attachments = append(attachments, &model.SlackAttachment{
+			Text: myIssueText(jiraURL, issue),
+			Actions: []*model.PostAction{
+				p.transitionAction(issue.Key, t),
+				snooze,
+				action(todoActionUnwatch, t(msgActionUnwatch)),
+			},
+		})
+	}
+	post.AddProp("attachments", attachments)
+	return post
+}
+
+func executeTodo(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 0 {
+		return p.help(header)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeTodo: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	list, err := p.loadTodoList(ji, header.UserId)
+	if err != nil {
+		return p.responsef(header, "Failed to load your to-do list: %v", err)
+	}
+
+	issues, snoozed, err := todoIssues(client, list, time.Now())
+	if err != nil {
+		return p.responsef(header, "Failed to search your Jira issues: %v", err)
+	}
+	p.API.SendEphemeralPost(header.UserId, p.todoPost(strings.TrimRight(ji.GetURL(), "/"), header.ChannelId, issues, snoozed,
+		p.localizer(p.userLocale(header.UserId))))
+	return &model.CommandResponse{}
+}
+
+func executeTodoUnsnooze(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira todo unsnooze <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeTodoUnsnooze: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+
+	found := false
+	err = p.modifyTodoList(ji, header.UserId, func(list *todoList) {
+		_, found = list.Snoozed[issueKey]
+		delete(list.Snoozed, issueKey)
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to update your to-do list: %v", err)
+	}
+	if !found {
+		return p.responsef(header, "%s is not snoozed.", issueKey)
+	}
+	return p.responsef(header, "%s is back in your to-do list.", issueKey)
+}
+
+func executeTodoRemind(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please use `/jira todo remind <schedule|off>`, e.g. `/jira todo remind weekdays@09:00`, in UTC.")
+	}
+	var schedule *channelReportSchedule
+	if args[0] != "off" {
+		s, err := parseChannelReportSchedule(args[0])
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if s.Every == channelReportHourly {
+			return p.responsef(header, "The reminders can be sent once a day at most, e.g. `daily@09:00`.")
+		}
+		schedule = &s
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeTodoRemind: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	if _, err = p.userStore.LoadJIRAUser(ji, header.UserId); err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	err = p.modifyTodoList(ji, header.UserId, func(list *todoList) {
+		list.Reminder = schedule
+		list.NextReminder = 0
+		if schedule != nil {
+			list.NextReminder = model.GetMillisForTime(schedule.next(time.Now()))
+		}
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to update your to-do list: %v", err)
+	}
+	if schedule == nil {
+		return p.responsef(header, "Your to-do list reminders are turned off.")
+	}
+	return p.responsef(header, "Your to-do list will be sent to you by direct message %s UTC, when you have open issues that are not snoozed.", schedule)
+}
+
+// postTodoReminders sends their to-do list to the users whose reminder is
+// due, and schedules their next reminder.
+func (p *Plugin) postTodoReminders() {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return
+	}
+	lists, err := p.loadTodoLists(ji)
+	if err != nil {
+		p.errorf("postTodoReminders: failed to load the to-do lists: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for mattermostUserId, list := range lists.ByUser {
+		if list.Reminder == nil || list.NextReminder > model.GetMillisForTime(now) {
+			continue
+		}
+		err = p.postTodoReminder(ji, mattermostUserId, list, now)
+		if err != nil {
+			p.errorf("postTodoReminders: failed to remind user %s: %v", mattermostUserId, err)
+		}
+
+		err = p.modifyTodoList(ji, mattermostUserId, func(list *todoList) {
+			if list.Reminder != nil {
+				list.NextReminder = model.GetMillisForTime(list.Reminder.next(now))
+			}
+		})
+		if err != nil {
+			p.errorf("postTodoReminders: failed to schedule the reminder of user %s: %v", mattermostUserId, err)
+		}
+	}
+}
+
+func (p *Plugin) postTodoReminder(ji Instance, mattermostUserId string, list todoList, now time.Time) error {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return errors.WithMessage(err, "user is not connected to Jira")
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err
+	}
+	issues, snoozed, err := todoIssues(client, list, now)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	channel, appErr := p.API.GetDirectChannel(mattermostUserId, p.getUserID())
+	if appErr != nil {
+		return appErr
+	}
+	_, appErr = p.API.CreatePost(p.todoPost(strings.TrimRight(ji.GetURL(), "/"), channel.Id, issues, snoozed,
+		p.localizer(p.userLocale(mattermostUserId))))
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// httpAPITodoAction handles the Snooze and Unwatch buttons of the to-do
+// lists. The Transition button is the one of the issue posts.
+func httpAPITodoAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	issueKey, _ := request.Context["issue_key"].(string)
+	action, _ := request.Context["action"].(string)
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("missing issue key")
+	}
+
+	p := ji.GetPlugin()
+	if err := p.requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	var message string
+	switch action {
+	case todoActionSnooze:
+		days, err := strconv.Atoi(fmt.Sprint(request.Context["selected_option"]))
+		if err != nil || days < 1 {
+			return http.StatusBadRequest, errors.New("invalid snooze duration")
+		}
+		until := time.Now().AddDate(0, 0, days)
+		err = p.modifyTodoList(ji, mattermostUserId, func(list *todoList) {
+			if list.Snoozed == nil {
+				list.Snoozed = map[string]int64{}
+			}
+			list.Snoozed[issueKey] = model.GetMillisForTime(until)
+		})
+		if err != nil {
+			message = fmt.Sprintf("Failed to snooze %s: %v", issueKey, err)
+		} else {
+			message = fmt.Sprintf("%s is snoozed until %s.", issueKey, until.UTC().Format(time.RFC1123))
+		}
+
+	case todoActionUnwatch:
+		message = p.unwatchTodoIssue(ji, mattermostUserId, issueKey)
+
+	default:
+		return http.StatusBadRequest, errors.New("unknown action " + action)
+	}
+
+	p.API.SendEphemeralPost(mattermostUserId, &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: request.ChannelId,
+		Message:   message,
+	})
+
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{})
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func (p *Plugin) unwatchTodoIssue(ji Instance, mattermostUserId, issueKey string) string {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return "Your Mattermost account is not connected to Jira."
+	}
+	err = p.requireWriteAccess(mattermostUserId, jiraUser)
+	if err != nil {
+		return err.Error()
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return err.Error()
+	}
+	err = p.requireWriteAllowed(mattermostUserId)
+	if err != nil {
+		return err.Error()
+	}
+	err = client.RemoveWatcher(issueKey, &jiraUser.User)
+	if err != nil {
+		return fmt.Sprintf("Failed to stop watching %s: %v", issueKey, err)
+	}
+	return fmt.Sprintf("You are no longer watching %s.", issueKey)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+type todoTestClient struct {
+	testClient
+	issues     []jira.Issue
+	maxResults int
+}
+
+func (client *todoTestClient) SearchIssues(jql string, options *jira.SearchOptions) ([]jira.Issue, error) {
+	client.maxResults = options.MaxResults
+	if len(client.issues) > options.MaxResults {
+		return client.issues[:options.MaxResults], nil
+	}
+	return client.issues, nil
+}
+
+func TestTodoIssues(t *testing.T) {
+	client := &todoTestClient{}
+	for i := 1; i <= 15; i++ {
+		client.issues = append(client.issues, jira.Issue{Key: fmt.Sprintf("TEST-%d", i)})
+	}
+	now := time.Now()
+	list := todoList{Snoozed: map[string]int64{
+		"TEST-1": model.GetMillisForTime(now.Add(time.Hour)),
+		"TEST-3": model.GetMillisForTime(now.Add(time.Hour)),
+		"TEST-4": model.GetMillisForTime(now.Add(-time.Hour)),
+	}}
+
+	issues, snoozed, err := todoIssues(client, list, now)
+	require.NoError(t, err)
+	assert.Equal(t, todoMaxIssues+3, client.maxResults)
+	assert.Equal(t, 2, snoozed)
+	require.Len(t, issues, todoMaxIssues)
+	assert.Equal(t, "TEST-2", issues[0].Key)
+	assert.Equal(t, "TEST-4", issues[1].Key)
+}
+
+func TestTodoPost(t *testing.T) {
+	p := &Plugin{}
+	post := p.todoPost("https://jira.example.com", "channel", []jira.Issue{
+		{Key: "TEST-1", Fields: &jira.IssueFields{Summary: "Fix the build"}},
+	}, 2, englishLocalizer)
+	assert.Contains(t, post.Message, "2 snoozed issues are not listed")
+
+	attachments, ok := post.Props["attachments"].([]*model.SlackAttachment)
+	require.True(t, ok)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, "[TEST-1](https://jira.example.com/browse/TEST-1) Fix the build", attachments[0].Text)
+	require.Len(t, attachments[0].Actions, 3)
+	assert.Equal(t, "transition", attachments[0].Actions[0].Id)
+	assert.Equal(t, model.POST_ACTION_TYPE_SELECT, attachments[0].Actions[1].Type)
+	assert.Len(t, attachments[0].Actions[1].Options, len(todoSnoozeDays))
+	assert.Equal(t, todoActionUnwatch, attachments[0].Actions[2].Integration.Context["action"])
+
+	post = p.todoPost("https://jira.example.com", "channel", nil, 0, englishLocalizer)
+	assert.Equal(t, "You have no open Jira issues to work on.", post.Message)
+	assert.Nil(t, post.Props["attachments"])
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	transitionActionOpen   = "open"
+	transitionActionSelect = "select"
+)
+
+// transitionAction returns the button of an issue post listing the
+// transitions of the issue. The transitions depend on the workflow and on the
+// permissions of the user, so they are only fetched when the button is
+// clicked, and the user picks one in a select sent to them.
+func (p *Plugin) transitionAction(issueKey string, t localizer) *model.PostAction {
+	return &model.PostAction{
+		Id:   "transition",
+		Name: t(msgActionTransition),
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPITransitionAction,
+			Context: map[string]interface{}{
+				"issue_key": issueKey,
+				"action":    transitionActionOpen,
+			},
+		},
+	}
+}
+
+// transitionSelectPost renders the select of the transitions of an issue
+// currently available to the user.
+func (p *Plugin) transitionSelectPost(channelId, issueKey string, transitions []jira.Transition) *model.Post {
+	selectAction := &model.PostAction{
+		Id:   "transitionselect",
+		Name: "Select a transition",
+		Type: model.POST_ACTION_TYPE_SELECT,
+		Integration: &model.PostActionIntegration{
+			URL: p.GetPluginURLPath() + routeAPITransitionAction,
+			Context: map[string]interface{}{
+				"issue_key": issueKey,
+				"action":    transitionActionSelect,
+			},
+		},
+	}
+	for _, t := range transitions {
+		text := t.Name
+		if t.To.Name != "" && t.To.Name != t.Name {
+			text = fmt.Sprintf("%s (to %s)", t.Name, t.To.Name)
+		}
+		selectAction.Options = append(selectAction.Options, &model.PostActionOptions{
+			Text:  text,
+			Value: t.ID,
+		})
+	}
+
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelId,
+		Message:   fmt.Sprintf("Transition %s:", issueKey),
+	}
+	post.AddProp("attachments", []*model.SlackAttachment{{Actions: []*model.PostAction{selectAction}}})
+	return post
+}
+
+// httpAPITransitionAction handles the Transition button of the issue posts,
+// and the select of the transitions it sends.
+func httpAPITransitionAction(ji Instance, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		return http.StatusBadRequest, errors.New("failed to decode the action request")
+	}
+	issueKey, _ := request.Context["issue_key"].(string)
+	action, _ := request.Context["action"].(string)
+	if issueKey == "" {
+		return http.StatusBadRequest, errors.New("missing issue key")
+	}
+
+	p := ji.GetPlugin()
+	if err := p.requireTeamInstance(ji, mattermostUserId, request.ChannelId); err != nil {
+		return writePostActionText(w, err.Error())
+	}
+	switch action {
+	case transitionActionOpen:
+		post := &model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: request.ChannelId,
+		}
+		transitions, err := p.getIssueTransitions(ji, mattermostUserId, issueKey)
+		if err != nil {
+			post.Message = err.Error()
+		} else {
+			post = p.transitionSelectPost(request.ChannelId, issueKey, transitions)
+		}
+		p.API.SendEphemeralPost(mattermostUserId, post)
+
+	case transitionActionSelect:
+		transitionId, _ := request.Context["selected_option"].(string)
+		message, err := p.transitionJiraIssueById(ji, mattermostUserId, issueKey, transitionId)
+		if err != nil {
+			message = err.Error()
+		}
+		p.API.UpdateEphemeralPost(mattermostUserId, &model.Post{
+			Id:        request.PostId,
+			UserId:    p.getUserID(),
+			ChannelId: request.ChannelId,
+			Message:   message,
+		})
+
+	default:
+		return http.StatusBadRequest, errors.New("unknown action " + action)
+	}
+
+	b, _ := json.Marshal(model.PostActionIntegrationResponse{})
+	_, err := w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// getIssueTransitions returns the transitions of the issue available to the
+// user.
+func (p *Plugin) getIssueTransitions(ji Instance, mattermostUserId, issueKey string) ([]jira.Transition, error) {
+	client, err := p.transitionClient(ji, mattermostUserId)
+	if err != nil {
+		return nil, err
+	}
+	return listTransitions(client, issueKey)
+}
+
+// transitionJiraIssueById runs the selected transition, after checking that
+// it is still available, since the workflow may have changed since the
+// select was sent.
+func (p *Plugin) transitionJiraIssueById(ji Instance, mattermostUserId, issueKey, transitionId string) (string, error) {
+	client, err := p.transitionClient(ji, mattermostUserId)
+	if err != nil {
+		return "", err
+	}
+	transitions, err := listTransitions(client, issueKey)
+	if err != nil {
+		return "", err
+	}
+	var transition *jira.Transition
+	for i := range transitions {
+		if transitions[i].ID == transitionId {
+			transition = &transitions[i]
+			break
+		}
+	}
+	if transition == nil {
+		return "", errors.Errorf("This transition is no longer available for %s, please click Transition again.", issueKey)
+	}
+
+	if err := p.requireWriteAllowed(mattermostUserId); err != nil {
+		return "", err
+	}
+	if err := client.DoTransition(issueKey, transition.ID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[%s](%v/browse/%v) transitioned to `%s`",
+		issueKey, ji.GetURL(), issueKey, transition.To.Name), nil
+}
+
+func (p *Plugin) transitionClient(ji Instance, mattermostUserId string) (Client, error) {
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return nil, errors.New(p.localize(p.userLocale(mattermostUserId), msgNotConnected))
+	}
+	err = p.requireWriteAccess(mattermostUserId, jiraUser)
+	if err != nil {
+		return nil, err
+	}
+	return ji.GetClient(jiraUser)
+}
+
+func listTransitions(client Client, issueKey string) ([]jira.Transition, error) {
+	transitions, err := client.GetTransitions(issueKey)
+	if err != nil {
+		return nil, errors.New("We couldn't find the issue key. Please confirm the issue key and try again. You may not have permissions to access this issue.")
+	}
+	if len(transitions) < 1 {
+		return nil, errors.New("You do not have the appropriate permissions to perform this action. Please contact your Jira administrator.")
+	}
+	return transitions, nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionSelectPost(t *testing.T) {
+	p := &Plugin{}
+	transitions, err := listTransitions(testClient{}, existingIssueKey)
+	require.NoError(t, err)
+
+	post := p.transitionSelectPost("channelid", existingIssueKey, transitions)
+	assert.Equal(t, "Transition REAL-1:", post.Message)
+	attachments := post.Props["attachments"].([]*model.SlackAttachment)
+	require.Len(t, attachments, 1)
+	require.Len(t, attachments[0].Actions, 1)
+	action := attachments[0].Actions[0]
+	assert.Equal(t, model.POST_ACTION_TYPE_SELECT, action.Type)
+	assert.Equal(t, transitionActionSelect, action.Integration.Context["action"])
+	assert.Equal(t, []*model.PostActionOptions{
+		{Text: "Reopen (to To Do)", Value: "11"},
+		{Text: "Start (to In Progress)", Value: "21"},
+		{Text: "In Testing", Value: "31"},
+	}, action.Options)
+}
+
+func TestTransitionJiraIssueById(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{}, nil)
+	p := &Plugin{userStore: mockUserStore{}}
+	p.SetAPI(api)
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+
+	msg, err := p.transitionJiraIssueById(ji, "user", existingIssueKey, "21")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("[%s](%s/browse/%s) transitioned to `In Progress`", existingIssueKey, mockCurrentInstanceURL, existingIssueKey), msg)
+
+	_, err = p.transitionJiraIssueById(ji, "user", existingIssueKey, "41")
+	assert.EqualError(t, err, "This transition is no longer available for REAL-1, please click Transition again.")
+
+	_, err = p.transitionJiraIssueById(ji, "user", noPermissionsIssueKey, "21")
+	assert.EqualError(t, err, noPermissionsError)
+}
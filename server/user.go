@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/pkg/errors"
@@ -19,12 +20,32 @@ const (
 	WS_EVENT_DISCONNECT = "disconnect"
 )
 
+// Scopes of a user connection. A read-only connection can view issues and be
+// notified, but the plugin does not change anything in Jira on its behalf.
+const (
+	userScopeRead  = "read"
+	userScopeWrite = "write"
+)
+
+// keyConnectScopes is the one-time secret recording the scopes requested by
+// a user's pending connection.
+const keyConnectScopes = "connect_scopes_"
+
+var ErrReadOnlyConnection = errors.New("your Jira account is connected with read-only access. " +
+	"Please use `/jira disconnect`, then `/jira connect` to connect with write access")
+
+var ErrGuestAccess = errors.New("guest accounts are not allowed to change Jira or its subscriptions")
+
 type JIRAUser struct {
 	jira.User
 	PluginVersion      string
 	Oauth1AccessToken  string `json:",omitempty"`
 	Oauth1AccessSecret string `json:",omitempty"`
 	Settings           *UserSettings
+
+	// Scopes granted by the user when connecting. Connections made before
+	// scopes were recorded have none, and have full access.
+	Scopes []string `json:",omitempty"`
 }
 
 func (u JIRAUser) Key() string {
@@ -35,6 +56,55 @@ func (u JIRAUser) Key() string {
 	}
 }
 
+// HasScope returns true if the connection was granted scope.
+func (u JIRAUser) HasScope(scope string) bool {
+	if len(u.Scopes) == 0 {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// requireWriteScope returns ErrReadOnlyConnection if the plugin may not
+// change Jira on behalf of the user.
+func (u JIRAUser) requireWriteScope() error {
+	if !u.HasScope(userScopeWrite) {
+		return ErrReadOnlyConnection
+	}
+	return nil
+}
+
+// requireNotGuest returns ErrGuestAccess if the user is a Mattermost guest,
+// unless guests are allowed to edit Jira.
+func (p *Plugin) requireNotGuest(mattermostUserId string) error {
+	if p.getConfig().AllowGuestsToEditJira {
+		return nil
+	}
+	user, appErr := p.API.GetUser(mattermostUserId)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to get user to check permission")
+	}
+	if user.IsGuest() {
+		return ErrGuestAccess
+	}
+	return nil
+}
+
+// requireWriteAccess returns an error if the plugin may not change Jira on
+// behalf of the user, being a guest or connected with read-only access. The
+// changes themselves are counted by requireWriteAllowed, when they are sent.
+func (p *Plugin) requireWriteAccess(mattermostUserId string, jiraUser JIRAUser) error {
+	err := p.requireNotGuest(mattermostUserId)
+	if err != nil {
+		return err
+	}
+	return jiraUser.requireWriteScope()
+}
+
 type UserSettings struct {
 	Notifications bool `json:"notifications"`
 }
@@ -63,9 +133,6 @@ func httpUserConnect(ji Instance, w http.ResponseWriter, r *http.Request) (int,
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	// Users shouldn't be able to make multiple connections.
 	jiraUser, err := ji.GetPlugin().userStore.LoadJIRAUser(ji, mattermostUserId)
@@ -73,6 +140,19 @@ func httpUserConnect(ji Instance, w http.ResponseWriter, r *http.Request) (int,
 		return http.StatusBadRequest, errors.New("You already have a Jira account linked to your Mattermost account. Please use `/jira disconnect` to disconnect.")
 	}
 
+	scopes := userScopeRead + "," + userScopeWrite
+	switch r.URL.Query().Get("scope") {
+	case "", userScopeWrite:
+	case userScopeRead:
+		scopes = userScopeRead
+	default:
+		return http.StatusBadRequest, errors.New("scope must be read or write")
+	}
+	err = ji.GetPlugin().otsStore.StoreOneTimeSecret(keyConnectScopes+mattermostUserId, scopes)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	redirectURL, err := ji.GetUserConnectURL(mattermostUserId)
 	if err != nil {
 		return http.StatusInternalServerError, err
@@ -89,9 +169,6 @@ func httpAPIGetUserInfo(p *Plugin, w http.ResponseWriter, r *http.Request) (int,
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	resp := getUserInfo(p, mattermostUserId)
 
@@ -124,11 +201,6 @@ func httpAPIGetSettingsInfo(p *Plugin, w http.ResponseWriter, r *http.Request) (
 			errors.New("method " + r.Method + " is not allowed, must be GET")
 	}
 
-	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
-
 	resp := struct {
 		UIEnabled bool `json:"ui_enabled"`
 	}{
@@ -144,6 +216,19 @@ func httpAPIGetSettingsInfo(p *Plugin, w http.ResponseWriter, r *http.Request) (
 }
 
 func (p *Plugin) StoreUserInfoNotify(ji Instance, mattermostUserId string, jiraUser JIRAUser) error {
+	if jiraUser.Scopes == nil {
+		scopes, err := p.otsStore.LoadOneTimeSecret(keyConnectScopes + mattermostUserId)
+		if err != nil {
+			return err
+		}
+		if scopes == "" {
+			// The secret expired before the connection completed, or was
+			// never stored: only grant what every connection is granted.
+			scopes = userScopeRead
+		}
+		jiraUser.Scopes = strings.Split(scopes, ",")
+	}
+
 	err := p.userStore.StoreUserInfo(ji, mattermostUserId, jiraUser)
 	if err != nil {
 		return err
@@ -0,0 +1,112 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const sysAdminsPerPage = 100
+
+// handleUserLifecycleEvent keeps the stored Jira account of a connected
+// Mattermost user in sync with Jira, and notifies the system admins when a
+// connected account is deactivated or deleted. Events for Jira users that
+// are not connected to Mattermost are ignored.
+func (p *Plugin) handleUserLifecycleEvent(wh *webhook) error {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return err
+	}
+
+	updated := wh.JiraWebhook.User
+	mattermostUserId, err := p.userStore.LoadMattermostUserId(ji, JIRAUser{User: updated}.Key())
+	if err == ErrUserNotFound {
+		return ErrWebhookIgnored
+	}
+	if err != nil {
+		return err
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, mattermostUserId)
+	if err != nil {
+		return err
+	}
+
+	if wh.Events().ContainsAny(eventUserDeleted) {
+		err = p.userStore.DeleteUserInfo(ji, mattermostUserId)
+		if err != nil {
+			return err
+		}
+		p.notifySysAdminsOfJiraUser(mattermostUserId, jiraUser, msgJiraUserDeleted)
+		return nil
+	}
+
+	wasActive := jiraUser.Active
+	if updated.DisplayName != "" {
+		jiraUser.DisplayName = updated.DisplayName
+	}
+	if updated.EmailAddress != "" {
+		jiraUser.EmailAddress = updated.EmailAddress
+	}
+	// Some events omit the active field, which doesn't mean the account
+	// was deactivated.
+	if active, ok := payloadUserActive(wh.JiraWebhook.Event.Raw()); ok {
+		jiraUser.Active = active
+	}
+
+	err = p.userStore.StoreUserInfo(ji, mattermostUserId, jiraUser)
+	if err != nil {
+		return err
+	}
+
+	if wasActive && !jiraUser.Active {
+		p.notifySysAdminsOfJiraUser(mattermostUserId, jiraUser, msgJiraUserDeactivated)
+	}
+	return nil
+}
+
+// payloadUserActive returns the active field of the user of a webhook
+// payload, and whether the payload has one.
+func payloadUserActive(raw interface{}) (bool, bool) {
+	payload, _ := raw.(map[string]interface{})
+	user, _ := payload["user"].(map[string]interface{})
+	active, ok := user["active"].(bool)
+	return active, ok
+}
+
+func (p *Plugin) notifySysAdminsOfJiraUser(mattermostUserId string, jiraUser JIRAUser, id string) {
+	mattermostName := mattermostUserId
+	if user, appErr := p.API.GetUser(mattermostUserId); appErr == nil {
+		mattermostName = "@" + user.Username
+	}
+	p.notifySysAdmins(id, jiraUser.DisplayName, mattermostName)
+}
+
+// notifySysAdmins sends the localized message id to each system admin, in
+// the admin's locale.
+func (p *Plugin) notifySysAdmins(id string, args ...interface{}) {
+	for page := 0; ; page++ {
+		admins, appErr := p.API.GetUsers(&model.UserGetOptions{
+			Role:    model.SYSTEM_ADMIN_ROLE_ID,
+			Page:    page,
+			PerPage: sysAdminsPerPage,
+		})
+		if appErr != nil {
+			p.errorf("notifySysAdmins: failed to list system admins: %v", appErr)
+			return
+		}
+		for _, admin := range admins {
+			locale := admin.Locale
+			if locale == "" && p.isLocalized() {
+				locale = p.serverLocale()
+			}
+			_, err := p.CreateBotDMtoMMUserId(admin.Id, "%s", p.localize(locale, id, args...))
+			if err != nil {
+				p.debugf("notifySysAdmins: %v", err)
+			}
+		}
+		if len(admins) < sysAdminsPerPage {
+			return
+		}
+	}
+}
@@ -0,0 +1,483 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	keyUserMapping        = "user_mapping_"
+	keyUserMappingRequest = "user_mapping_request_"
+	keyJiraUserMapping    = "jira_user_mapping_"
+	keyUserMappingSends   = "user_mapping_sends_"
+
+	// How long a user has to enter the code sent by email, and an admin to
+	// approve a request.
+	userMappingCodeExpirySeconds     = 15 * 60
+	userMappingApprovalExpirySeconds = 7 * 24 * 60 * 60
+
+	userMappingCodeLength = 8
+
+	// A code is locked after this many wrong tries, and a new one must be
+	// asked for.
+	userMappingMaxCodeAttempts = 5
+
+	// A user may ask for this many codes or approvals, and an email address
+	// receive this many codes, within userMappingSendWindowSeconds.
+	userMappingMaxSends          = 3
+	userMappingSendWindowSeconds = 60 * 60
+
+	userMappingVerifiedByEmail = "email"
+)
+
+// UserMapping maps a Jira user to a Mattermost user, for the notifications of
+// the users who can't connect with OAuth. Unlike a connection, it gives no
+// access to Jira, so the notifications only link to the issues.
+type UserMapping struct {
+	MattermostUserId string `json:"mattermost_user_id"`
+
+	// JiraUser is the Jira username, account ID or email address.
+	JiraUser string `json:"jira_user"`
+
+	// VerifiedBy is userMappingVerifiedByEmail, or the ID of the admin who
+	// approved the mapping.
+	VerifiedBy string `json:"verified_by,omitempty"`
+}
+
+// userMappingRequest is a mapping waiting for the code sent by email, or for
+// the approval of an admin if Code is empty.
+type userMappingRequest struct {
+	UserMapping
+	Code string `json:"code,omitempty"`
+
+	// SentAt is when the code was sent, in seconds, and Attempts the number
+	// of wrong codes entered since.
+	SentAt   int64 `json:"sent_at,omitempty"`
+	Attempts int   `json:"attempts,omitempty"`
+}
+
+// userMappingSends counts the codes and approvals asked for by a user, or the
+// codes sent to an email address, within the window started at Since.
+type userMappingSends struct {
+	Since int64 `json:"since"`
+	Count int   `json:"count"`
+}
+
+func userMappingKey(ji Instance, jiraUser string) string {
+	return keyWithInstance(ji, keyJiraUserMapping+strings.ToLower(jiraUser))
+}
+
+func (p *Plugin) loadUserMapping(key string) (*UserMapping, error) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, ErrUserNotFound
+	}
+	mapping := &UserMapping{}
+	err := json.Unmarshal(data, mapping)
+	if err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// loadUserMappingOf returns the mapping of a Mattermost user.
+func (p *Plugin) loadUserMappingOf(ji Instance, mattermostUserId string) (*UserMapping, error) {
+	return p.loadUserMapping(keyWithInstance(ji, keyUserMapping+mattermostUserId))
+}
+
+// loadMappedUserId returns the Mattermost user mapped to one of the Jira
+// usernames, account IDs or email addresses.
+func (p *Plugin) loadMappedUserId(ji Instance, jiraUsers ...string) (string, error) {
+	for _, jiraUser := range jiraUsers {
+		if jiraUser == "" {
+			continue
+		}
+		mapping, err := p.loadUserMapping(userMappingKey(ji, jiraUser))
+		if err == nil {
+			return mapping.MattermostUserId, nil
+		}
+		if err != ErrUserNotFound {
+			return "", err
+		}
+	}
+	return "", ErrUserNotFound
+}
+
+func (p *Plugin) deleteUserMapping(ji Instance, mattermostUserId string) error {
+	mapping, err := p.loadUserMappingOf(ji, mattermostUserId)
+	if err != nil {
+		return err
+	}
+	err = p.releaseMappedJiraUser(userMappingKey(ji, mapping.JiraUser), mattermostUserId)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVDelete(keyWithInstance(ji, keyUserMapping+mattermostUserId))
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) storeUserMappingRequest(ji Instance, request userMappingRequest, expirySeconds int64) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	appErr := p.API.KVSetWithExpiry(keyWithInstance(ji, keyUserMappingRequest+request.MattermostUserId), data, expirySeconds)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func (p *Plugin) loadUserMappingRequest(ji Instance, mattermostUserId string) (*userMappingRequest, error) {
+	data, appErr := p.API.KVGet(keyWithInstance(ji, keyUserMappingRequest+mattermostUserId))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return nil, ErrUserNotFound
+	}
+	request := &userMappingRequest{}
+	err := json.Unmarshal(data, request)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// allowUserMappingSend counts a code or an approval asked for, and returns
+// false if userMappingMaxSends were already counted on the key within
+// userMappingSendWindowSeconds. The counts are in the KV store, so that they
+// apply across the servers of a cluster.
+func (p *Plugin) allowUserMappingSend(key string, now time.Time) (bool, error) {
+	allowed := false
+	err := p.atomicModifyWithExpiry(key, userMappingSendWindowSeconds, func(initial []byte) ([]byte, error) {
+		sends := userMappingSends{}
+		if len(initial) > 0 {
+			err := json.Unmarshal(initial, &sends)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if now.Unix()-sends.Since >= userMappingSendWindowSeconds {
+			sends = userMappingSends{Since: now.Unix()}
+		}
+		allowed = sends.Count < userMappingMaxSends
+		if allowed {
+			sends.Count++
+		}
+		return json.Marshal(sends)
+	})
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// newUserMappingCode returns a random code of digits, easy to type.
+func newUserMappingCode() (string, error) {
+	code := ""
+	for i := 0; i < userMappingCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code += n.String()
+	}
+	return code, nil
+}
+
+// executeConnectAs maps the user to a Jira user without OAuth, right away if
+// it is the verified email address of the user, after checking a code sent to
+// it if it is another email address, or once approved by an admin.
+func executeConnectAs(p *Plugin, header *model.CommandArgs, jiraUser string) *model.CommandResponse {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return p.responseT(header, msgNoInstance)
+	}
+	if connected, err := p.userStore.LoadJIRAUser(ji, header.UserId); err == nil && len(connected.Key()) != 0 {
+		return p.responseT(header, msgAlreadyConnected)
+	}
+	user, appErr := p.API.GetUser(header.UserId)
+	if appErr != nil {
+		return p.responsef(header, "%v", appErr)
+	}
+	if mappedUserId, err := p.loadMappedUserId(ji, jiraUser); err == nil && mappedUserId != header.UserId {
+		return p.responsef(header, "Jira user %s is already mapped to another Mattermost user.", jiraUser)
+	}
+
+	now := time.Now()
+	request := userMappingRequest{UserMapping: UserMapping{MattermostUserId: header.UserId, JiraUser: jiraUser}}
+	isEmail := strings.Contains(jiraUser, "@")
+	if isEmail && user.EmailVerified && strings.EqualFold(user.Email, jiraUser) {
+		request.VerifiedBy = userMappingVerifiedByEmail
+		err = p.mapUser(ji, request.UserMapping)
+		if err != nil {
+			return p.responsef(header, "Failed to map your account to Jira user %s: %v", jiraUser, err)
+		}
+		return p.responsef(header, "Mapped your account to Jira user %s, the verified email address of your account. You will get a direct message when you are mentioned or assigned in Jira.", jiraUser)
+	}
+
+	allowed, err := p.allowUserMappingSend(keyWithInstance(ji, keyUserMappingSends+header.UserId), now)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !allowed {
+		return p.responsef(header, "You asked to map your account too many times. Please try again in an hour.")
+	}
+
+	if isEmail {
+		allowed, err = p.allowUserMappingSend(hashkey(keyUserMappingSends, strings.ToLower(jiraUser)), now)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if !allowed {
+			return p.responsef(header, "Too many codes were sent to %s. Please try again in an hour.", jiraUser)
+		}
+		request.Code, err = newUserMappingCode()
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		request.SentAt = now.Unix()
+		err = p.storeUserMappingRequest(ji, request, userMappingCodeExpirySeconds)
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		appErr = p.API.SendMail(jiraUser, "Your Mattermost code for Jira",
+			fmt.Sprintf("<p>To receive the Jira notifications of %s in Mattermost as @%s, run:</p><p><code>/jira connect --verify %s</code></p><p>The code expires in %d minutes. If you did not ask for it, ignore this email.</p>",
+				html.EscapeString(jiraUser), html.EscapeString(user.Username), request.Code, userMappingCodeExpirySeconds/60))
+		if appErr != nil {
+			p.errorf("executeConnectAs: failed to send the code to %s: %v", jiraUser, appErr)
+			return p.responsef(header, "Failed to send a code to %s. Please ask a system administrator to check the email settings, or use your Jira username.", jiraUser)
+		}
+		return p.responsef(header, "Sent a code to %s. Run `/jira connect --verify <code>` within %d minutes to map your account to this Jira user.", jiraUser, userMappingCodeExpirySeconds/60)
+	}
+
+	err = p.storeUserMappingRequest(ji, request, userMappingApprovalExpirySeconds)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	conf := p.getConfig()
+	if conf.AdminAlertsChannelId == "" {
+		return p.responsef(header, "Please ask a system administrator to run `/jira connect approve @%s` to map your account to Jira user %s.", user.Username, jiraUser)
+	}
+	_, appErr = p.API.CreatePost(&model.Post{
+		ChannelId: conf.AdminAlertsChannelId,
+		UserId:    p.getUserID(),
+		Message: p.localize(p.channelLocale(conf.AdminAlertsChannelId), msgMappingRequested,
+			user.Username, jiraUser, user.Username),
+	})
+	if appErr != nil {
+		p.errorf("executeConnectAs: failed to post the request of %s to channel %s: %v", user.Username, conf.AdminAlertsChannelId, appErr)
+	}
+	return p.responsef(header, "Asked the system administrators to map your account to Jira user %s. You will get a direct message once they approve it.", jiraUser)
+}
+
+// executeConnectVerify maps the user once they enter the code sent by email.
+func executeConnectVerify(p *Plugin, header *model.CommandArgs, code string) *model.CommandResponse {
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return p.responseT(header, msgNoInstance)
+	}
+	request, err := p.loadUserMappingRequest(ji, header.UserId)
+	if err != nil || request.Code == "" {
+		return p.responsef(header, "There is no code to verify, or it expired. Run `/jira connect --as <email>` again.")
+	}
+	if subtle.ConstantTimeCompare([]byte(code), []byte(request.Code)) != 1 {
+		left, err := p.countUserMappingCodeAttempt(ji, request, time.Now())
+		if err != nil {
+			return p.responsef(header, "%v", err)
+		}
+		if left <= 0 {
+			return p.responsef(header, "The code does not match the one sent to %s, and was entered wrong too many times. Run `/jira connect --as <email>` again for a new code.", request.JiraUser)
+		}
+		return p.responsef(header, "The code does not match the one sent to %s. %d tries left.", request.JiraUser, left)
+	}
+
+	request.VerifiedBy = userMappingVerifiedByEmail
+	err = p.mapUser(ji, request.UserMapping)
+	if err != nil {
+		return p.responsef(header, "Failed to map your account to Jira user %s: %v", request.JiraUser, err)
+	}
+	return p.responsef(header, "Mapped your account to Jira user %s. You will get a direct message when you are mentioned or assigned in Jira.", request.JiraUser)
+}
+
+// countUserMappingCodeAttempt counts a wrong code entered for the request,
+// and returns how many tries are left. The code is deleted once none are
+// left.
+func (p *Plugin) countUserMappingCodeAttempt(ji Instance, request *userMappingRequest, now time.Time) (int, error) {
+	key := keyWithInstance(ji, keyUserMappingRequest+request.MattermostUserId)
+	// Keep the expiry of the code sent
+	expirySeconds := request.SentAt + userMappingCodeExpirySeconds - now.Unix()
+	if expirySeconds <= 0 {
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			return 0, appErr
+		}
+		return 0, nil
+	}
+
+	left := 0
+	err := p.atomicModifyWithExpiry(key, expirySeconds, func(initial []byte) ([]byte, error) {
+		left = 0
+		if len(initial) == 0 {
+			return nil, nil
+		}
+		current := &userMappingRequest{}
+		err := json.Unmarshal(initial, current)
+		if err != nil {
+			return nil, err
+		}
+		if current.Code != request.Code {
+			// A new code was sent since
+			left = userMappingMaxCodeAttempts - current.Attempts
+			return initial, nil
+		}
+		current.Attempts++
+		left = userMappingMaxCodeAttempts - current.Attempts
+		if left <= 0 {
+			return nil, nil
+		}
+		return json.Marshal(current)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return left, nil
+}
+
+func executeConnectApprove(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira connect approve` can only be run by a system administrator.")
+	}
+	if len(args) != 1 {
+		return p.responsef(header, "Please use `/jira connect approve @user`.")
+	}
+	username := strings.TrimPrefix(args[0], "@")
+	user, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return p.responsef(header, "User @%s was not found.", username)
+	}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return p.responseT(header, msgNoInstance)
+	}
+	request, err := p.loadUserMappingRequest(ji, user.Id)
+	if err != nil || request.Code != "" {
+		return p.responsef(header, "@%s has no request to approve, or it expired.", username)
+	}
+
+	request.VerifiedBy = header.UserId
+	err = p.mapUser(ji, request.UserMapping)
+	if err != nil {
+		return p.responsef(header, "Failed to map @%s to Jira user %s: %v", username, request.JiraUser, err)
+	}
+	_, err = p.CreateBotDMtoMMUserId(user.Id, "%s", p.localize(p.userLocale(user.Id), msgMappingApproved, request.JiraUser))
+	if err != nil {
+		p.errorf("executeConnectApprove: %v", err)
+	}
+	return p.responsef(header, "Mapped @%s to Jira user %s.", username, request.JiraUser)
+}
+
+// mapUser stores a verified mapping, replacing the previous one of the user.
+// The Jira user is claimed atomically, so that two Mattermost users can't be
+// mapped to it at once.
+func (p *Plugin) mapUser(ji Instance, mapping UserMapping) error {
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	previous, err := p.loadUserMappingOf(ji, mapping.MattermostUserId)
+	if err != nil && err != ErrUserNotFound {
+		return err
+	}
+
+	key := userMappingKey(ji, mapping.JiraUser)
+	err = p.atomicModify(key, func(initial []byte) ([]byte, error) {
+		if len(initial) > 0 {
+			current := &UserMapping{}
+			err := json.Unmarshal(initial, current)
+			if err != nil {
+				return nil, err
+			}
+			if current.MattermostUserId != mapping.MattermostUserId {
+				return nil, errors.Errorf("Jira user %s is already mapped to another Mattermost user", mapping.JiraUser)
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if previous != nil {
+		if previousKey := userMappingKey(ji, previous.JiraUser); previousKey != key {
+			err = p.releaseMappedJiraUser(previousKey, mapping.MattermostUserId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	appErr := p.API.KVSet(keyWithInstance(ji, keyUserMapping+mapping.MattermostUserId), data)
+	if appErr != nil {
+		return appErr
+	}
+	appErr = p.API.KVDelete(keyWithInstance(ji, keyUserMappingRequest+mapping.MattermostUserId))
+	if appErr != nil {
+		p.errorf("mapUser: failed to delete the request of %s: %v", mapping.MattermostUserId, appErr)
+	}
+	return nil
+}
+
+// releaseMappedJiraUser deletes the mapping of a Jira user, unless another
+// Mattermost user was mapped to it since.
+func (p *Plugin) releaseMappedJiraUser(key, mattermostUserId string) error {
+	return p.atomicModify(key, func(initial []byte) ([]byte, error) {
+		if len(initial) == 0 {
+			return nil, nil
+		}
+		current := &UserMapping{}
+		err := json.Unmarshal(initial, current)
+		if err != nil {
+			return nil, err
+		}
+		if current.MattermostUserId != mattermostUserId {
+			return initial, nil
+		}
+		return nil, nil
+	})
+}
+
+// postMappedUserNotification tells a mapped user about a Jira event. Since the
+// plugin can't check what the user may view in Jira, the post only links to
+// the issue.
+func (p *Plugin) postMappedUserNotification(wh *webhook, mattermostUserId string) (*model.Post, error) {
+	link := wh.JiraWebhook.mdJiraLink(wh.Issue.Key, "/browse/"+wh.Issue.Key)
+	if link == "" {
+		link = wh.Issue.Key
+	}
+	return p.CreateBotDMtoMMUserId(mattermostUserId, "%s", p.localize(p.userLocale(mattermostUserId), msgMappedUserActivity, link))
+}
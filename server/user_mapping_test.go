@@ -0,0 +1,177 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserMapping(t *testing.T) {
+	api := &plugintest.API{}
+	p := Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{&p}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+
+	kv := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte { return kv[key] },
+		func(key string) *model.AppError { return nil })
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	})
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(
+		func(key string, oldValue, newValue []byte) bool {
+			if !bytes.Equal(kv[key], oldValue) {
+				return false
+			}
+			if newValue == nil {
+				delete(kv, key)
+			} else {
+				kv[key] = newValue
+			}
+			return true
+		}, nil)
+
+	_, err = p.loadMappedUserId(ji, "jdoe@example.com")
+	assert.Equal(t, ErrUserNotFound, err)
+
+	mapping := UserMapping{MattermostUserId: "userId", JiraUser: "JDoe@example.com", VerifiedBy: userMappingVerifiedByEmail}
+	require.NoError(t, p.mapUser(ji, mapping))
+
+	mappedUserId, err := p.loadMappedUserId(ji, "", "jdoe", "jdoe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "userId", mappedUserId)
+	stored, err := p.loadUserMappingOf(ji, "userId")
+	require.NoError(t, err)
+	assert.Equal(t, &mapping, stored)
+
+	t.Run("already mapped", func(t *testing.T) {
+		err := p.mapUser(ji, UserMapping{MattermostUserId: "otherUserId", JiraUser: "jdoe@example.com"})
+		assert.Error(t, err)
+	})
+
+	t.Run("remapped", func(t *testing.T) {
+		remapped := UserMapping{MattermostUserId: "userId", JiraUser: "jdoe", VerifiedBy: "adminId"}
+		require.NoError(t, p.mapUser(ji, remapped))
+		_, err := p.loadMappedUserId(ji, "jdoe@example.com")
+		assert.Equal(t, ErrUserNotFound, err)
+		mappedUserId, err := p.loadMappedUserId(ji, "JDOE")
+		require.NoError(t, err)
+		assert.Equal(t, "userId", mappedUserId)
+	})
+
+	require.NoError(t, p.deleteUserMapping(ji, "userId"))
+	_, err = p.loadMappedUserId(ji, "jdoe")
+	assert.Equal(t, ErrUserNotFound, err)
+	_, err = p.loadUserMappingOf(ji, "userId")
+	assert.Equal(t, ErrUserNotFound, err)
+	assert.Empty(t, kv)
+}
+
+func TestNewUserMappingCode(t *testing.T) {
+	code, err := newUserMappingCode()
+	require.NoError(t, err)
+	assert.Len(t, code, userMappingCodeLength)
+	assert.Regexp(t, "^[0-9]+$", code)
+}
+
+func newUserMappingTestPlugin() (*Plugin, map[string][]byte) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+
+	kv := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte { return kv[key] },
+		func(key string) *model.AppError { return nil })
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	})
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if !bytes.Equal(kv[key], options.OldValue) {
+				return false
+			}
+			if value == nil {
+				delete(kv, key)
+			} else {
+				kv[key] = value
+			}
+			return true
+		}, nil)
+	return p, kv
+}
+
+func TestAllowUserMappingSend(t *testing.T) {
+	p, _ := newUserMappingTestPlugin()
+	now := time.Now()
+	for i := 0; i < userMappingMaxSends; i++ {
+		allowed, err := p.allowUserMappingSend("sends", now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, err := p.allowUserMappingSend("sends", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = p.allowUserMappingSend("other", now)
+	require.NoError(t, err)
+	assert.True(t, allowed, "the sends are counted by key")
+
+	allowed, err = p.allowUserMappingSend("sends", now.Add(userMappingSendWindowSeconds*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed, "the window is over")
+}
+
+func TestCountUserMappingCodeAttempt(t *testing.T) {
+	p, kv := newUserMappingTestPlugin()
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+	now := time.Now()
+	request := userMappingRequest{
+		UserMapping: UserMapping{MattermostUserId: "userId", JiraUser: "jdoe@example.com"},
+		Code:        "12345678",
+		SentAt:      now.Unix(),
+	}
+	require.NoError(t, p.storeUserMappingRequest(ji, request, userMappingCodeExpirySeconds))
+
+	for i := 1; i < userMappingMaxCodeAttempts; i++ {
+		left, err := p.countUserMappingCodeAttempt(ji, &request, now)
+		require.NoError(t, err)
+		assert.Equal(t, userMappingMaxCodeAttempts-i, left)
+	}
+	stored, err := p.loadUserMappingRequest(ji, "userId")
+	require.NoError(t, err)
+	assert.Equal(t, userMappingMaxCodeAttempts-1, stored.Attempts)
+
+	left, err := p.countUserMappingCodeAttempt(ji, &request, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, left)
+	_, err = p.loadUserMappingRequest(ji, "userId")
+	assert.Equal(t, ErrUserNotFound, err, "the code is locked")
+
+	t.Run("expired", func(t *testing.T) {
+		require.NoError(t, p.storeUserMappingRequest(ji, request, userMappingCodeExpirySeconds))
+		left, err := p.countUserMappingCodeAttempt(ji, &request, now.Add(userMappingCodeExpirySeconds*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, 0, left)
+		assert.Empty(t, kv)
+	})
+}
@@ -48,9 +48,6 @@ func httpOAuth1aComplete(jsi *jiraServerInstance, w http.ResponseWriter, r *http
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-ID")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 	mmuser, appErr := jsi.Plugin.API.GetUser(mattermostUserId)
 	if appErr != nil {
 		return http.StatusInternalServerError,
@@ -123,9 +120,6 @@ func httpOAuth1aDisconnect(ji *jiraServerInstance, w http.ResponseWriter, r *htt
 	}
 
 	mattermostUserId := r.Header.Get("Mattermost-User-Id")
-	if mattermostUserId == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	err := ji.GetPlugin().userDisconnect(ji, mattermostUserId)
 	if err != nil {
@@ -149,9 +143,6 @@ func httpOAuth1aPublicKey(p *Plugin, w http.ResponseWriter, r *http.Request) (in
 	}
 
 	userID := r.Header.Get("Mattermost-User-Id")
-	if userID == "" {
-		return http.StatusUnauthorized, errors.New("not authorized")
-	}
 
 	if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
 		return http.StatusForbidden, errors.New("forbidden")
@@ -1,8 +1,16 @@
 package main
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
 	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserSettings_String(t *testing.T) {
@@ -25,3 +33,171 @@ func TestUserSettings_String(t *testing.T) {
 		})
 	}
 }
+
+type recordingUserStore struct {
+	mockUserStore
+	jiraUser JIRAUser
+	stored   *JIRAUser
+	deleted  bool
+}
+
+func (store *recordingUserStore) LoadJIRAUser(ji Instance, mattermostUserId string) (JIRAUser, error) {
+	return store.jiraUser, nil
+}
+func (store *recordingUserStore) StoreUserInfo(ji Instance, mattermostUserId string, jiraUser JIRAUser) error {
+	store.stored = &jiraUser
+	return nil
+}
+func (store *recordingUserStore) DeleteUserInfo(ji Instance, mattermostUserId string) error {
+	store.deleted = true
+	return nil
+}
+
+func TestHandleUserLifecycleEvent(t *testing.T) {
+	for name, tc := range map[string]struct {
+		webhookEvent   string
+		wasActive      bool
+		omitActive     bool
+		expectNotified bool
+		expectDeleted  bool
+		expectActive   bool
+	}{
+		"updated":        {webhookEvent: "user_updated", wasActive: false},
+		"deactivated":    {webhookEvent: "user_updated", wasActive: true, expectNotified: true},
+		"active omitted": {webhookEvent: "user_updated", wasActive: true, omitActive: true, expectActive: true},
+		"deleted":        {webhookEvent: "user_deleted", wasActive: true, expectNotified: true, expectDeleted: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{p}
+			store := &recordingUserStore{
+				jiraUser: JIRAUser{User: jira.User{AccountID: "5c5f880629be9642ce1e0cd6", DisplayName: "Old Name", Active: tc.wasActive}},
+			}
+			p.userStore = store
+
+			notified := false
+			api.On("GetUser", "testMattermostUserId012345").Return(&model.User{Username: "someuser"}, nil)
+			api.On("GetUsers", mock.AnythingOfType("*model.UserGetOptions")).Return([]*model.User{{Id: "adminId"}}, nil)
+			api.On("GetDirectChannel", "adminId", mock.Anything).Return(&model.Channel{Id: "dmChannelId"}, nil)
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				notified = true
+			}).Return(&model.Post{}, nil)
+
+			data, err := getJiraTestData("webhook-user-updated.json")
+			require.NoError(t, err)
+			if tc.omitActive {
+				data = bytes.Replace(data, []byte(`"active": false,`), nil, 1)
+			}
+			wh, err := ParseWebhook(data)
+			require.NoError(t, err)
+			wh.(*webhook).JiraWebhook.WebhookEvent = tc.webhookEvent
+			if tc.webhookEvent == "user_deleted" {
+				wh.(*webhook).eventTypes = NewStringSet(eventUserDeleted)
+			}
+
+			err = p.handleUserLifecycleEvent(wh.(*webhook))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectNotified, notified)
+			assert.Equal(t, tc.expectDeleted, store.deleted)
+			if !tc.expectDeleted {
+				require.NotNil(t, store.stored)
+				assert.Equal(t, "Test User", store.stored.DisplayName)
+				assert.Equal(t, tc.expectActive, store.stored.Active)
+			}
+		})
+	}
+}
+
+func TestJIRAUserHasScope(t *testing.T) {
+	legacy := JIRAUser{}
+	assert.True(t, legacy.HasScope(userScopeWrite))
+	assert.NoError(t, legacy.requireWriteScope())
+
+	readOnly := JIRAUser{Scopes: []string{userScopeRead}}
+	assert.True(t, readOnly.HasScope(userScopeRead))
+	assert.False(t, readOnly.HasScope(userScopeWrite))
+	assert.Equal(t, ErrReadOnlyConnection, readOnly.requireWriteScope())
+
+	readWrite := JIRAUser{Scopes: []string{userScopeRead, userScopeWrite}}
+	assert.True(t, readWrite.HasScope(userScopeWrite))
+}
+
+func TestRequireWriteAccess(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", "guest").Return(&model.User{Id: "guest", Roles: model.SYSTEM_GUEST_ROLE_ID}, nil)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: model.SYSTEM_USER_ROLE_ID}, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	assert.NoError(t, p.requireWriteAccess("user", JIRAUser{}))
+	assert.Equal(t, ErrReadOnlyConnection, p.requireWriteAccess("user", JIRAUser{Scopes: []string{userScopeRead}}))
+	assert.Equal(t, ErrGuestAccess, p.requireWriteAccess("guest", JIRAUser{}))
+	assert.Equal(t, ErrGuestAccess, p.hasPermissionToManageSubscription("guest", "channelId"))
+
+	p.updateConfig(func(conf *config) {
+		conf.AllowGuestsToEditJira = true
+	})
+	assert.NoError(t, p.requireWriteAccess("guest", JIRAUser{}))
+}
+
+func TestStoreUserInfoNotifyScopes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		secret         string
+		expectedScopes []string
+	}{
+		"write access":   {userScopeRead + "," + userScopeWrite, []string{userScopeRead, userScopeWrite}},
+		"read-only":      {userScopeRead, []string{userScopeRead}},
+		"secret expired": {"", []string{userScopeRead}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("KVGet", mock.AnythingOfType("string")).Return([]byte(tc.secret), nil)
+			api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+			api.On("PublishWebSocketEvent", WS_EVENT_CONNECT, mock.Anything, mock.Anything).Return()
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.otsStore = NewStore(p)
+			userStore := &recordingUserStore{}
+			p.userStore = userStore
+			ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+
+			err := p.StoreUserInfoNotify(ji, "userid", JIRAUser{User: jira.User{AccountID: "accountId"}})
+			require.NoError(t, err)
+			require.NotNil(t, userStore.stored)
+			assert.Equal(t, tc.expectedScopes, userStore.stored.Scopes)
+		})
+	}
+}
+
+func TestWithWriteScope(t *testing.T) {
+	for name, tc := range map[string]struct {
+		scopes         []string
+		expectedCalled bool
+	}{
+		"legacy connection": {nil, true},
+		"write access":      {[]string{userScopeRead, userScopeWrite}, true},
+		"read-only":         {[]string{userScopeRead}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.MatchedBy(func(post *model.Post) bool {
+				return assert.Contains(t, post.Message, "connect with write access to use `/jira transition`")
+			})).Return(&model.Post{})
+
+			p := Plugin{}
+			p.SetAPI(api)
+			p.currentInstanceStore = mockCurrentInstanceStore{&p}
+			p.userStore = &recordingUserStore{jiraUser: JIRAUser{User: jira.User{AccountID: "accountId"}, Scopes: tc.scopes}}
+
+			called := false
+			h := withWriteScope("transition", func(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+				called = true
+				return &model.CommandResponse{}
+			})
+			h(&p, &plugin.Context{}, &model.CommandArgs{UserId: "userId", ChannelId: "channelId"}, "TEST-1", "done")
+			assert.Equal(t, tc.expectedCalled, called)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SelectJSON evaluates a JSONPath-style selector on a document unmarshaled
+// into interface{} values, and returns the selected values. Selectors start
+// with "$", followed by ".name" object members, "[n]" array elements, or "[*]"
+// or ".*" for all elements, e.g. "$.issue.fields.customfield_10010[*].value".
+// Missing members select nothing.
+func SelectJSON(doc interface{}, selector string) ([]interface{}, error) {
+	steps, err := parseJSONSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range current {
+			switch typed := v.(type) {
+			case map[string]interface{}:
+				if step == "*" {
+					for _, member := range typed {
+						next = append(next, member)
+					}
+				} else if member, ok := typed[step]; ok {
+					next = append(next, member)
+				}
+			case []interface{}:
+				if step == "*" {
+					next = append(next, typed...)
+				} else if i, convErr := strconv.Atoi(step); convErr == nil && i >= 0 && i < len(typed) {
+					next = append(next, typed[i])
+				}
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// SelectJSONStrings returns the scalar values selected by SelectJSON,
+// formatted as strings. Objects, arrays and nulls are skipped.
+func SelectJSONStrings(doc interface{}, selector string) ([]string, error) {
+	values, err := SelectJSON(doc, selector)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, v := range values {
+		switch typed := v.(type) {
+		case string:
+			out = append(out, typed)
+		case float64:
+			out = append(out, strconv.FormatFloat(typed, 'f', -1, 64))
+		case bool:
+			out = append(out, strconv.FormatBool(typed))
+		}
+	}
+	return out, nil
+}
+
+func parseJSONSelector(selector string) ([]string, error) {
+	if !strings.HasPrefix(selector, "$") {
+		return nil, errors.Errorf("invalid selector %q: must start with $", selector)
+	}
+	s := selector[1:]
+	var steps []string
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, errors.Errorf("invalid selector %q: empty member name", selector)
+			}
+			steps = append(steps, s[:end])
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid selector %q: missing ]", selector)
+			}
+			index := strings.Trim(s[1:end], `'"`)
+			if index == "" {
+				return nil, errors.Errorf("invalid selector %q: empty index", selector)
+			}
+			steps = append(steps, index)
+			s = s[end+1:]
+		default:
+			return nil, errors.Errorf("invalid selector %q", selector)
+		}
+	}
+	return steps, nil
+}
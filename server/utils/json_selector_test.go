@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectJSONStrings(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"issue": {
+			"key": "TES-1",
+			"fields": {
+				"customfield_10010": [{"value": "red"}, {"value": "blue"}],
+				"customfield_10011": 3.5,
+				"flagged": true,
+				"empty": null
+			}
+		}
+	}`), &doc)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		selector string
+		expected []string
+		err      bool
+	}{
+		"member":          {selector: "$.issue.key", expected: []string{"TES-1"}},
+		"index":           {selector: "$.issue.fields.customfield_10010[1].value", expected: []string{"blue"}},
+		"wildcard":        {selector: "$.issue.fields.customfield_10010[*].value", expected: []string{"red", "blue"}},
+		"quoted member":   {selector: "$.issue['fields'].customfield_10011", expected: []string{"3.5"}},
+		"bool":            {selector: "$.issue.fields.flagged", expected: []string{"true"}},
+		"null":            {selector: "$.issue.fields.empty"},
+		"object skipped":  {selector: "$.issue.fields"},
+		"missing":         {selector: "$.issue.nothing.here"},
+		"out of range":    {selector: "$.issue.fields.customfield_10010[5].value"},
+		"root":            {selector: "$"},
+		"no root":         {selector: "issue.key", err: true},
+		"empty member":    {selector: "$.issue..key", err: true},
+		"unterminated":    {selector: "$.issue[0", err: true},
+		"garbage after $": {selector: "$issue", err: true},
+		"empty index":     {selector: "$.issue[]", err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			values, err := SelectJSONStrings(doc, tc.selector)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+}
@@ -63,3 +63,35 @@ func IsJiraCloudURL(jiraURL string) (bool, error) {
 	}
 	return strings.HasSuffix(u.Hostname(), ".atlassian.net"), nil
 }
+
+// ParseKeyValueList parses a comma-separated list of key=value pairs, such as
+// "white_check_mark=Done, eyes=In Review", into a map. Whitespace around keys
+// and values is trimmed, and entries without a key or a value are skipped.
+func ParseKeyValueList(s string) map[string]string {
+	result := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if k == "" || v == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// ParseList parses a comma-separated list, such as "Automation for Jira,
+// svc-sync", trimming whitespace around the entries and skipping empty ones.
+func ParseList(s string) []string {
+	result := []string{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
@@ -148,3 +148,35 @@ func TestIsJiraCloudURL(t *testing.T) {
 	require.Nil(t, err)
 	assert.False(t, serverLinkIsCloud)
 }
+
+func TestParseKeyValueList(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		out map[string]string
+	}{
+		{"", map[string]string{}},
+		{"eyes=In Review", map[string]string{"eyes": "In Review"}},
+		{" white_check_mark = Done ,eyes=In Review", map[string]string{"white_check_mark": "Done", "eyes": "In Review"}},
+		{"a=b=c", map[string]string{"a": "b=c"}},
+		{"novalue=, =nokey, junk", map[string]string{}},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.out, ParseKeyValueList(tc.in))
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		out []string
+	}{
+		{"", []string{}},
+		{"svc-sync", []string{"svc-sync"}},
+		{" Automation for Jira , svc-sync,, ", []string{"Automation for Jira", "svc-sync"}},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.out, ParseList(tc.in))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	jira "github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const warRoomChannelPrefix = "jira-"
+
+func warRoomChannelName(issueKey string) string {
+	return warRoomChannelPrefix + strings.ToLower(issueKey)
+}
+
+// executeWarRoom creates a channel dedicated to a single issue, subscribes it
+// to all of the issue's events, and seeds it with the current issue details.
+func executeWarRoom(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira war-room <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+
+	err := p.hasPermissionToManageSubscription(header.UserId, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to create Jira subscriptions: %v", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeWarRoom: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	issue, err := client.GetIssue(issueKey, nil)
+	if err != nil {
+		return p.responsef(header, "Failed to get issue %s: %v", issueKey, err)
+	}
+
+	name := warRoomChannelName(issueKey)
+	if existing, _ := p.API.GetChannelByName(header.TeamId, name, false); existing != nil {
+		return p.responsef(header, "Channel ~%s already exists for %s.", name, issueKey)
+	}
+
+	displayName := fmt.Sprintf("%s %s", issueKey, issue.Fields.Summary)
+	if utf8.RuneCountInString(displayName) > model.CHANNEL_DISPLAY_NAME_MAX_RUNES {
+		displayName = string([]rune(displayName)[:model.CHANNEL_DISPLAY_NAME_MAX_RUNES])
+	}
+	channel, appErr := p.API.CreateChannel(&model.Channel{
+		TeamId:      header.TeamId,
+		Type:        model.CHANNEL_OPEN,
+		Name:        name,
+		DisplayName: displayName,
+		Purpose:     fmt.Sprintf("Discussion of %s", p.issueDeepLink(issueKey)),
+		CreatorId:   header.UserId,
+	})
+	if appErr != nil {
+		return p.responsef(header, "Failed to create channel ~%s: %v", name, appErr)
+	}
+	_, appErr = p.API.AddChannelMember(channel.Id, header.UserId)
+	if appErr != nil {
+		p.errorf("executeWarRoom: failed to add user %s to channel %s: %v", header.UserId, channel.Id, appErr)
+	}
+
+	err = p.addChannelSubscription(&ChannelSubscription{
+		ChannelId: channel.Id,
+		Name:      issueKey,
+		IssueKey:  issueKey,
+		CreatorId: header.UserId,
+	}, client)
+	if err != nil {
+		return p.responsef(header, "Created channel ~%s, but failed to subscribe it to %s: %v", name, issueKey, err)
+	}
+
+	p.postWarRoomIssue(channel.Id, issue)
+
+	return p.responsef(header, "Created channel ~%s for %s.", name, issueKey)
+}
+
+// executeWarRoomArchive removes the issue subscription of a dedicated channel
+// and archives the channel.
+func executeWarRoomArchive(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira war-room archive <issue-key>`.")
+	}
+	issueKey := strings.ToUpper(args[0])
+	name := warRoomChannelName(issueKey)
+
+	channel, appErr := p.API.GetChannelByName(header.TeamId, name, false)
+	if appErr != nil {
+		return p.responsef(header, "Could not find channel ~%s for %s.", name, issueKey)
+	}
+
+	err := p.hasPermissionToManageSubscription(header.UserId, channel.Id)
+	if err != nil {
+		return p.responsef(header, "You are not allowed to remove Jira subscriptions: %v", err)
+	}
+
+	subs, err := p.getSubscriptionsForChannel(channel.Id)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	for _, sub := range subs {
+		if sub.IssueKey != issueKey {
+			continue
+		}
+		err = p.removeChannelSubscription(sub.Id)
+		if err != nil {
+			return p.responsef(header, "Failed to remove subscription %q: %v", sub.Name, err)
+		}
+	}
+
+	appErr = p.API.DeleteChannel(channel.Id)
+	if appErr != nil {
+		return p.responsef(header, "Failed to archive channel ~%s: %v", name, appErr)
+	}
+
+	return p.responsef(header, "Archived channel ~%s for %s.", name, issueKey)
+}
+
+func (p *Plugin) postWarRoomIssue(channelId string, issue *jira.Issue) {
+	conf := p.getConfig()
+	locale := p.channelLocale(channelId)
+	attachments := parseIssue(issue, conf.maxTextLength, conf.webhookParseOptions.issueEmoji, p.jiraAvatarURLFunc(), p.localizer(locale))
+	attachments[0].Pretext = p.localize(locale, msgWarRoomSubscribed, issue.Key)
+	attachments[0].Fallback = attachments[0].Pretext
+	p.addIssueAppLink(attachments[0], issue.Key, channelId)
+
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelId,
+	}
+	post.AddProp(postPropIssueKey, issue.Key)
+	model.ParseSlackAttachment(post, attachments)
+
+	created, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		p.errorf("postWarRoomIssue: failed to post %s to channel %s: %v", issue.Key, channelId, appErr)
+		return
+	}
+	p.rememberIssuePosts(issue, []*model.Post{created})
+}
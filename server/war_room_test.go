@@ -0,0 +1,156 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarRoomChannelName(t *testing.T) {
+	assert.Equal(t, "jira-real-1", warRoomChannelName("REAL-1"))
+	assert.Equal(t, "jira-real-1", warRoomChannelName("real-1"))
+}
+
+type warRoomTest struct {
+	p        *Plugin
+	api      *plugintest.API
+	kv       map[string][]byte
+	channels map[string]*model.Channel
+	posts    []*model.Post
+	messages []string
+	deleted  []string
+}
+
+func newWarRoomTest() *warRoomTest {
+	wt := &warRoomTest{
+		kv:       map[string][]byte{},
+		channels: map[string]*model.Channel{},
+	}
+	api := &plugintest.API{}
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{Roles: model.SYSTEM_USER_ROLE_ID}, nil)
+	api.On("GetConfig").Return(&model.Config{})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return wt.kv[key] }, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		wt.kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		wt.kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		wt.kv[args.String(0)] = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Run(func(args mock.Arguments) {
+		wt.kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(true, nil)
+	api.On("GetChannelByName", "team1", mock.AnythingOfType("string"), false).Return(
+		func(teamId, name string, includeDeleted bool) *model.Channel { return wt.channels[name] },
+		func(teamId, name string, includeDeleted bool) *model.AppError {
+			if wt.channels[name] == nil {
+				return model.NewAppError("GetChannelByName", "not found", nil, "", 404)
+			}
+			return nil
+		})
+	api.On("GetChannel", mock.AnythingOfType("string")).Return(
+		func(channelId string) *model.Channel {
+			for _, channel := range wt.channels {
+				if channel.Id == channelId {
+					return channel
+				}
+			}
+			return &model.Channel{Id: channelId, Type: model.CHANNEL_OPEN}
+		}, nil)
+	api.On("CreateChannel", mock.AnythingOfType("*model.Channel")).Return(
+		func(channel *model.Channel) *model.Channel {
+			channel.Id = model.NewId()
+			wt.channels[channel.Name] = channel
+			return channel
+		}, nil)
+	api.On("AddChannelMember", mock.AnythingOfType("string"), "userid").Return(&model.ChannelMember{}, nil)
+	api.On("DeleteChannel", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		wt.deleted = append(wt.deleted, args.String(0))
+	}).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(
+		func(post *model.Post) *model.Post {
+			post.Id = model.NewId()
+			wt.posts = append(wt.posts, post)
+			return post
+		}, nil)
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		wt.messages = append(wt.messages, args.Get(1).(*model.Post).Message)
+	}).Return(nil)
+
+	p := &Plugin{userStore: mockUserStore{}}
+	p.updateConfig(func(conf *config) {
+		conf.RolesAllowedToEditJiraSubscriptions = "users"
+	})
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	wt.p = p
+	wt.api = api
+	return wt
+}
+
+func (wt *warRoomTest) run(handler CommandHandlerFunc, args ...string) string {
+	wt.messages = nil
+	handler(wt.p, nil, &model.CommandArgs{UserId: "userid", TeamId: "team1", ChannelId: "channel1"}, args...)
+	return strings.Join(wt.messages, "\n")
+}
+
+func TestExecuteWarRoom(t *testing.T) {
+	t.Run("usage", func(t *testing.T) {
+		wt := newWarRoomTest()
+		assert.Contains(t, wt.run(executeWarRoom), "/jira war-room <issue-key>")
+	})
+
+	t.Run("unknown issue", func(t *testing.T) {
+		wt := newWarRoomTest()
+		assert.Contains(t, wt.run(executeWarRoom, nonExistantIssueKey), "Failed to get issue FAKE-1")
+		assert.Empty(t, wt.channels)
+	})
+
+	t.Run("create and archive", func(t *testing.T) {
+		wt := newWarRoomTest()
+		assert.Equal(t, "Created channel ~jira-real-1 for REAL-1.", wt.run(executeWarRoom, "real-1"))
+
+		channel := wt.channels["jira-real-1"]
+		require.NotNil(t, channel)
+		assert.Equal(t, "REAL-1 Checkout fails", channel.DisplayName)
+		assert.Equal(t, model.CHANNEL_OPEN, channel.Type)
+		assert.Equal(t, "team1", channel.TeamId)
+		wt.api.AssertCalled(t, "AddChannelMember", channel.Id, "userid")
+
+		subs, err := wt.p.getSubscriptionsForChannel(channel.Id)
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "REAL-1", subs[0].IssueKey)
+		assert.Equal(t, "userid", subs[0].CreatorId)
+
+		require.Len(t, wt.posts, 1)
+		assert.Equal(t, channel.Id, wt.posts[0].ChannelId)
+		assert.Equal(t, "REAL-1", wt.posts[0].Props[postPropIssueKey])
+
+		assert.Equal(t, "Channel ~jira-real-1 already exists for REAL-1.", wt.run(executeWarRoom, "REAL-1"))
+
+		assert.Equal(t, "Archived channel ~jira-real-1 for REAL-1.", wt.run(executeWarRoomArchive, "REAL-1"))
+		assert.Equal(t, []string{channel.Id}, wt.deleted)
+		subs, err = wt.p.getSubscriptionsForChannel(channel.Id)
+		require.NoError(t, err)
+		assert.Empty(t, subs)
+	})
+
+	t.Run("archive without channel", func(t *testing.T) {
+		wt := newWarRoomTest()
+		assert.Equal(t, "Could not find channel ~jira-real-2 for REAL-2.", wt.run(executeWarRoomArchive, "REAL-2"))
+		assert.Empty(t, wt.deleted)
+	})
+}
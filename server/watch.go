@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+func executeWatch(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	return p.executeWatchCommand(header, true, args...)
+}
+
+func executeUnwatch(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	return p.executeWatchCommand(header, false, args...)
+}
+
+// executeWatchCommand adds the connected user to the watchers of an issue in
+// Jira, or removes them, so that Jira notifies them of the issue changes.
+func (p *Plugin) executeWatchCommand(header *model.CommandArgs, watch bool, args ...string) *model.CommandResponse {
+	command := "watch"
+	if !watch {
+		command = "unwatch"
+	}
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira %s <issue-key>`.", command)
+	}
+	issueKey := strings.ToUpper(args[0])
+	if !reJiraIssueKeyLoose.MatchString(issueKey) {
+		return p.responsef(header, "%q is not a valid issue key.", args[0])
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeWatchCommand: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	err = p.requireWriteAccess(header.UserId, jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	err = p.requireWriteAllowed(header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	issueLink := "[" + issueKey + "](" + ji.GetURL() + "/browse/" + issueKey + ")"
+	if watch {
+		err = client.AddWatcher(issueKey, &jiraUser.User)
+		if err != nil {
+			return p.responsef(header, "Failed to watch %s: %v", issueKey, err)
+		}
+		return p.responsef(header, "You are now watching %s.", issueLink)
+	}
+
+	err = client.RemoveWatcher(issueKey, &jiraUser.User)
+	if err != nil {
+		return p.responsef(header, "Failed to stop watching %s: %v", issueKey, err)
+	}
+	return p.responsef(header, "You are no longer watching %s.", issueLink)
+}
@@ -4,15 +4,34 @@
 package main
 
 import (
-	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 )
 
+// postPropIssueKey is set on posts rendered for an issue, so that later
+// interactions with the post (e.g. reactions) can resolve the issue.
+const postPropIssueKey = "jira_issue_key"
+
+// The posts of webhook events are stamped with the event they were posted
+// for, so that they can be traced back to it.
+const (
+	postPropEvent          = "jira_event"
+	postPropEventTypes     = "jira_event_types"
+	postPropDeliveryId     = "jira_delivery_id"
+	postPropSubscriptionId = "jira_subscription_id"
+	postPropSimulated      = "jira_simulated"
+)
+
+// headerWebhookIdentifier is the ID Jira Cloud gives to the deliveries of a
+// webhook event.
+const headerWebhookIdentifier = "X-Atlassian-Webhook-Identifier"
+
 type Webhook interface {
 	Events() StringSet
 	PostToChannel(p *Plugin, channelId, fromUserId string) (*model.Post, int, error)
@@ -28,20 +47,112 @@ type webhookField struct {
 
 type webhook struct {
 	*JiraWebhook
-	eventTypes    StringSet
-	headline      string
+	eventTypes StringSet
+
+	// headline is the English rendering of localizedHeadline, which is
+	// rendered in the locale of each channel the event is posted to.
+	headline          string
+	localizedHeadline *localizedText
+
 	text          string
 	fields        []*model.SlackAttachmentField
 	notifications []webhookNotification
 	fieldInfo     webhookField
+
+	// mentions are added to the post message, outside of the attachment
+	// so that they notify.
+	mentions []string
+
+	// instanceId is the URL of the Jira instance the webhook request was
+	// authenticated as coming from, or empty if not known.
+	instanceId string
+
+	// deliveryId identifies the webhook request, and subscriptionId the
+	// subscription the webhook is posted for, if any.
+	deliveryId     string
+	subscriptionId string
+
+	// simulated events are tests of the subscriptions, posted with a
+	// marked headline and without notifying anyone.
+	simulated bool
+
+	// conf is the configuration snapshot the event is processed with, nil
+	// to use the current configuration.
+	conf *config
+}
+
+// getConfig returns the configuration the event is processed with, so that a
+// configuration change applies to whole events only.
+func (wh webhook) getConfig(p *Plugin) config {
+	if wh.conf != nil {
+		return *wh.conf
+	}
+	return p.getConfig()
 }
 
 type webhookNotification struct {
 	jiraUsername  string
 	jiraAccountID string
-	message       string
-	postType      string
-	commentSelf   string
+
+	// jiraEmail is only known for the assignee, for the users mapped by
+	// email address.
+	jiraEmail string
+
+	// message is rendered in the locale of the notified user.
+	message     *localizedText
+	postType    string
+	commentSelf string
+}
+
+// webhookFieldTitles are the message ids of the attachment field titles set
+// by the plugin. The other titles are names of Jira fields, and are posted
+// as Jira gives them.
+var webhookFieldTitles = map[string]string{
+	"Value":    msgWebhookFieldValue,
+	"Lead":     msgWebhookFieldLead,
+	"Assignee": msgWebhookFieldAssignee,
+	"Priority": msgWebhookFieldPriority,
+	"Estimate": msgWebhookFieldEstimate,
+	"Reporter": msgWebhookFieldReporter,
+	"Votes":    msgWebhookFieldVotes,
+	"Watchers": msgWebhookFieldWatchers,
+}
+
+// localizeFields returns a copy of fields with the titles set by the plugin
+// translated by t.
+func localizeFields(fields []*model.SlackAttachmentField, t localizer) []*model.SlackAttachmentField {
+	if len(fields) == 0 {
+		return fields
+	}
+	localized := make([]*model.SlackAttachmentField, 0, len(fields))
+	for _, field := range fields {
+		if id, ok := webhookFieldTitles[field.Title]; ok {
+			copied := *field
+			copied.Title = t(id)
+			field = &copied
+		}
+		localized = append(localized, field)
+	}
+	return localized
+}
+
+// setHeadline sets the headline of the webhook to the message id.
+func (wh *webhook) setHeadline(id string, args ...interface{}) {
+	wh.localizedHeadline = newLocalizedText(id, args...)
+	wh.headline = wh.localizedHeadline.String()
+}
+
+// renderHeadline renders the headline in locale, marked if the event is
+// simulated.
+func (wh webhook) renderHeadline(p *Plugin, locale string) string {
+	headline := wh.headline
+	if wh.localizedHeadline != nil {
+		headline = p.render(locale, wh.localizedHeadline)
+		if wh.simulated {
+			headline = p.localize(locale, msgWebhookSimulated, headline)
+		}
+	}
+	return headline
 }
 
 func (wh *webhook) Events() StringSet {
@@ -49,37 +160,63 @@ func (wh *webhook) Events() StringSet {
 }
 
 func (wh webhook) PostToChannel(p *Plugin, channelId, fromUserId string) (*model.Post, int, error) {
+	return wh.postToThread(p, channelId, "", fromUserId)
+}
+
+// postToThread posts the webhook as a reply to rootId, or to the channel if
+// rootId is empty.
+func (wh webhook) postToThread(p *Plugin, channelId, rootId, fromUserId string) (*model.Post, int, error) {
 	if wh.headline == "" {
 		return nil, http.StatusBadRequest, errors.Errorf("unsupported webhook")
 	}
 
 	post := &model.Post{
 		ChannelId: channelId,
+		RootId:    rootId,
+		ParentId:  rootId,
 		UserId:    fromUserId,
 		// Props: map[string]interface{}{
 		// 	"from_webhook":  "true",
 		// 	"use_user_icon": "true",
 		// },
 	}
+	if wh.JiraWebhook != nil && wh.Issue.Key != "" {
+		post.AddProp(postPropIssueKey, wh.Issue.Key)
+	}
+	wh.addEventProps(post)
+	locale := p.channelLocale(channelId)
+	headline := wh.renderHeadline(p, locale)
 	if wh.text != "" || len(wh.fields) != 0 {
 		// Get instance for replacing accountids in text. If no instance is available, just skip it.
 		ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
 		if err == nil {
 			wh.text = replaceJiraAccountIds(ji, wh.text)
 		}
+		if wh.JiraWebhook != nil && wh.Issue.Key != "" {
+			wh.text = truncateWithLink(wh.text, wh.getConfig(p).maxTextLength,
+				wh.JiraWebhook.mdJiraLink(p.localize(locale, msgWebhookShowMore), "/browse/"+wh.Issue.Key))
+		}
 
-		model.ParseSlackAttachment(post, []*model.SlackAttachment{
-			{
-				// TODO is this supposed to be themed?
-				Color:    "#95b7d0",
-				Fallback: wh.headline,
-				Pretext:  wh.headline,
-				Text:     wh.text,
-				Fields:   wh.fields,
-			},
-		})
+		attachment := &model.SlackAttachment{
+			// TODO is this supposed to be themed?
+			Color:    "#95b7d0",
+			Fallback: headline,
+			Pretext:  headline,
+			Text:     wh.text,
+			Fields:   localizeFields(wh.fields, p.localizer(locale)),
+		}
+		if wh.JiraWebhook != nil && wh.Issue.Key != "" {
+			attachment.Actions = p.issuePostActions(wh.Issue.Key, p.localizer(locale))
+			p.addIssueAppLink(attachment, wh.Issue.Key, channelId)
+		}
+		if avatarURL := p.jiraAvatarURLFunc(); avatarURL != nil && wh.JiraWebhook != nil && wh.User.AvatarUrls.Four8X48 != "" {
+			attachment.AuthorName = wh.User.DisplayName
+			attachment.AuthorIcon = avatarURL(wh.User.AvatarUrls.Four8X48)
+		}
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+		post.Message = strings.Join(wh.mentions, " ")
 	} else {
-		post.Message = wh.headline
+		post.Message = strings.TrimSpace(headline + " " + strings.Join(wh.mentions, " "))
 	}
 
 	_, appErr := p.API.CreatePost(post)
@@ -90,6 +227,36 @@ func (wh webhook) PostToChannel(p *Plugin, channelId, fromUserId string) (*model
 	return post, http.StatusOK, nil
 }
 
+// addEventProps stamps a post with the webhook event it is posted for.
+func (wh webhook) addEventProps(post *model.Post) {
+	if wh.JiraWebhook != nil && wh.WebhookEvent != "" {
+		post.AddProp(postPropEvent, wh.WebhookEvent)
+	}
+	if wh.eventTypes.Len() != 0 {
+		eventTypes := wh.eventTypes.Elems()
+		sort.Strings(eventTypes)
+		post.AddProp(postPropEventTypes, eventTypes)
+	}
+	if wh.deliveryId != "" {
+		post.AddProp(postPropDeliveryId, wh.deliveryId)
+	}
+	if wh.subscriptionId != "" {
+		post.AddProp(postPropSubscriptionId, wh.subscriptionId)
+	}
+	if wh.simulated {
+		post.AddProp(postPropSimulated, "true")
+	}
+}
+
+// webhookDeliveryId returns the ID of a webhook request, as given by Jira
+// Cloud, or a new ID for the Jira servers that don't give one.
+func webhookDeliveryId(r *http.Request) string {
+	if id := r.Header.Get(headerWebhookIdentifier); id != "" {
+		return id
+	}
+	return model.NewId()
+}
+
 func (wh *webhook) PostNotifications(p *Plugin) ([]*model.Post, int, error) {
 	if len(wh.notifications) == 0 {
 		return nil, http.StatusOK, nil
@@ -114,6 +281,17 @@ func (wh *webhook) PostNotifications(p *Plugin) ([]*model.Post, int, error) {
 			mattermostUserId, err = p.userStore.LoadMattermostUserId(ji, notification.jiraUsername)
 		}
 		if err != nil {
+			// Users mapped without OAuth only get a link to the issue
+			mappedUserId, mappingErr := p.loadMappedUserId(ji, notification.jiraAccountID, notification.jiraUsername, notification.jiraEmail)
+			if mappingErr != nil {
+				continue
+			}
+			post, mappingErr := p.postMappedUserNotification(wh, mappedUserId)
+			if mappingErr != nil {
+				p.errorf("PostNotifications: failed to notify mapped user %s, err: %v", mappedUserId, mappingErr)
+				continue
+			}
+			posts = append(posts, post)
 			continue
 		}
 
@@ -142,9 +320,9 @@ func (wh *webhook) PostNotifications(p *Plugin) ([]*model.Post, int, error) {
 			continue
 		}
 
-		notification.message = replaceJiraAccountIds(ji, notification.message)
+		message := replaceJiraAccountIds(ji, p.render(p.userLocale(mattermostUserId), notification.message))
 
-		post, err := ji.GetPlugin().CreateBotDMPost(ji, mattermostUserId, notification.message, notification.postType)
+		post, err := ji.GetPlugin().CreateBotDMPost(ji, mattermostUserId, message, notification.postType)
 		if err != nil {
 			p.errorf("PostNotifications: failed to create notification post, err: %v", err)
 			continue
@@ -154,12 +332,32 @@ func (wh *webhook) PostNotifications(p *Plugin) ([]*model.Post, int, error) {
 	return posts, http.StatusOK, nil
 }
 
-func newWebhook(jwh *JiraWebhook, eventType string, format string, args ...interface{}) *webhook {
-	return &webhook{
+// isRestrictedComment returns true for comment events whose comment is only
+// visible to a Jira role or group.
+func (wh *webhook) isRestrictedComment() bool {
+	return wh.JiraWebhook != nil &&
+		wh.Events().Intersection(commentEvents).Len() > 0 &&
+		wh.Comment.Visibility.Value != ""
+}
+
+// restrictedCommentStub returns a copy of a restricted comment webhook with
+// the comment content replaced by a notice in locale.
+func (wh webhook) restrictedCommentStub(p *Plugin, locale string) *webhook {
+	wh.text = p.localize(locale, msgRestrictedComment,
+		wh.Comment.Visibility.Type, wh.Comment.Visibility.Value)
+	wh.fields = nil
+	return &wh
+}
+
+// newWebhook returns the webhook of an issue event, headlined with the message
+// id formatted with the user, args and the issue link.
+func newWebhook(jwh *JiraWebhook, eventType string, id string, args ...interface{}) *webhook {
+	wh := &webhook{
 		JiraWebhook: jwh,
 		eventTypes:  NewStringSet(eventType),
-		headline:    jwh.mdUser() + " " + fmt.Sprintf(format, args...) + " " + jwh.mdKeySummaryLink(),
 	}
+	wh.setHeadline(id, append(append([]interface{}{jwh.mdUser()}, args...), jwh.mdKeySummaryLink())...)
+	return wh
 }
 
 func (p *Plugin) GetWebhookURL(teamId, channelId string) (string, error) {
@@ -0,0 +1,266 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// How long whether a channel can be posted to is cached.
+	blockedChannelCacheTTL = time.Minute
+
+	// The creator of a subscription is warned at most this often that the
+	// events of its channel could not be posted there.
+	blockedChannelWarningInterval = time.Hour
+)
+
+type blockedChannel struct {
+	// reason is the message id of why the channel can't be posted to, or ""
+	// if it can.
+	reason  string
+	name    string
+	expires time.Time
+}
+
+// blockedChannels caches why the channels can't be posted to, and records when
+// the creators of their subscriptions were last warned.
+type blockedChannels struct {
+	lock     sync.Mutex
+	channels map[string]blockedChannel
+	warnings map[string]time.Time
+}
+
+func (b *blockedChannels) get(channelId string, now time.Time) (blockedChannel, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c, ok := b.channels[channelId]
+	if !ok || now.After(c.expires) {
+		return blockedChannel{}, false
+	}
+	return c, true
+}
+
+func (b *blockedChannels) set(channelId string, c blockedChannel) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.channels == nil {
+		b.channels = map[string]blockedChannel{}
+	}
+	b.channels[channelId] = c
+}
+
+// warningDue returns true, and records the warning, if the user was not warned
+// about the subscription within blockedChannelWarningInterval.
+func (b *blockedChannels) warningDue(userId, subscriptionId string, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.warnings == nil {
+		b.warnings = map[string]time.Time{}
+	}
+	key := userId + "/" + subscriptionId
+	if last, ok := b.warnings[key]; ok && now.Sub(last) < blockedChannelWarningInterval {
+		return false
+	}
+	b.warnings[key] = now
+	return true
+}
+
+// blockedChannelsOf returns why webhook events can't or shouldn't be posted to
+// the channels, with no reason for those they can. The bot posts through the
+// API, in the channels it is a member of or not, but joins the public
+// channels it is not a member of, checking its membership in all the
+// channels of a team at once. Channels that fail to load are not blocked, so
+// that the post reports the error.
+func (p *Plugin) blockedChannelsOf(channelIds []string) map[string]blockedChannel {
+	now := time.Now()
+	result := map[string]blockedChannel{}
+	channels := []*model.Channel{}
+	teamIds := NewStringSet()
+	for _, channelId := range channelIds {
+		if _, ok := result[channelId]; ok {
+			continue
+		}
+		if c, ok := p.blockedChannels.get(channelId, now); ok {
+			result[channelId] = c
+			continue
+		}
+		channel, appErr := p.API.GetChannel(channelId)
+		if appErr != nil {
+			result[channelId] = blockedChannel{}
+			continue
+		}
+		result[channelId] = blockedChannel{}
+		channels = append(channels, channel)
+		if channel.TeamId != "" && channel.Type == model.CHANNEL_OPEN {
+			teamIds = teamIds.Add(channel.TeamId)
+		}
+	}
+	if len(channels) == 0 {
+		return result
+	}
+
+	// The channels of the bot in each team, or nil if they failed to load
+	botChannels := map[string]StringSet{}
+	for teamId := range teamIds {
+		teamChannels, appErr := p.API.GetChannelsForTeamForUser(teamId, p.getUserID(), false)
+		if appErr != nil {
+			p.errorf("blockedChannelsOf: failed to load the channels of the bot in team %s: %v", teamId, appErr)
+			continue
+		}
+		ids := []string{}
+		for _, channel := range teamChannels {
+			ids = append(ids, channel.Id)
+		}
+		botChannels[teamId] = NewStringSet(ids...)
+	}
+
+	config := p.API.GetConfig()
+	botMissing := p.botMissingReason()
+	for _, channel := range channels {
+		c := blockedChannel{name: channel.Name, expires: now.Add(blockedChannelCacheTTL)}
+		switch {
+		case channel.DeleteAt != 0:
+			c.reason = msgBlockedArchived
+		case botMissing != "":
+			c.reason = botMissing
+		case channel.Name == model.DEFAULT_CHANNEL && townSquareIsReadOnly(config):
+			c.reason = msgBlockedReadOnly
+		case channel.Type == model.CHANNEL_OPEN:
+			p.joinPublicChannel(channel, botChannels[channel.TeamId])
+		}
+		p.blockedChannels.set(channel.Id, c)
+		result[channel.Id] = c
+	}
+	return result
+}
+
+// botMissingReason returns the message id of why the bot can't post at all,
+// or "" if it can.
+func (p *Plugin) botMissingReason() string {
+	botUserId := p.getUserID()
+	if botUserId == "" {
+		return msgBlockedBotMissing
+	}
+	bot, appErr := p.API.GetUser(botUserId)
+	switch {
+	case appErr != nil:
+		return msgBlockedBotMissing
+	case bot.DeleteAt != 0:
+		return msgBlockedBotDeactivated
+	}
+	return ""
+}
+
+// joinPublicChannel joins the bot to the public channel if it is not a
+// member. memberOf are the channels of the bot in the team of the channel, or
+// nil if they failed to load. A failure to join doesn't block the posts.
+func (p *Plugin) joinPublicChannel(channel *model.Channel, memberOf StringSet) {
+	botUserId := p.getUserID()
+	if memberOf != nil && memberOf.ContainsAny(channel.Id) {
+		return
+	}
+	if memberOf == nil {
+		if _, appErr := p.API.GetChannelMember(channel.Id, botUserId); appErr == nil {
+			return
+		}
+	}
+	_, appErr := p.API.AddChannelMember(channel.Id, botUserId)
+	if appErr != nil {
+		p.errorf("joinPublicChannel: failed to join channel %s: %v", channel.Id, appErr)
+	}
+}
+
+func townSquareIsReadOnly(config *model.Config) bool {
+	return config != nil && config.TeamSettings.ExperimentalTownSquareIsReadOnly != nil &&
+		*config.TeamSettings.ExperimentalTownSquareIsReadOnly
+}
+
+// rerouteBlockedPosts drops the posts for the channels that can't be posted
+// to, notifies the fallback channel if there is one, and warns the creators of
+// the subscriptions. The notices don't say anything about the events: the
+// members of the fallback channel may not be allowed to see them.
+func (p *Plugin) rerouteBlockedPosts(wh *webhook, posts []webhookPost) []webhookPost {
+	fallbackChannelId := wh.getConfig(p).FallbackChannelId
+	channelIds := []string{}
+	for _, post := range posts {
+		channelIds = append(channelIds, post.channelId)
+	}
+	channels := p.blockedChannelsOf(channelIds)
+
+	result := []webhookPost{}
+	blocked := map[string]blockedChannel{}
+	dropped := map[string]int{}
+	for _, post := range posts {
+		c := channels[post.channelId]
+		if c.reason == "" {
+			result = append(result, post)
+			continue
+		}
+		blocked[post.channelId] = c
+		dropped[post.channelId]++
+	}
+	if fallbackChannelId != "" {
+		for channelId, n := range dropped {
+			if channelId != fallbackChannelId {
+				p.postBlockedChannelNotice(fallbackChannelId, blocked[channelId], n)
+			}
+		}
+	}
+	if len(blocked) > 0 {
+		p.warnBlockedSubscriptionCreators(wh, blocked, fallbackChannelId)
+	}
+	return result
+}
+
+// postBlockedChannelNotice tells the fallback channel that n events could not
+// be delivered to the blocked channel.
+func (p *Plugin) postBlockedChannelNotice(fallbackChannelId string, c blockedChannel, n int) {
+	locale := p.channelLocale(fallbackChannelId)
+	message := p.localize(locale, msgBlockedEvents, n, c.name, p.localize(locale, c.reason))
+	if n == 1 {
+		message = p.localize(locale, msgBlockedEvent, c.name, p.localize(locale, c.reason))
+	}
+	_, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: fallbackChannelId,
+		Message:   message,
+	})
+	if appErr != nil {
+		p.errorf("postBlockedChannelNotice: failed to post to the fallback channel %s: %v", fallbackChannelId, appErr)
+	}
+}
+
+func (p *Plugin) warnBlockedSubscriptionCreators(wh *webhook, blocked map[string]blockedChannel, fallbackChannelId string) {
+	subs, err := p.getSubscriptions()
+	if err != nil {
+		p.errorf("warnBlockedSubscriptionCreators: failed to load subscriptions: %v", err)
+		return
+	}
+	threadSubs, _ := p.getThreadsSubscribed(wh)
+
+	instead := newLocalizedText(msgBlockedDropped)
+	if fallbackChannelId != "" {
+		instead = newLocalizedText(msgBlockedDroppedFallback)
+		if channel, appErr := p.API.GetChannel(fallbackChannelId); appErr == nil {
+			instead = newLocalizedText(msgBlockedDroppedNotified, channel.Name)
+		}
+	}
+
+	now := time.Now()
+	for _, sub := range append(p.matchingChannelSubscriptions(subs, wh), threadSubs...) {
+		c, ok := blocked[sub.ChannelId]
+		if !ok || sub.CreatorId == "" || !p.blockedChannels.warningDue(sub.CreatorId, sub.Id, now) {
+			continue
+		}
+		message := newLocalizedText(msgBlockedSubscription, sub.Name, c.name, newLocalizedText(c.reason), instead)
+		_, err = p.CreateBotDMtoMMUserId(sub.CreatorId, "%s", p.render(p.userLocale(sub.CreatorId), message))
+		if err != nil {
+			p.errorf("warnBlockedSubscriptionCreators: %v", err)
+		}
+	}
+}
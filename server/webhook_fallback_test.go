@@ -0,0 +1,152 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestRerouteBlockedPosts(t *testing.T) {
+	readOnly := true
+	subs := NewSubscriptions()
+	subs.Channel.add(&ChannelSubscription{Id: "sub1", ChannelId: "archived", Name: "Bugs", CreatorId: "creator1", IssueKey: "TEST-1"})
+	subs.Channel.add(&ChannelSubscription{Id: "sub2", ChannelId: "open", Name: "All", CreatorId: "creator2", IssueKey: "TEST-1"})
+	stored, err := json.Marshal(subs)
+	require.NoError(t, err)
+
+	dms := map[string]string{}
+	notices := []string{}
+	api := &plugintest.API{}
+	api.On("GetChannel", "archived").Return(&model.Channel{Id: "archived", Name: "old-bugs", DeleteAt: 1}, nil)
+	api.On("GetChannel", "townsquare").Return(&model.Channel{Id: "townsquare", Name: model.DEFAULT_CHANNEL}, nil)
+	api.On("GetChannel", "open").Return(&model.Channel{Id: "open", Name: "dev"}, nil)
+	api.On("GetChannel", "fallback").Return(&model.Channel{Id: "fallback", Name: "jira-fallback"}, nil)
+	api.On("GetConfig").Return(&model.Config{TeamSettings: model.TeamSettings{ExperimentalTownSquareIsReadOnly: &readOnly}})
+	api.On("GetUser", "botUserId").Return(&model.User{Id: "botUserId"}, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(stored, nil)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), "botUserId").Return(func(userId, botId string) *model.Channel {
+		return &model.Channel{Id: "dm_" + userId}
+	}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		if post.ChannelId == "fallback" {
+			notices = append(notices, post.Message)
+			return
+		}
+		dms[post.ChannelId] = post.Message
+	}).Return(&model.Post{}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	p.updateConfig(func(conf *config) {
+		conf.botUserID = "botUserId"
+		conf.FallbackChannelId = "fallback"
+	})
+
+	wh := &webhook{JiraWebhook: &JiraWebhook{Event: jiraevent.Event{Issue: jira.Issue{Key: "TEST-1"}}}, headline: "TEST-1 was updated", eventTypes: NewStringSet(eventUpdatedAny)}
+	posts := []webhookPost{
+		{wh: *wh, channelId: "archived"},
+		{wh: *wh, channelId: "townsquare"},
+		{wh: *wh, channelId: "open"},
+	}
+
+	posts = append(posts, webhookPost{wh: *wh, channelId: "archived", rootId: "thread"})
+
+	result := p.rerouteBlockedPosts(wh, posts)
+	require.Len(t, result, 1)
+	assert.Equal(t, "open", result[0].channelId)
+	assert.Equal(t, "TEST-1 was updated", result[0].wh.headline)
+
+	assert.ElementsMatch(t, []string{
+		"2 Jira events for ~old-bugs could not be delivered, because the channel is archived.",
+		"1 Jira event for ~town-square could not be delivered, because the channel is read-only.",
+	}, notices)
+	for _, notice := range notices {
+		assert.NotContains(t, notice, "TEST-1", "the notices don't tell the content of the events")
+	}
+
+	require.Len(t, dms, 1)
+	assert.Contains(t, dms["dm_creator1"], `Your Jira subscription "Bugs" could not post an event to ~old-bugs, because the channel is archived. The event was dropped, and ~jira-fallback was notified. Please move or delete the subscription.`)
+
+	// The creator is not warned again right away, and there is no fallback
+	// channel anymore.
+	delete(dms, "dm_creator1")
+	notices = nil
+	p.updateConfig(func(conf *config) {
+		conf.FallbackChannelId = ""
+	})
+	result = p.rerouteBlockedPosts(wh, posts)
+	require.Len(t, result, 1)
+	assert.Equal(t, "open", result[0].channelId)
+	assert.Empty(t, dms)
+	assert.Empty(t, notices)
+}
+
+func TestBlockedChannelsOf(t *testing.T) {
+	api := &plugintest.API{}
+	for _, channel := range []*model.Channel{
+		{Id: "member", Name: "dev", TeamId: "team", Type: model.CHANNEL_OPEN},
+		{Id: "join", Name: "support", TeamId: "team", Type: model.CHANNEL_OPEN},
+		{Id: "joinFails", Name: "ops", TeamId: "team", Type: model.CHANNEL_OPEN},
+		{Id: "private", Name: "secret", TeamId: "team", Type: model.CHANNEL_PRIVATE},
+		{Id: "archived", Name: "old", TeamId: "team", Type: model.CHANNEL_PRIVATE, DeleteAt: 1},
+	} {
+		api.On("GetChannel", channel.Id).Return(channel, nil)
+	}
+	api.On("GetConfig").Return(&model.Config{})
+	api.On("GetUser", "botUserId").Return(&model.User{Id: "botUserId"}, nil)
+	api.On("GetChannelsForTeamForUser", "team", "botUserId", false).Return([]*model.Channel{{Id: "member"}, {Id: "other"}}, nil)
+	api.On("AddChannelMember", "join", "botUserId").Return(&model.ChannelMember{}, nil)
+	api.On("AddChannelMember", "joinFails", "botUserId").Return(nil, &model.AppError{Message: "not a team member"})
+	api.On("LogError", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.botUserID = "botUserId"
+	})
+
+	channels := p.blockedChannelsOf([]string{"member", "join", "joinFails", "private", "archived", "member"})
+	require.Len(t, channels, 5)
+	assert.Equal(t, "", channels["member"].reason)
+	assert.Equal(t, "", channels["join"].reason)
+	assert.Equal(t, "", channels["joinFails"].reason, "the bot posts to the channels it could not join")
+	assert.Equal(t, "", channels["private"].reason, "the bot posts to the private channels it is not a member of")
+	assert.Equal(t, msgBlockedArchived, channels["archived"].reason)
+	api.AssertNumberOfCalls(t, "GetChannelsForTeamForUser", 1)
+	api.AssertCalled(t, "AddChannelMember", "join", "botUserId")
+	api.AssertNotCalled(t, "AddChannelMember", "private", "botUserId")
+
+	// The channels are cached
+	p.blockedChannelsOf([]string{"member", "join"})
+	api.AssertNumberOfCalls(t, "GetChannelsForTeamForUser", 1)
+	api.AssertNumberOfCalls(t, "AddChannelMember", 2)
+}
+
+func TestBlockedChannelsOfMissingBot(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannel", "private").Return(&model.Channel{Id: "private", Name: "secret", TeamId: "team", Type: model.CHANNEL_PRIVATE}, nil)
+	api.On("GetConfig").Return(&model.Config{})
+	api.On("GetUser", "botUserId").Return(&model.User{Id: "botUserId", DeleteAt: 1}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.botUserID = "botUserId"
+	})
+
+	channels := p.blockedChannelsOf([]string{"private"})
+	assert.Equal(t, msgBlockedBotDeactivated, channels["private"].reason)
+}
@@ -71,6 +71,7 @@ func httpWebhook(p *Plugin, w http.ResponseWriter, r *http.Request) (status int,
 	}
 	status, err = verifyHTTPSecret(conf.Secret, r.FormValue("secret"))
 	if err != nil {
+		p.recordWebhookAuthFailure()
 		return status, err
 	}
 	teamName := r.FormValue("team")
@@ -97,13 +98,16 @@ func httpWebhook(p *Plugin, w http.ResponseWriter, r *http.Request) (status int,
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	if p.isStaleWebhookEvent(bb, start) {
+		return http.StatusOK, ErrWebhookIgnored
+	}
 
 	channel, appErr := p.API.GetChannelByNameForTeamName(teamName, channelName, false)
 	if appErr != nil {
 		return appErr.StatusCode, appErr
 	}
 
-	wh, err := ParseWebhook(bb)
+	wh, err := ParseWebhookWithOptions(bb, conf.webhookParseOptions)
 	if err == ErrWebhookIgnored {
 		return http.StatusOK, err
 	}
@@ -117,6 +121,10 @@ func httpWebhook(p *Plugin, w http.ResponseWriter, r *http.Request) (status int,
 	}
 
 	// Post the event to the channel
+	if parsed, ok := wh.(*webhook); ok {
+		parsed.deliveryId = webhookDeliveryId(r)
+		parsed.conf = &conf
+	}
 	_, statusCode, err := wh.PostToChannel(p, channel.Id, p.getUserID())
 	if err != nil {
 		return statusCode, err
@@ -6,29 +6,29 @@ package main
 import (
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/andygrunwald/go-jira"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
 )
 
+// JiraWebhook is a parsed webhook event, with the methods rendering it and
+// matching it against the subscriptions.
 type JiraWebhook struct {
-	WebhookEvent string       `json:"webhookEvent,omitempty"`
-	Issue        jira.Issue   `json:"issue,omitempty"`
-	User         jira.User    `json:"user,omitempty"`
-	Comment      jira.Comment `json:"comment,omitempty"`
-	ChangeLog    struct {
-		Items []struct {
-			From       string
-			FromString string
-			To         string
-			ToString   string
-			Field      string
-			FieldId    string
-			FieldType  string `json:"fieldtype"`
-		}
-	} `json:"changelog,omitempty"`
-	IssueEventTypeName string `json:"issue_event_type_name"`
+	jiraevent.Event
+
+	// emoji shown before the issue type and the changed statuses and
+	// priorities, from the parse options
+	emoji issueEmoji
 }
 
+type JiraWebhookProject = jiraevent.Project
+
+type JiraWebhookProperty = jiraevent.Property
+
 func (jwh *JiraWebhook) mdJiraLink(title, suffix string) string {
 	// Use Self URL only to extract the full hostname from it
 	pos := strings.LastIndex(jwh.Issue.Self, "/rest/api")
@@ -38,8 +38,94 @@ func (jwh *JiraWebhook) mdJiraLink(title, suffix string) string {
 	return fmt.Sprintf("[%s](%s%s)", title, jwh.Issue.Self[:pos], suffix)
 }
 
+func (jwh *JiraWebhook) mdProjectLink() string {
+	title := fmt.Sprintf("%s (%s)", jwh.Project.Name, jwh.Project.Key)
+	pos := strings.LastIndex(jwh.Project.Self, "/rest/api")
+	if pos < 0 {
+		return title
+	}
+	return fmt.Sprintf("[%s](%s/browse/%s)", title, jwh.Project.Self[:pos], jwh.Project.Key)
+}
+
+// resolveEventAliases renames the webhook event and the issue event type with
+// aliases, or defaultEventAliases.
+func (jwh *JiraWebhook) resolveEventAliases(aliases map[string]string) {
+	resolve := func(name string) string {
+		if alias, ok := aliases[name]; ok {
+			return alias
+		}
+		if alias, ok := defaultEventAliases[name]; ok {
+			return alias
+		}
+		return name
+	}
+	jwh.WebhookEvent = resolve(jwh.WebhookEvent)
+	jwh.IssueEventTypeName = resolve(jwh.IssueEventTypeName)
+}
+
+// normalizeLegacyEvent rewrites the issue updates of older Jira versions as
+// the jira:issue_updated events of the current versions, so that they match
+// the same subscriptions. The worklog changes, which the plugin doesn't post,
+// are dropped from the changelog, and it returns false if the event changed
+// nothing else.
+func (jwh *JiraWebhook) normalizeLegacyEvent() bool {
+	if eventType, ok := legacyWebhookEvents[jwh.WebhookEvent]; ok {
+		jwh.WebhookEvent = "jira:issue_updated"
+		if jwh.IssueEventTypeName == "" {
+			jwh.IssueEventTypeName = eventType
+		}
+	}
+	if jwh.WebhookEvent != "jira:issue_updated" {
+		return true
+	}
+
+	worklog := worklogEventTypes.ContainsAny(jwh.IssueEventTypeName)
+	for _, item := range jwh.ChangeLog.Items {
+		if strings.EqualFold(item.Field, "WorklogId") {
+			worklog = true
+		}
+	}
+	if !worklog {
+		return true
+	}
+	jwh.IssueEventTypeName = "issue_updated"
+	if len(jwh.ChangeLog.Items) == 0 {
+		return false
+	}
+	return jwh.DropChanges(func(item jiraevent.ChangeItem) bool {
+		return worklogFields.ContainsAny(strings.ToLower(item.Field))
+	})
+}
+
+// removeIgnoredChangeLogItems drops the changelog items of the fields, by
+// lowercase name or ID, and returns false if the changelog had only such
+// items.
+func (jwh *JiraWebhook) removeIgnoredChangeLogItems(fields StringSet) bool {
+	if fields.Len() == 0 || len(jwh.ChangeLog.Items) == 0 {
+		return true
+	}
+	return jwh.DropChanges(func(item jiraevent.ChangeItem) bool {
+		return fields.ContainsAny(strings.ToLower(item.Field), strings.ToLower(item.FieldId))
+	})
+}
+
+// isRawFieldSelector reports whether a filter field key is a JSONPath-style
+// selector on the raw webhook payload, rather than an issue field name.
+func isRawFieldSelector(key string) bool {
+	return strings.HasPrefix(key, "$")
+}
+
+// rawFieldValues returns the scalar values selected in the raw payload.
+func (jwh *JiraWebhook) rawFieldValues(selector string) StringSet {
+	values, err := utils.SelectJSONStrings(jwh.Raw(), selector)
+	if err != nil {
+		return NewStringSet()
+	}
+	return NewStringSet(values...)
+}
+
 func (jwh *JiraWebhook) mdIssueDescription() string {
-	return truncate(jwh.Issue.Fields.Description, 3000)
+	return jwh.Issue.Fields.Description
 }
 
 func (jwh *JiraWebhook) mdIssueSummary() string {
@@ -72,7 +158,25 @@ func (jwh *JiraWebhook) mdUser() string {
 }
 
 func (jwh *JiraWebhook) mdIssueType() string {
-	return strings.ToLower(jwh.Issue.Fields.Type.Name)
+	return jwh.emoji.prefix(emojiKindType, strings.ToLower(jwh.Issue.Fields.Type.Name))
+}
+
+// mdFieldValue quotes a value of a changed field, after its emoji if the field
+// is the status, priority or type of the issue.
+func (jwh *JiraWebhook) mdFieldValue(fieldId, value string) string {
+	kind := ""
+	switch fieldId {
+	case "status":
+		kind = emojiKindStatus
+	case "priority":
+		kind = emojiKindPriority
+	case "issuetype":
+		kind = emojiKindType
+	}
+	if e := jwh.emoji.get(kind, value); kind != "" && e != "" {
+		return e + " " + fmt.Sprintf("%q", value)
+	}
+	return fmt.Sprintf("%q", value)
 }
 
 func (jwh *JiraWebhook) expandIssue(p *Plugin) error {
@@ -94,20 +198,19 @@ func (jwh *JiraWebhook) expandIssue(p *Plugin) error {
 	return nil
 }
 
-func mdAddRemove(from, to, add, remove string) string {
+// mdAddRemove returns the message id and arguments of the values added to and
+// removed from a field, from the ids of the addition, the removal, and both.
+func mdAddRemove(from, to, addId, removeId, bothId string) (string, []interface{}) {
 	added := mdDiff(from, to)
 	removed := mdDiff(to, from)
-	s := ""
-	if added != "" {
-		s += fmt.Sprintf("%v [%v] to", add, added)
-	}
-	if removed != "" {
-		if added != "" {
-			s += ", "
-		}
-		s += fmt.Sprintf("%v [%v] from", remove, removed)
+	switch {
+	case added != "" && removed != "":
+		return bothId, []interface{}{added, removed}
+	case removed != "":
+		return removeId, []interface{}{removed}
+	default:
+		return addId, []interface{}{added}
 	}
-	return s
 }
 
 func mdDiff(from, to string) string {
@@ -138,6 +241,58 @@ func mdUser(user *jira.User) string {
 	return user.DisplayName
 }
 
+// truncateWithLink truncates s to max characters, or defaultMaxTextLength if
+// max is 0, and appends link to the full text. The text is cut before a link
+// or a URL it would cut in half.
+func truncateWithLink(s string, max int, link string) string {
+	if max <= 0 {
+		max = defaultMaxTextLength
+	}
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	s = strings.TrimRightFunc(string(runes[:safeTruncation(runes, max)]), unicode.IsSpace) + "..."
+	if link != "" {
+		s += "\n\n" + link
+	}
+	return s
+}
+
+// safeTruncation returns where to cut the text at max, before the Markdown
+// link `[text](url)`, the Jira link `[text|url]`, or the URL the cut would
+// break.
+func safeTruncation(runes []rune, max int) int {
+	cut := max
+	// The start of the word of the cut, if the cut is inside a word
+	if runes[cut] != ' ' && runes[cut] != '\n' {
+		start := cut
+		for start > 0 && !unicode.IsSpace(runes[start-1]) {
+			start--
+		}
+		if strings.Contains(string(runes[start:cut]), "://") {
+			cut = start
+		}
+	}
+
+	// An open bracket that is not closed before the cut, or a Markdown link
+	// whose URL is not closed.
+	for i := cut - 1; i >= 0; i-- {
+		switch runes[i] {
+		case ']', ')':
+			if i+1 < cut && runes[i] == ']' && runes[i+1] == '(' {
+				continue
+			}
+			return cut
+		case '[':
+			return i
+		case '\n':
+			return cut
+		}
+	}
+	return cut
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max || max < 0 {
 		return s
@@ -0,0 +1,43 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func hasJiraUserId(u *jira.User) bool {
+	return u != nil && (u.AccountID != "" || u.Name != "" || u.Key != "")
+}
+
+// sameJiraUser compares users by account ID on Jira Cloud, and by name or key
+// on Jira Server.
+func sameJiraUser(a, b *jira.User) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	switch {
+	case a.AccountID != "" || b.AccountID != "":
+		return a.AccountID == b.AccountID
+	case a.Name != "" || b.Name != "":
+		return a.Name == b.Name
+	case a.Key != "" || b.Key != "":
+		return a.Key == b.Key
+	}
+	return false
+}
+
+// isJiraUserMentioned reports whether mention, as returned by
+// parseJIRAUsernamesFromText, refers to user.
+func isJiraUserMentioned(mention string, user *jira.User) bool {
+	if user == nil {
+		return false
+	}
+	if strings.HasPrefix(mention, "accountid:") {
+		return user.AccountID != "" && mention[len("accountid:"):] == user.AccountID
+	}
+	return mention == user.Name || (user.AccountID != "" && mention == user.AccountID)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhookCloudADFComment(t *testing.T) {
+	data, err := getJiraTestData("webhook-cloud-comment-created-adf.json")
+	require.Nil(t, err)
+
+	w, err := ParseWebhook(data)
+	require.Nil(t, err)
+	wh := w.(*webhook)
+
+	assert.Equal(t, "Thanks [~accountid:5c5f880629be9642ba529341], see [the docs|https://example.com/docs] for *details*\n\n"+
+		"* first\n"+
+		"* second\n"+
+		"*# nested\n"+
+		"\n"+
+		"{code}\nx := 1\n{code}", wh.text)
+
+	// Jira Cloud comment events have no user, the comment author is used
+	assert.Equal(t, "5c5f880629be9642ba529340", wh.JiraWebhook.User.AccountID)
+
+	require.Len(t, wh.notifications, 1)
+	assert.Equal(t, "5c5f880629be9642ba529341", wh.notifications[0].jiraAccountID)
+}
+
+func TestSameJiraUser(t *testing.T) {
+	cloud := &jira.User{AccountID: "5c5f880629be9642ba529340", Name: "admin"}
+	server := &jira.User{Name: "admin", Key: "admin"}
+
+	assert.True(t, sameJiraUser(cloud, &jira.User{AccountID: "5c5f880629be9642ba529340"}))
+	assert.False(t, sameJiraUser(cloud, server))
+	assert.True(t, sameJiraUser(server, &jira.User{Name: "admin"}))
+	assert.False(t, sameJiraUser(server, &jira.User{Name: "other", Key: "admin"}))
+	assert.False(t, sameJiraUser(&jira.User{}, &jira.User{}))
+	assert.False(t, sameJiraUser(server, nil))
+}
+
+func TestIsJiraUserMentioned(t *testing.T) {
+	cloud := &jira.User{AccountID: "5c5f880629be9642ba529340"}
+	server := &jira.User{Name: "admin"}
+
+	assert.True(t, isJiraUserMentioned("accountid:5c5f880629be9642ba529340", cloud))
+	assert.False(t, isJiraUserMentioned("accountid:5c5f880629be9642ba529340", server))
+	assert.True(t, isJiraUserMentioned("admin", server))
+	assert.False(t, isJiraUserMentioned("admin", cloud))
+	assert.False(t, isJiraUserMentioned("accountid:", &jira.User{}))
+}
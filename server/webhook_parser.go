@@ -4,21 +4,96 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
 )
 
 var webhookWrapperFunc func(wh Webhook) Webhook
 
+// nonIssueWebhookEvents are the Jira webhook events that carry no issue.
+var nonIssueWebhookEvents = NewStringSet(
+	"project_created",
+	"project_deleted",
+	"user_created",
+	"user_updated",
+	"user_deleted",
+)
+
+// defaultEventAliases map the variant event names sent by some Jira versions,
+// automation rules and add-ons to the names handled by the parser. They apply
+// to both the webhook event and the issue event type name.
+var defaultEventAliases = map[string]string{
+	"jira:comment_created":  "comment_created",
+	"jira:comment_updated":  "comment_updated",
+	"jira:comment_deleted":  "comment_deleted",
+	"issue_comment_created": "issue_commented",
+	"issue_comment_updated": "issue_comment_edited",
+	"issue_moved":           "issue_updated",
+}
+
+// legacyWebhookEvents are the issue updates that older Jira Server versions
+// send under their own webhook event, with the issue event type they are
+// parsed as when they have none.
+var legacyWebhookEvents = map[string]string{
+	"jira:issue_generic":   "issue_generic",
+	"jira:worklog_updated": "issue_worklog_updated",
+}
+
+// worklogEventTypes are the issue event types of the changes of the worklogs
+// of an issue.
+var worklogEventTypes = NewStringSet(
+	"issue_work_logged",
+	"issue_worklog_updated",
+	"issue_worklog_deleted",
+)
+
+// worklogFields are the lowercase fields of the changelog items that record
+// a change of the worklogs of an issue, and of the time spent and remaining.
+var worklogFields = NewStringSet(
+	"worklogid",
+	"timespent",
+	"timeestimate",
+	"aggregatetimespent",
+	"aggregatetimeestimate",
+)
+
+// webhookParseOptions are the settings that change how webhooks are parsed.
+type webhookParseOptions struct {
+	// eventAliases rename the webhook events, in addition to
+	// defaultEventAliases.
+	eventAliases map[string]string
+
+	// ignoredFields are the lowercase names or IDs of the fields whose
+	// changes are dropped from issue updates. An update changing only
+	// these fields is ignored.
+	ignoredFields StringSet
+
+	// showVotesAndWatchers adds the votes and watchers counts of the issue
+	// to the attachments of issue events.
+	showVotesAndWatchers bool
+
+	// issueEmoji are shown before the issue types, and the statuses and
+	// priorities of the updates. nil shows no emoji.
+	issueEmoji issueEmoji
+}
+
 func ParseWebhook(bb []byte) (wh Webhook, err error) {
+	return ParseWebhookWithOptions(bb, webhookParseOptions{})
+}
+
+// ParseWebhookWithOptions parses a webhook after renaming its events with the
+// event aliases, and dropping the changes of the ignored fields.
+func ParseWebhookWithOptions(bb []byte, options webhookParseOptions) (wh Webhook, err error) {
 	defer func() {
 		if err == nil || err == ErrWebhookIgnored {
 			return
@@ -37,29 +112,44 @@ func ParseWebhook(bb []byte) (wh Webhook, err error) {
 		err = errors.WithMessagef(err, "Failed to process webhook. Body stored in %s", f.Name())
 	}()
 
-	jwh := &JiraWebhook{}
-	err = json.Unmarshal(bb, &jwh)
+	ev, err := jiraevent.Parse(bb)
 	if err != nil {
 		return nil, err
 	}
+	jwh := &JiraWebhook{Event: *ev, emoji: options.issueEmoji}
+	jwh.resolveEventAliases(options.eventAliases)
 	if jwh.WebhookEvent == "" {
 		return nil, errors.New("No webhook event")
 	}
-	if jwh.Issue.Fields == nil {
+	if !jwh.normalizeLegacyEvent() {
+		return nil, ErrWebhookIgnored
+	}
+	if jwh.Issue.Fields == nil && !nonIssueWebhookEvents.ContainsAny(jwh.WebhookEvent) {
 		return nil, ErrWebhookIgnored
 	}
 
 	switch jwh.WebhookEvent {
+	case "project_created", "project_deleted":
+		wh = parseWebhookProject(jwh)
+	case "user_created", "user_updated", "user_deleted":
+		wh = parseWebhookUser(jwh)
 	case "jira:issue_created":
 		wh = parseWebhookCreated(jwh)
 	case "jira:issue_deleted":
 		wh = parseWebhookDeleted(jwh)
 	case "jira:issue_updated":
+		if !jwh.removeIgnoredChangeLogItems(options.ignoredFields) {
+			return nil, ErrWebhookIgnored
+		}
 		switch jwh.IssueEventTypeName {
 		case "issue_assigned":
 			wh = parseWebhookAssigned(jwh, jwh.ChangeLog.Items[0].FromString, jwh.ChangeLog.Items[0].ToString)
-		case "issue_updated", "issue_generic", "issue_resolved", "issue_closed", "issue_work_started", "issue_reopened":
+		case "issue_updated", "issue_resolved", "issue_closed", "issue_work_started", "issue_reopened":
 			wh = parseWebhookChangeLog(jwh)
+		case "issue_generic":
+			// Sent for the workflow transitions and the changes of older
+			// Jira versions, with a changelog or a comment.
+			wh, err = parseWebhookUnspecified(jwh)
 		case "issue_commented":
 			wh, err = parseWebhookCommentCreated(jwh)
 		case "issue_comment_edited":
@@ -69,6 +159,8 @@ func ParseWebhook(bb []byte) (wh Webhook, err error) {
 		default:
 			wh, err = parseWebhookUnspecified(jwh)
 		}
+	case "issue_property_set":
+		wh = parseWebhookPropertySet(jwh)
 	case "comment_created":
 		wh, err = parseWebhookCommentCreated(jwh)
 	case "comment_updated":
@@ -76,8 +168,7 @@ func ParseWebhook(bb []byte) (wh Webhook, err error) {
 	case "comment_deleted":
 		wh, err = parseWebhookCommentDeleted(jwh)
 	default:
-		err = errors.Errorf("Unsupported webhook event: %v", jwh.WebhookEvent)
-
+		wh = parseWebhookUnrecognized(jwh)
 	}
 	if err != nil {
 		return nil, err
@@ -85,6 +176,11 @@ func ParseWebhook(bb []byte) (wh Webhook, err error) {
 	if wh == nil {
 		return nil, errors.Errorf("Unsupported webhook data: %v", jwh.WebhookEvent)
 	}
+	if options.showVotesAndWatchers {
+		if issueWebhook, ok := wh.(*webhook); ok {
+			issueWebhook.appendVotesAndWatchers()
+		}
+	}
 
 	// For HTTP testing, so we can capture the output of the interface
 	if webhookWrapperFunc != nil {
@@ -106,7 +202,85 @@ func parseWebhookUnspecified(jwh *JiraWebhook) (Webhook, error) {
 		return parseWebhookCommentUpdated(jwh)
 	}
 
-	return nil, errors.Errorf("Unsupported webhook event: %v", jwh.WebhookEvent)
+	return parseWebhookUnrecognized(jwh), nil
+}
+
+// parseWebhookPropertySet renders an issue property set, showing its value.
+func parseWebhookPropertySet(jwh *JiraWebhook) Webhook {
+	if jwh.Property.Key == "" {
+		return nil
+	}
+
+	wh := &webhook{
+		JiraWebhook: jwh,
+		eventTypes:  NewStringSet(eventPropertySet),
+	}
+	wh.setHeadline(msgWebhookPropertySet, jwh.Property.Key, jwh.mdKeySummaryLink())
+	if user := mdUser(&jwh.User); user != "" {
+		wh.setHeadline(msgWebhookPropertySetBy, user, jwh.Property.Key, jwh.mdKeySummaryLink())
+	}
+	if value := propertyValueText(jwh.Property.Value); value != "" {
+		wh.fields = []*model.SlackAttachmentField{{
+			Title: "Value",
+			Value: truncate(value, 300),
+		}}
+	}
+	return wh
+}
+
+func parseWebhookProject(jwh *JiraWebhook) Webhook {
+	if jwh.Project.Key == "" {
+		return nil
+	}
+
+	wh := &webhook{
+		JiraWebhook: jwh,
+		eventTypes:  NewStringSet(eventProjectCreated),
+	}
+	wh.setHeadline(msgWebhookProjectCreated, jwh.mdProjectLink())
+	if jwh.WebhookEvent == "project_deleted" {
+		wh.eventTypes = NewStringSet(eventProjectDeleted)
+		wh.setHeadline(msgWebhookProjectDeleted, jwh.Project.Name, jwh.Project.Key)
+	}
+	if jwh.Project.ProjectLead != nil {
+		wh.fields = []*model.SlackAttachmentField{{
+			Title: "Lead",
+			Value: mdUser(jwh.Project.ProjectLead),
+			Short: true,
+		}}
+	}
+	return wh
+}
+
+func parseWebhookUser(jwh *JiraWebhook) Webhook {
+	if jwh.User.AccountID == "" && jwh.User.Name == "" {
+		return nil
+	}
+
+	eventType, id := eventUserCreated, msgWebhookUserCreated
+	switch jwh.WebhookEvent {
+	case "user_updated":
+		eventType, id = eventUserUpdated, msgWebhookUserUpdated
+	case "user_deleted":
+		eventType, id = eventUserDeleted, msgWebhookUserDeleted
+	}
+	wh := &webhook{
+		JiraWebhook: jwh,
+		eventTypes:  NewStringSet(eventType),
+	}
+	wh.setHeadline(id, jwh.mdUser())
+	return wh
+}
+
+// parseWebhookUnrecognized renders an event type the plugin doesn't know
+// about generically, so that it can still be delivered to subscriptions
+// that opted in to eventUnrecognized.
+func parseWebhookUnrecognized(jwh *JiraWebhook) Webhook {
+	name := jwh.WebhookEvent
+	if jwh.IssueEventTypeName != "" {
+		name = jwh.IssueEventTypeName
+	}
+	return newWebhook(jwh, eventUnrecognized, msgWebhookUnrecognized, name)
 }
 
 func parseWebhookChangeLog(jwh *JiraWebhook) Webhook {
@@ -121,6 +295,10 @@ func parseWebhookChangeLog(jwh *JiraWebhook) Webhook {
 
 		from := item.FromString
 		to := item.ToString
+		if item.FieldType == "custom" || timeTrackingFields.ContainsAny(fieldId) {
+			from = formatFieldValue(fieldId, from)
+			to = formatFieldValue(fieldId, to)
+		}
 		fromWithDefault := from
 		if fromWithDefault == "" {
 			fromWithDefault = "~~None~~"
@@ -164,6 +342,10 @@ func parseWebhookChangeLog(jwh *JiraWebhook) Webhook {
 			event = parseWebhookUpdatedField(jwh, eventUpdatedReporter, field, fieldId, fromWithDefault, toWithDefault)
 		case field == "Component":
 			event = parseWebhookUpdatedField(jwh, eventUpdatedComponents, field, fieldId, fromWithDefault, toWithDefault)
+		case field == "timeoriginalestimate":
+			event = parseWebhookUpdatedField(jwh, eventUpdatedOriginalEstimate, "Original Estimate", fieldId, fromWithDefault, toWithDefault)
+		case field == "Key" && from != "" && to != "":
+			event = parseWebhookMoved(jwh, from, to)
 		case item.FieldType == "custom":
 			eventType := fmt.Sprintf("event_updated_%s", fieldId)
 			event = parseWebhookUpdatedField(jwh, eventType, field, fieldId, fromWithDefault, toWithDefault)
@@ -185,7 +367,7 @@ func parseWebhookChangeLog(jwh *JiraWebhook) Webhook {
 }
 
 func parseWebhookCreated(jwh *JiraWebhook) Webhook {
-	wh := newWebhook(jwh, eventCreated, "**created**")
+	wh := newWebhook(jwh, eventCreated, msgWebhookCreated)
 	wh.text = jwh.mdIssueDescription()
 
 	if jwh.Issue.Fields == nil {
@@ -216,8 +398,36 @@ func parseWebhookCreated(jwh *JiraWebhook) Webhook {
 	return wh
 }
 
+// appendVotesAndWatchers adds the votes and watchers counts of the issue to
+// the fields of issue events, to help prioritizing by impact. Comment events
+// of Jira Cloud don't include them.
+func (wh *webhook) appendVotesAndWatchers() {
+	jwh := wh.JiraWebhook
+	if jwh == nil || jwh.Issue.Fields == nil || nonIssueWebhookEvents.ContainsAny(jwh.WebhookEvent) ||
+		wh.Events().Intersection(commentEvents).Len() > 0 {
+		return
+	}
+
+	if votes, ok := jwh.Issue.Fields.Unknowns["votes"].(map[string]interface{}); ok {
+		if n, ok := votes["votes"].(float64); ok {
+			wh.fields = append(wh.fields, &model.SlackAttachmentField{
+				Title: "Votes",
+				Value: strconv.Itoa(int(n)),
+				Short: true,
+			})
+		}
+	}
+	if jwh.Issue.Fields.Watches != nil {
+		wh.fields = append(wh.fields, &model.SlackAttachmentField{
+			Title: "Watchers",
+			Value: strconv.Itoa(jwh.Issue.Fields.Watches.WatchCount),
+			Short: true,
+		})
+	}
+}
+
 func parseWebhookDeleted(jwh *JiraWebhook) Webhook {
-	wh := newWebhook(jwh, eventDeleted, "**deleted**")
+	wh := newWebhook(jwh, eventDeleted, msgWebhookDeleted)
 	if jwh.Issue.Fields != nil && jwh.Issue.Fields.Resolution == nil {
 		wh.eventTypes = wh.eventTypes.Add(eventDeletedUnresolved)
 	}
@@ -241,42 +451,42 @@ func parseWebhookCommentCreated(jwh *JiraWebhook) (Webhook, error) {
 	wh := &webhook{
 		JiraWebhook: jwh,
 		eventTypes:  NewStringSet(eventCreatedComment),
-		headline:    fmt.Sprintf("%s **commented** on %s", commentAuthor, jwh.mdKeySummaryLink()),
-		text:        truncate(jwh.Comment.Body, 3000),
+		text:        jwh.Comment.Body,
 	}
+	wh.setHeadline(msgWebhookCommented, commentAuthor, jwh.mdKeySummaryLink())
 
-	appendCommentNotifications(wh, "**mentioned** you in a new comment on")
+	appendCommentNotifications(wh, msgNotificationMentionedNewComment)
 
 	return wh, nil
 }
 
-// appendCommentNotifications modifies wh
-func appendCommentNotifications(wh *webhook, verb string) {
+// appendCommentNotifications modifies wh, notifying the mentioned users with
+// the message id.
+func appendCommentNotifications(wh *webhook, mentionedId string) {
 	jwh := wh.JiraWebhook
 	commentAuthor := mdUser(&jwh.Comment.UpdateAuthor)
 
-	message := fmt.Sprintf("%s %s %s:\n>%s",
-		commentAuthor, verb, jwh.mdKeySummaryLink(), jwh.Comment.Body)
+	message := newLocalizedText(mentionedId, commentAuthor, jwh.mdKeySummaryLink(), jwh.Comment.Body)
 
 	assigneeMentioned := false
 
 	for _, u := range parseJIRAUsernamesFromText(wh.Comment.Body) {
-		isAccountId := false
-		if strings.HasPrefix(u, "accountid:") {
-			u = u[10:]
-			isAccountId = true
-		}
-
 		// don't mention the author of the comment
-		if u == jwh.User.Name || u == jwh.User.AccountID {
+		if isJiraUserMentioned(u, &jwh.User) {
 			continue
 		}
 
 		// Avoid duplicated mention for assignee. Boolean value is checked after the loop.
-		if jwh.Issue.Fields.Assignee != nil && (u == jwh.Issue.Fields.Assignee.Name || u == jwh.Issue.Fields.Assignee.AccountID) {
+		if isJiraUserMentioned(u, jwh.Issue.Fields.Assignee) {
 			assigneeMentioned = true
 		}
 
+		isAccountId := false
+		if strings.HasPrefix(u, "accountid:") {
+			u = u[len("accountid:"):]
+			isAccountId = true
+		}
+
 		notification := webhookNotification{
 			message:     message,
 			postType:    PostTypeMention,
@@ -294,16 +504,15 @@ func appendCommentNotifications(wh *webhook, verb string) {
 
 	// Don't send a notification to the assignee if they don't exist, or if are also the author.
 	// Also, if the assignee was mentioned above, avoid sending a duplicate notification here.
-	// Jira Server uses name field, Jira Cloud uses the AccountID field.
-	if assigneeMentioned || jwh.Issue.Fields.Assignee == nil || jwh.Issue.Fields.Assignee.Name == jwh.User.Name ||
-		(jwh.Issue.Fields.Assignee.AccountID != "" && jwh.Issue.Fields.Assignee.AccountID == jwh.Comment.UpdateAuthor.AccountID) {
+	if assigneeMentioned || jwh.Issue.Fields.Assignee == nil || sameJiraUser(jwh.Issue.Fields.Assignee, &jwh.Comment.UpdateAuthor) {
 		return
 	}
 
 	wh.notifications = append(wh.notifications, webhookNotification{
 		jiraUsername:  jwh.Issue.Fields.Assignee.Name,
 		jiraAccountID: jwh.Issue.Fields.Assignee.AccountID,
-		message:       fmt.Sprintf("%s **commented** on %s:\n>%s", commentAuthor, jwh.mdKeySummaryLink(), jwh.Comment.Body),
+		jiraEmail:     jwh.Issue.Fields.Assignee.EmailAddress,
+		message:       newLocalizedText(msgNotificationCommented, commentAuthor, jwh.mdKeySummaryLink(), jwh.Comment.Body),
 		postType:      PostTypeComment,
 		commentSelf:   jwh.Comment.Self,
 	})
@@ -314,22 +523,21 @@ func parseWebhookCommentDeleted(jwh *JiraWebhook) (Webhook, error) {
 		return nil, ErrWebhookIgnored
 	}
 
-	// Jira server vs Jira cloud pass the user info differently
+	// Normalized to the comment author on Jira Cloud
 	user := ""
-	if jwh.User.Key != "" {
+	if hasJiraUserId(&jwh.User) {
 		user = mdUser(&jwh.User)
-	} else if jwh.Comment.UpdateAuthor.Key != "" || jwh.Comment.UpdateAuthor.AccountID != "" {
-		user = mdUser(&jwh.Comment.UpdateAuthor)
 	}
 	if user == "" {
 		return nil, errors.New("No update author found")
 	}
 
-	return &webhook{
+	wh := &webhook{
 		JiraWebhook: jwh,
 		eventTypes:  NewStringSet(eventDeletedComment),
-		headline:    fmt.Sprintf("%s **deleted comment** in %s", user, jwh.mdKeySummaryLink()),
-	}, nil
+	}
+	wh.setHeadline(msgWebhookCommentDeleted, user, jwh.mdKeySummaryLink())
+	return wh, nil
 }
 
 func parseWebhookCommentUpdated(jwh *JiraWebhook) (Webhook, error) {
@@ -340,16 +548,16 @@ func parseWebhookCommentUpdated(jwh *JiraWebhook) (Webhook, error) {
 	wh := &webhook{
 		JiraWebhook: jwh,
 		eventTypes:  NewStringSet(eventUpdatedComment),
-		headline:    fmt.Sprintf("%s **edited comment** in %s", mdUser(&jwh.Comment.UpdateAuthor), jwh.mdKeySummaryLink()),
-		text:        truncate(jwh.Comment.Body, 3000),
+		text:        jwh.Comment.Body,
 	}
+	wh.setHeadline(msgWebhookCommentEdited, mdUser(&jwh.Comment.UpdateAuthor), jwh.mdKeySummaryLink())
 
-	appendCommentNotifications(wh, "**mentioned** you in a comment update on")
+	appendCommentNotifications(wh, msgNotificationMentionedUpdatedComment)
 	return wh, nil
 }
 
 func parseWebhookAssigned(jwh *JiraWebhook, from, to string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedAssignee, "**assigned** %s to", jwh.mdIssueAssignee())
+	wh := newWebhook(jwh, eventUpdatedAssignee, msgWebhookAssigned, jwh.mdIssueAssignee())
 	fromFixed := from
 	if fromFixed == "" {
 		fromFixed = "_nobody_"
@@ -373,38 +581,47 @@ func appendNotificationForAssignee(wh *webhook) {
 	}
 
 	// Don't send a notification to the assignee if they are the one who made the change. (They probably know already.)
-	if (jwh.User.Name != "" && jwh.User.Name == jwh.Issue.Fields.Assignee.Name) ||
-		(jwh.User.AccountID != "" && jwh.User.AccountID == jwh.Issue.Fields.Assignee.AccountID) {
+	if sameJiraUser(&jwh.User, jwh.Issue.Fields.Assignee) {
 		return
 	}
 
 	wh.notifications = append(wh.notifications, webhookNotification{
 		jiraUsername:  jwh.Issue.Fields.Assignee.Name,
 		jiraAccountID: jwh.Issue.Fields.Assignee.AccountID,
-		message:       fmt.Sprintf("%s **assigned** you to %s", jwh.mdUser(), jwh.mdKeySummaryLink()),
+		jiraEmail:     jwh.Issue.Fields.Assignee.EmailAddress,
+		message:       newLocalizedText(msgNotificationAssigned, jwh.mdUser(), jwh.mdKeySummaryLink()),
 	})
 }
 
 func parseWebhookReopened(jwh *JiraWebhook, from string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedReopened, "**reopened**")
+	wh := newWebhook(jwh, eventUpdatedReopened, msgWebhookReopened)
 	wh.fieldInfo = webhookField{"reopened", "resolution", from, "Open"}
 	return wh
 }
 
 func parseWebhookResolved(jwh *JiraWebhook, to string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedResolved, "**resolved**")
+	wh := newWebhook(jwh, eventUpdatedResolved, msgWebhookResolved)
 	wh.fieldInfo = webhookField{"resolved", "resolution", "Open", to}
 	return wh
 }
 
 func parseWebhookUpdatedField(jwh *JiraWebhook, eventType string, field, fieldId, from, to string) *webhook {
-	wh := newWebhook(jwh, eventType, "**updated** %s from %q to %q on", field, from, to)
+	wh := newWebhook(jwh, eventType, msgWebhookUpdatedField, field,
+		jwh.mdFieldValue(fieldId, from), jwh.mdFieldValue(fieldId, to))
 	wh.fieldInfo = webhookField{field, fieldId, from, to}
 	return wh
 }
 
+// parseWebhookMoved renders an issue moved to another project, under its new
+// key.
+func parseWebhookMoved(jwh *JiraWebhook, from, to string) *webhook {
+	wh := newWebhook(jwh, eventUpdatedMoved, msgWebhookMoved, from)
+	wh.fieldInfo = webhookField{"key", "key", from, to}
+	return wh
+}
+
 func parseWebhookUpdatedDescription(jwh *JiraWebhook, from, to string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedDescription, "**edited** the description of")
+	wh := newWebhook(jwh, eventUpdatedDescription, msgWebhookEditedDescription)
 	fromFmttd := "\n**From:** " + truncate(from, 500)
 	toFmttd := "\n**To:** " + truncate(to, 500)
 	wh.fieldInfo = webhookField{"description", "description", fromFmttd, toFmttd}
@@ -413,13 +630,15 @@ func parseWebhookUpdatedDescription(jwh *JiraWebhook, from, to string) *webhook
 }
 
 func parseWebhookUpdatedAttachments(jwh *JiraWebhook, from, to string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedAttachment, mdAddRemove(from, to, "**attached**", "**removed** attachments"))
+	id, args := mdAddRemove(from, to, msgWebhookAttached, msgWebhookRemovedAttachments, msgWebhookAttachedRemovedAttachments)
+	wh := newWebhook(jwh, eventUpdatedAttachment, id, args...)
 	wh.fieldInfo = webhookField{name: "attachments"}
 	return wh
 }
 
 func parseWebhookUpdatedLabels(jwh *JiraWebhook, from, to, fromWithDefault, toWithDefault string) *webhook {
-	wh := newWebhook(jwh, eventUpdatedLabels, mdAddRemove(from, to, "**added** labels", "**removed** labels"))
+	id, args := mdAddRemove(from, to, msgWebhookAddedLabels, msgWebhookRemovedLabels, msgWebhookAddedRemovedLabels)
+	wh := newWebhook(jwh, eventUpdatedLabels, id, args...)
 	wh.fieldInfo = webhookField{"labels", "labels", fromWithDefault, toWithDefault}
 	return wh
 }
@@ -428,9 +647,9 @@ func parseWebhookUpdatedLabels(jwh *JiraWebhook, from, to, fromWithDefault, toWi
 func mergeWebhookEvents(events []*webhook) Webhook {
 	merged := &webhook{
 		JiraWebhook: events[0].JiraWebhook,
-		headline:    events[0].mdUser() + " **updated** " + events[0].mdKeySummaryLink(),
 		eventTypes:  NewStringSet(),
 	}
+	merged.setHeadline(msgWebhookUpdated, events[0].mdUser(), events[0].mdKeySummaryLink())
 
 	for _, event := range events {
 		merged.eventTypes = merged.eventTypes.Union(event.eventTypes)
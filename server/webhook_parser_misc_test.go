@@ -4,13 +4,17 @@
 package main
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
 )
 
 func TestMarkdown(t *testing.T) {
@@ -43,6 +47,10 @@ func TestEventTypeFormat(t *testing.T) {
 		"issue updated commented created":             {"testdata/webhook-server-issue-updated-commented-3.json", "Test User **commented** on improvement"},
 		"issue updated comment edited":                {"testdata/webhook-server-issue-updated-comment-edited.json", "Lev Brouk **edited comment** in story"},
 		"issue updated comment deleted":               {"testdata/webhook-server-issue-updated-comment-deleted.json", "Lev Brouk **deleted comment** in story"},
+		"project created":                             {"testdata/webhook-project-created.json", "Project [New Project (NEW)](https://some-instance-test.atlassian.net/browse/NEW) was **created**"},
+		"project deleted":                             {"testdata/webhook-project-deleted.json", "Project Test Project (TES) was **deleted**"},
+		"user updated":                                {"testdata/webhook-user-updated.json", "Jira user Test User was **updated**"},
+		"unrecognized event":                          {"testdata/webhook-issue-archived-unrecognized.json", "Test User triggered `issue_archived` on story"},
 	} {
 		f, err := os.Open(value.filename)
 		require.NoError(t, err)
@@ -74,7 +82,7 @@ func TestNotificationsFormat(t *testing.T) {
 		w := wh.(*webhook)
 		require.NotNil(t, w)
 		require.NotNil(t, w.notifications)
-		require.Contains(t, w.notifications[0].message, value.expected)
+		require.Contains(t, w.notifications[0].message.String(), value.expected)
 	}
 }
 
@@ -82,11 +90,11 @@ func TestWebhookVariousErrors(t *testing.T) {
 	assert.Equal(t, "", mdUser(nil))
 
 	wh := &webhook{
-		JiraWebhook: &JiraWebhook{
+		JiraWebhook: &JiraWebhook{Event: jiraevent.Event{
 			Issue: jira.Issue{
 				Fields: &jira.IssueFields{},
 			},
-		},
+		}},
 	}
 
 	assert.Equal(t, "", wh.mdJiraLink("test", "/test"))
@@ -109,6 +117,22 @@ func TestTruncate(t *testing.T) {
 	assert.Equal(t, "12345", truncate("12345", -1))
 }
 
+func TestTruncateWithLink(t *testing.T) {
+	link := "[Show more](http://localhost:8080/browse/TES-1)"
+	assert.Equal(t, "12345", truncateWithLink("12345", 5, link))
+	assert.Equal(t, "123...\n\n"+link, truncateWithLink("12345", 3, link))
+	assert.Equal(t, "12...", truncateWithLink("12 345", 3, ""))
+	assert.Equal(t, "äöü...", truncateWithLink("äöüß", 3, ""))
+	assert.Equal(t, "see...", truncateWithLink("see [the docs](https://example.com/docs) first", 20, ""))
+	assert.Equal(t, "see...", truncateWithLink("see [the docs](https://example.com/docs) first", 8, ""))
+	assert.Equal(t, "see [the docs](https://example.com/docs) f...", truncateWithLink("see [the docs](https://example.com/docs) first", 42, ""))
+	assert.Equal(t, "see...", truncateWithLink("see [docs|https://example.com/docs] first", 20, ""))
+	assert.Equal(t, "open...", truncateWithLink("open https://example.com/docs now", 15, ""))
+	assert.Equal(t, "a [b] c...", truncateWithLink("a [b] cde", 7, ""))
+	assert.Equal(t, strings.Repeat("x", defaultMaxTextLength), truncateWithLink(strings.Repeat("x", defaultMaxTextLength), 0, link))
+	assert.Equal(t, strings.Repeat("x", defaultMaxTextLength)+"...", truncateWithLink(strings.Repeat("x", defaultMaxTextLength+1), 0, ""))
+}
+
 func TestJiraLink(t *testing.T) {
 	var jwh JiraWebhook
 	jwh.Issue.Self = "http://localhost:8080/rest/api/2/issue/10006"
@@ -117,3 +141,145 @@ func TestJiraLink(t *testing.T) {
 	jwh.Issue.Self = "http://localhost:8080/foo/bar/rest/api/2/issue/10006"
 	assert.Equal(t, "[1](http://localhost:8080/foo/bar/QWERTY)", jwh.mdJiraLink("1", "/QWERTY"))
 }
+
+func TestResolveEventAliases(t *testing.T) {
+	jwh := &JiraWebhook{Event: jiraevent.Event{WebhookEvent: "jira:comment_created", IssueEventTypeName: "issue_comment_updated"}}
+	jwh.resolveEventAliases(nil)
+	assert.Equal(t, "comment_created", jwh.WebhookEvent)
+	assert.Equal(t, "issue_comment_edited", jwh.IssueEventTypeName)
+
+	jwh = &JiraWebhook{Event: jiraevent.Event{WebhookEvent: "jira:issue_moved", IssueEventTypeName: "issue_moved"}}
+	jwh.resolveEventAliases(map[string]string{
+		"jira:issue_moved": "jira:issue_updated",
+		"issue_moved":      "issue_generic",
+	})
+	assert.Equal(t, "jira:issue_updated", jwh.WebhookEvent)
+	assert.Equal(t, "issue_generic", jwh.IssueEventTypeName)
+
+	jwh = &JiraWebhook{Event: jiraevent.Event{WebhookEvent: "jira:issue_created"}}
+	jwh.resolveEventAliases(map[string]string{"other": "jira:issue_updated"})
+	assert.Equal(t, "jira:issue_created", jwh.WebhookEvent)
+}
+
+func TestParseWebhookLegacyEvents(t *testing.T) {
+	data, err := getJiraTestData("webhook-issue-updated-started-working.json")
+	require.NoError(t, err)
+	expected, err := ParseWebhook(data)
+	require.NoError(t, err)
+
+	worklogItems := []interface{}{
+		map[string]interface{}{"field": "timespent", "fieldtype": "jira", "to": "3600", "toString": "3600"},
+		map[string]interface{}{"field": "WorklogId", "fieldtype": "jira", "to": "10100", "toString": "10100"},
+	}
+	variant := func(webhookEvent, eventType string, withStatus bool) []byte {
+		payload := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(data, &payload))
+		payload["webhookEvent"] = webhookEvent
+		delete(payload, "issue_event_type_name")
+		if eventType != "" {
+			payload["issue_event_type_name"] = eventType
+		}
+		changelog := payload["changelog"].(map[string]interface{})
+		items := changelog["items"].([]interface{})
+		if withStatus {
+			changelog["items"] = append(worklogItems, items...)
+		} else {
+			changelog["items"] = worklogItems
+		}
+		bb, err := json.Marshal(payload)
+		require.NoError(t, err)
+		return bb
+	}
+
+	// Older Jira Server versions send generic events under their own name
+	payload := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	payload["webhookEvent"] = "jira:issue_generic"
+	delete(payload, "issue_event_type_name")
+	generic, err := json.Marshal(payload)
+	require.NoError(t, err)
+	wh, err := ParseWebhook(generic)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Events(), wh.Events())
+	assert.Equal(t, "jira:issue_updated", wh.(*webhook).WebhookEvent)
+	assert.Equal(t, "issue_generic", wh.(*webhook).IssueEventTypeName)
+
+	// A generic event with neither changelog nor comment
+	jwh := &JiraWebhook{Event: jiraevent.Event{WebhookEvent: "jira:issue_generic"}}
+	jwh.Issue.Fields = &jira.IssueFields{Type: jira.IssueType{Name: "Story"}}
+	assert.True(t, jwh.normalizeLegacyEvent())
+	w, err := parseWebhookUnspecified(jwh)
+	require.NoError(t, err)
+	assert.Equal(t, NewStringSet(eventUnrecognized), w.Events())
+
+	// The worklog changes are dropped, in the legacy and current events
+	for name, bb := range map[string][]byte{
+		"legacy worklog event":          variant("jira:worklog_updated", "", true),
+		"work logged with a transition": variant("jira:issue_updated", "issue_work_logged", true),
+		"worklog in an issue update":    variant("jira:issue_updated", "issue_updated", true),
+	} {
+		t.Run(name, func(t *testing.T) {
+			wh, err := ParseWebhook(bb)
+			require.NoError(t, err)
+			assert.Equal(t, expected.Events(), wh.Events())
+			assert.Equal(t, expected.(*webhook).headline, wh.(*webhook).headline)
+			assert.Len(t, wh.(*webhook).ChangeLog.Items, 1)
+		})
+	}
+
+	// Only the worklogs changed
+	_, err = ParseWebhook(variant("jira:worklog_updated", "", false))
+	assert.Equal(t, ErrWebhookIgnored, err)
+	_, err = ParseWebhook(variant("jira:issue_updated", "issue_worklog_deleted", false))
+	assert.Equal(t, ErrWebhookIgnored, err)
+}
+
+func TestParseWebhookIgnoredFields(t *testing.T) {
+	options := webhookParseOptions{ignoredFields: NewStringSet("rank", "customfield_10072")}
+
+	data, err := getJiraTestData("webhook-issue-updated-rank.json")
+	require.NoError(t, err)
+	_, err = ParseWebhookWithOptions(data, options)
+	assert.Equal(t, ErrWebhookIgnored, err)
+
+	wh, err := ParseWebhook(data)
+	require.NoError(t, err)
+	assert.True(t, wh.Events().ContainsAny(eventUpdatedRank))
+
+	data, err = getJiraTestData("webhook-issue-updated-multiple-values.json")
+	require.NoError(t, err)
+	wh, err = ParseWebhookWithOptions(data, options)
+	require.NoError(t, err)
+	assert.True(t, wh.Events().ContainsAll(eventUpdatedFixVersion, eventUpdatedAssignee))
+	assert.False(t, wh.Events().ContainsAny("event_updated_customfield_10072"))
+}
+
+func TestParseWebhookVotesAndWatchers(t *testing.T) {
+	options := webhookParseOptions{showVotesAndWatchers: true}
+	data, err := getJiraTestData("webhook-issue-created.json")
+	require.NoError(t, err)
+	data = []byte(strings.Replace(strings.Replace(string(data),
+		`"votes": 0`, `"votes": 4`, 1),
+		`"watchCount": 0`, `"watchCount": 7`, 1))
+
+	wh, err := ParseWebhookWithOptions(data, options)
+	require.NoError(t, err)
+	fields := wh.(*webhook).fields
+	require.True(t, len(fields) >= 2)
+	assert.Equal(t, "Votes", fields[len(fields)-2].Title)
+	assert.Equal(t, "4", fields[len(fields)-2].Value)
+	assert.Equal(t, "Watchers", fields[len(fields)-1].Title)
+	assert.Equal(t, "7", fields[len(fields)-1].Value)
+
+	wh, err = ParseWebhook(data)
+	require.NoError(t, err)
+	for _, field := range wh.(*webhook).fields {
+		assert.NotEqual(t, "Votes", field.Title)
+	}
+
+	data, err = getJiraTestData("webhook-cloud-comment-created.json")
+	require.NoError(t, err)
+	wh, err = ParseWebhookWithOptions(data, options)
+	require.NoError(t, err)
+	assert.Empty(t, wh.(*webhook).fields, "comment events don't show the counts")
+}
@@ -0,0 +1,307 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// The webhook payloads of the replay window are stored in their own keys,
+// indexed by the buckets of the hours they were received in. Both expire
+// after the replay window. The payloads of an hour are spread over
+// webhookReplayBucketShards buckets, so that the webhook requests received
+// at once don't all modify the same key.
+const (
+	prefixWebhookReplayBucket  = "webhook_replay_h_"
+	prefixWebhookReplayPayload = "webhook_replay_p_"
+	webhookReplayLockKey       = "webhook_replay_lock"
+
+	webhookReplayBucketShards = 16
+
+	// A replay holds the lock until it is done, or for at most
+	// webhookReplayLockExpiry if the server stops meanwhile. Another replay
+	// retries the lock until then, since a replay posts to all the
+	// subscribed channels.
+	webhookReplayLockExpiry = time.Hour
+	webhookReplayLockRetry  = 5 * time.Second
+
+	webhookReplayDryRunFlag = "--dry-run"
+)
+
+// webhookReplayBucket lists the webhook payloads received during an hour, in
+// one of its shards. Payloads beyond the share of the shard of the hour of
+// WebhookReplayMaxSize are dropped.
+type webhookReplayBucket struct {
+	Payloads []webhookReplayPayload `json:"payloads"`
+	Size     int                    `json:"size"`
+	Dropped  int                    `json:"dropped,omitempty"`
+}
+
+type webhookReplayPayload struct {
+	Key        string    `json:"key"`
+	ReceivedAt time.Time `json:"received_at"`
+	InstanceId string    `json:"instance_id,omitempty"`
+	DeliveryId string    `json:"delivery_id,omitempty"`
+}
+
+// webhookReplayBucketKey returns the key of a shard of the bucket of an hour.
+// The first shard is at the key of the buckets before they were sharded.
+func webhookReplayBucketKey(hour time.Time, shard int) string {
+	key := prefixWebhookReplayBucket + strconv.FormatInt(hour.Unix(), 10)
+	if shard > 0 {
+		key += "_" + strconv.Itoa(shard)
+	}
+	return key
+}
+
+func webhookReplayPayloadKey(receivedAt time.Time) string {
+	return prefixWebhookReplayPayload + strconv.FormatInt(receivedAt.UnixNano(), 36) + "_" + model.NewId()[:8]
+}
+
+// storeWebhookPayload keeps an accepted webhook payload for the replay
+// window, if it is enabled.
+func (p *Plugin) storeWebhookPayload(receivedAt time.Time, msg webhookMessage) error {
+	conf := p.getConfig()
+	if conf.webhookReplayWindow <= 0 {
+		return nil
+	}
+	// The window spans the buckets of its hours, and of the current one
+	hours := int(conf.webhookReplayWindow/time.Hour) + 1
+	maxBucketSize := int(conf.webhookReplayMaxSize) / hours / webhookReplayBucketShards
+	shard := int(receivedAt.UnixNano() % webhookReplayBucketShards)
+	expiry := int64((conf.webhookReplayWindow + time.Hour) / time.Second)
+
+	payload := webhookReplayPayload{
+		Key:        webhookReplayPayloadKey(receivedAt),
+		ReceivedAt: receivedAt,
+		InstanceId: msg.instanceId,
+		DeliveryId: msg.deliveryId,
+	}
+	stored := false
+	err := p.atomicModifyWithExpiry(webhookReplayBucketKey(receivedAt.Truncate(time.Hour), shard), expiry, func(initial []byte) ([]byte, error) {
+		bucket := webhookReplayBucket{}
+		if len(initial) > 0 {
+			err := json.Unmarshal(initial, &bucket)
+			if err != nil {
+				return nil, err
+			}
+		}
+		stored = bucket.Size+len(msg.data) <= maxBucketSize
+		if stored {
+			bucket.Payloads = append(bucket.Payloads, payload)
+			bucket.Size += len(msg.data)
+		} else {
+			bucket.Dropped++
+		}
+		return json.Marshal(bucket)
+	})
+	if err != nil || !stored {
+		return err
+	}
+
+	appErr := p.API.KVSetWithExpiry(payload.Key, msg.data, expiry)
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// atomicModifyWithExpiry is atomicModify for the keys that expire, the
+// expiry being renewed by each modification.
+func (p *Plugin) atomicModifyWithExpiry(key string, expireInSeconds int64, modify func(initialValue []byte) ([]byte, error)) error {
+	for attempt := 0; ; attempt++ {
+		initial, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to read inital value")
+		}
+		modified, err := modify(initial)
+		if err != nil {
+			return errors.Wrap(err, "modification error")
+		}
+		ok, appErr := p.API.KVSetWithOptions(key, modified, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        initial,
+			ExpireInSeconds: expireInSeconds,
+		})
+		if appErr != nil {
+			return errors.Wrap(appErr, "problem writing value")
+		}
+		if ok {
+			return nil
+		}
+		if attempt >= 5 {
+			return errors.New("reached write attempt limit")
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+}
+
+// loadWebhookReplayPayloads returns the payloads kept since a time, oldest
+// first, and how many were dropped because the replay window was full.
+func (p *Plugin) loadWebhookReplayPayloads(since time.Time) ([]webhookReplayPayload, int, error) {
+	payloads := []webhookReplayPayload{}
+	dropped := 0
+	for hour := since.Truncate(time.Hour); !hour.After(time.Now()); hour = hour.Add(time.Hour) {
+		for shard := 0; shard < webhookReplayBucketShards; shard++ {
+			data, appErr := p.API.KVGet(webhookReplayBucketKey(hour, shard))
+			if appErr != nil {
+				return nil, 0, appErr
+			}
+			if len(data) == 0 {
+				continue
+			}
+			bucket := webhookReplayBucket{}
+			err := json.Unmarshal(data, &bucket)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, payload := range bucket.Payloads {
+				if !payload.ReceivedAt.Before(since) {
+					payloads = append(payloads, payload)
+				}
+			}
+			dropped += bucket.Dropped
+		}
+	}
+	sort.SliceStable(payloads, func(i, j int) bool {
+		return payloads[i].ReceivedAt.Before(payloads[j].ReceivedAt)
+	})
+	return payloads, dropped, nil
+}
+
+// claimWebhookReplay returns true if this replay acquired the replay lock,
+// across the servers of a cluster.
+func (p *Plugin) claimWebhookReplay() bool {
+	ok, appErr := p.API.KVSetWithOptions(webhookReplayLockKey, []byte(time.Now().UTC().Format(time.RFC3339)),
+		model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        nil,
+			ExpireInSeconds: int64(webhookReplayLockExpiry.Seconds()),
+		})
+	if appErr != nil {
+		p.errorf("claimWebhookReplay: failed to acquire the replay lock: %v", appErr)
+		return false
+	}
+	return ok
+}
+
+// replayWebhookPayloads processes the kept payloads again, through the
+// current subscriptions and templates, and tells the admin how it went. The
+// replays run one at a time, even when started in different channels, so
+// that the events are not posted twice to the channels they have in common.
+func (p *Plugin) replayWebhookPayloads(header *model.CommandArgs, payloads []webhookReplayPayload, claimed bool) {
+	for waited := time.Duration(0); !claimed; waited += webhookReplayLockRetry {
+		if waited >= webhookReplayLockExpiry {
+			p.API.SendEphemeralPost(header.UserId, &model.Post{
+				UserId:    p.getUserID(),
+				ChannelId: header.ChannelId,
+				Message:   "The webhook events were not replayed, because another replay did not finish.",
+			})
+			return
+		}
+		time.Sleep(webhookReplayLockRetry)
+		claimed = p.claimWebhookReplay()
+	}
+	defer func() {
+		appErr := p.API.KVDelete(webhookReplayLockKey)
+		if appErr != nil {
+			p.errorf("replayWebhookPayloads: failed to release the replay lock: %v", appErr)
+		}
+	}()
+
+	ww := webhookWorker{id: -1, p: p}
+	replayed, missing, failed := 0, 0, 0
+	for _, payload := range payloads {
+		data, appErr := p.API.KVGet(payload.Key)
+		if appErr != nil || len(data) == 0 {
+			missing++
+			continue
+		}
+		err := ww.process(data, payload.InstanceId, payload.DeliveryId)
+		if err != nil {
+			p.errorf("replayWebhookPayloads: failed to process the webhook event received at %v: %v", payload.ReceivedAt, err)
+			failed++
+			continue
+		}
+		replayed++
+	}
+
+	message := fmt.Sprintf("Replayed %d webhook events.", replayed)
+	if failed > 0 {
+		message += fmt.Sprintf(" %d failed, see the server logs.", failed)
+	}
+	if missing > 0 {
+		message += fmt.Sprintf(" %d were no longer available.", missing)
+	}
+	p.API.SendEphemeralPost(header.UserId, &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: header.ChannelId,
+		Message:   message,
+	})
+}
+
+func executeWebhookReplay(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira webhook replay` can only be run by a system administrator.")
+	}
+	dryRun := false
+	if len(args) == 2 && args[1] == webhookReplayDryRunFlag {
+		dryRun = true
+		args = args[:1]
+	}
+	if len(args) != 1 {
+		return p.help(header)
+	}
+	window := p.getConfig().webhookReplayWindow
+	if window <= 0 {
+		return p.responsef(header, "The webhook events are not kept for replays. Please set **Webhook Replay Window (Hours)** in the plugin settings.")
+	}
+	duration, err := time.ParseDuration(strings.ToLower(args[0]))
+	if err != nil || duration <= 0 {
+		return p.responsef(header, "Please give the duration to replay the events of, like `2h` or `90m`.")
+	}
+	if duration > window {
+		return p.responsef(header, "The webhook events are only kept for %v.", window)
+	}
+
+	since := time.Now().Add(-duration)
+	payloads, dropped, err := p.loadWebhookReplayPayloads(since)
+	if err != nil {
+		return p.responsef(header, "Failed to load the webhook events: %v", err)
+	}
+	if len(payloads) == 0 {
+		return p.responsef(header, "No webhook events were received since %s.", since.UTC().Format(time.RFC1123))
+	}
+
+	message := fmt.Sprintf("%d webhook events were received since %s.", len(payloads), since.UTC().Format(time.RFC1123))
+	if dropped > 0 {
+		message += fmt.Sprintf(" %d more were not kept, because the replay window was full.", dropped)
+	}
+	if dryRun {
+		return p.responsef(header, "%s Run the command without `%s` to replay them.", message, webhookReplayDryRunFlag)
+	}
+
+	claimed := p.claimWebhookReplay()
+	go p.replayWebhookPayloads(header, payloads, claimed)
+	when := ""
+	if !claimed {
+		when = " once the replay already running is done"
+	}
+	return p.responsef(header, "%s They will be replayed through the current subscriptions%s, "+
+		"so their notifications are posted again, including those that were already posted.", message, when)
+}
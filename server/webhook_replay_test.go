@@ -0,0 +1,101 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+func TestWebhookReplayPayloads(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if !bytes.Equal(kv[key], options.OldValue) {
+				return false
+			}
+			kv[key] = value
+			return true
+		}, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(3*3600)).Run(func(args mock.Arguments) {
+		kv[args.String(0)] = args.Get(1).([]byte)
+	}).Return(nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	// Disabled
+	received := time.Now().Truncate(time.Hour).Add(-30 * time.Minute)
+	require.NoError(t, p.storeWebhookPayload(received, webhookMessage{data: []byte("event")}))
+	assert.Empty(t, kv)
+
+	// 3 hourly buckets of 10 bytes per shard. The events received at
+	// nanoseconds multiple of the number of shards are in the first one.
+	p.updateConfig(func(conf *config) {
+		conf.webhookReplayWindow = 2 * time.Hour
+		conf.webhookReplayMaxSize = utils.ByteSize(30 * webhookReplayBucketShards)
+	})
+	require.NoError(t, p.storeWebhookPayload(received.Add(-3*time.Hour), webhookMessage{data: []byte("old")}))
+	require.NoError(t, p.storeWebhookPayload(received, webhookMessage{instanceId: "https://jira", deliveryId: "d1", data: []byte("event1")}))
+	// Dropped from the full bucket
+	require.NoError(t, p.storeWebhookPayload(received.Add(time.Minute), webhookMessage{data: []byte("event2")}))
+
+	payloads, dropped, err := p.loadWebhookReplayPayloads(received.Add(-10 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "https://jira", payloads[0].InstanceId)
+	assert.Equal(t, "d1", payloads[0].DeliveryId)
+	assert.Equal(t, []byte("event1"), kv[payloads[0].Key])
+	assert.Equal(t, 1, dropped)
+
+	// Another shard of the hour
+	require.NoError(t, p.storeWebhookPayload(received.Add(time.Minute+time.Nanosecond), webhookMessage{data: []byte("event3")}))
+	_, ok := kv[webhookReplayBucketKey(received.Truncate(time.Hour), 1)]
+	assert.True(t, ok)
+	payloads, dropped, err = p.loadWebhookReplayPayloads(received.Add(-10 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, []byte("event3"), kv[payloads[1].Key])
+	assert.Equal(t, 1, dropped)
+}
+
+func TestClaimWebhookReplay(t *testing.T) {
+	kv := map[string][]byte{}
+	api := &plugintest.API{}
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if !bytes.Equal(kv[key], options.OldValue) {
+				return false
+			}
+			kv[key] = value
+			return true
+		}, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		delete(kv, args.String(0))
+	}).Return(nil)
+	var message string
+	api.On("SendEphemeralPost", "userId", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		message = args.Get(1).(*model.Post).Message
+	}).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	assert.True(t, p.claimWebhookReplay())
+	assert.False(t, p.claimWebhookReplay(), "a replay is running, in any channel")
+
+	p.replayWebhookPayloads(&model.CommandArgs{UserId: "userId", ChannelId: "channel1"}, nil, true)
+	assert.Equal(t, "Replayed 0 webhook events.", message)
+	assert.True(t, p.claimWebhookReplay(), "the lock is released once the replay is done")
+}
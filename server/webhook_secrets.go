@@ -0,0 +1,257 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// keyWebhookSecrets stores the webhook secrets of the Jira instances, by
+// instance URL, encrypted like the other credentials. Unlike the Secret
+// setting, shared by all the instances, an instance secret identifies the
+// instance a webhook event comes from.
+const keyWebhookSecrets = "webhook_secrets"
+
+const webhookSecretLength = 32
+
+// webhookMessage is a webhook request waiting to be processed. InstanceId
+// is the URL of the Jira instance whose secret the request used, or empty
+// if it used the Secret setting.
+type webhookMessage struct {
+	instanceId string
+	deliveryId string
+	data       []byte
+}
+
+// loadWebhookSecrets returns the decrypted webhook secrets by instance URL.
+// The secrets that can't be decrypted with the configured keys are left out,
+// so that the other instances keep receiving their events.
+func (p *Plugin) loadWebhookSecrets() (map[string]string, error) {
+	secrets := map[string]string{}
+	data, appErr := p.API.KVGet(keyWebhookSecrets)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(data) == 0 {
+		return secrets, nil
+	}
+	stored := map[string]string{}
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, err
+	}
+	credentials := p.getConfig().credentials
+	for url, value := range stored {
+		secret, _, err := credentials.decrypt(value)
+		if err != nil {
+			p.errorf("loadWebhookSecrets: failed to decrypt the webhook secret of %s: %v", url, err)
+			continue
+		}
+		secrets[url] = secret
+	}
+	return secrets, nil
+}
+
+// modifyWebhookSecrets applies modify to the decrypted webhook secrets, and
+// stores the changed ones, and those not encrypted with the current key,
+// encrypted with the current key. It fails if a secret can't be decrypted,
+// rather than dropping it.
+func (p *Plugin) modifyWebhookSecrets(modify func(secrets map[string]string)) error {
+	credentials := p.getConfig().credentials
+	return p.atomicModify(keyWebhookSecrets, func(initialBytes []byte) ([]byte, error) {
+		stored := map[string]string{}
+		if len(initialBytes) != 0 {
+			err := json.Unmarshal(initialBytes, &stored)
+			if err != nil {
+				return nil, err
+			}
+		}
+		secrets, current := map[string]string{}, map[string]string{}
+		for url, value := range stored {
+			secret, stale, err := credentials.decrypt(value)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to decrypt the webhook secret of "+url)
+			}
+			secrets[url] = secret
+			if !stale {
+				current[url] = secret
+			}
+		}
+
+		modify(secrets)
+
+		for url, secret := range secrets {
+			if s, ok := current[url]; ok && s == secret {
+				continue
+			}
+			value, err := credentials.encrypt(secret)
+			if err != nil {
+				return nil, err
+			}
+			stored[url] = value
+		}
+		for url := range stored {
+			if _, ok := secrets[url]; !ok {
+				delete(stored, url)
+			}
+		}
+		return json.Marshal(stored)
+	})
+}
+
+// ensureInstanceWebhookSecret returns the webhook secret of the instance,
+// generating it if it has none yet, or if regenerate is set.
+func (p *Plugin) ensureInstanceWebhookSecret(ji Instance, regenerate bool) (string, error) {
+	var secret string
+	err := p.modifyWebhookSecrets(func(secrets map[string]string) {
+		secret = secrets[ji.GetURL()]
+		if secret == "" || regenerate {
+			secret = model.NewRandomString(webhookSecretLength)
+			secrets[ji.GetURL()] = secret
+		}
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to store the webhook secret")
+	}
+	return secret, nil
+}
+
+func (p *Plugin) deleteInstanceWebhookSecret(ji Instance) error {
+	return p.modifyWebhookSecrets(func(secrets map[string]string) {
+		delete(secrets, ji.GetURL())
+	})
+}
+
+// resolveWebhookInstance returns the URL of the instance whose secret the
+// webhook request used, or "" for the Secret setting.
+func (p *Plugin) resolveWebhookInstance(secret string) (string, int, error) {
+	conf := p.getConfig()
+	if conf.Secret != "" {
+		if _, err := verifyHTTPSecret(conf.Secret, secret); err == nil {
+			return "", http.StatusOK, nil
+		}
+	}
+
+	secrets, err := p.loadWebhookSecrets()
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	if conf.Secret == "" && len(secrets) == 0 {
+		return "", http.StatusForbidden, errors.New("JIRA plugin not configured correctly; must provide Secret")
+	}
+	urls := []string{}
+	for url := range secrets {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		if _, err := verifyHTTPSecret(secrets[url], secret); err == nil {
+			return url, http.StatusOK, nil
+		}
+	}
+	return "", http.StatusForbidden, errors.New("Request URL: secret did not match")
+}
+
+// instanceWebhookURL returns the subscriptions webhook URL with the secret of
+// an instance, to be copied into Jira as is.
+func (p *Plugin) instanceWebhookURL(secret string) string {
+	v := url.Values{}
+	v.Add("secret", secret)
+	return p.GetPluginURL() + routeAPISubscribeWebhook + "?" + v.Encode()
+}
+
+// httpAPIWebhookSecret responds with the subscriptions webhook URL of the
+// current instance on GET, empty if it has no secret yet, or generates a new
+// secret on POST, which requires the CSRF token.
+func httpAPIWebhookSecret(p *Plugin, w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed,
+			errors.New("method " + r.Method + " is not allowed, must be GET or POST")
+	}
+	mattermostUserId := r.Header.Get("Mattermost-User-Id")
+	authorized, err := authorizedSysAdmin(p, mattermostUserId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !authorized {
+		return http.StatusForbidden, errors.New("only a system administrator can manage the webhook secret")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	regenerate := r.Method == http.MethodPost
+	var secret string
+	if regenerate {
+		secret, err = p.ensureInstanceWebhookSecret(ji, true)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		p.infof("httpAPIWebhookSecret: the webhook secret of %s was regenerated by user %s", ji.GetURL(), mattermostUserId)
+	} else {
+		secrets, err := p.loadWebhookSecrets()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		secret = secrets[ji.GetURL()]
+	}
+
+	resp := struct {
+		InstanceURL string `json:"instance_url"`
+		WebhookURL  string `json:"webhook_url"`
+		Regenerated bool   `json:"regenerated"`
+	}{
+		InstanceURL: ji.GetURL(),
+		Regenerated: regenerate,
+	}
+	if secret != "" {
+		resp.WebhookURL = p.instanceWebhookURL(secret)
+	}
+	b, _ := json.Marshal(resp)
+	_, err = w.Write(b)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+// executeWebhookInstance responds with the subscriptions webhook URL of the
+// current instance, using its own secret.
+func executeWebhookInstance(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira webhook` can only be run by a system administrator.")
+	}
+	regenerate := len(args) == 1 && args[0] == "regenerate"
+	if len(args) != 0 && !regenerate {
+		return p.responsef(header, "Please use `/jira webhook instance [regenerate]`.")
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	secret, err := p.ensureInstanceWebhookSecret(ji, regenerate)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	u := p.instanceWebhookURL(secret)
+	if regenerate {
+		return p.responsef(header, "The webhook secret of %s was regenerated. Please update the webhook in Jira to use the following URL: %s", ji.GetURL(), u)
+	}
+	return p.responsef(header, "Please use the following URL for the webhook of %s in Jira, so that its events are only posted to the subscriptions of this instance: %s", ji.GetURL(), u)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveWebhookInstance(t *testing.T) {
+	stored, err := json.Marshal(map[string]string{
+		"https://one.atlassian.net": "secretone",
+		"https://two.example.com":   "secret/two",
+	})
+	require.NoError(t, err)
+	api := &plugintest.API{}
+	api.On("KVGet", keyWebhookSecrets).Return(func(string) []byte { return stored }, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	for name, tc := range map[string]struct {
+		configSecret string
+		secret       string
+		instanceId   string
+		status       int
+	}{
+		"config secret":           {configSecret: "shared", secret: "shared", status: http.StatusOK},
+		"instance secret":         {configSecret: "shared", secret: "secretone", instanceId: "https://one.atlassian.net", status: http.StatusOK},
+		"escaped instance secret": {secret: "secret%2Ftwo", instanceId: "https://two.example.com", status: http.StatusOK},
+		"wrong secret":            {configSecret: "shared", secret: "other", status: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p.updateConfig(func(conf *config) {
+				conf.Secret = tc.configSecret
+			})
+			instanceId, status, err := p.resolveWebhookInstance(tc.secret)
+			assert.Equal(t, tc.status, status)
+			assert.Equal(t, tc.instanceId, instanceId)
+			assert.Equal(t, tc.status != http.StatusOK, err != nil)
+		})
+	}
+
+	stored = nil
+	p.updateConfig(func(conf *config) {
+		conf.Secret = ""
+	})
+	_, status, err := p.resolveWebhookInstance("")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, err.Error(), "must provide Secret")
+}
+
+func TestEnsureInstanceWebhookSecret(t *testing.T) {
+	var stored []byte
+	api := &plugintest.API{}
+	api.On("KVGet", keyWebhookSecrets).Return(func(string) []byte { return stored }, nil)
+	api.On("KVCompareAndSet", keyWebhookSecrets, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+	ji := &jiraTestInstance{JIRAInstance: *NewJIRAInstance(p, "test", "jiraTestInstanceKey")}
+
+	secret, err := p.ensureInstanceWebhookSecret(ji, false)
+	require.NoError(t, err)
+	assert.Len(t, secret, webhookSecretLength)
+
+	again, err := p.ensureInstanceWebhookSecret(ji, false)
+	require.NoError(t, err)
+	assert.Equal(t, secret, again)
+
+	regenerated, err := p.ensureInstanceWebhookSecret(ji, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, regenerated)
+
+	// The secrets are encrypted once there is an encryption key, and read
+	// with the previous keys.
+	p.updateConfig(func(conf *config) {
+		conf.credentials = newCredentialCipher("a long enough encryption key", nil)
+	})
+	again, err = p.ensureInstanceWebhookSecret(ji, false)
+	require.NoError(t, err)
+	assert.Equal(t, regenerated, again)
+	assert.NotContains(t, string(stored), regenerated)
+	assert.Contains(t, string(stored), encryptedCredentialPrefix)
+
+	p.updateConfig(func(conf *config) {
+		conf.credentials = newCredentialCipher("a new encryption key", []string{"a long enough encryption key"})
+	})
+	secrets, err := p.loadWebhookSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, regenerated, secrets[ji.GetURL()])
+
+	p.updateConfig(func(conf *config) {
+		conf.credentials = newCredentialCipher("another encryption key", nil)
+	})
+	_, err = p.ensureInstanceWebhookSecret(ji, false)
+	assert.Error(t, err, "the secrets are not overwritten when they can't be decrypted")
+	p.updateConfig(func(conf *config) {
+		conf.credentials = newCredentialCipher("a new encryption key", []string{"a long enough encryption key"})
+	})
+
+	require.NoError(t, p.deleteInstanceWebhookSecret(ji))
+	secrets, err = p.loadWebhookSecrets()
+	require.NoError(t, err)
+	assert.Empty(t, secrets)
+}
+
+func TestHTTPAPIWebhookSecret(t *testing.T) {
+	var stored []byte
+	api := &plugintest.API{}
+	api.On("KVGet", keyWebhookSecrets).Return(func(string) []byte { return stored }, nil)
+	api.On("KVCompareAndSet", keyWebhookSecrets, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("GetUser", "admin").Return(&model.User{Id: "admin", Roles: "system_admin system_user"}, nil)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: "system_user"}, nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("https://mm.example.com")}})
+	api.On("LogInfo", mock.AnythingOfType("string")).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.currentInstanceStore = mockCurrentInstanceStore{p}
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	require.NoError(t, err)
+
+	request := func(method, userId string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, routeAPIWebhookSecret, nil)
+		r.Header.Set("Mattermost-User-Id", userId)
+		status, err := httpAPIWebhookSecret(p, w, r)
+		if err != nil {
+			return status, nil
+		}
+		resp := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return status, resp
+	}
+
+	assert.True(t, isCSRFProtectedRoute(routeAPIWebhookSecret), "POST requires the CSRF token")
+
+	status, _ := request(http.MethodGet, "user")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Nil(t, stored)
+
+	status, resp := request(http.MethodGet, "admin")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "", resp["webhook_url"])
+	assert.Nil(t, stored, "GET doesn't generate the secret")
+
+	status, resp = request(http.MethodPost, "admin")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, resp["regenerated"])
+	secrets, err := p.loadWebhookSecrets()
+	require.NoError(t, err)
+	secret := secrets[ji.GetURL()]
+	require.NotEmpty(t, secret)
+	assert.Equal(t, "https://mm.example.com/plugins/jira/api/v2/webhook?secret="+secret, resp["webhook_url"])
+
+	status, resp = request(http.MethodGet, "admin")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "https://mm.example.com/plugins/jira/api/v2/webhook?secret="+secret, resp["webhook_url"])
+	assert.Equal(t, false, resp["regenerated"])
+
+	status, resp = request(http.MethodPost, "admin")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, resp["regenerated"])
+	assert.NotContains(t, resp["webhook_url"], secret)
+}
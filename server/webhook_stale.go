@@ -0,0 +1,43 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+)
+
+// webhookEventAge returns how long before now Jira sent the webhook event,
+// from the timestamp of its payload, or false if it has none.
+func webhookEventAge(data []byte, now time.Time) (time.Duration, bool) {
+	payload := struct {
+		Timestamp int64 `json:"timestamp"`
+	}{}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Timestamp <= 0 {
+		return 0, false
+	}
+	return now.Sub(time.Unix(0, payload.Timestamp*int64(time.Millisecond))), true
+}
+
+// isStaleWebhookEvent returns true if the webhook event was sent by Jira
+// longer ago than the WebhookMaxEventAgeMinutes setting, like the retries
+// Jira sends after an outage, and counts it in the stats. The events without
+// a timestamp are never stale.
+func (p *Plugin) isStaleWebhookEvent(data []byte, now time.Time) bool {
+	conf := p.getConfig()
+	if conf.webhookMaxEventAge == 0 {
+		return false
+	}
+	age, ok := webhookEventAge(data, now)
+	if !ok || age <= conf.webhookMaxEventAge {
+		return false
+	}
+	p.debugf("isStaleWebhookEvent: dropping a webhook event sent %v ago", age.Round(time.Second))
+	if conf.stats != nil {
+		conf.stats.EnsureEndpoint("jira/webhook/stale").Record(utils.ByteSize(len(data)), 0, 0, false, true)
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIsStaleWebhookEvent(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mock.AnythingOfType("string")).Return(nil)
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	sent := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	data := []byte(`{"timestamp": 1577977445000, "webhookEvent": "jira:issue_created"}`)
+	age, ok := webhookEventAge(data, sent.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, age)
+
+	assert.False(t, p.isStaleWebhookEvent(data, sent.Add(24*time.Hour)), "events are never stale without the setting")
+
+	p.updateConfig(func(conf *config) {
+		conf.webhookMaxEventAge = 30 * time.Minute
+	})
+	assert.False(t, p.isStaleWebhookEvent(data, sent.Add(10*time.Minute)))
+	assert.True(t, p.isStaleWebhookEvent(data, sent.Add(31*time.Minute)))
+	assert.False(t, p.isStaleWebhookEvent([]byte(`{"webhookEvent": "jira:issue_created"}`), sent.Add(time.Hour)), "events without a timestamp are never stale")
+	assert.False(t, p.isStaleWebhookEvent([]byte(`not json`), sent.Add(time.Hour)))
+}
@@ -4,20 +4,100 @@
 package main
 
 import (
+	"encoding/json"
+	"hash/fnv"
+	"sync"
 	"time"
 
 	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+	"github.com/mattermost/mattermost-server/v5/model"
 )
 
+// webhookQueues are the queues of the webhook workers, one per worker. All the
+// events of an issue go to the same worker, which processes them one at a
+// time, so that the channels and threads see them in the order Jira sent
+// them. The events without an issue all go to the first worker.
+type webhookQueues []chan webhookMessage
+
+func newWebhookQueues(nworkers, size int) webhookQueues {
+	queueSize := size / nworkers
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	queues := make(webhookQueues, nworkers)
+	for i := range queues {
+		queues[i] = make(chan webhookMessage, queueSize)
+	}
+	return queues
+}
+
+// enqueue adds the webhook event to the queue of the worker of its issue,
+// or returns false if that queue is full.
+func (queues webhookQueues) enqueue(msg webhookMessage) bool {
+	select {
+	case queues[queues.index(msg.data)] <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (queues webhookQueues) index(data []byte) int {
+	issueId := webhookIssueId(data)
+	if issueId == "" {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(issueId))
+	return int(h.Sum32() % uint32(len(queues)))
+}
+
+// len returns the number of webhook events waiting in all the queues.
+func (queues webhookQueues) len() int {
+	n := 0
+	for _, queue := range queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// cap returns the number of webhook events all the queues can hold.
+func (queues webhookQueues) cap() int {
+	n := 0
+	for _, queue := range queues {
+		n += cap(queue)
+	}
+	return n
+}
+
+// webhookIssueId returns the id of the issue of the webhook event, or its
+// key if it has no id, or empty if the event is not about an issue. The id
+// doesn't change when the issue is moved to another project.
+func webhookIssueId(data []byte) string {
+	payload := struct {
+		Issue struct {
+			Id  string `json:"id"`
+			Key string `json:"key"`
+		} `json:"issue"`
+	}{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+	if payload.Issue.Id != "" {
+		return payload.Issue.Id
+	}
+	return payload.Issue.Key
+}
+
 type webhookWorker struct {
 	id        int
 	p         *Plugin
-	workQueue <-chan []byte
+	workQueue <-chan webhookMessage
 }
 
 func (ww webhookWorker) work() {
-	for rawData := range ww.workQueue {
-		err := ww.process(rawData)
+	for msg := range ww.workQueue {
+		err := ww.process(msg.data, msg.instanceId, msg.deliveryId)
 		if err != nil {
 			ww.p.errorf("WebhookWorker id: %d, error processing, err: %v", ww.id, err)
 		}
@@ -25,7 +105,7 @@ func (ww webhookWorker) work() {
 	}
 }
 
-func (ww webhookWorker) process(rawData []byte) (err error) {
+func (ww webhookWorker) process(rawData []byte, instanceId, deliveryId string) (err error) {
 	conf := ww.p.getConfig()
 	start := time.Now()
 	defer func() {
@@ -46,10 +126,35 @@ func (ww webhookWorker) process(rawData []byte) (err error) {
 		}
 	}()
 
-	wh, err := ParseWebhook(rawData)
+	wh, err := ParseWebhookWithOptions(rawData, conf.webhookParseOptions)
 	if err != nil {
 		return err
 	}
+	wh.(*webhook).instanceId = instanceId
+	wh.(*webhook).deliveryId = deliveryId
+	wh.(*webhook).conf = &conf
+
+	// Only the subscriptions of the current instance exist, the events of
+	// the other instances are not posted
+	if instanceId != "" {
+		ji, loadErr := ww.p.currentInstanceStore.LoadCurrentJIRAInstance()
+		if loadErr != nil || ji.GetURL() != instanceId {
+			ww.p.debugf("WebhookWorker id: %d, ignoring an event of Jira instance %s, which is not the current instance", ww.id, instanceId)
+			return ErrWebhookIgnored
+		}
+	}
+
+	if wh.Events().ContainsAny(eventUnrecognized) {
+		jwh := wh.(*webhook).JiraWebhook
+		ww.p.debugf("WebhookWorker id: %d, unrecognized webhook event %q (%q)", ww.id, jwh.WebhookEvent, jwh.IssueEventTypeName)
+		if conf.stats != nil {
+			conf.stats.EnsureEndpoint("jira/subscribe/unrecognized").Record(utils.ByteSize(len(rawData)), 0, 0, false, false)
+		}
+	}
+
+	if wh.Events().Intersection(userEvents).Len() > 0 {
+		return ww.p.handleUserLifecycleEvent(wh.(*webhook))
+	}
 
 	if _, _, err = wh.PostNotifications(ww.p); err != nil {
 		ww.p.errorf("WebhookWorker id: %d, error posting notifications, err: %v", ww.id, err)
@@ -58,17 +163,84 @@ func (ww webhookWorker) process(rawData []byte) (err error) {
 	if err = wh.(*webhook).JiraWebhook.expandIssue(ww.p); err != nil {
 		return err
 	}
+	if err = ww.p.updateMovedIssueReferences(wh.(*webhook)); err != nil {
+		ww.p.errorf("WebhookWorker id: %d, error updating the references to a moved issue, err: %v", ww.id, err)
+	}
+	ww.p.recordRecentEvent(start, rawData, wh.(*webhook))
 
-	channelIds, err := ww.p.getChannelsSubscribed(wh.(*webhook))
+	if err = ww.p.flagSubscriptionsForProjectEvent(wh.(*webhook)); err != nil {
+		ww.p.errorf("WebhookWorker id: %d, error flagging subscriptions, err: %v", ww.id, err)
+	}
+
+	subscriptionIds, stubSubscriptionIds, err := ww.p.getChannelSubscriptionIds(wh.(*webhook))
+	if err != nil {
+		return err
+	}
+	channelIds, stubChannelIds := channelIdSet(subscriptionIds), channelIdSet(stubSubscriptionIds)
+	mentions, err := ww.p.getChannelMentions(wh.(*webhook))
 	if err != nil {
 		return err
 	}
-	botUserId := ww.p.getUserID()
+	digestChannelIds, err := ww.p.getDigestChannels(wh.(*webhook))
+	if err != nil {
+		return err
+	}
+	hidingChannelIds, err := ww.p.getAuthorHidingChannels(wh.(*webhook))
+	if err != nil {
+		return err
+	}
+	for _, channelId := range digestChannelIds.Elems() {
+		digestWebhook := *wh.(*webhook)
+		if hidingChannelIds.ContainsAny(channelId) {
+			digestWebhook = digestWebhook.withHiddenAuthor(ww.p.localize(ww.p.channelLocale(channelId), msgAnonymousJiraUser))
+		}
+		if err = ww.p.addToUpdatesDigest(channelId, &digestWebhook); err != nil {
+			ww.p.errorf("WebhookWorker id: %d, error adding to the updates digest of channel %s, err: %v", ww.id, channelId, err)
+		}
+	}
+	channelIds = channelIds.Subtract(digestChannelIds.Elems()...)
+
+	posts := []webhookPost{}
 	for _, channelId := range channelIds.Elems() {
-		if _, _, err1 := wh.PostToChannel(ww.p, channelId, botUserId); err1 != nil {
-			ww.p.errorf("WebhookWorker id: %d, error posting to channel, err: %v", ww.id, err)
+		channelWebhook := *wh.(*webhook)
+		channelWebhook.mentions = mentions[channelId]
+		channelWebhook.subscriptionId = subscriptionIds[channelId]
+		posts = append(posts, webhookPost{wh: channelWebhook, channelId: channelId})
+	}
+	for _, channelId := range stubChannelIds.Elems() {
+		stub := wh.(*webhook).restrictedCommentStub(ww.p, ww.p.channelLocale(channelId))
+		stub.subscriptionId = stubSubscriptionIds[channelId]
+		posts = append(posts, webhookPost{wh: *stub, channelId: channelId})
+	}
+
+	threadSubs, err := ww.p.getThreadsSubscribed(wh.(*webhook))
+	if err != nil {
+		ww.p.errorf("WebhookWorker id: %d, error loading thread subscriptions, err: %v", ww.id, err)
+	}
+	for _, sub := range threadSubs {
+		threadWebhook := *wh.(*webhook)
+		threadWebhook.subscriptionId = sub.Id
+		posts = append(posts, webhookPost{wh: threadWebhook, channelId: sub.ChannelId, rootId: sub.RootId})
+	}
+
+	posts = ww.p.threadCommentActivity(wh.(*webhook), posts)
+	for i := range posts {
+		if hidingChannelIds.ContainsAny(posts[i].channelId) {
+			posts[i].wh = posts[i].wh.withHiddenAuthor(ww.p.localize(ww.p.channelLocale(posts[i].channelId), msgAnonymousJiraUser))
 		}
 	}
+	posts = ww.p.rerouteBlockedPosts(wh.(*webhook), posts)
+	created := ww.postAll(posts, ww.p.getUserID())
+	ww.p.rememberCommentPosts(wh.(*webhook), created)
+	if wh.Events().ContainsAny(eventCreated) {
+		ww.p.rememberIssuePosts(&wh.(*webhook).Issue, created)
+	}
+	ww.p.cleanupDeletedIssue(wh.(*webhook))
+	if err = ww.p.forwardEvent(wh.(*webhook), rawData); err != nil {
+		ww.p.errorf("WebhookWorker id: %d, error forwarding the event, err: %v", ww.id, err)
+	}
+
+	ww.p.refreshChannelStatusesForWebhook(channelIds.Union(stubChannelIds).Union(digestChannelIds))
 
 	if err := ww.p.NotifyWorkflow(wh.(*webhook)); err != nil {
 		ww.p.errorf("WebhookWorker id: %d, error notifying workflow, err: %v", ww.id, err)
@@ -76,3 +248,73 @@ func (ww webhookWorker) process(rawData []byte) (err error) {
 
 	return nil
 }
+
+// maxParallelWebhookPosts bounds the number of channels that a webhook event
+// is posted to concurrently.
+const maxParallelWebhookPosts = 8
+
+// webhookPost is a post of a webhook event to a channel, or to a thread if
+// rootId is set.
+type webhookPost struct {
+	wh        webhook
+	channelId string
+	rootId    string
+}
+
+// postAll creates the posts, in parallel across channels but in order within
+// each channel, and returns the created posts. A failed post is logged and
+// does not prevent the others.
+func (ww webhookWorker) postAll(posts []webhookPost, fromUserId string) []*model.Post {
+	byChannel := map[string][]webhookPost{}
+	channelIds := []string{}
+	for _, post := range posts {
+		if _, ok := byChannel[post.channelId]; !ok {
+			channelIds = append(channelIds, post.channelId)
+		}
+		byChannel[post.channelId] = append(byChannel[post.channelId], post)
+	}
+
+	queue := make(chan []webhookPost, len(channelIds))
+	for _, channelId := range channelIds {
+		queue <- byChannel[channelId]
+	}
+	close(queue)
+
+	nworkers := maxParallelWebhookPosts
+	if len(channelIds) < nworkers {
+		nworkers = len(channelIds)
+	}
+	created := []*model.Post{}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(nworkers)
+	for i := 0; i < nworkers; i++ {
+		go func() {
+			defer wg.Done()
+			for channelPosts := range queue {
+				for _, post := range channelPosts {
+					start := time.Now()
+					mmPost, _, err := post.wh.postToThread(ww.p, post.channelId, post.rootId, fromUserId)
+					ww.recordPost(start, err)
+					if err != nil {
+						ww.p.errorf("WebhookWorker id: %d, error posting to channel %s, err: %v", ww.id, post.channelId, err)
+						continue
+					}
+					lock.Lock()
+					created = append(created, mmPost)
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return created
+}
+
+// recordPost records the outcome of posting a webhook event to a channel or
+// thread, so that post failures show in the stats and metrics.
+func (ww webhookWorker) recordPost(start time.Time, err error) {
+	if stats := ww.p.getConfig().stats; stats != nil {
+		stats.EnsureEndpoint("jira/subscribe/post").Record(0, 0, time.Since(start), err != nil, false)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/jiraevent"
+)
+
+func TestWebhookWorkerPostAll(t *testing.T) {
+	api := &plugintest.API{}
+	lock := sync.Mutex{}
+	posted := map[string][]string{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(
+		func(post *model.Post) *model.Post {
+			lock.Lock()
+			defer lock.Unlock()
+			posted[post.ChannelId] = append(posted[post.ChannelId], post.Message)
+			return post
+		},
+		func(post *model.Post) *model.AppError {
+			if post.ChannelId == "failing" {
+				return model.NewAppError("CreatePost", "id", nil, "failed", http.StatusInternalServerError)
+			}
+			return nil
+		})
+	api.On("LogError", mock.AnythingOfTypeArgument("string")).Return(nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {})
+
+	posts := []webhookPost{}
+	expected := map[string][]string{}
+	for i := 0; i < 3*maxParallelWebhookPosts; i++ {
+		channelId := fmt.Sprintf("channel%d", i%(2*maxParallelWebhookPosts))
+		if i == 5 {
+			channelId = "failing"
+		}
+		message := fmt.Sprintf("event %d", i)
+		posts = append(posts, webhookPost{wh: webhook{headline: message}, channelId: channelId})
+		expected[channelId] = append(expected[channelId], message)
+	}
+
+	ww := webhookWorker{p: p}
+	ww.postAll(posts, "botUserId")
+
+	assert.Equal(t, expected, posted)
+	api.AssertNumberOfCalls(t, "LogError", 1)
+}
+
+func TestWebhookQueuesByIssue(t *testing.T) {
+	queues := newWebhookQueues(4, 40)
+	assert.Equal(t, 40, queues.cap())
+
+	event := func(issue string, i int) webhookMessage {
+		return webhookMessage{data: []byte(fmt.Sprintf(`{"webhookEvent":"jira:issue_updated","timestamp":%d,%s}`, i, issue))}
+	}
+	issues := []string{`"issue":{"id":"10001","key":"TEST-1"}`, `"issue":{"id":"10002","key":"TEST-2"}`, `"issue":{"key":"TEST-3"}`, `"user":{"name":"jdoe"}`}
+	expected := map[int][]webhookMessage{}
+	for i := 0; i < 5; i++ {
+		for _, issue := range issues {
+			msg := event(issue, i)
+			assert.True(t, queues.enqueue(msg))
+			index := queues.index(msg.data)
+			expected[index] = append(expected[index], msg)
+		}
+	}
+	assert.Equal(t, 0, queues.index(event(issues[3], 0).data))
+	assert.Equal(t, 20, queues.len())
+
+	for index, msgs := range expected {
+		for _, msg := range msgs {
+			assert.Equal(t, msg, <-queues[index])
+		}
+	}
+	assert.Equal(t, 0, queues.len())
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, queues.enqueue(event(issues[0], i)))
+	}
+	assert.False(t, queues.enqueue(event(issues[0], 10)))
+}
+
+func TestWebhookEventProps(t *testing.T) {
+	wh := webhook{
+		JiraWebhook:    &JiraWebhook{Event: jiraevent.Event{WebhookEvent: "jira:issue_updated"}},
+		eventTypes:     NewStringSet(eventUpdatedStatus, eventUpdatedAssignee),
+		deliveryId:     "delivery1",
+		subscriptionId: "sub1",
+	}
+	post := &model.Post{}
+	wh.addEventProps(post)
+	assert.Equal(t, "jira:issue_updated", post.Props[postPropEvent])
+	assert.Equal(t, []string{eventUpdatedAssignee, eventUpdatedStatus}, post.Props[postPropEventTypes])
+	assert.Equal(t, "delivery1", post.Props[postPropDeliveryId])
+	assert.Equal(t, "sub1", post.Props[postPropSubscriptionId])
+
+	post = &model.Post{}
+	webhook{headline: "test"}.addEventProps(post)
+	assert.Empty(t, post.Props)
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	assert.True(t, model.IsValidId(webhookDeliveryId(r)))
+	r.Header.Set(headerWebhookIdentifier, "1234")
+	assert.Equal(t, "1234", webhookDeliveryId(r))
+}
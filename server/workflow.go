@@ -45,6 +45,11 @@ func (t *TriggerStore) ForEach(forEach func(string, WorkflowTrigger)) {
 }
 
 func (p *Plugin) NotifyWorkflow(wh *webhook) error {
+	// Workflows are triggered by issue events only
+	if wh.Issue.Fields == nil {
+		return nil
+	}
+
 	activateParams := workflowclient.ActivateParameters{
 		TriggerVars: map[string]string{
 			"Summary":     wh.Issue.Fields.Summary,
@@ -0,0 +1,119 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const worklogPostFlag = "--post"
+
+// reWorklogDuration matches one part of a Jira duration, like "2h" or "1.5d".
+var reWorklogDuration = regexp.MustCompile(`^[[:digit:]]+(\.[[:digit:]]+)?[wdhm]$`)
+
+func executeLogWork(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	issueKey, timeSpent, comment, post, err := parseWorklogArgs(args)
+	if err != nil {
+		return p.responsef(header, "%v Please use `/jira log <issue-key> <time spent> [comment] [--post]`, e.g. `/jira log PROJ-123 2h 30m \"fixed flaky test\"`.", err)
+	}
+
+	ji, err := p.currentInstanceStore.LoadCurrentJIRAInstance()
+	if err != nil {
+		p.errorf("executeLogWork: failed to load current Jira instance: %v", err)
+		return p.responseT(header, msgInstanceLoadFailed)
+	}
+	jiraUser, err := p.userStore.LoadJIRAUser(ji, header.UserId)
+	if err != nil {
+		return p.responseT(header, msgNotConnected)
+	}
+	err = p.requireWriteAccess(header.UserId, jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	client, err := ji.GetClient(jiraUser)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	err = p.requireWriteAllowed(header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	_, err = client.AddWorklog(issueKey, &jira.WorklogRecord{
+		TimeSpent: timeSpent,
+		Comment:   markdownToWikiMarkup(comment),
+	})
+	if err != nil {
+		return p.responsef(header, "Failed to log work on %s: %v", issueKey, err)
+	}
+
+	issueLink := fmt.Sprintf("[%s](%s/browse/%s)", issueKey, ji.GetURL(), issueKey)
+	if post {
+		who := ""
+		if user, appErr := p.API.GetUser(header.UserId); appErr == nil {
+			who = "@" + user.Username
+		}
+		message := strings.TrimSpace(p.localize(p.channelLocale(header.ChannelId), msgWorklogLogged, who, timeSpent, issueLink))
+		if comment != "" {
+			message += ": " + comment
+		}
+		_, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.getUserID(),
+			ChannelId: header.ChannelId,
+			RootId:    header.RootId,
+			Message:   message,
+		})
+		if appErr != nil {
+			return p.responsef(header, "Logged %s on %s, but failed to post it to the channel: %v", timeSpent, issueLink, appErr)
+		}
+	}
+
+	return p.responsef(header, "Logged %s on %s.", timeSpent, issueLink)
+}
+
+// parseWorklogArgs splits the arguments of `/jira log` into the issue key,
+// the time spent, which may have several parts like "1h 30m", the comment
+// without its enclosing quotes, and whether the --post flag is set.
+func parseWorklogArgs(args []string) (issueKey, timeSpent, comment string, post bool, err error) {
+	rest := []string{}
+	for _, arg := range args {
+		if arg == worklogPostFlag {
+			post = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	if len(rest) < 2 {
+		return "", "", "", false, errors.New("Please specify an issue key and the time spent.")
+	}
+
+	issueKey = strings.ToUpper(rest[0])
+	if !reJiraIssueKeyLoose.MatchString(issueKey) {
+		return "", "", "", false, errors.Errorf("%q is not a valid issue key.", rest[0])
+	}
+
+	durations := []string{}
+	rest = rest[1:]
+	for len(rest) > 0 && reWorklogDuration.MatchString(strings.ToLower(rest[0])) {
+		durations = append(durations, strings.ToLower(rest[0]))
+		rest = rest[1:]
+	}
+	if len(durations) == 0 {
+		return "", "", "", false, errors.Errorf("%q is not a valid time spent.", rest[0])
+	}
+
+	comment = strings.Join(rest, " ")
+	if len(comment) >= 2 && comment[0] == '"' && comment[len(comment)-1] == '"' {
+		comment = comment[1 : len(comment)-1]
+	}
+	return issueKey, strings.Join(durations, " "), comment, post, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorklogArgs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args      []string
+		issueKey  string
+		timeSpent string
+		comment   string
+		post      bool
+		expectErr bool
+	}{
+		"no comment": {
+			args:      []string{"proj-123", "2h"},
+			issueKey:  "PROJ-123",
+			timeSpent: "2h",
+		},
+		"quoted comment": {
+			args:      []string{"PROJ-123", "2h", `"fixed`, "flaky", `test"`},
+			issueKey:  "PROJ-123",
+			timeSpent: "2h",
+			comment:   "fixed flaky test",
+		},
+		"several durations and post": {
+			args:      []string{"PROJ-123", "1D", "2h", "30m", "--post", "pairing"},
+			issueKey:  "PROJ-123",
+			timeSpent: "1d 2h 30m",
+			comment:   "pairing",
+			post:      true,
+		},
+		"fractional duration": {
+			args:      []string{"PROJ-123", "1.5h"},
+			issueKey:  "PROJ-123",
+			timeSpent: "1.5h",
+		},
+		"missing time spent": {
+			args:      []string{"PROJ-123"},
+			expectErr: true,
+		},
+		"invalid time spent": {
+			args:      []string{"PROJ-123", "two", "hours"},
+			expectErr: true,
+		},
+		"invalid issue key": {
+			args:      []string{"PROJ", "2h"},
+			expectErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issueKey, timeSpent, comment, post, err := parseWorklogArgs(tc.args)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.issueKey, issueKey)
+			assert.Equal(t, tc.timeSpent, timeSpent)
+			assert.Equal(t, tc.comment, comment)
+			assert.Equal(t, tc.post, post)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The changes a user makes in Jira through the plugin are limited to
+// MaxJiraWritesPerMinute within writeLimitWindow.
+const (
+	writeLimitWindow              = time.Minute
+	defaultMaxJiraWritesPerMinute = 30
+)
+
+// userWriteLimits records when the users last changed Jira, to limit the
+// changes per user on this server. The limit is soft: each server of a
+// cluster counts the changes made through it.
+type userWriteLimits struct {
+	lock   sync.Mutex
+	writes map[string][]time.Time
+}
+
+// allow records a change of the user, and returns 0, or how long to wait if
+// the user made max changes within writeLimitWindow. A max of 0 allows all
+// the changes.
+func (l *userWriteLimits) allow(mattermostUserId string, max int, now time.Time) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.writes == nil {
+		l.writes = map[string][]time.Time{}
+	}
+	recent := []time.Time{}
+	for _, t := range l.writes[mattermostUserId] {
+		if now.Sub(t) < writeLimitWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= max {
+		l.writes[mattermostUserId] = recent
+		return writeLimitWindow - now.Sub(recent[len(recent)-max])
+	}
+	l.writes[mattermostUserId] = append(recent, now)
+
+	// Forget the users who haven't changed Jira recently
+	for userId, writes := range l.writes {
+		if len(writes) > 0 && now.Sub(writes[len(writes)-1]) >= writeLimitWindow {
+			delete(l.writes, userId)
+		}
+	}
+	return 0
+}
+
+// requireWriteAllowed returns an error if the user made too many changes in
+// Jira within writeLimitWindow, and otherwise records the change. Call it
+// right before sending each change to Jira, so that the reads and the
+// requests changing nothing are not counted.
+func (p *Plugin) requireWriteAllowed(mattermostUserId string) error {
+	wait := p.writeLimits.allow(mattermostUserId, p.getConfig().maxJiraWritesPerMinute, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	return errors.Errorf("You are making changes in Jira too quickly. Please wait %d seconds and try again.",
+		int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See License for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserWriteLimits(t *testing.T) {
+	limits := userWriteLimits{}
+	now := time.Now()
+
+	assert.Equal(t, time.Duration(0), limits.allow("user1", 2, now))
+	assert.Equal(t, time.Duration(0), limits.allow("user1", 2, now.Add(10*time.Second)))
+	assert.Equal(t, 50*time.Second, limits.allow("user1", 2, now.Add(10*time.Second)))
+	// Other users have their own limit
+	assert.Equal(t, time.Duration(0), limits.allow("user2", 2, now.Add(10*time.Second)))
+
+	// The first change left the window
+	assert.Equal(t, time.Duration(0), limits.allow("user1", 2, now.Add(writeLimitWindow)))
+	assert.Equal(t, 10*time.Second, limits.allow("user1", 2, now.Add(writeLimitWindow)))
+
+	// No limit
+	assert.Equal(t, time.Duration(0), limits.allow("user1", 0, now.Add(writeLimitWindow)))
+
+	// user2 is forgotten once out of the window
+	assert.Equal(t, time.Duration(0), limits.allow("user1", 3, now.Add(2*writeLimitWindow)))
+	_, ok := limits.writes["user2"]
+	assert.False(t, ok)
+}
+
+func TestRequireWriteAllowed(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: model.SYSTEM_USER_ROLE_ID}, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	p.updateConfig(func(conf *config) {
+		conf.maxJiraWritesPerMinute = 1
+	})
+
+	// Checking the access doesn't count as a change
+	assert.NoError(t, p.requireWriteAccess("user", JIRAUser{}))
+	assert.NoError(t, p.requireWriteAccess("user", JIRAUser{}))
+
+	assert.NoError(t, p.requireWriteAllowed("user"))
+	err := p.requireWriteAllowed("user")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "You are making changes in Jira too quickly. Please wait 60 seconds")
+	}
+}
@@ -95,6 +95,21 @@ export const fetchJiraProjectMetadata = () => {
     };
 };
 
+export const fetchCreateDefaults = (channelId) => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/create-defaults${buildQueryString({channel_id: channelId})}`, {
+                method: 'get',
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
 export const searchIssues = (params) => {
     return async (dispatch, getState) => {
         const url = getPluginServerRoute(getState()) + '/api/v2/get-search-issues';
@@ -102,6 +117,22 @@ export const searchIssues = (params) => {
     };
 };
 
+export const getIssuePreview = (issueKey) => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        const query = buildQueryString({issue_key: issueKey, channel_id: getCurrentChannelId(getState())});
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/issue-preview${query}`, {
+                method: 'get',
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
 export const createIssue = (payload) => {
     return async (dispatch, getState) => {
         const baseUrl = getPluginServerRoute(getState());
@@ -133,6 +164,22 @@ export const attachCommentToIssue = (payload) => {
     };
 };
 
+export const subscribeThreadToIssue = (postId) => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/subscribe-thread`, {
+                method: 'post',
+                body: JSON.stringify({post_id: postId}),
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
 export const createChannelSubscription = (subscription) => {
     return async (dispatch, getState) => {
         const baseUrl = getPluginServerRoute(getState());
@@ -195,12 +242,58 @@ export const deleteChannelSubscription = (subscription) => {
     };
 };
 
+export const dryRunChannelSubscription = (subscription) => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/subscriptions/dry-run`, {
+                method: 'post',
+                body: JSON.stringify(subscription),
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
+export const fetchWebhookSecret = () => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/webhook-secret`, {
+                method: 'get',
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
+export const regenerateWebhookSecret = () => {
+    return async (dispatch, getState) => {
+        const baseUrl = getPluginServerRoute(getState());
+        try {
+            const data = await doFetch(`${baseUrl}/api/v2/webhook-secret`, {
+                method: 'post',
+            });
+
+            return {data};
+        } catch (error) {
+            return {error};
+        }
+    };
+};
+
 export const fetchChannelSubscriptions = (channelId) => {
     return async (dispatch, getState) => {
         const baseUrl = getPluginServerRoute(getState());
         let data = null;
         try {
-            data = await doFetch(`${baseUrl}/api/v2/subscriptions/channel/${channelId}`, {
+            data = await doFetch(`${baseUrl}/api/v2/subscriptions/channel/${channelId}?counts=true`, {
                 method: 'get',
             });
         } catch (error) {
@@ -292,6 +385,23 @@ export const closeChannelSettings = () => {
     };
 };
 
+export function handleHelpAction(store) {
+    return (msg) => {
+        if (!msg.data) {
+            return;
+        }
+
+        switch (msg.data.action) {
+        case 'subscribe':
+            store.dispatch(openChannelSettings(msg.data.channel_id));
+            break;
+        case 'create':
+            store.dispatch(openCreateModalWithoutPost('', msg.data.channel_id));
+            break;
+        }
+    };
+}
+
 export function handleInstanceStatusChange(store) {
     return (msg) => {
         // Update the user's UI state when the instance state changes
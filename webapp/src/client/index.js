@@ -10,7 +10,31 @@ export const doFetch = async (url, options) => {
     return data;
 };
 
+const csrfTokenHeader = 'X-Jira-CSRF-Token';
+const csrfTokens = {};
+
+// getCSRFToken returns the token that the plugin's state-changing endpoints
+// require, fetched once per plugin route.
+const getCSRFToken = async (url) => {
+    const baseUrl = url.substring(0, url.indexOf('/api/v2/'));
+    if (!csrfTokens[baseUrl]) {
+        const response = await fetch(`${baseUrl}/api/v2/csrf-token`, Client4.getOptions({}));
+        if (!response.ok) {
+            return '';
+        }
+        const data = await response.json();
+        csrfTokens[baseUrl] = data.token;
+    }
+    return csrfTokens[baseUrl];
+};
+
 export const doFetchWithResponse = async (url, options = {}) => {
+    const method = (options.method || 'get').toLowerCase();
+    if (method !== 'get' && url.includes('/api/v2/')) {
+        const token = await getCSRFToken(url);
+        options = {...options, headers: {...options.headers, [csrfTokenHeader]: token}};
+    }
+
     const response = await fetch(url, Client4.getOptions(options));
 
     let data;
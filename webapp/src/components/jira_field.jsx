@@ -106,6 +106,64 @@ export default class JiraField extends React.Component {
             );
         }
 
+        if (field.schema.type === 'number') {
+            return (
+                <Input
+                    key={this.props.id}
+                    id={this.props.id}
+                    label={field.name}
+                    type='number'
+                    onChange={this.props.onChange}
+                    required={this.props.obeyRequired && field.required}
+                    value={this.props.value}
+                    addValidate={this.props.addValidate}
+                    removeValidate={this.props.removeValidate}
+                />
+            );
+        }
+
+        // labels are entered separated by spaces, and sent as an array
+        if (field.schema.type === 'array' && field.schema.items === 'string' && !(field.allowedValues && field.allowedValues.length)) {
+            return (
+                <Input
+                    key={this.props.id}
+                    id={this.props.id}
+                    label={field.name}
+                    type='input'
+                    placeholder='Separate labels with spaces'
+                    onChange={(id, val) => this.props.onChange(id, val.split(' ').filter(Boolean))}
+                    required={this.props.obeyRequired && field.required}
+                    value={(this.props.value || []).join(' ')}
+                    addValidate={this.props.addValidate}
+                    removeValidate={this.props.removeValidate}
+                />
+            );
+        }
+
+        // multi-value fields with allowedValues, like components and multi-select
+        // custom fields, have an array of objects as props.value
+        if (field.allowedValues && field.allowedValues.length && field.schema.type === 'array') {
+            const options = field.allowedValues.map(this.makeReactSelectValue);
+            const selected = (this.props.value || []).map((v) => v.id);
+
+            return (
+                <ReactSelectSetting
+                    key={this.props.id}
+                    name={this.props.id}
+                    label={field.name}
+                    options={options}
+                    required={this.props.obeyRequired && field.required}
+                    onChange={(id, vals) => this.props.onChange(id, (vals || []).map((val) => ({id: val})))}
+                    isMulti={true}
+                    value={options.filter((option) => selected.includes(option.value))}
+                    theme={this.props.theme}
+                    components={{Option: JiraField.IconOption}}
+                    addValidate={this.props.addValidate}
+                    removeValidate={this.props.removeValidate}
+                />
+            );
+        }
+
         // if this.props.field has allowedValues, then props.value will be an object
         if (field.allowedValues && field.allowedValues.length && field.schema.type !== 'array') {
             const options = field.allowedValues.map(this.makeReactSelectValue);
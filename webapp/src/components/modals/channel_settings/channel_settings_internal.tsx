@@ -15,18 +15,19 @@ export default class ChannelSettingsModalInner extends React.PureComponent<Share
     state = {
         creatingSubscription: false,
         selectedSubscription: null,
+        warnings: [] as string[],
     };
 
     showEditChannelSubscription = (subscription: ChannelSubscription): void => {
-        this.setState({selectedSubscription: subscription, creatingSubscription: false});
+        this.setState({selectedSubscription: subscription, creatingSubscription: false, warnings: []});
     };
 
     showCreateChannelSubscription = (): void => {
-        this.setState({selectedSubscription: null, creatingSubscription: true});
+        this.setState({selectedSubscription: null, creatingSubscription: true, warnings: []});
     };
 
-    finishEditSubscription = (): void => {
-        this.setState({selectedSubscription: null, creatingSubscription: false});
+    finishEditSubscription = (warnings?: string[]): void => {
+        this.setState({selectedSubscription: null, creatingSubscription: false, warnings: warnings || []});
     };
 
     handleBack = (): void => {
@@ -37,7 +38,7 @@ export default class ChannelSettingsModalInner extends React.PureComponent<Share
     };
 
     render(): JSX.Element {
-        const {selectedSubscription, creatingSubscription} = this.state;
+        const {selectedSubscription, creatingSubscription, warnings} = this.state;
 
         let form;
         if (selectedSubscription || creatingSubscription) {
@@ -55,6 +56,7 @@ export default class ChannelSettingsModalInner extends React.PureComponent<Share
                     {...this.props}
                     showEditChannelSubscription={this.showEditChannelSubscription}
                     showCreateChannelSubscription={this.showCreateChannelSubscription}
+                    warnings={warnings}
                 />
             );
         }
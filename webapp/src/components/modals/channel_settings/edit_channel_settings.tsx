@@ -49,10 +49,13 @@ const JiraEventOptions: ReactSelectOption[] = [
     {value: 'event_updated_status', label: 'Issue Updated: Status'},
     {value: 'event_updated_summary', label: 'Issue Updated: Summary'},
     {value: 'event_updated_components', label: 'Issue Updated: Components'},
+    {value: 'event_updated_moved', label: 'Issue Updated: Moved'},
+    {value: 'event_property_set', label: 'Issue Property Set'},
+    {value: 'event_unrecognized', label: 'Other Events (not otherwise supported)'},
 ];
 
 export type Props = SharedProps & {
-    finishEditSubscription: () => void;
+    finishEditSubscription: (warnings?: string[]) => void;
     selectedSubscription: ChannelSubscription | null;
     creatingSubscription: boolean;
 };
@@ -284,12 +287,13 @@ export default class EditChannelSettings extends PureComponent<Props, State> {
 
         if (this.props.selectedSubscription) {
             subscription.id = this.props.selectedSubscription.id;
+            subscription.version = this.props.selectedSubscription.version;
             this.props.editChannelSubscription(subscription).then((edited) => {
                 if (edited.error) {
                     this.setState({error: edited.error.message, submitting: false});
                     return;
                 }
-                this.handleClose(e);
+                this.props.finishEditSubscription(edited.data && edited.data.warnings);
             });
         } else {
             this.props.createChannelSubscription(subscription).then((created) => {
@@ -297,7 +301,7 @@ export default class EditChannelSettings extends PureComponent<Props, State> {
                     this.setState({error: created.error.message, submitting: false});
                     return;
                 }
-                this.handleClose(e);
+                this.props.finishEditSubscription(created.data && created.data.warnings);
             });
         }
     };
@@ -9,6 +9,7 @@ import {SharedProps} from './shared_props';
 type Props = SharedProps & {
     showEditChannelSubscription: (subscription: ChannelSubscription) => void;
     showCreateChannelSubscription: () => void;
+    warnings?: string[];
 };
 
 type State = {
@@ -64,10 +65,21 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
             >
                 <td>
                     <span>{sub.name || '(no name)'}</span>
+                    {sub.non_compliant && (
+                        <span
+                            className='light'
+                            title={sub.non_compliant}
+                        >
+                            {' (not posting: ' + sub.non_compliant + ')'}
+                        </span>
+                    )}
                 </td>
                 <td>
                     <span>{projectName}</span>
                 </td>
+                <td>
+                    <span>{typeof sub.open_issues === 'number' ? sub.open_issues : '-'}</span>
+                </td>
                 <td>
                     <button
                         className='style--none color--link'
@@ -90,7 +102,7 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
     }
 
     render(): React.ReactElement {
-        const {channel, channelSubscriptions, omitDisplayName} = this.props;
+        const {channel, channelSubscriptions, omitDisplayName, warnings} = this.props;
         const {error, showConfirmModal, subscriptionToDelete} = this.state;
 
         let errorDisplay = null;
@@ -100,6 +112,17 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
             );
         }
 
+        let warningsDisplay = null;
+        if (warnings && warnings.length) {
+            warningsDisplay = (
+                <div className='alert alert-warning'>
+                    {warnings.map((warning) => (
+                        <p key={warning}>{warning}</p>
+                    ))}
+                </div>
+            );
+        }
+
         let confirmDeleteMessage = 'Delete Subscription?';
         if (subscriptionToDelete && subscriptionToDelete.name) {
             confirmDeleteMessage = `Delete Subscription "${subscriptionToDelete.name}"?`;
@@ -135,6 +158,7 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
                         <tr>
                             <th scope='col'>{'Name'}</th>
                             <th scope='col'>{'Project'}</th>
+                            <th scope='col'>{'Open issues'}</th>
                             <th scope='col'>{'Actions'}</th>
                         </tr>
                     </thead>
@@ -165,6 +189,7 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
                 </div>
                 {confirmModal}
                 {errorDisplay}
+                {warningsDisplay}
                 {subscriptionRows}
             </div>
         );
@@ -45,6 +45,7 @@ export default class CreateIssueModal extends PureComponent {
         jiraProjectMetadata: PropTypes.object,
         fetchJiraIssueMetadataForProjects: PropTypes.func.isRequired,
         fetchJiraProjectMetadata: PropTypes.func.isRequired,
+        fetchCreateDefaults: PropTypes.func.isRequired,
     };
 
     constructor(props) {
@@ -57,19 +58,25 @@ export default class CreateIssueModal extends PureComponent {
 
     componentDidUpdate(prevProps) {
         if (this.props.post && (!prevProps.post || this.props.post.id !== prevProps.post.id)) {
+            const channelId = this.props.post.channel_id;
             this.props.fetchJiraProjectMetadata().then((fetched) => {
                 if (fetched.error) {
                     this.setState({getMetaDataError: fetched.error.message, submitting: false});
+                    return;
                 }
+                this.applyCreateDefaults(channelId, fetched.data);
             });
             const fields = {...this.state.fields};
             fields.description = this.props.post.message;
             this.setState({fields}); //eslint-disable-line react/no-did-update-set-state
         } else if (this.props.channelId && (this.props.channelId !== prevProps.channelId || this.props.description !== prevProps.description)) {
+            const channelId = this.props.channelId;
             this.props.fetchJiraProjectMetadata().then((fetched) => {
                 if (fetched.error) {
                     this.setState({getMetaDataError: fetched.error.message, submitting: false});
+                    return;
                 }
+                this.applyCreateDefaults(channelId, fetched.data);
             });
             const fields = {...this.state.fields};
             fields.description = this.props.description;
@@ -77,18 +84,47 @@ export default class CreateIssueModal extends PureComponent {
         }
     }
 
+    // applyCreateDefaults selects the channel's default project and issue
+    // type, set with `/jira create defaults`, if the user can create issues
+    // in them.
+    applyCreateDefaults = (channelId, projectMetadata) => {
+        this.props.fetchCreateDefaults(channelId).then(({data}) => {
+            if (!data || !data.project_key || this.state.projectKey) {
+                return;
+            }
+            if (!getProjectValues(projectMetadata).find((option) => option.value === data.project_key)) {
+                return;
+            }
+
+            this.handleProjectChange('project', data.project_key, projectMetadata);
+            const issueTypes = getIssueValues(projectMetadata, data.project_key) || [];
+            if (data.issue_type_id && issueTypes.find((option) => option.value === data.issue_type_id)) {
+                this.handleIssueTypeChange('issuetype', data.issue_type_id);
+            }
+        });
+    };
+
     allowedFields = [
         'project',
         'issuetype',
         'priority',
         'description',
         'summary',
+        'components',
+        'labels',
+        'fixVersions',
+        'versions',
     ];
 
     allowedSchemaCustom = [
         'com.atlassian.jira.plugin.system.customfieldtypes:textarea',
         'com.atlassian.jira.plugin.system.customfieldtypes:textfield',
         'com.atlassian.jira.plugin.system.customfieldtypes:select',
+        'com.atlassian.jira.plugin.system.customfieldtypes:multiselect',
+        'com.atlassian.jira.plugin.system.customfieldtypes:multicheckboxes',
+        'com.atlassian.jira.plugin.system.customfieldtypes:radiobuttons',
+        'com.atlassian.jira.plugin.system.customfieldtypes:float',
+        'com.atlassian.jira.plugin.system.customfieldtypes:labels',
         'com.atlassian.jira.plugin.system.customfieldtypes:project',
 
         // 'com.pyxis.greenhopper.jira:gh-epic-link',
@@ -170,7 +206,7 @@ export default class CreateIssueModal extends PureComponent {
         this.setState({fields: nFields});
     };
 
-    handleProjectChange = (id, value) => {
+    handleProjectChange = (id, value, projectMetadata = this.props.jiraProjectMetadata) => {
         const projectKey = value;
 
         // Clear the current metadata so that we display a loading indicator while we fetch the new metadata
@@ -182,7 +218,7 @@ export default class CreateIssueModal extends PureComponent {
         });
 
         const fields = {...this.state.fields};
-        const issueTypes = getIssueValues(this.props.jiraProjectMetadata, value);
+        const issueTypes = getIssueValues(projectMetadata, value);
         const issueType = issueTypes.length && issueTypes[0].value;
         fields.project = {
             key: value,
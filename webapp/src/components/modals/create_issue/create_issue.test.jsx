@@ -18,6 +18,7 @@ describe('components/CreateIssue', () => {
         clearIssueMetadata: jest.fn().mockResolvedValue({}),
         fetchJiraIssueMetadataForProjects: jest.fn().mockResolvedValue({}),
         fetchJiraProjectMetadata: jest.fn().mockResolvedValue({}),
+        fetchCreateDefaults: jest.fn().mockResolvedValue({}),
         create: jest.fn().mockResolvedValue({}),
     };
 
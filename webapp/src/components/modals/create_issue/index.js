@@ -7,7 +7,7 @@ import {bindActionCreators} from 'redux';
 import {getPost} from 'mattermost-redux/selectors/entities/posts';
 import {getCurrentTeam} from 'mattermost-redux/selectors/entities/teams';
 
-import {closeCreateModal, createIssue, fetchJiraIssueMetadataForProjects, fetchJiraProjectMetadata, clearIssueMetadata} from 'actions';
+import {closeCreateModal, createIssue, fetchCreateDefaults, fetchJiraIssueMetadataForProjects, fetchJiraProjectMetadata, clearIssueMetadata} from 'actions';
 import {isCreateModalVisible, getCreateModal, getJiraIssueMetadata, getJiraProjectMetadata} from 'selectors';
 
 import CreateIssue from './create_issue';
@@ -34,6 +34,7 @@ const mapStateToProps = (state) => {
 const mapDispatchToProps = (dispatch) => bindActionCreators({
     close: closeCreateModal,
     create: createIssue,
+    fetchCreateDefaults,
     fetchJiraIssueMetadataForProjects,
     fetchJiraProjectMetadata,
     clearIssueMetadata,
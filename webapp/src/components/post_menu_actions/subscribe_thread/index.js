@@ -0,0 +1,37 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {connect} from 'react-redux';
+import {bindActionCreators} from 'redux';
+
+import {getPost} from 'mattermost-redux/selectors/entities/posts';
+import {isSystemMessage} from 'mattermost-redux/utils/post_utils';
+
+import {subscribeThreadToIssue, sendEphemeralPost} from 'actions';
+
+import {getCurrentUserLocale, isUserConnected, isInstanceInstalled} from 'selectors';
+import {isCombinedUserActivityPost, isJiraIssuePost} from 'utils/posts';
+
+import SubscribeThreadPostMenuAction from './subscribe_thread';
+
+const mapStateToProps = (state, ownProps) => {
+    const post = getPost(state, ownProps.postId);
+    const oldSystemMessageOrNull = post ? isSystemMessage(post) : true;
+    const systemMessage = isCombinedUserActivityPost(post) || oldSystemMessageOrNull;
+
+    return {
+        locale: getCurrentUserLocale(state),
+        isSystemMessage: systemMessage,
+        isIssuePost: isJiraIssuePost(post),
+        channelId: post ? post.channel_id : '',
+        userConnected: isUserConnected(state),
+        isInstanceInstalled: isInstanceInstalled(state),
+    };
+};
+
+const mapDispatchToProps = (dispatch) => bindActionCreators({
+    subscribeThreadToIssue,
+    sendEphemeralPost,
+}, dispatch);
+
+export default connect(mapStateToProps, mapDispatchToProps)(SubscribeThreadPostMenuAction);
@@ -0,0 +1,68 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, {PureComponent} from 'react';
+import PropTypes from 'prop-types';
+
+import JiraIcon from 'components/icon';
+
+export default class SubscribeThreadPostMenuAction extends PureComponent {
+    static propTypes = {
+        isSystemMessage: PropTypes.bool.isRequired,
+        isIssuePost: PropTypes.bool.isRequired,
+        locale: PropTypes.string,
+        postId: PropTypes.string,
+        channelId: PropTypes.string,
+        userConnected: PropTypes.bool.isRequired,
+        isInstanceInstalled: PropTypes.bool.isRequired,
+        subscribeThreadToIssue: PropTypes.func.isRequired,
+        sendEphemeralPost: PropTypes.func.isRequired,
+    };
+
+    static defaultTypes = {
+        locale: 'en',
+    };
+
+    getLocalizedTitle = () => {
+        const {locale} = this.props;
+        switch (locale) {
+        case 'es':
+            return 'Suscribir el hilo a la incidencia de Jira';
+        default:
+            return 'Subscribe Thread to Jira Issue';
+        }
+    };
+
+    handleClick = async (e) => {
+        const {postId, channelId} = this.props;
+        e.preventDefault();
+
+        // The plugin confirms the subscription with an ephemeral post
+        const {error} = await this.props.subscribeThreadToIssue(postId);
+        if (error) {
+            this.props.sendEphemeralPost(error.message, channelId);
+        }
+    };
+
+    render() {
+        if (this.props.isSystemMessage || !this.props.isIssuePost || !this.props.isInstanceInstalled || !this.props.userConnected) {
+            return null;
+        }
+
+        return (
+            <li
+                className='MenuItem'
+                role='menuitem'
+            >
+                <button
+                    className='style--none'
+                    role='presentation'
+                    onClick={this.handleClick}
+                >
+                    <JiraIcon type='menu'/>
+                    {this.getLocalizedTitle()}
+                </button>
+            </li>
+        );
+    }
+}
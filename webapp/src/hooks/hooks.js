@@ -27,7 +27,7 @@ export default class Hooks {
             shouldEnableCreate = this.settings.ui_enabled;
         }
 
-        if (messageTrimmed && messageTrimmed.startsWith('/jira create') && shouldEnableCreate) {
+        if (messageTrimmed && messageTrimmed.startsWith('/jira create') && !messageTrimmed.startsWith('/jira create defaults') && shouldEnableCreate) {
             if (!isInstanceInstalled(this.store.getState())) {
                 this.store.dispatch(sendEphemeralPost('There is no Jira instance installed. Please contact your system administrator.'));
                 return Promise.resolve({});
@@ -10,12 +10,13 @@ import ChannelSettingsModal from 'components/modals/channel_settings';
 
 import AttachCommentToIssuePostMenuAction from 'components/post_menu_actions/attach_comment_to_issue';
 import AttachCommentToIssueModal from 'components/modals/attach_comment_to_issue';
+import SubscribeThreadPostMenuAction from 'components/post_menu_actions/subscribe_thread';
 import SetupUI from 'components/setup_ui';
 
 import PluginId from 'plugin_id';
 
 import reducers from './reducers';
-import {handleConnectChange, getConnected, handleInstanceStatusChange, getSettings} from './actions';
+import {handleConnectChange, getConnected, handleInstanceStatusChange, handleHelpAction, getSettings} from './actions';
 import Hooks from './hooks/hooks';
 
 const setupUILater = (registry: PluginRegistry, store: Store<object, Action<object>>): () => Promise<void> => async () => {
@@ -31,6 +32,7 @@ const setupUILater = (registry: PluginRegistry, store: Store<object, Action<obje
             registry.registerPostDropdownMenuComponent(CreateIssuePostMenuAction);
             registry.registerRootComponent(AttachCommentToIssueModal);
             registry.registerPostDropdownMenuComponent(AttachCommentToIssuePostMenuAction);
+            registry.registerPostDropdownMenuComponent(SubscribeThreadPostMenuAction);
         }
 
         registry.registerRootComponent(ChannelSettingsModal);
@@ -41,6 +43,7 @@ const setupUILater = (registry: PluginRegistry, store: Store<object, Action<obje
         registry.registerWebSocketEventHandler(`custom_${PluginId}_connect`, handleConnectChange(store));
         registry.registerWebSocketEventHandler(`custom_${PluginId}_disconnect`, handleConnectChange(store));
         registry.registerWebSocketEventHandler(`custom_${PluginId}_instance_status`, handleInstanceStatusChange(store));
+        registry.registerWebSocketEventHandler(`custom_${PluginId}_help_action`, handleHelpAction(store));
     }
 };
 
@@ -97,7 +97,16 @@ export type ChannelSubscriptionFilters = {
     projects: string[];
     events: string[];
     issue_types: string[];
+    issue_type_names?: {[id: string]: string};
     fields: FilterValue[];
+    restricted_comments?: string;
+    parent_keys?: string[];
+    exclude_subtasks?: boolean;
+    ignored_actors?: string[];
+    updates_digest?: boolean;
+    hide_authors?: boolean;
+    property_key?: string;
+    property_values?: string[];
 };
 
 export type ChannelSubscription = {
@@ -105,4 +114,10 @@ export type ChannelSubscription = {
     channel_id: string;
     filters: ChannelSubscriptionFilters;
     name: string;
+    mention_rules?: {match: string; mention: string}[];
+    forward?: {type: string; url: string};
+    version?: number;
+    open_issues?: number;
+    non_compliant?: string;
+    paused?: boolean;
 }
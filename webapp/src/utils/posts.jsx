@@ -6,3 +6,14 @@ export const isCombinedUserActivityPost = (id) => {
     return (/^user-activity-(?:[^_]+_)*[^_]+$/).test(id);
 };
 
+// isJiraIssuePost returns true if the post is about a Jira issue: the post of
+// a Jira event, or a post linking to an issue.
+export const isJiraIssuePost = (post) => {
+    if (!post) {
+        return false;
+    }
+    if (post.props && post.props.jira_issue_key) {
+        return true;
+    }
+    return (/\/browse\/[A-Za-z][A-Za-z0-9_]*-[0-9]+/).test(post.message || '');
+};